@@ -0,0 +1,26 @@
+// Command calcifer is a command-line client for a running gateway, for
+// smoke-testing a deployment without hand-written curl requests.
+//
+// Point it at the gateway with --url or CALCIFER_URL; see calcifer --help.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/davidbz/calcifer/internal/cli"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cli.NewRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,705 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/cli"
+	"github.com/davidbz/calcifer/internal/httpserver"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/mockllm"
+)
+
+// e2eRules script the mock upstream for the end-to-end tests.
+//
+//nolint:gochecknoglobals // Shared test fixture
+var e2eRules = []mockllm.Rule{
+	{Contains: "explode", Status: http.StatusInternalServerError},
+	{Contains: "falter", Chunks: []string{"The ", "sky ", "is ", "blue"}, FailAfter: 2},
+	{
+		Contains: "slow down",
+		Status:   http.StatusTooManyRequests,
+		// The SDK honors retry-after-ms when retrying, keeping the test fast.
+		Headers: map[string]string{
+			"Retry-After":                  "7",
+			"Retry-After-Ms":               "10",
+			"X-Ratelimit-Remaining-Tokens": "0",
+		},
+	},
+	{
+		Contains: "show headers",
+		Content:  "Here they are",
+		Headers: map[string]string{
+			"X-Upstream-Trace": "trace-1",
+			"Authorization":    "Bearer upstream-secret",
+			"Set-Cookie":       "session=upstream",
+			"Upgrade":          "h2c",
+		},
+	},
+	{Model: "gpt-4", Content: "The sky is blue"},
+}
+
+// e2eAdminToken authorizes the admin API in the end-to-end tests.
+const e2eAdminToken = "admin-secret"
+
+// startGateway boots the full gateway, wired as in main, against a mock upstream.
+func startGateway(t *testing.T) (*httptest.Server, *mockllm.Server) {
+	t.Helper()
+
+	upstream := mockllm.NewServer(e2eRules)
+	upstreamServer := httptest.NewServer(upstream)
+	t.Cleanup(upstreamServer.Close)
+
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_BASE_URL", upstreamServer.URL+"/v1")
+	t.Setenv("OPENAI_MAX_RETRIES", "0")
+	t.Setenv("CHAOS_PROVIDER_ENABLED", "true")
+	t.Setenv("ADMIN_TOKEN", e2eAdminToken)
+
+	var handler http.Handler
+	require.NoError(t, buildContainer().Invoke(func(server *httpserver.Server) {
+		handler = server.Handler()
+	}))
+
+	gateway := httptest.NewServer(handler)
+	t.Cleanup(gateway.Close)
+	return gateway, upstream
+}
+
+// complete posts a completion request to the gateway.
+func complete(t *testing.T, gateway *httptest.Server, body string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, gateway.URL+"/v1/completions",
+		strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// get sends a GET request to the gateway, with the admin token when admin is set.
+func get(t *testing.T, gateway *httptest.Server, path string, admin bool) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, gateway.URL+path, nil)
+	require.NoError(t, err)
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+e2eAdminToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// send sends an admin request with a JSON body to the gateway.
+func send(t *testing.T, gateway *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, gateway.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e2eAdminToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// runCLI runs the calcifer client against the gateway and returns its output.
+func runCLI(t *testing.T, gateway *httptest.Server, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	cmd := cli.NewRootCommand()
+	cmd.SetArgs(append([]string{"--url", gateway.URL, "--admin-token", e2eAdminToken}, args...))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	require.NoError(t, cmd.ExecuteContext(t.Context()))
+	return out.String()
+}
+
+// decode reads a JSON response body.
+func decode(t *testing.T, resp *http.Response) map[string]any {
+	t.Helper()
+
+	var payload map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+	return payload
+}
+
+// readErrorEvents reads an SSE stream, returning its event names and whether the
+// error event flagged the stream as partial.
+func readErrorEvents(t *testing.T, resp *http.Response) ([]string, bool) {
+	t.Helper()
+
+	var events []string
+	partial := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+			require.True(t, scanner.Scan())
+			data, found := strings.CutPrefix(scanner.Text(), "data: ")
+			require.True(t, found)
+
+			var frame struct {
+				Error   map[string]any `json:"error"`
+				Partial bool           `json:"partial"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &frame))
+			require.NotEmpty(t, frame.Error["message"])
+			partial = frame.Partial
+		}
+	}
+	return events, partial
+}
+
+func TestEndToEnd(t *testing.T) {
+	gateway, upstream := startGateway(t)
+
+	t.Run("should route a model to the upstream provider", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","messages":[{"role":"user","content":"What color is the sky?"}]}`, nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		payload := decode(t, resp)
+		require.Equal(t, "openai", payload["provider"])
+		require.Equal(t, "The sky is blue", payload["content"])
+	})
+
+	t.Run("should route the echo model without calling the upstream", func(t *testing.T) {
+		before := upstream.Requests()
+
+		resp := complete(t, gateway, `{"model":"echo4","messages":[{"role":"user","content":"ping"}]}`, nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "echo", decode(t, resp)["provider"])
+		require.Equal(t, before, upstream.Requests())
+	})
+
+	t.Run("should stream upstream chunks as server-sent events", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","stream":true,"messages":[{"role":"user","content":"What color is the sky?"}]}`, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		var content strings.Builder
+		done := false
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var chunk struct {
+				Delta string `json:"delta"`
+				Done  bool   `json:"done"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+			content.WriteString(chunk.Delta)
+			done = done || chunk.Done
+		}
+
+		require.True(t, done)
+		require.Equal(t, "The sky is blue", content.String())
+	})
+
+	t.Run("should replay an idempotent request without calling the upstream again", func(t *testing.T) {
+		body := `{"model":"gpt-4","messages":[{"role":"user","content":"Only once"}]}`
+		headers := map[string]string{middleware.IdempotencyKeyHeader: "e2e-replay"}
+		before := upstream.Requests()
+
+		first := complete(t, gateway, body, headers)
+		require.Equal(t, http.StatusOK, first.StatusCode)
+		firstPayload := decode(t, first)
+
+		second := complete(t, gateway, body, headers)
+		require.Equal(t, http.StatusOK, second.StatusCode)
+		require.Equal(t, "true", second.Header.Get(middleware.IdempotentReplayedHeader))
+		require.Equal(t, firstPayload, decode(t, second))
+		require.Equal(t, before+1, upstream.Requests())
+	})
+
+	t.Run("should report an upstream failure as a bad gateway", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","messages":[{"role":"user","content":"explode"}]}`, nil)
+
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "upstream_error", envelope["type"])
+		require.InDelta(t, http.StatusInternalServerError, envelope["upstream_status"], 0)
+	})
+
+	t.Run("should pass an upstream rate limit and its headers to the client", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","messages":[{"role":"user","content":"slow down"}]}`, nil)
+
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.Equal(t, "7", resp.Header.Get("Retry-After"))
+		require.Equal(t, "0", resp.Header.Get("X-Ratelimit-Remaining-Tokens"))
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "rate_limited", envelope["type"])
+		require.Equal(t, "openai", envelope["provider"])
+	})
+
+	t.Run("should report an upstream failure opening a stream as a bad gateway", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","stream":true,"messages":[{"role":"user","content":"explode"}]}`, nil)
+
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "upstream_error", envelope["type"])
+		require.InDelta(t, http.StatusInternalServerError, envelope["upstream_status"], 0)
+	})
+
+	t.Run("should pass an upstream rate limit opening a stream and its headers to the client", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","stream":true,"messages":[{"role":"user","content":"slow down"}]}`, nil)
+
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.Equal(t, "7", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("should report an upstream failure mid-stream as a partial error event", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","stream":true,"messages":[{"role":"user","content":"falter"}]}`, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		events, partial := readErrorEvents(t, resp)
+		require.Equal(t, []string{"error"}, events)
+		require.True(t, partial)
+	})
+
+	t.Run("should flag a stream that fails after sending content as partial and not replay it", func(t *testing.T) {
+		body := `{"model":"chaos4","stream":true,"metadata":{"chaos_drop_after":"2"},` +
+			`"messages":[{"role":"user","content":"one two three four five"}]}`
+		headers := map[string]string{middleware.IdempotencyKeyHeader: "e2e-partial"}
+
+		first := complete(t, gateway, body, headers)
+		require.Equal(t, http.StatusOK, first.StatusCode)
+		events, partial := readErrorEvents(t, first)
+		require.Equal(t, []string{"error"}, events)
+		require.True(t, partial)
+
+		second := complete(t, gateway, body, headers)
+		require.Equal(t, http.StatusOK, second.StatusCode)
+		require.Empty(t, second.Header.Get(middleware.IdempotentReplayedHeader))
+	})
+
+	t.Run("should reject an invalid message with a field-level error", func(t *testing.T) {
+		before := upstream.Requests()
+
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","messages":[{"role":"user","content":"hi"},{"role":"user","content":""}]}`, nil)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "messages[1].content", envelope["param"])
+		require.Equal(t, before, upstream.Requests())
+	})
+
+	t.Run("should reject a model no provider serves", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"no-such-model","messages":[{"role":"user","content":"hi"}]}`, nil)
+
+		require.NotEqual(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, decode(t, resp), "error")
+	})
+
+	t.Run("should serve the admin dashboard page", func(t *testing.T) {
+		resp := get(t, gateway, "/admin/ui/", false)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+		require.NotEmpty(t, resp.Header.Get("Content-Security-Policy"))
+	})
+
+	t.Run("should report provider health and recent usage to the dashboard", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, get(t, gateway, "/admin/dashboard", false).StatusCode)
+
+		resp := get(t, gateway, "/admin/dashboard?window=1h", true)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		payload := decode(t, resp)
+		require.Contains(t, payload["providers"], map[string]any{"name": "openai", "enabled": true, "healthy": true})
+
+		usage, ok := payload["usage"].(map[string]any)
+		require.True(t, ok)
+		require.Positive(t, usage["requests"])
+		recent, ok := usage["recent"].([]any)
+		require.True(t, ok)
+		require.NotEmpty(t, recent)
+	})
+	t.Run("should list the served models", func(t *testing.T) {
+		resp := get(t, gateway, "/v1/models", false)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		models, ok := decode(t, resp)["data"].([]any)
+		require.True(t, ok)
+		require.Contains(t, models, map[string]any{"id": "echo4", "providers": []any{"echo"}})
+	})
+
+	t.Run("should smoke-test the gateway with the CLI", func(t *testing.T) {
+		require.Equal(t, "The sky is blue\n", runCLI(t, gateway, "chat", "-m", "gpt-4", "What color is the sky?"))
+		require.Contains(t, runCLI(t, gateway, "chat", "--no-stream", "-m", "gpt-4", "Hi"), "gpt-4 via openai")
+		require.Contains(t, runCLI(t, gateway, "models"), "echo4")
+		require.Contains(t, runCLI(t, gateway, "usage", "--group-by", "provider"), "openai")
+		require.Contains(t, runCLI(t, gateway, "cache", "stats", "--window", "1h"), "prompt cache hit rate: ")
+	})
+
+	t.Run("should require the admin token for usage without client authentication", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, get(t, gateway, "/v1/usage", false).StatusCode)
+		require.Equal(t, http.StatusOK, get(t, gateway, "/v1/usage", true).StatusCode)
+	})
+	t.Run("should report registered providers and effective settings", func(t *testing.T) {
+		resp := get(t, gateway, "/admin/status", true)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		payload := decode(t, resp)
+		require.Equal(t, "normal", payload["mode"])
+		require.Contains(t, payload["providers"],
+			map[string]any{"name": "openai", "source": "built-in", "registered": true})
+		require.Contains(t, payload["providers"],
+			map[string]any{"name": "chaos", "source": "test", "registered": true})
+
+		settings, ok := payload["settings"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "model", settings["routing"])
+		require.Equal(t, "ttl 300s", settings["idempotency_cache"])
+	})
+}
+
+func TestEndToEnd_Overrides(t *testing.T) {
+	t.Setenv("OVERRIDES_DB_PATH", filepath.Join(t.TempDir(), "overrides.db"))
+	gateway, _ := startGateway(t)
+
+	resp := send(t, gateway, http.MethodPut, "/admin/overrides/aliases/fast", `{"model":"echo4"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = send(t, gateway, http.MethodPut, "/admin/overrides/pricing/echo4",
+		`{"input_cost_per_1k":1,"output_cost_per_1k":2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("should route an alias set through the admin API", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"fast","messages":[{"role":"user","content":"ping"}]}`, nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		payload := decode(t, resp)
+		require.Equal(t, "echo", payload["provider"])
+		usage, ok := payload["usage"].(map[string]any)
+		require.True(t, ok)
+		require.Positive(t, usage["cost"])
+	})
+
+	t.Run("should keep overrides across restarts", func(t *testing.T) {
+		restarted, _ := startGateway(t)
+
+		resp := get(t, restarted, "/admin/overrides", true)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		payload := decode(t, resp)
+		require.Equal(t, []any{map[string]any{"alias": "fast", "model": "echo4"}}, payload["aliases"])
+		require.Equal(t, []any{map[string]any{"model": "echo4", "input_cost_per_1k": 1.0, "output_cost_per_1k": 2.0}},
+			payload["pricing"])
+	})
+
+	t.Run("should reject a key policy reusing another policy's key", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPut, "/admin/overrides/policies/a", `{"keys":["ci"]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = send(t, gateway, http.MethodPut, "/admin/overrides/policies/b", `{"keys":["ci"]}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestEndToEnd_Deprecations(t *testing.T) {
+	t.Setenv("MODEL_DEPRECATIONS", "gpt-4=2099-01-01:gpt-4o,gpt-3.5-turbo=2000-01-01:gpt-4,echo3=2000-01-01")
+	gateway, _ := startGateway(t)
+	ask := func(model string) string {
+		return `{"model":"` + model + `","messages":[{"role":"user","content":"What color is the sky?"}]}`
+	}
+
+	t.Run("should serve a deprecated model with deprecation headers", func(t *testing.T) {
+		resp := complete(t, gateway, ask("gpt-4"), nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "true", resp.Header.Get("Deprecation"))
+		require.Equal(t, "Thu, 01 Jan 2099 00:00:00 GMT", resp.Header.Get("Sunset"))
+		require.Equal(t, "gpt-4o", resp.Header.Get("X-Calcifer-Model-Replacement"))
+		require.Equal(t, "gpt-4", decode(t, resp)["model"])
+	})
+
+	t.Run("should route a model past its sunset to the replacement", func(t *testing.T) {
+		resp := complete(t, gateway, ask("gpt-3.5-turbo"), nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "true", resp.Header.Get("Deprecation"))
+		require.Equal(t, "The sky is blue", decode(t, resp)["content"])
+	})
+
+	t.Run("should reject a retired model without a replacement", func(t *testing.T) {
+		resp := complete(t, gateway, ask("echo3"), nil)
+
+		require.Equal(t, http.StatusGone, resp.StatusCode)
+		errorBody, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "model_retired", errorBody["type"])
+	})
+}
+
+func TestEndToEnd_Responses(t *testing.T) {
+	gateway, _ := startGateway(t)
+
+	t.Run("should apply the message count limit to the input", func(t *testing.T) {
+		items := strings.Repeat(`{"role":"user","content":"hi"},`, 257)
+		resp := send(t, gateway, http.MethodPost, "/v1/responses",
+			`{"model":"gpt-4","input":[`+strings.TrimSuffix(items, ",")+`]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "too many messages: 257 exceeds limit of 256", envelope["message"])
+	})
+
+	t.Run("should answer a Responses API request with a response object", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/responses",
+			`{"model":"gpt-4","instructions":"Be brief.","input":"What color is the sky?"}`)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var payload struct {
+			ID     string `json:"id"`
+			Object string `json:"object"`
+			Status string `json:"status"`
+			Output []struct {
+				Role    string `json:"role"`
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"output"`
+			Usage struct {
+				InputTokens int `json:"input_tokens"`
+			} `json:"usage"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		require.True(t, strings.HasPrefix(payload.ID, "resp_"))
+		require.Equal(t, "response", payload.Object)
+		require.Equal(t, "completed", payload.Status)
+		require.Len(t, payload.Output, 1)
+		require.Equal(t, "assistant", payload.Output[0].Role)
+		require.Equal(t, "output_text", payload.Output[0].Content[0].Type)
+		require.Equal(t, "The sky is blue", payload.Output[0].Content[0].Text)
+		require.Positive(t, payload.Usage.InputTokens)
+	})
+
+	t.Run("should accept message items with text parts", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/responses",
+			`{"model":"echo4","input":[{"role":"user","content":[{"type":"input_text","text":"ping"}]}]}`)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		output, ok := decode(t, resp)["output"].([]any)
+		require.True(t, ok)
+		require.Len(t, output, 1)
+	})
+
+	t.Run("should stream Responses API events", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/responses",
+			`{"model":"gpt-4","stream":true,"input":"What color is the sky?"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		var events []string
+		var content strings.Builder
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event struct {
+				Type  string `json:"type"`
+				Delta string `json:"delta"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			events = append(events, event.Type)
+			if event.Type == "response.output_text.delta" {
+				content.WriteString(event.Delta)
+			}
+		}
+
+		require.Equal(t, "response.created", events[0])
+		require.Equal(t, "response.completed", events[len(events)-1])
+		require.Equal(t, "The sky is blue", content.String())
+	})
+
+	t.Run("should reject a request that continues a stored response", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/responses",
+			`{"model":"gpt-4","input":"And at night?","previous_response_id":"resp_123"}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestEndToEnd_LegacyCompletions(t *testing.T) {
+	gateway, _ := startGateway(t)
+
+	t.Run("should apply the message length limit to the prompt", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","prompt":"`+strings.Repeat("x", 100001)+`"}`, nil)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		envelope, ok := decode(t, resp)["error"].(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "prompt: length 100001 exceeds limit of 100000", envelope["message"])
+	})
+
+	t.Run("should answer a prompt with a text completion", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","prompt":"What color is the sky?","echo":true}`, nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var payload struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Text  string `json:"text"`
+				Index int    `json:"index"`
+			} `json:"choices"`
+			Usage struct {
+				TotalTokens int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		require.Equal(t, "text_completion", payload.Object)
+		require.Len(t, payload.Choices, 1)
+		require.Equal(t, "What color is the sky?The sky is blue", payload.Choices[0].Text)
+		require.Positive(t, payload.Usage.TotalTokens)
+	})
+
+	t.Run("should stream text completion chunks ending with done", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","stream":true,"prompt":["What color is the sky?"]}`, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var content strings.Builder
+		done := false
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				done = true
+				continue
+			}
+			var chunk struct {
+				Object  string `json:"object"`
+				Choices []struct {
+					Text string `json:"text"`
+				} `json:"choices"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+			require.Equal(t, "text_completion", chunk.Object)
+			content.WriteString(chunk.Choices[0].Text)
+		}
+
+		require.True(t, done)
+		require.Equal(t, "The sky is blue", content.String())
+	})
+
+	t.Run("should reject batched prompts", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","prompt":["one","two"]}`, nil)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should reject a prompt sent with messages", func(t *testing.T) {
+		resp := complete(t, gateway,
+			`{"model":"gpt-4","prompt":"hi","messages":[{"role":"user","content":"hi"}]}`, nil)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestEndToEnd_Speech(t *testing.T) {
+	gateway, _ := startGateway(t)
+
+	t.Run("should relay synthesized audio and record per-character usage", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/audio/speech",
+			`{"model":"tts-1","input":"Hello there","voice":"alloy"}`)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "audio/mpeg", resp.Header.Get("Content-Type"))
+		audio, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "audio:Hello there", string(audio))
+
+		usageResp := send(t, gateway, http.MethodGet, "/v1/usage?group_by=model", "")
+		require.Equal(t, http.StatusOK, usageResp.StatusCode)
+		var report struct {
+			Data []struct {
+				Group      string  `json:"group"`
+				Characters int     `json:"characters"`
+				Cost       float64 `json:"cost"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(usageResp.Body).Decode(&report))
+		require.Len(t, report.Data, 1)
+		require.Equal(t, "tts-1", report.Data[0].Group)
+		require.Equal(t, 11, report.Data[0].Characters)
+		require.InDelta(t, 0.000165, report.Data[0].Cost, 1e-9)
+	})
+
+	t.Run("should reject a model no provider synthesizes", func(t *testing.T) {
+		resp := send(t, gateway, http.MethodPost, "/v1/audio/speech", `{"model":"gpt-4","input":"Hi","voice":"alloy"}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestEndToEnd_Moderation(t *testing.T) {
+	gateway, _ := startGateway(t)
+
+	for _, body := range []string{`{}`, `{"input":null}`, `{"input":[]}`} {
+		t.Run("should reject a missing input: "+body, func(t *testing.T) {
+			resp := send(t, gateway, http.MethodPost, "/v1/moderations", body)
+
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			envelope, ok := decode(t, resp)["error"].(map[string]any)
+			require.True(t, ok)
+			require.Equal(t, "input is required", envelope["message"])
+		})
+	}
+}
+
+func TestEndToEnd_ResponseHeaders(t *testing.T) {
+	t.Setenv("SERVER_RESPONSE_HEADER_ALLOWLIST", "*")
+	gateway, _ := startGateway(t)
+
+	t.Run("should never forward blocked headers, even to a wildcard allowlist", func(t *testing.T) {
+		resp := complete(t, gateway, `{"model":"gpt-4","messages":[{"role":"user","content":"show headers"}]}`, nil)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "trace-1", resp.Header.Get("X-Upstream-Trace"))
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Empty(t, resp.Header.Get("Authorization"))
+		require.Empty(t, resp.Header.Get("Set-Cookie"))
+		require.Empty(t, resp.Header.Get("Upgrade"))
+		require.Equal(t, "Here they are", decode(t, resp)["content"])
+	})
+}
@@ -6,19 +6,34 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"go.uber.org/dig"
 
+	"github.com/davidbz/calcifer/internal/alerts"
 	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/conversations"
 	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/events"
+	"github.com/davidbz/calcifer/internal/healthsync"
 	"github.com/davidbz/calcifer/internal/httpserver"
 	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/keys"
 	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/overrides"
+	"github.com/davidbz/calcifer/internal/pricing"
 	"github.com/davidbz/calcifer/internal/provider/echo"
+	"github.com/davidbz/calcifer/internal/provider/elevenlabs"
 	"github.com/davidbz/calcifer/internal/provider/openai"
 	"github.com/davidbz/calcifer/internal/provider/registry"
+	"github.com/davidbz/calcifer/internal/provider/replay"
+	"github.com/davidbz/calcifer/internal/quota"
+	"github.com/davidbz/calcifer/internal/scripting"
+	"github.com/davidbz/calcifer/internal/usage"
+	"github.com/davidbz/calcifer/pkg/plugin"
 )
 
 const (
@@ -26,14 +41,19 @@ const (
 	shutdownTimeout = 30 * time.Second
 )
 
-// ErrProviderNotConfigured indicates that a provider is not configured and should be skipped.
-var ErrProviderNotConfigured = errors.New("provider not configured")
-
 func main() {
 	container := buildContainer()
 	ctx := context.Background()
 	logger := observability.FromContext(ctx)
 
+	mustInvoke(container, func(report *domain.StartupReport) {
+		report.Log(ctx)
+	})
+
+	// Background jobs run until the server has shut down.
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	startBackgroundJobs(jobsCtx, container)
+
 	// Start server in goroutine
 	serverErr := make(chan error, 1)
 	go func() {
@@ -64,6 +84,20 @@ func main() {
 	})
 	cancel()
 
+	// Streams still open after the shutdown timeout are cut before stores close.
+	mustInvoke(container, func(streams *domain.StreamWatchdog) {
+		streams.CancelAll()
+	})
+
+	// Jobs stop only once requests are done, so the events those requests published are still delivered.
+	stopJobs()
+	mustInvoke(container, func(eventBus *events.Bus) {
+		if eventBus != nil {
+			<-eventBus.Stopped()
+		}
+	})
+	closeStores(container)
+
 	if err != nil {
 		logger.Error("server shutdown failed", observability.Error(err))
 		os.Exit(1)
@@ -77,13 +111,21 @@ func buildContainer() *dig.Container {
 
 	provideConfig(container)
 	provideObservability(container)
+	providePlugins(container)
 	provideRegistries(container)
 	provideCostCalculator(container)
 	provideEcho(container)
 	provideOpenAI(container)
+	provideElevenLabs(container)
 	registerProviders(container)
 	registerPricing(container)
+	registerCapabilities(container)
+	registerCustomProviders(container)
+	registerPluginProviders(container)
 	provideDomainServices(container)
+	checkPricing(container)
+	reportSettings(container)
+	reportDegraded(container)
 	provideHTTPLayer(container)
 
 	return container
@@ -92,67 +134,218 @@ func buildContainer() *dig.Container {
 func provideConfig(container *dig.Container) {
 	mustProvide(container, config.Load)
 	mustProvide(container, config.ParseDependenciesConfig)
+
+	// Inconsistent configuration stops startup before any component is built.
+	if err := container.Invoke(config.Validate); err != nil {
+		logger := observability.FromContext(context.Background())
+		logger.Fatal("refusing to start", observability.Error(dig.RootCause(err)))
+	}
 }
 
 func provideObservability(container *dig.Container) {
-	mustProvide(container, observability.InitLogger)
+	// Install the configured logger before anything else logs.
+	mustInvoke(container, func(cfg *config.LoggingConfig) error {
+		_, err := observability.InitLogger(observability.LoggerOptions{RedactContent: cfg.RedactContent})
+		return err
+	})
+}
+
+func providePlugins(container *dig.Container) {
+	mustProvide(container, func() *plugin.Registry {
+		plugins := plugin.NewRegistry()
+		registerPlugins(plugins)
+		return plugins
+	})
 }
 
 func provideRegistries(container *dig.Container) {
 	mustProvide(container, func() domain.ProviderRegistry {
 		return registry.NewRegistry()
 	})
-	mustProvide(container, func() domain.PricingRegistry {
-		return domain.NewInMemoryPricingRegistry()
+	mustProvide(container, overrides.NewStore)
+	mustProvide(container, newOverrides)
+	// Pricing overrides made through the admin API take precedence over registered pricing.
+	mustProvide(container, func(manager *domain.Overrides) domain.PricingRegistry {
+		return manager.PricingRegistry(domain.NewInMemoryPricingRegistry())
+	})
+	mustProvide(container, func() domain.CapabilityRegistry {
+		return domain.NewInMemoryCapabilityRegistry()
 	})
+	mustProvide(container, domain.NewStartupReport)
 }
 
 func provideCostCalculator(container *dig.Container) {
-	mustProvide(container, func(reg domain.PricingRegistry) domain.CostCalculator {
-		return domain.NewStandardCostCalculator(reg)
+	mustProvide(container, func(
+		reg domain.PricingRegistry,
+		cfg *config.ChargebackConfig,
+	) (domain.CostCalculator, error) {
+		calculator := domain.NewStandardCostCalculator(reg)
+		if cfg.MarkupPercent == 0 && strings.EqualFold(cfg.Currency, domain.DefaultCurrency) {
+			return calculator, nil
+		}
+
+		// Chargeback pricing reports the raw provider cost alongside the charged cost.
+		chargeback, err := domain.NewChargebackCostCalculator(
+			calculator, cfg.MarkupPercent, cfg.Currency, cfg.ExchangeRates,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return chargeback, nil
 	})
 }
 
+// testProviders contributes test providers to the "test" group. It is empty when
+// the provider is disabled, which its constructor records in the startup report.
+type testProviders struct {
+	dig.Out
+
+	Providers []domain.Provider `group:"test,flatten"`
+}
+
+// upstreamProviders contributes providers that call an upstream API to the
+// "upstream" group, and those synthesizing speech to the "speech" group. It is
+// empty when the provider is not configured, which its constructor records in
+// the startup report; a provider that is configured but cannot be built fails
+// startup instead.
+type upstreamProviders struct {
+	dig.Out
+
+	Providers    []domain.Provider    `group:"upstream,flatten"`
+	Synthesizers []domain.Synthesizer `group:"speech,flatten"`
+}
+
+// builtInProviders is every test, upstream, and speech provider that was built.
+type builtInProviders struct {
+	dig.In
+
+	Test     []domain.Provider    `group:"test"`
+	Upstream []domain.Provider    `group:"upstream"`
+	Speech   []domain.Synthesizer `group:"speech"`
+}
+
 func provideEcho(container *dig.Container) {
-	mustProvide(container, echo.NewProvider)
+	// The echo provider is always served (no config needed).
+	mustProvide(container, func() testProviders {
+		return testProviders{Out: dig.Out{}, Providers: []domain.Provider{echo.NewProvider()}}
+	})
+	mustProvide(container, func(cfg *config.ChaosConfig, report *domain.StartupReport) testProviders {
+		if !cfg.Enabled {
+			report.Skipped(echo.ChaosProviderName, domain.ProviderSourceTest, "CHAOS_PROVIDER_ENABLED is false")
+			return testProviders{Out: dig.Out{}, Providers: nil}
+		}
+		return testProviders{Out: dig.Out{}, Providers: []domain.Provider{echo.NewChaosProvider()}}
+	})
 }
 
 func provideOpenAI(container *dig.Container) {
-	mustProvide(container, func(cfg *openai.Config) (*openai.Provider, error) {
-		if cfg.APIKey == "" {
-			return nil, ErrProviderNotConfigured
+	mustProvide(container, func(cfg *openai.Config, report *domain.StartupReport) (upstreamProviders, error) {
+		if !cfg.HasAPIKey() {
+			report.Skipped(openai.ProviderName, domain.ProviderSourceBuiltIn, "OPENAI_API_KEY is not set")
+			return upstreamProviders{Out: dig.Out{}, Providers: nil, Synthesizers: nil}, nil
+		}
+
+		provider, err := openai.NewProvider(*cfg)
+		if err != nil {
+			return upstreamProviders{Out: dig.Out{}, Providers: nil, Synthesizers: nil},
+				fmt.Errorf("failed to create OpenAI provider: %w", err)
 		}
+		return upstreamProviders{
+			Out:          dig.Out{},
+			Providers:    []domain.Provider{provider},
+			Synthesizers: []domain.Synthesizer{provider},
+		}, nil
+	})
+}
 
-		return openai.NewProvider(*cfg)
+func provideElevenLabs(container *dig.Container) {
+	mustProvide(container, func(cfg *elevenlabs.Config, report *domain.StartupReport) (upstreamProviders, error) {
+		if !cfg.HasAPIKey() {
+			report.Skipped(elevenlabs.ProviderName, domain.ProviderSourceBuiltIn, "ELEVENLABS_API_KEY is not set")
+			return upstreamProviders{Out: dig.Out{}, Providers: nil, Synthesizers: nil}, nil
+		}
+
+		provider, err := elevenlabs.NewProvider(*cfg)
+		if err != nil {
+			return upstreamProviders{Out: dig.Out{}, Providers: nil, Synthesizers: nil},
+				fmt.Errorf("failed to create ElevenLabs provider: %w", err)
+		}
+		return upstreamProviders{Out: dig.Out{}, Providers: nil, Synthesizers: []domain.Synthesizer{provider}}, nil
 	})
 }
 
 func registerProviders(container *dig.Container) {
-	err := container.Invoke(func(
+	mustInvoke(container, func(
 		reg domain.ProviderRegistry,
-		echoProvider *echo.Provider,
-		openaiProvider *openai.Provider,
+		providers builtInProviders,
+		replayCfg *config.ReplayConfig,
+		report *domain.StartupReport,
 	) error {
 		ctx := context.Background()
 
-		// Echo provider is always registered (no config needed)
-		if err := reg.Register(ctx, echoProvider); err != nil {
-			return fmt.Errorf("failed to register echo provider: %w", err)
+		// Group order is unspecified, so register by name for a stable report.
+		byName := func(a, b domain.Provider) int { return strings.Compare(a.Name(), b.Name()) }
+		slices.SortFunc(providers.Test, byName)
+		slices.SortFunc(providers.Upstream, byName)
+
+		for _, provider := range providers.Test {
+			if err := reg.Register(ctx, provider); err != nil {
+				return fmt.Errorf("failed to register %s provider: %w", provider.Name(), err)
+			}
+			report.Registered(provider.Name(), domain.ProviderSourceTest)
+		}
+
+		switch replayCfg.Mode {
+		case replay.ModeOff, replay.ModeRecord:
+		case replay.ModeReplay:
+			players, err := replay.NewPlayers(replayCfg.Dir)
+			if err != nil {
+				return err
+			}
+			for _, player := range players {
+				if err = reg.Register(ctx, player); err != nil {
+					return fmt.Errorf("failed to register replayed provider %s: %w", player.Name(), err)
+				}
+				report.Registered(player.Name(), domain.ProviderSourceReplay)
+			}
+		default:
+			return fmt.Errorf("unknown replay mode %q", replayCfg.Mode)
 		}
 
-		if openaiProvider != nil {
-			if err := reg.Register(ctx, openaiProvider); err != nil {
-				return fmt.Errorf("failed to register OpenAI provider: %w", err)
+		// Upstream providers are skipped when replayed from cassettes.
+		for _, provider := range providers.Upstream {
+			if replayCfg.Mode == replay.ModeReplay {
+				report.Skipped(provider.Name(), domain.ProviderSourceBuiltIn, "REPLAY_MODE is replay")
+				continue
 			}
+			if err := reg.Register(ctx, recordProvider(replayCfg, provider)); err != nil {
+				return fmt.Errorf("failed to register %s provider: %w", provider.Name(), err)
+			}
+			report.Registered(provider.Name(), domain.ProviderSourceBuiltIn)
 		}
 
+		// Speech-only providers are not registered for completions; chat providers
+		// that also synthesize speech were reported above.
+		for _, synthesizer := range providers.Speech {
+			if _, ok := synthesizer.(domain.Provider); ok {
+				continue
+			}
+			if replayCfg.Mode == replay.ModeReplay {
+				report.Skipped(synthesizer.Name(), domain.ProviderSourceBuiltIn, "REPLAY_MODE is replay")
+				continue
+			}
+			report.Registered(synthesizer.Name(), domain.ProviderSourceBuiltIn)
+		}
 		return nil
 	})
-	if err != nil && !errors.Is(err, ErrProviderNotConfigured) {
-		ctx := context.Background()
-		logger := observability.FromContext(ctx)
-		logger.Fatal("failed to register providers", observability.Error(err))
+}
+
+// recordProvider saves the upstream provider's responses as cassettes in record mode.
+func recordProvider(cfg *config.ReplayConfig, provider domain.Provider) domain.Provider {
+	if cfg.Mode == replay.ModeRecord {
+		return replay.NewRecorder(provider, cfg.Dir)
 	}
+	return provider
 }
 
 func registerPricing(container *dig.Container) {
@@ -169,25 +362,735 @@ func registerPricing(container *dig.Container) {
 			return fmt.Errorf("failed to register OpenAI pricing: %w", err)
 		}
 
+		// Register ElevenLabs pricing (per character)
+		if err := elevenlabs.RegisterPricing(ctx, pricingReg); err != nil {
+			return fmt.Errorf("failed to register ElevenLabs pricing: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func registerCapabilities(container *dig.Container) {
+	mustInvoke(container, func(capabilityReg domain.CapabilityRegistry) error {
+		ctx := context.Background()
+
+		if err := echo.RegisterCapabilities(ctx, capabilityReg); err != nil {
+			return fmt.Errorf("failed to register echo capabilities: %w", err)
+		}
+
+		if err := openai.RegisterCapabilities(ctx, capabilityReg); err != nil {
+			return fmt.Errorf("failed to register OpenAI capabilities: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func registerCustomProviders(container *dig.Container) {
+	mustInvoke(container, func(
+		cfg *config.CustomProviderConfig,
+		replayCfg *config.ReplayConfig,
+		reg domain.ProviderRegistry,
+		pricingReg domain.PricingRegistry,
+		capabilityReg domain.CapabilityRegistry,
+		report *domain.StartupReport,
+	) error {
+		if cfg.Path == "" {
+			return nil
+		}
+
+		customs, err := openai.LoadCompatibleConfigs(cfg.Path)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		for _, custom := range customs {
+			provider, err := openai.NewCompatibleProvider(custom)
+			if err != nil {
+				return fmt.Errorf("failed to create custom provider %s: %w", custom.Name, err)
+			}
+
+			// Replayed providers are served from cassettes instead.
+			if replayCfg.Mode == replay.ModeReplay {
+				report.Skipped(custom.Name, domain.ProviderSourceCustom, "REPLAY_MODE is replay")
+			} else {
+				if err := reg.Register(ctx, recordProvider(replayCfg, provider)); err != nil {
+					return fmt.Errorf("failed to register custom provider %s: %w", custom.Name, err)
+				}
+				report.Registered(custom.Name, domain.ProviderSourceCustom)
+			}
+
+			if err := custom.RegisterPricing(ctx, pricingReg); err != nil {
+				return fmt.Errorf("failed to register %s pricing: %w", custom.Name, err)
+			}
+
+			if err := custom.RegisterCapabilities(ctx, capabilityReg); err != nil {
+				return fmt.Errorf("failed to register %s capabilities: %w", custom.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// registerPluginProviders registers the providers of compiled-in plugins, see cmd/plugins.go.
+func registerPluginProviders(container *dig.Container) {
+	mustInvoke(container, func(
+		plugins *plugin.Registry,
+		replayCfg *config.ReplayConfig,
+		reg domain.ProviderRegistry,
+		pricingReg domain.PricingRegistry,
+		capabilityReg domain.CapabilityRegistry,
+		report *domain.StartupReport,
+	) error {
+		ctx := context.Background()
+		providers, skipped, err := plugins.Providers(ctx, pricingReg, capabilityReg)
+		if err != nil {
+			return err
+		}
+		for _, name := range skipped {
+			report.Skipped(name, domain.ProviderSourcePlugin, "plugin is not configured")
+		}
+
+		for _, provider := range providers {
+			// Replayed providers are served from cassettes instead.
+			if replayCfg.Mode == replay.ModeReplay {
+				report.Skipped(provider.Name(), domain.ProviderSourcePlugin, "REPLAY_MODE is replay")
+				continue
+			}
+			if err := reg.Register(ctx, recordProvider(replayCfg, provider)); err != nil {
+				return fmt.Errorf("failed to register plugin provider %s: %w", provider.Name(), err)
+			}
+			report.Registered(provider.Name(), domain.ProviderSourcePlugin)
+		}
 		return nil
 	})
 }
 
 func provideDomainServices(container *dig.Container) {
-	mustProvide(container, domain.NewGatewayService)
+	mustProvide(container, domain.NewLoadTracker)
+	mustProvide(container, domain.NewProviderManager)
+	mustProvide(container, usage.NewStore)
+	mustProvide(container, pricing.NewCatalog)
+	mustProvide(container, newAlertMonitor)
+	mustProvide(container, newEventBus)
+	mustProvide(container, quota.NewStore)
+	mustProvide(container, newQuotaManager)
+	mustProvide(container, newTenants)
+	mustProvide(container, keys.NewStore)
+	mustProvide(container, newVirtualKeys)
+	mustProvide(container, scripting.NewLuaHook)
+	mustProvide(container, func(bus *events.Bus) domain.EventPublisher {
+		if bus == nil {
+			return nil // A nil interface, not a typed nil, disables event publishing
+		}
+		return bus
+	})
+	mustProvide(container, func(cfg *config.StreamConfig) *domain.StreamWatchdog {
+		return domain.NewStreamWatchdog(time.Duration(cfg.MaxDuration) * time.Second)
+	})
+	mustProvide(container, func(cfg *config.HealthCheckConfig) (*healthsync.Redis, error) {
+		if cfg.SyncRedisURL == "" {
+			return nil, nil //nolint:nilnil // Health stays local without Redis
+		}
+		return healthsync.NewRedis(cfg.SyncRedisURL, cfg.SyncChannel, time.Duration(cfg.SyncTimeout)*time.Second)
+	})
+	mustProvide(container, func(
+		reg domain.ProviderRegistry,
+		cfg *config.HealthCheckConfig,
+		redis *healthsync.Redis,
+	) *domain.HealthMonitor {
+		var peers domain.HealthPeers
+		if redis != nil {
+			peers = redis
+		}
+		return domain.NewHealthMonitor(
+			reg,
+			time.Duration(cfg.Interval)*time.Second,
+			time.Duration(cfg.Timeout)*time.Second,
+			cfg.FailureThreshold,
+			peers,
+		)
+	})
+	mustProvide(container, func(reg domain.ProviderRegistry, cfg *config.DiscoveryConfig) *domain.ModelDiscovery {
+		return domain.NewModelDiscovery(
+			reg,
+			time.Duration(cfg.Interval)*time.Second,
+			time.Duration(cfg.Timeout)*time.Second,
+		)
+	})
+	mustProvide(container, func(
+		reg domain.ProviderRegistry,
+		costCalculator domain.CostCalculator,
+		pricingReg domain.PricingRegistry,
+		capabilityReg domain.CapabilityRegistry,
+		load *domain.LoadTracker,
+		contextCfg *config.ContextConfig,
+		concurrencyCfg *config.ConcurrencyConfig,
+		transformCfg *config.TransformConfig,
+		promptCfg *config.PromptConfig,
+		shadowCfg *config.ShadowConfig,
+		experimentCfg *config.ExperimentConfig,
+		evaluationCfg *config.EvaluationConfig,
+		autoModelCfg *config.AutoModelConfig,
+		fallbackCfg *config.FallbackConfig,
+		moderationCfg *config.ModerationConfig,
+		attributionCfg *config.AttributionConfig,
+		policyCfg *config.PolicyConfig,
+		parameterCfg *config.ParameterLimitConfig,
+		coalescingCfg *config.CoalescingConfig,
+		validationCfg *config.ValidationConfig,
+		usageStore *usage.Store,
+		alertMonitor *domain.AlertMonitor,
+		quotaManager *domain.QuotaManager,
+		overrideManager *domain.Overrides,
+		tenants *domain.Tenants,
+		virtualKeys *domain.VirtualKeys,
+		streams *domain.StreamWatchdog,
+		eventPublisher domain.EventPublisher,
+		plugins *plugin.Registry,
+		hook *scripting.LuaHook,
+		providers builtInProviders,
+		replayCfg *config.ReplayConfig,
+	) (*domain.GatewayService, error) {
+		opts := []domain.GatewayOption{
+			domain.WithModelAliases(promptCfg.ModelAliases),
+			domain.WithSystemPrompts(promptCfg.KeySystemPrompts, promptCfg.ModelSystemPrompts),
+			domain.WithPromptCompression(promptCfg.CompressionKeys, promptCfg.CompressionModels),
+			domain.WithStrictRoleOrder(validationCfg.StrictRoleOrderProviders),
+			domain.WithStopEmulation(validationCfg.StopEmulationProviders),
+			domain.WithModeration(moderationCfg.Provider, moderationCfg.Preflight, moderationCfg.FailOpen),
+			domain.WithCostAttribution(attributionCfg.Tags),
+			domain.WithQuotas(quotaManager),
+			domain.WithOverrides(overrideManager),
+			domain.WithStreamWatchdog(streams),
+			domain.WithGuardrails(plugins.Guardrails()...),
+			domain.WithRouters(plugins.Routers()...),
+		}
+
+		if hook != nil {
+			opts = append(opts, domain.WithRequestHook(hook))
+		}
+
+		// Speech, like completions, never reaches upstream providers in replay mode.
+		if replayCfg.Mode != replay.ModeReplay {
+			opts = append(opts, domain.WithSynthesizers(providers.Speech...))
+		}
+
+		deprecations, err := domain.ParseDeprecations(promptCfg.ModelDeprecations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MODEL_DEPRECATIONS: %w", err)
+		}
+		opts = append(opts, domain.WithDeprecations(deprecations))
+
+		strategy := contextCfg.OverflowStrategy
+		if strategy == "" && contextCfg.TrimHistory {
+			strategy = domain.ContextStrategyTrimOldest
+		}
+		switch strategy {
+		case "":
+		case domain.ContextStrategyError, domain.ContextStrategyTrimOldest, domain.ContextStrategySummarize:
+			opts = append(opts, domain.WithContextWindow(
+				capabilityReg, contextCfg.ReservedOutputTokens, strategy, contextCfg.SummaryModel))
+		default:
+			return nil, fmt.Errorf("invalid CONTEXT_OVERFLOW_STRATEGY %q: must be error, trim_oldest, or summarize",
+				strategy)
+		}
+
+		if contextCfg.AdjustMaxTokens {
+			opts = append(opts, domain.WithMaxTokensAdjustment(capabilityReg))
+		}
+
+		tokenizers, err := domain.NewTokenizers(contextCfg.Tokenizers, plugins.Tokenizers())
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONTEXT_TOKENIZERS: %w", err)
+		}
+		opts = append(opts, domain.WithTokenizers(tokenizers))
+
+		if len(concurrencyCfg.Limits) > 0 || len(concurrencyCfg.AdaptiveProviders) > 0 {
+			opts = append(opts, domain.WithConcurrencyLimiter(domain.NewConcurrencyLimiter(
+				concurrencyCfg.Limits,
+				time.Duration(concurrencyCfg.QueueTimeoutMs)*time.Millisecond,
+				time.Duration(concurrencyCfg.BatchQueueTimeoutMs)*time.Millisecond,
+				concurrencyCfg.MaxQueue,
+				load,
+				domain.AdaptiveLimits{
+					Providers: concurrencyCfg.AdaptiveProviders,
+					Initial:   concurrencyCfg.AdaptiveInitial,
+					Min:       concurrencyCfg.AdaptiveMin,
+					Max:       concurrencyCfg.AdaptiveMax,
+					Tolerance: concurrencyCfg.AdaptiveLatencyTolerance,
+				},
+			)))
+		}
+
+		if shadowCfg.Provider != "" && shadowCfg.Percent > 0 {
+			opts = append(opts, domain.WithShadowTraffic(domain.NewShadowTraffic(
+				shadowCfg.Provider,
+				shadowCfg.Model,
+				shadowCfg.Percent,
+				time.Duration(shadowCfg.Timeout)*time.Second,
+				shadowCfg.MaxInFlight,
+			)))
+		}
+
+		if evaluationCfg.Percent > 0 {
+			evaluators := plugins.Evaluators()
+			if evaluationCfg.JudgeModel != "" {
+				evaluators = append(evaluators, domain.NewJudgeEvaluator(reg, evaluationCfg.JudgeModel))
+			}
+			opts = append(opts, domain.WithEvaluation(domain.NewEvaluation(
+				evaluators,
+				evaluationCfg.Percent,
+				time.Duration(evaluationCfg.TimeoutMs)*time.Millisecond,
+				evaluationCfg.MaxInFlight,
+			)))
+		}
+
+		if experimentCfg.Name != "" {
+			if experimentCfg.Model == "" || experimentCfg.VariantModel == "" {
+				return nil, errors.New("experiment requires EXPERIMENT_MODEL and EXPERIMENT_VARIANT_MODEL")
+			}
+			opts = append(opts, domain.WithExperiment(domain.Experiment{
+				Name:         experimentCfg.Name,
+				Model:        experimentCfg.Model,
+				VariantModel: experimentCfg.VariantModel,
+				Percent:      experimentCfg.Percent,
+				BucketBy:     experimentCfg.BucketBy,
+			}))
+		}
+
+		if len(autoModelCfg.Tiers) > 0 {
+			routing, err := domain.NewAutoRouting(
+				autoModelCfg.Tiers,
+				autoModelCfg.ClassifierModel,
+				time.Duration(autoModelCfg.ClassifierTimeoutMs)*time.Millisecond,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("invalid AUTO_MODEL_TIERS: %w", err)
+			}
+			opts = append(opts, domain.WithAutoRouting(routing))
+		}
+
+		if len(fallbackCfg.ContextLength) > 0 || len(fallbackCfg.ContentFilter) > 0 {
+			fallbacks, err := domain.NewFallbacks(fallbackCfg.ContextLength, fallbackCfg.ContentFilter)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, domain.WithFallbacks(fallbacks))
+		}
+
+		if eventPublisher != nil {
+			opts = append(opts, domain.WithEventPublisher(eventPublisher))
+		}
+
+		if usageStore != nil {
+			opts = append(opts, domain.WithUsageStore(usageStore))
+		}
+
+		if alertMonitor != nil {
+			opts = append(opts, domain.WithAlerts(alertMonitor))
+		}
+
+		if tenants != nil {
+			opts = append(opts, domain.WithTenants(tenants))
+		}
+
+		if virtualKeys != nil {
+			opts = append(opts, domain.WithVirtualKeys(virtualKeys))
+		}
+
+		if coalescingCfg.Enabled {
+			opts = append(opts, domain.WithRequestCoalescing())
+		}
+
+		if policyCfg.Path != "" {
+			data, err := os.ReadFile(policyCfg.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read key policies: %w", err)
+			}
+			policies, err := domain.ParseKeyPolicies(data)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, domain.WithKeyPolicies(policies))
+		}
+
+		if policyCfg.RoutingHints {
+			opts = append(opts, domain.WithRoutingHints(pricingReg))
+		}
+
+		if len(policyCfg.ProviderRegions) > 0 {
+			opts = append(opts, domain.WithProviderRegions(policyCfg.ProviderRegions))
+		}
+
+		if parameterCfg.Mode != domain.LimitModeClamp && parameterCfg.Mode != domain.LimitModeReject {
+			return nil, fmt.Errorf("invalid PARAMETER_LIMIT_MODE %q: must be clamp or reject", parameterCfg.Mode)
+		}
+		modelLimits, err := domain.NewModelLimits(parameterCfg.MaxTokens, parameterCfg.TemperatureRanges)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, domain.WithParameterLimits(modelLimits, parameterCfg.Mode))
+
+		transformers, err := domain.NewResponseTransformers(transformCfg.Pipeline, transformCfg.Disclaimer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response transformers: %w", err)
+		}
+		if len(transformers) > 0 {
+			opts = append(opts, domain.WithResponseTransformers(transformers...))
+		}
+
+		if len(moderationCfg.OutputKeywords) > 0 || moderationCfg.OutputPattern != "" || moderationCfg.OutputClassify {
+			filter, err := domain.NewOutputFilter(
+				moderationCfg.OutputKeywords,
+				moderationCfg.OutputPattern,
+				moderationCfg.OutputClassify,
+				moderationCfg.OutputAction,
+				moderationCfg.OutputWindow,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("invalid output filter: %w", err)
+			}
+			opts = append(opts, domain.WithOutputFilter(filter))
+		}
+
+		return domain.NewGatewayService(reg, costCalculator, opts...), nil
+	})
+	mustProvide(container, conversations.NewStore)
+	mustProvide(container, newConversations)
+}
+
+// newAlertMonitor builds the spend and error-rate alert monitor, seeding this
+// month's spend from the usage store. It returns nil when no alert is configured.
+func newAlertMonitor(cfg *config.AlertConfig, usageStore *usage.Store) (*domain.AlertMonitor, error) {
+	if len(cfg.KeyBudgets) == 0 && len(cfg.TeamBudgets) == 0 && cfg.ErrorRateThreshold <= 0 {
+		return nil, nil //nolint:nilnil // A nil monitor disables alerting
+	}
+
+	var sink domain.AlertSink
+	if cfg.WebhookURL != "" {
+		sink = alerts.NewWebhookSink(
+			cfg.WebhookURL,
+			time.Duration(cfg.WebhookTimeout)*time.Second,
+			cfg.WebhookMaxAttempts,
+			time.Duration(cfg.WebhookBackoffMs)*time.Millisecond,
+		)
+	}
+
+	monitor := domain.NewAlertMonitor(sink, domain.AlertOptions{
+		KeyBudgets:         cfg.KeyBudgets,
+		TeamBudgets:        cfg.TeamBudgets,
+		SpendThresholds:    cfg.SpendThresholds,
+		ErrorRateThreshold: cfg.ErrorRateThreshold,
+		ErrorRateWindow:    time.Duration(cfg.ErrorRateWindow) * time.Second,
+		ErrorRateMinimum:   cfg.ErrorRateMinRequests,
+		ErrorRateCooldown:  time.Duration(cfg.ErrorRateCooldown) * time.Second,
+	})
+
+	if usageStore != nil {
+		records, err := usageStore.Query(context.Background(), domain.UsageFilter{
+			From:      monthStart(time.Now()),
+			To:        time.Time{},
+			ClientKey: "",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed alert spend: %w", err)
+		}
+		monitor.Seed(records)
+	}
+
+	return monitor, nil
+}
+
+// newConversations builds the conversation service, keeping conversations in
+// the conversation store, or in memory without one.
+func newConversations(
+	gateway *domain.GatewayService,
+	store *conversations.Store,
+	cfg *config.ConversationConfig,
+) *domain.Conversations {
+	var conversationStore domain.ConversationStore = domain.NewInMemoryConversationStore(cfg.MaxInMemory)
+	if store != nil {
+		conversationStore = store
+	}
+	return domain.NewConversations(gateway, conversationStore)
+}
+
+// newOverrides builds the override manager, loading the overrides persisted in
+// the override store.
+func newOverrides(store *overrides.Store) (*domain.Overrides, error) {
+	var overrideStore domain.OverrideStore
+	if store != nil {
+		overrideStore = store
+	}
+
+	return domain.NewOverrides(context.Background(), overrideStore) //nolint:wrapcheck // Already described
+}
+
+// newQuotaManager builds the client key quota manager. Without a quota store,
+// which counts usage itself, this month's usage is seeded from the usage store.
+func newQuotaManager(store *quota.Store, usageStore *usage.Store) (*domain.QuotaManager, error) {
+	var quotaStore domain.QuotaStore
+	if store != nil {
+		quotaStore = store
+	}
+
+	manager, err := domain.NewQuotaManager(context.Background(), quotaStore)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already describes the failure
+	}
+
+	if store == nil && usageStore != nil {
+		records, err := usageStore.Query(context.Background(), domain.UsageFilter{
+			From:      monthStart(time.Now()),
+			To:        time.Time{},
+			ClientKey: "",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed quota usage: %w", err)
+		}
+		manager.Seed(records)
+	}
+
+	return manager, nil
+}
+
+// newTenants loads the tenant directory, creating provider instances for tenants
+// with their own credentials and seeding this month's tenant spend from the usage
+// store. It returns nil when no tenants file is configured.
+func newTenants(
+	cfg *config.TenantConfig,
+	openaiCfg *openai.Config,
+	usageStore *usage.Store,
+) (*domain.Tenants, error) {
+	if cfg.Path == "" {
+		return nil, nil //nolint:nilnil // A nil directory disables multi-tenancy
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	settings, err := domain.ParseTenants(data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already describes the failure
+	}
+
+	tenants := domain.NewTenants(settings)
+	for _, tenant := range settings {
+		for providerName, apiKey := range tenant.Credentials {
+			if providerName != openai.ProviderName {
+				return nil, fmt.Errorf("tenant %s: credentials for provider %s are not supported",
+					tenant.Name, providerName)
+			}
+
+			tenantCfg := *openaiCfg
+			tenantCfg.APIKey, tenantCfg.APIKeys = apiKey, nil
+			provider, err := openai.NewProvider(tenantCfg)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s: failed to create %s provider: %w", tenant.Name, providerName, err)
+			}
+			tenants.SetProvider(tenant.Name, provider)
+		}
+	}
+
+	if usageStore != nil {
+		records, err := usageStore.Query(context.Background(), domain.UsageFilter{
+			From:      monthStart(time.Now()),
+			To:        time.Time{},
+			ClientKey: "",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed tenant spend: %w", err)
+		}
+		tenants.Seed(records)
+	}
+
+	return tenants, nil
+}
+
+// newVirtualKeys builds the virtual key manager when key issuance is enabled; the
+// statically configured client key names are reserved.
+func newVirtualKeys(
+	cfg *config.VirtualKeyConfig,
+	authCfg *config.AuthConfig,
+	store *keys.Store,
+	quotas *domain.QuotaManager,
+) (*domain.VirtualKeys, error) {
+	if !cfg.Enabled {
+		return nil, nil //nolint:nilnil // A nil manager disables key issuance
+	}
+
+	var keyStore domain.VirtualKeyStore
+	if store != nil {
+		keyStore = store
+	}
+
+	reserved := make([]string, 0, len(authCfg.ClientKeys))
+	for name := range authCfg.ClientKeys {
+		reserved = append(reserved, name)
+	}
+
+	return domain.NewVirtualKeys(context.Background(), keyStore, quotas, reserved) //nolint:wrapcheck // Already described
+}
+
+// monthStart returns the start of the UTC month containing now.
+func monthStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// newEventBus builds the telemetry event bus for the configured sinks.
+// It returns nil when no sink is configured.
+func newEventBus(cfg *config.EventConfig) (*events.Bus, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil //nolint:nilnil // A nil bus disables event publishing
+	}
+
+	sinks := make([]events.Sink, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		switch strings.TrimSpace(name) {
+		case events.SinkWebhook:
+			if cfg.WebhookURL == "" {
+				return nil, errors.New("event sink webhook requires EVENT_WEBHOOK_URL")
+			}
+			sinks = append(sinks, events.NewWebhookSink(
+				cfg.WebhookURL,
+				time.Duration(cfg.WebhookTimeout)*time.Second,
+				cfg.WebhookMaxAttempts,
+				time.Duration(cfg.WebhookBackoffMs)*time.Millisecond,
+			))
+		case events.SinkNATS:
+			if cfg.NATSURL == "" {
+				return nil, errors.New("event sink nats requires EVENT_NATS_URL")
+			}
+			sink, err := events.NewNATSSink(cfg.NATSURL, cfg.NATSSubject, time.Duration(cfg.NATSTimeout)*time.Second)
+			if err != nil {
+				return nil, fmt.Errorf("invalid nats event sink: %w", err)
+			}
+			sinks = append(sinks, sink)
+		case events.SinkStdout:
+			sinks = append(sinks, events.NewWriterSink(os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", name)
+		}
+	}
+
+	return events.NewBus(cfg.QueueSize, sinks...), nil
+}
+
+// checkPricing fails startup when registered providers serve models without
+// pricing, which would otherwise be billed at zero cost. With a price catalog the
+// prices may arrive once it loads, so the gateway starts degraded instead.
+func checkPricing(container *dig.Container) {
+	mustInvoke(container, func(
+		reg domain.ProviderRegistry,
+		pricingReg domain.PricingRegistry,
+		priceCatalog *pricing.Catalog,
+		report *domain.StartupReport,
+	) error {
+		ctx := context.Background()
+
+		unpriced, err := domain.UnpricedModels(ctx, reg, pricingReg)
+		if err != nil || len(unpriced) == 0 {
+			return err
+		}
+
+		if priceCatalog != nil {
+			report.Degrade("models have no pricing until the price catalog provides it: " +
+				strings.Join(unpriced, ", "))
+			return nil
+		}
+		return fmt.Errorf("models have no pricing and would be billed at zero cost: %s; "+
+			"declare their prices or set PRICING_CATALOG_SOURCE", strings.Join(unpriced, ", "))
+	})
 }
 
 func provideHTTPLayer(container *dig.Container) {
+	mustProvide(container, middleware.NewIdempotencyStore)
+	mustProvide(container, middleware.NewIPAllowList)
 	mustProvide(container, httpserver.NewHandler)
+	mustProvide(container, httpserver.NewAdminHandler)
 	mustProvide(container, middleware.BuildMiddlewareChain)
 	mustProvide(container, httpserver.NewServer)
 }
 
+func startBackgroundJobs(ctx context.Context, container *dig.Container) {
+	mustInvoke(container, func(
+		idempotencyStore *middleware.IdempotencyStore,
+		healthMonitor *domain.HealthMonitor,
+		modelDiscovery *domain.ModelDiscovery,
+		usageStore *usage.Store,
+		priceCatalog *pricing.Catalog,
+		alertMonitor *domain.AlertMonitor,
+		eventBus *events.Bus,
+	) {
+		if idempotencyStore != nil {
+			go idempotencyStore.RunCompaction(ctx)
+		}
+		if usageStore != nil {
+			go usageStore.RunCompaction(ctx)
+		}
+		if priceCatalog != nil {
+			go priceCatalog.Run(ctx)
+		}
+		if alertMonitor != nil {
+			go alertMonitor.Run(ctx)
+		}
+		if eventBus != nil {
+			go eventBus.Run(ctx)
+		}
+		go healthMonitor.Run(ctx)
+		go modelDiscovery.Run(ctx)
+	})
+}
+
+func closeStores(container *dig.Container) {
+	mustInvoke(container, func(
+		usageStore *usage.Store,
+		overrideStore *overrides.Store,
+		conversationStore *conversations.Store,
+		quotaStore *quota.Store,
+		healthPeers *healthsync.Redis,
+	) {
+		logger := observability.FromContext(context.Background())
+		if usageStore != nil {
+			if err := usageStore.Close(); err != nil {
+				logger.Error("failed to close usage store", observability.Error(err))
+			}
+		}
+		if overrideStore != nil {
+			if err := overrideStore.Close(); err != nil {
+				logger.Error("failed to close override store", observability.Error(err))
+			}
+		}
+		if conversationStore != nil {
+			if err := conversationStore.Close(); err != nil {
+				logger.Error("failed to close conversation store", observability.Error(err))
+			}
+		}
+		if quotaStore != nil {
+			if err := quotaStore.Close(); err != nil {
+				logger.Error("failed to close quota store", observability.Error(err))
+			}
+		}
+		if healthPeers != nil {
+			if err := healthPeers.Close(); err != nil {
+				logger.Error("failed to close health sync connection", observability.Error(err))
+			}
+		}
+	})
+}
+
 func mustProvide(container *dig.Container, constructor any) {
 	if err := container.Provide(constructor); err != nil {
 		ctx := context.Background()
 		logger := observability.FromContext(ctx)
-		logger.Fatal("failed to provide dependency", observability.Error(err))
+		logger.Fatal("failed to provide dependency",
+			observability.Error(dig.RootCause(err)), observability.String("detail", err.Error()))
 	}
 }
 
@@ -195,6 +1098,7 @@ func mustInvoke(container *dig.Container, function any) {
 	if err := container.Invoke(function); err != nil {
 		ctx := context.Background()
 		logger := observability.FromContext(ctx)
-		logger.Fatal("failed to invoke function", observability.Error(err))
+		logger.Fatal("failed to invoke function",
+			observability.Error(dig.RootCause(err)), observability.String("detail", err.Error()))
 	}
 }
@@ -0,0 +1,65 @@
+// Command mockllm serves a scripted OpenAI-compatible API for end-to-end tests.
+//
+// Point the gateway at it with OPENAI_BASE_URL=http://localhost:9090/v1. Replies
+// come from a JSON script of rules (see mockllm.Rule); unmatched requests get
+// their last message echoed back.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/mockllm"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	script := flag.String("script", "", "JSON file of scripted reply rules")
+	flag.Parse()
+
+	logger := observability.FromContext(context.Background())
+
+	var rules []mockllm.Rule
+	if *script != "" {
+		var err error
+		if rules, err = mockllm.LoadRules(*script); err != nil {
+			logger.Fatal("failed to load script", observability.Error(err))
+		}
+	}
+
+	srv := &http.Server{ //nolint:exhaustruct // Only the listener settings matter
+		Addr:              *addr,
+		Handler:           mockllm.NewServer(rules),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	logger.Info("mock LLM server listening",
+		observability.String("addr", *addr),
+		observability.Int("rules", len(rules)),
+	)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("mock LLM server failed", observability.Error(err))
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import "github.com/davidbz/calcifer/pkg/plugin"
+
+// registerPlugins compiles in proprietary plugins. Each plugin package exposes a
+// function adding its providers, middleware, guardrails, routers, evaluators, and tokenizers, called here:
+//
+//	acme.Register(plugins)
+//
+// Keep proprietary plugins in their own directories; this is the only file of
+// the gateway that changes when one is added.
+func registerPlugins(plugins *plugin.Registry) {
+	_ = plugins // No plugins are compiled in by default
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.uber.org/dig"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/pkg/plugin"
+)
+
+// settingOff reports a disabled feature in the startup report.
+const settingOff = "off"
+
+// reportSettings records the effective routing and caching settings in the
+// startup report, as derived from configuration.
+func reportSettings(container *dig.Container) {
+	mustInvoke(container, func(
+		report *domain.StartupReport,
+		plugins *plugin.Registry,
+		cfg *config.Config,
+	) {
+		routing := "model"
+		if routers := len(plugins.Routers()); routers > 0 {
+			routing = fmt.Sprintf("%d plugin routers, then model", routers)
+		}
+		report.Set("routing", routing)
+		report.Set("model_aliases", strconv.Itoa(len(cfg.Prompts.ModelAliases)))
+		report.Set("model_deprecations", strconv.Itoa(len(cfg.Prompts.ModelDeprecations)))
+		report.Set("concurrency_limits", strconv.Itoa(len(cfg.Concurrency.Limits)))
+
+		healthSync := "local"
+		if cfg.HealthCheck.SyncRedisURL != "" {
+			healthSync = "redis channel " + cfg.HealthCheck.SyncChannel // the URL may hold credentials
+		}
+		report.Set("health_sync", healthSync)
+
+		experiment := settingOff
+		if cfg.Experiment.Name != "" {
+			experiment = fmt.Sprintf("%s: %g%% to %s", cfg.Experiment.Name, cfg.Experiment.Percent,
+				cfg.Experiment.VariantModel)
+		}
+		report.Set("experiment", experiment)
+
+		shadow := settingOff
+		if cfg.Shadow.Provider != "" && cfg.Shadow.Percent > 0 {
+			shadow = fmt.Sprintf("%g%% to %s", cfg.Shadow.Percent, cfg.Shadow.Provider)
+		}
+		report.Set("shadow_traffic", shadow)
+
+		overflow := cfg.Context.OverflowStrategy
+		if overflow == "" && cfg.Context.TrimHistory {
+			overflow = domain.ContextStrategyTrimOldest
+		}
+		report.Set("context_overflow", orOff(overflow))
+
+		idempotency := settingOff
+		if cfg.Idempotency.Enabled {
+			idempotency = fmt.Sprintf("ttl %ds", cfg.Idempotency.TTL)
+		}
+		report.Set("idempotency_cache", idempotency)
+		report.Set("request_coalescing", strconv.FormatBool(cfg.Coalescing.Enabled))
+
+		usageStore := settingOff
+		if cfg.Usage.Enabled {
+			usageStore = "memory"
+			if cfg.Usage.Path != "" {
+				usageStore = cfg.Usage.Path
+			}
+		}
+		report.Set("usage_store", usageStore)
+		overrideStore := "memory"
+		if cfg.Overrides.Path != "" {
+			overrideStore = cfg.Overrides.Path
+		}
+		report.Set("override_store", overrideStore)
+		report.Set("price_catalog", orOff(cfg.Pricing.Source))
+		report.Set("policy_script", orOff(cfg.Scripting.PolicyScript))
+	})
+}
+
+// reportDegraded marks the startup degraded when no registered provider reaches
+// an upstream API, so the gateway serves only test models.
+func reportDegraded(container *dig.Container) {
+	mustInvoke(container, func(report *domain.StartupReport) {
+		for _, provider := range report.Status().Providers {
+			if provider.Registered && provider.Source != domain.ProviderSourceTest {
+				return
+			}
+		}
+		report.Degrade("no upstream provider is registered, so only test models are served")
+	})
+}
+
+// orOff returns value, or "off" when it is empty.
+func orOff(value string) string {
+	if value == "" {
+		return settingOff
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+// Package alerts delivers gateway alerts to external systems.
+package alerts
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/webhook"
+)
+
+// WebhookSink implements domain.AlertSink by POSTing alerts as JSON, retrying
+// network errors, 429, and 5xx responses with exponential backoff.
+type WebhookSink struct {
+	client *webhook.Client
+}
+
+// NewWebhookSink creates a webhook sink. maxAttempts below 1 is treated as 1.
+func NewWebhookSink(url string, timeout time.Duration, maxAttempts int, backoff time.Duration) *WebhookSink {
+	return &WebhookSink{client: webhook.NewClient(url, timeout, maxAttempts, backoff)}
+}
+
+// Send delivers an alert, retrying transient failures until attempts run out or ctx is done.
+func (s *WebhookSink) Send(ctx context.Context, alert domain.Alert) error {
+	return s.client.Post(ctx, alert) //nolint:wrapcheck // Errors already describe the delivery
+}
@@ -0,0 +1,169 @@
+// Package billing exports gateway usage and cost in formats finance tooling
+// ingests: CSV and the OpenCost custom cost JSON schema.
+package billing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// Export formats.
+const (
+	FormatCSV      = "csv"
+	FormatOpenCost = "opencost"
+)
+
+const (
+	// openCostSource and openCostDomain identify the gateway as the cost source.
+	openCostSource = "calcifer"
+	openCostDomain = "calcifer"
+
+	// openCostVersion is the version of the OpenCost custom cost schema produced.
+	openCostVersion = "v1"
+
+	// openCostCategory is the FOCUS charge category of metered usage.
+	openCostCategory = "Usage"
+
+	// openCostUsageUnit is the unit of a cost's usage quantity.
+	openCostUsageUnit = "tokens"
+)
+
+// Period resolves the export period from the requested bounds: to defaults to
+// now, and from to the start of the calendar month (UTC) containing to.
+func Period(from, to, now time.Time) (time.Time, time.Time) {
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		year, month, _ := to.UTC().Date()
+		from = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return from, to
+}
+
+// WriteCSV writes report as CSV with a header row and one row per group.
+func WriteCSV(w io.Writer, report domain.BillingReport) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{
+		"period_start", "period_end", report.GroupBy, "requests", "prompt_tokens", "completion_tokens",
+		"total_tokens", "characters", "images", "cost", "currency", "provider_cost_usd",
+	}}
+
+	start, end := report.From.UTC().Format(time.RFC3339), report.To.UTC().Format(time.RFC3339)
+	for _, group := range report.Groups {
+		rows = append(rows, []string{
+			start,
+			end,
+			group.Group,
+			strconv.Itoa(group.Requests),
+			strconv.Itoa(group.PromptTokens),
+			strconv.Itoa(group.CompletionTokens),
+			strconv.Itoa(group.TotalTokens),
+			strconv.Itoa(group.Characters),
+			strconv.Itoa(group.Images),
+			formatCost(group.Cost),
+			group.Currency,
+			formatCost(group.ProviderCost),
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write billing CSV: %w", err)
+	}
+	return nil
+}
+
+// OpenCostResponse is a custom cost response of the OpenCost plugin schema,
+// which OpenCost ingests as costs outside the cluster.
+type OpenCostResponse struct {
+	Metadata   map[string]string `json:"metadata"`
+	CostSource string            `json:"cost_source"`
+	Domain     string            `json:"domain"`
+	Version    string            `json:"version"`
+	Currency   string            `json:"currency"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Errors     []string          `json:"errors"`
+	Costs      []OpenCostCost    `json:"costs"`
+}
+
+// OpenCostCost is one custom cost: the usage of one group over the period.
+type OpenCostCost struct {
+	Metadata       map[string]string `json:"metadata"`
+	ChargeCategory string            `json:"charge_category"`
+	Description    string            `json:"description"`
+	ResourceName   string            `json:"resource_name"`
+	ResourceType   string            `json:"resource_type"`
+	ID             string            `json:"id"`
+	ProviderID     string            `json:"provider_id"`
+	BilledCost     float64           `json:"billed_cost"`
+	ListCost       float64           `json:"list_cost"`
+	ListUnitPrice  float64           `json:"list_unit_price"`
+	UsageQuantity  float64           `json:"usage_quantity"`
+	UsageUnit      string            `json:"usage_unit"`
+	Labels         map[string]string `json:"labels"`
+}
+
+// OpenCost converts report to an OpenCost custom cost response. Each group
+// becomes a cost whose resource type is the grouping; provider costs in USD are
+// kept in the cost metadata. A response has a single currency, so groups charged
+// in another than the report's are left out and listed in the response errors.
+func OpenCost(report domain.BillingReport) OpenCostResponse {
+	costs := make([]OpenCostCost, 0, len(report.Groups))
+	errs := []string{}
+	for _, group := range report.Groups {
+		if group.Currency != report.Currency {
+			errs = append(errs, fmt.Sprintf("%s %s omitted: charged in %s, not %s",
+				report.GroupBy, group.Group, group.Currency, report.Currency))
+			continue
+		}
+
+		unitPrice := 0.0
+		if group.TotalTokens > 0 {
+			unitPrice = group.Cost / float64(group.TotalTokens)
+		}
+
+		costs = append(costs, OpenCostCost{
+			Metadata: map[string]string{
+				"requests":          strconv.Itoa(group.Requests),
+				"prompt_tokens":     strconv.Itoa(group.PromptTokens),
+				"completion_tokens": strconv.Itoa(group.CompletionTokens),
+				"provider_cost_usd": formatCost(group.ProviderCost),
+			},
+			ChargeCategory: openCostCategory,
+			Description:    fmt.Sprintf("%d requests to %s %s", group.Requests, report.GroupBy, group.Group),
+			ResourceName:   group.Group,
+			ResourceType:   report.GroupBy,
+			ID:             report.GroupBy + "/" + group.Group,
+			ProviderID:     group.Group,
+			BilledCost:     group.Cost,
+			ListCost:       group.Cost,
+			ListUnitPrice:  unitPrice,
+			UsageQuantity:  float64(group.TotalTokens),
+			UsageUnit:      openCostUsageUnit,
+			Labels:         map[string]string{report.GroupBy: group.Group},
+		})
+	}
+
+	return OpenCostResponse{
+		Metadata:   map[string]string{"group_by": report.GroupBy},
+		CostSource: openCostSource,
+		Domain:     openCostDomain,
+		Version:    openCostVersion,
+		Currency:   report.Currency,
+		Start:      report.From.UTC(),
+		End:        report.To.UTC(),
+		Errors:     errs,
+		Costs:      costs,
+	}
+}
+
+// formatCost formats a cost without exponent notation or rounding.
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
@@ -0,0 +1,90 @@
+package billing_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/billing"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func newReport() domain.BillingReport {
+	return domain.BillingReport{
+		From:     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		GroupBy:  domain.GroupByModel,
+		Currency: "EUR",
+		Groups: []domain.UsageAggregate{{
+			Group:            "gpt-4",
+			Currency:         "EUR",
+			Requests:         2,
+			PromptTokens:     1500,
+			CompletionTokens: 500,
+			TotalTokens:      2000,
+			Cost:             0.108,
+			ProviderCost:     0.1,
+		}},
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2026, 3, 18, 9, 30, 0, 0, time.UTC)
+
+	t.Run("should default to the current month until now", func(t *testing.T) {
+		from, to := billing.Period(time.Time{}, time.Time{}, now)
+		require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), from)
+		require.Equal(t, now, to)
+	})
+
+	t.Run("should start at the month of an explicit end", func(t *testing.T) {
+		to := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+		from, end := billing.Period(time.Time{}, to, now)
+		require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), from)
+		require.Equal(t, to, end)
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Run("should write a header and a row per group", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, billing.WriteCSV(&out, newReport()))
+
+		require.Equal(t, "period_start,period_end,model,requests,prompt_tokens,completion_tokens,"+
+			"total_tokens,characters,images,cost,currency,provider_cost_usd\n"+
+			"2026-03-01T00:00:00Z,2026-04-01T00:00:00Z,gpt-4,2,1500,500,2000,0,0,0.108,EUR,0.1\n", out.String())
+	})
+}
+
+func TestOpenCost(t *testing.T) {
+	t.Run("should convert each group to a custom cost", func(t *testing.T) {
+		response := billing.OpenCost(newReport())
+
+		require.Equal(t, "EUR", response.Currency)
+		require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), response.Start)
+		require.Len(t, response.Costs, 1)
+
+		cost := response.Costs[0]
+		require.Equal(t, "gpt-4", cost.ResourceName)
+		require.Equal(t, domain.GroupByModel, cost.ResourceType)
+		require.InDelta(t, 0.108, cost.BilledCost, 1e-12)
+		require.InDelta(t, 2000, cost.UsageQuantity, 1e-12)
+		require.InDelta(t, 0.000054, cost.ListUnitPrice, 1e-12)
+		require.Equal(t, "0.1", cost.Metadata["provider_cost_usd"])
+		require.Empty(t, response.Errors)
+	})
+
+	t.Run("should leave out groups charged in another currency", func(t *testing.T) {
+		report := newReport()
+		usd := report.Groups[0]
+		usd.Currency = domain.DefaultCurrency
+		report.Groups = append(report.Groups, usd)
+
+		response := billing.OpenCost(report)
+
+		require.Len(t, response.Costs, 1)
+		require.Equal(t, []string{"model gpt-4 omitted: charged in USD, not EUR"}, response.Errors)
+	})
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// Client calls the HTTP API of a running gateway.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	adminToken string
+	http       *http.Client
+}
+
+// NewClient creates a client for the gateway at baseURL. apiKey authenticates
+// client endpoints and adminToken the admin API; either may be empty.
+func NewClient(baseURL, apiKey, adminToken string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		adminToken: adminToken,
+		http:       httpClient,
+	}
+}
+
+// Complete sends a non-streaming completion request.
+func (c *Client) Complete(ctx context.Context, req *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+	var resp domain.CompletionResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/completions", req, c.apiKey, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Stream sends a streaming completion request, calling onDelta with each piece of
+// content as it arrives. It returns the metadata of the first chunk.
+func (c *Client) Stream(
+	ctx context.Context,
+	req *domain.CompletionRequest,
+	onDelta func(delta string),
+) (map[string]string, error) {
+	streamed := *req
+	streamed.Stream = true
+
+	resp, err := c.send(ctx, http.MethodPost, "/v1/completions", &streamed, c.apiKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var metadata map[string]string
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			if event == "error" {
+				return metadata, streamError(resp.StatusCode, data)
+			}
+
+			var chunk struct {
+				Delta    string            `json:"delta"`
+				Done     bool              `json:"done"`
+				Metadata map[string]string `json:"metadata"`
+			}
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return metadata, fmt.Errorf("failed to decode stream chunk: %w", err)
+			}
+			if metadata == nil {
+				metadata = chunk.Metadata
+			}
+			if chunk.Delta != "" {
+				onDelta(chunk.Delta)
+			}
+			if chunk.Done {
+				return metadata, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return metadata, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return metadata, errors.New("stream ended before completion")
+}
+
+// Models lists the models the gateway serves.
+func (c *Client) Models(ctx context.Context) ([]domain.ModelInfo, error) {
+	var resp struct {
+		Data []domain.ModelInfo `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/models", nil, c.apiKey, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// Usage reports usage grouped by groupBy. from and to are RFC 3339 timestamps
+// and may be empty. Without an API key it sends the admin token, since the
+// gateway then reports the usage of every client.
+func (c *Client) Usage(ctx context.Context, groupBy, from, to string) ([]domain.UsageAggregate, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"group_by": groupBy, "from": from, "to": to} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	var resp struct {
+		Data []domain.UsageAggregate `json:"data"`
+	}
+	token := c.apiKey
+	if token == "" {
+		token = c.adminToken
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/usage?"+query.Encode(), nil, token, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// Dashboard fetches the admin dashboard report for window, e.g. "24h".
+func (c *Client) Dashboard(ctx context.Context, window string) (*Dashboard, error) {
+	path := "/admin/dashboard"
+	if window != "" {
+		path += "?" + url.Values{"window": {window}}.Encode()
+	}
+
+	var resp Dashboard
+	if err := c.do(ctx, http.MethodGet, path, nil, c.adminToken, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExportBilling writes the admin billing export, in format "csv" or "opencost",
+// to w. from and to are RFC 3339 timestamps and may be empty.
+func (c *Client) ExportBilling(ctx context.Context, w io.Writer, format, groupBy, from, to string) error {
+	query := url.Values{}
+	for name, value := range map[string]string{"format": format, "group_by": groupBy, "from": from, "to": to} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	resp, err := c.send(ctx, http.MethodGet, "/admin/billing/export?"+query.Encode(), nil, c.adminToken)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read billing export: %w", err)
+	}
+	return nil
+}
+
+// do sends a request and decodes its JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, body any, token string, out any) error {
+	resp, err := c.send(ctx, method, path, body, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request authenticated with token, turning non-2xx responses into
+// an APIError.
+func (c *Client) send(ctx context.Context, method, path string, body any, token string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach gateway: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, apiError(resp.StatusCode, data)
+	}
+	return resp, nil
+}
+
+// APIError is a non-2xx gateway response. Type is empty for responses without the
+// JSON error envelope, such as admin endpoint errors.
+type APIError struct {
+	Status  int
+	Type    string
+	Message string
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("gateway returned %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("gateway returned %d %s: %s", e.Status, e.Type, e.Message)
+}
+
+// Dashboard is the admin dashboard report.
+type Dashboard struct {
+	Window    string               `json:"window"`
+	Usage     *domain.UsageSummary `json:"usage"` // nil when usage recording is disabled
+	Tenants   []domain.TenantLoad  `json:"tenants"`
+	Providers []DashboardProvider  `json:"providers"`
+}
+
+// DashboardProvider is the state of one provider on the admin dashboard.
+type DashboardProvider struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Healthy bool   `json:"healthy"`
+}
+
+// apiError parses an error response, falling back to its raw body.
+func apiError(status int, data []byte) error {
+	var envelope struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Error.Message != "" {
+		return &APIError{Status: status, Type: envelope.Error.Type, Message: envelope.Error.Message}
+	}
+	return &APIError{Status: status, Type: "", Message: strings.TrimSpace(string(data))}
+}
+
+// streamError parses the error event that ends a failed stream.
+func streamError(status int, data []byte) error {
+	err := apiError(status, data)
+
+	var event struct {
+		Partial bool `json:"partial"`
+	}
+	if json.Unmarshal(data, &event) == nil && event.Partial {
+		return fmt.Errorf("stream failed after partial output: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package cli_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/cli"
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// serve starts a gateway stand-in replying to every request with status and body.
+func serve(t *testing.T, status int, body string) (*httptest.Server, *http.Request) {
+	t.Helper()
+
+	var received http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = *r.Clone(r.Context())
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func TestClient_Stream(t *testing.T) {
+	req := &domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "hi"}}}
+
+	t.Run("should collect deltas until the done chunk", func(t *testing.T) {
+		server, _ := serve(t, http.StatusOK,
+			"data: {\"delta\":\"Hel\",\"done\":false,\"metadata\":{\"route\":\"a\"}}\n\n"+
+				"data: {\"delta\":\"lo\",\"done\":false}\n\n"+
+				"data: {\"delta\":\"\",\"done\":true}\n\n")
+		client := cli.NewClient(server.URL, "", "", http.DefaultClient)
+
+		var content strings.Builder
+		metadata, err := client.Stream(t.Context(), req, func(delta string) {
+			content.WriteString(delta)
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, "Hello", content.String())
+		require.Equal(t, map[string]string{"route": "a"}, metadata)
+	})
+
+	t.Run("should report an error event as a partial failure", func(t *testing.T) {
+		server, _ := serve(t, http.StatusOK,
+			"data: {\"delta\":\"Hel\",\"done\":false}\n\n"+
+				"event: error\ndata: {\"error\":{\"type\":\"server_error\",\"message\":\"boom\"},\"partial\":true}\n\n")
+		client := cli.NewClient(server.URL, "", "", http.DefaultClient)
+
+		_, err := client.Stream(t.Context(), req, func(string) {})
+
+		var apiErr *cli.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, "boom", apiErr.Message)
+		require.ErrorContains(t, err, "partial output")
+	})
+
+	t.Run("should fail a stream that ends without a done chunk", func(t *testing.T) {
+		server, _ := serve(t, http.StatusOK, "data: {\"delta\":\"Hel\",\"done\":false}\n\n")
+		client := cli.NewClient(server.URL, "", "", http.DefaultClient)
+
+		_, err := client.Stream(t.Context(), req, func(string) {})
+
+		require.ErrorContains(t, err, "stream ended before completion")
+	})
+}
+
+func TestClient_Errors(t *testing.T) {
+	t.Run("should parse the error envelope", func(t *testing.T) {
+		server, received := serve(t, http.StatusUnauthorized,
+			`{"error":{"type":"invalid_request","message":"missing API key"}}`)
+		client := cli.NewClient(server.URL, "secret", "", http.DefaultClient)
+
+		_, err := client.Models(t.Context())
+
+		var apiErr *cli.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusUnauthorized, apiErr.Status)
+		require.Equal(t, "invalid_request", apiErr.Type)
+		require.Equal(t, "Bearer secret", received.Header.Get("Authorization"))
+	})
+
+	t.Run("should keep a plain-text admin error", func(t *testing.T) {
+		server, received := serve(t, http.StatusUnauthorized, "unauthorized\n")
+		client := cli.NewClient(server.URL, "secret", "admin", http.DefaultClient)
+
+		_, err := client.Dashboard(t.Context(), "1h")
+
+		var apiErr *cli.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, "unauthorized", apiErr.Message)
+		require.Equal(t, "Bearer admin", received.Header.Get("Authorization"))
+		require.Equal(t, "1h", received.URL.Query().Get("window"))
+	})
+}
+
+func TestClient_ExportBilling(t *testing.T) {
+	t.Run("should copy the export with the admin token", func(t *testing.T) {
+		server, received := serve(t, http.StatusOK, "period_start,period_end,model\n")
+		client := cli.NewClient(server.URL, "secret", "admin", http.DefaultClient)
+
+		var out bytes.Buffer
+		err := client.ExportBilling(t.Context(), &out, "csv", "model", "2026-03-01T00:00:00Z", "")
+
+		require.NoError(t, err)
+		require.Equal(t, "period_start,period_end,model\n", out.String())
+		require.Equal(t, "/admin/billing/export", received.URL.Path)
+		require.Equal(t, "2026-03-01T00:00:00Z", received.URL.Query().Get("from"))
+		require.False(t, received.URL.Query().Has("to"))
+		require.Equal(t, "Bearer admin", received.Header.Get("Authorization"))
+	})
+}
+
+func TestRootCommand(t *testing.T) {
+	t.Run("should read the chat message from stdin", func(t *testing.T) {
+		server, _ := serve(t, http.StatusOK, `{"model":"gpt-4","provider":"openai","content":"Hi there"}`)
+
+		var out, errOut bytes.Buffer
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"--url", server.URL, "chat", "--no-stream", "-m", "gpt-4"})
+		cmd.SetIn(strings.NewReader("hello\n"))
+		cmd.SetOut(&out)
+		cmd.SetErr(&errOut)
+
+		require.NoError(t, cmd.ExecuteContext(t.Context()))
+		require.Equal(t, "Hi there\n", out.String())
+		require.Contains(t, errOut.String(), "gpt-4 via openai")
+	})
+
+	t.Run("should list every configuration problem", func(t *testing.T) {
+		t.Setenv("SHADOW_PERCENT", "150")
+
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"validate", "--env-file", ""})
+
+		err := cmd.ExecuteContext(t.Context())
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Len(t, validationErr.Problems, 2)
+	})
+
+	t.Run("should require a model", func(t *testing.T) {
+		t.Setenv("CALCIFER_MODEL", "")
+
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"chat", "hello"})
+
+		require.ErrorContains(t, cmd.ExecuteContext(t.Context()), "model is required")
+	})
+}
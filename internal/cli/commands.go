@@ -0,0 +1,343 @@
+// Package cli implements the calcifer command-line client, which talks to a
+// running gateway to smoke-test a deployment and validates gateway configuration.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+const (
+	defaultURL     = "http://localhost:8080"
+	defaultTimeout = 2 * time.Minute
+)
+
+// options holds the flags shared by every command.
+type options struct {
+	url        string
+	apiKey     string
+	adminToken string
+	timeout    time.Duration
+}
+
+// NewRootCommand creates the calcifer command. Flag defaults come from the
+// CALCIFER_URL, CALCIFER_API_KEY, and CALCIFER_ADMIN_TOKEN environment variables.
+func NewRootCommand() *cobra.Command {
+	opts := &options{url: "", apiKey: "", adminToken: "", timeout: 0}
+
+	root := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:           "calcifer",
+		Short:         "Command-line client for a running Calcifer gateway",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.url, "url", envOr("CALCIFER_URL", defaultURL), "gateway base URL")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("CALCIFER_API_KEY"), "client API key")
+	flags.StringVar(&opts.adminToken, "admin-token", os.Getenv("CALCIFER_ADMIN_TOKEN"), "admin API token")
+	flags.DurationVar(&opts.timeout, "timeout", defaultTimeout, "request timeout")
+
+	root.AddCommand(
+		newChatCommand(opts),
+		newModelsCommand(opts),
+		newUsageCommand(opts),
+		newCacheCommand(opts),
+		newBillingCommand(opts),
+		newValidateCommand(),
+	)
+	return root
+}
+
+// client creates a gateway client from the shared flags.
+func (o *options) client() *Client {
+	return NewClient(o.url, o.apiKey, o.adminToken, &http.Client{ //nolint:exhaustruct // Only the timeout is set
+		Timeout: o.timeout,
+	})
+}
+
+// newChatCommand creates the chat command, which sends one message and prints the reply.
+func newChatCommand(opts *options) *cobra.Command {
+	var (
+		model       string
+		system      string
+		maxTokens   int
+		temperature float64
+		noStream    bool
+	)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "chat [message]",
+		Short: "Send a message and print the reply; reads the message from stdin when omitted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content := strings.Join(args, " ")
+			if content == "" {
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("failed to read message: %w", err)
+				}
+				content = strings.TrimSpace(string(data))
+			}
+			if content == "" {
+				return errors.New("message is required")
+			}
+			if model == "" {
+				return errors.New("model is required: set --model or CALCIFER_MODEL")
+			}
+
+			messages := make([]domain.Message, 0, 2)
+			if system != "" {
+				messages = append(messages,
+					domain.Message{Role: "system", Content: system, ToolCallID: "", ToolCalls: nil})
+			}
+			messages = append(messages, domain.Message{Role: "user", Content: content, ToolCallID: "", ToolCalls: nil})
+
+			req := &domain.CompletionRequest{ //nolint:exhaustruct // Optional sampling parameters stay unset
+				Model:       model,
+				Messages:    messages,
+				MaxTokens:   maxTokens,
+				Temperature: temperature,
+			}
+
+			if noStream {
+				return complete(cmd, opts.client(), req)
+			}
+			return stream(cmd, opts.client(), req)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&model, "model", "m", os.Getenv("CALCIFER_MODEL"), "model or alias to request")
+	flags.StringVarP(&system, "system", "s", "", "system prompt")
+	flags.IntVar(&maxTokens, "max-tokens", 0, "maximum completion tokens, 0 for the provider default")
+	flags.Float64Var(&temperature, "temperature", 0, "sampling temperature")
+	flags.BoolVar(&noStream, "no-stream", false, "wait for the full reply and report its usage")
+	return cmd
+}
+
+// newModelsCommand creates the models command, which lists the served models.
+func newModelsCommand(opts *options) *cobra.Command {
+	return &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "models",
+		Short: "List the models and aliases the gateway serves",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			models, err := opts.client().Models(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			table := newTable(cmd.OutOrStdout(), "MODEL", "PROVIDERS")
+			for _, model := range models {
+				served := strings.Join(model.Providers, ",")
+				if model.AliasOf != "" {
+					served = "alias of " + model.AliasOf
+				}
+				table.row(model.ID, served)
+			}
+			table.flush()
+			return nil
+		},
+	}
+}
+
+// newUsageCommand creates the usage command, which prints aggregated usage.
+func newUsageCommand(opts *options) *cobra.Command {
+	var groupBy, from, to string
+
+	cmd := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "usage",
+		Short: "Report requests, tokens, and cost recorded by the gateway",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			report, err := opts.client().Usage(cmd.Context(), groupBy, from, to)
+			if err != nil {
+				return err
+			}
+
+			table := newTable(cmd.OutOrStdout(),
+				strings.ToUpper(groupBy), "REQUESTS", "PROMPT", "COMPLETION", "COST", "CURRENCY")
+			for _, row := range report {
+				table.row(row.Group, row.Requests, row.PromptTokens, row.CompletionTokens,
+					fmt.Sprintf("%.6f", row.Cost), row.Currency)
+			}
+			table.flush()
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&groupBy, "group-by", domain.GroupByModel, "model, provider, tenant, key, day, or tag:<name>")
+	flags.StringVar(&from, "from", "", "start of the period as an RFC 3339 timestamp")
+	flags.StringVar(&to, "to", "", "end of the period as an RFC 3339 timestamp")
+	return cmd
+}
+
+// newCacheCommand creates the cache command group.
+func newCacheCommand(opts *options) *cobra.Command {
+	var window string
+
+	stats := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "stats",
+		Short: "Report the share of prompt tokens served from provider prompt caches (admin)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dashboard, err := opts.client().Dashboard(cmd.Context(), window)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if dashboard.Usage == nil {
+				fmt.Fprintln(out, "usage recording is disabled on the gateway")
+				return nil
+			}
+			fmt.Fprintf(out, "window: %s\nrequests: %d\nprompt cache hit rate: %.1f%%\n",
+				dashboard.Window, dashboard.Usage.Requests, dashboard.Usage.CacheHitRate*100)
+			return nil
+		},
+	}
+	stats.Flags().StringVar(&window, "window", "24h", "period to report, e.g. 1h")
+
+	cache := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "cache",
+		Short: "Inspect prompt caching",
+	}
+	cache.AddCommand(stats)
+	return cache
+}
+
+// newBillingCommand creates the billing command group.
+func newBillingCommand(opts *options) *cobra.Command {
+	var format, groupBy, from, to, output string
+
+	export := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "export",
+		Short: "Export usage and cost for a period as CSV or OpenCost JSON (admin)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out := cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", output, err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			return opts.client().ExportBilling(cmd.Context(), out, format, groupBy, from, to)
+		},
+	}
+
+	flags := export.Flags()
+	flags.StringVar(&format, "format", "csv", "csv or opencost")
+	flags.StringVar(&groupBy, "group-by", domain.GroupByModel, "model, provider, tenant, key, day, or tag:<name>")
+	flags.StringVar(&from, "from", "", "start of the period as an RFC 3339 timestamp, default the start of the month")
+	flags.StringVar(&to, "to", "", "end of the period as an RFC 3339 timestamp, default now")
+	flags.StringVarP(&output, "output", "o", "", "file to write, default stdout")
+
+	billing := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "billing",
+		Short: "Export gateway spend for finance tooling",
+	}
+	billing.AddCommand(export)
+	return billing
+}
+
+// newValidateCommand creates the validate command, which checks the gateway
+// configuration without starting it.
+func newValidateCommand() *cobra.Command {
+	var envFile string
+
+	cmd := &cobra.Command{ //nolint:exhaustruct // Cobra commands set only the fields they use
+		Use:   "validate",
+		Short: "Check the gateway configuration in the environment, listing every problem",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Parse(envFile)
+			if err != nil {
+				return err
+			}
+			if err = config.Validate(cfg); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&envFile, "env-file", ".env", "environment file to load first, when it exists")
+	return cmd
+}
+
+// complete sends req without streaming and prints the reply, followed by its
+// usage on stderr.
+func complete(cmd *cobra.Command, client *Client, req *domain.CompletionRequest) error {
+	resp, err := client.Complete(cmd.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), resp.Content)
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s via %s: %d prompt + %d completion tokens, cost %.6f\n",
+		resp.Model, resp.Provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.Cost)
+	return nil
+}
+
+// stream sends req as a stream and prints the reply as it arrives.
+func stream(cmd *cobra.Command, client *Client, req *domain.CompletionRequest) error {
+	out := cmd.OutOrStdout()
+
+	_, err := client.Stream(cmd.Context(), req, func(delta string) {
+		fmt.Fprint(out, delta)
+	})
+	// End the reply's line even when the stream failed part way.
+	fmt.Fprintln(out)
+	return err
+}
+
+// envOr returns the named environment variable, or fallback when it is unset.
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// table writes tab-aligned columns.
+type table struct {
+	writer *tabwriter.Writer
+}
+
+// newTable starts a table with the given header.
+func newTable(out io.Writer, header ...any) *table {
+	t := &table{writer: tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)} //nolint:mnd // Column padding
+	t.row(header...)
+	return t
+}
+
+// row writes one row.
+func (t *table) row(columns ...any) {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = fmt.Sprint(column)
+	}
+	fmt.Fprintln(t.writer, strings.Join(values, "\t"))
+}
+
+// flush writes the aligned table.
+func (t *table) flush() {
+	_ = t.writer.Flush()
+}
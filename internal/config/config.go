@@ -1,18 +1,61 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 	"go.uber.org/dig"
 
+	"github.com/davidbz/calcifer/internal/provider/elevenlabs"
 	"github.com/davidbz/calcifer/internal/provider/openai"
 )
 
 // Config represents the gateway configuration.
 type Config struct {
-	Server ServerConfig
-	CORS   CORSConfig
-	OpenAI openai.Config
+	Server      ServerConfig
+	CORS        CORSConfig
+	Limits      LimitsConfig
+	Idempotency IdempotencyConfig
+	Context     ContextConfig
+	Admin       AdminConfig
+	Concurrency ConcurrencyConfig
+	Ensemble    EnsembleConfig
+	HealthCheck HealthCheckConfig
+	Transform   TransformConfig
+	Auth        AuthConfig
+	Prompts     PromptConfig
+	Shadow      ShadowConfig
+	Experiment  ExperimentConfig
+	Evaluation  EvaluationConfig
+	AutoModel   AutoModelConfig
+	Fallback    FallbackConfig
+	Moderation  ModerationConfig
+	Usage       UsageConfig
+	Attribution AttributionConfig
+	Custom      CustomProviderConfig
+	Discovery   DiscoveryConfig
+	Policy      PolicyConfig
+	Parameters  ParameterLimitConfig
+	Logging     LoggingConfig
+	Coalescing  CoalescingConfig
+	Pricing     PricingCatalogConfig
+	Chargeback  ChargebackConfig
+	Alerts      AlertConfig
+	Events      EventConfig
+	Quotas      QuotaConfig
+	Overrides   OverridesConfig
+	Convos      ConversationConfig
+	Tenants     TenantConfig
+	VirtualKeys VirtualKeyConfig
+	IPAllow     IPAllowConfig
+	Streams     StreamConfig
+	Chaos       ChaosConfig
+	Replay      ReplayConfig
+	Validation  ValidationConfig
+	Scripting   ScriptingConfig
+	OpenAI      openai.Config
+	ElevenLabs  elevenlabs.Config
 }
 
 // ServerConfig contains HTTP server settings.
@@ -20,6 +63,18 @@ type ServerConfig struct {
 	Port         int `env:"SERVER_PORT"          envDefault:"8080"`
 	ReadTimeout  int `env:"SERVER_READ_TIMEOUT"  envDefault:"30"`
 	WriteTimeout int `env:"SERVER_WRITE_TIMEOUT" envDefault:"30"`
+
+	// TLSCertFile and TLSKeyFile serve HTTPS, which negotiates HTTP/2 with clients that support it.
+	TLSCertFile string `env:"SERVER_TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"SERVER_TLS_KEY_FILE"`
+	// H2C serves unencrypted HTTP/2 with prior knowledge alongside HTTP/1.1, for internal deployments.
+	H2C bool `env:"SERVER_H2C" envDefault:"false"`
+	// HTTP2MaxConcurrentStreams bounds concurrent streams, e.g. SSE responses, per HTTP/2 connection.
+	HTTP2MaxConcurrentStreams int `env:"SERVER_HTTP2_MAX_CONCURRENT_STREAMS" envDefault:"250"`
+
+	// ResponseHeaderAllowlist lists upstream provider response headers forwarded to clients.
+	// Entries are case-insensitive; a trailing "*" matches any header with that prefix.
+	ResponseHeaderAllowlist []string `env:"SERVER_RESPONSE_HEADER_ALLOWLIST" envSeparator:","`
 }
 
 // CORSConfig contains CORS policy settings.
@@ -31,26 +86,464 @@ type CORSConfig struct {
 	MaxAge           int      `env:"CORS_MAX_AGE"                            envDefault:"86400"`
 }
 
+// LimitsConfig contains request payload limits. A zero value disables the limit.
+type LimitsConfig struct {
+	MaxBodyBytes     int64 `env:"LIMITS_MAX_BODY_BYTES"     envDefault:"1048576"`
+	MaxMessages      int   `env:"LIMITS_MAX_MESSAGES"       envDefault:"256"`
+	MaxMessageLength int   `env:"LIMITS_MAX_MESSAGE_LENGTH" envDefault:"100000"`
+}
+
+// IdempotencyConfig contains Idempotency-Key replay settings.
+type IdempotencyConfig struct {
+	Enabled            bool  `env:"IDEMPOTENCY_ENABLED"             envDefault:"true"`
+	TTL                int   `env:"IDEMPOTENCY_TTL"                 envDefault:"300"`      // seconds
+	MaxBytes           int64 `env:"IDEMPOTENCY_MAX_BYTES"           envDefault:"67108864"` // LRU budget, 0 = unbounded
+	CompactionInterval int   `env:"IDEMPOTENCY_COMPACTION_INTERVAL" envDefault:"60"`       // seconds, 0 = disabled
+
+	// MaxResponseBytes bounds the response buffered for replay, so long streams are
+	// relayed without being held in memory; larger responses are not stored. 0 = unbounded.
+	MaxResponseBytes int64 `env:"IDEMPOTENCY_MAX_RESPONSE_BYTES" envDefault:"1048576"`
+}
+
+// ContextConfig contains context window handling settings.
+type ContextConfig struct {
+	// TrimHistory drops the oldest messages when a prompt exceeds the model context window.
+	TrimHistory bool `env:"CONTEXT_TRIM_HISTORY" envDefault:"false"`
+	// OverflowStrategy is error, trim_oldest, or summarize; empty means trim_oldest when
+	// TrimHistory is set and no context window check otherwise.
+	OverflowStrategy string `env:"CONTEXT_OVERFLOW_STRATEGY"`
+	// SummaryModel writes the summaries of the summarize strategy; empty uses the requested model.
+	SummaryModel string `env:"CONTEXT_SUMMARY_MODEL"`
+	// ReservedOutputTokens are kept free for the completion when max_tokens is not set.
+	ReservedOutputTokens int `env:"CONTEXT_RESERVED_OUTPUT_TOKENS" envDefault:"1024"`
+	// AdjustMaxTokens retries context length errors once with max_tokens lowered to fit the context window.
+	AdjustMaxTokens bool `env:"CONTEXT_ADJUST_MAX_TOKENS" envDefault:"false"`
+	// Tokenizers maps model patterns (a trailing "*" matches any suffix) to a built-in
+	// tokenizer family: openai, claude, or llama.
+	Tokenizers map[string]string `env:"CONTEXT_TOKENIZERS"`
+}
+
+// AdminConfig contains admin API settings.
+type AdminConfig struct {
+	// Token is the bearer token required by /admin endpoints; empty disables the admin API.
+	Token string `env:"ADMIN_TOKEN"`
+}
+
+// ConcurrencyConfig contains per-provider and per-model concurrency limits.
+type ConcurrencyConfig struct {
+	// Limits maps "provider" or "provider/model" to max concurrent requests,
+	// e.g. "ollama=8,openai/gpt-4=20".
+	Limits map[string]int `env:"CONCURRENCY_LIMITS" envSeparator:"," envKeyValSeparator:"="`
+	// QueueTimeoutMs is how long a request waits for a slot; 0 rejects immediately.
+	QueueTimeoutMs int `env:"CONCURRENCY_QUEUE_TIMEOUT_MS" envDefault:"0"`
+	// BatchQueueTimeoutMs is how long a batch priority request waits for a slot; 0 rejects immediately.
+	BatchQueueTimeoutMs int `env:"CONCURRENCY_BATCH_QUEUE_TIMEOUT_MS" envDefault:"60000"`
+	// MaxQueue bounds waiting requests per limit; 0 means unbounded.
+	MaxQueue int `env:"CONCURRENCY_MAX_QUEUE" envDefault:"0"`
+	// AdaptiveProviders get a provider-wide limit that adapts to latency and 429s
+	// instead of a fixed one.
+	AdaptiveProviders []string `env:"CONCURRENCY_ADAPTIVE_PROVIDERS" envSeparator:","`
+	// AdaptiveInitial is the limit adaptive providers start from.
+	AdaptiveInitial int `env:"CONCURRENCY_ADAPTIVE_INITIAL" envDefault:"10"`
+	// AdaptiveMin and AdaptiveMax bound adaptive limits.
+	AdaptiveMin int `env:"CONCURRENCY_ADAPTIVE_MIN" envDefault:"1"`
+	AdaptiveMax int `env:"CONCURRENCY_ADAPTIVE_MAX" envDefault:"100"`
+	// AdaptiveLatencyTolerance is the ratio of recent to long-term latency above which
+	// an adaptive limit shrinks.
+	AdaptiveLatencyTolerance float64 `env:"CONCURRENCY_ADAPTIVE_LATENCY_TOLERANCE" envDefault:"2"`
+}
+
+// EnsembleConfig contains multi-model ensemble endpoint settings.
+type EnsembleConfig struct {
+	Enabled   bool `env:"ENSEMBLE_ENABLED"    envDefault:"false"`
+	MaxModels int  `env:"ENSEMBLE_MAX_MODELS" envDefault:"5"`
+}
+
+// HealthCheckConfig contains provider health-check settings.
+type HealthCheckConfig struct {
+	Interval         int `env:"HEALTH_CHECK_INTERVAL"          envDefault:"30"` // seconds, 0 = disabled
+	Timeout          int `env:"HEALTH_CHECK_TIMEOUT"           envDefault:"5"`  // seconds
+	FailureThreshold int `env:"HEALTH_CHECK_FAILURE_THRESHOLD" envDefault:"3"`  // consecutive failures before eviction
+
+	// SyncRedisURL shares health transitions with other replicas over Redis pub/sub,
+	// as redis://[[user]:pass@]host[:port]; empty keeps health local.
+	SyncRedisURL string `env:"HEALTH_SYNC_REDIS_URL"`
+	SyncChannel  string `env:"HEALTH_SYNC_CHANNEL"   envDefault:"calcifer:provider-health"`
+	SyncTimeout  int    `env:"HEALTH_SYNC_TIMEOUT"   envDefault:"5"` // seconds, per command and between pings
+}
+
+// TransformConfig contains response post-processing settings.
+type TransformConfig struct {
+	// Pipeline lists response transformers applied in order, e.g. "strip_think,trim_whitespace".
+	Pipeline []string `env:"RESPONSE_TRANSFORMERS" envSeparator:","`
+	// Disclaimer is the text appended by the disclaimer transformer.
+	Disclaimer string `env:"RESPONSE_DISCLAIMER"`
+}
+
+// AuthConfig contains client API key settings.
+type AuthConfig struct {
+	// ClientKeys maps key names to secrets, e.g. "mobile=sk-abc,batch=sk-def".
+	// When empty, the client API does not require authentication.
+	ClientKeys map[string]string `env:"AUTH_CLIENT_KEYS" envSeparator:"," envKeyValSeparator:"="`
+}
+
+// PromptConfig contains virtual model aliases and injected system prompts.
+// Prompt maps are separated by ";" so prompts may contain commas.
+type PromptConfig struct {
+	// ModelAliases maps virtual model names to real models, e.g. "support-bot=gpt-4o".
+	ModelAliases map[string]string `env:"MODEL_ALIASES" envSeparator:"," envKeyValSeparator:"="`
+	// ModelDeprecations marks models deprecated as sunset:replacement, e.g. "gpt-4=2026-06-30:gpt-4o".
+	ModelDeprecations map[string]string `env:"MODEL_DEPRECATIONS" envSeparator:"," envKeyValSeparator:"="`
+	// KeySystemPrompts maps client key names to a system prompt.
+	KeySystemPrompts map[string]string `env:"SYSTEM_PROMPTS_BY_KEY" envSeparator:";" envKeyValSeparator:"="`
+	// ModelSystemPrompts maps requested models or aliases to a system prompt.
+	ModelSystemPrompts map[string]string `env:"SYSTEM_PROMPTS_BY_MODEL" envSeparator:";" envKeyValSeparator:"="`
+	// CompressionKeys lists client key names whose prompts are compressed; "*" compresses all.
+	CompressionKeys []string `env:"PROMPT_COMPRESSION_KEYS" envSeparator:","`
+	// CompressionModels lists requested models or aliases whose prompts are compressed; "*" compresses all.
+	CompressionModels []string `env:"PROMPT_COMPRESSION_MODELS" envSeparator:","`
+}
+
+// ShadowConfig contains shadow traffic settings for evaluating a secondary provider.
+type ShadowConfig struct {
+	// Provider receives mirrored requests; empty disables shadowing.
+	Provider    string  `env:"SHADOW_PROVIDER"`
+	Model       string  `env:"SHADOW_MODEL"`                         // empty keeps the requested model
+	Percent     float64 `env:"SHADOW_PERCENT"       envDefault:"0"`  // 0-100
+	Timeout     int     `env:"SHADOW_TIMEOUT"       envDefault:"60"` // seconds
+	MaxInFlight int     `env:"SHADOW_MAX_IN_FLIGHT" envDefault:"16"`
+}
+
+// ExperimentConfig contains A/B experiment routing settings.
+type ExperimentConfig struct {
+	// Name identifies the experiment in metadata and metrics; empty disables the experiment.
+	Name         string  `env:"EXPERIMENT_NAME"`
+	Model        string  `env:"EXPERIMENT_MODEL"`                                   // control model
+	VariantModel string  `env:"EXPERIMENT_VARIANT_MODEL"`                           // alternate model
+	Percent      float64 `env:"EXPERIMENT_PERCENT"       envDefault:"0"`            // 0-100
+	BucketBy     string  `env:"EXPERIMENT_BUCKET_BY"     envDefault:"conversation"` // key or conversation
+}
+
+// EvaluationConfig contains response quality evaluation settings.
+type EvaluationConfig struct {
+	// Percent of responses scored by evaluators; 0 disables evaluation.
+	Percent float64 `env:"EVALUATION_PERCENT" envDefault:"0"` // 0-100
+	// JudgeModel scores responses by asking a model to rate them; empty leaves scoring to plugin evaluators.
+	JudgeModel  string `env:"EVALUATION_JUDGE_MODEL"`
+	TimeoutMs   int    `env:"EVALUATION_TIMEOUT_MS"    envDefault:"10000"`
+	MaxInFlight int    `env:"EVALUATION_MAX_IN_FLIGHT" envDefault:"4"`
+}
+
+// AutoModelConfig contains settings of the "auto" virtual model.
+type AutoModelConfig struct {
+	// Tiers maps models to the minimum complexity, 0-1, they serve; empty disables "auto".
+	Tiers map[string]float64 `env:"AUTO_MODEL_TIERS" envSeparator:"," envKeyValSeparator:"="`
+	// ClassifierModel scores prompts with a model call instead of heuristics.
+	ClassifierModel     string `env:"AUTO_MODEL_CLASSIFIER"`
+	ClassifierTimeoutMs int    `env:"AUTO_MODEL_CLASSIFIER_TIMEOUT_MS" envDefault:"2000"`
+}
+
+// FallbackConfig contains the fallbacks of provider error classes.
+type FallbackConfig struct {
+	// ContextLength maps models to larger-context models, e.g. "gpt-4=gpt-4-turbo".
+	ContextLength map[string]string `env:"FALLBACK_CONTEXT_LENGTH" envSeparator:"," envKeyValSeparator:"="`
+	// ContentFilter maps providers to providers filtering content differently, e.g. "azure=openai".
+	ContentFilter map[string]string `env:"FALLBACK_CONTENT_FILTER" envSeparator:"," envKeyValSeparator:"="`
+}
+
+// ModerationConfig contains content moderation settings.
+type ModerationConfig struct {
+	// Provider serves /v1/moderations and pre-flight checks; it must support moderation.
+	Provider string `env:"MODERATION_PROVIDER"  envDefault:"openai"`
+	// Preflight moderates prompts before routing and rejects flagged ones.
+	Preflight bool `env:"MODERATION_PREFLIGHT" envDefault:"false"`
+	// FailOpen allows requests through when the moderation call fails.
+	FailOpen bool `env:"MODERATION_FAIL_OPEN" envDefault:"false"`
+
+	// OutputKeywords and OutputPattern enable the output filter; keywords match whole words.
+	OutputKeywords []string `env:"MODERATION_OUTPUT_KEYWORDS" envSeparator:","`
+	OutputPattern  string   `env:"MODERATION_OUTPUT_PATTERN"` // regular expression
+	// OutputClassify screens output with the moderation provider, enabling the filter.
+	OutputClassify bool   `env:"MODERATION_OUTPUT_CLASSIFY" envDefault:"false"`
+	OutputAction   string `env:"MODERATION_OUTPUT_ACTION"   envDefault:"mask"` // mask or abort
+	OutputWindow   int    `env:"MODERATION_OUTPUT_WINDOW"   envDefault:"64"`   // bytes held back from streams
+}
+
+// UsageConfig contains usage recording and reporting settings.
+type UsageConfig struct {
+	Enabled bool `env:"USAGE_ENABLED" envDefault:"true"`
+	// Path persists records as JSON Lines replayed on startup; empty keeps them in memory only.
+	Path               string `env:"USAGE_STORE_PATH"`
+	RetentionDays      int    `env:"USAGE_RETENTION_DAYS"      envDefault:"30"`     // 0 = keep forever
+	MaxRecords         int    `env:"USAGE_MAX_RECORDS"         envDefault:"100000"` // 0 = unbounded
+	CompactionInterval int    `env:"USAGE_COMPACTION_INTERVAL" envDefault:"3600"`   // seconds, 0 = disabled
+
+	// Records past RetentionDays are rolled up into hourly aggregates kept until
+	// RollupHourlyDays, then into daily aggregates kept until RollupDailyDays.
+	RollupHourlyDays int `env:"USAGE_ROLLUP_HOURLY_DAYS" envDefault:"90"` // <= RetentionDays rolls up daily only
+	RollupDailyDays  int `env:"USAGE_ROLLUP_DAILY_DAYS"  envDefault:"0"`  // 0 = keep forever
+}
+
+// AttributionConfig contains cost attribution settings.
+type AttributionConfig struct {
+	// Tags lists the request metadata fields reported on usage records, logs, and metrics.
+	Tags []string `env:"COST_ATTRIBUTION_TAGS" envDefault:"team,feature,environment" envSeparator:","`
+}
+
+// CustomProviderConfig contains settings for providers declared without Go code.
+type CustomProviderConfig struct {
+	// Path is a JSON file declaring OpenAI-compatible providers; empty disables them.
+	Path string `env:"CUSTOM_PROVIDERS_FILE"`
+}
+
+// DiscoveryConfig contains dynamic model discovery settings.
+type DiscoveryConfig struct {
+	Interval int `env:"MODEL_DISCOVERY_INTERVAL" envDefault:"3600"` // seconds, 0 = disabled
+	Timeout  int `env:"MODEL_DISCOVERY_TIMEOUT"  envDefault:"10"`   // seconds
+}
+
+// PolicyConfig contains client key policy settings.
+type PolicyConfig struct {
+	// Path is a JSON file of key policies restricting models and providers; empty disables policies.
+	Path string `env:"KEY_POLICIES_FILE"`
+	// RoutingHints lets clients influence provider selection with routing.* request metadata.
+	RoutingHints bool `env:"ROUTING_HINTS_ENABLED" envDefault:"false"`
+	// ProviderRegions assigns providers to the regions key policies may restrict them to, e.g. "openai=us,azure-eu=eu".
+	ProviderRegions map[string]string `env:"PROVIDER_REGIONS" envSeparator:"," envKeyValSeparator:"="`
+}
+
+// ParameterLimitConfig contains per-model generation parameter caps.
+// Per-key caps are set in key policies.
+type ParameterLimitConfig struct {
+	// Mode is "clamp" to bring out-of-range parameters within limits or "reject" to fail the request.
+	Mode string `env:"PARAMETER_LIMIT_MODE" envDefault:"clamp"`
+	// MaxTokens caps max_tokens per model, e.g. "gpt-4=4096,gpt-4o=8192".
+	MaxTokens map[string]int `env:"MODEL_MAX_TOKENS" envSeparator:"," envKeyValSeparator:"="`
+	// TemperatureRanges bounds temperature per model as min:max, e.g. "gpt-4=0:1.2".
+	TemperatureRanges map[string]string `env:"MODEL_TEMPERATURE_RANGES" envSeparator:"," envKeyValSeparator:"="`
+}
+
+// LoggingConfig contains log redaction settings.
+// API keys and Authorization headers are always redacted.
+type LoggingConfig struct {
+	// RedactContent also redacts prompt and completion text from log fields.
+	RedactContent bool `env:"LOG_REDACT_CONTENT" envDefault:"false"`
+}
+
+// CoalescingConfig contains request coalescing settings.
+type CoalescingConfig struct {
+	// Enabled shares one provider call among identical concurrent non-streaming requests.
+	Enabled bool `env:"REQUEST_COALESCING_ENABLED" envDefault:"false"`
+}
+
+// PricingCatalogConfig contains external price catalog settings.
+type PricingCatalogConfig struct {
+	// Source is a file path or http(s) URL of a JSON price catalog (LiteLLM format or per-1K prices).
+	Source          string `env:"PRICING_CATALOG_SOURCE"`
+	RefreshInterval int    `env:"PRICING_CATALOG_REFRESH_INTERVAL" envDefault:"3600"` // seconds; 0 loads once at startup
+	Timeout         int    `env:"PRICING_CATALOG_TIMEOUT"          envDefault:"10"`   // seconds
+}
+
+// ChargebackConfig contains internal chargeback pricing settings.
+type ChargebackConfig struct {
+	// MarkupPercent is added to provider costs; negative values apply a discount.
+	MarkupPercent float64 `env:"COST_MARKUP_PERCENT" envDefault:"0"`
+	// Currency is the currency costs are reported in.
+	Currency string `env:"COST_CURRENCY" envDefault:"USD"`
+	// ExchangeRates gives units of each currency per USD, e.g. "EUR=0.92,GBP=0.79".
+	ExchangeRates map[string]float64 `env:"COST_EXCHANGE_RATES" envSeparator:"," envKeyValSeparator:"="`
+}
+
+// AlertConfig contains spend and error-rate alert settings.
+type AlertConfig struct {
+	WebhookURL         string `env:"ALERT_WEBHOOK_URL"`
+	WebhookTimeout     int    `env:"ALERT_WEBHOOK_TIMEOUT"      envDefault:"10"`  // seconds
+	WebhookMaxAttempts int    `env:"ALERT_WEBHOOK_MAX_ATTEMPTS" envDefault:"3"`   // deliveries per alert
+	WebhookBackoffMs   int    `env:"ALERT_WEBHOOK_BACKOFF_MS"   envDefault:"500"` // first retry delay, doubled per retry
+
+	// KeyBudgets and TeamBudgets set monthly budgets per client key name and "team"
+	// cost attribution tag, e.g. "alice=100,bob=250".
+	KeyBudgets      map[string]float64 `env:"ALERT_KEY_BUDGETS"      envSeparator:"," envKeyValSeparator:"="`
+	TeamBudgets     map[string]float64 `env:"ALERT_TEAM_BUDGETS"     envSeparator:"," envKeyValSeparator:"="`
+	SpendThresholds []float64          `env:"ALERT_SPEND_THRESHOLDS" envSeparator:"," envDefault:"50,90,100"`
+
+	// ErrorRateThreshold is the provider error rate (0-1) that raises an alert; 0 disables.
+	ErrorRateThreshold   float64 `env:"ALERT_ERROR_RATE_THRESHOLD"    envDefault:"0"`
+	ErrorRateWindow      int     `env:"ALERT_ERROR_RATE_WINDOW"       envDefault:"300"` // seconds
+	ErrorRateMinRequests int     `env:"ALERT_ERROR_RATE_MIN_REQUESTS" envDefault:"20"`
+	ErrorRateCooldown    int     `env:"ALERT_ERROR_RATE_COOLDOWN"     envDefault:"900"` // seconds
+}
+
+// EventConfig contains telemetry event publishing settings.
+type EventConfig struct {
+	// Sinks lists event destinations: "webhook", "nats", and/or "stdout". Empty disables events.
+	Sinks     []string `env:"EVENT_SINKS"      envSeparator:","`
+	QueueSize int      `env:"EVENT_QUEUE_SIZE" envDefault:"1024"` // events buffered before dropping
+
+	WebhookURL         string `env:"EVENT_WEBHOOK_URL"`
+	WebhookTimeout     int    `env:"EVENT_WEBHOOK_TIMEOUT"      envDefault:"10"`  // seconds
+	WebhookMaxAttempts int    `env:"EVENT_WEBHOOK_MAX_ATTEMPTS" envDefault:"3"`   // deliveries per event
+	WebhookBackoffMs   int    `env:"EVENT_WEBHOOK_BACKOFF_MS"   envDefault:"500"` // first retry delay, doubled per retry
+
+	NATSURL     string `env:"EVENT_NATS_URL"` // nats://[user:pass@]host[:port]
+	NATSSubject string `env:"EVENT_NATS_SUBJECT" envDefault:"calcifer.events"`
+	NATSTimeout int    `env:"EVENT_NATS_TIMEOUT" envDefault:"5"` // seconds, per connect and publish
+}
+
+// QuotaConfig contains client key quota settings.
+type QuotaConfig struct {
+	// Path is a SQLite database that persists quotas set through the admin API and
+	// the usage counted against them; empty keeps both in memory.
+	Path string `env:"QUOTA_STORE_PATH"`
+}
+
+// OverridesConfig contains settings for pricing, model alias, and key policy
+// overrides made through the admin API.
+type OverridesConfig struct {
+	// Path is a SQLite database that persists overrides; empty keeps them in memory.
+	Path string `env:"OVERRIDES_DB_PATH"`
+}
+
+// ConversationConfig contains settings for conversations kept by the gateway.
+type ConversationConfig struct {
+	// Path is a SQLite database that persists conversations; empty keeps them in memory.
+	Path string `env:"CONVERSATIONS_DB_PATH"`
+	// MaxInMemory bounds the conversations kept in memory, evicting the least recently updated.
+	MaxInMemory int `env:"CONVERSATIONS_MAX_IN_MEMORY" envDefault:"10000"` // 0 = unbounded
+}
+
+// TenantConfig contains multi-tenancy settings.
+type TenantConfig struct {
+	// Path is a JSON file defining tenants, their client keys, limits, and provider credentials.
+	Path string `env:"TENANTS_FILE"`
+}
+
+// VirtualKeyConfig contains settings for client keys issued through the admin API.
+type VirtualKeyConfig struct {
+	// Enabled turns on key issuance; the client API then requires a key.
+	Enabled bool `env:"VIRTUAL_KEYS_ENABLED" envDefault:"false"`
+	// Path is a JSON file persisting issued key digests; empty keeps them in memory.
+	Path string `env:"VIRTUAL_KEYS_PATH"`
+}
+
+// IPAllowConfig restricts which client networks may reach the gateway.
+type IPAllowConfig struct {
+	// AllowedCIDRs lists allowed client networks or addresses, e.g. "10.0.0.0/8,192.0.2.7".
+	// When empty, every client is allowed.
+	AllowedCIDRs []string `env:"IP_ALLOWLIST" envSeparator:","`
+	// TrustedProxies lists proxies whose X-Forwarded-For entries are believed.
+	TrustedProxies []string `env:"IP_TRUSTED_PROXIES" envSeparator:","`
+}
+
+// StreamConfig contains provider stream lifecycle settings.
+type StreamConfig struct {
+	// MaxDuration cancels streams open longer than this many seconds; 0 disables the limit.
+	MaxDuration int `env:"STREAM_MAX_DURATION" envDefault:"600"`
+}
+
+// ChaosConfig contains settings for the fault-injecting test provider.
+type ChaosConfig struct {
+	// Enabled registers the chaos provider, which serves the chaos4 model.
+	Enabled bool `env:"CHAOS_PROVIDER_ENABLED" envDefault:"false"`
+}
+
+// ReplayConfig contains settings for recording and replaying provider responses.
+type ReplayConfig struct {
+	// Mode is off, record (save upstream responses as cassettes), or replay (serve cassettes only).
+	Mode string `env:"REPLAY_MODE" envDefault:"off"`
+	// Dir holds the cassettes, one subdirectory per provider.
+	Dir string `env:"REPLAY_DIR" envDefault:"testdata/cassettes"`
+}
+
+// ValidationConfig contains request message validation settings.
+type ValidationConfig struct {
+	// StrictRoleOrderProviders require a user message first (after system messages) and
+	// strictly alternating user and assistant messages, as Anthropic-style APIs do.
+	StrictRoleOrderProviders []string `env:"STRICT_ROLE_ORDER_PROVIDERS" envSeparator:","`
+	// StopEmulationProviders lack native stop sequence support; the gateway enforces
+	// stop sequences for them instead, ending streams early.
+	StopEmulationProviders []string `env:"STOP_EMULATION_PROVIDERS" envSeparator:","`
+}
+
+// ScriptingConfig contains settings for operator-written request policies.
+type ScriptingConfig struct {
+	// PolicyScript is a Lua file defining on_request and on_response hooks; empty disables scripting.
+	PolicyScript string `env:"POLICY_SCRIPT"`
+	// TimeoutMs bounds each hook invocation.
+	TimeoutMs int `env:"POLICY_SCRIPT_TIMEOUT_MS" envDefault:"50"`
+}
+
 // DepConfig is used for dependency injection with dig.
 type DepConfig struct {
 	dig.Out
 	*ServerConfig
 	*CORSConfig
+	*LimitsConfig
+	*IdempotencyConfig
+	*ContextConfig
+	*AdminConfig
+	*ConcurrencyConfig
+	*EnsembleConfig
+	*HealthCheckConfig
+	*TransformConfig
+	*AuthConfig
+	*PromptConfig
+	*ShadowConfig
+	*ExperimentConfig
+	*EvaluationConfig
+	*AutoModelConfig
+	*FallbackConfig
+	*ModerationConfig
+	*UsageConfig
+	*AttributionConfig
+	*CustomProviderConfig
+	*DiscoveryConfig
+	*PolicyConfig
+	*ParameterLimitConfig
+	*LoggingConfig
+	*CoalescingConfig
+	*PricingCatalogConfig
+	*ChargebackConfig
+	*AlertConfig
+	*EventConfig
+	*QuotaConfig
+	*OverridesConfig
+	*ConversationConfig
+	*TenantConfig
+	*VirtualKeyConfig
+	*IPAllowConfig
+	*StreamConfig
+	*ChaosConfig
+	*ReplayConfig
+	*ValidationConfig
+	*ScriptingConfig
 	*openai.Config
+	ElevenLabs *elevenlabs.Config // Named: its type name collides with openai.Config
 }
 
 // Load loads environment files and parses configuration.
 func Load() *Config {
-	for _, file := range []string{".env"} {
+	cfg, err := Parse(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Parse loads the environment files that exist among envFiles, without overriding
+// variables already set, and parses configuration from the environment.
+func Parse(envFiles ...string) (*Config, error) {
+	for _, file := range envFiles {
 		_ = godotenv.Load(file)
 	}
 
 	var cfg Config
 	if err := env.Parse(&cfg); err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
-	return &cfg
+	return &cfg, nil
 }
 
 // ParseDependenciesConfig returns pointers to sub-configs for dependency injection.
@@ -59,6 +552,46 @@ func ParseDependenciesConfig(cfg *Config) DepConfig {
 		dig.Out{},
 		&cfg.Server,
 		&cfg.CORS,
+		&cfg.Limits,
+		&cfg.Idempotency,
+		&cfg.Context,
+		&cfg.Admin,
+		&cfg.Concurrency,
+		&cfg.Ensemble,
+		&cfg.HealthCheck,
+		&cfg.Transform,
+		&cfg.Auth,
+		&cfg.Prompts,
+		&cfg.Shadow,
+		&cfg.Experiment,
+		&cfg.Evaluation,
+		&cfg.AutoModel,
+		&cfg.Fallback,
+		&cfg.Moderation,
+		&cfg.Usage,
+		&cfg.Attribution,
+		&cfg.Custom,
+		&cfg.Discovery,
+		&cfg.Policy,
+		&cfg.Parameters,
+		&cfg.Logging,
+		&cfg.Coalescing,
+		&cfg.Pricing,
+		&cfg.Chargeback,
+		&cfg.Alerts,
+		&cfg.Events,
+		&cfg.Quotas,
+		&cfg.Overrides,
+		&cfg.Convos,
+		&cfg.Tenants,
+		&cfg.VirtualKeys,
+		&cfg.IPAllow,
+		&cfg.Streams,
+		&cfg.Chaos,
+		&cfg.Replay,
+		&cfg.Validation,
+		&cfg.Scripting,
 		&cfg.OpenAI,
+		&cfg.ElevenLabs,
 	}
 }
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/events"
+	"github.com/davidbz/calcifer/internal/provider/openai"
+	"github.com/davidbz/calcifer/internal/provider/replay"
+	"github.com/davidbz/calcifer/internal/provider/transport"
+)
+
+const (
+	maxPort    = 65535
+	maxPercent = 100
+)
+
+// ValidationError lists every configuration problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks cfg for invalid values and settings that contradict each other,
+// so the gateway fails at startup instead of running without the features they
+// configure. Every problem is reported at once, naming the variables to fix.
+// Referenced files must exist; their contents are parsed where the format is known.
+func Validate(cfg *Config) error {
+	v := &validator{problems: nil}
+
+	v.server(&cfg.Server)
+	v.routing(cfg)
+	v.traffic(&cfg.Shadow, &cfg.Experiment, &cfg.Evaluation)
+	v.costs(&cfg.Chargeback, &cfg.Alerts, &cfg.Pricing)
+	v.usage(&cfg.Usage)
+	v.events(&cfg.Events)
+	v.transport("OPENAI_", cfg.OpenAI.Transport)
+	v.transport("ELEVENLABS_", cfg.ElevenLabs.Transport)
+	v.access(cfg)
+	v.files(cfg)
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	slices.Sort(v.problems)
+	return &ValidationError{Problems: v.problems}
+}
+
+// validator collects configuration problems.
+type validator struct {
+	problems []string
+}
+
+// server checks the HTTP server settings.
+func (v *validator) server(cfg *ServerConfig) {
+	v.check(cfg.Port > 0 && cfg.Port <= maxPort, "SERVER_PORT must be between 1 and %d, got %d", maxPort, cfg.Port)
+	v.check(cfg.ReadTimeout >= 0 && cfg.WriteTimeout >= 0,
+		"SERVER_READ_TIMEOUT and SERVER_WRITE_TIMEOUT cannot be negative")
+	v.check((cfg.TLSCertFile == "") == (cfg.TLSKeyFile == ""),
+		"SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+	v.check(cfg.TLSCertFile == "" || !cfg.H2C,
+		"SERVER_H2C has no effect when SERVER_TLS_CERT_FILE is set, since TLS already negotiates HTTP/2")
+}
+
+// routing checks the settings that shape how requests are prepared and routed.
+func (v *validator) routing(cfg *Config) {
+	switch cfg.Context.OverflowStrategy {
+	case "", domain.ContextStrategyError, domain.ContextStrategyTrimOldest, domain.ContextStrategySummarize:
+	default:
+		v.addf("CONTEXT_OVERFLOW_STRATEGY must be error, trim_oldest, or summarize, got %q",
+			cfg.Context.OverflowStrategy)
+	}
+
+	if _, err := domain.NewTokenizers(cfg.Context.Tokenizers, nil); err != nil {
+		v.addf("CONTEXT_TOKENIZERS is invalid: %v", err)
+	}
+
+	v.check(cfg.Parameters.Mode == domain.LimitModeClamp || cfg.Parameters.Mode == domain.LimitModeReject,
+		"PARAMETER_LIMIT_MODE must be clamp or reject, got %q", cfg.Parameters.Mode)
+	if _, err := domain.NewModelLimits(cfg.Parameters.MaxTokens, cfg.Parameters.TemperatureRanges); err != nil {
+		v.addf("MODEL_MAX_TOKENS or MODEL_TEMPERATURE_RANGES is invalid: %v", err)
+	}
+	if _, err := domain.NewResponseTransformers(cfg.Transform.Pipeline, cfg.Transform.Disclaimer); err != nil {
+		v.addf("RESPONSE_TRANSFORMERS is invalid: %v", err)
+	}
+
+	for alias, model := range cfg.Prompts.ModelAliases {
+		v.check(model != "" && model != alias, "MODEL_ALIASES maps %q to no other model", alias)
+	}
+	if _, err := domain.ParseDeprecations(cfg.Prompts.ModelDeprecations); err != nil {
+		v.addf("MODEL_DEPRECATIONS is invalid: %v", err)
+	}
+	for name, limit := range cfg.Concurrency.Limits {
+		v.check(limit > 0, "CONCURRENCY_LIMITS for %s must be positive, got %d", name, limit)
+	}
+	if adaptive := cfg.Concurrency; len(adaptive.AdaptiveProviders) > 0 {
+		v.check(adaptive.AdaptiveMin > 0 && adaptive.AdaptiveMin <= adaptive.AdaptiveInitial &&
+			adaptive.AdaptiveInitial <= adaptive.AdaptiveMax,
+			"CONCURRENCY_ADAPTIVE_MIN, _INITIAL, and _MAX must be positive and ascending")
+		v.check(adaptive.AdaptiveLatencyTolerance > 1,
+			"CONCURRENCY_ADAPTIVE_LATENCY_TOLERANCE must be greater than 1, got %g", adaptive.AdaptiveLatencyTolerance)
+		for _, provider := range adaptive.AdaptiveProviders {
+			_, fixed := adaptive.Limits[provider]
+			v.check(!fixed, "CONCURRENCY_LIMITS and CONCURRENCY_ADAPTIVE_PROVIDERS both limit %s", provider)
+		}
+	}
+
+	v.check(!cfg.Moderation.Preflight || cfg.Moderation.Provider != "",
+		"MODERATION_PREFLIGHT requires MODERATION_PROVIDER")
+	for model, minComplexity := range cfg.AutoModel.Tiers {
+		v.check(minComplexity >= 0 && minComplexity <= 1,
+			"AUTO_MODEL_TIERS threshold of %s must be between 0 and 1, got %g", model, minComplexity)
+	}
+	v.check(cfg.AutoModel.ClassifierTimeoutMs > 0,
+		"AUTO_MODEL_CLASSIFIER_TIMEOUT_MS must be positive, got %d", cfg.AutoModel.ClassifierTimeoutMs)
+	if _, err := domain.NewFallbacks(cfg.Fallback.ContextLength, cfg.Fallback.ContentFilter); err != nil {
+		v.addf("FALLBACK_CONTEXT_LENGTH or FALLBACK_CONTENT_FILTER is invalid: %v", err)
+	}
+	v.check(!cfg.Moderation.OutputClassify || cfg.Moderation.Provider != "",
+		"MODERATION_OUTPUT_CLASSIFY requires MODERATION_PROVIDER")
+	outputAction := cfg.Moderation.OutputAction
+	v.check(outputAction == domain.OutputFilterMask || outputAction == domain.OutputFilterAbort,
+		"MODERATION_OUTPUT_ACTION must be mask or abort, got %q", outputAction)
+	v.check(cfg.Moderation.OutputWindow > 0,
+		"MODERATION_OUTPUT_WINDOW must be positive, got %d", cfg.Moderation.OutputWindow)
+	v.check(!cfg.Ensemble.Enabled || cfg.Ensemble.MaxModels > 0,
+		"ENSEMBLE_MAX_MODELS must be positive when ENSEMBLE_ENABLED is set")
+	v.check(cfg.HealthCheck.Interval <= 0 || (cfg.HealthCheck.Timeout > 0 && cfg.HealthCheck.FailureThreshold > 0),
+		"HEALTH_CHECK_TIMEOUT and HEALTH_CHECK_FAILURE_THRESHOLD must be positive when HEALTH_CHECK_INTERVAL is set")
+
+	switch cfg.Replay.Mode {
+	case replay.ModeOff, replay.ModeRecord, replay.ModeReplay:
+	default:
+		v.addf("REPLAY_MODE must be off, record, or replay, got %q", cfg.Replay.Mode)
+	}
+}
+
+// traffic checks the shadow traffic, response evaluation, and experiment settings.
+func (v *validator) traffic(shadow *ShadowConfig, experiment *ExperimentConfig, evaluation *EvaluationConfig) {
+	v.percent("SHADOW_PERCENT", shadow.Percent)
+	v.check(shadow.Percent == 0 || shadow.Provider != "", "SHADOW_PERCENT is set but SHADOW_PROVIDER is empty")
+
+	v.percent("EVALUATION_PERCENT", evaluation.Percent)
+	v.check(evaluation.TimeoutMs > 0, "EVALUATION_TIMEOUT_MS must be positive, got %d", evaluation.TimeoutMs)
+	v.check(evaluation.MaxInFlight > 0, "EVALUATION_MAX_IN_FLIGHT must be positive, got %d", evaluation.MaxInFlight)
+
+	v.percent("EXPERIMENT_PERCENT", experiment.Percent)
+	if experiment.Name == "" {
+		return
+	}
+	v.check(experiment.Model != "" && experiment.VariantModel != "",
+		"EXPERIMENT_NAME requires EXPERIMENT_MODEL and EXPERIMENT_VARIANT_MODEL")
+	v.check(experiment.BucketBy == domain.BucketByKey || experiment.BucketBy == domain.BucketByConversation,
+		"EXPERIMENT_BUCKET_BY must be key or conversation, got %q", experiment.BucketBy)
+}
+
+// costs checks the chargeback, alert, and price catalog settings.
+func (v *validator) costs(chargeback *ChargebackConfig, alerts *AlertConfig, catalog *PricingCatalogConfig) {
+	if _, err := domain.NewChargebackCostCalculator(
+		nil, chargeback.MarkupPercent, chargeback.Currency, chargeback.ExchangeRates,
+	); err != nil {
+		v.addf("COST_MARKUP_PERCENT, COST_CURRENCY, or COST_EXCHANGE_RATES is invalid: %v", err)
+	}
+
+	v.check(alerts.ErrorRateThreshold >= 0 && alerts.ErrorRateThreshold <= 1,
+		"ALERT_ERROR_RATE_THRESHOLD must be between 0 and 1, got %g", alerts.ErrorRateThreshold)
+	for _, threshold := range alerts.SpendThresholds {
+		v.check(threshold > 0, "ALERT_SPEND_THRESHOLDS must be positive percentages, got %g", threshold)
+	}
+	for name, budget := range alerts.KeyBudgets {
+		v.check(budget > 0, "ALERT_KEY_BUDGETS for %s must be positive, got %g", name, budget)
+	}
+	for name, budget := range alerts.TeamBudgets {
+		v.check(budget > 0, "ALERT_TEAM_BUDGETS for %s must be positive, got %g", name, budget)
+	}
+	v.check(alerts.WebhookURL == "" || alerts.WebhookMaxAttempts > 0,
+		"ALERT_WEBHOOK_MAX_ATTEMPTS must be positive when ALERT_WEBHOOK_URL is set")
+
+	v.check(catalog.Source == "" || catalog.Timeout > 0,
+		"PRICING_CATALOG_TIMEOUT must be positive when PRICING_CATALOG_SOURCE is set")
+}
+
+// usage checks the usage retention and rollup settings.
+func (v *validator) usage(cfg *UsageConfig) {
+	v.check(cfg.RetentionDays >= 0 && cfg.RollupHourlyDays >= 0 && cfg.RollupDailyDays >= 0,
+		"USAGE_RETENTION_DAYS, USAGE_ROLLUP_HOURLY_DAYS, and USAGE_ROLLUP_DAILY_DAYS cannot be negative")
+	v.check(cfg.RollupDailyDays == 0 || cfg.RollupDailyDays >= max(cfg.RetentionDays, cfg.RollupHourlyDays),
+		"USAGE_ROLLUP_DAILY_DAYS must be 0 or at least USAGE_RETENTION_DAYS and USAGE_ROLLUP_HOURLY_DAYS, got %d",
+		cfg.RollupDailyDays)
+}
+
+// events checks the telemetry event sinks.
+func (v *validator) events(cfg *EventConfig) {
+	for _, name := range cfg.Sinks {
+		switch strings.TrimSpace(name) {
+		case events.SinkWebhook:
+			v.check(cfg.WebhookURL != "", "EVENT_SINKS includes webhook but EVENT_WEBHOOK_URL is empty")
+			v.check(cfg.WebhookMaxAttempts > 0, "EVENT_WEBHOOK_MAX_ATTEMPTS must be positive")
+		case events.SinkNATS:
+			v.check(cfg.NATSURL != "", "EVENT_SINKS includes nats but EVENT_NATS_URL is empty")
+		case events.SinkStdout:
+		default:
+			v.addf("EVENT_SINKS includes unknown sink %q: must be webhook, nats, or stdout", name)
+		}
+	}
+	v.check(len(cfg.Sinks) == 0 || cfg.QueueSize > 0, "EVENT_QUEUE_SIZE must be positive when EVENT_SINKS is set")
+}
+
+// transport checks the connection pool settings of the provider configured
+// under the env prefix.
+func (v *validator) transport(prefix string, cfg transport.Config) {
+	v.check(cfg.Validate() == nil,
+		"%[1]sMAX_IDLE_CONNS_PER_HOST, %[1]sIDLE_CONN_TIMEOUT, and %[1]sDIAL_TIMEOUT cannot be negative", prefix)
+}
+
+// access checks the client access settings.
+func (v *validator) access(cfg *Config) {
+	v.check(!cfg.VirtualKeys.Enabled || cfg.Admin.Token != "",
+		"VIRTUAL_KEYS_ENABLED requires ADMIN_TOKEN, since keys are issued through the admin API")
+
+	for name, entries := range map[string][]string{
+		"IP_ALLOWLIST":       cfg.IPAllow.AllowedCIDRs,
+		"IP_TRUSTED_PROXIES": cfg.IPAllow.TrustedProxies,
+	} {
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if _, err := netip.ParsePrefix(entry); err == nil {
+				continue
+			}
+			if _, err := netip.ParseAddr(entry); err != nil {
+				v.addf("%s entry %q is neither an address nor a CIDR", name, entry)
+			}
+		}
+	}
+}
+
+// files checks that referenced files exist and parses those in a known format.
+func (v *validator) files(cfg *Config) {
+	v.file("SERVER_TLS_CERT_FILE", cfg.Server.TLSCertFile)
+	v.file("SERVER_TLS_KEY_FILE", cfg.Server.TLSKeyFile)
+	v.file("POLICY_SCRIPT", cfg.Scripting.PolicyScript)
+	if !strings.HasPrefix(cfg.Pricing.Source, "http://") && !strings.HasPrefix(cfg.Pricing.Source, "https://") {
+		v.file("PRICING_CATALOG_SOURCE", cfg.Pricing.Source)
+	}
+	if cfg.Replay.Mode == replay.ModeReplay {
+		v.file("REPLAY_DIR", cfg.Replay.Dir)
+	}
+
+	if data, ok := v.file("KEY_POLICIES_FILE", cfg.Policy.Path); ok {
+		if _, err := domain.ParseKeyPolicies(data); err != nil {
+			v.addf("KEY_POLICIES_FILE %s is invalid: %v", cfg.Policy.Path, err)
+		}
+	}
+	if data, ok := v.file("TENANTS_FILE", cfg.Tenants.Path); ok {
+		if _, err := domain.ParseTenants(data); err != nil {
+			v.addf("TENANTS_FILE %s is invalid: %v", cfg.Tenants.Path, err)
+		}
+	}
+	if _, ok := v.file("CUSTOM_PROVIDERS_FILE", cfg.Custom.Path); ok {
+		if _, err := openai.LoadCompatibleConfigs(cfg.Custom.Path); err != nil {
+			v.addf("CUSTOM_PROVIDERS_FILE %s is invalid: %v", cfg.Custom.Path, err)
+		}
+	}
+}
+
+// file reports a problem when the file the named variable sets is missing. It
+// returns the contents of regular files, and false when the variable is unset
+// or the file is unusable.
+func (v *validator) file(name, path string) ([]byte, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			v.addf("%s points to %s, which does not exist", name, path)
+		} else {
+			v.addf("%s points to %s, which cannot be read: %v", name, path, err)
+		}
+		return nil, false
+	}
+	if info.IsDir() {
+		return nil, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.addf("%s points to %s, which cannot be read: %v", name, path, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// percent reports a problem when the named percentage is outside 0-100.
+func (v *validator) percent(name string, value float64) {
+	v.check(value >= 0 && value <= maxPercent, "%s must be between 0 and 100, got %g", name, value)
+}
+
+// check reports a problem when ok is false.
+func (v *validator) check(ok bool, format string, args ...any) {
+	if !ok {
+		v.addf(format, args...)
+	}
+}
+
+// addf reports a problem.
+func (v *validator) addf(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
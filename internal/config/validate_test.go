@@ -0,0 +1,143 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("should accept the defaults", func(t *testing.T) {
+		os.Clearenv()
+
+		cfg, err := config.Parse()
+		require.NoError(t, err)
+
+		require.NoError(t, config.Validate(cfg))
+	})
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		problem string
+	}{
+		{
+			name:    "should reject an out-of-range port",
+			env:     map[string]string{"SERVER_PORT": "70000"},
+			problem: "SERVER_PORT must be between 1 and 65535, got 70000",
+		},
+		{
+			name:    "should require TLS certificate and key together",
+			env:     map[string]string{"SERVER_TLS_KEY_FILE": "key.pem"},
+			problem: "SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together",
+		},
+		{
+			name:    "should reject an unknown overflow strategy",
+			env:     map[string]string{"CONTEXT_OVERFLOW_STRATEGY": "drop"},
+			problem: `CONTEXT_OVERFLOW_STRATEGY must be error, trim_oldest, or summarize, got "drop"`,
+		},
+		{
+			name:    "should reject shadow traffic without a provider",
+			env:     map[string]string{"SHADOW_PERCENT": "10"},
+			problem: "SHADOW_PERCENT is set but SHADOW_PROVIDER is empty",
+		},
+		{
+			name:    "should reject an experiment without both models",
+			env:     map[string]string{"EXPERIMENT_NAME": "tone", "EXPERIMENT_MODEL": "gpt-4"},
+			problem: "EXPERIMENT_NAME requires EXPERIMENT_MODEL and EXPERIMENT_VARIANT_MODEL",
+		},
+		{
+			name:    "should reject a currency without an exchange rate",
+			env:     map[string]string{"COST_CURRENCY": "EUR"},
+			problem: "COST_MARKUP_PERCENT, COST_CURRENCY, or COST_EXCHANGE_RATES is invalid: no exchange rate",
+		},
+		{
+			name:    "should reject an error rate threshold above one",
+			env:     map[string]string{"ALERT_ERROR_RATE_THRESHOLD": "5"},
+			problem: "ALERT_ERROR_RATE_THRESHOLD must be between 0 and 1, got 5",
+		},
+		{
+			name:    "should reject daily rollups expiring before hourly ones",
+			env:     map[string]string{"USAGE_ROLLUP_DAILY_DAYS": "60"},
+			problem: "USAGE_ROLLUP_DAILY_DAYS must be 0 or at least USAGE_RETENTION_DAYS and USAGE_ROLLUP_HOURLY_DAYS",
+		},
+		{
+			name:    "should reject an event sink without its URL",
+			env:     map[string]string{"EVENT_SINKS": "webhook"},
+			problem: "EVENT_SINKS includes webhook but EVENT_WEBHOOK_URL is empty",
+		},
+		{
+			name:    "should require an admin token for virtual keys",
+			env:     map[string]string{"VIRTUAL_KEYS_ENABLED": "true"},
+			problem: "VIRTUAL_KEYS_ENABLED requires ADMIN_TOKEN",
+		},
+		{
+			name:    "should reject a provider with both a fixed and an adaptive limit",
+			env:     map[string]string{"CONCURRENCY_LIMITS": "ollama=8", "CONCURRENCY_ADAPTIVE_PROVIDERS": "ollama"},
+			problem: "CONCURRENCY_LIMITS and CONCURRENCY_ADAPTIVE_PROVIDERS both limit ollama",
+		},
+		{
+			name:    "should reject a malformed IP allow-list entry",
+			env:     map[string]string{"IP_ALLOWLIST": "10.0.0.0/33"},
+			problem: `IP_ALLOWLIST entry "10.0.0.0/33" is neither an address nor a CIDR`,
+		},
+		{
+			name:    "should reject a missing policy script",
+			env:     map[string]string{"POLICY_SCRIPT": "/does/not/exist.lua"},
+			problem: "POLICY_SCRIPT points to /does/not/exist.lua, which does not exist",
+		},
+		{
+			name:    "should reject negative provider transport settings",
+			env:     map[string]string{"OPENAI_DIAL_TIMEOUT": "-1"},
+			problem: "OPENAI_DIAL_TIMEOUT cannot be negative",
+		},
+		{
+			name:    "should reject an unknown replay mode",
+			env:     map[string]string{"REPLAY_MODE": "rewind"},
+			problem: `REPLAY_MODE must be off, record, or replay, got "rewind"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := config.Parse()
+			require.NoError(t, err)
+
+			require.ErrorContains(t, config.Validate(cfg), tt.problem)
+		})
+	}
+
+	t.Run("should report every problem at once", func(t *testing.T) {
+		os.Clearenv()
+		t.Setenv("SHADOW_PERCENT", "150")
+		t.Setenv("PARAMETER_LIMIT_MODE", "ignore")
+
+		cfg, err := config.Parse()
+		require.NoError(t, err)
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, config.Validate(cfg), &validationErr)
+		require.Len(t, validationErr.Problems, 3)
+	})
+
+	t.Run("should reject an invalid key policies file", func(t *testing.T) {
+		os.Clearenv()
+		path := filepath.Join(t.TempDir(), "policies.json")
+		require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+		t.Setenv("KEY_POLICIES_FILE", path)
+
+		cfg, err := config.Parse()
+		require.NoError(t, err)
+
+		require.ErrorContains(t, config.Validate(cfg), "KEY_POLICIES_FILE "+path+" is invalid")
+	})
+}
@@ -0,0 +1,19 @@
+// Package connretry retries operations on the long-lived connections the
+// gateway keeps to message brokers.
+package connretry
+
+import "errors"
+
+// Stale runs op, and runs it once more when it fails on a connection that was
+// reused from an earlier call. Servers and the proxies in front of them close
+// idle connections without notice, so the first write on a reused connection
+// may fail although the server is up. op must drop the connection it failed on,
+// so the retry dials a fresh one. Errors matching serverErr were replied by the
+// server and are returned without a retry, since they would fail again.
+func Stale(reused bool, serverErr error, op func() error) error {
+	err := op()
+	if err != nil && reused && !errors.Is(err, serverErr) {
+		err = op()
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package connretry_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/connretry"
+)
+
+func TestStale(t *testing.T) {
+	errServer := errors.New("server error")
+	errBroken := errors.New("broken pipe")
+
+	tests := []struct {
+		name     string
+		reused   bool
+		failures []error
+		calls    int
+		wantErr  error
+	}{
+		{name: "should not retry a success", reused: true, failures: nil, calls: 1, wantErr: nil},
+		{name: "should retry a reused connection once", reused: true, failures: []error{errBroken}, calls: 2,
+			wantErr: nil},
+		{name: "should return the error of the retry", reused: true, failures: []error{errBroken, errBroken},
+			calls: 2, wantErr: errBroken},
+		{name: "should not retry a fresh connection", reused: false, failures: []error{errBroken}, calls: 1,
+			wantErr: errBroken},
+		{name: "should not retry server errors", reused: true,
+			failures: []error{fmt.Errorf("%w: permission denied", errServer)}, calls: 1, wantErr: errServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := connretry.Stale(tt.reused, errServer, func() error {
+				calls++
+				if calls <= len(tt.failures) {
+					return tt.failures[calls-1]
+				}
+				return nil
+			})
+
+			require.Equal(t, tt.calls, calls)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package conversations
+
+// migrations returns the schema changes in order, applied by sqlstore.Open.
+// Released migrations are never edited, only appended to.
+func migrations() []string {
+	return []string{
+		`CREATE TABLE conversations (
+			id         TEXT PRIMARY KEY,
+			owner      TEXT NOT NULL,
+			model      TEXT NOT NULL,
+			messages   TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+	}
+}
@@ -0,0 +1,106 @@
+// Package conversations persists conversation histories to a SQLite database.
+package conversations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/sqlstore"
+)
+
+// Store implements domain.ConversationStore on a SQLite database. Its schema is
+// migrated to the current version when the store opens.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens the conversation store (DI constructor), creating the database
+// and applying pending migrations. It returns nil when no path is configured,
+// keeping conversations in memory only.
+func NewStore(cfg *config.ConversationConfig) (*Store, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, nil //nolint:nilnil // A nil store keeps conversations in memory
+	}
+
+	return Open(context.Background(), cfg.Path)
+}
+
+// Open opens the SQLite database at path and migrates it.
+func Open(ctx context.Context, path string) (*Store, error) {
+	db, err := sqlstore.Open(ctx, path, "conversation store", migrations())
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Get returns the conversation with id.
+func (s *Store) Get(ctx context.Context, id string) (domain.Conversation, error) {
+	var (
+		conversation         domain.Conversation
+		messages             []byte
+		createdAt, updatedAt string
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT id, owner, model, messages, created_at, updated_at
+		FROM conversations WHERE id = ?`, id).
+		Scan(&conversation.ID, &conversation.Owner, &conversation.Model, &messages, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Conversation{}, fmt.Errorf("%w: %s", domain.ErrUnknownConversation, id)
+	}
+	if err != nil {
+		return domain.Conversation{}, fmt.Errorf("failed to read conversation store: %w", err)
+	}
+
+	if err = json.Unmarshal(messages, &conversation.Messages); err != nil {
+		return domain.Conversation{}, fmt.Errorf("failed to parse conversation messages: %w", err)
+	}
+	if conversation.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return domain.Conversation{}, fmt.Errorf("failed to parse conversation creation time: %w", err)
+	}
+	if conversation.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return domain.Conversation{}, fmt.Errorf("failed to parse conversation update time: %w", err)
+	}
+	return conversation, nil
+}
+
+// Save creates or replaces a conversation, storing its messages as JSON.
+func (s *Store) Save(ctx context.Context, conversation domain.Conversation) error {
+	messages, err := json.Marshal(conversation.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation messages: %w", err)
+	}
+
+	return s.exec(ctx, `INSERT INTO conversations (id, owner, model, messages, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET model = excluded.model, messages = excluded.messages,
+		updated_at = excluded.updated_at`,
+		conversation.ID, conversation.Owner, conversation.Model, messages,
+		conversation.CreatedAt.UTC().Format(time.RFC3339Nano), conversation.UpdatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// Delete removes the conversation with id.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.exec(ctx, `DELETE FROM conversations WHERE id = ?`, id)
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close conversation store: %w", err)
+	}
+	return nil
+}
+
+// exec runs a statement that changes the store.
+func (s *Store) exec(ctx context.Context, statement string, args ...any) error {
+	if _, err := s.db.ExecContext(ctx, statement, args...); err != nil {
+		return fmt.Errorf("failed to write conversation store: %w", err)
+	}
+	return nil
+}
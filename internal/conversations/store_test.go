@@ -0,0 +1,72 @@
+package conversations_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/conversations"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	open := func(t *testing.T, path string) *conversations.Store {
+		t.Helper()
+		store, err := conversations.Open(ctx, path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+
+	t.Run("should return nil without a path", func(t *testing.T) {
+		store, err := conversations.NewStore(&config.ConversationConfig{Path: ""})
+		require.NoError(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("should report unknown conversations", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "conversations.db"))
+
+		_, err := store.Get(ctx, "conv_missing")
+		require.ErrorIs(t, err, domain.ErrUnknownConversation)
+	})
+
+	t.Run("should persist conversations across reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "conversations.db")
+		store := open(t, path)
+
+		created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		conversation := domain.Conversation{
+			ID:        "conv_1",
+			Owner:     "team-a",
+			Model:     "gpt-4",
+			Messages:  []domain.Message{{Role: "user", Content: "Hello"}},
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		require.NoError(t, store.Save(ctx, conversation))
+		conversation.Messages = append(conversation.Messages, domain.Message{Role: "assistant", Content: "Hi"})
+		conversation.UpdatedAt = created.Add(time.Minute)
+		require.NoError(t, store.Save(ctx, conversation))
+		require.NoError(t, store.Close())
+
+		loaded, err := open(t, path).Get(ctx, "conv_1")
+		require.NoError(t, err)
+		require.Equal(t, conversation, loaded)
+	})
+
+	t.Run("should delete conversations", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "conversations.db"))
+		require.NoError(t, store.Save(ctx, domain.Conversation{ID: "conv_1", Messages: []domain.Message{}}))
+		require.NoError(t, store.Delete(ctx, "conv_1"))
+
+		_, err := store.Get(ctx, "conv_1")
+		require.ErrorIs(t, err, domain.ErrUnknownConversation)
+	})
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// adaptiveBackoff is the factor an adaptive limit shrinks by on overload.
+	adaptiveBackoff = 0.75
+
+	// adaptiveWarmup is the number of latency samples taken before latency can
+	// shrink an adaptive limit.
+	adaptiveWarmup = 20
+
+	// Smoothing factors of the short- and long-term latency averages.
+	shortLatencyWeight = 0.1
+	longLatencyWeight  = 0.01
+)
+
+// AdaptiveLimits configures providers whose concurrency limit adapts to observed
+// latency and rate limiting instead of being fixed.
+type AdaptiveLimits struct {
+	Providers []string
+	Initial   int
+	Min       int
+	Max       int
+
+	// Tolerance is how far the short-term latency average may rise above the
+	// long-term average, as a ratio, before the limit shrinks.
+	Tolerance float64
+}
+
+// aimd adjusts a concurrency limit by additive increase, multiplicative decrease:
+// the limit grows by one per limit-worth of successful requests while it is
+// being used, and shrinks by adaptiveBackoff on a 429 or a latency rise. After
+// shrinking it waits a limit-worth of requests before shrinking again, so one
+// burst of errors or one slow stretch cannot collapse it.
+type aimd struct {
+	settings      AdaptiveLimits
+	limit         float64
+	shortLatency  float64
+	longLatency   float64
+	samples       int
+	sinceDecrease int
+}
+
+// newAIMD creates a controller starting at the initial limit, free to shrink at once.
+func newAIMD(settings AdaptiveLimits) *aimd {
+	return &aimd{
+		settings:      settings,
+		limit:         float64(settings.Initial),
+		shortLatency:  0,
+		longLatency:   0,
+		samples:       0,
+		sinceDecrease: settings.Initial,
+	}
+}
+
+// observe records the outcome of a request and returns the new limit. latency
+// is zero when the request gave no usable latency sample, as for streams.
+func (a *aimd) observe(latency time.Duration, overloaded bool, inUse int) int {
+	a.sinceDecrease++
+	slow := a.sampleLatency(latency)
+
+	switch {
+	case overloaded || slow:
+		if a.sinceDecrease >= int(a.limit) {
+			a.limit = max(a.limit*adaptiveBackoff, float64(a.settings.Min))
+			a.sinceDecrease = 0
+		}
+	case 2*inUse >= int(a.limit):
+		// Only grow a limit that is being used; idle capacity proves nothing.
+		a.limit = min(a.limit+1/a.limit, float64(a.settings.Max))
+	}
+	return int(a.limit)
+}
+
+// sampleLatency folds latency into the averages, reporting whether the short-term
+// average has risen above the long-term average by more than the tolerance.
+func (a *aimd) sampleLatency(latency time.Duration) bool {
+	if latency <= 0 {
+		return false
+	}
+
+	seconds := latency.Seconds()
+	a.samples++
+	if a.samples == 1 {
+		a.shortLatency, a.longLatency = seconds, seconds
+		return false
+	}
+	a.shortLatency += shortLatencyWeight * (seconds - a.shortLatency)
+	a.longLatency += longLatencyWeight * (seconds - a.longLatency)
+
+	return a.samples >= adaptiveWarmup && a.shortLatency > a.longLatency*a.settings.Tolerance
+}
+
+// rateLimited reports whether err is an upstream 429.
+func rateLimited(err error) bool {
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,99 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestConcurrencyLimiter_Adaptive(t *testing.T) {
+	newLimiter := func(initial int) *domain.ConcurrencyLimiter {
+		return domain.NewConcurrencyLimiter(nil, time.Second, time.Second, 0, nil, domain.AdaptiveLimits{
+			Providers: []string{"ollama"},
+			Initial:   initial,
+			Min:       2,
+			Max:       5,
+			Tolerance: 2,
+		})
+	}
+	rateLimited := &domain.ProviderError{Provider: "ollama", StatusCode: http.StatusTooManyRequests}
+
+	t.Run("should shrink on rate limiting down to the minimum", func(t *testing.T) {
+		limiter := newLimiter(4)
+
+		limiter.Observe("ollama", 0, rateLimited)
+		require.Equal(t, 3, limiter.Limit("ollama"))
+
+		limiter.Observe("ollama", 0, rateLimited)
+		require.Equal(t, 3, limiter.Limit("ollama"), "must wait a limit-worth of requests before shrinking again")
+
+		for range 10 {
+			limiter.Observe("ollama", 0, rateLimited)
+		}
+		require.Equal(t, 2, limiter.Limit("ollama"))
+	})
+
+	t.Run("should ignore failures unrelated to capacity", func(t *testing.T) {
+		limiter := newLimiter(4)
+
+		limiter.Observe("ollama", 0, errors.New("connection refused"))
+		require.Equal(t, 4, limiter.Limit("ollama"))
+	})
+
+	t.Run("should grow while in use and admit queued requests", func(t *testing.T) {
+		limiter := newLimiter(2)
+		ctx := context.Background()
+
+		for range 2 {
+			release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+			require.NoError(t, err)
+			defer release()
+		}
+
+		acquired := make(chan error, 1)
+		go func() {
+			release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+			if err == nil {
+				release()
+			}
+			acquired <- err
+		}()
+
+		for limiter.Limit("ollama") < 3 {
+			limiter.Observe("ollama", 10*time.Millisecond, nil)
+		}
+		require.NoError(t, <-acquired)
+
+		for range 100 {
+			limiter.Observe("ollama", 10*time.Millisecond, nil)
+		}
+		require.Equal(t, 5, limiter.Limit("ollama"), "must not grow past the maximum")
+	})
+
+	t.Run("should not grow while idle", func(t *testing.T) {
+		limiter := newLimiter(4)
+
+		for range 100 {
+			limiter.Observe("ollama", 10*time.Millisecond, nil)
+		}
+		require.Equal(t, 4, limiter.Limit("ollama"))
+	})
+
+	t.Run("should shrink when latency rises", func(t *testing.T) {
+		limiter := newLimiter(4)
+
+		for range 20 {
+			limiter.Observe("ollama", 10*time.Millisecond, nil)
+		}
+		for range 20 {
+			limiter.Observe("ollama", time.Second, nil)
+		}
+		require.Less(t, limiter.Limit("ollama"), 4)
+	})
+}
@@ -0,0 +1,325 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// AlertKindSpend fires when a client key or team crosses a share of its monthly budget.
+	AlertKindSpend = "spend"
+
+	// AlertKindErrorRate fires when a provider's error rate exceeds the threshold.
+	AlertKindErrorRate = "error_rate"
+
+	// alertQueueSize bounds alerts awaiting delivery; further alerts are dropped.
+	alertQueueSize = 64
+
+	// teamTag is the cost attribution tag that identifies a team.
+	teamTag = "team"
+
+	// budgetPeriodLayout formats the month a budget applies to.
+	budgetPeriodLayout = "2006-01"
+
+	// errorRateBuckets is the number of buckets in the error-rate sliding window.
+	errorRateBuckets = 10
+)
+
+// Alert describes a spend or error-rate condition worth notifying operators about.
+type Alert struct {
+	Kind      string    `json:"kind"`
+	Subject   string    `json:"subject"`            // "key:<name>", "team:<name>", or "provider:<name>"
+	Threshold float64   `json:"threshold"`          // percent of budget, or error rate (0-1)
+	Value     float64   `json:"value"`              // spend so far this month, or observed error rate
+	Budget    float64   `json:"budget,omitempty"`   // monthly budget for spend alerts
+	Period    string    `json:"period,omitempty"`   // budget month as YYYY-MM
+	Requests  int       `json:"requests,omitempty"` // requests in the error-rate window
+	Time      time.Time `json:"time"`
+}
+
+// AlertSink delivers alerts to an external system such as a webhook.
+type AlertSink interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// AlertOptions configures the alert monitor. Zero values disable a check.
+type AlertOptions struct {
+	KeyBudgets         map[string]float64 // monthly budget per client key name
+	TeamBudgets        map[string]float64 // monthly budget per "team" cost attribution tag
+	SpendThresholds    []float64          // percent of budget, e.g. 50, 90, 100
+	ErrorRateThreshold float64            // provider error rate (0-1) that raises an alert
+	ErrorRateWindow    time.Duration      // window the error rate is measured over
+	ErrorRateMinimum   int                // requests needed in the window before alerting
+	ErrorRateCooldown  time.Duration      // minimum time between alerts for one provider
+}
+
+// providerOutcomes counts requests and failures in one window bucket.
+type providerOutcomes struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+// budgetSubject is a client key or team with a monthly budget.
+type budgetSubject struct {
+	name   string
+	budget float64
+}
+
+// AlertMonitor tracks monthly spend per client key and team, and error rates per
+// provider, raising each alert once: spend thresholds once per month, error-rate
+// alerts at most once per cooldown. Alerts are logged, counted in metrics, and
+// delivered to the sink in the background by Run.
+type AlertMonitor struct {
+	sink       AlertSink
+	options    AlertOptions
+	bucketSize time.Duration
+	queue      chan Alert
+
+	mu       sync.Mutex
+	period   string
+	spend    map[string]float64
+	fired    map[string]bool
+	outcomes map[string][]providerOutcomes
+	lastRate map[string]time.Time
+}
+
+// NewAlertMonitor creates an alert monitor. A nil sink only logs alerts.
+func NewAlertMonitor(sink AlertSink, options AlertOptions) *AlertMonitor {
+	thresholds := append([]float64(nil), options.SpendThresholds...)
+	sort.Float64s(thresholds)
+	options.SpendThresholds = thresholds
+
+	return &AlertMonitor{
+		sink:       sink,
+		options:    options,
+		bucketSize: max(options.ErrorRateWindow/errorRateBuckets, time.Millisecond),
+		queue:      make(chan Alert, alertQueueSize),
+		mu:         sync.Mutex{},
+		period:     "",
+		spend:      make(map[string]float64),
+		fired:      make(map[string]bool),
+		outcomes:   make(map[string][]providerOutcomes),
+		lastRate:   make(map[string]time.Time),
+	}
+}
+
+// WithAlerts feeds usage and provider outcomes to the alert monitor.
+func WithAlerts(monitor *AlertMonitor) GatewayOption {
+	return func(g *GatewayService) {
+		g.alerts = monitor
+	}
+}
+
+// Run delivers queued alerts to the sink until ctx is done.
+func (m *AlertMonitor) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-m.queue:
+			m.deliver(ctx, alert)
+		}
+	}
+}
+
+// Seed restores this month's spend from stored usage records, so a restart neither
+// forgets spend nor re-raises alerts for thresholds crossed before it.
+func (m *AlertMonitor) Seed(records []UsageRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollPeriod()
+	for _, record := range records {
+		if record.Time.UTC().Format(budgetPeriodLayout) != m.period {
+			continue
+		}
+		for _, subject := range m.budgetSubjects(record) {
+			m.spend[subject.name] += record.Cost
+			m.markCrossed(subject.name, subject.budget)
+		}
+	}
+}
+
+// ObserveUsage adds a request's cost to its client key and team spend.
+func (m *AlertMonitor) ObserveUsage(ctx context.Context, record UsageRecord) {
+	m.mu.Lock()
+	m.rollPeriod()
+
+	var alerts []Alert
+	for _, subject := range m.budgetSubjects(record) {
+		m.spend[subject.name] += record.Cost
+		spend := m.spend[subject.name]
+
+		for _, threshold := range m.markCrossed(subject.name, subject.budget) {
+			alerts = append(alerts, Alert{
+				Kind:      AlertKindSpend,
+				Subject:   subject.name,
+				Threshold: threshold,
+				Value:     spend,
+				Budget:    subject.budget,
+				Period:    m.period,
+				Requests:  0,
+				Time:      time.Now().UTC(),
+			})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		m.raise(ctx, alert)
+	}
+}
+
+// ObserveProviderResult records a provider call outcome for error-rate alerting.
+func (m *AlertMonitor) ObserveProviderResult(ctx context.Context, provider string, failed bool) {
+	if m.options.ErrorRateThreshold <= 0 || m.options.ErrorRateWindow <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	m.mu.Lock()
+	buckets := m.recordOutcome(provider, now, failed)
+
+	requests, failures := 0, 0
+	for _, bucket := range buckets {
+		requests += bucket.requests
+		failures += bucket.failures
+	}
+	rate := float64(failures) / float64(requests)
+
+	if requests < m.options.ErrorRateMinimum || rate < m.options.ErrorRateThreshold ||
+		now.Sub(m.lastRate[provider]) < m.options.ErrorRateCooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.lastRate[provider] = now
+	m.mu.Unlock()
+
+	m.raise(ctx, Alert{
+		Kind:      AlertKindErrorRate,
+		Subject:   "provider:" + provider,
+		Threshold: m.options.ErrorRateThreshold,
+		Value:     rate,
+		Budget:    0,
+		Period:    "",
+		Requests:  requests,
+		Time:      now.UTC(),
+	})
+}
+
+// observeProviderResult reports a provider call outcome to the alert monitor, if any.
+func (g *GatewayService) observeProviderResult(ctx context.Context, provider Provider, err error) {
+	if g.alerts != nil {
+		g.alerts.ObserveProviderResult(ctx, provider.Name(), err != nil)
+	}
+}
+
+// budgetSubjects returns the budgeted key and team a record is charged to.
+func (m *AlertMonitor) budgetSubjects(record UsageRecord) []budgetSubject {
+	var subjects []budgetSubject
+	if budget, ok := m.options.KeyBudgets[record.ClientKey]; ok && record.ClientKey != "" && budget > 0 {
+		subjects = append(subjects, budgetSubject{name: "key:" + record.ClientKey, budget: budget})
+	}
+	team := record.Tags[teamTag]
+	if budget, ok := m.options.TeamBudgets[team]; ok && team != "" && budget > 0 {
+		subjects = append(subjects, budgetSubject{name: "team:" + team, budget: budget})
+	}
+	return subjects
+}
+
+// markCrossed marks the thresholds subject's spend has newly crossed and returns them.
+// Caller must hold the lock.
+func (m *AlertMonitor) markCrossed(subject string, budget float64) []float64 {
+	var crossed []float64
+	for _, threshold := range m.options.SpendThresholds {
+		if m.spend[subject] < budget*threshold/percent {
+			break
+		}
+		key := fmt.Sprintf("%s@%g", subject, threshold)
+		if m.fired[key] {
+			continue
+		}
+		m.fired[key] = true
+		crossed = append(crossed, threshold)
+	}
+	return crossed
+}
+
+// rollPeriod resets spend at the start of a new month. Caller must hold the lock.
+func (m *AlertMonitor) rollPeriod() {
+	period := time.Now().UTC().Format(budgetPeriodLayout)
+	if period == m.period {
+		return
+	}
+	m.period = period
+	clear(m.spend)
+	clear(m.fired)
+}
+
+// recordOutcome adds an outcome to the provider's sliding window, dropping expired
+// buckets, and returns the live buckets. Caller must hold the lock.
+func (m *AlertMonitor) recordOutcome(provider string, now time.Time, failed bool) []providerOutcomes {
+	buckets := m.outcomes[provider]
+
+	cutoff := now.Add(-m.options.ErrorRateWindow)
+	live := 0
+	for live < len(buckets) && !buckets[live].start.After(cutoff) {
+		live++
+	}
+	buckets = buckets[live:]
+
+	start := now.Truncate(m.bucketSize)
+	if len(buckets) == 0 || !buckets[len(buckets)-1].start.Equal(start) {
+		buckets = append(buckets, providerOutcomes{start: start, requests: 0, failures: 0})
+	}
+	last := &buckets[len(buckets)-1]
+	last.requests++
+	if failed {
+		last.failures++
+	}
+
+	m.outcomes[provider] = buckets
+	return buckets
+}
+
+// raise logs and counts an alert and queues it for delivery, dropping it when the
+// queue is full so alerting never blocks requests.
+func (m *AlertMonitor) raise(ctx context.Context, alert Alert) {
+	observability.AlertsRaised.WithLabelValues(alert.Kind).Inc()
+	observability.FromContext(ctx).Warn("alert raised",
+		observability.String("kind", alert.Kind),
+		observability.String("subject", alert.Subject),
+		observability.Float64("threshold", alert.Threshold),
+		observability.Float64("value", alert.Value),
+	)
+
+	if m.sink == nil {
+		return
+	}
+
+	select {
+	case m.queue <- alert:
+	default:
+		observability.AlertDeliveries.WithLabelValues("dropped").Inc()
+	}
+}
+
+// deliver sends one alert to the sink.
+func (m *AlertMonitor) deliver(ctx context.Context, alert Alert) {
+	if err := m.sink.Send(ctx, alert); err != nil {
+		observability.AlertDeliveries.WithLabelValues("error").Inc()
+		observability.FromContext(ctx).Error("failed to deliver alert",
+			observability.String("subject", alert.Subject),
+			observability.Error(err),
+		)
+		return
+	}
+	observability.AlertDeliveries.WithLabelValues("success").Inc()
+}
@@ -0,0 +1,143 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// memoryAlertSink collects delivered alerts.
+type memoryAlertSink struct {
+	mu     sync.Mutex
+	alerts []domain.Alert
+}
+
+func (s *memoryAlertSink) Send(_ context.Context, alert domain.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func (s *memoryAlertSink) delivered() []domain.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.Alert(nil), s.alerts...)
+}
+
+// startMonitor runs a monitor delivering to a memory sink until the test ends.
+func startMonitor(t *testing.T, options domain.AlertOptions) (*domain.AlertMonitor, *memoryAlertSink) {
+	t.Helper()
+
+	sink := &memoryAlertSink{}
+	monitor := domain.NewAlertMonitor(sink, options)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go monitor.Run(ctx)
+	return monitor, sink
+}
+
+func TestAlertMonitor(t *testing.T) {
+	t.Run("should raise each spend threshold once per budget", func(t *testing.T) {
+		monitor, sink := startMonitor(t, domain.AlertOptions{
+			KeyBudgets:      map[string]float64{"alice": 10},
+			TeamBudgets:     map[string]float64{"search": 100},
+			SpendThresholds: []float64{100, 50, 90},
+		})
+
+		record := domain.UsageRecord{ClientKey: "alice", Cost: 3, Tags: map[string]string{"team": "search"}}
+		for range 4 {
+			monitor.ObserveUsage(context.Background(), record)
+		}
+
+		require.Eventually(t, func() bool { return len(sink.delivered()) == 3 }, time.Second, 5*time.Millisecond)
+		alerts := sink.delivered()
+		require.Equal(t, []float64{50, 90, 100}, []float64{alerts[0].Threshold, alerts[1].Threshold, alerts[2].Threshold})
+		for _, alert := range alerts {
+			require.Equal(t, domain.AlertKindSpend, alert.Kind)
+			require.Equal(t, "key:alice", alert.Subject)
+		}
+		require.InDelta(t, 12.0, alerts[2].Value, 1e-9)
+	})
+
+	t.Run("should not re-raise thresholds crossed before a restart", func(t *testing.T) {
+		monitor, sink := startMonitor(t, domain.AlertOptions{
+			KeyBudgets:      map[string]float64{"alice": 10},
+			SpendThresholds: []float64{50, 90},
+		})
+		monitor.Seed([]domain.UsageRecord{{Time: time.Now(), ClientKey: "alice", Cost: 6}})
+
+		monitor.ObserveUsage(context.Background(), domain.UsageRecord{ClientKey: "alice", Cost: 3})
+
+		require.Eventually(t, func() bool { return len(sink.delivered()) == 1 }, time.Second, 5*time.Millisecond)
+		require.InDelta(t, 90.0, sink.delivered()[0].Threshold, 1e-9)
+	})
+
+	t.Run("should raise a provider error-rate alert once per cooldown", func(t *testing.T) {
+		monitor, sink := startMonitor(t, domain.AlertOptions{
+			ErrorRateThreshold: 0.5,
+			ErrorRateWindow:    time.Minute,
+			ErrorRateMinimum:   4,
+			ErrorRateCooldown:  time.Hour,
+		})
+
+		for _, failed := range []bool{false, true, true, true, true, true} {
+			monitor.ObserveProviderResult(context.Background(), "openai", failed)
+		}
+		monitor.ObserveProviderResult(context.Background(), "echo", true)
+
+		require.Eventually(t, func() bool { return len(sink.delivered()) == 1 }, time.Second, 5*time.Millisecond)
+		alert := sink.delivered()[0]
+		require.Equal(t, domain.AlertKindErrorRate, alert.Kind)
+		require.Equal(t, "provider:openai", alert.Subject)
+		require.Equal(t, 4, alert.Requests)
+		require.InDelta(t, 0.75, alert.Value, 1e-9)
+	})
+}
+
+func TestGatewayService_Alerts(t *testing.T) {
+	t.Run("should feed usage and provider failures to the alert monitor", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		monitor, sink := startMonitor(t, domain.AlertOptions{
+			KeyBudgets:         map[string]float64{"alice": 1},
+			SpendThresholds:    []float64{100},
+			ErrorRateThreshold: 0.5,
+			ErrorRateWindow:    time.Minute,
+			ErrorRateMinimum:   2,
+		})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+		}, nil).Once()
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, errors.New("upstream down")).Once()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(1.5, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithAlerts(monitor))
+		ctx := observability.WithClientKey(context.Background(), "alice")
+		req := &domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "Hi"}}}
+
+		_, err := gateway.CompleteByModel(ctx, req)
+		require.NoError(t, err)
+		_, err = gateway.CompleteByModel(ctx, req)
+		require.Error(t, err)
+
+		require.Eventually(t, func() bool { return len(sink.delivered()) == 2 }, time.Second, 5*time.Millisecond)
+		kinds := []string{sink.delivered()[0].Kind, sink.delivered()[1].Kind}
+		require.ElementsMatch(t, []string{domain.AlertKindSpend, domain.AlertKindErrorRate}, kinds)
+	})
+}
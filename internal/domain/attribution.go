@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// maxTagValueLength bounds attribution tag values, in bytes, so a misbehaving
+	// client cannot flood logs and metric labels with arbitrarily long strings.
+	maxTagValueLength = 64
+
+	// maxTagLabelValues bounds the distinct values of each tag reported as metric
+	// labels; later values are reported as otherTagLabel.
+	maxTagLabelValues = 100
+
+	// otherTagLabel stands in for tag values beyond maxTagLabelValues in metrics.
+	otherTagLabel = "other"
+)
+
+// WithCostAttribution copies the allow-listed request metadata fields (for example
+// team, feature, or environment) onto usage records, logs, and cost metrics.
+// Fields outside the allow-list are ignored, and each field reports at most
+// maxTagLabelValues distinct metric labels, so metric cardinality stays bounded.
+func WithCostAttribution(tags []string) GatewayOption {
+	return func(g *GatewayService) {
+		g.attributionTags = tags
+		g.attributionLabels = newTagLabels()
+	}
+}
+
+// tagLabels admits the first maxTagLabelValues values of each tag as metric labels.
+type tagLabels struct {
+	mu   sync.Mutex
+	seen map[string]map[string]struct{}
+}
+
+// newTagLabels creates an empty label set.
+func newTagLabels() *tagLabels {
+	return &tagLabels{mu: sync.Mutex{}, seen: make(map[string]map[string]struct{})}
+}
+
+// label returns the metric label reporting value of the tag name.
+func (l *tagLabels) label(name, value string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	values, ok := l.seen[name]
+	if !ok {
+		values = make(map[string]struct{})
+		l.seen[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= maxTagLabelValues {
+		return otherTagLabel
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+// costTags extracts the allow-listed attribution tags from request metadata.
+func (g *GatewayService) costTags(metadata map[string]string) map[string]string {
+	if len(g.attributionTags) == 0 || len(metadata) == 0 {
+		return nil
+	}
+
+	var tags map[string]string
+	for _, name := range g.attributionTags {
+		value := metadata[name]
+		if value == "" {
+			continue
+		}
+		value = truncateTagValue(value)
+		if value == "" {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string, len(g.attributionTags))
+		}
+		tags[name] = value
+	}
+
+	return tags
+}
+
+// attributeCost reports the cost and tokens of a completed request against each
+// of its attribution tags.
+func (g *GatewayService) attributeCost(ctx context.Context, record UsageRecord) {
+	if len(record.Tags) == 0 {
+		return
+	}
+
+	for name, value := range record.Tags {
+		if g.attributionLabels != nil {
+			value = g.attributionLabels.label(name, value)
+		}
+		observability.AttributedCost.WithLabelValues(name, value).Add(record.Cost)
+		observability.AttributedTokens.WithLabelValues(name, value).Add(float64(record.TotalTokens))
+	}
+
+	observability.FromContext(ctx).Info("request cost attributed",
+		observability.String("provider", record.Provider),
+		observability.String("model", record.Model),
+		observability.Int("total_tokens", record.TotalTokens),
+		observability.Float64("cost", record.Cost),
+		observability.Any("tags", record.Tags),
+	)
+}
+
+// truncateTagValue drops invalid UTF-8 from value, which metric labels reject,
+// and cuts it to maxTagValueLength bytes without splitting a character.
+func truncateTagValue(value string) string {
+	value = strings.ToValidUTF8(value, "")
+	if len(value) <= maxTagValueLength {
+		return value
+	}
+
+	cut := maxTagValueLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
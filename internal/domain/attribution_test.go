@@ -0,0 +1,163 @@
+package domain_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestGatewayService_CostAttribution(t *testing.T) {
+	t.Run("should record allow-listed metadata tags on usage", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+			Usage:    domain.Usage{TotalTokens: 15},
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.05, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithUsageStore(store),
+			domain.WithCostAttribution([]string{"team", "feature", "environment"}),
+		)
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Metadata: map[string]string{
+				"team":    "search",
+				"feature": strings.Repeat("x", 100),
+				"user_id": "u-123",
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, store.records, 1)
+		tags := store.records[0].Tags
+		require.Equal(t, "search", tags["team"])
+		require.Len(t, tags["feature"], 64)
+		require.NotContains(t, tags, "user_id")
+		require.NotContains(t, tags, "environment")
+	})
+
+	t.Run("should truncate multi-byte tag values on character boundaries", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithUsageStore(store),
+			domain.WithCostAttribution([]string{"team"}),
+		)
+
+		// The 64-byte cut falls inside the 32nd two-byte character.
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Metadata: map[string]string{"team": "x" + strings.Repeat("é", 40)},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, store.records, 1)
+		team := store.records[0].Tags["team"]
+		require.True(t, utf8.ValidString(team))
+		require.Equal(t, "x"+strings.Repeat("é", 31), team)
+	})
+
+	t.Run("should report tag values beyond the label limit as other", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(1.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithCostAttribution([]string{"label_limit_team"}),
+		)
+
+		for i := range 105 {
+			_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+				Model:    "gpt-4",
+				Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+				Metadata: map[string]string{"label_limit_team": "team-" + strconv.Itoa(i)},
+			})
+			require.NoError(t, err)
+		}
+
+		require.InDelta(t, 1.0,
+			testutil.ToFloat64(observability.AttributedCost.WithLabelValues("label_limit_team", "team-99")), 1e-9)
+		require.InDelta(t, 5.0,
+			testutil.ToFloat64(observability.AttributedCost.WithLabelValues("label_limit_team", "other")), 1e-9)
+	})
+
+	t.Run("should not tag usage without an allow-list", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithUsageStore(store))
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Metadata: map[string]string{"team": "search"},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, store.records, 1)
+		require.Nil(t, store.records[0].Tags)
+	})
+
+	t.Run("should aggregate usage by attribution tag", func(t *testing.T) {
+		records := []domain.UsageRecord{
+			{Model: "gpt-4", Cost: 0.1, Tags: map[string]string{"team": "search"}},
+			{Model: "gpt-4", Cost: 0.2, Tags: map[string]string{"team": "search"}},
+			{Model: "gpt-4", Cost: 0.4, Tags: map[string]string{"team": "ads"}},
+			{Model: "gpt-4", Cost: 0.8},
+		}
+
+		aggregates, err := domain.AggregateUsage(records, domain.GroupByTagPrefix+"team")
+		require.NoError(t, err)
+		require.Len(t, aggregates, 3)
+		require.Empty(t, aggregates[0].Group)
+		require.Equal(t, "ads", aggregates[1].Group)
+		require.Equal(t, "search", aggregates[2].Group)
+		require.InDelta(t, 0.3, aggregates[2].Cost, 1e-9)
+	})
+}
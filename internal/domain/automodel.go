@@ -0,0 +1,255 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// AutoModel is the virtual model routed to a real model by prompt complexity.
+const AutoModel = "auto"
+
+const (
+	// MetadataAutoComplexity reports the complexity score, 0-1, an "auto" request was routed by.
+	MetadataAutoComplexity = "auto_complexity"
+
+	// MetadataAutoClassifier reports what scored the request: ClassifierHeuristic or the classifier model.
+	MetadataAutoClassifier = "auto_classifier"
+
+	// ClassifierHeuristic names the built-in heuristic scorer.
+	ClassifierHeuristic = "heuristic"
+)
+
+// Heuristic complexity features. Each adds its weight, scaled by how strongly it
+// is present, to a score capped at 1.
+const (
+	complexityLengthWeight  = 0.4
+	complexityLengthTokens  = 2000 // prompt tokens scoring the full length weight
+	complexityCodeWeight    = 0.2
+	complexityKeywordWeight = 0.1 // per reasoning keyword
+	complexityMaxKeywords   = 3
+	complexityTurnsWeight   = 0.1
+	complexityTurns         = 6 // client messages making a conversation count as long
+
+	// classifierMaxScore is the top of the scale the classifier model rates on.
+	classifierMaxScore = 10
+
+	classifierInstructions = "Rate how difficult it is to answer the conversation below well, " +
+		"from 0 (trivial lookup or small talk) to 10 (expert multi-step reasoning, " +
+		"complex code, or math). Reply with the number only."
+)
+
+// reasoningKeywords mark prompts asking for analysis rather than recall.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var reasoningKeywords = []string{
+	"analyze", "analyse", "architecture", "compare", "debug", "derive", "design",
+	"explain why", "optimize", "proof", "prove", "refactor", "step by step", "trade-off",
+}
+
+// codePattern matches code blocks and common code constructs.
+//
+//nolint:gochecknoglobals // Compiled once
+var codePattern = regexp.MustCompile("```|\\bfunc\\s+\\w+\\(|\\bdef\\s+\\w+\\(|\\bclass\\s+\\w+|" +
+	"\\bSELECT\\b.+\\bFROM\\b|#include|=>|\\w+\\([^)]*\\)\\s*\\{")
+
+// scoreInteger matches the rating in a classifier reply.
+//
+//nolint:gochecknoglobals // Compiled once
+var scoreInteger = regexp.MustCompile(`\d+`)
+
+// ModelTier is a model serving "auto" requests scoring at least MinComplexity.
+type ModelTier struct {
+	Model         string
+	MinComplexity float64 // 0-1
+}
+
+// AutoRouting configures the "auto" virtual model: requests for it are scored for
+// complexity and routed to the tier with the highest MinComplexity not above the
+// score. Scores come from heuristics, or from a call to ClassifierModel when set,
+// falling back to heuristics when that call fails.
+type AutoRouting struct {
+	Tiers             []ModelTier // sorted by MinComplexity
+	ClassifierModel   string
+	ClassifierTimeout time.Duration
+}
+
+// NewAutoRouting builds "auto" routing from the minimum complexity, 0-1, of each
+// model.
+func NewAutoRouting(tiers map[string]float64, classifierModel string, timeout time.Duration) (AutoRouting, error) {
+	routing := AutoRouting{
+		Tiers:             make([]ModelTier, 0, len(tiers)),
+		ClassifierModel:   classifierModel,
+		ClassifierTimeout: timeout,
+	}
+	if len(tiers) == 0 {
+		return routing, errors.New("auto routing needs at least one model tier")
+	}
+
+	for model, minComplexity := range tiers {
+		if minComplexity < 0 || minComplexity > 1 {
+			return routing, fmt.Errorf("complexity threshold of %s must be between 0 and 1, got %g",
+				model, minComplexity)
+		}
+		routing.Tiers = append(routing.Tiers, ModelTier{Model: model, MinComplexity: minComplexity})
+	}
+	sort.Slice(routing.Tiers, func(i, j int) bool {
+		return routing.Tiers[i].MinComplexity < routing.Tiers[j].MinComplexity
+	})
+	return routing, nil
+}
+
+// WithAutoRouting routes the "auto" virtual model by prompt complexity.
+func WithAutoRouting(routing AutoRouting) GatewayOption {
+	return func(g *GatewayService) {
+		g.autoRouting = &routing
+	}
+}
+
+// pick returns the model for a complexity score. Scores below every threshold
+// go to the cheapest tier.
+func (a *AutoRouting) pick(score float64) string {
+	model := a.Tiers[0].Model
+	for _, tier := range a.Tiers {
+		if score >= tier.MinComplexity {
+			model = tier.Model
+		}
+	}
+	return model
+}
+
+// applyAutoModel rewrites requests for the "auto" model to the model their
+// complexity calls for. It returns the metadata recording the decision.
+func (g *GatewayService) applyAutoModel(ctx context.Context, req *CompletionRequest) map[string]string {
+	if g.autoRouting == nil || req.Model != AutoModel {
+		return nil
+	}
+
+	score, classifier := g.scoreComplexity(ctx, req)
+	req.Model = g.autoRouting.pick(score)
+
+	observability.AutoModelSelections.WithLabelValues(req.Model).Inc()
+	observability.FromContext(ctx).Info("auto model selected",
+		observability.String("model", req.Model),
+		observability.Float64("complexity", score),
+		observability.String("classifier", classifier),
+	)
+
+	return map[string]string{
+		MetadataAutoComplexity: strconv.FormatFloat(score, 'f', 2, 64),
+		MetadataAutoClassifier: classifier,
+	}
+}
+
+// scoreComplexity rates a request from 0 to 1, returning the score and what produced it.
+func (g *GatewayService) scoreComplexity(ctx context.Context, req *CompletionRequest) (float64, string) {
+	model := g.autoRouting.ClassifierModel
+	if model == "" {
+		return heuristicComplexity(req), ClassifierHeuristic
+	}
+
+	score, err := g.classifyComplexity(ctx, model, req)
+	if err != nil {
+		observability.FromContext(ctx).Warn("complexity classifier failed, using heuristics",
+			observability.String("classifier_model", model),
+			observability.Error(err),
+		)
+		return heuristicComplexity(req), ClassifierHeuristic
+	}
+	return score, model
+}
+
+// classifyComplexity asks the classifier model to rate the client's messages.
+func (g *GatewayService) classifyComplexity(
+	ctx context.Context,
+	model string,
+	req *CompletionRequest,
+) (float64, error) {
+	if g.autoRouting.ClassifierTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.autoRouting.ClassifierTimeout)
+		defer cancel()
+	}
+
+	provider, err := g.registry.GetByModel(ctx, model)
+	if err != nil {
+		return 0, fmt.Errorf("classifier model unavailable: %w", err)
+	}
+
+	var transcript strings.Builder
+	for _, msg := range req.Messages {
+		if !isInstruction(msg) {
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+		}
+	}
+
+	response, err := provider.Complete(ctx, &CompletionRequest{
+		Model: model,
+		Messages: []Message{
+			{Role: "system", Content: classifierInstructions, ToolCallID: "", ToolCalls: nil},
+			{Role: "user", Content: transcript.String(), ToolCallID: "", ToolCalls: nil},
+		},
+		Temperature:      0,
+		MaxTokens:        4,
+		Stream:           false,
+		Metadata:         nil,
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("classification failed: %w", err)
+	}
+
+	rating, err := strconv.Atoi(scoreInteger.FindString(response.Content))
+	if err != nil || rating > classifierMaxScore {
+		return 0, fmt.Errorf("classifier %s replied %q instead of a rating", model, response.Content)
+	}
+	return float64(rating) / classifierMaxScore, nil
+}
+
+// heuristicComplexity scores the client's messages by length, code, reasoning
+// keywords, and conversation length. Operator-injected instructions are ignored.
+func heuristicComplexity(req *CompletionRequest) float64 {
+	var text strings.Builder
+	turns := 0
+	for _, msg := range req.Messages {
+		if !isInstruction(msg) {
+			text.WriteString(msg.Content)
+			text.WriteString("\n")
+			turns++
+		}
+	}
+	prompt := text.String()
+
+	score := complexityLengthWeight * min(float64(EstimateTokens(prompt))/complexityLengthTokens, 1)
+	if codePattern.MatchString(prompt) {
+		score += complexityCodeWeight
+	}
+
+	lower := strings.ToLower(prompt)
+	keywords := 0
+	for _, keyword := range reasoningKeywords {
+		if strings.Contains(lower, keyword) {
+			keywords++
+		}
+	}
+	score += complexityKeywordWeight * float64(min(keywords, complexityMaxKeywords))
+
+	if turns >= complexityTurns {
+		score += complexityTurnsWeight
+	}
+	return min(score, 1)
+}
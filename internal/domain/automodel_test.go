@@ -0,0 +1,112 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestNewAutoRouting(t *testing.T) {
+	t.Run("should require a tier", func(t *testing.T) {
+		_, err := domain.NewAutoRouting(nil, "", 0)
+		require.Error(t, err)
+	})
+
+	t.Run("should reject thresholds outside 0-1", func(t *testing.T) {
+		_, err := domain.NewAutoRouting(map[string]float64{"gpt-4o": 1.5}, "", 0)
+		require.Error(t, err)
+	})
+
+	t.Run("should sort tiers by threshold", func(t *testing.T) {
+		routing, err := domain.NewAutoRouting(map[string]float64{"o1": 0.75, "gpt-4o-mini": 0, "gpt-4o": 0.4}, "", 0)
+		require.NoError(t, err)
+		require.Equal(t, []domain.ModelTier{
+			{Model: "gpt-4o-mini", MinComplexity: 0},
+			{Model: "gpt-4o", MinComplexity: 0.4},
+			{Model: "o1", MinComplexity: 0.75},
+		}, routing.Tiers)
+	})
+}
+
+func TestGatewayService_AutoModel(t *testing.T) {
+	tiers := map[string]float64{"gpt-4o-mini": 0.1, "gpt-4o": 0.4, "o1": 0.75}
+	newGateway := func(t *testing.T, classifier string) (*domain.GatewayService, *mocks.MockProviderRegistry) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		routing, err := domain.NewAutoRouting(tiers, classifier, 0)
+		require.NoError(t, err)
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithAutoRouting(routing)), mockRegistry
+	}
+	expectModel := func(t *testing.T, mockRegistry *mocks.MockProviderRegistry, model string) {
+		mockProvider := mocks.NewMockProvider(t)
+		mockProvider.EXPECT().Name().Return("openai").Maybe()
+		mockProvider.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.Model == model
+		})).Return(&domain.CompletionResponse{Provider: "openai", Model: model, Content: "ok"}, nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, model).Return(mockProvider, nil)
+	}
+	request := func(content string) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model: "auto",
+			Messages: []domain.Message{
+				{Role: "system", Content: "Prove it step by step. " + strings.Repeat("x", 4000)},
+				{Role: "user", Content: content},
+			},
+		}
+	}
+	complexPrompt := "Analyze this code, debug it and refactor it step by step:\n```go\nfunc main() {}\n```\n" +
+		strings.Repeat("The service fails under load. ", 300)
+
+	t.Run("should route simple prompts to the cheapest tier ignoring system prompts", func(t *testing.T) {
+		gateway, mockRegistry := newGateway(t, "")
+		expectModel(t, mockRegistry, "gpt-4o-mini")
+
+		response, err := gateway.CompleteByModel(context.Background(), request("What is the capital of France?"))
+		require.NoError(t, err)
+		require.Equal(t, "0.00", response.Metadata[domain.MetadataAutoComplexity])
+		require.Equal(t, domain.ClassifierHeuristic, response.Metadata[domain.MetadataAutoClassifier])
+	})
+
+	t.Run("should route complex prompts to the premium tier", func(t *testing.T) {
+		gateway, mockRegistry := newGateway(t, "")
+		expectModel(t, mockRegistry, "o1")
+
+		response, err := gateway.CompleteByModel(context.Background(), request(complexPrompt))
+		require.NoError(t, err)
+		require.Equal(t, "o1", response.Model)
+	})
+
+	t.Run("should score with the classifier model when configured", func(t *testing.T) {
+		gateway, mockRegistry := newGateway(t, "judge")
+		judge := mocks.NewMockProvider(t)
+		judge.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.Model == "judge" && !strings.Contains(req.Messages[1].Content, "Prove it")
+		})).Return(&domain.CompletionResponse{Content: " 5\n"}, nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "judge").Return(judge, nil)
+		expectModel(t, mockRegistry, "gpt-4o")
+
+		response, err := gateway.CompleteByModel(context.Background(), request("hi"))
+		require.NoError(t, err)
+		require.Equal(t, "0.50", response.Metadata[domain.MetadataAutoComplexity])
+		require.Equal(t, "judge", response.Metadata[domain.MetadataAutoClassifier])
+	})
+
+	t.Run("should fall back to heuristics when the classifier fails", func(t *testing.T) {
+		gateway, mockRegistry := newGateway(t, "judge")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "judge").Return(nil, errors.New("unknown model"))
+		expectModel(t, mockRegistry, "o1")
+
+		response, err := gateway.CompleteByModel(context.Background(), request(complexPrompt))
+		require.NoError(t, err)
+		require.Equal(t, domain.ClassifierHeuristic, response.Metadata[domain.MetadataAutoClassifier])
+	})
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BillingReport is the usage and cost of every client key over a period,
+// grouped for export to finance tooling.
+type BillingReport struct {
+	From     time.Time        `json:"from"` // inclusive
+	To       time.Time        `json:"to"`   // exclusive
+	GroupBy  string           `json:"group_by"`
+	Currency string           `json:"currency"` // currency costs are charged in now; ProviderCost is USD
+	Groups   []UsageAggregate `json:"groups"`
+}
+
+// BillingReport aggregates the usage of every client key recorded from from
+// until to by groupBy. Usage charged in another currency, before the chargeback
+// settings changed, is kept in groups of its own currency.
+func (g *GatewayService) BillingReport(
+	ctx context.Context,
+	from, to time.Time,
+	groupBy string,
+) (BillingReport, error) {
+	if g.usage == nil {
+		return BillingReport{}, ErrUsageUnavailable
+	}
+
+	records, err := g.usage.Query(ctx, UsageFilter{From: from, To: to, ClientKey: ""})
+	if err != nil {
+		return BillingReport{}, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	groups, err := AggregateUsage(records, groupBy)
+	if err != nil {
+		return BillingReport{}, err
+	}
+
+	currency := DefaultCurrency
+	if chargeback, ok := g.costCalculator.(ChargebackCalculator); ok {
+		currency = chargeback.Currency()
+	}
+
+	return BillingReport{From: from, To: to, GroupBy: groupBy, Currency: currency, Groups: groups}, nil
+}
@@ -0,0 +1,57 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_BillingReport(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should aggregate the period across client keys", func(t *testing.T) {
+		store := &memoryUsageStore{records: []domain.UsageRecord{
+			{Time: from.Add(-time.Hour), Model: "gpt-4", ClientKey: "a", Cost: 1},
+			{Time: from.Add(time.Hour), Model: "gpt-4", ClientKey: "a", Cost: 0.2},
+			{Time: from.Add(2 * time.Hour), Model: "gpt-4", ClientKey: "b", Cost: 0.3},
+			{Time: to, Model: "gpt-4", ClientKey: "b", Cost: 1},
+		}}
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithUsageStore(store))
+
+		report, err := gateway.BillingReport(t.Context(), from, to, domain.GroupByModel)
+
+		require.NoError(t, err)
+		require.Equal(t, domain.DefaultCurrency, report.Currency)
+		require.Len(t, report.Groups, 1)
+		require.Equal(t, 2, report.Groups[0].Requests)
+		require.InDelta(t, 0.5, report.Groups[0].Cost, 1e-9)
+		require.Equal(t, domain.DefaultCurrency, report.Groups[0].Currency)
+	})
+
+	t.Run("should report in the chargeback currency", func(t *testing.T) {
+		calculator, err := domain.NewChargebackCostCalculator(mocks.NewMockCostCalculator(t), 0, "EUR",
+			map[string]float64{"EUR": 0.9})
+		require.NoError(t, err)
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), calculator,
+			domain.WithUsageStore(&memoryUsageStore{}))
+
+		report, err := gateway.BillingReport(t.Context(), from, to, domain.GroupByModel)
+
+		require.NoError(t, err)
+		require.Equal(t, "EUR", report.Currency)
+	})
+
+	t.Run("should fail without a usage store", func(t *testing.T) {
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t))
+
+		_, err := gateway.BillingReport(t.Context(), from, to, domain.GroupByModel)
+
+		require.ErrorIs(t, err, domain.ErrUsageUnavailable)
+	})
+}
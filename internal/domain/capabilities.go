@@ -0,0 +1,18 @@
+package domain
+
+import "context"
+
+// ModelCapabilities describes the limits of a model.
+type ModelCapabilities struct {
+	ContextWindow   int // Max prompt + completion tokens
+	MaxOutputTokens int // Max completion tokens (0 = bounded only by ContextWindow)
+}
+
+// CapabilityRegistry maintains capability metadata for models.
+type CapabilityRegistry interface {
+	// GetCapabilities returns capability metadata for a model.
+	GetCapabilities(ctx context.Context, model string) (ModelCapabilities, error)
+
+	// RegisterCapabilities adds capability metadata for a model.
+	RegisterCapabilities(ctx context.Context, model string, capabilities ModelCapabilities) error
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// InMemoryCapabilityRegistry stores model capabilities in memory.
+type InMemoryCapabilityRegistry struct {
+	mu           sync.RWMutex
+	capabilities map[string]ModelCapabilities
+}
+
+// NewInMemoryCapabilityRegistry creates a new in-memory capability registry.
+func NewInMemoryCapabilityRegistry() *InMemoryCapabilityRegistry {
+	return &InMemoryCapabilityRegistry{
+		mu:           sync.RWMutex{},
+		capabilities: make(map[string]ModelCapabilities),
+	}
+}
+
+// GetCapabilities retrieves capabilities for a model.
+func (r *InMemoryCapabilityRegistry) GetCapabilities(
+	_ context.Context,
+	model string,
+) (ModelCapabilities, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	capabilities, exists := r.capabilities[model]
+	if !exists {
+		return ModelCapabilities{}, fmt.Errorf("capabilities not found for model: %s", model)
+	}
+
+	return capabilities, nil
+}
+
+// RegisterCapabilities adds capabilities for a model.
+func (r *InMemoryCapabilityRegistry) RegisterCapabilities(
+	_ context.Context,
+	model string,
+	capabilities ModelCapabilities,
+) error {
+	if model == "" {
+		return errors.New("model cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.capabilities[model] = capabilities
+	return nil
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ModelInfo describes a model clients can request.
+type ModelInfo struct {
+	ID        string   `json:"id"`
+	Providers []string `json:"providers,omitempty"` // routing providers serving the model, by name
+	AliasOf   string   `json:"alias_of,omitempty"`  // set for configured model aliases
+
+	Deprecation *Deprecation `json:"deprecation,omitempty"` // set for deprecated models
+}
+
+// Models lists the models served by routing providers and the configured and
+// overriding aliases, ordered by ID.
+func (g *GatewayService) Models(ctx context.Context) ([]ModelInfo, error) {
+	names, err := g.registry.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	slices.Sort(names)
+
+	providers := make(map[string][]string)
+	for _, name := range names {
+		provider, err := g.registry.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider %s: %w", name, err)
+		}
+		for _, model := range provider.SupportedModels(ctx) {
+			providers[model] = append(providers[model], name)
+		}
+	}
+
+	aliases := maps.Clone(g.modelAliases)
+	if overridden := g.overrides.aliasMap(); overridden != nil {
+		if aliases == nil {
+			aliases = overridden
+		} else {
+			maps.Copy(aliases, overridden)
+		}
+	}
+
+	models := make([]ModelInfo, 0, len(providers)+len(aliases))
+	for _, model := range slices.Sorted(maps.Keys(providers)) {
+		models = append(models, ModelInfo{ID: model, Providers: providers[model], AliasOf: "", Deprecation: nil})
+	}
+	for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+		models = append(models, ModelInfo{ID: alias, Providers: nil, AliasOf: aliases[alias], Deprecation: nil})
+	}
+	for i := range models {
+		if deprecation, ok := g.deprecations[models[i].ID]; ok {
+			models[i].Deprecation = &deprecation
+		}
+	}
+	slices.SortStableFunc(models, func(a, b ModelInfo) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return models, nil
+}
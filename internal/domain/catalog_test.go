@@ -0,0 +1,38 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_Models(t *testing.T) {
+	t.Run("should list models with their providers and aliases by ID", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		openai := mocks.NewMockProvider(t)
+		azure := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"openai", "azure"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(openai, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "azure").Return(azure, nil)
+		openai.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4o", "gpt-4"})
+		azure.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4o"})
+
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t),
+			domain.WithModelAliases(map[string]string{"fast": "gpt-4o"}),
+		)
+
+		models, err := gateway.Models(t.Context())
+
+		require.NoError(t, err)
+		require.Equal(t, []domain.ModelInfo{
+			{ID: "fast", AliasOf: "gpt-4o"},
+			{ID: "gpt-4", Providers: []string{"openai"}},
+			{ID: "gpt-4o", Providers: []string{"azure", "openai"}},
+		}, models)
+	})
+}
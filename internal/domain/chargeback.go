@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultCurrency is the currency provider prices are quoted in.
+	DefaultCurrency = "USD"
+
+	percent = 100.0
+)
+
+// ChargebackCalculator is implemented by cost calculators that charge a different
+// amount than the provider bills, such as a marked-up or converted internal price.
+// The gateway then reports the raw provider cost alongside the charged cost.
+type ChargebackCalculator interface {
+	CostCalculator
+
+	// ProviderCost returns the raw provider cost in USD.
+	ProviderCost(ctx context.Context, model string, usage Usage) (float64, error)
+	// Currency returns the currency Calculate reports costs in.
+	Currency() string
+}
+
+// ChargebackCostCalculator applies a markup and a currency conversion to the
+// costs of another calculator, so internal chargeback reflects negotiated
+// discounts or internal pricing.
+type ChargebackCostCalculator struct {
+	base       CostCalculator
+	multiplier float64
+	currency   string
+}
+
+// NewChargebackCostCalculator wraps base with a markup (negative for a discount)
+// and converts costs to currency using exchangeRates, given in units of each
+// currency per USD. USD needs no exchange rate.
+func NewChargebackCostCalculator(
+	base CostCalculator,
+	markupPercent float64,
+	currency string,
+	exchangeRates map[string]float64,
+) (*ChargebackCostCalculator, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	rate := 1.0
+	if currency != DefaultCurrency {
+		rate = 0
+		for code, value := range exchangeRates {
+			if strings.EqualFold(code, currency) {
+				rate = value
+			}
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("no exchange rate for currency %s", currency)
+		}
+	}
+
+	if markupPercent <= -percent {
+		return nil, fmt.Errorf("markup %.2f%% would make costs negative", markupPercent)
+	}
+
+	return &ChargebackCostCalculator{
+		base:       base,
+		multiplier: (1 + markupPercent/percent) * rate,
+		currency:   currency,
+	}, nil
+}
+
+// Calculate returns the charged cost: the provider cost with markup, in the configured currency.
+func (c *ChargebackCostCalculator) Calculate(ctx context.Context, model string, usage Usage) (float64, error) {
+	cost, err := c.base.Calculate(ctx, model, usage)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate provider cost: %w", err)
+	}
+	return cost * c.multiplier, nil
+}
+
+// ProviderCost returns the raw provider cost in USD.
+func (c *ChargebackCostCalculator) ProviderCost(ctx context.Context, model string, usage Usage) (float64, error) {
+	cost, err := c.base.Calculate(ctx, model, usage)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate provider cost: %w", err)
+	}
+	return cost, nil
+}
+
+// Currency returns the currency charged costs are reported in.
+func (c *ChargebackCostCalculator) Currency() string {
+	return c.currency
+}
+
+// price sets the charged cost of usage and, when the calculator charges a different
+// amount than the provider bills, the raw provider cost and currency.
+func (g *GatewayService) price(ctx context.Context, model string, usage *Usage) {
+	usage.Cost, _ = g.costCalculator.Calculate(ctx, model, *usage)
+
+	if chargeback, ok := g.costCalculator.(ChargebackCalculator); ok {
+		usage.ProviderCost, _ = chargeback.ProviderCost(ctx, model, *usage)
+		usage.Currency = chargeback.Currency()
+	}
+}
@@ -0,0 +1,93 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestChargebackCostCalculator(t *testing.T) {
+	usage := domain.Usage{PromptTokens: 1000, CompletionTokens: 500}
+
+	t.Run("should apply markup and currency conversion", func(t *testing.T) {
+		base := mocks.NewMockCostCalculator(t)
+		base.EXPECT().Calculate(mock.Anything, "gpt-4", usage).Return(1.0, nil)
+
+		calculator, err := domain.NewChargebackCostCalculator(base, 20, "eur", map[string]float64{"EUR": 0.9})
+		require.NoError(t, err)
+
+		cost, err := calculator.Calculate(context.Background(), "gpt-4", usage)
+		require.NoError(t, err)
+		require.InDelta(t, 1.08, cost, 1e-9)
+
+		providerCost, err := calculator.ProviderCost(context.Background(), "gpt-4", usage)
+		require.NoError(t, err)
+		require.InDelta(t, 1.0, providerCost, 1e-9)
+		require.Equal(t, "EUR", calculator.Currency())
+	})
+
+	t.Run("should apply a discount in USD", func(t *testing.T) {
+		base := mocks.NewMockCostCalculator(t)
+		base.EXPECT().Calculate(mock.Anything, "gpt-4", usage).Return(2.0, nil)
+
+		calculator, err := domain.NewChargebackCostCalculator(base, -25, "", nil)
+		require.NoError(t, err)
+
+		cost, err := calculator.Calculate(context.Background(), "gpt-4", usage)
+		require.NoError(t, err)
+		require.InDelta(t, 1.5, cost, 1e-9)
+		require.Equal(t, domain.DefaultCurrency, calculator.Currency())
+	})
+
+	t.Run("should reject a currency without an exchange rate", func(t *testing.T) {
+		_, err := domain.NewChargebackCostCalculator(mocks.NewMockCostCalculator(t), 0, "JPY", nil)
+		require.Error(t, err)
+	})
+
+	t.Run("should reject a discount of 100 percent or more", func(t *testing.T) {
+		_, err := domain.NewChargebackCostCalculator(mocks.NewMockCostCalculator(t), -100, "USD", nil)
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_Chargeback(t *testing.T) {
+	t.Run("should report charged and provider cost on usage", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+		base := mocks.NewMockCostCalculator(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+			Usage:    domain.Usage{TotalTokens: 15},
+		}, nil)
+		base.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.10, nil)
+
+		calculator, err := domain.NewChargebackCostCalculator(base, 50, "GBP", map[string]float64{"GBP": 0.8})
+		require.NoError(t, err)
+		gateway := domain.NewGatewayService(mockRegistry, calculator, domain.WithUsageStore(store))
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+
+		require.InDelta(t, 0.12, response.Usage.Cost, 1e-9)
+		require.InDelta(t, 0.10, response.Usage.ProviderCost, 1e-9)
+		require.Equal(t, "GBP", response.Usage.Currency)
+
+		require.Len(t, store.records, 1)
+		aggregates, err := domain.AggregateUsage(store.records, domain.GroupByModel)
+		require.NoError(t, err)
+		require.InDelta(t, 0.12, aggregates[0].Cost, 1e-9)
+		require.InDelta(t, 0.10, aggregates[0].ProviderCost, 1e-9)
+	})
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// MetadataCoalesced marks a response shared from an identical in-flight request.
+const MetadataCoalesced = "coalesced"
+
+// coalescedCall is a provider call shared by identical concurrent requests.
+type coalescedCall struct {
+	done     chan struct{}
+	response *CompletionResponse
+	err      error
+
+	// abandoned is set when the leader's request was cancelled, so its error
+	// belongs to the leader alone and followers run the call again.
+	abandoned bool
+}
+
+// requestCoalescer collapses identical concurrent completions into one provider call.
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// newRequestCoalescer creates an empty request coalescer.
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{mu: sync.Mutex{}, calls: make(map[string]*coalescedCall)}
+}
+
+// join returns the in-flight call for key, or registers a new one.
+// leader is true when the caller must run the call and finish it.
+func (c *requestCoalescer) join(key string) (*coalescedCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.calls[key]; ok {
+		observability.CoalescedWaiting.Inc()
+		return call, false
+	}
+
+	call := &coalescedCall{done: make(chan struct{}), response: nil, err: nil, abandoned: false}
+	c.calls[key] = call
+	return call, true
+}
+
+// finish publishes the call result to waiting followers. abandoned marks a call
+// whose leader's request was cancelled.
+func (c *requestCoalescer) finish(
+	key string,
+	call *coalescedCall,
+	response *CompletionResponse,
+	err error,
+	abandoned bool,
+) {
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+
+	call.response = response
+	call.err = err
+	call.abandoned = abandoned
+	close(call.done)
+}
+
+// WithRequestCoalescing shares one provider call among identical concurrent
+// non-streaming requests from the same client key, protecting providers from
+// thundering herds of retries. Only the call that reached the provider records usage.
+func WithRequestCoalescing() GatewayOption {
+	return func(g *GatewayService) {
+		g.coalescer = newRequestCoalescer()
+	}
+}
+
+// coalesce runs execute, sharing the result with identical requests already in flight.
+func (g *GatewayService) coalesce(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+	metadata map[string]string,
+) (*CompletionResponse, error) {
+	if g.coalescer == nil {
+		return g.execute(ctx, provider, req, metadata)
+	}
+
+	key, err := coalescingKey(ctx, provider.Name(), req)
+	if err != nil {
+		return g.execute(ctx, provider, req, metadata)
+	}
+
+	for {
+		call, leader := g.coalescer.join(key)
+		if leader {
+			response, execErr := g.execute(ctx, provider, req, metadata)
+			g.coalescer.finish(key, call, response, execErr, execErr != nil && ctx.Err() != nil)
+			return response, execErr
+		}
+
+		select {
+		case <-call.done:
+			observability.CoalescedWaiting.Dec()
+		case <-ctx.Done():
+			observability.CoalescedWaiting.Dec()
+			return nil, fmt.Errorf("waiting for coalesced request: %w", ctx.Err())
+		}
+
+		// The leader's client went away mid-call; retry, possibly as the new leader.
+		if call.abandoned {
+			continue
+		}
+
+		observability.CoalescedRequests.WithLabelValues(provider.Name()).Inc()
+		if call.err != nil {
+			return nil, call.err
+		}
+		return sharedResponse(call.response), nil
+	}
+}
+
+// coalescingKey identifies requests that may share a provider call: same client key,
+// tenant, provider, and prepared request body.
+func coalescingKey(ctx context.Context, providerName string, req *CompletionRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	hasher := sha256.New()
+	for _, part := range []string{
+		observability.GetClientKey(ctx),
+		observability.GetTenant(ctx),
+		providerName,
+	} {
+		hasher.Write([]byte(part))
+		hasher.Write([]byte{0})
+	}
+	hasher.Write(body)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// sharedResponse copies a leader's response for a follower, so transformers and
+// annotations applied later never race on shared maps and slices.
+func sharedResponse(response *CompletionResponse) *CompletionResponse {
+	shared := response.clone()
+	annotate(shared, map[string]string{MetadataCoalesced: "true"})
+	return shared
+}
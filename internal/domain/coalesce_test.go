@@ -0,0 +1,160 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestGatewayService_RequestCoalescing(t *testing.T) {
+	t.Run("should share one provider call among identical concurrent requests", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+		release := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			RunAndReturn(func(context.Context, *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				<-release
+				return &domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "Hi"}, nil
+			}).Once()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil).Once()
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithUsageStore(store),
+			domain.WithRequestCoalescing(),
+		)
+
+		const requests = 3
+		results := make(chan *domain.CompletionResponse, requests)
+		for range requests {
+			go func() {
+				response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+					Model:    "gpt-4",
+					Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+				})
+				require.NoError(t, err)
+				results <- response
+			}()
+		}
+
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(observability.CoalescedWaiting) == requests-1
+		}, time.Second, 5*time.Millisecond)
+		close(release)
+
+		coalesced := 0
+		for range requests {
+			response := <-results
+			require.Equal(t, "Hi", response.Content)
+			if response.Metadata[domain.MetadataCoalesced] == "true" {
+				coalesced++
+			}
+		}
+		require.Equal(t, requests-1, coalesced)
+		require.Len(t, store.records, 1)
+	})
+
+	t.Run("should not share calls between client keys", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		entered := make(chan struct{}, 2)
+		release := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			RunAndReturn(func(context.Context, *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				entered <- struct{}{}
+				<-release
+				return &domain.CompletionResponse{Model: "gpt-4", Provider: "openai"}, nil
+			}).Twice()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRequestCoalescing())
+
+		results := make(chan *domain.CompletionResponse, 2)
+		for _, key := range []string{"team-a", "team-b"} {
+			go func() {
+				ctx := observability.WithClientKey(context.Background(), key)
+				response, err := gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+					Model:    "gpt-4",
+					Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+				})
+				require.NoError(t, err)
+				results <- response
+			}()
+		}
+
+		// Both calls reach the provider while neither has finished.
+		<-entered
+		<-entered
+		close(release)
+
+		for range 2 {
+			require.NotContains(t, (<-results).Metadata, domain.MetadataCoalesced)
+		}
+	})
+
+	t.Run("should run the call again for followers when the leader is cancelled", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		entered := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, _ *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				close(entered)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}).Once()
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "Hi"}, nil).Once()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRequestCoalescing())
+		request := func() *domain.CompletionRequest {
+			return &domain.CompletionRequest{
+				Model:    "gpt-4",
+				Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			}
+		}
+
+		waiting := testutil.ToFloat64(observability.CoalescedWaiting)
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := gateway.CompleteByModel(leaderCtx, request())
+			leaderErr <- err
+		}()
+		<-entered
+
+		followerResult := make(chan *domain.CompletionResponse, 1)
+		go func() {
+			response, err := gateway.CompleteByModel(context.Background(), request())
+			require.NoError(t, err)
+			followerResult <- response
+		}()
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(observability.CoalescedWaiting) == waiting+1
+		}, time.Second, 5*time.Millisecond)
+
+		cancelLeader()
+		require.ErrorIs(t, <-leaderErr, context.Canceled)
+		require.Equal(t, "Hi", (<-followerResult).Content)
+	})
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// MetadataCompressionOriginalTokens reports the estimated prompt tokens before compression.
+	MetadataCompressionOriginalTokens = "compression_original_tokens"
+
+	// MetadataCompressionTokens reports the estimated prompt tokens after compression.
+	MetadataCompressionTokens = "compression_tokens"
+
+	// compressionWildcard enables compression for every key or model.
+	compressionWildcard = "*"
+
+	codeFence = "```"
+)
+
+// fillerWords carry little information for a model and are dropped by compression.
+// Negations and quantifiers are deliberately absent: dropping them changes meaning.
+//
+//nolint:gochecknoglobals // Read-only lookup table
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true,
+	"please": true, "kindly": true, "just": true, "really": true, "very": true,
+	"basically": true, "actually": true, "simply": true, "quite": true, "rather": true,
+	"somewhat": true, "literally": true, "totally": true, "definitely": true, "certainly": true,
+	"honestly": true, "essentially": true,
+}
+
+// WithPromptCompression compresses the prompts of requests from the listed client
+// keys or for the listed models (requested model or alias) before forwarding.
+// "*" in either list compresses every request.
+func WithPromptCompression(keys, models []string) GatewayOption {
+	return func(g *GatewayService) {
+		g.compressionKeys = keys
+		g.compressionModels = models
+	}
+}
+
+// CompressText drops low-information words from text, in the spirit of
+// LLMLingua-style prompt compression but with a fixed heuristic instead of a
+// scoring model. Filler words are removed, runs of whitespace within a line are
+// collapsed, and repeated lines are dropped. Indentation is kept, and runs of
+// blank lines become a single paragraph break. Fenced code blocks are kept verbatim.
+func CompressText(text string) string {
+	lines := strings.Split(text, "\n")
+	compressed := make([]string, 0, len(lines))
+
+	inCode := false
+	previous := ""
+	paragraphBreak := false
+	emit := func(line string) {
+		if paragraphBreak {
+			compressed = append(compressed, "")
+			paragraphBreak = false
+		}
+		compressed = append(compressed, line)
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), codeFence) {
+			inCode = !inCode
+			emit(line)
+			previous = ""
+			continue
+		}
+		if inCode {
+			emit(line)
+			continue
+		}
+
+		line = compressLine(line)
+		if line == "" {
+			paragraphBreak = len(compressed) > 0
+			previous = ""
+			continue
+		}
+		if line == previous {
+			continue
+		}
+		emit(line)
+		previous = line
+	}
+
+	return strings.Join(compressed, "\n")
+}
+
+// compressLine drops filler words from a line of prose and collapses its whitespace,
+// keeping its indentation. A blank line compresses to the empty string.
+func compressLine(line string) string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return ""
+	}
+	indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		if fillerWords[strings.ToLower(word)] {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	if len(kept) == 0 {
+		return indent + strings.Join(words, " ")
+	}
+
+	// Restore the capital of a sentence whose first word was dropped.
+	if len(kept) < len(words) && unicode.IsUpper([]rune(words[0])[0]) {
+		first := []rune(kept[0])
+		first[0] = unicode.ToUpper(first[0])
+		kept[0] = string(first)
+	}
+	return indent + strings.Join(kept, " ")
+}
+
+// compressPrompt compresses the user and developer messages of req when
+// compression is enabled for its client key or model, returning metadata with
+// the savings. System prompts, assistant turns, and tool results are sent as-is,
+// since rewording them can change instructions the gateway or model relies on.
+func (g *GatewayService) compressPrompt(ctx context.Context, req *CompletionRequest, model string) map[string]string {
+	if !g.compressionEnabled(observability.GetClientKey(ctx), model, req.Model) {
+		return nil
+	}
+
+	tokenizer := g.tokenizers.ForModel(req.Model)
+	original := CountMessagesTokens(tokenizer, req.Messages)
+	messages := make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = msg
+		if msg.Role == "user" || msg.Role == "developer" {
+			messages[i].Content = CompressText(msg.Content)
+		}
+	}
+	compressed := CountMessagesTokens(tokenizer, messages)
+	req.Messages = messages
+
+	observability.FromContext(ctx).Debug("compressed prompt",
+		observability.Int("original_tokens", original),
+		observability.Int("compressed_tokens", compressed),
+	)
+
+	return map[string]string{
+		MetadataCompressionOriginalTokens: strconv.Itoa(original),
+		MetadataCompressionTokens:         strconv.Itoa(compressed),
+	}
+}
+
+// compressionEnabled reports whether compression applies to a client key or any of models.
+func (g *GatewayService) compressionEnabled(key string, models ...string) bool {
+	if slices.Contains(g.compressionKeys, compressionWildcard) ||
+		slices.Contains(g.compressionModels, compressionWildcard) {
+		return true
+	}
+	if key != "" && slices.Contains(g.compressionKeys, key) {
+		return true
+	}
+	for _, model := range models {
+		if slices.Contains(g.compressionModels, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressionSavings reads the compression token counts recorded in metadata.
+func compressionSavings(metadata map[string]string) (int, int) {
+	original, err := strconv.Atoi(metadata[MetadataCompressionOriginalTokens])
+	if err != nil {
+		return 0, 0
+	}
+	compressed, err := strconv.Atoi(metadata[MetadataCompressionTokens])
+	if err != nil {
+		return 0, 0
+	}
+	return original, compressed
+}
@@ -0,0 +1,166 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestCompressText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "should drop filler words and collapse whitespace",
+			text:     "Please   just summarize the   report, it is very long.",
+			expected: "Summarize report, it is long.",
+		},
+		{
+			name:     "should keep negations",
+			text:     "Do not use the cache.",
+			expected: "Do not use cache.",
+		},
+		{
+			name:     "should drop repeated lines",
+			text:     "Check the logs.\nCheck the logs.\nThen restart.",
+			expected: "Check logs.\nThen restart.",
+		},
+		{
+			name:     "should collapse blank lines into one paragraph break",
+			text:     "\nCheck the logs.\n\n \n\nThen restart.\n\n",
+			expected: "Check logs.\n\nThen restart.",
+		},
+		{
+			name:     "should keep indentation",
+			text:     "Steps:\n  - open the file\n\t- save the file",
+			expected: "Steps:\n  - open file\n\t- save file",
+		},
+		{
+			name:     "should keep fenced code verbatim",
+			text:     "Fix the bug:\n```\nif  the == a {\n```",
+			expected: "Fix bug:\n```\nif  the == a {\n```",
+		},
+		{
+			name:     "should keep a line made only of filler words",
+			text:     "Just the",
+			expected: "Just the",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, domain.CompressText(tt.text))
+		})
+	}
+}
+
+func TestGatewayService_PromptCompression(t *testing.T) {
+	t.Run("should compress prompts for a listed key and record the savings", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return len(req.Messages) == 2 &&
+					req.Messages[0].Content == "Be brief." &&
+					req.Messages[1].Content == "Could you explain difference between a, b."
+			})).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithUsageStore(store),
+			domain.WithSystemPrompts(map[string]string{"mobile": "Be brief."}, nil),
+			domain.WithPromptCompression([]string{"mobile"}, nil),
+		)
+
+		ctx := observability.WithClientKey(context.Background(), "mobile")
+		req := &domain.CompletionRequest{
+			Model: "gpt-4",
+			Messages: []domain.Message{
+				{Role: "user", Content: "Could you please really explain the difference between a, b."},
+			},
+		}
+
+		response, err := gateway.CompleteByModel(ctx, req)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, response.Metadata[domain.MetadataCompressionOriginalTokens])
+		require.Len(t, store.records, 1)
+		record := store.records[0]
+		require.Positive(t, record.CompressedPromptTokens)
+		require.Less(t, record.CompressedPromptTokens, record.OriginalPromptTokens)
+		require.Contains(t, req.Messages[0].Content, "please", "caller request must not be mutated")
+	})
+
+	t.Run("should leave system, assistant, and tool messages untouched", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		messages := []domain.Message{
+			{Role: "system", Content: "You are a very helpful assistant."},
+			{Role: "user", Content: "Please explain the difference."},
+			{Role: "assistant", Content: "It is just the same."},
+			{Role: "tool", Content: "the result", ToolCallID: "call_1"},
+			{Role: "developer", Content: "Answer in the plain text."},
+		}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return len(req.Messages) == 5 &&
+					req.Messages[0].Content == messages[0].Content &&
+					req.Messages[1].Content == "Explain difference." &&
+					req.Messages[2].Content == messages[2].Content &&
+					req.Messages[3].Content == messages[3].Content &&
+					req.Messages[4].Content == "Answer in plain text."
+			})).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithPromptCompression([]string{"*"}, nil))
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: messages,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should leave prompts of unlisted keys and models untouched", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.Messages[0].Content == "Please explain the difference."
+			})).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithPromptCompression([]string{"batch"}, []string{"gpt-3.5-turbo"}))
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Please explain the difference."}},
+		})
+		require.NoError(t, err)
+		require.NotContains(t, response.Metadata, domain.MetadataCompressionOriginalTokens)
+	})
+}
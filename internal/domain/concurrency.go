@@ -0,0 +1,274 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// capacityRetryAfter is the backoff suggested to clients rejected by a limit.
+const capacityRetryAfter = time.Second
+
+// Priority is the scheduling class of a request waiting for a concurrency slot.
+type Priority string
+
+const (
+	// PriorityInteractive is the default class, for requests a user is waiting on.
+	PriorityInteractive Priority = "interactive"
+
+	// PriorityBatch is for offline traffic; it queues behind interactive requests.
+	PriorityBatch Priority = "batch"
+)
+
+// ParsePriority parses a priority class; an empty value is PriorityInteractive.
+func ParsePriority(value string) (Priority, error) {
+	switch Priority(value) {
+	case "", PriorityInteractive:
+		return PriorityInteractive, nil
+	case PriorityBatch:
+		return PriorityBatch, nil
+	default:
+		return "", fmt.Errorf("priority must be %s or %s, got %q", PriorityInteractive, PriorityBatch, value)
+	}
+}
+
+// ConcurrencyLimiter bounds concurrent requests per provider and per provider/model.
+// Limits are keyed by provider name ("openai") or provider and model ("openai/gpt-4");
+// both apply when configured, and a provider-wide limit may adapt, see AdaptiveLimits.
+// Requests over the limit wait up to the queue timeout of their priority for a slot,
+// or are rejected immediately when it is zero. A freed slot goes to the longest-waiting
+// interactive request, and to batch requests only when no interactive request is waiting.
+type ConcurrencyLimiter struct {
+	mu            sync.Mutex
+	semaphores    map[string]*semaphore
+	queueTimeouts map[Priority]time.Duration
+	maxQueue      int
+	load          *LoadTracker
+}
+
+// NewConcurrencyLimiter creates a limiter. Interactive requests queue up to
+// queueTimeout and batch requests up to batchQueueTimeout. maxQueue bounds waiting
+// requests per key (0 = unbounded); load, when set, receives per-tenant queue depth.
+// Providers in adaptive get a provider-wide limit that adapts to their results.
+func NewConcurrencyLimiter(
+	limits map[string]int,
+	queueTimeout time.Duration,
+	batchQueueTimeout time.Duration,
+	maxQueue int,
+	load *LoadTracker,
+	adaptive AdaptiveLimits,
+) *ConcurrencyLimiter {
+	semaphores := make(map[string]*semaphore, len(limits)+len(adaptive.Providers))
+	for key, limit := range limits {
+		if limit > 0 {
+			semaphores[key] = newSemaphore(limit, nil)
+		}
+	}
+	for _, provider := range adaptive.Providers {
+		semaphores[provider] = newSemaphore(adaptive.Initial, newAIMD(adaptive))
+		observability.ConcurrencyLimit.WithLabelValues(provider).Set(float64(adaptive.Initial))
+	}
+
+	return &ConcurrencyLimiter{
+		mu:         sync.Mutex{},
+		semaphores: semaphores,
+		queueTimeouts: map[Priority]time.Duration{
+			PriorityInteractive: queueTimeout,
+			PriorityBatch:       batchQueueTimeout,
+		},
+		maxQueue: maxQueue,
+		load:     load,
+	}
+}
+
+// semaphore counts the slots of one limit and queues its waiters per priority.
+// An adaptive semaphore's limit is adjusted as results are observed.
+type semaphore struct {
+	limit    int
+	inUse    int
+	waiters  map[Priority][]*waiter
+	adaptive *aimd
+}
+
+func newSemaphore(limit int, adaptive *aimd) *semaphore {
+	return &semaphore{limit: limit, inUse: 0, waiters: make(map[Priority][]*waiter), adaptive: adaptive}
+}
+
+// queued returns the number of waiting requests of every priority.
+func (s *semaphore) queued() int {
+	total := 0
+	for _, waiters := range s.waiters {
+		total += len(waiters)
+	}
+	return total
+}
+
+// next removes and returns the next waiter, interactive first, or nil.
+func (s *semaphore) next() *waiter {
+	for _, priority := range [...]Priority{PriorityInteractive, PriorityBatch} {
+		if waiters := s.waiters[priority]; len(waiters) > 0 {
+			s.waiters[priority] = waiters[1:]
+			return waiters[0]
+		}
+	}
+	return nil
+}
+
+// waiter is a queued request. ready receives once the slot is handed over.
+type waiter struct {
+	ready chan struct{}
+}
+
+// Acquire takes a slot for the provider and the provider/model pair.
+// The returned function releases the slots and must be called exactly once.
+func (l *ConcurrencyLimiter) Acquire(
+	ctx context.Context,
+	providerName, model string,
+	priority Priority,
+) (func(), error) {
+	releaseProvider, err := l.acquire(ctx, providerName, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseModel, err := l.acquire(ctx, providerName+"/"+model, priority)
+	if err != nil {
+		releaseProvider()
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			releaseModel()
+			releaseProvider()
+		})
+	}, nil
+}
+
+// acquire takes a slot for a single key, queueing when allowed.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, key string, priority Priority) (func(), error) {
+	sem, limited := l.semaphores[key]
+	if !limited {
+		return func() {}, nil
+	}
+
+	release := func() { l.release(sem) }
+
+	l.mu.Lock()
+	if sem.inUse < sem.limit {
+		sem.inUse++
+		l.mu.Unlock()
+		return release, nil
+	}
+
+	queueTimeout := l.queueTimeouts[priority]
+	if queueTimeout <= 0 || (l.maxQueue > 0 && sem.queued() >= l.maxQueue) {
+		l.mu.Unlock()
+		return nil, l.reject(key)
+	}
+	queued := &waiter{ready: make(chan struct{}, 1)}
+	sem.waiters[priority] = append(sem.waiters[priority], queued)
+	l.mu.Unlock()
+
+	if l.load != nil {
+		defer l.load.Enqueue(observability.GetTenant(ctx))()
+	}
+	start := time.Now()
+	defer func() {
+		observability.ConcurrencyQueueWait.WithLabelValues(string(priority)).Observe(time.Since(start).Seconds())
+	}()
+
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-queued.ready:
+		return release, nil
+	case <-timer.C:
+		if l.abandon(sem, priority, queued) {
+			return release, nil // the slot was handed over as the timer fired
+		}
+		return nil, l.reject(key)
+	case <-ctx.Done():
+		if l.abandon(sem, priority, queued) {
+			release()
+		}
+		return nil, fmt.Errorf("waiting for concurrency slot: %w", ctx.Err())
+	}
+}
+
+// abandon removes a waiter that stopped waiting, reporting true when it was
+// handed a slot before it could be removed.
+func (l *ConcurrencyLimiter) abandon(sem *semaphore, priority Priority, queued *waiter) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i := slices.Index(sem.waiters[priority], queued); i >= 0 {
+		sem.waiters[priority] = slices.Delete(sem.waiters[priority], i, i+1)
+		return false
+	}
+	return true
+}
+
+// release hands the slot to the next waiter, interactive first, or frees it.
+// Slots above a limit that shrank are freed rather than handed over.
+func (l *ConcurrencyLimiter) release(sem *semaphore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if sem.inUse <= sem.limit {
+		if next := sem.next(); next != nil {
+			next.ready <- struct{}{}
+			return
+		}
+	}
+	sem.inUse--
+}
+
+// Limit returns the current limit of key, or 0 when key is not limited.
+func (l *ConcurrencyLimiter) Limit(key string) int {
+	sem, limited := l.semaphores[key]
+	if !limited {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return sem.limit
+}
+
+// Observe feeds the result of a provider call to the provider's adaptive limit,
+// if it has one. latency is zero when the call gave no usable latency sample.
+func (l *ConcurrencyLimiter) Observe(providerName string, latency time.Duration, err error) {
+	sem, limited := l.semaphores[providerName]
+	if !limited || sem.adaptive == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil && !rateLimited(err) {
+		return // other failures say nothing about the provider's capacity
+	}
+	sem.limit = max(sem.adaptive.observe(latency, err != nil, sem.inUse), 1)
+	for sem.inUse < sem.limit {
+		next := sem.next()
+		if next == nil {
+			break
+		}
+		sem.inUse++
+		next.ready <- struct{}{}
+	}
+	observability.ConcurrencyLimit.WithLabelValues(providerName).Set(float64(sem.limit))
+}
+
+func (l *ConcurrencyLimiter) reject(key string) error {
+	observability.ConcurrencyRejections.WithLabelValues(key).Inc()
+	return &CapacityError{Scope: key, RetryAfter: capacityRetryAfter}
+}
@@ -0,0 +1,259 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestConcurrencyLimiter_Acquire(t *testing.T) {
+	t.Run("should reject immediately over the limit without queue timeout", func(t *testing.T) {
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"openai/gpt-4": 1},
+			0, 0, 0, nil, domain.AdaptiveLimits{},
+		)
+		ctx := context.Background()
+
+		release, err := limiter.Acquire(ctx, "openai", "gpt-4", domain.PriorityInteractive)
+		require.NoError(t, err)
+
+		_, err = limiter.Acquire(ctx, "openai", "gpt-4", domain.PriorityInteractive)
+		var capacityErr *domain.CapacityError
+		require.ErrorAs(t, err, &capacityErr)
+		require.Equal(t, "openai/gpt-4", capacityErr.Scope)
+		require.Positive(t, capacityErr.RetryAfter)
+
+		// Other models of the same provider are not limited.
+		releaseOther, err := limiter.Acquire(ctx, "openai", "gpt-3.5-turbo", domain.PriorityInteractive)
+		require.NoError(t, err)
+		releaseOther()
+
+		release()
+		release() // Releasing twice must not free an extra slot.
+
+		releaseAgain, err := limiter.Acquire(ctx, "openai", "gpt-4", domain.PriorityInteractive)
+		require.NoError(t, err)
+		releaseAgain()
+	})
+
+	t.Run("should apply provider-wide limits", func(t *testing.T) {
+		limiter := domain.NewConcurrencyLimiter(map[string]int{"ollama": 1}, 0, 0, 0, nil, domain.AdaptiveLimits{})
+		ctx := context.Background()
+
+		release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = limiter.Acquire(ctx, "ollama", "mistral", domain.PriorityInteractive)
+		require.Error(t, err)
+	})
+
+	t.Run("should queue until a slot frees up and report tenant queue depth", func(t *testing.T) {
+		load := domain.NewLoadTracker()
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"ollama": 1},
+			time.Second, time.Second, 0, load, domain.AdaptiveLimits{},
+		)
+		ctx := observability.WithTenant(context.Background(), "team-a")
+
+		release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+
+		acquired := make(chan error, 1)
+		go func() {
+			queuedRelease, queuedErr := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+			if queuedErr == nil {
+				queuedRelease()
+			}
+			acquired <- queuedErr
+		}()
+
+		require.Eventually(t, func() bool {
+			snapshot := load.Snapshot()
+			return len(snapshot) == 1 && snapshot[0].Queued == 1
+		}, time.Second, 5*time.Millisecond)
+
+		release()
+		require.NoError(t, <-acquired)
+		require.Empty(t, load.Snapshot())
+	})
+
+	t.Run("should reject when the queue is full", func(t *testing.T) {
+		load := domain.NewLoadTracker()
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"ollama": 1},
+			time.Second, time.Second, 1, load, domain.AdaptiveLimits{},
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+		defer release()
+
+		waiting := make(chan error, 1)
+		go func() {
+			_, queuedErr := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+			waiting <- queuedErr
+		}()
+
+		require.Eventually(t, func() bool {
+			snapshot := load.Snapshot()
+			return len(snapshot) == 1 && snapshot[0].Queued == 1
+		}, time.Second, 5*time.Millisecond)
+
+		_, err = limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		var capacityErr *domain.CapacityError
+		require.ErrorAs(t, err, &capacityErr)
+
+		cancel()
+		require.ErrorIs(t, <-waiting, context.Canceled)
+	})
+
+	t.Run("should hand freed slots to interactive requests before batch requests", func(t *testing.T) {
+		load := domain.NewLoadTracker()
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"ollama": 1},
+			time.Second, time.Second, 0, load, domain.AdaptiveLimits{},
+		)
+		ctx := context.Background()
+
+		release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+
+		acquired := make(chan domain.Priority, 2)
+		queue := func(priority domain.Priority, queued int) {
+			go func() {
+				queuedRelease, queuedErr := limiter.Acquire(ctx, "ollama", "llama3", priority)
+				if queuedErr != nil {
+					acquired <- ""
+					return
+				}
+				acquired <- priority
+				queuedRelease()
+			}()
+			require.Eventually(t, func() bool {
+				snapshot := load.Snapshot()
+				return len(snapshot) == 1 && snapshot[0].Queued == queued
+			}, time.Second, 5*time.Millisecond)
+		}
+		queue(domain.PriorityBatch, 1)
+		queue(domain.PriorityInteractive, 2)
+
+		release()
+		require.Equal(t, domain.PriorityInteractive, <-acquired)
+		require.Equal(t, domain.PriorityBatch, <-acquired)
+	})
+
+	t.Run("should apply the batch queue timeout to batch requests", func(t *testing.T) {
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"ollama": 1},
+			time.Second, 0, 0, nil, domain.AdaptiveLimits{},
+		)
+		ctx := context.Background()
+
+		release, err := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityBatch)
+		var capacityErr *domain.CapacityError
+		require.ErrorAs(t, err, &capacityErr)
+	})
+}
+
+func TestParsePriority(t *testing.T) {
+	t.Run("should default to interactive", func(t *testing.T) {
+		priority, err := domain.ParsePriority("")
+		require.NoError(t, err)
+		require.Equal(t, domain.PriorityInteractive, priority)
+	})
+
+	t.Run("should parse batch", func(t *testing.T) {
+		priority, err := domain.ParsePriority("batch")
+		require.NoError(t, err)
+		require.Equal(t, domain.PriorityBatch, priority)
+	})
+
+	t.Run("should reject unknown classes", func(t *testing.T) {
+		_, err := domain.ParsePriority("urgent")
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_ConcurrencyLimit(t *testing.T) {
+	t.Run("should hold the slot until the stream ends", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		ch := make(chan domain.StreamChunk, 1)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "llama3").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("ollama")
+		mockProvider.EXPECT().
+			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
+			Return((<-chan domain.StreamChunk)(ch), nil)
+
+		limiter := domain.NewConcurrencyLimiter(map[string]int{"ollama": 1}, 0, 0, 0, nil, domain.AdaptiveLimits{})
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithConcurrencyLimiter(limiter))
+
+		ctx := context.Background()
+		req := &domain.CompletionRequest{Model: "llama3", Stream: true}
+
+		chunks, err := gateway.StreamByModel(ctx, req)
+		require.NoError(t, err)
+
+		_, err = limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+		require.Error(t, err, "slot must be held while streaming")
+
+		ch <- domain.StreamChunk{Delta: "hi", Done: true}
+		close(ch)
+		for chunk := range chunks {
+			require.Equal(t, "hi", chunk.Delta)
+		}
+
+		require.Eventually(t, func() bool {
+			release, acquireErr := limiter.Acquire(ctx, "ollama", "llama3", domain.PriorityInteractive)
+			if acquireErr != nil {
+				return false
+			}
+			release()
+			return true
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should queue requests of batch keys as batch", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "llama3").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("ollama")
+
+		limiter := domain.NewConcurrencyLimiter(
+			map[string]int{"ollama": 1},
+			time.Second, 0, 0, nil, domain.AdaptiveLimits{},
+		)
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithConcurrencyLimiter(limiter),
+			domain.WithKeyPolicies([]domain.KeyPolicy{{Name: "nightly", Keys: []string{"etl"}, Priority: "batch"}}),
+		)
+
+		release, err := limiter.Acquire(context.Background(), "ollama", "llama3", domain.PriorityInteractive)
+		require.NoError(t, err)
+		defer release()
+
+		// The batch queue timeout is zero, so a batch request is rejected at once.
+		ctx := observability.WithClientKey(context.Background(), "etl")
+		_, err = gateway.CompleteByModel(ctx, &domain.CompletionRequest{Model: "llama3"})
+		var capacityErr *domain.CapacityError
+		require.ErrorAs(t, err, &capacityErr)
+	})
+}
@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// ConversationIDPrefix starts every conversation ID.
+	ConversationIDPrefix = "conv_"
+
+	// conversationIDBytes is the entropy of a conversation ID.
+	conversationIDBytes = 12
+)
+
+// ErrUnknownConversation indicates no conversation of the client key exists under the given ID.
+var ErrUnknownConversation = errors.New("unknown conversation")
+
+// Conversation is a message history kept by the gateway, so clients send only
+// each new turn.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"-"`               // Client key that created the conversation
+	Model     string    `json:"model,omitempty"` // Default model of turns that name none
+	Messages  []Message `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConversationStore persists conversations.
+type ConversationStore interface {
+	// Get returns the conversation with id, or ErrUnknownConversation.
+	Get(ctx context.Context, id string) (Conversation, error)
+
+	// Save creates or replaces a conversation.
+	Save(ctx context.Context, conversation Conversation) error
+
+	// Delete removes the conversation with id.
+	Delete(ctx context.Context, id string) error
+}
+
+// InMemoryConversationStore keeps conversations in memory, evicting the least
+// recently updated once it holds its maximum.
+type InMemoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[string]Conversation
+	max           int
+}
+
+// NewInMemoryConversationStore creates a store holding at most maxConversations;
+// 0 is unbounded.
+func NewInMemoryConversationStore(maxConversations int) *InMemoryConversationStore {
+	return &InMemoryConversationStore{
+		mu:            sync.Mutex{},
+		conversations: make(map[string]Conversation),
+		max:           maxConversations,
+	}
+}
+
+// Get returns the conversation with id.
+func (s *InMemoryConversationStore) Get(_ context.Context, id string) (Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, ok := s.conversations[id]
+	if !ok {
+		return Conversation{}, fmt.Errorf("%w: %s", ErrUnknownConversation, id)
+	}
+	conversation.Messages = slices.Clone(conversation.Messages)
+	return conversation, nil
+}
+
+// Save creates or replaces a conversation.
+func (s *InMemoryConversationStore) Save(_ context.Context, conversation Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conversations[conversation.ID]; !ok && s.max > 0 && len(s.conversations) >= s.max {
+		s.evictOldest()
+	}
+	conversation.Messages = slices.Clone(conversation.Messages)
+	s.conversations[conversation.ID] = conversation
+	return nil
+}
+
+// Delete removes the conversation with id.
+func (s *InMemoryConversationStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conversations, id)
+	return nil
+}
+
+// evictOldest removes the least recently updated conversation. Callers hold mu.
+func (s *InMemoryConversationStore) evictOldest() {
+	var oldest string
+	var oldestAt time.Time
+	for id, conversation := range s.conversations {
+		if oldest == "" || conversation.UpdatedAt.Before(oldestAt) {
+			oldest, oldestAt = id, conversation.UpdatedAt
+		}
+	}
+	delete(s.conversations, oldest)
+}
+
+// Conversations runs completions on conversations kept by the gateway: each
+// turn is sent after the stored history, and the turn and its reply are
+// appended once the reply completes. Histories too long for the model are
+// fitted by the gateway's context window strategy, summarized when it is
+// ContextStrategySummarize, while the stored history stays complete.
+type Conversations struct {
+	gateway *GatewayService
+	store   ConversationStore
+
+	// mu serializes history appends, so concurrent turns never overwrite each other.
+	mu sync.Mutex
+}
+
+// NewConversations creates the conversation service over store.
+func NewConversations(gateway *GatewayService, store ConversationStore) *Conversations {
+	return &Conversations{gateway: gateway, store: store, mu: sync.Mutex{}}
+}
+
+// Create starts a conversation of the client key, optionally with a default
+// model and opening messages such as instructions.
+func (c *Conversations) Create(ctx context.Context, model string, messages []Message) (Conversation, error) {
+	if len(messages) > 0 {
+		if err := ValidateMessages(messages); err != nil {
+			return Conversation{}, err
+		}
+	}
+
+	random := make([]byte, conversationIDBytes)
+	if _, err := rand.Read(random); err != nil {
+		return Conversation{}, fmt.Errorf("failed to generate conversation ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	conversation := Conversation{
+		ID:        ConversationIDPrefix + hex.EncodeToString(random),
+		Owner:     observability.GetClientKey(ctx),
+		Model:     model,
+		Messages:  slices.Clone(messages),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := c.store.Save(ctx, conversation); err != nil {
+		return Conversation{}, fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// Get returns a conversation of the client key. Conversations of other keys
+// are reported as unknown.
+func (c *Conversations) Get(ctx context.Context, id string) (Conversation, error) {
+	conversation, err := c.store.Get(ctx, id)
+	if err != nil {
+		return Conversation{}, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	if conversation.Owner != observability.GetClientKey(ctx) {
+		return Conversation{}, fmt.Errorf("failed to load conversation: %w: %s", ErrUnknownConversation, id)
+	}
+	return conversation, nil
+}
+
+// Delete removes a conversation of the client key.
+func (c *Conversations) Delete(ctx context.Context, id string) error {
+	if _, err := c.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := c.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// Complete sends turn, the new messages of a conversation, after its history
+// and appends them and the reply to the history.
+func (c *Conversations) Complete(ctx context.Context, id string, turn *CompletionRequest) (*CompletionResponse, error) {
+	req, err := c.request(ctx, id, turn)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.gateway.CompleteByModel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.append(ctx, id, turn.Messages, response.Content); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Stream streams the reply to turn, the new messages of a conversation, sent
+// after its history. The turn and reply are appended to the history once the
+// stream completes; failed or abandoned streams leave it unchanged.
+func (c *Conversations) Stream(ctx context.Context, id string, turn *CompletionRequest) (<-chan StreamChunk, error) {
+	req, err := c.request(ctx, id, turn)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := c.gateway.StreamByModel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decorateStream(ctx, chunks, streamDecoration{
+		transformers: nil,
+		metadata:     nil,
+		onComplete: func(content string) {
+			if err := c.append(ctx, id, turn.Messages, content); err != nil {
+				observability.FromContext(ctx).Error("failed to append to conversation",
+					observability.String("conversation", id),
+					observability.Error(err),
+				)
+			}
+		},
+	}), nil
+}
+
+// request builds the completion request of turn: its messages follow the
+// conversation history, and the conversation model is used when it names none.
+func (c *Conversations) request(ctx context.Context, id string, turn *CompletionRequest) (*CompletionRequest, error) {
+	conversation, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := *turn
+	req.Messages = append(conversation.Messages, turn.Messages...)
+	if req.Model == "" {
+		req.Model = conversation.Model
+	}
+	return &req, nil
+}
+
+// append adds turn and the assistant reply to the stored history.
+func (c *Conversations) append(ctx context.Context, id string, turn []Message, reply string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conversation, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	conversation.Messages = append(conversation.Messages, turn...)
+	conversation.Messages = append(conversation.Messages,
+		Message{Role: "assistant", Content: reply, ToolCallID: "", ToolCalls: nil})
+	conversation.UpdatedAt = time.Now().UTC()
+	if err = c.store.Save(ctx, conversation); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
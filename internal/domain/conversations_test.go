@@ -0,0 +1,132 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestInMemoryConversationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should evict the least recently updated conversation when full", func(t *testing.T) {
+		store := domain.NewInMemoryConversationStore(2)
+		now := time.Now()
+		require.NoError(t, store.Save(ctx, domain.Conversation{ID: "a", UpdatedAt: now}))
+		require.NoError(t, store.Save(ctx, domain.Conversation{ID: "b", UpdatedAt: now.Add(-time.Minute)}))
+		require.NoError(t, store.Save(ctx, domain.Conversation{ID: "c", UpdatedAt: now}))
+
+		_, err := store.Get(ctx, "b")
+		require.ErrorIs(t, err, domain.ErrUnknownConversation)
+		_, err = store.Get(ctx, "a")
+		require.NoError(t, err)
+	})
+}
+
+func TestConversations(t *testing.T) {
+	ctx := observability.WithClientKey(context.Background(), "team-a")
+	user := func(content string) domain.Message {
+		return domain.Message{Role: "user", Content: content}
+	}
+
+	newConversations := func(t *testing.T) (*domain.Conversations, *mocks.MockProvider) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		provider := mocks.NewMockProvider(t)
+		provider.EXPECT().Name().Return("openai").Maybe()
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(provider, nil).Maybe()
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+		return domain.NewConversations(gateway, domain.NewInMemoryConversationStore(0)), provider
+	}
+
+	t.Run("should send each turn after the history and append it with the reply", func(t *testing.T) {
+		conversations, provider := newConversations(t)
+		instructions := domain.Message{Role: "system", Content: "Be brief."}
+		conversation, err := conversations.Create(ctx, "gpt-4", []domain.Message{instructions})
+		require.NoError(t, err)
+		require.Contains(t, conversation.ID, domain.ConversationIDPrefix)
+
+		provider.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return len(req.Messages) == 2
+		})).Return(&domain.CompletionResponse{Provider: "openai", Content: "Hi."}, nil).Once()
+		provider.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return len(req.Messages) == 4 && req.Messages[2].Content == "Hi."
+		})).Return(&domain.CompletionResponse{Provider: "openai", Content: "Fine."}, nil).Once()
+
+		_, err = conversations.Complete(ctx, conversation.ID,
+			&domain.CompletionRequest{Messages: []domain.Message{user("Hello")}})
+		require.NoError(t, err)
+		_, err = conversations.Complete(ctx, conversation.ID,
+			&domain.CompletionRequest{Messages: []domain.Message{user("How are you?")}})
+		require.NoError(t, err)
+
+		stored, err := conversations.Get(ctx, conversation.ID)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Message{
+			instructions,
+			user("Hello"),
+			{Role: "assistant", Content: "Hi."},
+			user("How are you?"),
+			{Role: "assistant", Content: "Fine."},
+		}, stored.Messages)
+	})
+
+	t.Run("should append streamed replies once the stream completes", func(t *testing.T) {
+		conversations, provider := newConversations(t)
+		conversation, err := conversations.Create(ctx, "gpt-4", nil)
+		require.NoError(t, err)
+
+		upstream := make(chan domain.StreamChunk, 3)
+		upstream <- domain.StreamChunk{Delta: "Hi"}
+		upstream <- domain.StreamChunk{Delta: " there"}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).Return(upstream, nil)
+
+		chunks, err := conversations.Stream(ctx, conversation.ID,
+			&domain.CompletionRequest{Messages: []domain.Message{user("Hello")}, Stream: true})
+		require.NoError(t, err)
+		collect(chunks)
+
+		stored, err := conversations.Get(ctx, conversation.ID)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Message{user("Hello"), {Role: "assistant", Content: "Hi there"}}, stored.Messages)
+	})
+
+	t.Run("should leave the history unchanged when the turn fails", func(t *testing.T) {
+		conversations, provider := newConversations(t)
+		conversation, err := conversations.Create(ctx, "gpt-4", nil)
+		require.NoError(t, err)
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(nil, &domain.ProviderError{Provider: "openai", StatusCode: 500})
+
+		_, err = conversations.Complete(ctx, conversation.ID,
+			&domain.CompletionRequest{Messages: []domain.Message{user("Hello")}})
+		require.Error(t, err)
+
+		stored, err := conversations.Get(ctx, conversation.ID)
+		require.NoError(t, err)
+		require.Empty(t, stored.Messages)
+	})
+
+	t.Run("should hide conversations from other client keys", func(t *testing.T) {
+		conversations, _ := newConversations(t)
+		conversation, err := conversations.Create(ctx, "gpt-4", nil)
+		require.NoError(t, err)
+
+		other := observability.WithClientKey(context.Background(), "team-b")
+		_, err = conversations.Get(other, conversation.ID)
+		require.ErrorIs(t, err, domain.ErrUnknownConversation)
+		require.ErrorIs(t, conversations.Delete(other, conversation.ID), domain.ErrUnknownConversation)
+	})
+}
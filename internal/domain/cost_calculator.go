@@ -5,9 +5,12 @@ import (
 	"errors"
 )
 
-const tokensToPerK = 1000.0
+const (
+	tokensToPerK   = 1000.0
+	charactersPerK = 1000.0
+)
 
-// StandardCostCalculator implements standard token-based cost calculation.
+// StandardCostCalculator implements standard token, character, request, and image based cost calculation.
 type StandardCostCalculator struct {
 	pricingRegistry PricingRegistry
 }
@@ -19,7 +22,7 @@ func NewStandardCostCalculator(registry PricingRegistry) *StandardCostCalculator
 	}
 }
 
-// Calculate computes the total cost based on token usage and model pricing.
+// Calculate computes the total cost of one request based on its usage and model pricing.
 func (c *StandardCostCalculator) Calculate(
 	ctx context.Context,
 	model string,
@@ -36,9 +39,28 @@ func (c *StandardCostCalculator) Calculate(
 		return 0, nil
 	}
 
-	inputCost := float64(usage.PromptTokens) / tokensToPerK * pricing.InputCostPer1K
-	outputCost := float64(usage.CompletionTokens) / tokensToPerK * pricing.OutputCostPer1K
-	totalCost := inputCost + outputCost
+	// Cached prompt tokens are part of PromptTokens but may be billed at a discount.
+	cachedTokens := min(usage.CachedPromptTokens, usage.PromptTokens)
+	cachedRate := pricing.CachedInputCostPer1K
+	if cachedRate == 0 {
+		cachedRate = pricing.InputCostPer1K
+	}
+
+	inputCost := float64(usage.PromptTokens-cachedTokens)/tokensToPerK*pricing.InputCostPer1K +
+		float64(cachedTokens)/tokensToPerK*cachedRate
+	// Reasoning tokens are part of CompletionTokens but may be billed differently.
+	reasoningTokens := min(usage.ReasoningTokens, usage.CompletionTokens)
+	reasoningRate := pricing.ReasoningCostPer1K
+	if reasoningRate == 0 {
+		reasoningRate = pricing.OutputCostPer1K
+	}
+
+	outputCost := float64(usage.CompletionTokens-reasoningTokens)/tokensToPerK*pricing.OutputCostPer1K +
+		float64(reasoningTokens)/tokensToPerK*reasoningRate
+	characterCost := float64(usage.Characters) / charactersPerK * pricing.CharacterCostPer1K
+	// Per-request and per-image prices add to any token-based cost.
+	unitCost := pricing.RequestCost + float64(usage.Images)*pricing.ImageCost
+	totalCost := inputCost + outputCost + characterCost + unitCost
 
 	return totalCost, nil
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
 )
 
 func TestStandardCostCalculator_Calculate(t *testing.T) {
@@ -20,6 +22,21 @@ func TestStandardCostCalculator_Calculate(t *testing.T) {
 	})
 	require.NoError(t, err)
 
+	err = registry.RegisterPricing(ctx, "cached-model", domain.PricingConfig{
+		InputCostPer1K:       0.01,
+		OutputCostPer1K:      0.02,
+		CachedInputCostPer1K: 0.005,
+		ReasoningCostPer1K:   0.04,
+	})
+	require.NoError(t, err)
+
+	err = registry.RegisterPricing(ctx, "unit-model", domain.PricingConfig{
+		InputCostPer1K: 0.01,
+		RequestCost:    0.002,
+		ImageCost:      0.04,
+	})
+	require.NoError(t, err)
+
 	calculator := domain.NewStandardCostCalculator(registry)
 
 	tests := []struct {
@@ -76,6 +93,56 @@ func TestStandardCostCalculator_Calculate(t *testing.T) {
 			expectedCost: 0.0045, // (250/1000 * 0.01) + (100/1000 * 0.02)
 			expectError:  false,
 		},
+		{
+			name:  "cached prompt tokens billed at the cached rate",
+			model: "cached-model",
+			usage: domain.Usage{
+				PromptTokens:       1000,
+				CompletionTokens:   500,
+				CachedPromptTokens: 600,
+			},
+			expectedCost: 0.017, // (400/1000 * 0.01) + (600/1000 * 0.005) + (500/1000 * 0.02)
+			expectError:  false,
+		},
+		{
+			name:  "reasoning tokens billed at the reasoning rate",
+			model: "cached-model",
+			usage: domain.Usage{
+				PromptTokens:     1000,
+				CompletionTokens: 500,
+				ReasoningTokens:  250,
+			},
+			expectedCost: 0.025, // (1000/1000 * 0.01) + (250/1000 * 0.02) + (250/1000 * 0.04)
+			expectError:  false,
+		},
+		{
+			name:  "cached prompt tokens without a cached rate billed as input",
+			model: "test-model",
+			usage: domain.Usage{
+				PromptTokens:       1000,
+				CompletionTokens:   500,
+				CachedPromptTokens: 600,
+			},
+			expectedCost: 0.02, // (1000/1000 * 0.01) + (500/1000 * 0.02)
+			expectError:  false,
+		},
+		{
+			name:  "request and image prices added to token cost",
+			model: "unit-model",
+			usage: domain.Usage{
+				PromptTokens: 500,
+				Images:       2,
+			},
+			expectedCost: 0.087, // (500/1000 * 0.01) + 0.002 + (2 * 0.04)
+			expectError:  false,
+		},
+		{
+			name:         "request price charged without tokens",
+			model:        "unit-model",
+			usage:        domain.Usage{},
+			expectedCost: 0.002,
+			expectError:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -149,3 +216,23 @@ func TestInMemoryPricingRegistry_RegisterAndGet(t *testing.T) {
 		require.InDelta(t, config2.OutputCostPer1K, retrieved.OutputCostPer1K, 0.0001)
 	})
 }
+
+func TestUnpricedModels(t *testing.T) {
+	t.Run("should list models of registered providers without pricing", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"openai"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(mockProvider, nil)
+		mockProvider.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4o", "gpt-4", "o1"})
+
+		pricing := domain.NewInMemoryPricingRegistry()
+		require.NoError(t, pricing.RegisterPricing(ctx, "gpt-4", domain.PricingConfig{InputCostPer1K: 0.03}))
+
+		unpriced, err := domain.UnpricedModels(ctx, mockRegistry, pricing)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"openai/gpt-4o", "openai/o1"}, unpriced)
+	})
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// UsageSummary condenses recorded usage for the admin dashboard.
+type UsageSummary struct {
+	Requests     int              `json:"requests"`
+	Cost         float64          `json:"cost"`
+	CacheHitRate float64          `json:"cache_hit_rate"` // share of prompt tokens served from provider caches
+	SpendByModel []UsageAggregate `json:"spend_by_model"` // most expensive first
+	Recent       []UsageRecord    `json:"recent"`         // newest first
+}
+
+// SummarizeUsage summarizes records, oldest first, keeping the latest recent of them.
+func SummarizeUsage(records []UsageRecord, recent int) UsageSummary {
+	summary := UsageSummary{
+		Requests:     0,
+		Cost:         0,
+		CacheHitRate: 0,
+		SpendByModel: nil,
+		Recent:       nil,
+	}
+
+	promptTokens, cachedTokens := 0, 0
+	for _, record := range records {
+		summary.Requests += record.requests()
+		summary.Cost += record.Cost
+		promptTokens += record.PromptTokens
+		cachedTokens += min(record.CachedTokens, record.PromptTokens)
+	}
+	if promptTokens > 0 {
+		summary.CacheHitRate = float64(cachedTokens) / float64(promptTokens)
+	}
+
+	// Grouping by model cannot fail.
+	summary.SpendByModel, _ = AggregateUsage(records, GroupByModel)
+	sort.SliceStable(summary.SpendByModel, func(i, j int) bool {
+		return summary.SpendByModel[i].Cost > summary.SpendByModel[j].Cost
+	})
+
+	summary.Recent = slices.Clone(records[max(len(records)-recent, 0):])
+	slices.Reverse(summary.Recent)
+
+	return summary
+}
+
+// UsageSummary summarizes the recorded usage matching filter, keeping the
+// latest recent records.
+func (g *GatewayService) UsageSummary(ctx context.Context, filter UsageFilter, recent int) (UsageSummary, error) {
+	if g.usage == nil {
+		return UsageSummary{}, ErrUsageUnavailable
+	}
+
+	records, err := g.usage.Query(ctx, filter)
+	if err != nil {
+		return UsageSummary{}, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	return SummarizeUsage(records, recent), nil
+}
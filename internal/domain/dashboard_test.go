@@ -0,0 +1,50 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestSummarizeUsage(t *testing.T) {
+	records := []domain.UsageRecord{
+		{RequestID: "1", Model: "gpt-4", PromptTokens: 100, CachedTokens: 50, TotalTokens: 120, Cost: 0.5},
+		{RequestID: "2", Model: "gpt-3.5-turbo", PromptTokens: 100, TotalTokens: 110, Cost: 0.1},
+		{RequestID: "3", Model: "gpt-4", PromptTokens: 200, CachedTokens: 50, TotalTokens: 250, Cost: 1},
+	}
+
+	t.Run("should total requests, spend, and cache hits", func(t *testing.T) {
+		summary := domain.SummarizeUsage(records, 2)
+
+		require.Equal(t, 3, summary.Requests)
+		require.InDelta(t, 1.6, summary.Cost, 1e-9)
+		require.InDelta(t, 0.25, summary.CacheHitRate, 1e-9)
+	})
+
+	t.Run("should rank models by spend", func(t *testing.T) {
+		summary := domain.SummarizeUsage(records, 2)
+
+		require.Len(t, summary.SpendByModel, 2)
+		require.Equal(t, "gpt-4", summary.SpendByModel[0].Group)
+		require.Equal(t, 2, summary.SpendByModel[0].Requests)
+	})
+
+	t.Run("should keep the latest requests newest first", func(t *testing.T) {
+		summary := domain.SummarizeUsage(records, 2)
+
+		require.Len(t, summary.Recent, 2)
+		require.Equal(t, "3", summary.Recent[0].RequestID)
+		require.Equal(t, "2", summary.Recent[1].RequestID)
+		require.Equal(t, "1", records[0].RequestID, "records must not be reordered")
+	})
+
+	t.Run("should summarize no usage", func(t *testing.T) {
+		summary := domain.SummarizeUsage(nil, 20)
+
+		require.Zero(t, summary.Requests)
+		require.Zero(t, summary.CacheHitRate)
+		require.Empty(t, summary.Recent)
+	})
+}
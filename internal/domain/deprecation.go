@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MetadataRetiredModel names the retired model a request was routed away from.
+const MetadataRetiredModel = "retired_model"
+
+// Deprecation marks a model deprecated. Until its sunset, requests for the model
+// are served as usual and only warned about; from the sunset on they are routed to
+// the replacement, or rejected when there is none.
+type Deprecation struct {
+	Model       string    `json:"model"`
+	Sunset      time.Time `json:"sunset"`
+	Replacement string    `json:"replacement,omitempty"`
+}
+
+// ParseDeprecations builds model deprecations from specs written as
+// "sunset:replacement", where sunset is a YYYY-MM-DD date (UTC) and the
+// replacement is optional, e.g. "2026-06-30:gpt-4o".
+func ParseDeprecations(specs map[string]string) (map[string]Deprecation, error) {
+	deprecations := make(map[string]Deprecation, len(specs))
+	for model, spec := range specs {
+		date, replacement, _ := strings.Cut(spec, ":")
+		sunset, err := time.Parse(time.DateOnly, strings.TrimSpace(date))
+		if err != nil {
+			return nil, fmt.Errorf("deprecation of %s must be sunset:replacement with a YYYY-MM-DD sunset, got %q",
+				model, spec)
+		}
+
+		replacement = strings.TrimSpace(replacement)
+		if replacement == model {
+			return nil, fmt.Errorf("deprecation of %s names the model as its own replacement", model)
+		}
+		deprecations[model] = Deprecation{Model: model, Sunset: sunset, Replacement: replacement}
+	}
+	return deprecations, nil
+}
+
+// WithDeprecations warns about deprecated models and routes them to their
+// replacements once their sunset passes.
+func WithDeprecations(deprecations map[string]Deprecation) GatewayOption {
+	return func(g *GatewayService) {
+		g.deprecations = deprecations
+	}
+}
+
+// Deprecation returns the deprecation of a requested model, or of the model the
+// requested alias routes to.
+func (g *GatewayService) Deprecation(model string) (Deprecation, bool) {
+	if deprecation, ok := g.deprecations[model]; ok {
+		return deprecation, true
+	}
+	if target, ok := g.resolveAlias(model); ok {
+		deprecation, ok := g.deprecations[target]
+		return deprecation, ok
+	}
+	return Deprecation{}, false
+}
+
+// retireModel routes a request for a model past its sunset to the replacement,
+// rejecting it with a ModelRetiredError when there is none.
+func (g *GatewayService) retireModel(req *CompletionRequest, now time.Time) (map[string]string, error) {
+	deprecation, ok := g.deprecations[req.Model]
+	if !ok || now.Before(deprecation.Sunset) {
+		return nil, nil
+	}
+	if deprecation.Replacement == "" {
+		return nil, &ModelRetiredError{Model: deprecation.Model, Sunset: deprecation.Sunset}
+	}
+
+	req.Model = deprecation.Replacement
+	return map[string]string{MetadataRetiredModel: deprecation.Model}, nil
+}
@@ -0,0 +1,121 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestParseDeprecations(t *testing.T) {
+	t.Run("should parse a sunset with an optional replacement", func(t *testing.T) {
+		deprecations, err := domain.ParseDeprecations(map[string]string{
+			"gpt-4":         "2026-06-30:gpt-4o",
+			"gpt-3.5-turbo": "2026-01-01",
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, domain.Deprecation{
+			Model:       "gpt-4",
+			Sunset:      time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+			Replacement: "gpt-4o",
+		}, deprecations["gpt-4"])
+		require.Empty(t, deprecations["gpt-3.5-turbo"].Replacement)
+	})
+
+	t.Run("should reject an invalid sunset", func(t *testing.T) {
+		_, err := domain.ParseDeprecations(map[string]string{"gpt-4": "June:gpt-4o"})
+		require.ErrorContains(t, err, "gpt-4")
+	})
+
+	t.Run("should reject a model replacing itself", func(t *testing.T) {
+		_, err := domain.ParseDeprecations(map[string]string{"gpt-4": "2026-06-30:gpt-4"})
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_Deprecations(t *testing.T) {
+	past := time.Now().AddDate(0, 0, -1)
+	future := time.Now().AddDate(0, 1, 0)
+	request := func(model string) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:    model,
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+	}
+
+	t.Run("should report the deprecation of a model and of an alias routing to it", func(t *testing.T) {
+		deprecation := domain.Deprecation{Model: "gpt-4", Sunset: future, Replacement: "gpt-4o"}
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithModelAliases(map[string]string{"smart": "gpt-4"}),
+			domain.WithDeprecations(map[string]domain.Deprecation{"gpt-4": deprecation}))
+
+		got, ok := gateway.Deprecation("gpt-4")
+		require.True(t, ok)
+		require.Equal(t, deprecation, got)
+
+		got, ok = gateway.Deprecation("smart")
+		require.True(t, ok)
+		require.Equal(t, deprecation, got)
+
+		_, ok = gateway.Deprecation("gpt-4o")
+		require.False(t, ok)
+	})
+
+	t.Run("should keep serving a deprecated model before its sunset", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithDeprecations(map[string]domain.Deprecation{
+				"gpt-4": {Model: "gpt-4", Sunset: future, Replacement: "gpt-4o"},
+			}))
+
+		result, err := gateway.DryRun(context.Background(), "", request("gpt-4"))
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4", result.Model)
+	})
+
+	t.Run("should route a model past its sunset to the replacement", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4o", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModelAliases(map[string]string{"smart": "gpt-4"}),
+			domain.WithDeprecations(map[string]domain.Deprecation{
+				"gpt-4": {Model: "gpt-4", Sunset: past, Replacement: "gpt-4o"},
+			}))
+
+		result, err := gateway.DryRun(context.Background(), "", request("smart"))
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4o", result.Model)
+	})
+
+	t.Run("should reject a model past its sunset without a replacement", func(t *testing.T) {
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithDeprecations(map[string]domain.Deprecation{
+				"gpt-3.5-turbo": {Model: "gpt-3.5-turbo", Sunset: past},
+			}))
+
+		_, err := gateway.CompleteByModel(context.Background(), request("gpt-3.5-turbo"))
+
+		var retiredErr *domain.ModelRetiredError
+		require.ErrorAs(t, err, &retiredErr)
+		require.Equal(t, "gpt-3.5-turbo", retiredErr.Model)
+	})
+}
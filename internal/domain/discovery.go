@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ModelDiscovery periodically refreshes the model lists of providers that
+// implement ModelRefresher and reindexes them in the registry, so newly
+// released models route without a redeploy.
+type ModelDiscovery struct {
+	registry ProviderRegistry
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// NewModelDiscovery creates a model discovery job.
+func NewModelDiscovery(registry ProviderRegistry, interval, timeout time.Duration) *ModelDiscovery {
+	return &ModelDiscovery{
+		registry: registry,
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+// Run refreshes all providers immediately and then on the configured interval
+// until ctx is done.
+func (d *ModelDiscovery) Run(ctx context.Context) {
+	if d.interval <= 0 {
+		return
+	}
+
+	d.RefreshAll(ctx)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.RefreshAll(ctx)
+		}
+	}
+}
+
+// RefreshAll refreshes every registered provider once.
+func (d *ModelDiscovery) RefreshAll(ctx context.Context) {
+	names, err := d.registry.List(ctx)
+	if err != nil {
+		observability.FromContext(ctx).Error("failed to list providers for model discovery", observability.Error(err))
+		return
+	}
+
+	for _, name := range names {
+		provider, getErr := d.registry.Get(ctx, name)
+		if getErr != nil {
+			continue
+		}
+
+		refresher, ok := provider.(ModelRefresher)
+		if !ok {
+			continue
+		}
+
+		d.refresh(ctx, provider, refresher)
+	}
+}
+
+func (d *ModelDiscovery) refresh(ctx context.Context, provider Provider, refresher ModelRefresher) {
+	name := provider.Name()
+	logger := observability.FromContext(ctx).With(observability.String("provider", name))
+
+	refreshCtx := ctx
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		refreshCtx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	// A failed refresh keeps the previous model list.
+	if err := refresher.RefreshModels(refreshCtx); err != nil {
+		observability.ModelDiscoveryRefreshes.WithLabelValues(name, "error").Inc()
+		logger.Warn("model discovery failed", observability.Error(err))
+		return
+	}
+
+	if err := d.registry.Reindex(ctx, name); err != nil {
+		observability.ModelDiscoveryRefreshes.WithLabelValues(name, "error").Inc()
+		logger.Error("failed to reindex provider models", observability.Error(err))
+		return
+	}
+
+	models := len(provider.SupportedModels(ctx))
+	observability.ModelDiscoveryRefreshes.WithLabelValues(name, "success").Inc()
+	observability.ProviderModels.WithLabelValues(name).Set(float64(models))
+	logger.Debug("provider models refreshed", observability.Int("models", models))
+}
@@ -0,0 +1,68 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// refreshingProvider adds a scripted RefreshModels to a mock provider.
+type refreshingProvider struct {
+	*mocks.MockProvider
+	err       error
+	refreshes int
+}
+
+func (p *refreshingProvider) RefreshModels(_ context.Context) error {
+	p.refreshes++
+	return p.err
+}
+
+func TestModelDiscovery_RefreshAll(t *testing.T) {
+	t.Run("should reindex providers after a successful refresh", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		provider := &refreshingProvider{MockProvider: mocks.NewMockProvider(t), err: nil, refreshes: 0}
+		provider.EXPECT().Name().Return("openai")
+		provider.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4", "gpt-5"})
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"openai"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(provider, nil)
+		mockRegistry.EXPECT().Reindex(mock.Anything, "openai").Return(nil).Once()
+
+		domain.NewModelDiscovery(mockRegistry, 0, 0).RefreshAll(ctx)
+
+		require.Equal(t, 1, provider.refreshes)
+	})
+
+	t.Run("should keep the previous index when a refresh fails", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		provider := &refreshingProvider{MockProvider: mocks.NewMockProvider(t), err: errors.New("down"), refreshes: 0}
+		provider.EXPECT().Name().Return("openai")
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"openai"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(provider, nil)
+
+		domain.NewModelDiscovery(mockRegistry, 0, 0).RefreshAll(ctx)
+
+		require.Equal(t, 1, provider.refreshes)
+	})
+
+	t.Run("should skip providers without discovery", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"echo"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "echo").Return(mockProvider, nil)
+
+		domain.NewModelDiscovery(mockRegistry, 0, 0).RefreshAll(ctx)
+	})
+}
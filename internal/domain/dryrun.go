@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// DryRunResult describes how a request would be served without calling the provider.
+type DryRunResult struct {
+	Provider         string            `json:"provider"`
+	Model            string            `json:"model"`
+	PromptTokens     int               `json:"estimated_prompt_tokens"`
+	CompletionTokens int               `json:"max_completion_tokens"` // 0 when max_tokens is unset
+	EstimatedCost    float64           `json:"estimated_cost"`        // prompt cost plus max_completion_tokens
+	Currency         string            `json:"currency,omitempty"`    // set when EstimatedCost is a chargeback amount
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// DryRun runs validation, key policies, parameter limits, routing, and history
+// trimming for a request, then estimates its tokens and cost instead of calling
+// the provider. An empty providerName routes by model. Pre-flight moderation is
+// skipped since it calls a provider too.
+func (g *GatewayService) DryRun(
+	ctx context.Context,
+	providerName string,
+	req *CompletionRequest,
+) (*DryRunResult, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if req.Model == "" {
+		return nil, errors.New("model cannot be empty")
+	}
+
+	req, metadata, err := g.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.validate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	var provider Provider
+	if providerName != "" {
+		provider, err = g.providerFor(ctx, providerName, req.Model)
+	} else {
+		provider, err = g.route(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.admit(ctx, provider); err != nil {
+		return nil, err
+	}
+	if err := g.enforceRoleOrder(provider, req); err != nil {
+		return nil, err
+	}
+
+	req, trim, err := g.fitContext(ctx, nil, req)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := Usage{
+		PromptTokens:     CountMessagesTokens(g.tokenizers.ForModel(req.Model), req.Messages),
+		CompletionTokens: req.MaxTokens * max(req.N, 1),
+		TotalTokens:      0,
+		Cost:             0,
+
+		CachedPromptTokens: 0,
+		ReasoningTokens:    0,
+		Characters:         0,
+		Images:             0,
+		ProviderCost:       0,
+		Currency:           "",
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	// Unknown pricing leaves the estimate at zero, as for real completions.
+	g.price(ctx, req.Model, &usage)
+
+	return &DryRunResult{
+		Provider:         provider.Name(),
+		Model:            req.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		EstimatedCost:    usage.Cost,
+		Currency:         usage.Currency,
+		Metadata:         mergeMetadata(metadata, trimMetadata(trim)),
+	}, nil
+}
@@ -0,0 +1,62 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestGatewayService_DryRun(t *testing.T) {
+	t.Run("should report routing and estimated cost without calling the provider", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockCostCalc.EXPECT().
+			Calculate(mock.Anything, "gpt-4o", mock.MatchedBy(func(usage domain.Usage) bool {
+				return usage.CompletionTokens == 200 && usage.PromptTokens > 0
+			})).
+			Return(0.02, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModelAliases(map[string]string{"support-bot": "gpt-4o"}))
+
+		result, err := gateway.DryRun(context.Background(), "", &domain.CompletionRequest{
+			Model:     "support-bot",
+			Messages:  []domain.Message{{Role: "user", Content: "Hello there"}},
+			MaxTokens: 100,
+			N:         2,
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, "openai", result.Provider)
+		require.Equal(t, "gpt-4o", result.Model)
+		require.Positive(t, result.PromptTokens)
+		require.Equal(t, 200, result.CompletionTokens)
+		require.InDelta(t, 0.02, result.EstimatedCost, 1e-9)
+	})
+
+	t.Run("should reject requests that violate a key policy", func(t *testing.T) {
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithKeyPolicies([]domain.KeyPolicy{
+				{Name: "interns", Keys: []string{"intern"}, AllowModels: []string{"gpt-3.5-turbo"}},
+			}))
+
+		ctx := observability.WithClientKey(context.Background(), "intern")
+		_, err := gateway.DryRun(ctx, "", &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+	})
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const judgeSystemPrompt = "You are a judge comparing candidate answers to the same conversation. " +
+	"Combine their correct parts into a single best final answer. " +
+	"Reply with the final answer only."
+
+// EnsembleRequest asks several models the same question.
+type EnsembleRequest struct {
+	Models      []string          `json:"models"`
+	JudgeModel  string            `json:"judge_model,omitempty"`
+	Messages    []Message         `json:"messages"`
+	Temperature float64           `json:"temperature,omitempty"`
+	MaxTokens   int               `json:"max_tokens,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// EnsembleAnswer is the outcome of one ensemble member.
+type EnsembleAnswer struct {
+	Model    string              `json:"model"`
+	Response *CompletionResponse `json:"response,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// EnsembleResponse holds every member answer, the optional judged answer,
+// and the usage summed across all calls (members and judge).
+type EnsembleResponse struct {
+	Answers []EnsembleAnswer    `json:"answers"`
+	Final   *CompletionResponse `json:"final,omitempty"`
+	Usage   Usage               `json:"usage"`
+}
+
+// CompleteEnsemble queries all requested models concurrently. When a judge model
+// is set, it synthesizes a final answer from the successful member answers.
+// The call fails only when every member fails.
+func (g *GatewayService) CompleteEnsemble(ctx context.Context, req *EnsembleRequest) (*EnsembleResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if len(req.Models) == 0 {
+		return nil, errors.New("at least one model is required")
+	}
+
+	answers := make([]EnsembleAnswer, len(req.Models))
+
+	var wg sync.WaitGroup
+	for i, model := range req.Models {
+		wg.Go(func() {
+			answers[i] = g.ensembleMember(ctx, req, model)
+		})
+	}
+	wg.Wait()
+
+	response := &EnsembleResponse{
+		Answers: answers,
+		Final:   nil,
+		Usage:   Usage{},
+	}
+
+	successful := 0
+	for _, answer := range answers {
+		if answer.Response != nil {
+			successful++
+			addUsage(&response.Usage, answer.Response.Usage)
+		}
+	}
+
+	if successful == 0 {
+		return nil, fmt.Errorf("all %d ensemble models failed: %s", len(answers), answers[0].Error)
+	}
+
+	if req.JudgeModel == "" {
+		return response, nil
+	}
+
+	final, err := g.CompleteByModel(ctx, &CompletionRequest{
+		Model:       req.JudgeModel,
+		Messages:    judgeMessages(req.Messages, answers),
+		Temperature: 0,
+		MaxTokens:   req.MaxTokens,
+		Stream:      false,
+		Metadata:    req.Metadata,
+
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("judge model %s failed: %w", req.JudgeModel, err)
+	}
+
+	response.Final = final
+	addUsage(&response.Usage, final.Usage)
+
+	return response, nil
+}
+
+// ensembleMember runs the request against a single model.
+func (g *GatewayService) ensembleMember(ctx context.Context, req *EnsembleRequest, model string) EnsembleAnswer {
+	resp, err := g.CompleteByModel(ctx, &CompletionRequest{
+		Model:       model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      false,
+		Metadata:    req.Metadata,
+
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	})
+	if err != nil {
+		return EnsembleAnswer{Model: model, Response: nil, Error: err.Error()}
+	}
+	return EnsembleAnswer{Model: model, Response: resp, Error: ""}
+}
+
+// judgeMessages builds the judge prompt from the conversation and member answers.
+func judgeMessages(conversation []Message, answers []EnsembleAnswer) []Message {
+	var builder strings.Builder
+
+	builder.WriteString("Conversation:\n")
+	for _, msg := range conversation {
+		fmt.Fprintf(&builder, "[%s]: %s\n", msg.Role, msg.Content)
+	}
+
+	builder.WriteString("\nCandidate answers:\n")
+	candidate := 0
+	for _, answer := range answers {
+		if answer.Response == nil {
+			continue
+		}
+		candidate++
+		fmt.Fprintf(&builder, "\n--- Answer %d (%s) ---\n%s\n", candidate, answer.Model, answer.Response.Content)
+	}
+
+	return []Message{
+		{Role: "system", Content: judgeSystemPrompt, ToolCallID: "", ToolCalls: nil},
+		{Role: "user", Content: builder.String(), ToolCallID: "", ToolCalls: nil},
+	}
+}
+
+// addUsage accumulates usage into total.
+func addUsage(total *Usage, usage Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	total.CachedPromptTokens += usage.CachedPromptTokens
+	total.ReasoningTokens += usage.ReasoningTokens
+	total.ProviderCost += usage.ProviderCost
+	total.Currency = usage.Currency
+	total.Cost += usage.Cost
+}
@@ -0,0 +1,110 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_CompleteEnsemble(t *testing.T) {
+	newResponse := func(model, content string) *domain.CompletionResponse {
+		return &domain.CompletionResponse{
+			ID:       model + "-id",
+			Model:    model,
+			Provider: "test-provider",
+			Content:  content,
+			Usage: domain.Usage{
+				PromptTokens:     10,
+				CompletionTokens: 5,
+				TotalTokens:      15,
+			},
+		}
+	}
+
+	isModel := func(model string) any {
+		return mock.MatchedBy(func(req *domain.CompletionRequest) bool { return req.Model == model })
+	}
+
+	t.Run("should return all answers with combined usage and judged final answer", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, mock.Anything).Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, isModel("model-a")).Return(newResponse("model-a", "A"), nil)
+		mockProvider.EXPECT().Complete(mock.Anything, isModel("model-b")).Return(newResponse("model-b", "B"), nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.Model == "judge" &&
+					strings.Contains(req.Messages[1].Content, "(model-a)") &&
+					strings.Contains(req.Messages[1].Content, "(model-b)")
+			})).
+			Return(newResponse("judge", "Final"), nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+
+		response, err := gateway.CompleteEnsemble(context.Background(), &domain.EnsembleRequest{
+			Models:     []string{"model-a", "model-b"},
+			JudgeModel: "judge",
+			Messages:   []domain.Message{{Role: "user", Content: "Question"}},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, response.Answers, 2)
+		require.Equal(t, "A", response.Answers[0].Response.Content)
+		require.Equal(t, "B", response.Answers[1].Response.Content)
+		require.Equal(t, "Final", response.Final.Content)
+		require.Equal(t, 45, response.Usage.TotalTokens)
+		require.InDelta(t, 0.03, response.Usage.Cost, 1e-9)
+	})
+
+	t.Run("should report member failures alongside successful answers", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "model-a").Return(mockProvider, nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "missing").Return(nil, errors.New("no provider"))
+		mockProvider.EXPECT().Complete(mock.Anything, isModel("model-a")).Return(newResponse("model-a", "A"), nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+
+		response, err := gateway.CompleteEnsemble(context.Background(), &domain.EnsembleRequest{
+			Models:   []string{"model-a", "missing"},
+			Messages: []domain.Message{{Role: "user", Content: "Question"}},
+		})
+
+		require.NoError(t, err)
+		require.Nil(t, response.Final)
+		require.Nil(t, response.Answers[1].Response)
+		require.Contains(t, response.Answers[1].Error, "provider routing failed")
+		require.Equal(t, 15, response.Usage.TotalTokens)
+	})
+
+	t.Run("should fail when every model fails", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "missing").Return(nil, errors.New("no provider"))
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+
+		response, err := gateway.CompleteEnsemble(context.Background(), &domain.EnsembleRequest{
+			Models:   []string{"missing"},
+			Messages: []domain.Message{{Role: "user", Content: "Question"}},
+		})
+
+		require.Error(t, err)
+		require.Nil(t, response)
+		require.Contains(t, err.Error(), "all 1 ensemble models failed")
+	})
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CapacityError indicates a request was rejected because a concurrency limit
+// was reached. Clients should retry after RetryAfter.
+type CapacityError struct {
+	Scope      string        // Limit that was hit, e.g. "openai" or "openai/gpt-4"
+	RetryAfter time.Duration // Suggested client backoff
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("concurrency limit reached for %s", e.Scope)
+}
+
+// ModerationError indicates a prompt was blocked by pre-flight moderation.
+type ModerationError struct {
+	Categories []string // Flagged categories, e.g. "harassment"
+}
+
+func (e *ModerationError) Error() string {
+	if len(e.Categories) == 0 {
+		return "prompt blocked by content moderation"
+	}
+	return "prompt blocked by content moderation: " + strings.Join(e.Categories, ", ")
+}
+
+// GuardrailError indicates a request was blocked by a guardrail.
+type GuardrailError struct {
+	Guardrail string // Name of the blocking guardrail
+	Err       error  // Reason given by the guardrail
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("request blocked by guardrail %s: %v", e.Guardrail, e.Err)
+}
+
+func (e *GuardrailError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedParameterError indicates a provider cannot honor a request parameter.
+type UnsupportedParameterError struct {
+	Provider  string // Provider that rejected the parameter
+	Parameter string // Request field name, e.g. "logit_bias"
+}
+
+func (e *UnsupportedParameterError) Error() string {
+	return fmt.Sprintf("parameter %s is not supported by provider %s", e.Parameter, e.Provider)
+}
+
+// UnsupportedGroupingError indicates a usage report was requested with an unknown grouping.
+type UnsupportedGroupingError struct {
+	GroupBy string
+}
+
+func (e *UnsupportedGroupingError) Error() string {
+	return fmt.Sprintf("unsupported group_by %q", e.GroupBy)
+}
+
+// PolicyError indicates a client key's policy does not allow the requested model
+// or provider, or the region of the provider.
+type PolicyError struct {
+	Policy string // Name of the violated policy
+	Kind   string // "model", "provider", or "region"
+	Name   string // Rejected model, provider, or region
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s %s is not allowed by policy %s", e.Kind, e.Name, e.Policy)
+}
+
+// ParameterLimitError indicates a request parameter exceeds an operator-configured limit.
+type ParameterLimitError struct {
+	Parameter string // Request field name, e.g. "max_tokens"
+	Limit     string // Allowed value or "min:max" range
+}
+
+func (e *ParameterLimitError) Error() string {
+	return fmt.Sprintf("parameter %s exceeds the configured limit %s", e.Parameter, e.Limit)
+}
+
+// ModelNotSupportedError indicates a request forced a provider that does not serve the model.
+type ModelNotSupportedError struct {
+	Provider string
+	Model    string
+}
+
+func (e *ModelNotSupportedError) Error() string {
+	return fmt.Sprintf("provider %s does not support model %s", e.Provider, e.Model)
+}
+
+// ModelRetiredError indicates a request for a model past its sunset date that has
+// no replacement.
+type ModelRetiredError struct {
+	Model  string
+	Sunset time.Time
+}
+
+func (e *ModelRetiredError) Error() string {
+	return fmt.Sprintf("model %s was retired on %s", e.Model, e.Sunset.Format(time.DateOnly))
+}
+
+// QuotaExceededError indicates a client key has used up one of its quota limits.
+// Clients should retry after RetryAfter, when the quota period resets.
+type QuotaExceededError struct {
+	Key        string        // Client key name
+	Limit      string        // Exhausted limit, e.g. "requests_per_day"
+	RetryAfter time.Duration // Time until the quota period resets
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota %s exceeded for key %q", e.Limit, e.Key)
+}
+
+// ContextWindowError indicates a prompt does not fit the model context window,
+// even after any configured trimming.
+type ContextWindowError struct {
+	Model        string
+	PromptTokens int // Estimated prompt tokens
+	Limit        int // Prompt tokens available once the completion is reserved
+}
+
+func (e *ContextWindowError) Error() string {
+	return fmt.Sprintf("prompt of about %d tokens exceeds the %d tokens available in the context window of %s",
+		e.PromptTokens, e.Limit, e.Model)
+}
+
+// ProviderError indicates an upstream provider answered a request with an error
+// status. Clients should retry after RetryAfter when it is set.
+type ProviderError struct {
+	Provider   string
+	StatusCode int               // Upstream HTTP status
+	Code       string            // Upstream error code, e.g. "context_length_exceeded"; empty when absent
+	RetryAfter time.Duration     // Upstream Retry-After, 0 when absent
+	Headers    map[string]string // Upstream Retry-After and rate-limit headers
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("provider %s returned status %d: %v", e.Provider, e.StatusCode, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError indicates a request field is invalid.
+type ValidationError struct {
+	Param   string // Invalid field, e.g. "messages[2].role"
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Param + ": " + e.Message
+}
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// JudgeEvaluatorName names the built-in judge model evaluator.
+const JudgeEvaluatorName = "judge"
+
+const judgeInstructions = "Rate how well the response below answers the conversation before it, " +
+	"from 0 (wrong, unhelpful, or off-topic) to 10 (correct, complete, and clear). Reply with the number only."
+
+// Evaluation scores a sample of completed responses with evaluators. Scoring
+// runs in the background after the response is returned; the usage record of a
+// sampled response is stored once its scores are in.
+type Evaluation struct {
+	evaluators []Evaluator
+	percent    float64
+	timeout    time.Duration
+	slots      chan struct{}
+}
+
+// NewEvaluation creates a response evaluation sampler scoring percent, 0-100,
+// of responses. At most maxInFlight samples are scored at once; samples beyond
+// that are recorded unscored so evaluation never queues behind itself.
+func NewEvaluation(evaluators []Evaluator, percent float64, timeout time.Duration, maxInFlight int) *Evaluation {
+	return &Evaluation{
+		evaluators: evaluators,
+		percent:    percent,
+		timeout:    timeout,
+		slots:      make(chan struct{}, max(maxInFlight, 1)),
+	}
+}
+
+// WithEvaluation scores a sample of completed responses for quality monitoring.
+func WithEvaluation(evaluation *Evaluation) GatewayOption {
+	return func(g *GatewayService) {
+		g.evaluation = evaluation
+	}
+}
+
+// sampled reports whether the current response should be scored.
+func (e *Evaluation) sampled() bool {
+	//nolint:gosec // Sampling does not need a cryptographic source
+	return len(e.evaluators) > 0 && e.percent > 0 && rand.Float64()*100 < e.percent
+}
+
+// recordEvaluatedUsage records the usage of a completed response, scoring it
+// first when it is sampled for evaluation. Quotas, alerts, and tenant limits
+// observe the usage immediately either way.
+func (g *GatewayService) recordEvaluatedUsage(
+	ctx context.Context,
+	record UsageRecord,
+	req *CompletionRequest,
+	content string,
+) {
+	if g.evaluation == nil || !g.evaluation.sampled() {
+		g.recordUsage(ctx, record)
+		return
+	}
+
+	select {
+	case g.evaluation.slots <- struct{}{}:
+	default:
+		for _, evaluator := range g.evaluation.evaluators {
+			observability.Evaluations.WithLabelValues(evaluator.Name(), "dropped").Inc()
+		}
+		g.recordUsage(ctx, record)
+		return
+	}
+
+	record = g.observeUsage(ctx, record)
+
+	// Scoring must outlive the client request but not the configured timeout.
+	evalCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-g.evaluation.slots }()
+
+		if g.evaluation.timeout > 0 {
+			var cancel context.CancelFunc
+			evalCtx, cancel = context.WithTimeout(evalCtx, g.evaluation.timeout)
+			defer cancel()
+		}
+
+		record.Scores = g.evaluate(evalCtx, record, req, content)
+		g.storeUsage(evalCtx, record)
+	}()
+}
+
+// evaluate scores content with every evaluator, returning the scores by
+// evaluator name. Failing evaluators are logged and left out.
+func (g *GatewayService) evaluate(
+	ctx context.Context,
+	record UsageRecord,
+	req *CompletionRequest,
+	content string,
+) map[string]float64 {
+	scores := make(map[string]float64, len(g.evaluation.evaluators))
+	for _, evaluator := range g.evaluation.evaluators {
+		score, err := evaluator.Evaluate(ctx, req, content)
+		if err != nil {
+			observability.Evaluations.WithLabelValues(evaluator.Name(), "error").Inc()
+			observability.FromContext(ctx).Warn("response evaluation failed",
+				observability.String("evaluator", evaluator.Name()),
+				observability.Error(err),
+			)
+			continue
+		}
+
+		score = min(max(score, 0), 1)
+		scores[evaluator.Name()] = score
+		observability.Evaluations.WithLabelValues(evaluator.Name(), "success").Inc()
+		observability.ResponseQuality.WithLabelValues(record.Provider, record.Model, evaluator.Name()).Observe(score)
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	return scores
+}
+
+// JudgeEvaluator scores responses by asking a judge model to rate them.
+type JudgeEvaluator struct {
+	registry ProviderRegistry
+	model    string
+}
+
+// NewJudgeEvaluator creates an evaluator asking model, routed through registry,
+// to rate responses.
+func NewJudgeEvaluator(registry ProviderRegistry, model string) *JudgeEvaluator {
+	return &JudgeEvaluator{registry: registry, model: model}
+}
+
+// Name identifies the judge evaluator.
+func (j *JudgeEvaluator) Name() string {
+	return JudgeEvaluatorName
+}
+
+// Evaluate asks the judge model to rate content as a response to the client's
+// messages, from 0 to 1. Operator-injected instructions are left out.
+func (j *JudgeEvaluator) Evaluate(ctx context.Context, req *CompletionRequest, content string) (float64, error) {
+	provider, err := j.registry.GetByModel(ctx, j.model)
+	if err != nil {
+		return 0, fmt.Errorf("judge model unavailable: %w", err)
+	}
+
+	var transcript strings.Builder
+	for _, msg := range req.Messages {
+		if !isInstruction(msg) {
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+		}
+	}
+	fmt.Fprintf(&transcript, "\nResponse:\n%s\n", content)
+
+	response, err := provider.Complete(ctx, &CompletionRequest{
+		Model: j.model,
+		Messages: []Message{
+			{Role: "system", Content: judgeInstructions, ToolCallID: "", ToolCalls: nil},
+			{Role: "user", Content: transcript.String(), ToolCallID: "", ToolCalls: nil},
+		},
+		Temperature:      0,
+		MaxTokens:        4,
+		Stream:           false,
+		Metadata:         nil,
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("judging failed: %w", err)
+	}
+
+	rating, err := strconv.Atoi(scoreInteger.FindString(response.Content))
+	if err != nil || rating > classifierMaxScore {
+		return 0, fmt.Errorf("judge %s replied %q instead of a rating", j.model, response.Content)
+	}
+	return float64(rating) / classifierMaxScore, nil
+}
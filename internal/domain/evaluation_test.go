@@ -0,0 +1,129 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// stubEvaluator scores every response with a fixed score or error.
+type stubEvaluator struct {
+	name  string
+	score float64
+	err   error
+}
+
+func (e stubEvaluator) Name() string {
+	return e.name
+}
+
+func (e stubEvaluator) Evaluate(context.Context, *domain.CompletionRequest, string) (float64, error) {
+	return e.score, e.err
+}
+
+// storedRecords returns the records in store once there are n of them.
+func storedRecords(t *testing.T, store *memoryUsageStore, n int) []domain.UsageRecord {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.records) == n
+	}, time.Second, 5*time.Millisecond)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.records
+}
+
+func TestGatewayService_Evaluation(t *testing.T) {
+	evaluators := []domain.Evaluator{
+		stubEvaluator{name: "groundedness", score: 0.8},
+		stubEvaluator{name: "toxicity", score: 1.4},
+		stubEvaluator{name: "broken", err: errors.New("evaluator down")},
+	}
+
+	newGateway := func(t *testing.T, store *memoryUsageStore) (*domain.GatewayService, *mocks.MockProvider) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		provider := mocks.NewMockProvider(t)
+		provider.EXPECT().Name().Return("openai").Maybe()
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(provider, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithUsageStore(store),
+			domain.WithEvaluation(domain.NewEvaluation(evaluators, 100, time.Second, 4)))
+		return gateway, provider
+	}
+	request := &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+	}
+
+	t.Run("should attach scores of sampled responses to their usage", func(t *testing.T) {
+		store := &memoryUsageStore{}
+		gateway, provider := newGateway(t, store)
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+			Content:  "Hi there",
+		}, nil)
+
+		_, err := gateway.CompleteByModel(context.Background(), request)
+		require.NoError(t, err)
+
+		records := storedRecords(t, store, 1)
+		require.Equal(t, map[string]float64{"groundedness": 0.8, "toxicity": 1}, records[0].Scores)
+	})
+
+	t.Run("should score streamed responses once the stream ends", func(t *testing.T) {
+		store := &memoryUsageStore{}
+		gateway, provider := newGateway(t, store)
+
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: "Hi there"}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).Return(upstream, nil)
+
+		streamReq := *request
+		streamReq.Stream = true
+		chunks, err := gateway.StreamByModel(context.Background(), &streamReq)
+		require.NoError(t, err)
+		collect(chunks)
+
+		records := storedRecords(t, store, 1)
+		require.InDelta(t, 0.8, records[0].Scores["groundedness"], 1e-9)
+	})
+}
+
+func TestJudgeEvaluator(t *testing.T) {
+	newJudge := func(t *testing.T, reply string) *domain.JudgeEvaluator {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		judge := mocks.NewMockProvider(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o-mini").Return(judge, nil)
+		judge.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.Model == "gpt-4o-mini" && len(req.Messages) == 2
+		})).Return(&domain.CompletionResponse{Content: reply}, nil)
+		return domain.NewJudgeEvaluator(mockRegistry, "gpt-4o-mini")
+	}
+	request := &domain.CompletionRequest{Messages: []domain.Message{{Role: "user", Content: "What is 2+2?"}}}
+
+	t.Run("should scale the judge rating to 0-1", func(t *testing.T) {
+		score, err := newJudge(t, " 7\n").Evaluate(context.Background(), request, "4")
+		require.NoError(t, err)
+		require.InDelta(t, 0.7, score, 1e-9)
+	})
+
+	t.Run("should reject replies that are not a rating", func(t *testing.T) {
+		_, err := newJudge(t, "It depends").Evaluate(context.Background(), request, "4")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Request lifecycle event types.
+const (
+	EventRequestStarted   = "request.started"
+	EventRequestCompleted = "request.completed"
+	EventRequestFailed    = "request.failed"
+	EventCacheHit         = "cache.hit"
+	EventCacheMiss        = "cache.miss"
+
+	// EventProviderFallback reports a request retried on a fallback after its
+	// provider failed with an error class that has one.
+	EventProviderFallback = "provider.fallback"
+)
+
+// Event is a gateway telemetry event for external consumers.
+type Event struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	ClientKey string    `json:"client_key,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Status    int       `json:"status,omitempty"`      // HTTP status; completed and failed events only
+	Duration  float64   `json:"duration_ms,omitempty"` // completed and failed events only
+
+	// Fallback events only: the provider and model retried, and the error that
+	// made the primary Provider and Model fail.
+	FallbackProvider string `json:"fallback_provider,omitempty"`
+	FallbackModel    string `json:"fallback_model,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// EventPublisher publishes gateway events. Publishing must not block requests;
+// implementations drop events they cannot keep up with.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// WithEventPublisher publishes the events the gateway service raises itself,
+// such as provider fallbacks, to publisher.
+func WithEventPublisher(publisher EventPublisher) GatewayOption {
+	return func(g *GatewayService) {
+		g.events = publisher
+	}
+}
+
+// publish publishes an event of eventType carrying the request's identity, with
+// set filling in its type-specific fields. It does nothing without a publisher.
+func (g *GatewayService) publish(ctx context.Context, eventType string, set func(*Event)) {
+	if g.events == nil {
+		return
+	}
+
+	event := Event{
+		Type:             eventType,
+		Time:             time.Now().UTC(),
+		RequestID:        observability.GetRequestID(ctx),
+		Tenant:           observability.GetTenant(ctx),
+		ClientKey:        observability.GetClientKey(ctx),
+		Method:           "",
+		Path:             "",
+		Model:            "",
+		Provider:         "",
+		Status:           0,
+		Duration:         0,
+		FallbackProvider: "",
+		FallbackModel:    "",
+		Error:            "",
+	}
+	set(&event)
+	g.events.Publish(ctx, event)
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// MetadataExperiment names the experiment a request was enrolled in.
+	MetadataExperiment = "experiment"
+
+	// MetadataExperimentArm reports the arm serving the request (control or variant).
+	MetadataExperimentArm = "experiment_arm"
+
+	// MetadataConversationID is the request metadata field used to bucket by conversation.
+	MetadataConversationID = "conversation_id"
+)
+
+// Experiment arms.
+const (
+	ArmControl = "control"
+	ArmVariant = "variant"
+)
+
+// Experiment bucketing units.
+const (
+	BucketByKey          = "key"
+	BucketByConversation = "conversation"
+)
+
+// bucketCount is the bucketing resolution; percentages are honored to two decimals.
+const bucketCount = 10000
+
+// Experiment routes a share of the traffic for one model to an alternate model.
+// Bucketing is deterministic: the same key or conversation always lands in the same arm.
+type Experiment struct {
+	Name         string
+	Model        string  // control model the experiment applies to
+	VariantModel string  // alternate model serving the variant arm
+	Percent      float64 // share of buckets routed to the variant, 0-100
+	BucketBy     string  // BucketByKey or BucketByConversation
+}
+
+// WithExperiment enables A/B routing for the experiment's control model.
+func WithExperiment(experiment Experiment) GatewayOption {
+	return func(g *GatewayService) {
+		g.experiment = &experiment
+	}
+}
+
+// assign returns the arm for a request to the control model.
+func (e *Experiment) assign(ctx context.Context, req *CompletionRequest) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(e.Name + ":" + bucketUnit(ctx, req, e.BucketBy)))
+
+	if float64(h.Sum64()%bucketCount) < e.Percent*bucketCount/100 {
+		return ArmVariant
+	}
+	return ArmControl
+}
+
+// bucketUnit identifies who is being bucketed. Conversation bucketing falls back
+// to the client key, and the client key falls back to the tenant.
+func bucketUnit(ctx context.Context, req *CompletionRequest, bucketBy string) string {
+	if bucketBy == BucketByConversation {
+		if conversation := req.Metadata[MetadataConversationID]; conversation != "" {
+			return "conversation:" + conversation
+		}
+	}
+
+	if key := observability.GetClientKey(ctx); key != "" {
+		return "key:" + key
+	}
+
+	return "tenant:" + normalizeTenant(observability.GetTenant(ctx))
+}
+
+// applyExperiment assigns an experiment arm and rewrites the model for the variant.
+// It returns the metadata tagging the response with the experiment and arm.
+func (g *GatewayService) applyExperiment(ctx context.Context, req *CompletionRequest) map[string]string {
+	if g.experiment == nil || req.Model != g.experiment.Model {
+		return nil
+	}
+
+	arm := g.experiment.assign(ctx, req)
+	if arm == ArmVariant {
+		req.Model = g.experiment.VariantModel
+	}
+
+	observability.ExperimentRequests.WithLabelValues(g.experiment.Name, arm).Inc()
+	observability.FromContext(ctx).Debug("experiment arm assigned",
+		observability.String("experiment", g.experiment.Name),
+		observability.String("arm", arm),
+	)
+
+	return map[string]string{
+		MetadataExperiment:    g.experiment.Name,
+		MetadataExperimentArm: arm,
+	}
+}
@@ -0,0 +1,92 @@
+package domain_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_Experiment(t *testing.T) {
+	newGateway := func(t *testing.T, percent float64) *domain.GatewayService {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, mock.Anything).Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, req *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				return &domain.CompletionResponse{Model: req.Model}, nil
+			})
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithExperiment(domain.Experiment{
+			Name:         "4o-rollout",
+			Model:        "gpt-4",
+			VariantModel: "gpt-4o",
+			Percent:      percent,
+			BucketBy:     domain.BucketByConversation,
+		}))
+	}
+
+	newRequest := func(conversation string) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Metadata: map[string]string{domain.MetadataConversationID: conversation},
+		}
+	}
+
+	t.Run("should keep a conversation in the same arm", func(t *testing.T) {
+		gateway := newGateway(t, 50)
+		ctx := context.Background()
+
+		first, err := gateway.CompleteByModel(ctx, newRequest("conv-42"))
+		require.NoError(t, err)
+
+		for range 5 {
+			again, againErr := gateway.CompleteByModel(ctx, newRequest("conv-42"))
+			require.NoError(t, againErr)
+			require.Equal(t, first.Model, again.Model)
+			require.Equal(t, first.Metadata[domain.MetadataExperimentArm], again.Metadata[domain.MetadataExperimentArm])
+		}
+	})
+
+	t.Run("should split traffic roughly by percent and tag the arm", func(t *testing.T) {
+		gateway := newGateway(t, 30)
+		ctx := context.Background()
+
+		variant := 0
+		for i := range 1000 {
+			response, err := gateway.CompleteByModel(ctx, newRequest(fmt.Sprintf("conv-%d", i)))
+			require.NoError(t, err)
+			require.Equal(t, "4o-rollout", response.Metadata[domain.MetadataExperiment])
+
+			if response.Metadata[domain.MetadataExperimentArm] == domain.ArmVariant {
+				require.Equal(t, "gpt-4o", response.Model)
+				variant++
+			} else {
+				require.Equal(t, "gpt-4", response.Model)
+			}
+		}
+
+		require.InDelta(t, 300, variant, 60)
+	})
+
+	t.Run("should not enroll requests for other models", func(t *testing.T) {
+		gateway := newGateway(t, 100)
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-3.5-turbo",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "gpt-3.5-turbo", response.Model)
+		require.Empty(t, response.Metadata)
+	})
+}
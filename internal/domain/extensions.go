@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// WithGuardrails checks every request against guardrails, in order, after
+// moderation and before routing. The first guardrail to object blocks the request.
+func WithGuardrails(guardrails ...Guardrail) GatewayOption {
+	return func(g *GatewayService) {
+		g.guardrails = append(g.guardrails, guardrails...)
+	}
+}
+
+// WithRouters consults routers, in order, before the provider registry when
+// routing requests by model.
+func WithRouters(routers ...Router) GatewayOption {
+	return func(g *GatewayService) {
+		g.routers = append(g.routers, routers...)
+	}
+}
+
+// WithRequestHook runs hook before every request is prepared and after every
+// completion. Rewrites by the hook, such as a new model, are subject to aliases,
+// policies, and limits like any client request.
+func WithRequestHook(hook RequestHook) GatewayOption {
+	return func(g *GatewayService) {
+		g.hook = hook
+	}
+}
+
+// checkGuardrails returns a GuardrailError from the first guardrail blocking req.
+func (g *GatewayService) checkGuardrails(ctx context.Context, req *CompletionRequest) error {
+	for _, guardrail := range g.guardrails {
+		if err := guardrail.Check(ctx, req); err != nil {
+			return &GuardrailError{Guardrail: guardrail.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+// route returns the provider chosen by the first router with an opinion,
+// falling back to the client's routing hint and then the registry's model index.
+// Routers choosing a provider the hint excludes are skipped, and the registry's
+// choice gives way to a provider in a region the client key's policy allows.
+func (g *GatewayService) route(ctx context.Context, req *CompletionRequest) (Provider, error) {
+	hint, err := g.parseRoutingHint(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, router := range g.routers {
+		providerName, err := router.Route(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("provider routing failed: %w", err)
+		}
+		if providerName != "" && (hint == nil || !slices.Contains(hint.exclude, providerName)) {
+			return g.providerFor(ctx, providerName, req.Model)
+		}
+	}
+
+	if hint != nil {
+		return g.routeByHint(ctx, req, hint)
+	}
+
+	provider, err := g.registry.GetByModel(ctx, req.Model)
+	if err != nil {
+		return nil, fmt.Errorf("provider routing failed: %w", err)
+	}
+	return g.residentProvider(ctx, req, provider), nil
+}
+
+// beforeRequest runs the request hook against req, returning its metadata.
+func (g *GatewayService) beforeRequest(ctx context.Context, req *CompletionRequest) (map[string]string, error) {
+	if g.hook == nil {
+		return nil, nil //nolint:nilnil // No hook means no metadata
+	}
+	return g.hook.BeforeRequest(ctx, req)
+}
+
+// afterResponse runs the request hook against a finished completion. The
+// completion has already been paid for, so a failing hook is logged rather than
+// failing the request.
+func (g *GatewayService) afterResponse(ctx context.Context, req *CompletionRequest, resp *CompletionResponse) {
+	if g.hook == nil {
+		return
+	}
+	if err := g.hook.AfterResponse(ctx, req, resp); err != nil {
+		observability.FromContext(ctx).Warn("response hook failed", observability.Error(err))
+	}
+}
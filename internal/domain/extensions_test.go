@@ -0,0 +1,150 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_Guardrails(t *testing.T) {
+	req := &domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "Hi"}}}
+
+	t.Run("should block a request rejected by a guardrail before routing", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		allow := mocks.NewMockGuardrail(t)
+		block := mocks.NewMockGuardrail(t)
+
+		allow.EXPECT().Check(mock.Anything, mock.Anything).Return(nil)
+		block.EXPECT().Check(mock.Anything, mock.Anything).Return(errors.New("contains an account number"))
+		block.EXPECT().Name().Return("pii")
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithGuardrails(allow, block))
+
+		_, err := gateway.CompleteByModel(context.Background(), req)
+
+		var guardrailErr *domain.GuardrailError
+		require.ErrorAs(t, err, &guardrailErr)
+		require.Equal(t, "pii", guardrailErr.Guardrail)
+		require.ErrorContains(t, err, "contains an account number")
+	})
+}
+
+func TestGatewayService_Routers(t *testing.T) {
+	req := &domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "Hi"}}}
+
+	t.Run("should route to the provider chosen by a router", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		abstain := mocks.NewMockRouter(t)
+		choose := mocks.NewMockRouter(t)
+
+		abstain.EXPECT().Route(mock.Anything, mock.Anything).Return("", nil)
+		choose.EXPECT().Route(mock.Anything, mock.Anything).Return("azure", nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "azure").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(true)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "azure", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRouters(abstain, choose))
+
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "azure", response.Provider)
+	})
+
+	t.Run("should fall back to the registry when no router chooses", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		abstain := mocks.NewMockRouter(t)
+
+		abstain.EXPECT().Route(mock.Anything, mock.Anything).Return("", nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRouters(abstain))
+
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "openai", response.Provider)
+	})
+
+	t.Run("should reject a router's choice that does not serve the model", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		router := mocks.NewMockRouter(t)
+
+		router.EXPECT().Route(mock.Anything, mock.Anything).Return("echo", nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "echo").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(false)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRouters(router))
+
+		_, err := gateway.CompleteByModel(context.Background(), req)
+
+		var notSupportedErr *domain.ModelNotSupportedError
+		require.ErrorAs(t, err, &notSupportedErr)
+	})
+}
+
+func TestGatewayService_RequestHook(t *testing.T) {
+	t.Run("should apply the hook's rewrites before aliases and tag the response", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		hook := mocks.NewMockRequestHook(t)
+
+		hook.EXPECT().BeforeRequest(mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, req *domain.CompletionRequest) (map[string]string, error) {
+				req.Model = "cheap"
+				return map[string]string{"team": "search"}, nil
+			})
+		hook.EXPECT().AfterResponse(mock.Anything, mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, _ *domain.CompletionRequest, resp *domain.CompletionResponse) error {
+				resp.Metadata["reviewed"] = "true"
+				return nil
+			})
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-3.5-turbo").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-3.5-turbo", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-3.5-turbo", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithRequestHook(hook),
+			domain.WithModelAliases(map[string]string{"cheap": "gpt-3.5-turbo"}))
+
+		req := &domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "Hi"}}}
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "search", response.Metadata["team"])
+		require.Equal(t, "true", response.Metadata["reviewed"])
+		require.Equal(t, "gpt-4", req.Model, "caller request must not be mutated")
+	})
+
+	t.Run("should reject the request when the hook fails", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		hook := mocks.NewMockRequestHook(t)
+
+		rejection := &domain.GuardrailError{Guardrail: "policy_script", Err: errors.New("no batch traffic")}
+		hook.EXPECT().BeforeRequest(mock.Anything, mock.Anything).Return(nil, rejection)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithRequestHook(hook))
+
+		_, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: []domain.Message{{Role: "user", Content: "Hi"}}})
+		require.ErrorIs(t, err, rejection)
+	})
+}
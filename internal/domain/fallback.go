@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Provider error classes with targeted fallbacks.
+const (
+	// ErrorClassContextLength is a prompt too long for the model's context window.
+	ErrorClassContextLength = "context_length_exceeded"
+
+	// ErrorClassContentFilter is a request refused by the provider's content filter.
+	ErrorClassContentFilter = "content_filter"
+)
+
+const (
+	// MetadataFallback reports the error class a request fell back from.
+	MetadataFallback = "fallback"
+
+	// MetadataFallbackFrom reports the provider/model that failed with it.
+	MetadataFallbackFrom = "fallback_from"
+)
+
+// contentFilterCodes are the upstream error codes of content filter refusals.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var contentFilterCodes = map[string]bool{
+	"content_filter":           true,
+	"content_policy_violation": true,
+}
+
+// Fallbacks re-route requests failing with a recognized provider error class:
+// prompts too long for a model go to a larger-context model, and requests a
+// provider's content filter refuses go to a provider filtering differently.
+type Fallbacks struct {
+	models    map[string]string // context length: model to larger-context model
+	providers map[string]string // content filter: provider to provider
+}
+
+// NewFallbacks builds the fallbacks of each error class: contextLength maps models
+// to larger-context models and contentFilter maps providers to providers serving
+// the same models with different filtering.
+func NewFallbacks(contextLength, contentFilter map[string]string) (*Fallbacks, error) {
+	for class, routes := range map[string]map[string]string{
+		ErrorClassContextLength: contextLength,
+		ErrorClassContentFilter: contentFilter,
+	} {
+		for from, to := range routes {
+			if to == "" || to == from {
+				return nil, fmt.Errorf("%s fallback of %s must name another target, got %q", class, from, to)
+			}
+		}
+	}
+
+	return &Fallbacks{models: contextLength, providers: contentFilter}, nil
+}
+
+// WithFallbacks retries routed requests failing with a recognized error class
+// against the class's fallback.
+func WithFallbacks(fallbacks *Fallbacks) GatewayOption {
+	return func(g *GatewayService) {
+		g.fallbacks = fallbacks
+	}
+}
+
+// ErrorClass returns the class of a provider error with a targeted fallback, or
+// "" for other errors. Prompts the gateway finds too long for the model's
+// context window are classed with upstream context length errors.
+func ErrorClass(err error) string {
+	var contextErr *ContextWindowError
+	if errors.As(err, &contextErr) {
+		return ErrorClassContextLength
+	}
+
+	var providerErr *ProviderError
+	switch {
+	case !errors.As(err, &providerErr):
+		return ""
+	case providerErr.Code == ErrorClassContextLength:
+		return ErrorClassContextLength
+	case contentFilterCodes[providerErr.Code]:
+		return ErrorClassContentFilter
+	default:
+		return ""
+	}
+}
+
+// withFallback retries a request that failed with err against the fallbacks of
+// its error class, following fallbacks of fallbacks until one succeeds, none is
+// configured, or the chain loops. A fallback that cannot be routed, such as a
+// model the client key may not use, returns the last error unchanged.
+func withFallback[T any](
+	ctx context.Context,
+	g *GatewayService,
+	provider Provider,
+	req *CompletionRequest,
+	metadata map[string]string,
+	err error,
+	attempt func(context.Context, Provider, *CompletionRequest, map[string]string) (T, error),
+) (T, error) {
+	var result T
+	if g.fallbacks == nil {
+		return result, err
+	}
+
+	from := provider.Name() + "/" + req.Model
+	tried := map[string]bool{from: true}
+	for {
+		class := ErrorClass(err)
+		next, nextReq, routeErr := g.fallbacks.route(ctx, g, class, provider, req)
+		if next == nil || tried[next.Name()+"/"+nextReq.Model] {
+			if routeErr != nil {
+				observability.FromContext(ctx).Warn("fallback unavailable",
+					observability.String("class", class),
+					observability.Error(routeErr),
+				)
+			}
+			return result, err
+		}
+		g.publish(ctx, EventProviderFallback, func(event *Event) {
+			event.Provider = provider.Name()
+			event.Model = req.Model
+			event.FallbackProvider = next.Name()
+			event.FallbackModel = nextReq.Model
+			event.Error = err.Error()
+		})
+		provider, req = next, nextReq
+		tried[provider.Name()+"/"+req.Model] = true
+
+		observability.FromContext(ctx).Info("falling back after provider error",
+			observability.String("class", class),
+			observability.String("from", from),
+			observability.String("provider", provider.Name()),
+			observability.String("model", req.Model),
+		)
+
+		fallbackMetadata := mergeMetadata(mergeMetadata(nil, metadata), map[string]string{
+			MetadataFallback:     class,
+			MetadataFallbackFrom: from,
+		})
+		if result, err = attempt(ctx, provider, req, fallbackMetadata); err == nil {
+			observability.Fallbacks.WithLabelValues(class, "success").Inc()
+			return result, nil
+		}
+		observability.Fallbacks.WithLabelValues(class, "error").Inc()
+	}
+}
+
+// route returns the provider and request to retry a request failing with an
+// error of class on, or a nil provider when the class has no fallback for it.
+func (f *Fallbacks) route(
+	ctx context.Context,
+	g *GatewayService,
+	class string,
+	provider Provider,
+	req *CompletionRequest,
+) (Provider, *CompletionRequest, error) {
+	switch class {
+	case ErrorClassContextLength:
+		model, ok := f.models[req.Model]
+		if !ok {
+			return nil, nil, nil
+		}
+		fallbackReq := *req
+		fallbackReq.Model = model
+		if err := g.validate(ctx, &fallbackReq); err != nil {
+			return nil, nil, err
+		}
+		next, err := g.route(ctx, &fallbackReq)
+		if err != nil {
+			return nil, nil, err
+		}
+		return next, &fallbackReq, nil
+	case ErrorClassContentFilter:
+		providerName, ok := f.providers[provider.Name()]
+		if !ok {
+			return nil, nil, nil
+		}
+		next, err := g.providerFor(ctx, providerName, req.Model)
+		if err != nil {
+			return nil, nil, err
+		}
+		return next, req, nil
+	default:
+		return nil, nil, nil
+	}
+}
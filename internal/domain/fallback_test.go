@@ -0,0 +1,278 @@
+package domain_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mockllm"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+	openaiadapter "github.com/davidbz/calcifer/internal/provider/openai"
+)
+
+// recordingPublisher records published events.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event domain.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+// newOpenAIUpstream starts an upstream serving model that rejects the requests
+// refuse picks with a 400 carrying code and streams "ok" for the rest, and returns
+// the real OpenAI adapter for it named name, so rejections surface as they do in
+// production rather than as a mock's synchronous error.
+func newOpenAIUpstream(
+	t *testing.T,
+	name, model, code string,
+	refuse func(maxTokens int) bool,
+) *openaiadapter.Provider {
+	t.Helper()
+
+	serve := mockllm.NewServer([]mockllm.Rule{{Model: model, Content: "ok"}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			MaxTokens int `json:"max_tokens"`
+		}
+		_ = json.Unmarshal(body, &req)
+		if refuse(req.MaxTokens) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"error":{"message":"refused","type":"invalid_request_error","code":%q}}`, code)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		serve.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openaiadapter.NewCompatibleProvider(openaiadapter.CompatibleConfig{
+		Name:    name,
+		Type:    openaiadapter.CompatibleType,
+		BaseURL: server.URL + "/v1",
+		APIKey:  "test-key",
+		Models:  []openaiadapter.CompatibleModel{{Name: model}},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewFallbacks(t *testing.T) {
+	t.Run("should reject a fallback to itself", func(t *testing.T) {
+		_, err := domain.NewFallbacks(nil, map[string]string{"azure": "azure"})
+		require.Error(t, err)
+	})
+
+	t.Run("should reject an empty target", func(t *testing.T) {
+		_, err := domain.NewFallbacks(map[string]string{"gpt-4": ""}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"upstream context length", &domain.ProviderError{Code: "context_length_exceeded"},
+			domain.ErrorClassContextLength},
+		{"gateway context window", &domain.ContextWindowError{Model: "gpt-4"}, domain.ErrorClassContextLength},
+		{"content filter", &domain.ProviderError{Code: "content_filter"}, domain.ErrorClassContentFilter},
+		{"content policy", &domain.ProviderError{Code: "content_policy_violation"}, domain.ErrorClassContentFilter},
+		{"wrapped", fmt.Errorf("completion failed: %w", &domain.ProviderError{Code: "content_filter"}),
+			domain.ErrorClassContentFilter},
+		{"other provider error", &domain.ProviderError{StatusCode: 500}, ""},
+		{"other error", errors.New("boom"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("should classify "+tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, domain.ErrorClass(tt.err))
+		})
+	}
+}
+
+func TestGatewayService_Fallbacks(t *testing.T) {
+	contextErr := &domain.ProviderError{Provider: "openai", StatusCode: 400, Code: "context_length_exceeded"}
+	filterErr := &domain.ProviderError{Provider: "azure", StatusCode: 400, Code: "content_filter"}
+
+	newProvider := func(t *testing.T, name string) *mocks.MockProvider {
+		provider := mocks.NewMockProvider(t)
+		provider.EXPECT().Name().Return(name).Maybe()
+		provider.EXPECT().IsModelSupported(mock.Anything, mock.Anything).Return(true).Maybe()
+		return provider
+	}
+	newGateway := func(
+		t *testing.T,
+		registry *mocks.MockProviderRegistry,
+		contextLength, contentFilter map[string]string,
+	) *domain.GatewayService {
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		fallbacks, err := domain.NewFallbacks(contextLength, contentFilter)
+		require.NoError(t, err)
+		return domain.NewGatewayService(registry, mockCostCalc, domain.WithFallbacks(fallbacks))
+	}
+
+	t.Run("should retry prompts too long for a model on the larger-context model", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		openai := newProvider(t, "openai")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, mock.Anything).Return(openai, nil)
+		openai.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.Model == "gpt-4"
+		})).Return(nil, contextErr)
+		openai.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.Model == "gpt-4-turbo"
+		})).Return(&domain.CompletionResponse{Provider: "openai", Model: "gpt-4-turbo", Content: "ok"}, nil)
+		gateway := newGateway(t, mockRegistry, map[string]string{"gpt-4": "gpt-4-turbo"}, nil)
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4"})
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4-turbo", response.Model)
+		require.Equal(t, domain.ErrorClassContextLength, response.Metadata[domain.MetadataFallback])
+		require.Equal(t, "openai/gpt-4", response.Metadata[domain.MetadataFallbackFrom])
+	})
+
+	t.Run("should open streams a content filter refuses on the fallback provider", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		azure, openai := newProvider(t, "azure"), newProvider(t, "openai")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(azure, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(openai, nil)
+		azure.EXPECT().Stream(mock.Anything, mock.Anything).Return(nil, filterErr)
+
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: "ok"}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		openai.EXPECT().Stream(mock.Anything, mock.Anything).Return(upstream, nil)
+		gateway := newGateway(t, mockRegistry, nil, map[string]string{"azure": "openai"})
+
+		chunks, err := gateway.StreamByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4o", Stream: true})
+		require.NoError(t, err)
+
+		first := <-chunks
+		require.Equal(t, "ok", first.Delta)
+		require.Equal(t, domain.ErrorClassContentFilter, first.Metadata[domain.MetadataFallback])
+		require.Equal(t, "azure/gpt-4o", first.Metadata[domain.MetadataFallbackFrom])
+		collect(chunks)
+	})
+
+	t.Run("should open streams an upstream content filter refuses on the fallback provider", func(t *testing.T) {
+		refusing := newOpenAIUpstream(t, "azure", "gpt-4o", "content_filter", func(int) bool { return true })
+		serving := newOpenAIUpstream(t, "openai", "gpt-4o", "", func(int) bool { return false })
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(refusing, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(serving, nil)
+		gateway := newGateway(t, mockRegistry, nil, map[string]string{"azure": "openai"})
+
+		chunks, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4o",
+			Messages: []domain.Message{{Role: "user", Content: "hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+
+		first := <-chunks
+		require.Equal(t, domain.ErrorClassContentFilter, first.Metadata[domain.MetadataFallback])
+		require.Equal(t, "azure/gpt-4o", first.Metadata[domain.MetadataFallbackFrom])
+		content, last := collect(chunks)
+		require.Equal(t, "ok", first.Delta+content)
+		require.NoError(t, last.Error)
+	})
+
+	t.Run("should publish a fallback event naming both providers and the error", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		azure, openai := newProvider(t, "azure"), newProvider(t, "openai")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(azure, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(openai, nil)
+		azure.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, filterErr)
+		openai.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Provider: "openai", Model: "gpt-4o", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+
+		fallbacks, err := domain.NewFallbacks(nil, map[string]string{"azure": "openai"})
+		require.NoError(t, err)
+		publisher := &recordingPublisher{}
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithFallbacks(fallbacks), domain.WithEventPublisher(publisher))
+
+		ctx := observability.WithClientKey(context.Background(), "mobile")
+		_, err = gateway.CompleteByModel(ctx, &domain.CompletionRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+
+		require.Len(t, publisher.events, 1)
+		event := publisher.events[0]
+		require.Equal(t, domain.EventProviderFallback, event.Type)
+		require.Equal(t, "mobile", event.ClientKey)
+		require.Equal(t, "azure", event.Provider)
+		require.Equal(t, "gpt-4o", event.Model)
+		require.Equal(t, "openai", event.FallbackProvider)
+		require.Equal(t, "gpt-4o", event.FallbackModel)
+		require.Contains(t, event.Error, filterErr.Error())
+	})
+
+	t.Run("should return errors of classes without a fallback", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		azure := newProvider(t, "azure")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(azure, nil)
+		azure.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, filterErr)
+		gateway := newGateway(t, mockRegistry, map[string]string{"gpt-4o": "gpt-4-turbo"}, nil)
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4o"})
+		require.ErrorIs(t, err, filterErr)
+	})
+
+	t.Run("should stop when the fallback chain loops", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		azure, openai := newProvider(t, "azure"), newProvider(t, "openai")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(azure, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(openai, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "azure").Return(azure, nil)
+		azure.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, filterErr).Once()
+		openaiErr := &domain.ProviderError{Provider: "openai", StatusCode: 400, Code: "content_policy_violation"}
+		openai.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, openaiErr).Once()
+		gateway := newGateway(t, mockRegistry, nil, map[string]string{"azure": "openai", "openai": "azure"})
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4o"})
+		require.ErrorIs(t, err, openaiErr)
+	})
+
+	t.Run("should keep the original error when the key policy denies the fallback model", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		openai := newProvider(t, "openai")
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(openai, nil)
+		openai.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, contextErr).Once()
+
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		fallbacks, err := domain.NewFallbacks(map[string]string{"gpt-4": "gpt-4-turbo"}, nil)
+		require.NoError(t, err)
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithFallbacks(fallbacks),
+			domain.WithKeyPolicies([]domain.KeyPolicy{
+				{Name: "no-turbo", Keys: []string{domain.DefaultPolicyKey}, DenyModels: []string{"gpt-4-turbo"}},
+			}))
+
+		_, err = gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4"})
+		require.ErrorIs(t, err, contextErr)
+	})
+}
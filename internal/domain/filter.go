@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Output filter actions.
+const (
+	// OutputFilterMask replaces disallowed text and lets generation continue.
+	OutputFilterMask = "mask"
+
+	// OutputFilterAbort ends the output before the first disallowed text.
+	OutputFilterAbort = "abort"
+)
+
+const (
+	// MetadataOutputFiltered reports the output filter action taken, "mask" or
+	// "abort". Streams carry it on the chunk where the filter first acted.
+	MetadataOutputFiltered = "output_filtered"
+
+	// finishReasonContentFilter reports a completion ended by the output filter.
+	finishReasonContentFilter = "content_filter"
+
+	// filteredText replaces masked text.
+	filteredText = "[FILTERED]"
+)
+
+// OutputFilter screens generated content for disallowed text, matched by keywords
+// and a regular expression, or flagged by the moderation provider.
+type OutputFilter struct {
+	pattern  *regexp.Regexp // nil when only the classifier screens output
+	classify bool
+	action   string
+	window   int
+}
+
+// NewOutputFilter creates an output filter. Keywords match whole words, ignoring
+// case; pattern is a regular expression, empty for none. classify also screens
+// output with the moderation provider; flagged output is always aborted since the
+// classifier does not locate the offending text. window is the number of bytes of
+// a stream held back so text split across deltas is screened whole, and the size
+// of the blocks sent to the classifier.
+func NewOutputFilter(
+	keywords []string,
+	pattern string,
+	classify bool,
+	action string,
+	window int,
+) (*OutputFilter, error) {
+	if action != OutputFilterMask && action != OutputFilterAbort {
+		return nil, fmt.Errorf("invalid output filter action %q: must be mask or abort", action)
+	}
+	if window <= 0 {
+		return nil, errors.New("output filter window must be positive")
+	}
+
+	var alternatives []string
+	if len(keywords) > 0 {
+		quoted := make([]string, len(keywords))
+		for i, keyword := range keywords {
+			quoted[i] = regexp.QuoteMeta(keyword)
+		}
+		alternatives = append(alternatives, `(?i:\b(?:`+strings.Join(quoted, "|")+`)\b)`)
+	}
+	if pattern != "" {
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+	if len(alternatives) == 0 && !classify {
+		return nil, errors.New("output filter needs keywords, a pattern, or the classifier")
+	}
+
+	filter := &OutputFilter{pattern: nil, classify: classify, action: action, window: window}
+	if len(alternatives) > 0 {
+		compiled, err := regexp.Compile(strings.Join(alternatives, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid output filter pattern: %w", err)
+		}
+		filter.pattern = compiled
+	}
+	return filter, nil
+}
+
+// WithOutputFilter screens every completion and stream with filter.
+func WithOutputFilter(filter *OutputFilter) GatewayOption {
+	return func(g *GatewayService) {
+		g.outputFilter = filter
+	}
+}
+
+// filterResponse screens every choice of a response, masking disallowed text or
+// cutting the content before it.
+func (g *GatewayService) filterResponse(ctx context.Context, response *CompletionResponse) error {
+	action := ""
+	screen := func(content string) (string, bool, error) {
+		screened, verdict, _ := g.outputFilter.match(content, true)
+		if verdict != OutputFilterAbort {
+			flagged, err := g.classifyOutput(ctx, screened)
+			if err != nil {
+				return "", false, err
+			}
+			if flagged {
+				screened, verdict = "", OutputFilterAbort
+			}
+		}
+		if verdict != "" {
+			action = verdict
+		}
+		return screened, verdict == OutputFilterAbort, nil
+	}
+
+	content, _, err := screen(response.Content)
+	if err != nil {
+		return err
+	}
+	response.Content = content
+	for i := range response.Choices {
+		content, aborted, screenErr := screen(response.Choices[i].Content)
+		if screenErr != nil {
+			return screenErr
+		}
+		response.Choices[i].Content = content
+		if aborted {
+			response.Choices[i].FinishReason = finishReasonContentFilter
+		}
+	}
+
+	if action != "" {
+		observability.OutputFilterActions.WithLabelValues(response.Provider, action).Inc()
+		annotate(response, map[string]string{MetadataOutputFiltered: action})
+	}
+	return nil
+}
+
+// classifyOutput reports whether the moderation provider flags text. It reports
+// false without a call when the classifier is disabled or text is blank.
+func (g *GatewayService) classifyOutput(ctx context.Context, text string) (bool, error) {
+	if !g.outputFilter.classify || strings.TrimSpace(text) == "" {
+		return false, nil
+	}
+
+	response, err := g.Moderate(ctx, &ModerationRequest{Input: []string{text}, Model: ""})
+	if err != nil {
+		if g.moderationFailOpen {
+			observability.FromContext(ctx).Warn("output moderation failed, allowing output", observability.Error(err))
+			return false, nil
+		}
+		return false, fmt.Errorf("output moderation failed: %w", err)
+	}
+	return response.Flagged(), nil
+}
+
+// filterStream screens a stream as it passes through. The last window bytes are
+// held back until later deltas show whether they start a violation; with the
+// classifier, text is released in blocks of at least window bytes, each
+// moderated before it is sent. An abort ends the stream with a final chunk
+// carrying the text before the violation.
+func (g *GatewayService) filterStream(
+	ctx context.Context,
+	in <-chan StreamChunk,
+	providerName string,
+) <-chan StreamChunk {
+	filter := g.outputFilter
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		abort := func(chunk StreamChunk, delta string) {
+			observability.OutputFilterActions.WithLabelValues(providerName, OutputFilterAbort).Inc()
+			chunk.Delta, chunk.Done, chunk.Error = delta, true, nil
+			chunk.Metadata = mergeMetadata(chunk.Metadata, map[string]string{MetadataOutputFiltered: OutputFilterAbort})
+			send(chunk)
+		}
+
+		var pending string
+		masked := false
+		for {
+			var chunk StreamChunk
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok = <-in:
+			}
+
+			final := !ok || chunk.Done || chunk.Error != nil
+			text, action, hold := filter.match(pending+chunk.Delta, final)
+			if action == OutputFilterAbort {
+				abort(chunk, text)
+				return
+			}
+			if action == OutputFilterMask && !masked {
+				masked = true
+				observability.OutputFilterActions.WithLabelValues(providerName, action).Inc()
+				chunk.Metadata = mergeMetadata(chunk.Metadata, map[string]string{MetadataOutputFiltered: action})
+			}
+			pending = text
+
+			// Release all but the held back window, never splitting a rune.
+			release := len(pending)
+			if !final {
+				release = max(len(pending)-filter.window, 0)
+				for release > 0 && !utf8.RuneStart(pending[release]) {
+					release--
+				}
+				release = min(release, hold)
+				if filter.classify && release < filter.window {
+					release = 0
+				}
+			}
+
+			flagged, err := g.classifyOutput(ctx, pending[:release])
+			if err != nil {
+				send(StreamChunk{Delta: "", Done: false, Error: err, ProviderHeaders: nil, Metadata: nil})
+				return
+			}
+			if flagged {
+				abort(chunk, "")
+				return
+			}
+
+			chunk.Delta, pending = pending[:release], pending[release:]
+			if !ok {
+				if chunk.Delta != "" || chunk.Metadata != nil {
+					send(chunk)
+				}
+				return
+			}
+			if chunk.Delta == "" && !final && chunk.ProviderHeaders == nil && chunk.Metadata == nil {
+				continue
+			}
+			if !send(chunk) || final {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// match masks disallowed text, or for the abort action cuts text before the first
+// violation and returns OutputFilterAbort. Unless final, a match reaching the end
+// of text may still grow or turn out not to match (a keyword followed by more
+// letters), so it is left alone and hold returns where it starts; otherwise hold
+// is len(text).
+func (f *OutputFilter) match(text string, final bool) (string, string, int) {
+	hold := len(text)
+	if f.pattern == nil {
+		return text, "", hold
+	}
+
+	matches := f.pattern.FindAllStringIndex(text, -1)
+	if n := len(matches); n > 0 && !final && matches[n-1][1] == len(text) {
+		hold = matches[n-1][0]
+		matches = matches[:n-1]
+	}
+	if len(matches) == 0 {
+		return text, "", hold
+	}
+
+	if f.action == OutputFilterAbort {
+		return text[:matches[0][0]], OutputFilterAbort, hold
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range matches {
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(filteredText)
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	masked := b.String()
+	return masked, OutputFilterMask, hold + len(masked) - len(text)
+}
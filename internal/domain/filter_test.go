@@ -0,0 +1,167 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// collect drains a stream, returning its content and last chunk.
+func collect(chunks <-chan domain.StreamChunk) (string, domain.StreamChunk) {
+	var content strings.Builder
+	var last domain.StreamChunk
+	for chunk := range chunks {
+		content.WriteString(chunk.Delta)
+		last = chunk
+	}
+	return content.String(), last
+}
+
+func TestNewOutputFilter(t *testing.T) {
+	t.Run("should reject an unknown action", func(t *testing.T) {
+		_, err := domain.NewOutputFilter([]string{"secret"}, "", false, "drop", 64)
+		require.Error(t, err)
+	})
+
+	t.Run("should reject an invalid pattern", func(t *testing.T) {
+		_, err := domain.NewOutputFilter(nil, "(", false, domain.OutputFilterMask, 64)
+		require.Error(t, err)
+	})
+
+	t.Run("should require something to filter on", func(t *testing.T) {
+		_, err := domain.NewOutputFilter(nil, "", false, domain.OutputFilterMask, 64)
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_OutputFilter(t *testing.T) {
+	newGateway := func(t *testing.T, filter *domain.OutputFilter, opts ...domain.GatewayOption) (
+		*domain.GatewayService, *moderatingProvider,
+	) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		provider := &moderatingProvider{MockProvider: mocks.NewMockProvider(t)}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(provider, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(provider, nil).Maybe()
+		provider.EXPECT().Name().Return("openai").Maybe()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		opts = append(opts, domain.WithOutputFilter(filter))
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, opts...), provider
+	}
+	newFilter := func(t *testing.T, pattern, action string) *domain.OutputFilter {
+		filter, err := domain.NewOutputFilter([]string{"secret"}, pattern, false, action, 8)
+		require.NoError(t, err)
+		return filter
+	}
+	streamOf := func(deltas ...string) <-chan domain.StreamChunk {
+		upstream := make(chan domain.StreamChunk, len(deltas)+1)
+		for _, delta := range deltas {
+			upstream <- domain.StreamChunk{Delta: delta}
+		}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		return upstream
+	}
+	streamReq := &domain.CompletionRequest{Model: "gpt-4", Stream: true}
+
+	t.Run("should mask whole-word keywords in responses", func(t *testing.T) {
+		gateway, provider := newGateway(t, newFilter(t, "", domain.OutputFilterMask))
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Provider: "openai",
+			Content:  "the Secret is out, said the secretary",
+		}, nil)
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4"})
+		require.NoError(t, err)
+		require.Equal(t, "the [FILTERED] is out, said the secretary", response.Content)
+		require.Equal(t, "mask", response.Metadata[domain.MetadataOutputFiltered])
+	})
+
+	t.Run("should cut responses before a violation when aborting", func(t *testing.T) {
+		gateway, provider := newGateway(t, newFilter(t, `\d{3}-\d{4}`, domain.OutputFilterAbort))
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Provider: "openai",
+			Choices:  []domain.Choice{{Index: 0, Content: "call 555-1234 now", FinishReason: "stop"}},
+		}, nil)
+
+		response, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4"})
+		require.NoError(t, err)
+		require.Equal(t, domain.Choice{Index: 0, Content: "call ", FinishReason: "content_filter"}, response.Choices[0])
+		require.Equal(t, "abort", response.Metadata[domain.MetadataOutputFiltered])
+	})
+
+	t.Run("should mask keywords split across deltas without sending any part", func(t *testing.T) {
+		gateway, provider := newGateway(t, newFilter(t, "", domain.OutputFilterMask))
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).
+			Return(streamOf("the sec", "ret is out, said the sec", "retary"), nil)
+
+		chunks, err := gateway.StreamByModel(context.Background(), streamReq)
+		require.NoError(t, err)
+
+		var deltas []string
+		var masked bool
+		for chunk := range chunks {
+			deltas = append(deltas, chunk.Delta)
+			masked = masked || chunk.Metadata[domain.MetadataOutputFiltered] == "mask"
+		}
+		require.Equal(t, "the [FILTERED] is out, said the secretary", strings.Join(deltas, ""))
+		require.NotContains(t, deltas, "the sec")
+		require.True(t, masked)
+	})
+
+	t.Run("should end the stream before a violation and cancel upstream", func(t *testing.T) {
+		gateway, provider := newGateway(t, newFilter(t, `\d{3}-\d{4}`, domain.OutputFilterAbort))
+
+		cancelled := make(chan struct{})
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, _ *domain.CompletionRequest) (<-chan domain.StreamChunk, error) {
+				upstream := make(chan domain.StreamChunk)
+				go func() {
+					defer close(upstream)
+					defer close(cancelled)
+					for _, delta := range []string{"call 555-", "1234 now", " or never"} {
+						select {
+						case upstream <- domain.StreamChunk{Delta: delta}:
+						case <-ctx.Done():
+							return
+						}
+					}
+					<-ctx.Done()
+				}()
+				return upstream, nil
+			})
+
+		chunks, err := gateway.StreamByModel(context.Background(), streamReq)
+		require.NoError(t, err)
+
+		content, last := collect(chunks)
+		require.Equal(t, "call ", content)
+		require.True(t, last.Done)
+		require.Equal(t, "abort", last.Metadata[domain.MetadataOutputFiltered])
+		<-cancelled
+	})
+
+	t.Run("should abort streams the classifier flags", func(t *testing.T) {
+		filter, err := domain.NewOutputFilter(nil, "", true, domain.OutputFilterMask, 8)
+		require.NoError(t, err)
+		gateway, provider := newGateway(t, filter, domain.WithModeration("openai", false, false))
+		provider.response = &domain.ModerationResponse{Results: []domain.ModerationResult{{Flagged: true}}}
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).Return(streamOf("something ", "harmful here"), nil)
+
+		chunks, err := gateway.StreamByModel(context.Background(), streamReq)
+		require.NoError(t, err)
+
+		content, last := collect(chunks)
+		require.Empty(t, content)
+		require.Equal(t, "abort", last.Metadata[domain.MetadataOutputFiltered])
+		require.Equal(t, []string{"something harm"}, provider.inputs, "moderated in blocks of at least the window")
+	})
+}
@@ -4,20 +4,177 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// MetadataTrimmedMessages reports how many history messages were dropped.
+	MetadataTrimmedMessages = "trimmed_messages"
+
+	// MetadataTrimmedTokens reports the estimated prompt tokens removed by trimming.
+	MetadataTrimmedTokens = "trimmed_tokens"
+
+	// MetadataSummarizedMessages reports how many dropped messages were replaced by a summary.
+	MetadataSummarizedMessages = "summarized_messages"
 )
 
 // GatewayService orchestrates requests to providers.
 type GatewayService struct {
 	registry       ProviderRegistry
 	costCalculator CostCalculator
+
+	capabilities         CapabilityRegistry
+	reservedOutputTokens int
+	contextStrategy      string
+	summaryModel         string
+	limiter              *ConcurrencyLimiter
+	transformers         []ResponseTransformer
+	modelAliases         map[string]string
+	deprecations         map[string]Deprecation
+	keyPrompts           map[string]string
+	modelPrompts         map[string]string
+	compressionKeys      []string
+	compressionModels    []string
+	strictRoleOrder      []string
+	stopEmulation        []string
+	outputFilter         *OutputFilter
+	guardrails           []Guardrail
+	routers              []Router
+	hints                *routingHints
+	providerRegions      map[string]string
+	fallbacks            *Fallbacks
+	adjustMaxTokens      CapabilityRegistry
+	evaluation           *Evaluation
+	tokenizers           *Tokenizers
+	hook                 RequestHook
+	shadow               *ShadowTraffic
+	experiment           *Experiment
+	autoRouting          *AutoRouting
+	moderationProvider   string
+	preflightModeration  bool
+	moderationFailOpen   bool
+	synthesizers         []Synthesizer
+	usage                UsageStore
+	attributionTags      []string
+	attributionLabels    *tagLabels
+	keyPolicies          map[string]*KeyPolicy
+	overrides            *Overrides
+	modelLimits          map[string]ParameterLimits
+	limitMode            string
+	coalescer            *requestCoalescer
+	alerts               *AlertMonitor
+	quotas               *QuotaManager
+	tenants              *Tenants
+	virtualKeys          *VirtualKeys
+	streams              *StreamWatchdog
+	events               EventPublisher
+}
+
+// GatewayOption configures optional GatewayService behavior.
+type GatewayOption func(*GatewayService)
+
+// WithHistoryTrimming enables trimming message history to fit the model context window.
+// When a request sets no max_tokens, reservedOutputTokens are kept free for the completion.
+func WithHistoryTrimming(capabilities CapabilityRegistry, reservedOutputTokens int) GatewayOption {
+	return WithContextWindow(capabilities, reservedOutputTokens, ContextStrategyTrimOldest, "")
+}
+
+// WithContextWindow checks every prompt against the model context window and applies
+// strategy to prompts that do not fit. The summarize strategy condenses dropped
+// messages with summaryModel, or with the requested model when it is empty.
+func WithContextWindow(
+	capabilities CapabilityRegistry,
+	reservedOutputTokens int,
+	strategy string,
+	summaryModel string,
+) GatewayOption {
+	return func(g *GatewayService) {
+		g.capabilities = capabilities
+		g.reservedOutputTokens = reservedOutputTokens
+		g.contextStrategy = strategy
+		g.summaryModel = summaryModel
+	}
+}
+
+// WithConcurrencyLimiter bounds concurrent requests per provider and model.
+func WithConcurrencyLimiter(limiter *ConcurrencyLimiter) GatewayOption {
+	return func(g *GatewayService) {
+		g.limiter = limiter
+	}
+}
+
+// WithResponseTransformers post-processes every response, and every stream chunk,
+// through transformers in order.
+func WithResponseTransformers(transformers ...ResponseTransformer) GatewayOption {
+	return func(g *GatewayService) {
+		g.transformers = append(g.transformers, transformers...)
+	}
 }
 
 // NewGatewayService creates a new gateway service (DI constructor).
-func NewGatewayService(registry ProviderRegistry, costCalculator CostCalculator) *GatewayService {
-	return &GatewayService{
-		registry:       registry,
-		costCalculator: costCalculator,
+func NewGatewayService(
+	registry ProviderRegistry,
+	costCalculator CostCalculator,
+	opts ...GatewayOption,
+) *GatewayService {
+	gateway := &GatewayService{
+		registry:             registry,
+		costCalculator:       costCalculator,
+		capabilities:         nil,
+		reservedOutputTokens: 0,
+		contextStrategy:      "",
+		summaryModel:         "",
+		limiter:              nil,
+		transformers:         nil,
+		modelAliases:         nil,
+		deprecations:         nil,
+		keyPrompts:           nil,
+		modelPrompts:         nil,
+		compressionKeys:      nil,
+		compressionModels:    nil,
+		strictRoleOrder:      nil,
+		stopEmulation:        nil,
+		outputFilter:         nil,
+		guardrails:           nil,
+		routers:              nil,
+		hints:                nil,
+		providerRegions:      nil,
+		fallbacks:            nil,
+		adjustMaxTokens:      nil,
+		evaluation:           nil,
+		tokenizers:           nil,
+		hook:                 nil,
+		shadow:               nil,
+		experiment:           nil,
+		autoRouting:          nil,
+		moderationProvider:   "",
+		preflightModeration:  false,
+		moderationFailOpen:   false,
+		synthesizers:         nil,
+		usage:                nil,
+		attributionTags:      nil,
+		attributionLabels:    nil,
+		keyPolicies:          nil,
+		overrides:            nil,
+		modelLimits:          nil,
+		limitMode:            LimitModeClamp,
+		coalescer:            nil,
+		alerts:               nil,
+		quotas:               nil,
+		tenants:              nil,
+		virtualKeys:          nil,
+		streams:              nil,
+		events:               nil,
+	}
+
+	for _, opt := range opts {
+		opt(gateway)
 	}
+
+	return gateway
 }
 
 // Complete handles a completion request.
@@ -34,22 +191,24 @@ func (g *GatewayService) Complete(
 		return nil, errors.New("provider name cannot be empty")
 	}
 
-	// Route to appropriate provider.
-	provider, err := g.registry.Get(ctx, providerName)
+	req, metadata, err := g.prepare(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("provider not found: %w", err)
+		return nil, err
+	}
+	if err := g.preflight(ctx, req); err != nil {
+		return nil, err
 	}
 
-	// Execute request.
-	response, err := provider.Complete(ctx, req)
+	// Route to the requested provider.
+	provider, err := g.providerFor(ctx, providerName, req.Model)
 	if err != nil {
-		return nil, fmt.Errorf("completion failed: %w", err)
+		return nil, err
 	}
 
-	// Calculate cost in domain layer
-	cost, _ := g.costCalculator.Calculate(ctx, response.Model, response.Usage)
-	response.Usage.Cost = cost
-
+	response, err := g.coalesce(ctx, provider, req, metadata)
+	if err != nil {
+		return withAdjustedMaxTokens(ctx, g, provider, req, metadata, err, g.execute)
+	}
 	return response, nil
 }
 
@@ -67,14 +226,22 @@ func (g *GatewayService) Stream(
 		return nil, errors.New("provider name cannot be empty")
 	}
 
-	provider, err := g.registry.Get(ctx, providerName)
+	req, metadata, err := g.prepare(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("provider not found: %w", err)
+		return nil, err
+	}
+	if err := g.preflight(ctx, req); err != nil {
+		return nil, err
 	}
 
-	chunks, err := provider.Stream(ctx, req)
+	provider, err := g.providerFor(ctx, providerName, req.Model)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stream from provider: %w", err)
+		return nil, err
+	}
+
+	chunks, err := g.openStream(ctx, provider, req, metadata)
+	if err != nil {
+		return withAdjustedMaxTokens(ctx, g, provider, req, metadata, err, g.openStream)
 	}
 	return chunks, nil
 }
@@ -92,22 +259,27 @@ func (g *GatewayService) CompleteByModel(
 		return nil, errors.New("model cannot be empty")
 	}
 
-	// Route to appropriate provider based on model.
-	provider, err := g.registry.GetByModel(ctx, req.Model)
+	req, metadata, err := g.prepare(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("provider routing failed: %w", err)
+		return nil, err
+	}
+	if err := g.preflight(ctx, req); err != nil {
+		return nil, err
 	}
 
-	// Execute request.
-	response, err := provider.Complete(ctx, req)
+	// Route to appropriate provider based on model.
+	provider, err := g.route(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("completion failed: %w", err)
+		return nil, err
 	}
 
-	// Calculate cost in domain layer
-	cost, _ := g.costCalculator.Calculate(ctx, response.Model, response.Usage)
-	response.Usage.Cost = cost
-
+	response, err := g.coalesce(ctx, provider, req, metadata)
+	if err != nil {
+		response, err = withAdjustedMaxTokens(ctx, g, provider, req, metadata, err, g.execute)
+	}
+	if err != nil {
+		return withFallback(ctx, g, provider, req, metadata, err, g.execute)
+	}
 	return response, nil
 }
 
@@ -124,14 +296,440 @@ func (g *GatewayService) StreamByModel(
 		return nil, errors.New("model cannot be empty")
 	}
 
-	provider, err := g.registry.GetByModel(ctx, req.Model)
+	req, metadata, err := g.prepare(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("provider routing failed: %w", err)
+		return nil, err
+	}
+	if err := g.preflight(ctx, req); err != nil {
+		return nil, err
 	}
 
-	chunks, err := provider.Stream(ctx, req)
+	provider, err := g.route(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stream from provider: %w", err)
+		return nil, err
+	}
+
+	chunks, err := g.openStream(ctx, provider, req, metadata)
+	if err != nil {
+		chunks, err = withAdjustedMaxTokens(ctx, g, provider, req, metadata, err, g.openStream)
+	}
+	if err != nil {
+		return withFallback(ctx, g, provider, req, metadata, err, g.openStream)
 	}
 	return chunks, nil
 }
+
+// providerFor returns the named provider after checking that it serves model.
+func (g *GatewayService) providerFor(ctx context.Context, providerName, model string) (Provider, error) {
+	provider, err := g.registry.Get(ctx, providerName)
+	if err != nil {
+		return nil, fmt.Errorf("provider not found: %w: %w", ErrUnknownProvider, err)
+	}
+
+	if !provider.IsModelSupported(ctx, model) {
+		return nil, &ModelNotSupportedError{Provider: providerName, Model: model}
+	}
+
+	return provider, nil
+}
+
+// preflight runs the checks a request must pass before it is routed.
+func (g *GatewayService) preflight(ctx context.Context, req *CompletionRequest) error {
+	if err := g.validate(ctx, req); err != nil {
+		return err
+	}
+
+	if err := g.enforceQuota(ctx); err != nil {
+		return err
+	}
+
+	if err := g.admitTenant(ctx); err != nil {
+		return err
+	}
+
+	if err := g.moderatePrompt(ctx, req); err != nil {
+		return err
+	}
+
+	return g.checkGuardrails(ctx, req)
+}
+
+// validate applies key policies and parameter limits, clamping parameters of
+// the prepared request where configured.
+func (g *GatewayService) validate(ctx context.Context, req *CompletionRequest) error {
+	if err := g.enforcePolicy(ctx, req.Model, ""); err != nil {
+		return err
+	}
+
+	if err := g.enforceTenantModel(ctx, req.Model); err != nil {
+		return err
+	}
+
+	if err := g.enforceVirtualKeyModel(ctx, req.Model); err != nil {
+		return err
+	}
+
+	return g.enforceLimits(ctx, req)
+}
+
+// admit checks the routed provider against the client key's policy.
+func (g *GatewayService) admit(ctx context.Context, provider Provider) error {
+	if g.keyPolicies == nil && g.overrides == nil {
+		return nil
+	}
+
+	return g.enforcePolicy(ctx, "", provider.Name())
+}
+
+// recordProvider notes the routed provider for the access log when one is being kept.
+func recordProvider(ctx context.Context, provider Provider) {
+	if observability.HasRequestSummary(ctx) {
+		observability.RecordProvider(ctx, provider.Name())
+	}
+}
+
+// execute runs a completion against an already routed provider.
+func (g *GatewayService) execute(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+	metadata map[string]string,
+) (*CompletionResponse, error) {
+	provider = g.tenantProvider(ctx, provider)
+	recordProvider(ctx, provider)
+	if err := g.admit(ctx, provider); err != nil {
+		return nil, err
+	}
+	if err := g.enforceRoleOrder(provider, req); err != nil {
+		return nil, err
+	}
+
+	req, trim, err := g.fitContext(ctx, provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := g.acquireSlot(ctx, provider, req.Model)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Execute request.
+	upstreamReq, stops := g.emulateStop(provider, req)
+	start := time.Now()
+	response, err := provider.Complete(ctx, upstreamReq)
+	latency := time.Since(start)
+	g.observeProviderResult(ctx, provider, err)
+	g.observeLimit(provider, latency, err)
+	if err != nil {
+		return nil, fmt.Errorf("completion failed: %w", err)
+	}
+	if stops != nil {
+		applyStop(response, stops)
+	}
+	if g.outputFilter != nil {
+		if err = g.filterResponse(ctx, response); err != nil {
+			return nil, err
+		}
+	}
+	observability.ProviderLatency.WithLabelValues(response.Provider, req.Model).Observe(latency.Seconds())
+	if g.hints != nil {
+		g.hints.observeLatency(provider.Name(), req.Model, false, latency)
+	}
+
+	// Calculate cost in domain layer
+	g.price(ctx, response.Model, &response.Usage)
+	annotate(response, metadata)
+	annotate(response, trimMetadata(trim))
+	g.afterResponse(ctx, req, response)
+
+	g.recordEvaluatedUsage(ctx, UsageRecord{
+		Time:             time.Time{},
+		RequestID:        "",
+		Tenant:           "",
+		ClientKey:        "",
+		Provider:         response.Provider,
+		Model:            response.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+		CachedTokens:     response.Usage.CachedPromptTokens,
+		Images:           response.Usage.Images,
+		Cost:             response.Usage.Cost,
+		Stream:           false,
+		Estimated:        false,
+		Metadata:         response.Metadata,
+		Tags:             g.costTags(req.Metadata),
+		ProviderCost:     response.Usage.ProviderCost,
+		Currency:         response.Usage.Currency,
+
+		OriginalPromptTokens:   0,
+		CompressedPromptTokens: 0,
+
+		Scores: nil,
+
+		Rollup:   "",
+		Requests: 0,
+	}, req, response.Content)
+
+	// Shadow comparison uses the untransformed response.
+	g.mirror(ctx, req, response, latency)
+
+	for _, transformer := range g.transformers {
+		transformer.TransformResponse(ctx, response)
+	}
+
+	return response, nil
+}
+
+// openStream starts a stream against an already routed provider.
+func (g *GatewayService) openStream(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+	metadata map[string]string,
+) (<-chan StreamChunk, error) {
+	provider = g.tenantProvider(ctx, provider)
+	recordProvider(ctx, provider)
+	if err := g.admit(ctx, provider); err != nil {
+		return nil, err
+	}
+	if err := g.enforceRoleOrder(provider, req); err != nil {
+		return nil, err
+	}
+
+	// Stream chunks carry a single sequence of deltas.
+	if req.N > 1 {
+		return nil, &UnsupportedParameterError{Provider: provider.Name(), Parameter: "n"}
+	}
+
+	req, trim, err := g.fitContext(ctx, provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	requestCtx := ctx
+	ctx, untrack := g.streams.track(ctx)
+	release, err := g.acquireSlot(ctx, provider, req.Model)
+	if err != nil {
+		untrack()
+		return nil, err
+	}
+
+	start := time.Now()
+	chunks, err := g.streamProvider(ctx, provider, req)
+	g.observeProviderResult(ctx, provider, err)
+	g.observeLimit(provider, 0, err) // stream latency depends on output length
+	if err != nil {
+		release()
+		untrack()
+		return nil, fmt.Errorf("failed to stream from provider: %w", err)
+	}
+	var onFirstChunk func(time.Duration)
+	if g.hints != nil {
+		onFirstChunk = func(latency time.Duration) {
+			g.hints.observeLatency(provider.Name(), req.Model, true, latency)
+		}
+	}
+	chunks = timeStream(ctx, chunks, provider.Name(), req.Model, start, onFirstChunk)
+
+	decoration := streamDecoration{
+		transformers: g.transformers,
+		metadata:     mergeMetadata(metadata, trimMetadata(trim)),
+		onComplete:   nil,
+	}
+	if g.observesUsage() {
+		decoration.onComplete = g.streamUsage(ctx, provider.Name(), req, decoration.metadata)
+	}
+	if !decoration.empty() {
+		chunks = decorateStream(ctx, chunks, decoration)
+	}
+
+	if g.limiter == nil && g.streams == nil {
+		return chunks, nil
+	}
+
+	// The concurrency slot and the watchdog entry are held until the stream ends.
+	return watchStream(requestCtx, ctx, chunks, func() {
+		release()
+		untrack()
+	}), nil
+}
+
+// acquireSlot waits for a concurrency slot when a limiter is configured.
+func (g *GatewayService) acquireSlot(ctx context.Context, provider Provider, model string) (func(), error) {
+	if g.limiter == nil {
+		return func() {}, nil
+	}
+
+	release, err := g.limiter.Acquire(ctx, provider.Name(), model, g.priority(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("concurrency limit: %w", err)
+	}
+	return release, nil
+}
+
+// observeLimit feeds a provider result to the provider's adaptive concurrency limit.
+func (g *GatewayService) observeLimit(provider Provider, latency time.Duration, err error) {
+	if g.limiter != nil {
+		g.limiter.Observe(provider.Name(), latency, err)
+	}
+}
+
+// priority returns the priority class of a request: batch when the client asked
+// for it or its key policy sets it, so a batch key cannot queue as interactive.
+func (g *GatewayService) priority(ctx context.Context) Priority {
+	if Priority(observability.GetPriority(ctx)) == PriorityBatch {
+		return PriorityBatch
+	}
+	if policy := g.keyPolicy(ctx); policy != nil && policy.Priority == PriorityBatch {
+		return PriorityBatch
+	}
+	return PriorityInteractive
+}
+
+// fitContext applies the context window strategy to prompts that do not fit the
+// model context window. The caller's request is never mutated; a trimmed copy is
+// returned instead. A prompt that still does not fit fails with a ContextWindowError
+// rather than being rejected by the provider. Without a provider, as for dry runs,
+// summarization is estimated as plain trimming.
+func (g *GatewayService) fitContext(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+) (*CompletionRequest, TrimResult, error) {
+	if g.capabilities == nil {
+		return req, TrimResult{}, nil
+	}
+
+	capabilities, err := g.capabilities.GetCapabilities(ctx, req.Model)
+	if err != nil || capabilities.ContextWindow <= 0 {
+		return req, TrimResult{}, nil
+	}
+
+	reserved := g.reservedOutputTokens
+	if req.MaxTokens > 0 {
+		reserved = req.MaxTokens
+	}
+	budget := capabilities.ContextWindow - reserved
+
+	overflow := func(tokens int) error {
+		return &ContextWindowError{Model: req.Model, PromptTokens: tokens, Limit: max(budget, 0)}
+	}
+
+	tokenizer := g.tokenizers.ForModel(req.Model)
+	if g.contextStrategy == ContextStrategyError {
+		if tokens := CountMessagesTokens(tokenizer, req.Messages); tokens > budget {
+			return nil, TrimResult{}, overflow(tokens)
+		}
+		return req, TrimResult{}, nil
+	}
+
+	// Leave room for the summary that replaces the dropped messages.
+	summarize := g.contextStrategy == ContextStrategySummarize && provider != nil
+	trimBudget := budget
+	if summarize {
+		trimBudget -= summaryMaxTokens + tokenizer.MessageOverhead()
+	}
+
+	messages, dropped, trim := splitHistory(req.Messages, trimBudget, tokenizer)
+	if summarize && trim.Trimmed() {
+		messages, trim = g.summarizeDropped(ctx, provider, req, messages, dropped, trim)
+	}
+	if trim.FinalTokens > budget {
+		return nil, TrimResult{}, overflow(trim.FinalTokens)
+	}
+	if !trim.Trimmed() {
+		return req, trim, nil
+	}
+
+	observability.FromContext(ctx).Info("trimmed message history to fit context window",
+		observability.Int("dropped_messages", trim.DroppedMessages),
+		observability.Int("summarized_messages", trim.SummarizedMessages),
+		observability.Int("original_tokens", trim.OriginalTokens),
+		observability.Int("final_tokens", trim.FinalTokens),
+		observability.Int("context_window", capabilities.ContextWindow),
+	)
+
+	trimmed := *req
+	trimmed.Messages = messages
+	return &trimmed, trim, nil
+}
+
+// summarizeDropped replaces dropped messages with a summary written by the summary
+// model. When summarization fails, the history stays trimmed without a summary.
+// The summary's usage is attributed with the tags of req.
+func (g *GatewayService) summarizeDropped(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+	kept []Message,
+	dropped []Message,
+	trim TrimResult,
+) ([]Message, TrimResult) {
+	logger := observability.FromContext(ctx)
+	model := req.Model
+	tokenizer := g.tokenizers.ForModel(model)
+
+	if g.summaryModel != "" && g.summaryModel != model {
+		var err error
+		if provider, err = g.registry.GetByModel(ctx, g.summaryModel); err != nil {
+			logger.Warn("summary model unavailable, trimming without summary",
+				observability.String("summary_model", g.summaryModel),
+				observability.Error(err),
+			)
+			return kept, trim
+		}
+		model = g.summaryModel
+	}
+
+	summary, err := g.summarize(ctx, provider, model, dropped, req.Metadata)
+	if err != nil {
+		logger.Warn("failed to summarize dropped messages, trimming without summary",
+			observability.String("summary_model", model),
+			observability.Error(err),
+		)
+		return kept, trim
+	}
+
+	trim.SummarizedMessages = len(dropped)
+	trim.FinalTokens += CountMessageTokens(tokenizer, summary)
+	return insertSummary(kept, summary), trim
+}
+
+// trimMetadata describes applied trimming as response metadata.
+func trimMetadata(trim TrimResult) map[string]string {
+	if !trim.Trimmed() {
+		return nil
+	}
+
+	metadata := map[string]string{
+		MetadataTrimmedMessages: strconv.Itoa(trim.DroppedMessages),
+		MetadataTrimmedTokens:   strconv.Itoa(trim.OriginalTokens - trim.FinalTokens),
+	}
+	if trim.SummarizedMessages > 0 {
+		metadata[MetadataSummarizedMessages] = strconv.Itoa(trim.SummarizedMessages)
+	}
+	return metadata
+}
+
+// annotate records gateway metadata on the response.
+func annotate(response *CompletionResponse, metadata map[string]string) {
+	response.Metadata = mergeMetadata(response.Metadata, metadata)
+}
+
+// mergeMetadata copies src into dst, allocating dst when needed.
+func mergeMetadata(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
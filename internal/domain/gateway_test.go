@@ -6,11 +6,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/davidbz/calcifer/internal/domain"
 	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
 )
 
 func TestGatewayService_Complete(t *testing.T) {
@@ -33,6 +36,7 @@ func TestGatewayService_Complete(t *testing.T) {
 				FinishTime: time.Now(),
 			}, nil)
 		mockRegistry.EXPECT().Get(mock.Anything, "test-provider").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(true)
 		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.AnythingOfType("domain.Usage")).Return(0.001, nil)
 
 		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
@@ -129,6 +133,26 @@ func TestGatewayService_Complete(t *testing.T) {
 		mockRegistry.AssertExpectations(t)
 	})
 
+	t.Run("should reject a provider that does not support the model", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().Get(mock.Anything, "echo").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(false)
+
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t))
+
+		response, err := gateway.Complete(context.Background(), "echo", &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+
+		var notSupportedErr *domain.ModelNotSupportedError
+		require.ErrorAs(t, err, &notSupportedErr)
+		require.Nil(t, response)
+		require.Equal(t, "echo", notSupportedErr.Provider)
+	})
+
 	t.Run("should return error when provider returns error", func(t *testing.T) {
 		mockRegistry := mocks.NewMockProviderRegistry(t)
 		mockCostCalc := mocks.NewMockCostCalculator(t)
@@ -138,6 +162,7 @@ func TestGatewayService_Complete(t *testing.T) {
 			Complete(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
 			Return(nil, errors.New("provider error"))
 		mockRegistry.EXPECT().Get(mock.Anything, "test-provider").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(true)
 
 		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
 
@@ -177,7 +202,9 @@ func TestGatewayService_Stream(t *testing.T) {
 		mockProvider.EXPECT().
 			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
 			Return((<-chan domain.StreamChunk)(ch), nil)
+		mockProvider.EXPECT().Name().Return("test-provider")
 		mockRegistry.EXPECT().Get(mock.Anything, "test-provider").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(true)
 
 		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
 
@@ -212,6 +239,43 @@ func TestGatewayService_Stream(t *testing.T) {
 		mockProvider.AssertExpectations(t)
 	})
 
+	t.Run("should observe time to first chunk and stream duration", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		ch := make(chan domain.StreamChunk, 2)
+		ch <- domain.StreamChunk{Delta: "test", Done: false}
+		ch <- domain.StreamChunk{Done: true}
+		close(ch)
+
+		mockProvider.EXPECT().
+			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
+			Return((<-chan domain.StreamChunk)(ch), nil)
+		mockProvider.EXPECT().Name().Return("timed-provider")
+		mockRegistry.EXPECT().Get(mock.Anything, "timed-provider").Return(mockProvider, nil)
+		mockProvider.EXPECT().IsModelSupported(mock.Anything, "timed-model").Return(true)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+		req := &domain.CompletionRequest{
+			Model:    "timed-model",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		}
+
+		chunks, err := gateway.Stream(context.Background(), "timed-provider", req)
+		require.NoError(t, err)
+		received := 0
+		for range chunks {
+			received++
+		}
+		require.Equal(t, 2, received)
+
+		labels := []string{"timed-provider", "timed-model"}
+		require.Equal(t, uint64(1), histogramCount(t, observability.StreamFirstChunkLatency, labels...))
+		require.Equal(t, uint64(1), histogramCount(t, observability.StreamDuration, labels...))
+	})
+
 	t.Run("should return error when request is nil", func(t *testing.T) {
 		mockRegistry := mocks.NewMockProviderRegistry(t)
 		mockCostCalc := mocks.NewMockCostCalculator(t)
@@ -429,6 +493,7 @@ func TestGatewayService_StreamByModel(t *testing.T) {
 		mockProvider.EXPECT().
 			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
 			Return((<-chan domain.StreamChunk)(ch), nil)
+		mockProvider.EXPECT().Name().Return("test-provider")
 
 		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
 
@@ -460,6 +525,99 @@ func TestGatewayService_StreamByModel(t *testing.T) {
 		mockProvider.AssertExpectations(t)
 	})
 
+	t.Run("should reject streams requesting multiple choices", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t))
+
+		_, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+			N:        2,
+		})
+
+		var unsupportedErr *domain.UnsupportedParameterError
+		require.ErrorAs(t, err, &unsupportedErr)
+		require.Equal(t, "n", unsupportedErr.Parameter)
+	})
+
+	t.Run("should apply response transformers to stream chunks", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		ch := make(chan domain.StreamChunk, 3)
+		ch <- domain.StreamChunk{Delta: "<think>plan</think>", Done: false}
+		ch <- domain.StreamChunk{Delta: "  answer ", Done: false}
+		ch <- domain.StreamChunk{Done: true}
+		close(ch)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
+			Return((<-chan domain.StreamChunk)(ch), nil)
+		mockProvider.EXPECT().Name().Return("test-provider")
+
+		transformers, err := domain.NewResponseTransformers(
+			[]string{domain.TransformerStripThink, domain.TransformerTrimWhitespace}, "")
+		require.NoError(t, err)
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithResponseTransformers(transformers...))
+
+		chunks, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+
+		var content string
+		for chunk := range chunks {
+			content += chunk.Delta
+		}
+		require.Equal(t, "answer", content)
+	})
+
+	t.Run("should flush transformers when a stream closes without a done chunk", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		ch := make(chan domain.StreamChunk, 1)
+		ch <- domain.StreamChunk{Delta: "answer", Done: false}
+		close(ch)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Stream(mock.Anything, mock.AnythingOfType("*domain.CompletionRequest")).
+			Return((<-chan domain.StreamChunk)(ch), nil)
+		mockProvider.EXPECT().Name().Return("test-provider")
+
+		transformers, err := domain.NewResponseTransformers([]string{domain.TransformerDisclaimer}, "AI-generated")
+		require.NoError(t, err)
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithResponseTransformers(transformers...))
+
+		chunks, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+
+		var content string
+		for chunk := range chunks {
+			require.False(t, chunk.Done)
+			content += chunk.Delta
+		}
+		require.Equal(t, "answer\n\nAI-generated", content)
+	})
+
 	t.Run("should return error when request is nil", func(t *testing.T) {
 		mockRegistry := mocks.NewMockProviderRegistry(t)
 		mockCostCalc := mocks.NewMockCostCalculator(t)
@@ -552,3 +710,18 @@ func TestGatewayService_StreamByModel(t *testing.T) {
 		mockProvider.AssertExpectations(t)
 	})
 }
+
+// histogramCount returns the number of observations recorded for the labels.
+func histogramCount(t *testing.T, histogram *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+
+	observer, err := histogram.GetMetricWithLabelValues(labels...)
+	require.NoError(t, err)
+
+	metric, ok := observer.(prometheus.Metric)
+	require.True(t, ok)
+
+	var written dto.Metric
+	require.NoError(t, metric.Write(&written))
+	return written.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// HealthUpdate is a provider health transition shared between replicas.
+type HealthUpdate struct {
+	Provider string `json:"provider"`
+	Healthy  bool   `json:"healthy"`
+}
+
+// HealthMonitor periodically probes providers that implement HealthChecker and
+// marks them unhealthy in the registry after consecutive failed probes.
+// A single successful probe marks the provider healthy again. With peers, the
+// transitions are shared across replicas: each replica publishes those it detects
+// and adopts those of the others, so one replica evicting a broken provider evicts
+// it fleet-wide. Without peers, or while they are unreachable, health is local.
+type HealthMonitor struct {
+	registry         ProviderRegistry
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	peers            HealthPeers
+
+	mu       sync.Mutex
+	failures map[string]int
+	healthy  map[string]bool
+}
+
+// NewHealthMonitor creates a health monitor. A failureThreshold below 1 is treated as 1.
+// peers may be nil to keep health local to this replica.
+func NewHealthMonitor(
+	registry ProviderRegistry,
+	interval time.Duration,
+	timeout time.Duration,
+	failureThreshold int,
+	peers HealthPeers,
+) *HealthMonitor {
+	return &HealthMonitor{
+		registry:         registry,
+		interval:         interval,
+		timeout:          timeout,
+		failureThreshold: max(failureThreshold, 1),
+		peers:            peers,
+		mu:               sync.Mutex{},
+		failures:         make(map[string]int),
+		healthy:          make(map[string]bool),
+	}
+}
+
+// Run probes all providers on the configured interval, and follows the
+// transitions of peer replicas, until ctx is done.
+func (m *HealthMonitor) Run(ctx context.Context) {
+	if m.peers != nil {
+		go m.peers.Subscribe(ctx, func(update HealthUpdate) { m.Adopt(ctx, update) })
+	}
+	if m.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.CheckAll(ctx)
+		}
+	}
+}
+
+// CheckAll probes every registered provider once.
+func (m *HealthMonitor) CheckAll(ctx context.Context) {
+	logger := observability.FromContext(ctx)
+
+	names, err := m.registry.List(ctx)
+	if err != nil {
+		logger.Error("failed to list providers for health check", observability.Error(err))
+		return
+	}
+
+	for _, name := range names {
+		provider, getErr := m.registry.Get(ctx, name)
+		if getErr != nil {
+			continue
+		}
+
+		checker, ok := provider.(HealthChecker)
+		if !ok {
+			continue
+		}
+
+		m.check(ctx, name, checker)
+	}
+}
+
+// Unhealthy returns the providers currently failing health checks, sorted.
+func (m *HealthMonitor) Unhealthy() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0)
+	for name, healthy := range m.healthy {
+		if !healthy {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (m *HealthMonitor) check(ctx context.Context, name string, checker HealthChecker) {
+	probeCtx := ctx
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		probeCtx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	probeErr := checker.Ping(probeCtx)
+	healthy, changed := m.record(name, probeErr == nil)
+	if !changed {
+		return
+	}
+
+	logger := observability.FromContext(ctx).With(observability.String("provider", name))
+	if !m.apply(ctx, name, healthy) {
+		return
+	}
+	if healthy {
+		logger.Info("provider recovered, routing resumed")
+	} else {
+		logger.Warn("provider failed health checks, removed from routing",
+			observability.Int("consecutive_failures", m.failureThreshold),
+			observability.Error(probeErr),
+		)
+	}
+
+	if m.peers == nil {
+		return
+	}
+	if err := m.peers.Publish(ctx, HealthUpdate{Provider: name, Healthy: healthy}); err != nil {
+		observability.HealthSyncMessages.WithLabelValues("publish_failed").Inc()
+		logger.Warn("failed to share provider health with peers, keeping it local", observability.Error(err))
+		return
+	}
+	observability.HealthSyncMessages.WithLabelValues("published").Inc()
+}
+
+// Adopt applies a transition detected by a peer replica. An adopted failure
+// counts as reaching the failure threshold, so the next successful local probe
+// restores the provider (and announces it). Providers not registered on this
+// replica are ignored.
+func (m *HealthMonitor) Adopt(ctx context.Context, update HealthUpdate) {
+	observability.HealthSyncMessages.WithLabelValues("received").Inc()
+	if _, err := m.registry.Get(ctx, update.Provider); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	wasHealthy, seen := m.healthy[update.Provider]
+	m.healthy[update.Provider] = update.Healthy
+	if update.Healthy {
+		m.failures[update.Provider] = 0
+	} else {
+		m.failures[update.Provider] = m.failureThreshold
+	}
+	m.mu.Unlock()
+
+	if (!seen || wasHealthy) == update.Healthy {
+		return
+	}
+	if !m.apply(ctx, update.Provider, update.Healthy) {
+		return
+	}
+
+	logger := observability.FromContext(ctx).With(observability.String("provider", update.Provider))
+	if update.Healthy {
+		logger.Info("provider recovered on a peer replica, routing resumed")
+	} else {
+		logger.Warn("provider failed health checks on a peer replica, removed from routing")
+	}
+}
+
+// apply routes around or back to a provider whose health changed, reporting
+// whether the registry accepted the change.
+func (m *HealthMonitor) apply(ctx context.Context, name string, healthy bool) bool {
+	if err := m.registry.SetHealthy(ctx, name, healthy); err != nil {
+		observability.FromContext(ctx).Error("failed to update provider health",
+			observability.String("provider", name), observability.Error(err))
+		return false
+	}
+
+	state := "unhealthy"
+	if healthy {
+		state = "healthy"
+		observability.ProviderHealthy.WithLabelValues(name).Set(1)
+	} else {
+		observability.ProviderHealthy.WithLabelValues(name).Set(0)
+	}
+	observability.ProviderHealthTransitions.WithLabelValues(name, state).Inc()
+	return true
+}
+
+// record updates the failure count for a provider and reports its health and
+// whether that health changed. Providers start out healthy.
+func (m *HealthMonitor) record(name string, ok bool) (bool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wasHealthy, seen := m.healthy[name]
+	if !seen {
+		wasHealthy = true
+		m.healthy[name] = true
+		observability.ProviderHealthy.WithLabelValues(name).Set(1)
+	}
+
+	if ok {
+		m.failures[name] = 0
+	} else {
+		m.failures[name]++
+	}
+
+	healthy := wasHealthy
+	switch {
+	case ok:
+		healthy = true
+	case m.failures[name] >= m.failureThreshold:
+		healthy = false
+	}
+
+	m.healthy[name] = healthy
+	return healthy, healthy != wasHealthy
+}
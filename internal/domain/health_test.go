@@ -0,0 +1,128 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// pingingProvider adds a scripted Ping to a mock provider.
+type pingingProvider struct {
+	*mocks.MockProvider
+	results []error
+}
+
+func (p *pingingProvider) Ping(_ context.Context) error {
+	err := p.results[0]
+	p.results = p.results[1:]
+	return err
+}
+
+func TestHealthMonitor_CheckAll(t *testing.T) {
+	t.Run("should evict after consecutive failures and restore on recovery", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		provider := &pingingProvider{
+			MockProvider: mocks.NewMockProvider(t),
+			results:      []error{errors.New("down"), nil, errors.New("down"), errors.New("down"), nil},
+		}
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"flaky"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "flaky").Return(provider, nil)
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", false).Return(nil).Once()
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", true).Return(nil).Once()
+
+		monitor := domain.NewHealthMonitor(mockRegistry, 0, 0, 2, nil)
+
+		// A single failure followed by success does not evict.
+		monitor.CheckAll(ctx)
+		monitor.CheckAll(ctx)
+
+		// Two consecutive failures evict, the next success restores.
+		monitor.CheckAll(ctx)
+		monitor.CheckAll(ctx)
+		monitor.CheckAll(ctx)
+	})
+
+	t.Run("should skip providers without health checks", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"echo"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "echo").Return(mockProvider, nil)
+
+		monitor := domain.NewHealthMonitor(mockRegistry, 0, 0, 1, nil)
+		monitor.CheckAll(ctx)
+	})
+}
+
+// recordingPeers records published health updates.
+type recordingPeers struct {
+	published []domain.HealthUpdate
+	err       error
+}
+
+func (p *recordingPeers) Publish(_ context.Context, update domain.HealthUpdate) error {
+	p.published = append(p.published, update)
+	return p.err
+}
+
+func (p *recordingPeers) Subscribe(_ context.Context, _ func(domain.HealthUpdate)) {}
+
+func TestHealthMonitor_Peers(t *testing.T) {
+	t.Run("should publish local transitions and keep them when publishing fails", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		provider := &pingingProvider{
+			MockProvider: mocks.NewMockProvider(t),
+			results:      []error{errors.New("down"), nil},
+		}
+		peers := &recordingPeers{published: nil, err: errors.New("redis unreachable")}
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"flaky"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "flaky").Return(provider, nil)
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", false).Return(nil).Once()
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", true).Return(nil).Once()
+
+		monitor := domain.NewHealthMonitor(mockRegistry, 0, 0, 1, peers)
+		monitor.CheckAll(ctx)
+		require.Equal(t, []string{"flaky"}, monitor.Unhealthy())
+		monitor.CheckAll(ctx)
+
+		require.Equal(t, []domain.HealthUpdate{
+			{Provider: "flaky", Healthy: false},
+			{Provider: "flaky", Healthy: true},
+		}, peers.published)
+	})
+
+	t.Run("should adopt peer transitions until a local probe succeeds", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		provider := &pingingProvider{MockProvider: mocks.NewMockProvider(t), results: []error{nil}}
+		peers := &recordingPeers{published: nil, err: nil}
+
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"flaky"}, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "flaky").Return(provider, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "gone").Return(nil, errors.New("not found"))
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", false).Return(nil).Once()
+		mockRegistry.EXPECT().SetHealthy(mock.Anything, "flaky", true).Return(nil).Once()
+
+		monitor := domain.NewHealthMonitor(mockRegistry, 0, 0, 3, peers)
+		monitor.Adopt(ctx, domain.HealthUpdate{Provider: "flaky", Healthy: false})
+		monitor.Adopt(ctx, domain.HealthUpdate{Provider: "flaky", Healthy: false}) // no change
+		monitor.Adopt(ctx, domain.HealthUpdate{Provider: "gone", Healthy: false})
+		require.Equal(t, []string{"flaky"}, monitor.Unhealthy())
+		require.Empty(t, peers.published, "adopted transitions must not be echoed")
+
+		monitor.CheckAll(ctx)
+		require.Empty(t, monitor.Unhealthy())
+		require.Equal(t, []domain.HealthUpdate{{Provider: "flaky", Healthy: true}}, peers.published)
+	})
+}
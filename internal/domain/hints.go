@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Routing hints clients may set in request metadata.
+const (
+	// MetadataRoutingPrefer asks for the PreferCheapest or PreferFastest provider
+	// of the model, or names one as "provider:<name>".
+	MetadataRoutingPrefer = "routing.prefer"
+
+	// MetadataRoutingExclude lists providers, comma-separated, not to route to.
+	MetadataRoutingExclude = "routing.exclude"
+)
+
+// Routing preferences.
+const (
+	PreferCheapest = "cheapest"
+	PreferFastest  = "fastest"
+
+	// preferProvider prefixes a preference for a named provider.
+	preferProvider = "provider:"
+
+	// hintLatencyWeight is the smoothing factor of the latency averages ranking
+	// providers for PreferFastest.
+	hintLatencyWeight = 0.2
+)
+
+// routingHints ranks the providers of a model for client routing hints, by the
+// price of each provider's deployment and by its recent latency.
+type routingHints struct {
+	pricing PricingRegistry
+
+	mu        sync.Mutex
+	latencies map[string]float64 // seconds by latencyKey
+}
+
+// routingHint is a parsed client routing hint.
+type routingHint struct {
+	prefer   string   // PreferCheapest, PreferFastest, or "" when provider is set
+	provider string   // named provider, if any
+	exclude  []string // providers not to route to
+}
+
+// WithRoutingHints lets clients influence provider selection with the
+// routing.prefer and routing.exclude metadata hints. Hints only choose among
+// healthy providers serving the model that the client key's policy allows;
+// routers keep precedence. Pricing registered for "provider/model" prices a
+// provider's deployment of a model, falling back to the model's pricing.
+func WithRoutingHints(pricing PricingRegistry) GatewayOption {
+	return func(g *GatewayService) {
+		g.hints = &routingHints{
+			pricing:   pricing,
+			mu:        sync.Mutex{},
+			latencies: make(map[string]float64),
+		}
+	}
+}
+
+// parseRoutingHint returns the request's routing hint, or nil when hints are
+// disabled or the request sets none.
+func (g *GatewayService) parseRoutingHint(req *CompletionRequest) (*routingHint, error) {
+	prefer, exclude := req.Metadata[MetadataRoutingPrefer], req.Metadata[MetadataRoutingExclude]
+	if g.hints == nil || (prefer == "" && exclude == "") {
+		return nil, nil //nolint:nilnil // No hint means default routing
+	}
+
+	hint := &routingHint{prefer: "", provider: "", exclude: nil}
+	for _, name := range strings.Split(exclude, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			hint.exclude = append(hint.exclude, name)
+		}
+	}
+
+	switch {
+	case prefer == "", prefer == PreferCheapest, prefer == PreferFastest:
+		hint.prefer = prefer
+	case strings.HasPrefix(prefer, preferProvider) && len(prefer) > len(preferProvider):
+		hint.provider = strings.TrimPrefix(prefer, preferProvider)
+		if slices.Contains(hint.exclude, hint.provider) {
+			return nil, &ValidationError{
+				Param:   "metadata." + MetadataRoutingPrefer,
+				Message: fmt.Sprintf("provider %s is also excluded", hint.provider),
+			}
+		}
+	default:
+		return nil, &ValidationError{
+			Param:   "metadata." + MetadataRoutingPrefer,
+			Message: fmt.Sprintf("must be %s, %s, or provider:<name>, got %q", PreferCheapest, PreferFastest, prefer),
+		}
+	}
+	return hint, nil
+}
+
+// routeByHint picks the provider for a request carrying a routing hint.
+func (g *GatewayService) routeByHint(ctx context.Context, req *CompletionRequest, hint *routingHint) (Provider, error) {
+	if hint.provider != "" {
+		if err := g.enforcePolicy(ctx, "", hint.provider); err != nil {
+			return nil, err
+		}
+		observability.RoutingHints.WithLabelValues("provider").Inc()
+		return g.providerFor(ctx, hint.provider, req.Model)
+	}
+
+	candidates, err := g.hintCandidates(ctx, req.Model, hint.exclude)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, &ValidationError{
+			Param:   "metadata." + MetadataRoutingExclude,
+			Message: "excludes every eligible provider of model " + req.Model,
+		}
+	}
+
+	// The sort is stable, so ties keep the registry's choice first.
+	switch hint.prefer {
+	case PreferCheapest:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return g.hints.price(ctx, candidates[i].Name(), req.Model) <
+				g.hints.price(ctx, candidates[j].Name(), req.Model)
+		})
+	case PreferFastest:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return g.hints.latency(candidates[i].Name(), req.Model, req.Stream) <
+				g.hints.latency(candidates[j].Name(), req.Model, req.Stream)
+		})
+	}
+
+	label := hint.prefer
+	if label == "" {
+		label = "exclude"
+	}
+	observability.RoutingHints.WithLabelValues(label).Inc()
+	observability.FromContext(ctx).Debug("provider chosen by routing hint",
+		observability.String("hint", label),
+		observability.String("provider", candidates[0].Name()),
+	)
+	return candidates[0], nil
+}
+
+// hintCandidates returns the healthy providers of model that the client key's
+// policy allows, by name and region, and the hint does not exclude, the
+// registry's choice first and the rest by name.
+func (g *GatewayService) hintCandidates(ctx context.Context, model string, exclude []string) ([]Provider, error) {
+	names, err := g.registry.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	sort.Strings(names)
+
+	if preferred, err := g.registry.GetByModel(ctx, model); err == nil {
+		names = slices.DeleteFunc(names, func(name string) bool { return name == preferred.Name() })
+		names = append([]string{preferred.Name()}, names...)
+	}
+
+	policy := g.keyPolicy(ctx)
+	health, tracksHealth := g.registry.(ProviderHealth)
+
+	candidates := make([]Provider, 0, len(names))
+	for _, name := range names {
+		if slices.Contains(exclude, name) ||
+			!g.allowsProvider(policy, name) ||
+			(tracksHealth && !health.IsHealthy(ctx, name)) {
+			continue
+		}
+		provider, err := g.registry.Get(ctx, name)
+		if err != nil || !provider.IsModelSupported(ctx, model) {
+			continue
+		}
+		candidates = append(candidates, provider)
+	}
+	return candidates, nil
+}
+
+// observeLatency folds a latency sample into the provider's average for model:
+// the completion latency, or for streams the time to the first chunk.
+func (h *routingHints) observeLatency(providerName, model string, stream bool, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := latencyKey(providerName, model, stream)
+	average, seen := h.latencies[key]
+	if !seen {
+		h.latencies[key] = latency.Seconds()
+		return
+	}
+	h.latencies[key] = average + hintLatencyWeight*(latency.Seconds()-average)
+}
+
+// latency returns the provider's average latency for model, or +Inf when it has
+// not been observed.
+func (h *routingHints) latency(providerName, model string, stream bool) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if average, seen := h.latencies[latencyKey(providerName, model, stream)]; seen {
+		return average
+	}
+	return math.Inf(1)
+}
+
+// price returns the per-1K token input and output price of the provider's
+// deployment of model, or +Inf when neither it nor the model is priced.
+func (h *routingHints) price(ctx context.Context, providerName, model string) float64 {
+	for _, key := range [...]string{providerName + "/" + model, model} {
+		if pricing, err := h.pricing.GetPricing(ctx, key); err == nil {
+			return pricing.InputCostPer1K + pricing.OutputCostPer1K
+		}
+	}
+	return math.Inf(1)
+}
+
+// latencyKey identifies a latency average.
+func latencyKey(providerName, model string, stream bool) string {
+	if stream {
+		return providerName + "/" + model + "/stream"
+	}
+	return providerName + "/" + model
+}
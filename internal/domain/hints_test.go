@@ -0,0 +1,145 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_RoutingHints(t *testing.T) {
+	// newProviders registers openai, the registry's choice for gpt-4o, and azure,
+	// which serves gpt-4o too; completions take the given latency.
+	newProviders := func(t *testing.T, latencies map[string]time.Duration) (
+		*mocks.MockProviderRegistry, map[string]*mocks.MockProvider,
+	) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		providers := make(map[string]*mocks.MockProvider)
+		for _, name := range []string{"azure", "openai"} {
+			provider := mocks.NewMockProvider(t)
+			provider.EXPECT().Name().Return(name).Maybe()
+			provider.EXPECT().IsModelSupported(mock.Anything, "gpt-4o").Return(true).Maybe()
+			provider.EXPECT().Complete(mock.Anything, mock.Anything).
+				RunAndReturn(func(context.Context, *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+					time.Sleep(latencies[name])
+					return &domain.CompletionResponse{Provider: name, Content: "ok"}, nil
+				}).Maybe()
+			mockRegistry.EXPECT().Get(mock.Anything, name).Return(provider, nil).Maybe()
+			providers[name] = provider
+		}
+		mockRegistry.EXPECT().List(mock.Anything).Return([]string{"openai", "azure"}, nil).Maybe()
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(providers["openai"], nil).Maybe()
+		return mockRegistry, providers
+	}
+	newGateway := func(t *testing.T, mockRegistry *mocks.MockProviderRegistry, opts ...domain.GatewayOption) (
+		*domain.GatewayService, *domain.InMemoryPricingRegistry,
+	) {
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		pricing := domain.NewInMemoryPricingRegistry()
+		opts = append(opts, domain.WithRoutingHints(pricing))
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, opts...), pricing
+	}
+	complete := func(gateway *domain.GatewayService, hints map[string]string) (*domain.CompletionResponse, error) {
+		return gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4o", Metadata: hints})
+	}
+
+	t.Run("should prefer the provider with the cheaper deployment price", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, pricing := newGateway(t, mockRegistry)
+		ctx := context.Background()
+		require.NoError(t, pricing.RegisterPricing(ctx, "gpt-4o",
+			domain.PricingConfig{InputCostPer1K: 5, OutputCostPer1K: 15}))
+		require.NoError(t, pricing.RegisterPricing(ctx, "azure/gpt-4o",
+			domain.PricingConfig{InputCostPer1K: 4, OutputCostPer1K: 12}))
+
+		response, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: domain.PreferCheapest})
+		require.NoError(t, err)
+		require.Equal(t, "azure", response.Provider)
+	})
+
+	t.Run("should keep the registry's choice when prices tie", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, _ := newGateway(t, mockRegistry)
+
+		response, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: domain.PreferCheapest})
+		require.NoError(t, err)
+		require.Equal(t, "openai", response.Provider)
+	})
+
+	t.Run("should prefer the provider with the lowest observed latency", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, map[string]time.Duration{"openai": 30 * time.Millisecond})
+		gateway, _ := newGateway(t, mockRegistry)
+
+		for _, name := range []string{"openai", "azure"} {
+			_, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: "provider:" + name})
+			require.NoError(t, err)
+		}
+
+		response, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: domain.PreferFastest})
+		require.NoError(t, err)
+		require.Equal(t, "azure", response.Provider)
+	})
+
+	t.Run("should skip excluded providers", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, _ := newGateway(t, mockRegistry)
+
+		response, err := complete(gateway, map[string]string{domain.MetadataRoutingExclude: "openai"})
+		require.NoError(t, err)
+		require.Equal(t, "azure", response.Provider)
+	})
+
+	t.Run("should reject excluding every eligible provider", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, _ := newGateway(t, mockRegistry)
+
+		_, err := complete(gateway, map[string]string{domain.MetadataRoutingExclude: "openai, azure"})
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Equal(t, "metadata.routing.exclude", validationErr.Param)
+	})
+
+	t.Run("should stay within the key policy", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, _ := newGateway(t, mockRegistry, domain.WithKeyPolicies([]domain.KeyPolicy{
+			{Name: "openai-only", Keys: []string{domain.DefaultPolicyKey}, AllowProviders: []string{"openai"}},
+		}))
+
+		_, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: "provider:azure"})
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+
+		_, err = complete(gateway, map[string]string{domain.MetadataRoutingExclude: "openai"})
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr, "azure is not eligible under the policy")
+	})
+
+	t.Run("should reject unknown preferences", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		gateway, _ := newGateway(t, mockRegistry)
+
+		_, err := complete(gateway, map[string]string{domain.MetadataRoutingPrefer: "provider:"})
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Equal(t, "metadata.routing.prefer", validationErr.Param)
+	})
+
+	t.Run("should ignore hints unless enabled", func(t *testing.T) {
+		mockRegistry, _ := newProviders(t, nil)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc)
+
+		response, err := complete(gateway, map[string]string{domain.MetadataRoutingExclude: "openai"})
+		require.NoError(t, err)
+		require.Equal(t, "openai", response.Provider)
+	})
+}
@@ -33,4 +33,128 @@ type ProviderRegistry interface {
 
 	// List returns all available providers.
 	List(ctx context.Context) ([]string, error)
+
+	// SetHealthy marks a provider as healthy or unhealthy; GetByModel skips unhealthy providers.
+	SetHealthy(ctx context.Context, providerName string, healthy bool) error
+
+	// Unregister removes a provider and its model index entries.
+	Unregister(ctx context.Context, providerName string) error
+
+	// Reindex rebuilds the model index of a provider from its current SupportedModels.
+	Reindex(ctx context.Context, providerName string) error
+}
+
+// ProviderHealth is implemented by provider registries that can report whether a
+// provider is currently healthy.
+type ProviderHealth interface {
+	// IsHealthy reports whether the named provider is registered and healthy.
+	IsHealthy(ctx context.Context, providerName string) bool
+}
+
+// CredentialReporter is implemented by providers that rotate between multiple credentials.
+type CredentialReporter interface {
+	// CredentialHealth returns the redacted health of every credential.
+	CredentialHealth(ctx context.Context) []CredentialStatus
+}
+
+// HealthChecker is implemented by providers that can be probed cheaply for availability.
+type HealthChecker interface {
+	// Ping returns an error when the provider cannot currently serve requests.
+	Ping(ctx context.Context) error
+}
+
+// HealthPeers shares provider health transitions between gateway replicas.
+type HealthPeers interface {
+	// Publish announces a transition detected by this replica to the others.
+	Publish(ctx context.Context, update HealthUpdate) error
+
+	// Subscribe calls handle with transitions published by other replicas until
+	// ctx is done, reconnecting as needed.
+	Subscribe(ctx context.Context, handle func(HealthUpdate))
+}
+
+// ModelRefresher is implemented by providers that can discover their models from
+// the upstream API. After a successful refresh SupportedModels reflects the new list.
+type ModelRefresher interface {
+	// RefreshModels queries the upstream model list and updates the supported models.
+	RefreshModels(ctx context.Context) error
+}
+
+// ResponseTransformer post-processes provider output before it reaches the client.
+type ResponseTransformer interface {
+	// TransformResponse rewrites a complete response in place.
+	TransformResponse(ctx context.Context, resp *CompletionResponse)
+
+	// NewChunkTransformer returns a transformer holding the state of a single stream.
+	NewChunkTransformer(ctx context.Context) ChunkTransformer
+}
+
+// ChunkTransformer rewrites the chunks of one stream in order. The done chunk is
+// passed through as well so that buffered content can be flushed into its delta;
+// a stream closing without one is ended with an empty done chunk for the flush.
+type ChunkTransformer func(chunk StreamChunk) StreamChunk
+
+// Moderator is implemented by providers that can classify content for policy violations.
+type Moderator interface {
+	// Moderate classifies every input and returns one result per input.
+	Moderate(ctx context.Context, req *ModerationRequest) (*ModerationResponse, error)
+}
+
+// Synthesizer is implemented by providers that synthesize speech from text.
+type Synthesizer interface {
+	// Name identifies the provider.
+	Name() string
+
+	// SpeechModels returns the speech models the provider serves.
+	SpeechModels(ctx context.Context) []string
+
+	// Synthesize starts synthesizing req.Input and returns the audio as it streams.
+	Synthesize(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error)
+}
+
+// Guardrail inspects requests before they are routed, e.g. a proprietary policy check.
+type Guardrail interface {
+	// Name identifies the guardrail in errors and logs.
+	Name() string
+
+	// Check returns an error describing why the request must be blocked, or nil to allow it.
+	Check(ctx context.Context, req *CompletionRequest) error
+}
+
+// Router picks the provider for requests routed by model.
+type Router interface {
+	// Route returns the name of the provider to serve req, or "" to defer to the
+	// next router and finally to the provider registry.
+	Route(ctx context.Context, req *CompletionRequest) (string, error)
+}
+
+// RequestHook applies operator-defined rules to requests and their responses.
+type RequestHook interface {
+	// BeforeRequest may rewrite req in place and returns metadata to tag the
+	// response with. An error rejects the request.
+	BeforeRequest(ctx context.Context, req *CompletionRequest) (map[string]string, error)
+
+	// AfterResponse may tag resp.Metadata once a completion has finished.
+	AfterResponse(ctx context.Context, req *CompletionRequest, resp *CompletionResponse) error
+}
+
+// Evaluator scores the quality of completed responses, e.g. with a judge model
+// or heuristics, for continuous model-quality monitoring.
+type Evaluator interface {
+	// Name identifies the evaluator in usage records and metrics.
+	Name() string
+
+	// Evaluate scores content, the response to req, from 0 (worst) to 1 (best).
+	Evaluate(ctx context.Context, req *CompletionRequest, content string) (float64, error)
+}
+
+// Tokenizer counts the tokens a model family's tokenizer encodes text to, for
+// estimating prompts before they are sent and usage the provider does not report.
+type Tokenizer interface {
+	// CountTokens returns the tokens text encodes to.
+	CountTokens(text string) int
+
+	// MessageOverhead returns the tokens each chat message adds for its role
+	// markers and separators.
+	MessageOverhead() int
 }
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Parameter limit modes.
+const (
+	// LimitModeClamp lowers or raises out-of-range parameters to the nearest limit.
+	LimitModeClamp = "clamp"
+
+	// LimitModeReject fails requests with out-of-range parameters.
+	LimitModeReject = "reject"
+)
+
+// ParameterLimits caps the generation parameters a client may request.
+// When a model and a client key both have limits, the stricter bound wins.
+type ParameterLimits struct {
+	MaxTokens      int      `json:"max_tokens"` // 0 = no cap; also applied when the request leaves max_tokens unset
+	MinTemperature *float64 `json:"min_temperature"`
+	MaxTemperature *float64 `json:"max_temperature"`
+}
+
+// NewModelLimits builds per-model limits from max token caps and temperature
+// ranges written as "min:max", where either bound may be empty (e.g. ":1.2").
+func NewModelLimits(maxTokens map[string]int, temperatureRanges map[string]string) (map[string]ParameterLimits, error) {
+	limits := make(map[string]ParameterLimits, len(maxTokens)+len(temperatureRanges))
+
+	for model, tokens := range maxTokens {
+		limit := limits[model]
+		limit.MaxTokens = tokens
+		limits[model] = limit
+	}
+
+	for model, spec := range temperatureRanges {
+		lower, upper, found := strings.Cut(spec, ":")
+		if !found {
+			return nil, fmt.Errorf("temperature range for %s must be min:max, got %q", model, spec)
+		}
+
+		limit := limits[model]
+		var err error
+		if limit.MinTemperature, err = parseBound(lower); err != nil {
+			return nil, fmt.Errorf("invalid temperature range for %s: %w", model, err)
+		}
+		if limit.MaxTemperature, err = parseBound(upper); err != nil {
+			return nil, fmt.Errorf("invalid temperature range for %s: %w", model, err)
+		}
+		limits[model] = limit
+	}
+
+	return limits, nil
+}
+
+// WithParameterLimits caps max_tokens and temperature per model, and applies the
+// limits of client key policies, in the given mode (clamp or reject).
+func WithParameterLimits(byModel map[string]ParameterLimits, mode string) GatewayOption {
+	return func(g *GatewayService) {
+		g.modelLimits = byModel
+		g.limitMode = mode
+	}
+}
+
+// enforceLimits clamps or rejects out-of-range generation parameters. req must
+// be the gateway's prepared copy since clamping modifies it.
+func (g *GatewayService) enforceLimits(ctx context.Context, req *CompletionRequest) error {
+	limits := g.modelLimits[req.Model]
+	if policy := g.keyPolicy(ctx); policy != nil {
+		limits = limits.tighten(policy.ParameterLimits)
+	}
+
+	var clamped []string
+
+	if limits.MaxTokens > 0 && (req.MaxTokens == 0 || req.MaxTokens > limits.MaxTokens) {
+		if req.MaxTokens != 0 && g.limitMode == LimitModeReject {
+			return &ParameterLimitError{Parameter: "max_tokens", Limit: strconv.Itoa(limits.MaxTokens)}
+		}
+		if req.MaxTokens != 0 {
+			clamped = append(clamped, "max_tokens")
+		}
+		req.MaxTokens = limits.MaxTokens
+	}
+
+	// A zero temperature is indistinguishable from unset and is left alone.
+	if req.Temperature != 0 {
+		bounded := req.Temperature
+		if limits.MinTemperature != nil {
+			bounded = max(bounded, *limits.MinTemperature)
+		}
+		if limits.MaxTemperature != nil {
+			bounded = min(bounded, *limits.MaxTemperature)
+		}
+
+		if bounded != req.Temperature {
+			if g.limitMode == LimitModeReject {
+				return &ParameterLimitError{Parameter: "temperature", Limit: formatRange(limits)}
+			}
+			clamped = append(clamped, "temperature")
+			req.Temperature = bounded
+		}
+	}
+
+	for _, parameter := range clamped {
+		observability.ParameterClamps.WithLabelValues(parameter).Inc()
+	}
+	if len(clamped) > 0 {
+		observability.FromContext(ctx).Info("request parameters clamped",
+			observability.Any("parameters", clamped),
+		)
+	}
+
+	return nil
+}
+
+// tighten returns the stricter combination of two limits.
+func (l ParameterLimits) tighten(other ParameterLimits) ParameterLimits {
+	if other.MaxTokens > 0 && (l.MaxTokens == 0 || other.MaxTokens < l.MaxTokens) {
+		l.MaxTokens = other.MaxTokens
+	}
+	if other.MinTemperature != nil && (l.MinTemperature == nil || *other.MinTemperature > *l.MinTemperature) {
+		l.MinTemperature = other.MinTemperature
+	}
+	if other.MaxTemperature != nil && (l.MaxTemperature == nil || *other.MaxTemperature < *l.MaxTemperature) {
+		l.MaxTemperature = other.MaxTemperature
+	}
+	return l
+}
+
+// parseBound parses an optional range bound; empty means unbounded.
+func parseBound(s string) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil //nolint:nilnil // An empty bound is valid and means unbounded
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bound %q: %w", s, err)
+	}
+	return &value, nil
+}
+
+// formatRange describes a temperature range for error messages.
+func formatRange(limits ParameterLimits) string {
+	lower, upper := "", ""
+	if limits.MinTemperature != nil {
+		lower = strconv.FormatFloat(*limits.MinTemperature, 'g', -1, 64)
+	}
+	if limits.MaxTemperature != nil {
+		upper = strconv.FormatFloat(*limits.MaxTemperature, 'g', -1, 64)
+	}
+	return lower + ":" + upper
+}
@@ -0,0 +1,134 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestNewModelLimits(t *testing.T) {
+	t.Run("should combine token caps and temperature ranges", func(t *testing.T) {
+		limits, err := domain.NewModelLimits(
+			map[string]int{"gpt-4": 4096},
+			map[string]string{"gpt-4": "0.2:1.2", "gpt-4o": ":1"},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, 4096, limits["gpt-4"].MaxTokens)
+		require.InDelta(t, 0.2, *limits["gpt-4"].MinTemperature, 1e-9)
+		require.InDelta(t, 1.2, *limits["gpt-4"].MaxTemperature, 1e-9)
+		require.Nil(t, limits["gpt-4o"].MinTemperature)
+		require.InDelta(t, 1.0, *limits["gpt-4o"].MaxTemperature, 1e-9)
+	})
+
+	t.Run("should reject malformed ranges", func(t *testing.T) {
+		_, err := domain.NewModelLimits(nil, map[string]string{"gpt-4": "1.2"})
+		require.Error(t, err)
+
+		_, err = domain.NewModelLimits(nil, map[string]string{"gpt-4": "low:1"})
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_ParameterLimits(t *testing.T) {
+	maxTemperature := 1.0
+	modelLimits := map[string]domain.ParameterLimits{
+		"gpt-4": {MaxTokens: 4096, MinTemperature: nil, MaxTemperature: &maxTemperature},
+	}
+	keyPolicies := []domain.KeyPolicy{
+		{ParameterLimits: domain.ParameterLimits{MaxTokens: 512}, Name: "batch", Keys: []string{"batch"}},
+	}
+
+	// completeAndCapture runs a completion and returns the request the provider received.
+	completeAndCapture := func(
+		t *testing.T,
+		ctx context.Context,
+		gateway func(domain.ProviderRegistry, domain.CostCalculator) *domain.GatewayService,
+		req *domain.CompletionRequest,
+	) *domain.CompletionRequest {
+		t.Helper()
+
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		var sent *domain.CompletionRequest
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai").Maybe()
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, r *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				sent = r
+				return &domain.CompletionResponse{Model: "gpt-4"}, nil
+			})
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.0, nil)
+
+		_, err := gateway(mockRegistry, mockCostCalc).CompleteByModel(ctx, req)
+		require.NoError(t, err)
+		return sent
+	}
+
+	t.Run("should clamp out-of-range parameters and fill an unset max_tokens", func(t *testing.T) {
+		req := &domain.CompletionRequest{
+			Model:       "gpt-4",
+			Messages:    []domain.Message{{Role: "user", Content: "Hello"}},
+			Temperature: 1.8,
+			MaxTokens:   100000,
+		}
+
+		sent := completeAndCapture(t, context.Background(),
+			func(reg domain.ProviderRegistry, calc domain.CostCalculator) *domain.GatewayService {
+				return domain.NewGatewayService(reg, calc,
+					domain.WithParameterLimits(modelLimits, domain.LimitModeClamp))
+			}, req)
+
+		require.Equal(t, 4096, sent.MaxTokens)
+		require.InDelta(t, 1.0, sent.Temperature, 1e-9)
+		// The caller's request is not modified.
+		require.Equal(t, 100000, req.MaxTokens)
+
+		sent = completeAndCapture(t, context.Background(),
+			func(reg domain.ProviderRegistry, calc domain.CostCalculator) *domain.GatewayService {
+				return domain.NewGatewayService(reg, calc,
+					domain.WithParameterLimits(modelLimits, domain.LimitModeClamp))
+			}, &domain.CompletionRequest{Model: "gpt-4", Messages: req.Messages})
+		require.Equal(t, 4096, sent.MaxTokens)
+	})
+
+	t.Run("should apply the stricter key policy limit", func(t *testing.T) {
+		ctx := observability.WithClientKey(context.Background(), "batch")
+		sent := completeAndCapture(t, ctx,
+			func(reg domain.ProviderRegistry, calc domain.CostCalculator) *domain.GatewayService {
+				return domain.NewGatewayService(reg, calc,
+					domain.WithKeyPolicies(keyPolicies),
+					domain.WithParameterLimits(modelLimits, domain.LimitModeClamp))
+			}, &domain.CompletionRequest{
+				Model:     "gpt-4",
+				Messages:  []domain.Message{{Role: "user", Content: "Hello"}},
+				MaxTokens: 2048,
+			})
+
+		require.Equal(t, 512, sent.MaxTokens)
+	})
+
+	t.Run("should reject out-of-range parameters in reject mode", func(t *testing.T) {
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithParameterLimits(modelLimits, domain.LimitModeReject))
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:       "gpt-4",
+			Messages:    []domain.Message{{Role: "user", Content: "Hello"}},
+			Temperature: 1.5,
+		})
+
+		var limitErr *domain.ParameterLimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, "temperature", limitErr.Parameter)
+		require.Equal(t, ":1", limitErr.Limit)
+	})
+}
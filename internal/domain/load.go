@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// DefaultTenant is used when a request cannot be attributed to a tenant.
+const DefaultTenant = "default"
+
+// TenantLoad reports the current concurrency of a tenant.
+type TenantLoad struct {
+	Tenant   string `json:"tenant"`
+	InFlight int    `json:"in_flight"`
+	Queued   int    `json:"queued"`
+}
+
+// LoadTracker tracks in-flight and queued requests per tenant.
+// Counts are mirrored to the tenant gauges exposed at /metrics.
+type LoadTracker struct {
+	mu      sync.Mutex
+	tenants map[string]*TenantLoad
+}
+
+// NewLoadTracker creates a new load tracker.
+func NewLoadTracker() *LoadTracker {
+	return &LoadTracker{
+		mu:      sync.Mutex{},
+		tenants: make(map[string]*TenantLoad),
+	}
+}
+
+// Start marks a request as in flight for tenant. The returned function must be
+// called exactly once when the request finishes.
+func (t *LoadTracker) Start(tenant string) func() {
+	tenant = normalizeTenant(tenant)
+	t.update(tenant, 1, 0)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.update(tenant, -1, 0) })
+	}
+}
+
+// Enqueue marks a request as waiting for a concurrency slot. The returned
+// function must be called exactly once when the request leaves the queue.
+func (t *LoadTracker) Enqueue(tenant string) func() {
+	tenant = normalizeTenant(tenant)
+	t.update(tenant, 0, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.update(tenant, 0, -1) })
+	}
+}
+
+// Snapshot returns the current load of every active tenant, sorted by tenant.
+func (t *LoadTracker) Snapshot() []TenantLoad {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	loads := make([]TenantLoad, 0, len(t.tenants))
+	for _, load := range t.tenants {
+		loads = append(loads, *load)
+	}
+
+	sort.Slice(loads, func(i, j int) bool {
+		return loads[i].Tenant < loads[j].Tenant
+	})
+	return loads
+}
+
+func (t *LoadTracker) update(tenant string, inFlightDelta, queuedDelta int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	load, exists := t.tenants[tenant]
+	if !exists {
+		load = &TenantLoad{Tenant: tenant, InFlight: 0, Queued: 0}
+		t.tenants[tenant] = load
+	}
+
+	load.InFlight += inFlightDelta
+	load.Queued += queuedDelta
+
+	// Idle tenants are dropped, from the snapshot and from the gauges, so neither
+	// grows with every tenant ever seen.
+	if load.InFlight == 0 && load.Queued == 0 {
+		delete(t.tenants, tenant)
+		observability.TenantInFlightRequests.DeleteLabelValues(tenant)
+		observability.TenantQueuedRequests.DeleteLabelValues(tenant)
+		return
+	}
+
+	observability.TenantInFlightRequests.WithLabelValues(tenant).Set(float64(load.InFlight))
+	observability.TenantQueuedRequests.WithLabelValues(tenant).Set(float64(load.Queued))
+}
+
+func normalizeTenant(tenant string) string {
+	if tenant == "" {
+		return DefaultTenant
+	}
+	return tenant
+}
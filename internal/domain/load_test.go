@@ -0,0 +1,60 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestLoadTracker(t *testing.T) {
+	t.Run("should track in-flight and queued requests per tenant", func(t *testing.T) {
+		tracker := domain.NewLoadTracker()
+
+		doneA := tracker.Start("team-a")
+		doneB := tracker.Start("team-a")
+		dequeue := tracker.Enqueue("team-b")
+
+		require.Equal(t, []domain.TenantLoad{
+			{Tenant: "team-a", InFlight: 2, Queued: 0},
+			{Tenant: "team-b", InFlight: 0, Queued: 1},
+		}, tracker.Snapshot())
+
+		doneA()
+		doneA() // Calling done twice must not double count.
+		dequeue()
+
+		require.Equal(t, []domain.TenantLoad{
+			{Tenant: "team-a", InFlight: 1, Queued: 0},
+		}, tracker.Snapshot())
+
+		doneB()
+		require.Empty(t, tracker.Snapshot())
+	})
+
+	t.Run("should attribute requests without tenant to default tenant", func(t *testing.T) {
+		tracker := domain.NewLoadTracker()
+
+		done := tracker.Start("")
+		defer done()
+
+		snapshot := tracker.Snapshot()
+		require.Len(t, snapshot, 1)
+		require.Equal(t, domain.DefaultTenant, snapshot[0].Tenant)
+	})
+
+	t.Run("should drop the gauges of idle tenants", func(t *testing.T) {
+		tracker := domain.NewLoadTracker()
+		series := testutil.CollectAndCount(observability.TenantInFlightRequests)
+
+		done := tracker.Start("idle-team")
+		require.Equal(t, series+1, testutil.CollectAndCount(observability.TenantInFlightRequests))
+
+		done()
+		require.Equal(t, series, testutil.CollectAndCount(observability.TenantInFlightRequests))
+		require.Equal(t, series, testutil.CollectAndCount(observability.TenantQueuedRequests))
+	})
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// MetadataMaxTokensAdjusted reports the max_tokens a request was retried with
+// after a context length error.
+const MetadataMaxTokensAdjusted = "max_tokens_adjusted"
+
+const (
+	// maxTokensPromptMargin pads the estimated prompt tokens when fitting
+	// max_tokens, since estimates are not exact tokenizer counts.
+	maxTokensPromptMargin = 0.1
+
+	// minAdjustedMaxTokens is the smallest max_tokens worth retrying with.
+	minAdjustedMaxTokens = 16
+)
+
+// WithMaxTokensAdjustment retries requests failing with a context length error
+// once, with max_tokens lowered to what the model context window leaves after
+// the prompt, before surfacing the error.
+func WithMaxTokensAdjustment(capabilities CapabilityRegistry) GatewayOption {
+	return func(g *GatewayService) {
+		g.adjustMaxTokens = capabilities
+	}
+}
+
+// withAdjustedMaxTokens retries a request that failed with err with a lowered
+// max_tokens when err is a context length error and lowering it can help. The
+// retry's outcome is returned; otherwise err is returned unchanged.
+func withAdjustedMaxTokens[T any](
+	ctx context.Context,
+	g *GatewayService,
+	provider Provider,
+	req *CompletionRequest,
+	metadata map[string]string,
+	err error,
+	attempt func(context.Context, Provider, *CompletionRequest, map[string]string) (T, error),
+) (T, error) {
+	var result T
+	if g.adjustMaxTokens == nil || ErrorClass(err) != ErrorClassContextLength {
+		return result, err
+	}
+
+	maxTokens := g.fittingMaxTokens(ctx, req)
+	if maxTokens == 0 {
+		return result, err
+	}
+
+	observability.FromContext(ctx).Info("retrying with max_tokens lowered to fit the context window",
+		observability.String("model", req.Model),
+		observability.Int("requested_max_tokens", req.MaxTokens),
+		observability.Int("max_tokens", maxTokens),
+	)
+
+	adjusted := *req
+	adjusted.MaxTokens = maxTokens
+	adjustedMetadata := mergeMetadata(mergeMetadata(nil, metadata), map[string]string{
+		MetadataMaxTokensAdjusted: strconv.Itoa(maxTokens),
+	})
+	if result, err = attempt(ctx, provider, &adjusted, adjustedMetadata); err != nil {
+		observability.MaxTokensAdjustments.WithLabelValues(req.Model, "error").Inc()
+		return result, err
+	}
+	observability.MaxTokensAdjustments.WithLabelValues(req.Model, "success").Inc()
+	return result, nil
+}
+
+// fittingMaxTokens returns the max_tokens that fits the estimated prompt of req
+// in the model context window, or 0 when the window is unknown, too little of
+// it is left, or the request already asks for no more.
+func (g *GatewayService) fittingMaxTokens(ctx context.Context, req *CompletionRequest) int {
+	capabilities, err := g.adjustMaxTokens.GetCapabilities(ctx, req.Model)
+	if err != nil || capabilities.ContextWindow <= 0 {
+		return 0
+	}
+
+	tokens := CountMessagesTokens(g.tokenizers.ForModel(req.Model), req.Messages)
+	prompt := math.Ceil(float64(tokens) * (1 + maxTokensPromptMargin))
+	available := capabilities.ContextWindow - int(prompt)
+	if capabilities.MaxOutputTokens > 0 {
+		available = min(available, capabilities.MaxOutputTokens)
+	}
+
+	if available < minAdjustedMaxTokens || (req.MaxTokens > 0 && available >= req.MaxTokens) {
+		return 0
+	}
+	return available
+}
@@ -0,0 +1,104 @@
+package domain_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_MaxTokensAdjustment(t *testing.T) {
+	contextErr := &domain.ProviderError{Provider: "openai", StatusCode: 400, Code: "context_length_exceeded"}
+
+	newGateway := func(t *testing.T) (*domain.GatewayService, *mocks.MockProvider) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		provider := mocks.NewMockProvider(t)
+		provider.EXPECT().Name().Return("openai").Maybe()
+		mockRegistry.EXPECT().GetByModel(mock.Anything, mock.Anything).Return(provider, nil)
+
+		capabilities := domain.NewInMemoryCapabilityRegistry()
+		require.NoError(t, capabilities.RegisterCapabilities(context.Background(), "gpt-4",
+			domain.ModelCapabilities{ContextWindow: 1000}))
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithMaxTokensAdjustment(capabilities))
+		return gateway, provider
+	}
+	request := func(model string, maxTokens int) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:     model,
+			Messages:  []domain.Message{{Role: "user", Content: "hello"}},
+			MaxTokens: maxTokens,
+		}
+	}
+
+	t.Run("should retry once with max_tokens lowered to fit the context window", func(t *testing.T) {
+		gateway, provider := newGateway(t)
+		provider.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.MaxTokens == 4000
+		})).Return(nil, contextErr).Once()
+		// The 6-token prompt, padded by 10%, leaves 993 of the 1000-token window.
+		provider.EXPECT().Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+			return req.MaxTokens == 993
+		})).Return(&domain.CompletionResponse{Provider: "openai", Content: "ok"}, nil).Once()
+
+		response, err := gateway.CompleteByModel(context.Background(), request("gpt-4", 4000))
+		require.NoError(t, err)
+		require.Equal(t, "993", response.Metadata[domain.MetadataMaxTokensAdjusted])
+	})
+
+	t.Run("should retry a stream an upstream rejects on open with max_tokens lowered", func(t *testing.T) {
+		var mu sync.Mutex
+		var requested []int
+		provider := newOpenAIUpstream(t, "openai", "gpt-4", domain.ErrorClassContextLength, func(maxTokens int) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			requested = append(requested, maxTokens)
+			return maxTokens > 1000
+		})
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, mock.Anything).Return(provider, nil)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+		capabilities := domain.NewInMemoryCapabilityRegistry()
+		require.NoError(t, capabilities.RegisterCapabilities(context.Background(), "gpt-4",
+			domain.ModelCapabilities{ContextWindow: 1000}))
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithMaxTokensAdjustment(capabilities))
+
+		req := request("gpt-4", 4000)
+		req.Stream = true
+		chunks, err := gateway.StreamByModel(context.Background(), req)
+		require.NoError(t, err)
+
+		first := <-chunks
+		require.Equal(t, "993", first.Metadata[domain.MetadataMaxTokensAdjusted])
+		content, last := collect(chunks)
+		require.Equal(t, "ok", first.Delta+content)
+		require.NoError(t, last.Error)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, []int{4000, 993}, requested)
+	})
+
+	t.Run("should surface the error when max_tokens already fits", func(t *testing.T) {
+		gateway, provider := newGateway(t)
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, contextErr).Once()
+
+		_, err := gateway.CompleteByModel(context.Background(), request("gpt-4", 100))
+		require.ErrorIs(t, err, contextErr)
+	})
+
+	t.Run("should surface the error when the context window is unknown", func(t *testing.T) {
+		gateway, provider := newGateway(t)
+		provider.EXPECT().Complete(mock.Anything, mock.Anything).Return(nil, contextErr).Once()
+
+		_, err := gateway.CompleteByModel(context.Background(), request("gpt-4o", 4000))
+		require.ErrorIs(t, err, contextErr)
+	})
+}
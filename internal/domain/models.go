@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"maps"
+	"slices"
+	"time"
+)
 
 // CompletionRequest represents a unified LLM request.
 type CompletionRequest struct {
@@ -10,12 +14,58 @@ type CompletionRequest struct {
 	MaxTokens   int               `json:"max_tokens,omitempty"`
 	Stream      bool              `json:"stream,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+
+	// Optional sampling parameters. Pointers distinguish "unset" from a zero value.
+	// Providers reject parameters they cannot honor with an UnsupportedParameterError.
+	TopP             *float64       `json:"top_p,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	N                int            `json:"n,omitempty"`
+	Seed             *int64         `json:"seed,omitempty"`
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
+	LogitBias        map[string]int `json:"logit_bias,omitempty"`
+
+	// Tools lists the functions the model may call instead of answering.
+	Tools []Tool `json:"tools,omitempty"`
 }
 
 // Message represents a chat message.
 type Message struct {
-	Role    string `json:"role"` // user, assistant, system
+	Role    string `json:"role"` // system, developer, user, assistant, or tool
 	Content string `json:"content"`
+
+	// ToolCallID names the tool call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+
+	// ToolCalls lists the tool calls an assistant message made. Replaying them in
+	// the history lets the tool messages answering them be matched up.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function; Parameters is its JSON schema.
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a call of a tool made by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // always "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and carries its JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // CompletionResponse represents a unified LLM response.
@@ -26,6 +76,28 @@ type CompletionResponse struct {
 	Content    string    `json:"content"`
 	Usage      Usage     `json:"usage"`
 	FinishTime time.Time `json:"finish_time"`
+
+	// Choices lists every generated completion when more than one was requested (n > 1).
+	// Content always holds the first choice.
+	Choices []Choice `json:"choices,omitempty"`
+
+	// ToolCalls lists the tool calls the model made for the first choice, instead
+	// of or alongside Content.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// Metadata carries gateway annotations about how the request was handled.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// ProviderHeaders holds the upstream response headers reported by the provider.
+	// The HTTP layer decides which of them are forwarded to clients.
+	ProviderHeaders map[string]string `json:"-"`
+}
+
+// Choice is one of several completions generated for a single request.
+type Choice struct {
+	Index        int    `json:"index"`
+	Content      string `json:"content"`
+	FinishReason string `json:"finish_reason,omitempty"`
 }
 
 // StreamChunk represents a single streaming response chunk.
@@ -33,6 +105,13 @@ type StreamChunk struct {
 	Delta string `json:"delta"`
 	Done  bool   `json:"done"`
 	Error error  `json:"error,omitempty"`
+
+	// ProviderHeaders holds the upstream response headers; only set on the first chunk.
+	ProviderHeaders map[string]string `json:"-"`
+
+	// Metadata carries gateway annotations about how the request was handled; set on the
+	// first chunk, and on the last when the gateway cuts the stream short.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // Usage tracks token consumption.
@@ -41,4 +120,75 @@ type Usage struct {
 	CompletionTokens int     `json:"completion_tokens"`
 	TotalTokens      int     `json:"total_tokens"`
 	Cost             float64 `json:"cost,omitempty"`
+
+	// CachedPromptTokens counts the prompt tokens served from the provider's prompt cache.
+	// They are included in PromptTokens and billed at the cached input rate.
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
+
+	// ReasoningTokens counts hidden reasoning tokens (o1/o3-style models).
+	// They are included in CompletionTokens and billed at the reasoning rate.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+
+	// Characters counts the input characters of a speech request, billed per character.
+	Characters int `json:"characters,omitempty"`
+
+	// Images counts the images a provider reports for a request, billed per image.
+	Images int `json:"images,omitempty"`
+
+	// ProviderCost is the raw provider cost in USD, set when Cost is a marked-up or
+	// converted chargeback amount.
+	ProviderCost float64 `json:"provider_cost,omitempty"`
+	// Currency is the currency of Cost when it is a chargeback amount.
+	Currency string `json:"currency,omitempty"`
+}
+
+// CredentialStatus reports the health of a single provider credential.
+// The credential itself is never exposed; ID is a redacted form.
+type CredentialStatus struct {
+	ID            string    `json:"id"`
+	Healthy       bool      `json:"healthy"`
+	DisabledUntil time.Time `json:"disabled_until,omitzero"`
+	LastStatus    int       `json:"last_status,omitempty"`
+	InFlight      int       `json:"in_flight"`
+	Requests      int64     `json:"requests"`
+	Failures      int64     `json:"failures"`
+}
+
+// clone returns a deep copy of r, safe to hand to another goroutine while r is
+// still being modified.
+func (r *CompletionRequest) clone() *CompletionRequest {
+	c := *r
+	c.Messages = slices.Clone(r.Messages)
+	for i := range c.Messages {
+		c.Messages[i].ToolCalls = slices.Clone(c.Messages[i].ToolCalls)
+	}
+	c.Metadata = maps.Clone(r.Metadata)
+	c.TopP = clonePointer(r.TopP)
+	c.Stop = slices.Clone(r.Stop)
+	c.Seed = clonePointer(r.Seed)
+	c.FrequencyPenalty = clonePointer(r.FrequencyPenalty)
+	c.PresencePenalty = clonePointer(r.PresencePenalty)
+	c.LogitBias = maps.Clone(r.LogitBias)
+	c.Tools = slices.Clone(r.Tools)
+	return &c
+}
+
+// clone returns a deep copy of r, safe to hand to another goroutine while r is
+// still being transformed or annotated.
+func (r *CompletionResponse) clone() *CompletionResponse {
+	c := *r
+	c.Choices = slices.Clone(r.Choices)
+	c.ToolCalls = slices.Clone(r.ToolCalls)
+	c.Metadata = maps.Clone(r.Metadata)
+	c.ProviderHeaders = maps.Clone(r.ProviderHeaders)
+	return &c
+}
+
+// clonePointer returns a pointer to a copy of *p, or nil when p is nil.
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
 }
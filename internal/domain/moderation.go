@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ErrModerationUnavailable indicates no moderation-capable provider is configured.
+var ErrModerationUnavailable = errors.New("moderation is not available")
+
+// ModerationRequest asks a provider to classify content.
+type ModerationRequest struct {
+	Input []string `json:"input"`
+	Model string   `json:"model,omitempty"`
+}
+
+// ModerationResult classifies a single input.
+type ModerationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+// ModerationResponse holds one result per input, in input order.
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+}
+
+// FlaggedCategories returns the sorted categories flagged in any result.
+func (r *ModerationResponse) FlaggedCategories() []string {
+	seen := make(map[string]bool)
+	for _, result := range r.Results {
+		for category, flagged := range result.Categories {
+			if flagged {
+				seen[category] = true
+			}
+		}
+	}
+
+	categories := make([]string, 0, len(seen))
+	for category := range seen {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Flagged reports whether any input was flagged.
+func (r *ModerationResponse) Flagged() bool {
+	for _, result := range r.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// WithModeration sets the provider serving moderation requests. When preflight is
+// true, prompts are moderated before routing and flagged prompts are rejected
+// without spending completion tokens. failOpen lets requests through when the
+// moderation call itself fails.
+func WithModeration(providerName string, preflight, failOpen bool) GatewayOption {
+	return func(g *GatewayService) {
+		g.moderationProvider = providerName
+		g.preflightModeration = preflight
+		g.moderationFailOpen = failOpen
+	}
+}
+
+// Moderate classifies content using the configured moderation provider.
+func (g *GatewayService) Moderate(ctx context.Context, req *ModerationRequest) (*ModerationResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if len(req.Input) == 0 {
+		return nil, errors.New("input cannot be empty")
+	}
+
+	if g.moderationProvider == "" {
+		return nil, ErrModerationUnavailable
+	}
+
+	provider, err := g.registry.Get(ctx, g.moderationProvider)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModerationUnavailable, err)
+	}
+
+	moderator, ok := provider.(Moderator)
+	if !ok {
+		return nil, fmt.Errorf("%w: provider %s does not support moderation", ErrModerationUnavailable, g.moderationProvider)
+	}
+
+	response, err := moderator.Moderate(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation failed: %w", err)
+	}
+
+	return response, nil
+}
+
+// moderatePrompt moderates the client's messages before the request is routed.
+// Operator-injected system prompts are not moderated.
+func (g *GatewayService) moderatePrompt(ctx context.Context, req *CompletionRequest) error {
+	if !g.preflightModeration {
+		return nil
+	}
+
+	inputs := make([]string, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		if !isInstruction(msg) && strings.TrimSpace(msg.Content) != "" {
+			inputs = append(inputs, msg.Content)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	logger := observability.FromContext(ctx)
+
+	response, err := g.Moderate(ctx, &ModerationRequest{Input: inputs, Model: ""})
+	if err != nil {
+		if g.moderationFailOpen {
+			logger.Warn("pre-flight moderation failed, allowing request", observability.Error(err))
+			return nil
+		}
+		return err
+	}
+
+	if !response.Flagged() {
+		return nil
+	}
+
+	categories := response.FlaggedCategories()
+	logger.Info("prompt blocked by pre-flight moderation", observability.Any("categories", categories))
+	return &ModerationError{Categories: categories}
+}
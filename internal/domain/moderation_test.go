@@ -0,0 +1,110 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// moderatingProvider adds a scripted Moderate to a mock provider.
+type moderatingProvider struct {
+	*mocks.MockProvider
+	response *domain.ModerationResponse
+	err      error
+	inputs   []string
+}
+
+func (p *moderatingProvider) Moderate(_ context.Context, req *domain.ModerationRequest) (*domain.ModerationResponse, error) {
+	p.inputs = req.Input
+	return p.response, p.err
+}
+
+func TestGatewayService_PreflightModeration(t *testing.T) {
+	req := func() *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model: "gpt-4",
+			Messages: []domain.Message{
+				{Role: "system", Content: "Be nice."},
+				{Role: "user", Content: "Say something mean"},
+			},
+		}
+	}
+
+	t.Run("should block flagged prompt before routing", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		moderator := &moderatingProvider{
+			MockProvider: mocks.NewMockProvider(t),
+			response: &domain.ModerationResponse{Results: []domain.ModerationResult{{
+				Flagged:    true,
+				Categories: map[string]bool{"harassment": true, "violence": false},
+			}}},
+		}
+
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(moderator, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModeration("openai", true, false))
+
+		_, err := gateway.CompleteByModel(context.Background(), req())
+
+		var moderationErr *domain.ModerationError
+		require.ErrorAs(t, err, &moderationErr)
+		require.Equal(t, []string{"harassment"}, moderationErr.Categories)
+		require.Equal(t, []string{"Say something mean"}, moderator.inputs)
+	})
+
+	t.Run("should reject request when moderation fails closed", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		moderator := &moderatingProvider{MockProvider: mocks.NewMockProvider(t), err: errors.New("timeout")}
+
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(moderator, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModeration("openai", true, false))
+
+		_, err := gateway.CompleteByModel(context.Background(), req())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "moderation failed")
+	})
+
+	t.Run("should allow request when moderation fails open", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		moderator := &moderatingProvider{MockProvider: mocks.NewMockProvider(t), err: errors.New("timeout")}
+
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(moderator, nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModeration("openai", true, true))
+
+		response, err := gateway.CompleteByModel(context.Background(), req())
+		require.NoError(t, err)
+		require.Equal(t, "ok", response.Content)
+	})
+
+	t.Run("should report moderation unavailable for providers without support", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+
+		mockRegistry.EXPECT().Get(mock.Anything, "echo").Return(mocks.NewMockProvider(t), nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModeration("echo", false, false))
+
+		_, err := gateway.Moderate(context.Background(), &domain.ModerationRequest{Input: []string{"hi"}})
+		require.ErrorIs(t, err, domain.ErrModerationUnavailable)
+	})
+}
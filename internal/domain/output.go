@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// MetadataOutputLimit reports the output token ceiling a stream was cut at. It is
+// set on the final chunk of the stream.
+const MetadataOutputLimit = "output_token_limit"
+
+// outputCeiling returns the client key's output token ceiling, or 0 for none.
+func (g *GatewayService) outputCeiling(ctx context.Context) int {
+	if policy := g.keyPolicy(ctx); policy != nil {
+		return policy.MaxOutputTokens
+	}
+	return 0
+}
+
+// limitOutput ends a stream once its estimated output tokens reach ceiling,
+// protecting against models that ignore max_tokens. The delta crossing the
+// ceiling is cut to fit and sent as a final chunk annotated with
+// MetadataOutputLimit.
+func limitOutput(
+	ctx context.Context,
+	in <-chan StreamChunk,
+	ceiling int,
+	providerName string,
+) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		var tokens float64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok := <-in:
+				if !ok {
+					return
+				}
+
+				cut := len(chunk.Delta)
+				for i, r := range chunk.Delta {
+					if tokens+runeTokens(r) > float64(ceiling) {
+						cut = i
+						break
+					}
+					tokens += runeTokens(r)
+				}
+
+				if cut < len(chunk.Delta) {
+					observability.OutputLimitTruncations.WithLabelValues(providerName).Inc()
+					chunk.Delta, chunk.Done, chunk.Error = chunk.Delta[:cut], true, nil
+					chunk.Metadata = mergeMetadata(chunk.Metadata,
+						map[string]string{MetadataOutputLimit: strconv.Itoa(ceiling)})
+				}
+
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+				if chunk.Done || chunk.Error != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,89 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestGatewayService_OutputTokenCeiling(t *testing.T) {
+	newGateway := func(t *testing.T) (*domain.GatewayService, *mocks.MockProvider) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai").Maybe()
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithKeyPolicies([]domain.KeyPolicy{
+			{Name: "capped", Keys: []string{"runaway"}, MaxOutputTokens: 3},
+		}))
+		return gateway, mockProvider
+	}
+
+	t.Run("should cut the stream at the ceiling and cancel upstream", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t)
+
+		cancelled := make(chan struct{})
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, _ *domain.CompletionRequest) (<-chan domain.StreamChunk, error) {
+				upstream := make(chan domain.StreamChunk)
+				go func() {
+					defer close(upstream)
+					for {
+						select {
+						case upstream <- domain.StreamChunk{Delta: "abc"}:
+						case <-ctx.Done():
+							close(cancelled)
+							return
+						}
+					}
+				}()
+				return upstream, nil
+			})
+
+		ctx := observability.WithClientKey(context.Background(), "runaway")
+		chunks, err := gateway.StreamByModel(ctx, &domain.CompletionRequest{Model: "gpt-4", Stream: true})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var last domain.StreamChunk
+		for chunk := range chunks {
+			content.WriteString(chunk.Delta)
+			last = chunk
+		}
+
+		require.Equal(t, strings.Repeat("abc", 4), content.String(), "3 tokens at 4 ASCII characters each")
+		require.True(t, last.Done)
+		require.Equal(t, "3", last.Metadata[domain.MetadataOutputLimit])
+		<-cancelled
+	})
+
+	t.Run("should leave streams of keys without a ceiling untouched", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t)
+
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: strings.Repeat("a", 100)}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).Return((<-chan domain.StreamChunk)(upstream), nil)
+
+		chunks, err := gateway.StreamByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Stream: true})
+		require.NoError(t, err)
+
+		var last domain.StreamChunk
+		for chunk := range chunks {
+			last = chunk
+		}
+		require.True(t, last.Done)
+		require.Empty(t, last.Metadata)
+	})
+}
@@ -0,0 +1,409 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrUnknownOverride indicates no override exists under the given name.
+	ErrUnknownOverride = errors.New("unknown override")
+
+	// ErrInvalidOverride indicates an override with missing, negative, or conflicting values.
+	ErrInvalidOverride = errors.New("invalid override")
+)
+
+// PricingOverride replaces the price of a model, taking precedence over the
+// built-in, custom provider, and price catalog prices.
+type PricingOverride struct {
+	PricingConfig
+
+	Model string `json:"model"`
+}
+
+// ModelAlias maps a virtual model name to the model it routes to.
+type ModelAlias struct {
+	Alias string `json:"alias"`
+	Model string `json:"model"`
+}
+
+// OverrideSet is every pricing, model alias, and key policy override.
+type OverrideSet struct {
+	Pricing     []PricingOverride `json:"pricing"`
+	Aliases     []ModelAlias      `json:"aliases"`
+	KeyPolicies []KeyPolicy       `json:"key_policies"`
+}
+
+// OverrideStore persists overrides so changes made at runtime survive restarts.
+type OverrideStore interface {
+	// Load returns the stored overrides.
+	Load(ctx context.Context) (OverrideSet, error)
+
+	// SavePricing creates or replaces a pricing override.
+	SavePricing(ctx context.Context, override PricingOverride) error
+
+	// DeletePricing removes the pricing override for model.
+	DeletePricing(ctx context.Context, model string) error
+
+	// SaveAlias creates or replaces a model alias.
+	SaveAlias(ctx context.Context, alias ModelAlias) error
+
+	// DeleteAlias removes a model alias.
+	DeleteAlias(ctx context.Context, alias string) error
+
+	// SaveKeyPolicy creates or replaces a key policy by name.
+	SaveKeyPolicy(ctx context.Context, policy KeyPolicy) error
+
+	// DeleteKeyPolicy removes the named key policy.
+	DeleteKeyPolicy(ctx context.Context, name string) error
+}
+
+// Overrides holds the pricing, model alias, and key policy overrides managed
+// through the admin API. They take precedence over configured values. Reads are
+// served from memory, so the request path never waits on the store; changes are
+// written to the store first and applied once persisted.
+type Overrides struct {
+	store OverrideStore
+
+	// writeMu serializes changes, so mu is only held to swap in their results.
+	writeMu sync.Mutex
+
+	mu         sync.RWMutex
+	pricing    map[string]PricingConfig
+	aliases    map[string]string
+	policies   map[string]*KeyPolicy // by policy name
+	policyKeys map[string]*KeyPolicy // by client key
+}
+
+// NewOverrides creates the override manager loaded from store. A nil store keeps
+// overrides in memory only.
+func NewOverrides(ctx context.Context, store OverrideStore) (*Overrides, error) {
+	overrides := &Overrides{
+		store:      store,
+		writeMu:    sync.Mutex{},
+		mu:         sync.RWMutex{},
+		pricing:    make(map[string]PricingConfig),
+		aliases:    make(map[string]string),
+		policies:   make(map[string]*KeyPolicy),
+		policyKeys: make(map[string]*KeyPolicy),
+	}
+
+	if store == nil {
+		return overrides, nil
+	}
+
+	set, err := store.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load overrides: %w", err)
+	}
+	for _, override := range set.Pricing {
+		if err = override.validate(); err != nil {
+			return nil, err
+		}
+		overrides.pricing[override.Model] = override.PricingConfig
+	}
+	for _, alias := range set.Aliases {
+		if err = alias.validate(); err != nil {
+			return nil, err
+		}
+		overrides.aliases[alias.Alias] = alias.Model
+	}
+	for _, policy := range set.KeyPolicies {
+		if err = overrides.validatePolicy(policy); err != nil {
+			return nil, err
+		}
+		overrides.putPolicy(policy)
+	}
+
+	return overrides, nil
+}
+
+// WithOverrides applies model alias and key policy overrides. Pricing overrides
+// apply through the registry returned by PricingRegistry.
+func WithOverrides(overrides *Overrides) GatewayOption {
+	return func(g *GatewayService) {
+		g.overrides = overrides
+	}
+}
+
+// List returns every override, each kind sorted by name.
+func (o *Overrides) List() OverrideSet {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	set := OverrideSet{
+		Pricing:     make([]PricingOverride, 0, len(o.pricing)),
+		Aliases:     make([]ModelAlias, 0, len(o.aliases)),
+		KeyPolicies: make([]KeyPolicy, 0, len(o.policies)),
+	}
+	for _, model := range slices.Sorted(maps.Keys(o.pricing)) {
+		set.Pricing = append(set.Pricing, PricingOverride{PricingConfig: o.pricing[model], Model: model})
+	}
+	for _, alias := range slices.Sorted(maps.Keys(o.aliases)) {
+		set.Aliases = append(set.Aliases, ModelAlias{Alias: alias, Model: o.aliases[alias]})
+	}
+	for _, name := range slices.Sorted(maps.Keys(o.policies)) {
+		set.KeyPolicies = append(set.KeyPolicies, *o.policies[name])
+	}
+	return set
+}
+
+// SetPricing creates or replaces the pricing override for override.Model.
+func (o *Overrides) SetPricing(ctx context.Context, override PricingOverride) error {
+	if err := override.validate(); err != nil {
+		return err
+	}
+
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if o.store != nil {
+		if err := o.store.SavePricing(ctx, override); err != nil {
+			return fmt.Errorf("failed to save pricing override: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pricing[override.Model] = override.PricingConfig
+	return nil
+}
+
+// DeletePricing removes the pricing override for model, restoring its configured price.
+func (o *Overrides) DeletePricing(ctx context.Context, model string) error {
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if _, ok := o.pricing[model]; !ok {
+		return fmt.Errorf("%w: pricing for %s", ErrUnknownOverride, model)
+	}
+	if o.store != nil {
+		if err := o.store.DeletePricing(ctx, model); err != nil {
+			return fmt.Errorf("failed to delete pricing override: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.pricing, model)
+	return nil
+}
+
+// SetAlias creates or replaces a model alias.
+func (o *Overrides) SetAlias(ctx context.Context, alias ModelAlias) error {
+	if err := alias.validate(); err != nil {
+		return err
+	}
+
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if o.store != nil {
+		if err := o.store.SaveAlias(ctx, alias); err != nil {
+			return fmt.Errorf("failed to save model alias: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.aliases[alias.Alias] = alias.Model
+	return nil
+}
+
+// DeleteAlias removes a model alias.
+func (o *Overrides) DeleteAlias(ctx context.Context, alias string) error {
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if _, ok := o.aliases[alias]; !ok {
+		return fmt.Errorf("%w: alias %s", ErrUnknownOverride, alias)
+	}
+	if o.store != nil {
+		if err := o.store.DeleteAlias(ctx, alias); err != nil {
+			return fmt.Errorf("failed to delete model alias: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.aliases, alias)
+	return nil
+}
+
+// SetKeyPolicy creates or replaces the key policy named policy.Name. Its keys may
+// not belong to another overriding policy.
+func (o *Overrides) SetKeyPolicy(ctx context.Context, policy KeyPolicy) error {
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if err := o.validatePolicy(policy); err != nil {
+		return err
+	}
+	if o.store != nil {
+		if err := o.store.SaveKeyPolicy(ctx, policy); err != nil {
+			return fmt.Errorf("failed to save key policy: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.removePolicy(policy.Name)
+	o.putPolicy(policy)
+	return nil
+}
+
+// DeleteKeyPolicy removes the named key policy.
+func (o *Overrides) DeleteKeyPolicy(ctx context.Context, name string) error {
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if _, ok := o.policies[name]; !ok {
+		return fmt.Errorf("%w: key policy %s", ErrUnknownOverride, name)
+	}
+	if o.store != nil {
+		if err := o.store.DeleteKeyPolicy(ctx, name); err != nil {
+			return fmt.Errorf("failed to delete key policy: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.removePolicy(name)
+	return nil
+}
+
+// PricingRegistry returns a registry that prices models with their overrides,
+// falling back to base. Pricing registered with it is added to base.
+func (o *Overrides) PricingRegistry(base PricingRegistry) PricingRegistry {
+	return &overridePricingRegistry{overrides: o, base: base}
+}
+
+// alias returns the model an alias override routes to.
+func (o *Overrides) alias(model string) (string, bool) {
+	if o == nil {
+		return "", false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	target, ok := o.aliases[model]
+	return target, ok
+}
+
+// aliasMap returns a copy of the alias overrides.
+func (o *Overrides) aliasMap() map[string]string {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return maps.Clone(o.aliases)
+}
+
+// keyPolicy returns the overriding policy assigned to a client key, or nil.
+func (o *Overrides) keyPolicy(key string) *KeyPolicy {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.policyKeys[key]
+}
+
+// validatePolicy checks a policy and that its keys are not assigned to another
+// overriding policy. Caller must hold writeMu.
+func (o *Overrides) validatePolicy(policy KeyPolicy) error {
+	if policy.Name == "" {
+		return fmt.Errorf("%w: key policy name is required", ErrInvalidOverride)
+	}
+	if len(policy.Keys) == 0 {
+		return fmt.Errorf("%w: key policy %s applies to no keys", ErrInvalidOverride, policy.Name)
+	}
+	if _, err := ParsePriority(string(policy.Priority)); err != nil {
+		return fmt.Errorf("%w: key policy %s: %w", ErrInvalidOverride, policy.Name, err)
+	}
+	if policy.MaxOutputTokens < 0 {
+		return fmt.Errorf("%w: key policy %s: max_output_tokens must not be negative", ErrInvalidOverride, policy.Name)
+	}
+	for _, key := range policy.Keys {
+		if other, taken := o.policyKeys[key]; taken && other.Name != policy.Name {
+			return fmt.Errorf("%w: key %s is assigned to policy %s", ErrInvalidOverride, key, other.Name)
+		}
+	}
+	return nil
+}
+
+// putPolicy adds a policy and indexes its keys. Caller must hold mu.
+func (o *Overrides) putPolicy(policy KeyPolicy) {
+	stored := &policy
+	o.policies[policy.Name] = stored
+	for _, key := range policy.Keys {
+		o.policyKeys[key] = stored
+	}
+}
+
+// removePolicy removes the named policy and its keys, if present. Caller must hold mu.
+func (o *Overrides) removePolicy(name string) {
+	policy, ok := o.policies[name]
+	if !ok {
+		return
+	}
+	for _, key := range policy.Keys {
+		delete(o.policyKeys, key)
+	}
+	delete(o.policies, name)
+}
+
+// validate checks that an override names a model and has no negative price.
+func (p PricingOverride) validate() error {
+	if p.Model == "" {
+		return fmt.Errorf("%w: model is required", ErrInvalidOverride)
+	}
+	lowest := min(p.InputCostPer1K, p.OutputCostPer1K, p.CachedInputCostPer1K, p.ReasoningCostPer1K,
+		p.CharacterCostPer1K, p.RequestCost, p.ImageCost)
+	if lowest < 0 {
+		return fmt.Errorf("%w: pricing for %s has a negative price", ErrInvalidOverride, p.Model)
+	}
+	return nil
+}
+
+// validate checks that an alias names another model.
+func (a ModelAlias) validate() error {
+	if a.Alias == "" || a.Model == "" {
+		return fmt.Errorf("%w: alias and model are required", ErrInvalidOverride)
+	}
+	if a.Alias == a.Model {
+		return fmt.Errorf("%w: alias %s maps to itself", ErrInvalidOverride, a.Alias)
+	}
+	return nil
+}
+
+// overridePricingRegistry prices models with their overrides before falling back
+// to the base registry.
+type overridePricingRegistry struct {
+	overrides *Overrides
+	base      PricingRegistry
+}
+
+// GetPricing returns the override for model, or its base pricing.
+func (r *overridePricingRegistry) GetPricing(ctx context.Context, model string) (PricingConfig, error) {
+	r.overrides.mu.RLock()
+	pricing, ok := r.overrides.pricing[model]
+	r.overrides.mu.RUnlock()
+	if ok {
+		return pricing, nil
+	}
+
+	return r.base.GetPricing(ctx, model) //nolint:wrapcheck // The base registry describes the failure
+}
+
+// RegisterPricing adds pricing for a model to the base registry.
+func (r *overridePricingRegistry) RegisterPricing(ctx context.Context, model string, config PricingConfig) error {
+	return r.base.RegisterPricing(ctx, model, config) //nolint:wrapcheck // The base registry describes the failure
+}
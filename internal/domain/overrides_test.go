@@ -0,0 +1,128 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should list overrides sorted by name", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, overrides.SetAlias(ctx, domain.ModelAlias{Alias: "smart", Model: "gpt-4"}))
+		require.NoError(t, overrides.SetAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-4o-mini"}))
+
+		require.Equal(t, []domain.ModelAlias{
+			{Alias: "fast", Model: "gpt-4o-mini"},
+			{Alias: "smart", Model: "gpt-4"},
+		}, overrides.List().Aliases)
+	})
+
+	t.Run("should reject invalid overrides", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+
+		err = overrides.SetAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "fast"})
+		require.ErrorIs(t, err, domain.ErrInvalidOverride)
+
+		err = overrides.SetPricing(ctx, domain.PricingOverride{
+			PricingConfig: domain.PricingConfig{InputCostPer1K: -1},
+			Model:         "gpt-4",
+		})
+		require.ErrorIs(t, err, domain.ErrInvalidOverride)
+
+		err = overrides.SetKeyPolicy(ctx, domain.KeyPolicy{Name: "empty"})
+		require.ErrorIs(t, err, domain.ErrInvalidOverride)
+	})
+
+	t.Run("should reject a key assigned to another policy but allow replacing a policy", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, overrides.SetKeyPolicy(ctx, domain.KeyPolicy{Name: "a", Keys: []string{"ci"}}))
+		require.NoError(t, overrides.SetKeyPolicy(ctx, domain.KeyPolicy{Name: "a", Keys: []string{"ci", "bot"}}))
+
+		err = overrides.SetKeyPolicy(ctx, domain.KeyPolicy{Name: "b", Keys: []string{"bot"}})
+		require.ErrorIs(t, err, domain.ErrInvalidOverride)
+	})
+
+	t.Run("should report deleting an unknown override", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, overrides.DeleteAlias(ctx, "fast"), domain.ErrUnknownOverride)
+		require.ErrorIs(t, overrides.DeletePricing(ctx, "gpt-4"), domain.ErrUnknownOverride)
+		require.ErrorIs(t, overrides.DeleteKeyPolicy(ctx, "a"), domain.ErrUnknownOverride)
+	})
+
+	t.Run("should price overridden models before the base registry", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+		base := domain.NewInMemoryPricingRegistry()
+		registry := overrides.PricingRegistry(base)
+
+		require.NoError(t, registry.RegisterPricing(ctx, "gpt-4", domain.PricingConfig{InputCostPer1K: 0.03}))
+		require.NoError(t, overrides.SetPricing(ctx, domain.PricingOverride{
+			PricingConfig: domain.PricingConfig{InputCostPer1K: 0.01},
+			Model:         "gpt-4",
+		}))
+
+		pricing, err := registry.GetPricing(ctx, "gpt-4")
+		require.NoError(t, err)
+		require.InDelta(t, 0.01, pricing.InputCostPer1K, 1e-9)
+
+		require.NoError(t, overrides.DeletePricing(ctx, "gpt-4"))
+		pricing, err = registry.GetPricing(ctx, "gpt-4")
+		require.NoError(t, err)
+		require.InDelta(t, 0.03, pricing.InputCostPer1K, 1e-9)
+	})
+
+	t.Run("should apply an overriding key policy before configured policies", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, overrides.SetKeyPolicy(ctx, domain.KeyPolicy{
+			Name: "locked", Keys: []string{"intern"}, DenyModels: []string{"*"},
+		}))
+
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithKeyPolicies([]domain.KeyPolicy{{Name: "interns", Keys: []string{"intern"}}}),
+			domain.WithOverrides(overrides))
+
+		_, err = gateway.CompleteByModel(observability.WithClientKey(ctx, "intern"), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "locked", policyErr.Policy)
+	})
+
+	t.Run("should list overriding aliases as models", func(t *testing.T) {
+		overrides, err := domain.NewOverrides(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, overrides.SetAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-4o-mini"}))
+
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().List(ctx).Return(nil, nil)
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t),
+			domain.WithModelAliases(map[string]string{"smart": "gpt-4"}),
+			domain.WithOverrides(overrides))
+
+		models, err := gateway.Models(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []domain.ModelInfo{
+			{ID: "fast", AliasOf: "gpt-4o-mini"},
+			{ID: "smart", AliasOf: "gpt-4"},
+		}, models)
+	})
+}
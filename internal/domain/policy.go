@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// DefaultPolicyKey assigns a policy to every client key without a policy of its own,
+// including unauthenticated clients.
+const DefaultPolicyKey = "*"
+
+// KeyPolicy restricts which models and providers a set of client keys may call,
+// and in which provider regions their requests may be served, and optionally caps
+// their generation parameters and streamed output and sets their priority class.
+// Empty allow lists allow everything; deny lists take precedence over allow lists.
+// Entries ending in "*" match any name with that prefix, e.g. "gpt-4*".
+type KeyPolicy struct {
+	ParameterLimits
+
+	Name           string   `json:"name"`
+	Keys           []string `json:"keys"` // client key names, or "*" for the default policy
+	AllowModels    []string `json:"allow_models"`
+	DenyModels     []string `json:"deny_models"`
+	AllowProviders []string `json:"allow_providers"`
+	DenyProviders  []string `json:"deny_providers"`
+	Priority       Priority `json:"priority,omitempty"` // empty is interactive
+
+	// Regions restricts routing to providers hosted in these regions, e.g. "eu";
+	// empty allows every region.
+	Regions []string `json:"regions,omitempty"`
+
+	// MaxOutputTokens is a hard ceiling on the tokens streamed back, enforced by
+	// counting them in the gateway; 0 = no ceiling.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+}
+
+// ParseKeyPolicies decodes and validates a JSON array of key policies.
+func ParseKeyPolicies(data []byte) ([]KeyPolicy, error) {
+	var policies []KeyPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse key policies: %w", err)
+	}
+
+	assigned := make(map[string]string)
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return nil, errors.New("key policy name is required")
+		}
+		if len(policy.Keys) == 0 {
+			return nil, fmt.Errorf("key policy %s applies to no keys", policy.Name)
+		}
+		if _, err := ParsePriority(string(policy.Priority)); err != nil {
+			return nil, fmt.Errorf("key policy %s: %w", policy.Name, err)
+		}
+		if policy.MaxOutputTokens < 0 {
+			return nil, fmt.Errorf("key policy %s: max_output_tokens must not be negative", policy.Name)
+		}
+		for _, key := range policy.Keys {
+			if other, taken := assigned[key]; taken {
+				return nil, fmt.Errorf("key %s is assigned to policies %s and %s", key, other, policy.Name)
+			}
+			assigned[key] = policy.Name
+		}
+	}
+
+	return policies, nil
+}
+
+// WithKeyPolicies enforces model and provider restrictions per client key.
+func WithKeyPolicies(policies []KeyPolicy) GatewayOption {
+	return func(g *GatewayService) {
+		if len(policies) == 0 {
+			return
+		}
+
+		g.keyPolicies = make(map[string]*KeyPolicy)
+		for i := range policies {
+			for _, key := range policies[i].Keys {
+				g.keyPolicies[key] = &policies[i]
+			}
+		}
+	}
+}
+
+// enforcePolicy rejects requests for a model or provider the client key's policy
+// does not allow, including providers outside its regions. Empty names are not checked.
+func (g *GatewayService) enforcePolicy(ctx context.Context, model, providerName string) error {
+	policy := g.keyPolicy(ctx)
+	if policy == nil {
+		return nil
+	}
+
+	var violation *PolicyError
+	switch {
+	case model != "" && !permitted(model, policy.AllowModels, policy.DenyModels):
+		violation = &PolicyError{Policy: policy.Name, Kind: "model", Name: model}
+	case providerName != "" && !permitted(providerName, policy.AllowProviders, policy.DenyProviders):
+		violation = &PolicyError{Policy: policy.Name, Kind: "provider", Name: providerName}
+	case providerName != "" && !g.resident(policy, providerName):
+		violation = &PolicyError{Policy: policy.Name, Kind: "region", Name: g.regionOf(providerName)}
+	default:
+		return nil
+	}
+
+	observability.PolicyViolations.WithLabelValues(policy.Name, violation.Kind).Inc()
+	observability.FromContext(ctx).Info("request rejected by key policy",
+		observability.String("policy", policy.Name),
+		observability.String(violation.Kind, violation.Name),
+	)
+	return violation
+}
+
+// keyPolicy returns the policy of the calling client key, falling back to the
+// default policy. Overriding policies take precedence over configured ones.
+func (g *GatewayService) keyPolicy(ctx context.Context) *KeyPolicy {
+	if g.keyPolicies == nil && g.overrides == nil {
+		return nil
+	}
+
+	for _, key := range [...]string{observability.GetClientKey(ctx), DefaultPolicyKey} {
+		if policy := g.overrides.keyPolicy(key); policy != nil {
+			return policy
+		}
+		if policy, ok := g.keyPolicies[key]; ok {
+			return policy
+		}
+	}
+	return nil
+}
+
+// permitted reports whether name passes an allow list and a deny list.
+func permitted(name string, allow, deny []string) bool {
+	if matchesAny(name, deny) {
+		return false
+	}
+	return len(allow) == 0 || matchesAny(name, allow)
+}
+
+// matchesAny reports whether name matches a pattern; a trailing "*" matches any suffix.
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestParseKeyPolicies(t *testing.T) {
+	t.Run("should parse policies", func(t *testing.T) {
+		policies, err := domain.ParseKeyPolicies([]byte(`[
+			{"name": "interns", "keys": ["intern"], "allow_models": ["gpt-3.5-turbo"]},
+			{"name": "default", "keys": ["*"], "deny_models": ["gpt-4*"]}
+		]`))
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+		require.Equal(t, []string{"gpt-3.5-turbo"}, policies[0].AllowModels)
+	})
+
+	t.Run("should reject a key assigned to two policies", func(t *testing.T) {
+		_, err := domain.ParseKeyPolicies([]byte(`[
+			{"name": "a", "keys": ["intern"]},
+			{"name": "b", "keys": ["intern"]}
+		]`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "intern")
+	})
+
+	t.Run("should reject a policy without keys", func(t *testing.T) {
+		_, err := domain.ParseKeyPolicies([]byte(`[{"name": "a"}]`))
+		require.Error(t, err)
+	})
+
+	t.Run("should reject an unknown priority", func(t *testing.T) {
+		_, err := domain.ParseKeyPolicies([]byte(`[{"name": "a", "keys": ["k"], "priority": "urgent"}]`))
+		require.Error(t, err)
+	})
+
+	t.Run("should reject a negative output token ceiling", func(t *testing.T) {
+		_, err := domain.ParseKeyPolicies([]byte(`[{"name": "a", "keys": ["k"], "max_output_tokens": -1}]`))
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_KeyPolicies(t *testing.T) {
+	policies := []domain.KeyPolicy{
+		{Name: "interns", Keys: []string{"intern"}, AllowModels: []string{"gpt-3.5-turbo"}},
+		{Name: "default", Keys: []string{domain.DefaultPolicyKey}, DenyModels: []string{"gpt-4*"}},
+		{Name: "no-echo", Keys: []string{"ci"}, DenyProviders: []string{"echo"}},
+	}
+	request := func(model string) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:    model,
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+	}
+
+	t.Run("should reject a model outside the key's allow list before routing", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t),
+			domain.WithKeyPolicies(policies))
+
+		ctx := observability.WithClientKey(context.Background(), "intern")
+		_, err := gateway.CompleteByModel(ctx, request("gpt-4"))
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "interns", policyErr.Policy)
+		require.Equal(t, "model", policyErr.Kind)
+	})
+
+	t.Run("should apply the default policy to keys without their own", func(t *testing.T) {
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithKeyPolicies(policies))
+
+		_, err := gateway.StreamByModel(context.Background(), request("gpt-4-turbo"))
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "default", policyErr.Policy)
+	})
+
+	t.Run("should reject a denied provider once routed", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "echo4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("echo")
+
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t),
+			domain.WithKeyPolicies(policies))
+
+		ctx := observability.WithClientKey(context.Background(), "ci")
+		_, err := gateway.CompleteByModel(ctx, request("echo4"))
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "provider", policyErr.Kind)
+		require.Equal(t, "echo", policyErr.Name)
+	})
+
+	t.Run("should allow permitted requests", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-3.5-turbo").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model: "gpt-3.5-turbo",
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-3.5-turbo", mock.Anything).Return(0.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithKeyPolicies(policies))
+
+		ctx := observability.WithClientKey(context.Background(), "intern")
+		_, err := gateway.CompleteByModel(ctx, request("gpt-3.5-turbo"))
+		require.NoError(t, err)
+	})
+}
@@ -1,11 +1,30 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"slices"
+)
 
 // PricingConfig contains model pricing information.
 type PricingConfig struct {
-	InputCostPer1K  float64 // USD per 1K input tokens
-	OutputCostPer1K float64 // USD per 1K output tokens
+	InputCostPer1K  float64 `json:"input_cost_per_1k"`  // USD per 1K input tokens
+	OutputCostPer1K float64 `json:"output_cost_per_1k"` // USD per 1K output tokens
+
+	// CachedInputCostPer1K is USD per 1K prompt-cached input tokens; 0 bills them as input tokens.
+	CachedInputCostPer1K float64 `json:"cached_input_cost_per_1k,omitempty"`
+
+	// ReasoningCostPer1K is USD per 1K reasoning tokens; 0 bills them as output tokens.
+	ReasoningCostPer1K float64 `json:"reasoning_cost_per_1k,omitempty"`
+
+	// CharacterCostPer1K is USD per 1K input characters, for speech models billed by the character.
+	CharacterCostPer1K float64 `json:"character_cost_per_1k,omitempty"`
+
+	// RequestCost is USD per request, for models billed per call rather than by the token.
+	RequestCost float64 `json:"request_cost,omitempty"`
+
+	// ImageCost is USD per image, for models billed by the image.
+	ImageCost float64 `json:"image_cost,omitempty"`
 }
 
 // CostCalculator calculates cost based on token usage.
@@ -22,3 +41,28 @@ type PricingRegistry interface {
 	// RegisterPricing adds pricing for a model.
 	RegisterPricing(ctx context.Context, model string, config PricingConfig) error
 }
+
+// UnpricedModels lists the models of registered providers that have no pricing,
+// as "provider/model" in sorted order. Requests for them are billed at zero cost.
+func UnpricedModels(ctx context.Context, providers ProviderRegistry, pricing PricingRegistry) ([]string, error) {
+	names, err := providers.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+
+	var unpriced []string
+	for _, name := range names {
+		provider, err := providers.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider %s: %w", name, err)
+		}
+		for _, model := range provider.SupportedModels(ctx) {
+			if _, err := pricing.GetPricing(ctx, model); err != nil {
+				unpriced = append(unpriced, name+"/"+model)
+			}
+		}
+	}
+
+	slices.Sort(unpriced)
+	return unpriced, nil
+}
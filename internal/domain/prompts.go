@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// WithModelAliases maps virtual model names to the real models they route to.
+func WithModelAliases(aliases map[string]string) GatewayOption {
+	return func(g *GatewayService) {
+		g.modelAliases = aliases
+	}
+}
+
+// WithSystemPrompts prepends operator-defined system prompts before forwarding.
+// byKey is keyed by client API key name and byModel by the requested model or alias.
+// The key prompt comes first, followed by the model prompt and the client's messages.
+func WithSystemPrompts(byKey, byModel map[string]string) GatewayOption {
+	return func(g *GatewayService) {
+		g.keyPrompts = byKey
+		g.modelPrompts = byModel
+	}
+}
+
+// prepare runs the request hook, resolves model aliases, replaces retired models,
+// assigns experiment arms, compresses prompts, and injects configured system
+// prompts. The caller's request is never mutated; a prepared copy is returned
+// together with metadata describing how the request was handled.
+func (g *GatewayService) prepare(
+	ctx context.Context,
+	req *CompletionRequest,
+) (*CompletionRequest, map[string]string, error) {
+	prepared := *req
+
+	metadata, err := g.beforeRequest(ctx, &prepared)
+	if err != nil {
+		return nil, nil, err
+	}
+	requested := prepared.Model
+
+	if target, ok := g.resolveAlias(requested); ok {
+		prepared.Model = target
+	}
+	metadata = mergeMetadata(metadata, g.applyAutoModel(ctx, &prepared))
+	retired, err := g.retireModel(&prepared, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata = mergeMetadata(metadata, retired)
+
+	metadata = mergeMetadata(metadata, g.applyExperiment(ctx, &prepared))
+	metadata = mergeMetadata(metadata, g.compressPrompt(ctx, &prepared, requested))
+
+	var injected []Message
+
+	if prompt, ok := g.keyPrompts[observability.GetClientKey(ctx)]; ok {
+		injected = append(injected, Message{Role: "system", Content: prompt, ToolCallID: "", ToolCalls: nil})
+	}
+
+	if prompt, ok := g.modelPrompts[requested]; ok {
+		injected = append(injected, Message{Role: "system", Content: prompt, ToolCallID: "", ToolCalls: nil})
+	}
+
+	if len(injected) > 0 {
+		prepared.Messages = append(injected, prepared.Messages...)
+	}
+
+	return &prepared, metadata, nil
+}
+
+// resolveAlias returns the model an alias routes to. Overriding aliases take
+// precedence over configured ones.
+func (g *GatewayService) resolveAlias(model string) (string, bool) {
+	if target, ok := g.overrides.alias(model); ok {
+		return target, true
+	}
+	target, ok := g.modelAliases[model]
+	return target, ok
+}
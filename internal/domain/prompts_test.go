@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestGatewayService_SystemPrompts(t *testing.T) {
+	t.Run("should resolve alias and prepend key and model prompts", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(mockProvider, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.Model == "gpt-4o" &&
+					len(req.Messages) == 3 &&
+					req.Messages[0].Role == "system" && req.Messages[0].Content == "Follow company policy." &&
+					req.Messages[1].Role == "system" && req.Messages[1].Content == "You are a support agent." &&
+					req.Messages[2].Content == "Hello"
+			})).
+			Return(&domain.CompletionResponse{Model: "gpt-4o", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4o", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithModelAliases(map[string]string{"support-bot": "gpt-4o"}),
+			domain.WithSystemPrompts(
+				map[string]string{"mobile": "Follow company policy."},
+				map[string]string{"support-bot": "You are a support agent."},
+			),
+		)
+
+		ctx := observability.WithClientKey(context.Background(), "mobile")
+		req := &domain.CompletionRequest{
+			Model:    "support-bot",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+
+		_, err := gateway.CompleteByModel(ctx, req)
+		require.NoError(t, err)
+
+		// The caller's request is left untouched.
+		require.Equal(t, "support-bot", req.Model)
+		require.Len(t, req.Messages, 1)
+	})
+
+	t.Run("should forward request unchanged without matching configuration", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		req := &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, req).Return(&domain.CompletionResponse{Model: "gpt-4"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithSystemPrompts(map[string]string{"mobile": "Follow company policy."}, nil),
+		)
+
+		_, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownProvider indicates the named provider is neither registered nor disabled.
+	ErrUnknownProvider = errors.New("unknown provider")
+
+	// ErrProviderNotDisabled indicates an enable request for a provider that is already routing.
+	ErrProviderNotDisabled = errors.New("provider is not disabled")
+)
+
+// ProviderManager takes providers out of routing and puts them back at runtime,
+// e.g. during an upstream incident, without restarting the gateway.
+// Disabled providers are unregistered and held until re-enabled.
+type ProviderManager struct {
+	registry ProviderRegistry
+
+	mu       sync.Mutex
+	disabled map[string]Provider
+}
+
+// NewProviderManager creates a provider manager (DI constructor).
+func NewProviderManager(registry ProviderRegistry) *ProviderManager {
+	return &ProviderManager{
+		registry: registry,
+		mu:       sync.Mutex{},
+		disabled: make(map[string]Provider),
+	}
+}
+
+// Disable unregisters a provider so no request is routed to it.
+func (m *ProviderManager) Disable(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	provider, err := m.registry.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+
+	if err := m.registry.Unregister(ctx, name); err != nil {
+		return fmt.Errorf("failed to unregister provider %s: %w", name, err)
+	}
+
+	m.disabled[name] = provider
+	return nil
+}
+
+// Enable registers a previously disabled provider again.
+func (m *ProviderManager) Enable(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	provider, ok := m.disabled[name]
+	if !ok {
+		if _, err := m.registry.Get(ctx, name); err == nil {
+			return fmt.Errorf("%w: %s", ErrProviderNotDisabled, name)
+		}
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+
+	if err := m.registry.Register(ctx, provider); err != nil {
+		return fmt.Errorf("failed to register provider %s: %w", name, err)
+	}
+
+	delete(m.disabled, name)
+	return nil
+}
+
+// Disabled returns the names of disabled providers, sorted.
+func (m *ProviderManager) Disabled() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.disabled))
+	for name := range m.disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,53 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestProviderManager(t *testing.T) {
+	t.Run("should disable and re-enable a provider", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(mockProvider, nil).Once()
+		mockRegistry.EXPECT().Unregister(mock.Anything, "openai").Return(nil).Once()
+		mockRegistry.EXPECT().Register(mock.Anything, mockProvider).Return(nil).Once()
+
+		manager := domain.NewProviderManager(mockRegistry)
+
+		require.NoError(t, manager.Disable(ctx, "openai"))
+		require.Equal(t, []string{"openai"}, manager.Disabled())
+
+		require.NoError(t, manager.Enable(ctx, "openai"))
+		require.Empty(t, manager.Disabled())
+	})
+
+	t.Run("should reject unknown providers", func(t *testing.T) {
+		ctx := context.Background()
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().Get(mock.Anything, "missing").Return(nil, errors.New("not found"))
+
+		manager := domain.NewProviderManager(mockRegistry)
+
+		require.ErrorIs(t, manager.Disable(ctx, "missing"), domain.ErrUnknownProvider)
+		require.ErrorIs(t, manager.Enable(ctx, "missing"), domain.ErrUnknownProvider)
+	})
+
+	t.Run("should reject enabling a routing provider", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().Get(mock.Anything, "openai").Return(mocks.NewMockProvider(t), nil)
+
+		manager := domain.NewProviderManager(mockRegistry)
+
+		require.ErrorIs(t, manager.Enable(context.Background(), "openai"), domain.ErrProviderNotDisabled)
+	})
+}
@@ -0,0 +1,436 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Quota limits, as reported in QuotaExceededError.
+const (
+	QuotaRequestsPerDay = "requests_per_day"
+	QuotaTokensPerDay   = "tokens_per_day"
+	QuotaSpendPerMonth  = "spend_per_month"
+)
+
+// quotaDayLayout formats the UTC day request and token quotas apply to.
+const quotaDayLayout = "2006-01-02"
+
+var (
+	// ErrUnknownQuota indicates no quota is set for the client key.
+	ErrUnknownQuota = errors.New("unknown quota")
+
+	// ErrInvalidQuota indicates a quota without a key or with a negative limit.
+	ErrInvalidQuota = errors.New("invalid quota")
+)
+
+// Quota caps a client key's usage. Zero limits are unlimited. The key "*" sets the
+// quota of every client key without a quota of its own, counted per key.
+type Quota struct {
+	Key            string  `json:"key"`
+	RequestsPerDay int64   `json:"requests_per_day,omitempty"`
+	TokensPerDay   int64   `json:"tokens_per_day,omitempty"`
+	SpendPerMonth  float64 `json:"spend_per_month,omitempty"`
+}
+
+// QuotaUsage is a client key's usage in the current quota periods (UTC day and month).
+type QuotaUsage struct {
+	RequestsToday  int64   `json:"requests_today"`
+	TokensToday    int64   `json:"tokens_today"`
+	SpendThisMonth float64 `json:"spend_this_month"`
+}
+
+// QuotaStatus is a quota with its key's current usage.
+type QuotaStatus struct {
+	Quota
+
+	Usage QuotaUsage `json:"usage"`
+}
+
+// QuotaStore persists quotas so changes made at runtime survive restarts.
+type QuotaStore interface {
+	// Load returns the stored quotas.
+	Load(ctx context.Context) ([]Quota, error)
+
+	// Save replaces the stored quotas.
+	Save(ctx context.Context, quotas []Quota) error
+}
+
+// QuotaCounterStore is a QuotaStore that also keeps the usage counted against
+// quotas. Each call applies in a single transaction, so counts survive restarts
+// and gateways sharing the store enforce quotas together.
+type QuotaCounterStore interface {
+	QuotaStore
+
+	// Admit counts a request for key unless its usage in the periods of now has
+	// already reached a limit of quota. It returns the exhausted limit, or ""
+	// when the request was counted.
+	Admit(ctx context.Context, key string, quota Quota, now time.Time) (string, error)
+
+	// Add adds tokens to key's usage on the UTC day of at, and spend to its usage
+	// in the UTC month of at.
+	Add(ctx context.Context, key string, at time.Time, tokens int64, spend float64) error
+
+	// Usage returns key's usage in the periods of now.
+	Usage(ctx context.Context, key string, now time.Time) (QuotaUsage, error)
+}
+
+// quotaCounters tracks one client key's usage in the current day and month.
+type quotaCounters struct {
+	day      string
+	requests int64
+	tokens   int64
+	month    string
+	spend    float64
+}
+
+// QuotaManager enforces per-key request, token, and spend quotas that can be
+// changed at runtime. Usage is counted for every key, so a quota set mid-day
+// applies to usage already made. It is counted by the store when the store is a
+// QuotaCounterStore, and in memory otherwise.
+type QuotaManager struct {
+	store  QuotaStore
+	counts QuotaCounterStore
+
+	mu       sync.Mutex
+	quotas   map[string]Quota
+	counters map[string]*quotaCounters
+}
+
+// NewQuotaManager creates a quota manager loaded from store. A nil store keeps
+// quotas in memory only.
+func NewQuotaManager(ctx context.Context, store QuotaStore) (*QuotaManager, error) {
+	manager := &QuotaManager{
+		store:    store,
+		counts:   nil,
+		mu:       sync.Mutex{},
+		quotas:   make(map[string]Quota),
+		counters: make(map[string]*quotaCounters),
+	}
+
+	if store == nil {
+		return manager, nil
+	}
+	if counts, ok := store.(QuotaCounterStore); ok {
+		manager.counts = counts
+	}
+
+	quotas, err := store.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quotas: %w", err)
+	}
+	for _, quota := range quotas {
+		if err = quota.validate(); err != nil {
+			return nil, err
+		}
+		manager.quotas[quota.Key] = quota
+	}
+
+	return manager, nil
+}
+
+// WithQuotas enforces per-key quotas before requests are routed.
+func WithQuotas(manager *QuotaManager) GatewayOption {
+	return func(g *GatewayService) {
+		g.quotas = manager
+	}
+}
+
+// List returns every quota with its key's usage, sorted by key.
+func (m *QuotaManager) List(ctx context.Context) ([]QuotaStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]QuotaStatus, 0, len(m.quotas))
+	for key, quota := range m.quotas {
+		usage, err := m.usage(ctx, key, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, QuotaStatus{Quota: quota, Usage: usage})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Key < statuses[j].Key })
+	return statuses, nil
+}
+
+// Get returns the quota set for key with the key's usage.
+func (m *QuotaManager) Get(ctx context.Context, key string) (QuotaStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quota, ok := m.quotas[key]
+	if !ok {
+		return QuotaStatus{}, fmt.Errorf("%w: %s", ErrUnknownQuota, key)
+	}
+	usage, err := m.usage(ctx, key, time.Now())
+	if err != nil {
+		return QuotaStatus{}, err
+	}
+	return QuotaStatus{Quota: quota, Usage: usage}, nil
+}
+
+// Set creates or replaces the quota for quota.Key and persists it.
+func (m *QuotaManager) Set(ctx context.Context, quota Quota) error {
+	if err := quota.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, existed := m.quotas[quota.Key]
+	m.quotas[quota.Key] = quota
+	if err := m.save(ctx); err != nil {
+		if existed {
+			m.quotas[quota.Key] = previous
+		} else {
+			delete(m.quotas, quota.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the quota for key and persists the change.
+func (m *QuotaManager) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, ok := m.quotas[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownQuota, key)
+	}
+
+	delete(m.quotas, key)
+	if err := m.save(ctx); err != nil {
+		m.quotas[key] = previous
+		return err
+	}
+	return nil
+}
+
+// Seed restores this month's usage from stored usage records, so a restart does
+// not reset quota counters. Usage counted by a QuotaCounterStore already
+// survives restarts and is not seeded.
+func (m *QuotaManager) Seed(records []UsageRecord) {
+	if m.counts != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, record := range records {
+		m.add(record, now, true)
+	}
+}
+
+// Admit counts a request against the calling client key's quota, rejecting it
+// with a QuotaExceededError when a limit is already used up.
+func (m *QuotaManager) Admit(ctx context.Context) error {
+	key := observability.GetClientKey(ctx)
+	now := time.Now()
+
+	// Keys without a quota get the zero quota, which counts the request without
+	// limiting it.
+	m.mu.Lock()
+	quota, _ := m.quotaFor(key)
+	limit := ""
+	if m.counts == nil {
+		counters := m.counter(key, now)
+		limit = quota.Exceeded(QuotaUsage{
+			RequestsToday:  counters.requests,
+			TokensToday:    counters.tokens,
+			SpendThisMonth: counters.spend,
+		})
+		if limit == "" {
+			counters.requests++
+		}
+	}
+	m.mu.Unlock()
+
+	if m.counts != nil {
+		var err error
+		if limit, err = m.counts.Admit(ctx, key, quota, now); err != nil {
+			// Failing open keeps the gateway serving while the store is unavailable.
+			observability.FromContext(ctx).Error("quota admission failed", observability.Error(err))
+			return nil
+		}
+	}
+	if limit == "" {
+		return nil
+	}
+
+	retryAfter := untilNextDay(now)
+	if limit == QuotaSpendPerMonth {
+		retryAfter = untilNextMonth(now)
+	}
+
+	observability.QuotaRejections.WithLabelValues(limit).Inc()
+	observability.FromContext(ctx).Info("request rejected by quota",
+		observability.String("quota", quota.Key),
+		observability.String("limit", limit),
+	)
+	return &QuotaExceededError{Key: key, Limit: limit, RetryAfter: retryAfter}
+}
+
+// Observe adds a completed request's tokens and cost to its client key's usage.
+func (m *QuotaManager) Observe(ctx context.Context, record UsageRecord) {
+	if m.counts != nil {
+		at := record.Time
+		if at.IsZero() {
+			at = time.Now()
+		}
+		if err := m.counts.Add(ctx, record.ClientKey, at, int64(record.TotalTokens), record.Cost); err != nil {
+			observability.FromContext(ctx).Error("failed to count quota usage", observability.Error(err))
+		}
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add(record, time.Now(), false)
+}
+
+// Exceeded returns the limit of q that usage has reached, or "" when every
+// limit has room left.
+func (q Quota) Exceeded(usage QuotaUsage) string {
+	switch {
+	case q.RequestsPerDay > 0 && usage.RequestsToday >= q.RequestsPerDay:
+		return QuotaRequestsPerDay
+	case q.TokensPerDay > 0 && usage.TokensToday >= q.TokensPerDay:
+		return QuotaTokensPerDay
+	case q.SpendPerMonth > 0 && usage.SpendThisMonth >= q.SpendPerMonth:
+		return QuotaSpendPerMonth
+	default:
+		return ""
+	}
+}
+
+// enforceQuota admits the request against the client key's quota, if quotas are enabled.
+func (g *GatewayService) enforceQuota(ctx context.Context) error {
+	if g.quotas == nil {
+		return nil
+	}
+
+	return g.quotas.Admit(ctx)
+}
+
+// add counts a record's usage if it falls in the current periods; countRequest also
+// counts it as a request. Caller must hold the lock.
+func (m *QuotaManager) add(record UsageRecord, now time.Time, countRequest bool) {
+	counters := m.counter(record.ClientKey, now)
+	recorded := record.Time
+	if recorded.IsZero() {
+		recorded = now
+	}
+	recorded = recorded.UTC()
+
+	if recorded.Format(quotaDayLayout) == counters.day {
+		counters.tokens += int64(record.TotalTokens)
+		if countRequest {
+			counters.requests += int64(record.requests())
+		}
+	}
+	if recorded.Format(budgetPeriodLayout) == counters.month {
+		counters.spend += record.Cost
+	}
+}
+
+// quotaFor returns the quota that applies to key. Caller must hold the lock.
+func (m *QuotaManager) quotaFor(key string) (Quota, bool) {
+	if quota, ok := m.quotas[key]; ok {
+		return quota, true
+	}
+	quota, ok := m.quotas[DefaultPolicyKey]
+	return quota, ok
+}
+
+// counter returns key's counters, resetting periods that have ended. Caller must hold the lock.
+func (m *QuotaManager) counter(key string, now time.Time) *quotaCounters {
+	counters, ok := m.counters[key]
+	if !ok {
+		counters = &quotaCounters{day: "", requests: 0, tokens: 0, month: "", spend: 0}
+		m.counters[key] = counters
+	}
+
+	now = now.UTC()
+	if day := now.Format(quotaDayLayout); counters.day != day {
+		counters.day, counters.requests, counters.tokens = day, 0, 0
+	}
+	if month := now.Format(budgetPeriodLayout); counters.month != month {
+		counters.month, counters.spend = month, 0
+	}
+	return counters
+}
+
+// usage reports key's usage. Caller must hold the lock.
+func (m *QuotaManager) usage(ctx context.Context, key string, now time.Time) (QuotaUsage, error) {
+	if key == DefaultPolicyKey {
+		return QuotaUsage{RequestsToday: 0, TokensToday: 0, SpendThisMonth: 0}, nil
+	}
+
+	if m.counts != nil {
+		usage, err := m.counts.Usage(ctx, key, now)
+		if err != nil {
+			return QuotaUsage{}, fmt.Errorf("failed to read quota usage: %w", err)
+		}
+		return usage, nil
+	}
+
+	counters := m.counter(key, now)
+	return QuotaUsage{
+		RequestsToday:  counters.requests,
+		TokensToday:    counters.tokens,
+		SpendThisMonth: counters.spend,
+	}, nil
+}
+
+// save persists the current quotas. Caller must hold the lock.
+func (m *QuotaManager) save(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+
+	quotas := make([]Quota, 0, len(m.quotas))
+	for _, quota := range m.quotas {
+		quotas = append(quotas, quota)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Key < quotas[j].Key })
+
+	if err := m.store.Save(ctx, quotas); err != nil {
+		return fmt.Errorf("failed to save quotas: %w", err)
+	}
+	return nil
+}
+
+// validate rejects quotas without a key or with negative limits.
+func (q Quota) validate() error {
+	if q.Key == "" {
+		return fmt.Errorf("%w: key is required", ErrInvalidQuota)
+	}
+	if q.RequestsPerDay < 0 || q.TokensPerDay < 0 || q.SpendPerMonth < 0 {
+		return fmt.Errorf("%w: %s has a negative limit", ErrInvalidQuota, q.Key)
+	}
+	return nil
+}
+
+// untilNextDay returns the time left until the next UTC day starts.
+func untilNextDay(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// untilNextMonth returns the time left until the next UTC month starts.
+func untilNextMonth(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
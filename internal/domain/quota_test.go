@@ -0,0 +1,234 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// memoryQuotaStore keeps saved quotas in memory.
+type memoryQuotaStore struct {
+	quotas  []domain.Quota
+	saveErr error
+}
+
+func (s *memoryQuotaStore) Load(_ context.Context) ([]domain.Quota, error) {
+	return s.quotas, nil
+}
+
+func (s *memoryQuotaStore) Save(_ context.Context, quotas []domain.Quota) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.quotas = quotas
+	return nil
+}
+
+// countingQuotaStore is a memoryQuotaStore that also counts usage, as a shared
+// database would.
+type countingQuotaStore struct {
+	memoryQuotaStore
+
+	usage map[string]domain.QuotaUsage
+}
+
+func (s *countingQuotaStore) Admit(_ context.Context, key string, quota domain.Quota, _ time.Time) (string, error) {
+	usage := s.usage[key]
+	if limit := quota.Exceeded(usage); limit != "" {
+		return limit, nil
+	}
+	usage.RequestsToday++
+	s.usage[key] = usage
+	return "", nil
+}
+
+func (s *countingQuotaStore) Add(_ context.Context, key string, _ time.Time, tokens int64, spend float64) error {
+	usage := s.usage[key]
+	usage.TokensToday += tokens
+	usage.SpendThisMonth += spend
+	s.usage[key] = usage
+	return nil
+}
+
+func (s *countingQuotaStore) Usage(_ context.Context, key string, _ time.Time) (domain.QuotaUsage, error) {
+	return s.usage[key], nil
+}
+
+func TestQuotaManager(t *testing.T) {
+	aliceCtx := observability.WithClientKey(context.Background(), "alice")
+
+	t.Run("should reject requests beyond the daily request quota", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", RequestsPerDay: 2}))
+
+		require.NoError(t, manager.Admit(aliceCtx))
+		require.NoError(t, manager.Admit(aliceCtx))
+
+		err = manager.Admit(aliceCtx)
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Equal(t, domain.QuotaRequestsPerDay, quotaErr.Limit)
+		require.Positive(t, quotaErr.RetryAfter)
+		require.LessOrEqual(t, quotaErr.RetryAfter, 24*time.Hour)
+
+		status, err := manager.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), status.Usage.RequestsToday)
+	})
+
+	t.Run("should reject requests once tokens or spend are used up", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", TokensPerDay: 100}))
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "bob", SpendPerMonth: 1}))
+
+		manager.Observe(context.Background(), domain.UsageRecord{ClientKey: "alice", TotalTokens: 100})
+		manager.Observe(context.Background(), domain.UsageRecord{ClientKey: "bob", TotalTokens: 10, Cost: 1.5})
+
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, manager.Admit(aliceCtx), &quotaErr)
+		require.Equal(t, domain.QuotaTokensPerDay, quotaErr.Limit)
+
+		bobCtx := observability.WithClientKey(context.Background(), "bob")
+		require.ErrorAs(t, manager.Admit(bobCtx), &quotaErr)
+		require.Equal(t, domain.QuotaSpendPerMonth, quotaErr.Limit)
+	})
+
+	t.Run("should apply the default quota to keys without their own", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "*", RequestsPerDay: 1}))
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", RequestsPerDay: 5}))
+
+		bobCtx := observability.WithClientKey(context.Background(), "bob")
+		carolCtx := observability.WithClientKey(context.Background(), "carol")
+		require.NoError(t, manager.Admit(bobCtx))
+		require.Error(t, manager.Admit(bobCtx))
+		require.NoError(t, manager.Admit(carolCtx), "the default quota is counted per key")
+		require.NoError(t, manager.Admit(aliceCtx))
+		require.NoError(t, manager.Admit(aliceCtx))
+	})
+
+	t.Run("should count usage made before a quota is set", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+
+		require.NoError(t, manager.Admit(aliceCtx))
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", RequestsPerDay: 1}))
+
+		require.Error(t, manager.Admit(aliceCtx))
+	})
+
+	t.Run("should seed usage from this period's records", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", RequestsPerDay: 10}))
+
+		now := time.Now().UTC()
+		manager.Seed([]domain.UsageRecord{
+			{Time: now, ClientKey: "alice", TotalTokens: 10, Cost: 1},
+			{Time: now, ClientKey: "alice", TotalTokens: 20, Cost: 2},
+			{Time: now.AddDate(0, -2, 0), ClientKey: "alice", TotalTokens: 1000, Cost: 100},
+		})
+
+		status, err := manager.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), status.Usage.RequestsToday)
+		require.Equal(t, int64(30), status.Usage.TokensToday)
+		require.InDelta(t, 3.0, status.Usage.SpendThisMonth, 1e-9)
+	})
+
+	t.Run("should count usage in a store that counts it", func(t *testing.T) {
+		store := &countingQuotaStore{
+			memoryQuotaStore: memoryQuotaStore{quotas: []domain.Quota{{Key: "alice", TokensPerDay: 100}}},
+			usage:            map[string]domain.QuotaUsage{"alice": {RequestsToday: 3}},
+		}
+		manager, err := domain.NewQuotaManager(context.Background(), store)
+		require.NoError(t, err)
+
+		manager.Seed([]domain.UsageRecord{{Time: time.Now(), ClientKey: "alice", TotalTokens: 1000}})
+		require.NoError(t, manager.Admit(aliceCtx))
+		manager.Observe(context.Background(), domain.UsageRecord{ClientKey: "alice", TotalTokens: 100, Cost: 2})
+
+		require.Equal(t, domain.QuotaUsage{RequestsToday: 4, TokensToday: 100, SpendThisMonth: 2}, store.usage["alice"])
+		status, err := manager.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, store.usage["alice"], status.Usage)
+
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, manager.Admit(aliceCtx), &quotaErr)
+		require.Equal(t, domain.QuotaTokensPerDay, quotaErr.Limit)
+	})
+
+	t.Run("should persist changes to the store", func(t *testing.T) {
+		store := &memoryQuotaStore{quotas: []domain.Quota{{Key: "alice", RequestsPerDay: 1}}}
+		manager, err := domain.NewQuotaManager(context.Background(), store)
+		require.NoError(t, err)
+		quotas, err := manager.List(context.Background())
+		require.NoError(t, err)
+		require.Len(t, quotas, 1)
+
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "bob", TokensPerDay: 50}))
+		require.Equal(t, []domain.Quota{{Key: "alice", RequestsPerDay: 1}, {Key: "bob", TokensPerDay: 50}}, store.quotas)
+
+		require.NoError(t, manager.Delete(context.Background(), "alice"))
+		require.Equal(t, []domain.Quota{{Key: "bob", TokensPerDay: 50}}, store.quotas)
+	})
+
+	t.Run("should keep the previous quota when saving fails", func(t *testing.T) {
+		store := &memoryQuotaStore{quotas: []domain.Quota{{Key: "alice", RequestsPerDay: 1}}}
+		manager, err := domain.NewQuotaManager(context.Background(), store)
+		require.NoError(t, err)
+
+		store.saveErr = errors.New("disk full")
+		require.Error(t, manager.Set(context.Background(), domain.Quota{Key: "alice", RequestsPerDay: 9}))
+
+		status, err := manager.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), status.RequestsPerDay)
+	})
+
+	t.Run("should reject invalid quotas and unknown keys", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, manager.Set(context.Background(), domain.Quota{Key: ""}), domain.ErrInvalidQuota)
+		require.ErrorIs(t,
+			manager.Set(context.Background(), domain.Quota{Key: "alice", TokensPerDay: -1}),
+			domain.ErrInvalidQuota,
+		)
+		require.ErrorIs(t, manager.Delete(context.Background(), "alice"), domain.ErrUnknownQuota)
+		_, err = manager.Get(context.Background(), "alice")
+		require.ErrorIs(t, err, domain.ErrUnknownQuota)
+	})
+}
+
+func TestGatewayService_Quotas(t *testing.T) {
+	t.Run("should reject a request over quota before routing", func(t *testing.T) {
+		manager, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Set(context.Background(), domain.Quota{Key: "alice", TokensPerDay: 10}))
+		manager.Observe(context.Background(), domain.UsageRecord{ClientKey: "alice", TotalTokens: 10})
+
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithQuotas(manager))
+
+		ctx := observability.WithClientKey(context.Background(), "alice")
+		_, err = gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Equal(t, "alice", quotaErr.Key)
+	})
+}
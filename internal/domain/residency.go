@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"slices"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// unassignedRegion names the region of providers without one in policy errors.
+const unassignedRegion = "unassigned"
+
+// WithProviderRegions assigns providers to the regions, e.g. "eu" or "us", their
+// deployments are hosted in, so key policies can keep requests within regions.
+func WithProviderRegions(regions map[string]string) GatewayOption {
+	return func(g *GatewayService) {
+		g.providerRegions = regions
+	}
+}
+
+// resident reports whether the policy allows serving requests from the region of
+// the named provider. Policies without regions allow any provider; providers
+// without a region never satisfy a policy with regions.
+func (g *GatewayService) resident(policy *KeyPolicy, providerName string) bool {
+	if len(policy.Regions) == 0 {
+		return true
+	}
+	region, assigned := g.providerRegions[providerName]
+	return assigned && slices.Contains(policy.Regions, region)
+}
+
+// regionOf returns the region of the named provider for policy errors.
+func (g *GatewayService) regionOf(providerName string) string {
+	if region, assigned := g.providerRegions[providerName]; assigned {
+		return region
+	}
+	return unassignedRegion
+}
+
+// allowsProvider reports whether the policy allows routing to the named provider,
+// by name and by region. A nil policy allows every provider.
+func (g *GatewayService) allowsProvider(policy *KeyPolicy, providerName string) bool {
+	return policy == nil ||
+		(permitted(providerName, policy.AllowProviders, policy.DenyProviders) && g.resident(policy, providerName))
+}
+
+// residentProvider keeps the registry's choice of provider for req when the
+// client key's policy allows its region, and otherwise switches to the first
+// eligible provider in an allowed region. Without one, the choice is kept so
+// admission rejects it with a policy error.
+func (g *GatewayService) residentProvider(ctx context.Context, req *CompletionRequest, provider Provider) Provider {
+	policy := g.keyPolicy(ctx)
+	if policy == nil || g.resident(policy, provider.Name()) {
+		return provider
+	}
+
+	candidates, err := g.hintCandidates(ctx, req.Model, nil)
+	if err != nil || len(candidates) == 0 {
+		return provider
+	}
+
+	observability.FromContext(ctx).Debug("provider rerouted for data residency",
+		observability.String("policy", policy.Name),
+		observability.String("from", provider.Name()),
+		observability.String("to", candidates[0].Name()),
+	)
+	return candidates[0]
+}
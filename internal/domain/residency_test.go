@@ -0,0 +1,87 @@
+package domain_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_DataResidency(t *testing.T) {
+	euPolicy := domain.WithKeyPolicies([]domain.KeyPolicy{
+		{Name: "eu-tenants", Keys: []string{domain.DefaultPolicyKey}, Regions: []string{"eu"}},
+	})
+	// newGateway registers openai, the registry's choice for gpt-4o, and the
+	// given other providers, each serving the models listed for it.
+	newGateway := func(t *testing.T, regions map[string]string, others map[string][]string) *domain.GatewayService {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		others["openai"] = []string{"gpt-4o"}
+		names := make([]string, 0, len(others))
+		for name, models := range others {
+			provider := mocks.NewMockProvider(t)
+			provider.EXPECT().Name().Return(name).Maybe()
+			provider.EXPECT().IsModelSupported(mock.Anything, mock.Anything).
+				RunAndReturn(func(_ context.Context, model string) bool {
+					return slices.Contains(models, model)
+				}).Maybe()
+			provider.EXPECT().Complete(mock.Anything, mock.Anything).
+				Return(&domain.CompletionResponse{Provider: name, Content: "ok"}, nil).Maybe()
+			mockRegistry.EXPECT().Get(mock.Anything, name).Return(provider, nil).Maybe()
+			if name == "openai" {
+				mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4o").Return(provider, nil).Maybe()
+			}
+			names = append(names, name)
+		}
+		mockRegistry.EXPECT().List(mock.Anything).Return(names, nil).Maybe()
+
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, euPolicy, domain.WithProviderRegions(regions))
+	}
+	complete := func(gateway *domain.GatewayService) (*domain.CompletionResponse, error) {
+		return gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{Model: "gpt-4o"})
+	}
+
+	t.Run("should route to a provider of the model in an allowed region", func(t *testing.T) {
+		gateway := newGateway(t, map[string]string{"openai": "us", "azure-eu": "eu", "mistral": "eu"},
+			map[string][]string{"azure-eu": {"gpt-4o"}, "mistral": {"mistral-large"}})
+
+		response, err := complete(gateway)
+		require.NoError(t, err)
+		require.Equal(t, "azure-eu", response.Provider)
+	})
+
+	t.Run("should keep the registry's choice when it is in an allowed region", func(t *testing.T) {
+		gateway := newGateway(t, map[string]string{"openai": "eu", "azure-eu": "eu"},
+			map[string][]string{"azure-eu": {"gpt-4o"}})
+
+		response, err := complete(gateway)
+		require.NoError(t, err)
+		require.Equal(t, "openai", response.Provider)
+	})
+
+	t.Run("should reject rather than call across regions", func(t *testing.T) {
+		gateway := newGateway(t, map[string]string{"openai": "us", "mistral": "eu"},
+			map[string][]string{"mistral": {"mistral-large"}})
+
+		_, err := complete(gateway)
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, domain.PolicyError{Policy: "eu-tenants", Kind: "region", Name: "us"}, *policyErr)
+	})
+
+	t.Run("should never route to providers without a region", func(t *testing.T) {
+		gateway := newGateway(t, nil, map[string][]string{})
+
+		_, err := complete(gateway)
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "region", policyErr.Kind)
+	})
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Usage rollup granularities.
+const (
+	RollupHourly = "hour"
+	RollupDaily  = "day"
+)
+
+// rollupKey identifies the records folded into one rollup: every dimension usage
+// reports group or filter by.
+type rollupKey struct {
+	bucket    time.Time
+	tenant    string
+	clientKey string
+	provider  string
+	model     string
+	currency  string
+	tags      string
+}
+
+// RollupUsage folds records into one record per UTC hour or day, by granularity,
+// and per tenant, client key, provider, model, currency, and attribution tags,
+// so reports keep their groupings at coarser time resolution. Request details
+// such as request IDs, metadata, and scores are dropped. Records may already be
+// rollups of a finer granularity. The rollups are returned oldest first.
+func RollupUsage(records []UsageRecord, granularity string) []UsageRecord {
+	rollups := make(map[rollupKey]*UsageRecord)
+	order := make([]rollupKey, 0)
+	for _, record := range records {
+		key := rollupKey{
+			bucket:    rollupBucket(record.Time, granularity),
+			tenant:    record.Tenant,
+			clientKey: record.ClientKey,
+			provider:  record.Provider,
+			model:     record.Model,
+			currency:  record.Currency,
+			tags:      tagsKey(record.Tags),
+		}
+
+		rollup, ok := rollups[key]
+		if !ok {
+			rollup = &UsageRecord{
+				Time:             key.bucket,
+				RequestID:        "",
+				Tenant:           record.Tenant,
+				ClientKey:        record.ClientKey,
+				Provider:         record.Provider,
+				Model:            record.Model,
+				PromptTokens:     0,
+				CompletionTokens: 0,
+				TotalTokens:      0,
+				CachedTokens:     0,
+				Characters:       0,
+				Images:           0,
+				Cost:             0,
+				Stream:           false,
+				Estimated:        false,
+				Metadata:         nil,
+				Tags:             maps.Clone(record.Tags),
+				ProviderCost:     0,
+				Currency:         record.Currency,
+
+				OriginalPromptTokens:   0,
+				CompressedPromptTokens: 0,
+
+				Scores: nil,
+
+				Rollup:   granularity,
+				Requests: 0,
+			}
+			rollups[key] = rollup
+			order = append(order, key)
+		}
+
+		rollup.PromptTokens += record.PromptTokens
+		rollup.CompletionTokens += record.CompletionTokens
+		rollup.TotalTokens += record.TotalTokens
+		rollup.CachedTokens += record.CachedTokens
+		rollup.Characters += record.Characters
+		rollup.Images += record.Images
+		rollup.Cost += record.Cost
+		rollup.ProviderCost += record.ProviderCost
+		rollup.Estimated = rollup.Estimated || record.Estimated
+		rollup.OriginalPromptTokens += record.OriginalPromptTokens
+		rollup.CompressedPromptTokens += record.CompressedPromptTokens
+		rollup.Requests += record.requests()
+	}
+
+	result := make([]UsageRecord, 0, len(order))
+	for _, key := range order {
+		result = append(result, *rollups[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
+
+// rollupBucket returns the start of the UTC hour or day holding t.
+func rollupBucket(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	if granularity == RollupHourly {
+		return t.Truncate(time.Hour)
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// tagsKey encodes tags in a comparable, order-independent form.
+func tagsKey(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, name := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, name+"="+tags[name])
+	}
+	return strings.Join(pairs, "\x00")
+}
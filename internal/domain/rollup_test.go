@@ -0,0 +1,44 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestRollupUsage(t *testing.T) {
+	hour := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	teamA, teamB := map[string]string{"team": "a"}, map[string]string{"team": "b"}
+	records := []domain.UsageRecord{
+		{Time: hour.Add(5 * time.Minute), Model: "gpt-4", Tags: teamA, TotalTokens: 10, Cost: 0.1},
+		{Time: hour.Add(50 * time.Minute), Model: "gpt-4", Tags: teamA, TotalTokens: 20, Cost: 0.2},
+		{Time: hour.Add(55 * time.Minute), Model: "gpt-4", Tags: teamB, TotalTokens: 5},
+		{Time: hour.Add(-time.Minute), Model: "gpt-4", Tags: teamA, TotalTokens: 1},
+	}
+
+	t.Run("should fold records per hour and reporting dimension", func(t *testing.T) {
+		rollups := domain.RollupUsage(records, domain.RollupHourly)
+
+		require.Len(t, rollups, 3)
+		require.Equal(t, hour.Add(-time.Hour), rollups[0].Time)
+		require.Equal(t, hour, rollups[1].Time)
+		require.Equal(t, domain.RollupHourly, rollups[1].Rollup)
+		require.Equal(t, 2, rollups[1].Requests)
+		require.Equal(t, 30, rollups[1].TotalTokens)
+		require.InDelta(t, 0.3, rollups[1].Cost, 1e-9)
+		require.Equal(t, "b", rollups[2].Tags["team"])
+	})
+
+	t.Run("should fold hourly rollups into daily ones", func(t *testing.T) {
+		daily := domain.RollupUsage(domain.RollupUsage(records, domain.RollupHourly), domain.RollupDaily)
+
+		aggregates, err := domain.AggregateUsage(daily, domain.GroupByTagPrefix+"team")
+		require.NoError(t, err)
+		require.Len(t, daily, 2)
+		require.Equal(t, 3, aggregates[0].Requests)
+		require.Equal(t, 1, aggregates[1].Requests)
+	})
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ShadowTraffic duplicates a sample of completions to a secondary provider.
+// Shadow responses are discarded; only usage, latency, and a content similarity
+// score against the primary response are recorded so a model swap can be evaluated.
+type ShadowTraffic struct {
+	provider string
+	model    string
+	percent  float64
+	timeout  time.Duration
+	slots    chan struct{}
+}
+
+// NewShadowTraffic creates a shadow traffic sampler. An empty model keeps the
+// requested model. At most maxInFlight shadow requests run at once; samples
+// beyond that are dropped so shadowing never queues behind itself.
+func NewShadowTraffic(provider, model string, percent float64, timeout time.Duration, maxInFlight int) *ShadowTraffic {
+	return &ShadowTraffic{
+		provider: provider,
+		model:    model,
+		percent:  percent,
+		timeout:  timeout,
+		slots:    make(chan struct{}, max(maxInFlight, 1)),
+	}
+}
+
+// WithShadowTraffic mirrors a sample of non-streaming completions to a secondary provider.
+func WithShadowTraffic(shadow *ShadowTraffic) GatewayOption {
+	return func(g *GatewayService) {
+		g.shadow = shadow
+	}
+}
+
+// sampled reports whether the current request should be shadowed.
+func (s *ShadowTraffic) sampled() bool {
+	//nolint:gosec // Sampling does not need a cryptographic source
+	return s.percent > 0 && rand.Float64()*100 < s.percent
+}
+
+// mirror sends req to the shadow provider in the background and compares the
+// result with the primary response.
+func (g *GatewayService) mirror(
+	ctx context.Context,
+	req *CompletionRequest,
+	primary *CompletionResponse,
+	primaryLatency time.Duration,
+) {
+	if g.shadow == nil || !g.shadow.sampled() {
+		return
+	}
+
+	// The caller keeps transforming and annotating the request and response, so
+	// the shadow call gets copies of its own.
+	shadowReq := req.clone()
+	if g.shadow.model != "" {
+		shadowReq.Model = g.shadow.model
+	}
+
+	// Never copy a prompt to a provider, region, or model the request could not be routed to.
+	if !g.shadowPermitted(ctx, shadowReq.Model) {
+		observability.ShadowRequests.WithLabelValues(g.shadow.provider, "skipped").Inc()
+		return
+	}
+
+	select {
+	case g.shadow.slots <- struct{}{}:
+	default:
+		observability.ShadowRequests.WithLabelValues(g.shadow.provider, "dropped").Inc()
+		return
+	}
+
+	primary = primary.clone()
+
+	// The shadow call must outlive the client request but not the configured timeout.
+	shadowCtx := context.WithoutCancel(ctx)
+
+	go func() {
+		defer func() { <-g.shadow.slots }()
+
+		if g.shadow.timeout > 0 {
+			var cancel context.CancelFunc
+			shadowCtx, cancel = context.WithTimeout(shadowCtx, g.shadow.timeout)
+			defer cancel()
+		}
+
+		g.runShadow(shadowCtx, shadowReq, primary, primaryLatency)
+	}()
+}
+
+// shadowPermitted applies the checks admission applies to a routed request: the
+// client key's provider, region, and model lists, and the tenant's model list.
+func (g *GatewayService) shadowPermitted(ctx context.Context, model string) bool {
+	policy := g.keyPolicy(ctx)
+	if !g.allowsProvider(policy, g.shadow.provider) {
+		return false
+	}
+	if policy != nil && !permitted(model, policy.AllowModels, policy.DenyModels) {
+		return false
+	}
+	if g.tenants == nil {
+		return true
+	}
+	settings, ok := g.tenants.settings[observability.GetTenant(ctx)]
+	return !ok || permitted(model, settings.AllowModels, settings.DenyModels)
+}
+
+// runShadow completes req on the shadow provider, using the tenant's own
+// instance and credentials where it has one, and records the comparison.
+func (g *GatewayService) runShadow(
+	ctx context.Context,
+	req *CompletionRequest,
+	primary *CompletionResponse,
+	primaryLatency time.Duration,
+) {
+	logger := observability.FromContext(ctx).With(
+		observability.String("shadow_provider", g.shadow.provider),
+		observability.String("shadow_model", req.Model),
+	)
+
+	provider, err := g.registry.Get(ctx, g.shadow.provider)
+	if err != nil {
+		observability.ShadowRequests.WithLabelValues(g.shadow.provider, "error").Inc()
+		logger.Warn("shadow provider not found", observability.Error(err))
+		return
+	}
+	provider = g.tenantProvider(ctx, provider)
+
+	start := time.Now()
+	response, err := provider.Complete(ctx, req)
+	latency := time.Since(start)
+	if err != nil {
+		observability.ShadowRequests.WithLabelValues(g.shadow.provider, "error").Inc()
+		logger.Warn("shadow completion failed", observability.Error(err))
+		return
+	}
+
+	cost, _ := g.costCalculator.Calculate(ctx, response.Model, response.Usage)
+	similarity := contentSimilarity(primary.Content, response.Content)
+
+	observability.ShadowRequests.WithLabelValues(g.shadow.provider, "success").Inc()
+	observability.ShadowLatencyDelta.WithLabelValues(g.shadow.provider).Observe((latency - primaryLatency).Seconds())
+	observability.ShadowSimilarity.WithLabelValues(g.shadow.provider).Observe(similarity)
+	observability.ShadowTokens.WithLabelValues(g.shadow.provider).Add(float64(response.Usage.TotalTokens))
+	observability.ShadowCost.WithLabelValues(g.shadow.provider).Add(cost)
+
+	logger.Info("shadow completion recorded",
+		observability.Duration("primary_latency", primaryLatency),
+		observability.Duration("shadow_latency", latency),
+		observability.Int("primary_tokens", primary.Usage.TotalTokens),
+		observability.Int("shadow_tokens", response.Usage.TotalTokens),
+		observability.Float64("primary_cost", primary.Usage.Cost),
+		observability.Float64("shadow_cost", cost),
+		observability.Float64("similarity", similarity),
+	)
+}
+
+// contentSimilarity returns the Jaccard similarity of the word sets of a and b,
+// from 0 (nothing shared) to 1 (same words).
+func contentSimilarity(a, b string) float64 {
+	wordsA := wordSet(a)
+	wordsB := wordSet(b)
+
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if _, ok := wordsB[word]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func wordSet(text string) map[string]struct{} {
+	words := strings.Fields(strings.ToLower(text))
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,243 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// rewritingTransformer rewrites the content and metadata of every response.
+type rewritingTransformer struct{}
+
+func (rewritingTransformer) TransformResponse(_ context.Context, resp *domain.CompletionResponse) {
+	resp.Content = "rewritten"
+	resp.Usage.TotalTokens++
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata["rewritten"] = "true"
+}
+
+func (rewritingTransformer) NewChunkTransformer(context.Context) domain.ChunkTransformer {
+	return nil
+}
+
+func TestGatewayService_ShadowTraffic(t *testing.T) {
+	t.Run("should mirror completion to shadow provider with shadow model", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+		mockShadow := mocks.NewMockProvider(t)
+
+		req := &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+
+		mirrored := make(chan *domain.CompletionRequest, 1)
+		recorded := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "candidate").Return(mockShadow, nil)
+		mockPrimary.EXPECT().Complete(mock.Anything, req).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "Hi there"}, nil)
+		mockShadow.EXPECT().Complete(mock.Anything, mock.Anything).
+			Run(func(_ context.Context, shadowReq *domain.CompletionRequest) { mirrored <- shadowReq }).
+			Return(&domain.CompletionResponse{Model: "llama-3", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "llama-3", mock.Anything).
+			Run(func(_ context.Context, _ string, _ domain.Usage) { close(recorded) }).
+			Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "llama-3", 100, time.Second, 1)))
+
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "Hi there", response.Content)
+
+		select {
+		case shadowReq := <-mirrored:
+			require.Equal(t, "llama-3", shadowReq.Model)
+			require.Equal(t, req.Messages, shadowReq.Messages)
+			require.Equal(t, "gpt-4", req.Model)
+		case <-time.After(time.Second):
+			t.Fatal("shadow request was not sent")
+		}
+
+		// Shadow usage is costed once the shadow response arrives.
+		select {
+		case <-recorded:
+		case <-time.After(time.Second):
+			t.Fatal("shadow usage was not recorded")
+		}
+	})
+
+	t.Run("should mirror copies while transformers rewrite the response", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+		mockShadow := mocks.NewMockProvider(t)
+		recorded := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "candidate").Return(mockShadow, nil)
+		mockPrimary.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Content:  "Hi there",
+			Metadata: map[string]string{"source": "primary"},
+		}, nil)
+		mockShadow.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "llama-3", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "llama-3", mock.Anything).
+			Run(func(_ context.Context, _ string, _ domain.Usage) { close(recorded) }).
+			Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "llama-3", 100, time.Second, 1)),
+			domain.WithResponseTransformers(rewritingTransformer{}),
+		)
+
+		req := &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Metadata: map[string]string{"team": "search"},
+		}
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+
+		// Run under -race: the mirror must not share the request or response being rewritten.
+		req.Messages[0].Content = "changed"
+		req.Metadata["team"] = "ads"
+		require.Equal(t, "rewritten", response.Content)
+		require.Equal(t, "true", response.Metadata["rewritten"])
+
+		select {
+		case <-recorded:
+		case <-time.After(time.Second):
+			t.Fatal("shadow usage was not recorded")
+		}
+	})
+
+	t.Run("should not mirror when sampling percent is zero", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockPrimary.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "", 0, time.Second, 1)))
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should not mirror to a provider the client key's policy denies", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockPrimary.EXPECT().Name().Return("openai")
+		mockPrimary.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "llama-3", 100, time.Second, 1)),
+			domain.WithKeyPolicies([]domain.KeyPolicy{
+				{Name: "no-candidate", Keys: []string{"client-a"}, DenyProviders: []string{"candidate"}},
+			}),
+		)
+
+		ctx := observability.WithClientKey(context.Background(), "client-a")
+		_, err := gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should not mirror a shadow model outside the tenant's model list", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockPrimary.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "llama-3", 100, time.Second, 1)),
+			domain.WithTenants(domain.NewTenants([]domain.TenantSettings{
+				{Name: "acme", Keys: []string{"client-a"}, DenyModels: []string{"llama-*"}},
+			})),
+		)
+
+		ctx := observability.WithTenant(context.Background(), "acme")
+		_, err := gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should mirror through the tenant's own provider", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockPrimary := mocks.NewMockProvider(t)
+		mockShadow := mocks.NewMockProvider(t)
+		mockTenantShadow := mocks.NewMockProvider(t)
+		recorded := make(chan struct{})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockPrimary, nil)
+		mockRegistry.EXPECT().Get(mock.Anything, "candidate").Return(mockShadow, nil)
+		mockPrimary.EXPECT().Name().Return("openai")
+		mockShadow.EXPECT().Name().Return("candidate")
+		mockTenantShadow.EXPECT().Name().Return("candidate")
+		mockPrimary.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Content: "Hi there"}, nil)
+		mockTenantShadow.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "llama-3", Content: "Hi"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "llama-3", mock.Anything).
+			Run(func(_ context.Context, _ string, _ domain.Usage) { close(recorded) }).
+			Return(0, nil)
+
+		tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", Keys: []string{"client-a"}}})
+		tenants.SetProvider("acme", mockTenantShadow)
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithShadowTraffic(domain.NewShadowTraffic("candidate", "llama-3", 100, time.Second, 1)),
+			domain.WithTenants(tenants),
+		)
+
+		ctx := observability.WithTenant(context.Background(), "acme")
+		_, err := gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+
+		select {
+		case <-recorded:
+		case <-time.After(time.Second):
+			t.Fatal("shadow usage was not recorded")
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ErrSpeechUnavailable indicates no speech-capable provider is configured.
+var ErrSpeechUnavailable = errors.New("speech synthesis is not available")
+
+// SpeechRequest asks a provider to synthesize speech from text.
+type SpeechRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+	Voice string `json:"voice"` // a voice name, or a voice ID for ElevenLabs
+
+	// ResponseFormat is the audio format, e.g. mp3, opus, or wav; empty uses the provider default.
+	ResponseFormat string            `json:"response_format,omitempty"`
+	Speed          float64           `json:"speed,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// SpeechResponse streams synthesized audio. The caller must close Audio.
+type SpeechResponse struct {
+	Audio       io.ReadCloser
+	ContentType string
+	Provider    string
+	Model       string
+	Usage       Usage
+}
+
+// WithSynthesizers registers the providers serving speech requests. A request is
+// routed to the first synthesizer listing its model.
+func WithSynthesizers(synthesizers ...Synthesizer) GatewayOption {
+	return func(g *GatewayService) {
+		g.synthesizers = synthesizers
+	}
+}
+
+// SpeechModels returns the speech models of every synthesizer, sorted.
+func (g *GatewayService) SpeechModels(ctx context.Context) []string {
+	var models []string
+	for _, synthesizer := range g.synthesizers {
+		models = append(models, synthesizer.SpeechModels(ctx)...)
+	}
+	slices.Sort(models)
+	return slices.Compact(models)
+}
+
+// Synthesize synthesizes speech with the named provider, or with the provider
+// serving the requested model when providerName is empty. Speech is billed per
+// input character and recorded once the provider accepts the request, since
+// providers bill for the audio whether or not the client reads all of it.
+func (g *GatewayService) Synthesize(
+	ctx context.Context,
+	providerName string,
+	req *SpeechRequest,
+) (*SpeechResponse, error) {
+	if err := validateSpeech(req); err != nil {
+		return nil, err
+	}
+
+	synthesizer, err := g.routeSpeech(ctx, providerName, req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = g.enforcePolicy(ctx, req.Model, synthesizer.Name()); err != nil {
+		return nil, err
+	}
+	if err = g.enforceTenantModel(ctx, req.Model); err != nil {
+		return nil, err
+	}
+	if err = g.enforceQuota(ctx); err != nil {
+		return nil, err
+	}
+	if err = g.admitTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	observability.RecordProvider(ctx, synthesizer.Name())
+	response, err := synthesizer.Synthesize(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("speech synthesis failed: %w", err)
+	}
+
+	response.Provider = synthesizer.Name()
+	response.Model = req.Model
+	response.Usage.Characters = utf8.RuneCountInString(req.Input)
+	g.price(ctx, req.Model, &response.Usage)
+
+	g.recordUsage(ctx, UsageRecord{
+		Time:             time.Time{},
+		RequestID:        "",
+		Tenant:           "",
+		ClientKey:        "",
+		Provider:         response.Provider,
+		Model:            response.Model,
+		PromptTokens:     0,
+		CompletionTokens: 0,
+		TotalTokens:      0,
+		CachedTokens:     0,
+		Characters:       response.Usage.Characters,
+		Images:           0,
+		Cost:             response.Usage.Cost,
+		Stream:           true,
+		Estimated:        false,
+		Metadata:         nil,
+		Tags:             g.costTags(req.Metadata),
+		ProviderCost:     response.Usage.ProviderCost,
+		Currency:         response.Usage.Currency,
+
+		OriginalPromptTokens:   0,
+		CompressedPromptTokens: 0,
+
+		Scores: nil,
+
+		Rollup:   "",
+		Requests: 0,
+	})
+
+	return response, nil
+}
+
+// routeSpeech returns the named synthesizer, which must serve model, or the
+// first synthesizer serving model.
+func (g *GatewayService) routeSpeech(ctx context.Context, providerName, model string) (Synthesizer, error) {
+	if len(g.synthesizers) == 0 {
+		return nil, ErrSpeechUnavailable
+	}
+
+	for _, synthesizer := range g.synthesizers {
+		if providerName != "" && synthesizer.Name() != providerName {
+			continue
+		}
+		if slices.Contains(synthesizer.SpeechModels(ctx), model) {
+			return synthesizer, nil
+		}
+		if providerName != "" {
+			return nil, &ModelNotSupportedError{Provider: providerName, Model: model}
+		}
+	}
+
+	if providerName != "" {
+		return nil, fmt.Errorf("%w: %s does not synthesize speech", ErrUnknownProvider, providerName)
+	}
+	return nil, fmt.Errorf("%w: no provider synthesizes speech with model %s", ErrUnknownProvider, model)
+}
+
+// validateSpeech checks the fields every speech request needs.
+func validateSpeech(req *SpeechRequest) error {
+	switch {
+	case req == nil:
+		return errors.New("request cannot be nil")
+	case req.Model == "":
+		return &ValidationError{Param: "model", Message: "is required"}
+	case req.Input == "":
+		return &ValidationError{Param: "input", Message: "is required"}
+	case req.Voice == "":
+		return &ValidationError{Param: "voice", Message: "is required"}
+	case req.Speed < 0:
+		return &ValidationError{Param: "speed", Message: "must not be negative"}
+	default:
+		return nil
+	}
+}
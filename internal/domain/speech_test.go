@@ -0,0 +1,108 @@
+package domain_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// scriptedSynthesizer returns fixed audio for its models.
+type scriptedSynthesizer struct {
+	name     string
+	models   []string
+	requests int
+}
+
+func (s *scriptedSynthesizer) Name() string { return s.name }
+
+func (s *scriptedSynthesizer) SpeechModels(_ context.Context) []string { return s.models }
+
+func (s *scriptedSynthesizer) Synthesize(_ context.Context, req *domain.SpeechRequest) (*domain.SpeechResponse, error) {
+	s.requests++
+	return &domain.SpeechResponse{
+		Audio:       io.NopCloser(strings.NewReader("audio:" + req.Input)),
+		ContentType: "audio/mpeg",
+	}, nil
+}
+
+func TestGatewayService_Synthesize(t *testing.T) {
+	speechReq := func(model string) *domain.SpeechRequest {
+		return &domain.SpeechRequest{Model: model, Input: "héllo", Voice: "alloy"}
+	}
+	newGateway := func(
+		t *testing.T,
+		store domain.UsageStore,
+		synthesizers ...domain.Synthesizer,
+	) *domain.GatewayService {
+		pricing := domain.NewInMemoryPricingRegistry()
+		require.NoError(t, pricing.RegisterPricing(context.Background(), "tts-1",
+			domain.PricingConfig{CharacterCostPer1K: 15}))
+		return domain.NewGatewayService(mocks.NewMockProviderRegistry(t), domain.NewStandardCostCalculator(pricing),
+			domain.WithSynthesizers(synthesizers...), domain.WithUsageStore(store))
+	}
+
+	t.Run("should route by model and bill per input character", func(t *testing.T) {
+		store := &memoryUsageStore{}
+		eleven := &scriptedSynthesizer{name: "elevenlabs", models: []string{"eleven_flash_v2_5"}}
+		openai := &scriptedSynthesizer{name: "openai", models: []string{"tts-1"}}
+		gateway := newGateway(t, store, eleven, openai)
+
+		response, err := gateway.Synthesize(context.Background(), "", speechReq("tts-1"))
+
+		require.NoError(t, err)
+		audio, err := io.ReadAll(response.Audio)
+		require.NoError(t, err)
+		require.Equal(t, "audio:héllo", string(audio))
+		require.Equal(t, "openai", response.Provider)
+		require.Equal(t, 5, response.Usage.Characters)
+		require.InDelta(t, 0.075, response.Usage.Cost, 1e-9)
+		require.Zero(t, eleven.requests)
+
+		require.Len(t, store.records, 1)
+		require.Equal(t, "tts-1", store.records[0].Model)
+		require.Equal(t, 5, store.records[0].Characters)
+		require.InDelta(t, 0.075, store.records[0].Cost, 1e-9)
+	})
+
+	t.Run("should reject a forced provider that does not serve the model", func(t *testing.T) {
+		eleven := &scriptedSynthesizer{name: "elevenlabs", models: []string{"eleven_flash_v2_5"}}
+		gateway := newGateway(t, &memoryUsageStore{}, eleven)
+
+		_, err := gateway.Synthesize(context.Background(), "elevenlabs", speechReq("tts-1"))
+
+		var notSupported *domain.ModelNotSupportedError
+		require.ErrorAs(t, err, &notSupported)
+	})
+
+	t.Run("should reject a model no provider synthesizes", func(t *testing.T) {
+		gateway := newGateway(t, &memoryUsageStore{}, &scriptedSynthesizer{name: "openai", models: []string{"tts-1"}})
+
+		_, err := gateway.Synthesize(context.Background(), "", speechReq("gpt-4"))
+
+		require.ErrorIs(t, err, domain.ErrUnknownProvider)
+	})
+
+	t.Run("should report speech unavailable without synthesizers", func(t *testing.T) {
+		gateway := newGateway(t, &memoryUsageStore{})
+
+		_, err := gateway.Synthesize(context.Background(), "", speechReq("tts-1"))
+
+		require.ErrorIs(t, err, domain.ErrSpeechUnavailable)
+	})
+
+	t.Run("should require a voice", func(t *testing.T) {
+		gateway := newGateway(t, &memoryUsageStore{}, &scriptedSynthesizer{name: "openai", models: []string{"tts-1"}})
+
+		_, err := gateway.Synthesize(context.Background(), "", &domain.SpeechRequest{Model: "tts-1", Input: "hi"})
+
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Equal(t, "voice", validationErr.Param)
+	})
+}
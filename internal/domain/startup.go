@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Provider sources reported at startup. Test providers serve canned responses
+// and never reach an upstream API.
+const (
+	ProviderSourceTest    = "test"
+	ProviderSourceBuiltIn = "built-in"
+	ProviderSourceCustom  = "custom"
+	ProviderSourcePlugin  = "plugin"
+	ProviderSourceReplay  = "replay"
+)
+
+// Startup modes. A gateway starts degraded when it runs with reduced function,
+// such as serving only test providers.
+const (
+	StartupModeNormal   = "normal"
+	StartupModeDegraded = "degraded"
+)
+
+// ProviderStatus reports whether a provider was registered at startup.
+type ProviderStatus struct {
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	Registered bool   `json:"registered"`
+	Reason     string `json:"reason,omitempty"` // why the provider was skipped
+}
+
+// StartupStatus is a snapshot of the startup report.
+type StartupStatus struct {
+	StartedAt time.Time         `json:"started_at"`
+	Mode      string            `json:"mode"`
+	Degraded  []string          `json:"degraded,omitempty"` // why the gateway started degraded
+	Providers []ProviderStatus  `json:"providers"`
+	Settings  map[string]string `json:"settings"` // effective routing and caching settings, by name
+}
+
+// StartupReport records which providers registered or were skipped while the
+// gateway is wired, and the effective settings it runs with, so that a provider
+// missing its configuration is reported instead of silently absent.
+type StartupReport struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	degraded  []string
+	providers []ProviderStatus
+	settings  map[string]string
+}
+
+// NewStartupReport creates an empty startup report (DI constructor).
+func NewStartupReport() *StartupReport {
+	return &StartupReport{
+		mu:        sync.Mutex{},
+		startedAt: time.Now(),
+		degraded:  nil,
+		providers: nil,
+		settings:  make(map[string]string),
+	}
+}
+
+// Registered records a provider that was registered.
+func (r *StartupReport) Registered(name, source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = append(r.providers, ProviderStatus{Name: name, Source: source, Registered: true, Reason: ""})
+}
+
+// Skipped records a provider that was not registered and why.
+func (r *StartupReport) Skipped(name, source, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = append(r.providers, ProviderStatus{Name: name, Source: source, Registered: false, Reason: reason})
+}
+
+// Degrade records a condition that leaves the gateway running with reduced function.
+func (r *StartupReport) Degrade(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.degraded = append(r.degraded, reason)
+}
+
+// Set records the effective value of a setting.
+func (r *StartupReport) Set(name, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[name] = value
+}
+
+// Status returns a snapshot of the report.
+func (r *StartupReport) Status() StartupStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mode := StartupModeNormal
+	if len(r.degraded) > 0 {
+		mode = StartupModeDegraded
+	}
+	return StartupStatus{
+		StartedAt: r.startedAt,
+		Mode:      mode,
+		Degraded:  slices.Clone(r.degraded),
+		Providers: slices.Clone(r.providers),
+		Settings:  maps.Clone(r.settings),
+	}
+}
+
+// Log writes the report: one line per skipped provider, a warning when the
+// gateway starts degraded, and a summary line.
+func (r *StartupReport) Log(ctx context.Context) {
+	status := r.Status()
+	logger := observability.FromContext(ctx)
+
+	var registered []string
+	for _, provider := range status.Providers {
+		if provider.Registered {
+			registered = append(registered, provider.Name)
+			continue
+		}
+		logger.Warn("provider skipped",
+			observability.String("provider", provider.Name),
+			observability.String("source", provider.Source),
+			observability.String("reason", provider.Reason),
+		)
+	}
+
+	if status.Mode == StartupModeDegraded {
+		logger.Warn("starting in degraded mode", observability.Strings("reasons", status.Degraded))
+	}
+
+	fields := []observability.Field{
+		observability.String("mode", status.Mode),
+		observability.Strings("providers", registered),
+		observability.Int("skipped", len(status.Providers)-len(registered)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(status.Settings)) {
+		fields = append(fields, observability.String(name, status.Settings[name]))
+	}
+	logger.Info("startup report", fields...)
+}
@@ -0,0 +1,57 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestStartupReport(t *testing.T) {
+	t.Run("should report registered and skipped providers in order", func(t *testing.T) {
+		report := domain.NewStartupReport()
+		report.Registered("echo", domain.ProviderSourceTest)
+		report.Skipped("openai", domain.ProviderSourceBuiltIn, "OPENAI_API_KEY is not set")
+		report.Set("routing", "model")
+
+		status := report.Status()
+
+		require.Equal(t, []domain.ProviderStatus{
+			{Name: "echo", Source: domain.ProviderSourceTest, Registered: true},
+			{Name: "openai", Source: domain.ProviderSourceBuiltIn, Reason: "OPENAI_API_KEY is not set"},
+		}, status.Providers)
+		require.Equal(t, map[string]string{"routing": "model"}, status.Settings)
+		require.False(t, status.StartedAt.IsZero())
+		require.Equal(t, domain.StartupModeNormal, status.Mode)
+		require.Empty(t, status.Degraded)
+
+		report.Log(context.Background())
+	})
+
+	t.Run("should report degraded mode with its reasons", func(t *testing.T) {
+		report := domain.NewStartupReport()
+		report.Registered("echo", domain.ProviderSourceTest)
+		report.Degrade("no upstream provider is registered")
+
+		status := report.Status()
+
+		require.Equal(t, domain.StartupModeDegraded, status.Mode)
+		require.Equal(t, []string{"no upstream provider is registered"}, status.Degraded)
+
+		report.Log(context.Background())
+	})
+
+	t.Run("should return a snapshot unaffected by later changes", func(t *testing.T) {
+		report := domain.NewStartupReport()
+		report.Set("routing", "model")
+
+		status := report.Status()
+		report.Set("routing", "plugin routers, then model")
+		report.Registered("echo", domain.ProviderSourceBuiltIn)
+
+		require.Equal(t, "model", status.Settings["routing"])
+		require.Empty(t, status.Providers)
+	})
+}
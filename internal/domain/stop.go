@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// finishReasonStop reports a completion that ended at a stop sequence.
+const finishReasonStop = "stop"
+
+// WithStopEmulation enforces stop sequences in the gateway for the listed
+// providers, which do not support them natively. Their requests are sent without
+// stop sequences; responses are truncated at the earliest one, and streams end
+// (cancelling the upstream stream) as soon as one appears.
+func WithStopEmulation(providers []string) GatewayOption {
+	return func(g *GatewayService) {
+		g.stopEmulation = providers
+	}
+}
+
+// emulateStop returns the request to send to provider and the stop sequences the
+// gateway must enforce, if any. The caller's request is never mutated.
+func (g *GatewayService) emulateStop(provider Provider, req *CompletionRequest) (*CompletionRequest, []string) {
+	if len(req.Stop) == 0 || !slices.Contains(g.stopEmulation, provider.Name()) {
+		return req, nil
+	}
+
+	upstream := *req
+	upstream.Stop = nil
+	return &upstream, req.Stop
+}
+
+// streamProvider opens the provider stream and applies the gateway's output
+// enforcement: emulated stop sequences, the output filter, and the client key's
+// output token ceiling. Each stage ends the stream early by sending a final
+// chunk; the upstream stream is cancelled once the stream ends, so no further
+// tokens are generated.
+func (g *GatewayService) streamProvider(
+	ctx context.Context,
+	provider Provider,
+	req *CompletionRequest,
+) (<-chan StreamChunk, error) {
+	upstreamReq, stops := g.emulateStop(provider, req)
+	ceiling := g.outputCeiling(ctx)
+	if stops == nil && g.outputFilter == nil && ceiling == 0 {
+		return provider.Stream(ctx, req) //nolint:wrapcheck // Wrapped by the caller
+	}
+
+	upstreamCtx, cancel := context.WithCancel(ctx)
+	chunks, err := provider.Stream(upstreamCtx, upstreamReq)
+	if err != nil {
+		cancel()
+		return nil, err //nolint:wrapcheck // Wrapped by the caller
+	}
+	if stops != nil {
+		chunks = enforceStop(upstreamCtx, chunks, stops, provider.Name())
+	}
+	if g.outputFilter != nil {
+		chunks = g.filterStream(upstreamCtx, chunks, provider.Name())
+	}
+	if ceiling > 0 {
+		chunks = limitOutput(upstreamCtx, chunks, ceiling, provider.Name())
+	}
+	return releaseOnClose(ctx, chunks, cancel), nil
+}
+
+// cutAtStop truncates content before the earliest stop sequence, reporting whether
+// one was found.
+func cutAtStop(content string, stops []string) (string, bool) {
+	cut := -1
+	for _, stop := range stops {
+		if i := strings.Index(content, stop); stop != "" && i >= 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return content, false
+	}
+	return content[:cut], true
+}
+
+// applyStop truncates every choice of a response at its earliest stop sequence.
+func applyStop(response *CompletionResponse, stops []string) {
+	truncated := false
+	if content, found := cutAtStop(response.Content, stops); found {
+		response.Content = content
+		truncated = true
+	}
+	for i := range response.Choices {
+		if content, found := cutAtStop(response.Choices[i].Content, stops); found {
+			response.Choices[i].Content = content
+			response.Choices[i].FinishReason = finishReasonStop
+			truncated = true
+		}
+	}
+
+	if truncated {
+		observability.StopSequenceTruncations.WithLabelValues(response.Provider).Inc()
+	}
+}
+
+// enforceStop ends a stream at the earliest stop sequence. Text that could be the
+// start of a stop sequence is held back until the next chunk rules it out, so
+// sequences split across chunks are caught.
+func enforceStop(
+	ctx context.Context,
+	in <-chan StreamChunk,
+	stops []string,
+	providerName string,
+) <-chan StreamChunk {
+	holdback := 0
+	for _, stop := range stops {
+		holdback = max(holdback, len(stop)-1)
+	}
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var pending string
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok := <-in:
+				if !ok {
+					if pending != "" {
+						send(StreamChunk{Delta: pending, Done: false, Error: nil, ProviderHeaders: nil, Metadata: nil})
+					}
+					return
+				}
+
+				pending += chunk.Delta
+				if content, found := cutAtStop(pending, stops); found {
+					observability.StopSequenceTruncations.WithLabelValues(providerName).Inc()
+					chunk.Delta, chunk.Done, chunk.Error = content, true, nil
+					send(chunk)
+					return
+				}
+
+				if chunk.Done || chunk.Error != nil {
+					chunk.Delta, pending = pending, ""
+					if !send(chunk) || chunk.Done {
+						return
+					}
+					continue
+				}
+
+				// Hold back a possible stop sequence prefix, never splitting a rune.
+				safe := max(len(pending)-holdback, 0)
+				for safe > 0 && safe < len(pending) && !utf8.RuneStart(pending[safe]) {
+					safe--
+				}
+				chunk.Delta, pending = pending[:safe], pending[safe:]
+				if chunk.Delta == "" && chunk.ProviderHeaders == nil && chunk.Metadata == nil {
+					continue
+				}
+				if !send(chunk) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
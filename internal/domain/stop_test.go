@@ -0,0 +1,121 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestGatewayService_StopEmulation(t *testing.T) {
+	newGateway := func(t *testing.T, providerName string) (*domain.GatewayService, *mocks.MockProvider) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "llama3").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return(providerName).Maybe()
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithStopEmulation([]string{"ollama"}))
+		return gateway, mockProvider
+	}
+	withoutStop := mock.MatchedBy(func(req *domain.CompletionRequest) bool { return req.Stop == nil })
+
+	t.Run("should truncate responses at the earliest stop sequence", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t, "ollama")
+		mockProvider.EXPECT().Complete(mock.Anything, withoutStop).Return(&domain.CompletionResponse{
+			Provider: "ollama",
+			Content:  "one, two. three\nfour",
+			Choices: []domain.Choice{
+				{Index: 0, Content: "one, two. three\nfour", FinishReason: "length"},
+				{Index: 1, Content: "no stop here", FinishReason: "length"},
+			},
+		}, nil)
+
+		req := &domain.CompletionRequest{Model: "llama3", Stop: []string{"\n", "."}}
+		response, err := gateway.CompleteByModel(context.Background(), req)
+		require.NoError(t, err)
+
+		require.Equal(t, "one, two", response.Content)
+		require.Equal(t, domain.Choice{Index: 0, Content: "one, two", FinishReason: "stop"}, response.Choices[0])
+		require.Equal(t, domain.Choice{Index: 1, Content: "no stop here", FinishReason: "length"}, response.Choices[1])
+		require.Equal(t, []string{"\n", "."}, req.Stop, "the caller's request must not be mutated")
+	})
+
+	t.Run("should pass stop sequences to providers that support them", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t, "openai")
+		withStop := mock.MatchedBy(func(req *domain.CompletionRequest) bool { return len(req.Stop) == 1 })
+		mockProvider.EXPECT().Complete(mock.Anything, withStop).
+			Return(&domain.CompletionResponse{Provider: "openai", Content: "a.b"}, nil)
+
+		response, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "llama3", Stop: []string{"."}})
+		require.NoError(t, err)
+		require.Equal(t, "a.b", response.Content)
+	})
+
+	t.Run("should end streams at a stop sequence split across chunks and cancel upstream", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t, "ollama")
+
+		upstream := make(chan domain.StreamChunk)
+		cancelled := make(chan struct{})
+		mockProvider.EXPECT().Stream(mock.Anything, withoutStop).
+			RunAndReturn(func(ctx context.Context, _ *domain.CompletionRequest) (<-chan domain.StreamChunk, error) {
+				go func() {
+					defer close(upstream)
+					for _, delta := range []string{"Héllo wor", "ld EN", "D of text", " never sent"} {
+						select {
+						case upstream <- domain.StreamChunk{Delta: delta}:
+						case <-ctx.Done():
+							close(cancelled)
+							return
+						}
+					}
+				}()
+				return upstream, nil
+			})
+
+		chunks, err := gateway.StreamByModel(context.Background(),
+			&domain.CompletionRequest{Model: "llama3", Stream: true, Stop: []string{"END"}})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var last domain.StreamChunk
+		for chunk := range chunks {
+			require.True(t, utf8.ValidString(chunk.Delta), "runes must not be split")
+			content.WriteString(chunk.Delta)
+			last = chunk
+		}
+
+		require.Equal(t, "Héllo world ", content.String())
+		require.True(t, last.Done)
+		<-cancelled
+	})
+
+	t.Run("should flush held back text when the stream ends without a stop sequence", func(t *testing.T) {
+		gateway, mockProvider := newGateway(t, "ollama")
+
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: "ends with EN"}
+		upstream <- domain.StreamChunk{Delta: "", Done: true}
+		close(upstream)
+		mockProvider.EXPECT().Stream(mock.Anything, withoutStop).Return((<-chan domain.StreamChunk)(upstream), nil)
+
+		chunks, err := gateway.StreamByModel(context.Background(),
+			&domain.CompletionRequest{Model: "llama3", Stream: true, Stop: []string{"END"}})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		for chunk := range chunks {
+			content.WriteString(chunk.Delta)
+		}
+		require.Equal(t, "ends with EN", content.String())
+	})
+}
@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// timeStream forwards chunks, observing the time from start to the first chunk
+// and, for streams that complete successfully, to the final chunk. onFirstChunk,
+// when set, is also called with the time to the first chunk.
+func timeStream(
+	ctx context.Context,
+	in <-chan StreamChunk,
+	provider, model string,
+	start time.Time,
+	onFirstChunk func(time.Duration),
+) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		first := true
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok := <-in:
+				if !ok {
+					return
+				}
+
+				elapsed := time.Since(start).Seconds()
+				if first && chunk.Error == nil {
+					observability.StreamFirstChunkLatency.WithLabelValues(provider, model).Observe(elapsed)
+					if onFirstChunk != nil {
+						onFirstChunk(time.Since(start))
+					}
+				}
+				first = false
+				if chunk.Done && chunk.Error == nil {
+					observability.StreamDuration.WithLabelValues(provider, model).Observe(elapsed)
+				}
+
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// releaseOnClose forwards chunks and calls release once the stream ends,
+// either because the provider closed it or because ctx was cancelled.
+func releaseOnClose(ctx context.Context, in <-chan StreamChunk, release func()) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+		defer release()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok := <-in:
+				if !ok {
+					return
+				}
+
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// streamDecoration describes gateway processing applied to a provider stream.
+type streamDecoration struct {
+	transformers []ResponseTransformer
+	metadata     map[string]string    // attached to the first chunk
+	onComplete   func(content string) // called once with the provider content streamed when the stream ends
+}
+
+func (d streamDecoration) empty() bool {
+	return len(d.transformers) == 0 && len(d.metadata) == 0 && d.onComplete == nil
+}
+
+// decorateStream applies the transformer pipeline to every chunk of a stream,
+// attaches gateway metadata to the first chunk, and reports the content once
+// the stream ends. A stream cut short by a disconnect, a cancellation, or an
+// upstream failure reports the content streamed so far, as the provider bills
+// for it; one failing before any content reports nothing. Content the
+// transformers still buffer when the provider closes the stream without a done
+// chunk is flushed into a final chunk.
+func decorateStream(ctx context.Context, in <-chan StreamChunk, decoration streamDecoration) <-chan StreamChunk {
+	pipeline := make([]ChunkTransformer, 0, len(decoration.transformers))
+	for _, transformer := range decoration.transformers {
+		pipeline = append(pipeline, transformer.NewChunkTransformer(ctx))
+	}
+
+	metadata := decoration.metadata
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		var content strings.Builder
+		failed, done := false, false
+		if decoration.onComplete != nil {
+			defer func() {
+				if !failed || content.Len() > 0 {
+					decoration.onComplete(content.String())
+				}
+			}()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chunk, ok := <-in:
+				if !ok {
+					if !done && !failed && len(pipeline) > 0 {
+						flushTransformers(ctx, out, pipeline, metadata)
+					}
+					return
+				}
+
+				content.WriteString(chunk.Delta)
+				failed = chunk.Error != nil
+				done = done || chunk.Done
+
+				for _, transform := range pipeline {
+					chunk = transform(chunk)
+				}
+
+				if metadata != nil {
+					chunk.Metadata = mergeMetadata(chunk.Metadata, metadata)
+					metadata = nil
+				}
+
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// flushTransformers sends the content the transformers of a stream still buffer
+// when it ended without a done chunk. Transformers flush on the done chunk, so
+// one is passed through the pipeline and relayed without its done flag: the
+// provider never completed the stream.
+func flushTransformers(ctx context.Context, out chan<- StreamChunk, pipeline []ChunkTransformer,
+	metadata map[string]string,
+) {
+	chunk := StreamChunk{Delta: "", Done: true, Error: nil, ProviderHeaders: nil, Metadata: nil}
+	for _, transform := range pipeline {
+		chunk = transform(chunk)
+	}
+	if chunk.Delta == "" {
+		return
+	}
+
+	chunk.Done = false
+	if metadata != nil {
+		chunk.Metadata = mergeMetadata(chunk.Metadata, metadata)
+	}
+
+	select {
+	case out <- chunk:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,273 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Tenant limits, as reported in QuotaExceededError.
+const (
+	QuotaTenantRequestsPerMinute = "tenant_requests_per_minute"
+	QuotaTenantSpendPerMonth     = "tenant_spend_per_month"
+)
+
+// tenantPolicyPrefix names the policy reported when a tenant's model lists reject a request.
+const tenantPolicyPrefix = "tenant:"
+
+// tenantPattern bounds tenant identifiers, which are used as metric labels.
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ErrTenantNotPermitted indicates a request claimed a configured tenant its client key does not belong to.
+var ErrTenantNotPermitted = errors.New("client key does not belong to tenant")
+
+// TenantSettings isolates the configuration of one tenant: the client keys that
+// belong to it, the models it may call, its monthly budget and request rate, and
+// its own provider credentials. Zero limits are unlimited.
+type TenantSettings struct {
+	Name              string            `json:"name"`
+	Keys              []string          `json:"keys"` // client key names
+	AllowModels       []string          `json:"allow_models"`
+	DenyModels        []string          `json:"deny_models"`
+	MonthlyBudget     float64           `json:"monthly_budget"`
+	RequestsPerMinute int               `json:"requests_per_minute"`
+	Credentials       map[string]string `json:"credentials"` // provider name -> API key
+}
+
+// ValidTenant reports whether name is a valid tenant identifier.
+func ValidTenant(name string) bool {
+	return tenantPattern.MatchString(name)
+}
+
+// ParseTenants decodes and validates a JSON array of tenant settings.
+func ParseTenants(data []byte) ([]TenantSettings, error) {
+	var tenants []TenantSettings
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants: %w", err)
+	}
+
+	names := make(map[string]bool)
+	owners := make(map[string]string)
+	for _, tenant := range tenants {
+		if !ValidTenant(tenant.Name) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant.Name)
+		}
+		if tenant.Name == DefaultTenant {
+			return nil, fmt.Errorf("tenant name %s is reserved", DefaultTenant)
+		}
+		if names[tenant.Name] {
+			return nil, fmt.Errorf("tenant %s is defined twice", tenant.Name)
+		}
+		names[tenant.Name] = true
+
+		if tenant.MonthlyBudget < 0 || tenant.RequestsPerMinute < 0 {
+			return nil, fmt.Errorf("tenant %s has a negative limit", tenant.Name)
+		}
+		for _, key := range tenant.Keys {
+			if other, taken := owners[key]; taken {
+				return nil, fmt.Errorf("key %s belongs to tenants %s and %s", key, other, tenant.Name)
+			}
+			owners[key] = tenant.Name
+		}
+	}
+
+	return tenants, nil
+}
+
+// tenantCounters tracks one tenant's request rate and monthly spend.
+type tenantCounters struct {
+	minute   time.Time
+	requests int
+	month    string
+	spend    float64
+}
+
+// Tenants resolves client keys to tenants and enforces each tenant's model lists,
+// request rate, and monthly budget. Requests of a tenant with its own provider
+// credentials are served by that tenant's provider instances.
+type Tenants struct {
+	settings  map[string]*TenantSettings
+	owners    map[string]string              // client key -> tenant
+	providers map[string]map[string]Provider // tenant -> provider name -> provider
+
+	mu       sync.Mutex
+	counters map[string]*tenantCounters
+}
+
+// NewTenants creates a tenant directory from validated settings.
+func NewTenants(settings []TenantSettings) *Tenants {
+	tenants := &Tenants{
+		settings:  make(map[string]*TenantSettings, len(settings)),
+		owners:    make(map[string]string),
+		providers: make(map[string]map[string]Provider),
+		mu:        sync.Mutex{},
+		counters:  make(map[string]*tenantCounters),
+	}
+
+	for i := range settings {
+		tenants.settings[settings[i].Name] = &settings[i]
+		for _, key := range settings[i].Keys {
+			tenants.owners[key] = settings[i].Name
+		}
+	}
+
+	return tenants
+}
+
+// WithTenants enforces tenant isolation: model lists, rate limits, budgets, and
+// per-tenant provider credentials.
+func WithTenants(tenants *Tenants) GatewayOption {
+	return func(g *GatewayService) {
+		g.tenants = tenants
+	}
+}
+
+// SetProvider serves the tenant's requests for provider.Name() with provider,
+// typically an instance holding the tenant's own credentials. Call before serving.
+func (t *Tenants) SetProvider(tenant string, provider Provider) {
+	if t.providers[tenant] == nil {
+		t.providers[tenant] = make(map[string]Provider)
+	}
+	t.providers[tenant][provider.Name()] = provider
+}
+
+// Resolve returns the tenant of a request by client key and the tenant it claims.
+// A key that belongs to a tenant always resolves to it. Otherwise the request is
+// attributed to the default tenant: a configured tenant may only be claimed by its
+// own keys, and unknown tenants are not accepted, so clients cannot mint tenants
+// (and metric labels) at will.
+func (t *Tenants) Resolve(clientKey, claimed string) (string, error) {
+	if tenant, ok := t.owners[clientKey]; ok {
+		return tenant, nil
+	}
+
+	if _, configured := t.settings[claimed]; configured {
+		return "", fmt.Errorf("%w %s", ErrTenantNotPermitted, claimed)
+	}
+	return DefaultTenant, nil
+}
+
+// Seed restores this month's tenant spend from stored usage records.
+func (t *Tenants) Seed(records []UsageRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for _, record := range records {
+		if record.Time.UTC().Format(budgetPeriodLayout) == now.UTC().Format(budgetPeriodLayout) {
+			t.counter(record.Tenant, now).spend += record.Cost
+		}
+	}
+}
+
+// enforceTenantModel rejects a model outside the tenant's model lists.
+func (g *GatewayService) enforceTenantModel(ctx context.Context, model string) error {
+	if g.tenants == nil {
+		return nil
+	}
+
+	settings, ok := g.tenants.settings[observability.GetTenant(ctx)]
+	if !ok || permitted(model, settings.AllowModels, settings.DenyModels) {
+		return nil
+	}
+
+	violation := &PolicyError{Policy: tenantPolicyPrefix + settings.Name, Kind: "model", Name: model}
+	observability.PolicyViolations.WithLabelValues(violation.Policy, violation.Kind).Inc()
+	observability.FromContext(ctx).Info("request rejected by tenant model list",
+		observability.String("model", model),
+	)
+	return violation
+}
+
+// admitTenant counts a request against its tenant's rate limit, rejecting it when
+// the tenant is over its request rate or monthly budget.
+func (g *GatewayService) admitTenant(ctx context.Context) error {
+	if g.tenants == nil {
+		return nil
+	}
+
+	tenant := observability.GetTenant(ctx)
+	settings, ok := g.tenants.settings[tenant]
+	if !ok {
+		return nil
+	}
+
+	err := g.tenants.admit(settings, observability.GetClientKey(ctx), time.Now())
+	if err != nil {
+		observability.QuotaRejections.WithLabelValues(err.Limit).Inc()
+		observability.FromContext(ctx).Info("request rejected by tenant limit",
+			observability.String("limit", err.Limit),
+		)
+		return err
+	}
+	return nil
+}
+
+// tenantProvider returns the tenant's own instance of the routed provider, if it has one.
+func (g *GatewayService) tenantProvider(ctx context.Context, provider Provider) Provider {
+	if g.tenants == nil || len(g.tenants.providers) == 0 {
+		return provider
+	}
+
+	if own, ok := g.tenants.providers[observability.GetTenant(ctx)][provider.Name()]; ok {
+		return own
+	}
+	return provider
+}
+
+// admit applies the tenant's rate and budget limits. Caller must not hold the lock.
+func (t *Tenants) admit(settings *TenantSettings, clientKey string, now time.Time) *QuotaExceededError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counters := t.counter(settings.Name, now)
+	switch {
+	case settings.RequestsPerMinute > 0 && counters.requests >= settings.RequestsPerMinute:
+		return &QuotaExceededError{
+			Key:        clientKey,
+			Limit:      QuotaTenantRequestsPerMinute,
+			RetryAfter: counters.minute.Add(time.Minute).Sub(now),
+		}
+	case settings.MonthlyBudget > 0 && counters.spend >= settings.MonthlyBudget:
+		return &QuotaExceededError{Key: clientKey, Limit: QuotaTenantSpendPerMonth, RetryAfter: untilNextMonth(now)}
+	}
+
+	counters.requests++
+	return nil
+}
+
+// observe adds a completed request's cost to its tenant's monthly spend.
+func (t *Tenants) observe(record UsageRecord) {
+	if _, ok := t.settings[record.Tenant]; !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counter(record.Tenant, time.Now()).spend += record.Cost
+}
+
+// counter returns the tenant's counters, resetting windows that have ended.
+// Caller must hold the lock.
+func (t *Tenants) counter(tenant string, now time.Time) *tenantCounters {
+	counters, ok := t.counters[tenant]
+	if !ok {
+		counters = &tenantCounters{minute: time.Time{}, requests: 0, month: "", spend: 0}
+		t.counters[tenant] = counters
+	}
+
+	if minute := now.Truncate(time.Minute); !counters.minute.Equal(minute) {
+		counters.minute, counters.requests = minute, 0
+	}
+	if month := now.UTC().Format(budgetPeriodLayout); counters.month != month {
+		counters.month, counters.spend = month, 0
+	}
+	return counters
+}
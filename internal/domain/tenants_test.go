@@ -0,0 +1,140 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestParseTenants(t *testing.T) {
+	t.Run("should parse valid tenants", func(t *testing.T) {
+		tenants, err := domain.ParseTenants([]byte(`[
+			{"name": "acme", "keys": ["alice", "bob"], "allow_models": ["gpt-4*"], "monthly_budget": 100,
+			 "requests_per_minute": 60, "credentials": {"openai": "sk-acme"}},
+			{"name": "globex", "keys": ["carol"]}
+		]`))
+		require.NoError(t, err)
+		require.Len(t, tenants, 2)
+		require.Equal(t, "sk-acme", tenants[0].Credentials["openai"])
+	})
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "malformed JSON", data: `{`},
+		{name: "invalid names", data: `[{"name": "a b"}]`},
+		{name: "the reserved default tenant", data: `[{"name": "default"}]`},
+		{name: "duplicate tenants", data: `[{"name": "acme"}, {"name": "acme"}]`},
+		{name: "keys shared between tenants", data: `[{"name": "a", "keys": ["k"]}, {"name": "b", "keys": ["k"]}]`},
+		{name: "negative limits", data: `[{"name": "acme", "monthly_budget": -1}]`},
+	}
+	for _, tt := range tests {
+		t.Run("should reject "+tt.name, func(t *testing.T) {
+			_, err := domain.ParseTenants([]byte(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTenants_Resolve(t *testing.T) {
+	tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", Keys: []string{"alice"}}})
+
+	t.Run("should resolve a key to its tenant regardless of the claimed tenant", func(t *testing.T) {
+		tenant, err := tenants.Resolve("alice", "other")
+		require.NoError(t, err)
+		require.Equal(t, "acme", tenant)
+	})
+
+	t.Run("should map unknown claimed tenants to the default tenant", func(t *testing.T) {
+		tenant, err := tenants.Resolve("bob", "team-x")
+		require.NoError(t, err)
+		require.Equal(t, domain.DefaultTenant, tenant)
+	})
+
+	t.Run("should reject claiming a configured tenant", func(t *testing.T) {
+		_, err := tenants.Resolve("bob", "acme")
+		require.ErrorIs(t, err, domain.ErrTenantNotPermitted)
+	})
+}
+
+func TestGatewayService_Tenants(t *testing.T) {
+	request := func(model string) *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:    model,
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		}
+	}
+	acmeCtx := observability.WithClientKey(observability.WithTenant(context.Background(), "acme"), "alice")
+
+	t.Run("should reject models outside the tenant's allow list before routing", func(t *testing.T) {
+		tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", AllowModels: []string{"gpt-3.5*"}}})
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithTenants(tenants))
+
+		_, err := gateway.CompleteByModel(acmeCtx, request("gpt-4"))
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "tenant:acme", policyErr.Policy)
+	})
+
+	t.Run("should reject requests over the tenant's rate", func(t *testing.T) {
+		tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", RequestsPerMinute: 1}})
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(nil, domain.ErrUnknownProvider).Once()
+		gateway := domain.NewGatewayService(mockRegistry, mocks.NewMockCostCalculator(t), domain.WithTenants(tenants))
+
+		_, err := gateway.CompleteByModel(acmeCtx, request("gpt-4"))
+		require.ErrorIs(t, err, domain.ErrUnknownProvider, "the first request is admitted")
+
+		_, err = gateway.CompleteByModel(acmeCtx, request("gpt-4"))
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Equal(t, domain.QuotaTenantRequestsPerMinute, quotaErr.Limit)
+		require.Positive(t, quotaErr.RetryAfter)
+	})
+
+	t.Run("should reject requests once the tenant's budget is spent", func(t *testing.T) {
+		tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", MonthlyBudget: 5}})
+		tenants.Seed([]domain.UsageRecord{{Time: time.Now(), Tenant: "acme", Cost: 5}})
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithTenants(tenants))
+
+		_, err := gateway.CompleteByModel(acmeCtx, request("gpt-4"))
+
+		var quotaErr *domain.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Equal(t, domain.QuotaTenantSpendPerMonth, quotaErr.Limit)
+	})
+
+	t.Run("should serve the tenant with its own provider instance", func(t *testing.T) {
+		sharedProvider := mocks.NewMockProvider(t)
+		sharedProvider.EXPECT().Name().Return("openai")
+		tenantProvider := mocks.NewMockProvider(t)
+		tenantProvider.EXPECT().Name().Return("openai")
+		tenantProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model: "gpt-4",
+		}, nil)
+
+		tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme"}})
+		tenants.SetProvider("acme", tenantProvider)
+
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(sharedProvider, nil)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithTenants(tenants))
+
+		_, err := gateway.CompleteByModel(acmeCtx, request("gpt-4"))
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,213 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Built-in tokenizer families.
+const (
+	// TokenizerOpenAI approximates OpenAI's tiktoken encodings (cl100k_base and
+	// o200k_base), also used by Llama 3.
+	TokenizerOpenAI = "openai"
+
+	// TokenizerClaude approximates Anthropic's Claude tokenizer.
+	TokenizerClaude = "claude"
+
+	// TokenizerLlama approximates the SentencePiece tokenizer of Llama 2 and Mistral
+	// models, whose smaller vocabulary falls back to bytes for much non-Latin text.
+	TokenizerLlama = "llama"
+)
+
+const (
+	// Approximate tokens per rune by script. BPE tokenizers pack roughly four
+	// ASCII characters per token, two for other alphabetic scripts (Cyrillic,
+	// Greek, Arabic, ...), and about one per CJK ideograph or syllable.
+	asciiTokensPerRune = 0.25
+	otherTokensPerRune = 0.5
+	cjkTokensPerRune   = 1.0
+
+	// messageOverheadTokens covers role markers and separators added per chat message.
+	messageOverheadTokens = 4
+)
+
+// builtinTokenizers are the approximated tokenizer of each family.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var builtinTokenizers = map[string]Tokenizer{
+	TokenizerOpenAI: defaultTokenizer(),
+	// Claude packs about 3.5 ASCII characters per token.
+	TokenizerClaude: scriptTokenizer{ascii: 1 / 3.5, other: 0.6, cjk: 1.2, overhead: messageOverheadTokens},
+	// A 32k SentencePiece vocabulary packs about 3.3 ASCII characters per token and
+	// spends several byte tokens on ideographs it does not cover.
+	TokenizerLlama: scriptTokenizer{ascii: 0.3, other: 0.8, cjk: 1.6, overhead: 6},
+}
+
+// builtinTokenizerModels selects the built-in tokenizer of known model families.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var builtinTokenizerModels = map[string]string{
+	"claude-*":           TokenizerClaude,
+	"anthropic.claude-*": TokenizerClaude,
+	"llama-2*":           TokenizerLlama,
+	"llama2*":            TokenizerLlama,
+	"mistral*":           TokenizerLlama,
+	"mixtral*":           TokenizerLlama,
+	"open-mistral*":      TokenizerLlama,
+}
+
+// EstimateTokens approximates the token count of text without a model tokenizer.
+// The estimate is script-aware so non-Latin prompts are not badly undercounted.
+func EstimateTokens(text string) int {
+	return defaultTokenizer().CountTokens(text)
+}
+
+// EstimateMessageTokens approximates the prompt tokens consumed by a single message.
+func EstimateMessageTokens(msg Message) int {
+	return CountMessageTokens(defaultTokenizer(), msg)
+}
+
+// EstimateMessagesTokens approximates the prompt tokens consumed by messages.
+func EstimateMessagesTokens(messages []Message) int {
+	return CountMessagesTokens(defaultTokenizer(), messages)
+}
+
+// CountMessageTokens counts the prompt tokens a single message consumes with tokenizer.
+func CountMessageTokens(tokenizer Tokenizer, msg Message) int {
+	return tokenizer.CountTokens(msg.Content) + tokenizer.MessageOverhead()
+}
+
+// CountMessagesTokens counts the prompt tokens messages consume with tokenizer.
+func CountMessagesTokens(tokenizer Tokenizer, messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += CountMessageTokens(tokenizer, msg)
+	}
+	return total
+}
+
+// Tokenizers selects the tokenizer of each model. Models are matched against
+// patterns, where a trailing "*" matches any suffix and the longest matching
+// pattern wins; configured patterns take precedence over the built-in model
+// families, and models matching none use the OpenAI approximation.
+type Tokenizers struct {
+	configured map[string]Tokenizer
+	builtin    map[string]Tokenizer
+}
+
+// NewTokenizers builds the tokenizer selection. models maps model patterns to a
+// built-in tokenizer family, and custom maps model patterns to tokenizers such
+// as exact encoders registered by plugins, which win over models.
+func NewTokenizers(models map[string]string, custom map[string]Tokenizer) (*Tokenizers, error) {
+	configured := make(map[string]Tokenizer, len(models)+len(custom))
+	for pattern, family := range models {
+		tokenizer, ok := builtinTokenizers[family]
+		if !ok {
+			return nil, fmt.Errorf("tokenizer of %s must be one of %s, got %q",
+				pattern, strings.Join(slices.Sorted(maps.Keys(builtinTokenizers)), ", "), family)
+		}
+		configured[pattern] = tokenizer
+	}
+	maps.Copy(configured, custom)
+
+	builtin := make(map[string]Tokenizer, len(builtinTokenizerModels))
+	for pattern, family := range builtinTokenizerModels {
+		builtin[pattern] = builtinTokenizers[family]
+	}
+	return &Tokenizers{configured: configured, builtin: builtin}, nil
+}
+
+// WithTokenizers counts the tokens of each model with its tokenizer when
+// estimating prompts, checking context windows, and reconstructing stream usage.
+func WithTokenizers(tokenizers *Tokenizers) GatewayOption {
+	return func(g *GatewayService) {
+		g.tokenizers = tokenizers
+	}
+}
+
+// ForModel returns the tokenizer of model.
+func (t *Tokenizers) ForModel(model string) Tokenizer {
+	if t == nil {
+		return defaultTokenizer()
+	}
+	if tokenizer := longestMatch(t.configured, model); tokenizer != nil {
+		return tokenizer
+	}
+	if tokenizer := longestMatch(t.builtin, model); tokenizer != nil {
+		return tokenizer
+	}
+	return defaultTokenizer()
+}
+
+// longestMatch returns the tokenizer of the longest pattern matching model, or nil.
+func longestMatch(patterns map[string]Tokenizer, model string) Tokenizer {
+	var match Tokenizer
+	longest := -1
+	for pattern, tokenizer := range patterns {
+		if len(pattern) > longest && matchesAny(model, []string{pattern}) {
+			match, longest = tokenizer, len(pattern)
+		}
+	}
+	return match
+}
+
+// defaultTokenizer returns the OpenAI approximation, used for models without a
+// more specific tokenizer.
+func defaultTokenizer() scriptTokenizer {
+	return scriptTokenizer{
+		ascii:    asciiTokensPerRune,
+		other:    otherTokensPerRune,
+		cjk:      cjkTokensPerRune,
+		overhead: messageOverheadTokens,
+	}
+}
+
+// runeTokens returns the tokens a single rune accounts for in the default approximation.
+func runeTokens(r rune) float64 {
+	return defaultTokenizer().runeTokens(r)
+}
+
+// scriptTokenizer approximates a tokenizer by the tokens it spends per rune of
+// each script, for models whose exact encoder is not available.
+type scriptTokenizer struct {
+	ascii    float64 // tokens per ASCII rune
+	other    float64 // tokens per rune of other alphabetic scripts
+	cjk      float64 // tokens per CJK ideograph or syllable
+	overhead int     // tokens per chat message
+}
+
+// CountTokens approximates the tokens text encodes to.
+func (s scriptTokenizer) CountTokens(text string) int {
+	var tokens float64
+	for _, r := range text {
+		tokens += s.runeTokens(r)
+	}
+	return int(math.Ceil(tokens))
+}
+
+// MessageOverhead returns the tokens each chat message adds.
+func (s scriptTokenizer) MessageOverhead() int {
+	return s.overhead
+}
+
+// runeTokens returns the tokens a single rune accounts for.
+func (s scriptTokenizer) runeTokens(r rune) float64 {
+	switch {
+	case r < utf8.RuneSelf:
+		return s.ascii
+	case isCJK(r):
+		return s.cjk
+	default:
+		return s.other
+	}
+}
+
+// isCJK reports whether r belongs to a script tokenized roughly one token per rune.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,89 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+// wordTokenizer counts one token per word, standing in for an exact encoder.
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func (wordTokenizer) MessageOverhead() int {
+	return 0
+}
+
+func TestTokenizers(t *testing.T) {
+	text := strings.Repeat("a", 71)
+
+	t.Run("should select the tokenizer of built-in model families", func(t *testing.T) {
+		tokenizers, err := domain.NewTokenizers(nil, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, 18, tokenizers.ForModel("gpt-4o").CountTokens(text))
+		require.Equal(t, 21, tokenizers.ForModel("claude-3-5-sonnet").CountTokens(text))
+		require.Equal(t, 22, tokenizers.ForModel("mistral-large").CountTokens(text))
+	})
+
+	t.Run("should prefer configured patterns, the longest first", func(t *testing.T) {
+		tokenizers, err := domain.NewTokenizers(
+			map[string]string{"acme-*": domain.TokenizerLlama, "acme-claude-*": domain.TokenizerClaude},
+			map[string]domain.Tokenizer{"gpt-4*": wordTokenizer{}},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, 22, tokenizers.ForModel("acme-7b").CountTokens(text))
+		require.Equal(t, 21, tokenizers.ForModel("acme-claude-1").CountTokens(text))
+		require.Equal(t, 1, tokenizers.ForModel("gpt-4o").CountTokens(text))
+	})
+
+	t.Run("should reject an unknown tokenizer family", func(t *testing.T) {
+		_, err := domain.NewTokenizers(map[string]string{"acme-*": "bert"}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestGatewayService_Tokenizers(t *testing.T) {
+	t.Run("should estimate stream usage with the model's tokenizer", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+		provider := mocks.NewMockProvider(t)
+		provider.EXPECT().Name().Return("acme").Maybe()
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "acme-1").Return(provider, nil)
+
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: "three word reply"}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		provider.EXPECT().Stream(mock.Anything, mock.Anything).Return(upstream, nil)
+
+		tokenizers, err := domain.NewTokenizers(nil, map[string]domain.Tokenizer{"acme-*": wordTokenizer{}})
+		require.NoError(t, err)
+		store := &memoryUsageStore{}
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithUsageStore(store), domain.WithTokenizers(tokenizers))
+
+		chunks, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "acme-1",
+			Messages: []domain.Message{{Role: "user", Content: "say three words"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+		collect(chunks)
+
+		records := storedRecords(t, store, 1)
+		require.Equal(t, 3, records[0].PromptTokens)
+		require.Equal(t, 3, records[0].CompletionTokens)
+	})
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Built-in response transformer names, usable in an ordered pipeline.
+const (
+	TransformerStripThink     = "strip_think"
+	TransformerTrimWhitespace = "trim_whitespace"
+	TransformerDisclaimer     = "disclaimer"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// NewResponseTransformers builds the ordered transformer pipeline from names.
+// The disclaimer text is only used by the disclaimer transformer.
+func NewResponseTransformers(names []string, disclaimer string) ([]ResponseTransformer, error) {
+	transformers := make([]ResponseTransformer, 0, len(names))
+
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case TransformerStripThink:
+			transformers = append(transformers, textTransformer{
+				newProcessor: func() textProcessor { return &thinkStripper{inThink: false, pending: ""} },
+			})
+		case TransformerTrimWhitespace:
+			transformers = append(transformers, textTransformer{
+				newProcessor: func() textProcessor { return &whitespaceTrimmer{started: false, pending: ""} },
+			})
+		case TransformerDisclaimer:
+			if disclaimer == "" {
+				return nil, fmt.Errorf("transformer %s requires disclaimer text", TransformerDisclaimer)
+			}
+			transformers = append(transformers, textTransformer{
+				newProcessor: func() textProcessor { return disclaimerAppender{text: disclaimer} },
+			})
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown response transformer: %s", name)
+		}
+	}
+
+	return transformers, nil
+}
+
+// textProcessor rewrites content incrementally. flush returns any buffered
+// content once the response is complete.
+type textProcessor interface {
+	process(text string) string
+	flush() string
+}
+
+// textTransformer adapts a textProcessor to both complete and streamed responses,
+// so the two paths always produce the same content.
+type textTransformer struct {
+	newProcessor func() textProcessor
+}
+
+func (t textTransformer) TransformResponse(_ context.Context, resp *CompletionResponse) {
+	processor := t.newProcessor()
+	resp.Content = processor.process(resp.Content) + processor.flush()
+
+	for i := range resp.Choices {
+		processor = t.newProcessor()
+		resp.Choices[i].Content = processor.process(resp.Choices[i].Content) + processor.flush()
+	}
+}
+
+func (t textTransformer) NewChunkTransformer(_ context.Context) ChunkTransformer {
+	processor := t.newProcessor()
+
+	return func(chunk StreamChunk) StreamChunk {
+		if chunk.Error != nil {
+			return chunk
+		}
+
+		chunk.Delta = processor.process(chunk.Delta)
+		if chunk.Done {
+			chunk.Delta += processor.flush()
+		}
+		return chunk
+	}
+}
+
+// thinkStripper removes <think>...</think> reasoning blocks, including tags split across chunks.
+type thinkStripper struct {
+	inThink bool
+	pending string
+}
+
+func (s *thinkStripper) process(text string) string {
+	text = s.pending + text
+	s.pending = ""
+
+	var out strings.Builder
+	for {
+		tag := thinkOpenTag
+		if s.inThink {
+			tag = thinkCloseTag
+		}
+
+		idx := strings.Index(text, tag)
+		if idx < 0 {
+			// Hold back a possible partial tag until the next chunk.
+			keep := partialSuffix(text, tag)
+			if !s.inThink {
+				out.WriteString(text[:len(text)-keep])
+			}
+			s.pending = text[len(text)-keep:]
+			return out.String()
+		}
+
+		if !s.inThink {
+			out.WriteString(text[:idx])
+		}
+		text = text[idx+len(tag):]
+		s.inThink = !s.inThink
+	}
+}
+
+func (s *thinkStripper) flush() string {
+	// An unterminated reasoning block is dropped; a dangling partial open tag is kept.
+	if s.inThink {
+		return ""
+	}
+	pending := s.pending
+	s.pending = ""
+	return pending
+}
+
+// partialSuffix returns the length of the longest suffix of text that is a proper prefix of tag.
+func partialSuffix(text, tag string) int {
+	for n := min(len(tag)-1, len(text)); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// whitespaceTrimmer removes leading and trailing whitespace from the content.
+type whitespaceTrimmer struct {
+	started bool
+	pending string
+}
+
+func (t *whitespaceTrimmer) process(text string) string {
+	if !t.started {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return ""
+		}
+		t.started = true
+	}
+
+	// Trailing whitespace is held back until more content arrives.
+	trimmed := strings.TrimRightFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		t.pending += text
+		return ""
+	}
+
+	out := t.pending + trimmed
+	t.pending = text[len(trimmed):]
+	return out
+}
+
+func (t *whitespaceTrimmer) flush() string {
+	t.pending = ""
+	return ""
+}
+
+// disclaimerAppender appends a fixed disclaimer after the content.
+type disclaimerAppender struct {
+	text string
+}
+
+func (d disclaimerAppender) process(text string) string {
+	return text
+}
+
+func (d disclaimerAppender) flush() string {
+	return "\n\n" + d.text
+}
@@ -0,0 +1,110 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+func TestNewResponseTransformers(t *testing.T) {
+	t.Run("should reject unknown transformer", func(t *testing.T) {
+		_, err := domain.NewResponseTransformers([]string{"shout"}, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unknown response transformer")
+	})
+
+	t.Run("should require disclaimer text", func(t *testing.T) {
+		_, err := domain.NewResponseTransformers([]string{domain.TransformerDisclaimer}, "")
+		require.Error(t, err)
+	})
+}
+
+func TestResponseTransformers(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []string
+		chunks   []string
+		expected string
+	}{
+		{
+			name:     "should strip reasoning split across chunks",
+			pipeline: []string{domain.TransformerStripThink},
+			chunks:   []string{"Hi <th", "ink>secret", " plan</thi", "nk>there"},
+			expected: "Hi there",
+		},
+		{
+			name:     "should keep text resembling a tag",
+			pipeline: []string{domain.TransformerStripThink},
+			chunks:   []string{"a <thin", "g> b <"},
+			expected: "a <thing> b <",
+		},
+		{
+			name:     "should trim leading and trailing whitespace",
+			pipeline: []string{domain.TransformerTrimWhitespace},
+			chunks:   []string{"  \n", " Hello ", " world", " \n"},
+			expected: "Hello  world",
+		},
+		{
+			name:     "should apply transformers in order",
+			pipeline: []string{domain.TransformerStripThink, domain.TransformerTrimWhitespace, domain.TransformerDisclaimer},
+			chunks:   []string{"<think>hmm</think>\n\n", "Answer", "\n"},
+			expected: "Answer\n\nAI-generated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			transformers, err := domain.NewResponseTransformers(tt.pipeline, "AI-generated")
+			require.NoError(t, err)
+
+			// Complete responses see the joined content at once.
+			var content string
+			for _, chunk := range tt.chunks {
+				content += chunk
+			}
+			response := &domain.CompletionResponse{Content: content}
+			for _, transformer := range transformers {
+				transformer.TransformResponse(ctx, response)
+			}
+			require.Equal(t, tt.expected, response.Content)
+
+			// Streams see the same content chunk by chunk, followed by a done chunk.
+			pipeline := make([]domain.ChunkTransformer, 0, len(transformers))
+			for _, transformer := range transformers {
+				pipeline = append(pipeline, transformer.NewChunkTransformer(ctx))
+			}
+
+			var streamed string
+			for i, delta := range append(tt.chunks, "") {
+				chunk := domain.StreamChunk{Delta: delta, Done: i == len(tt.chunks)}
+				for _, transform := range pipeline {
+					chunk = transform(chunk)
+				}
+				streamed += chunk.Delta
+			}
+			require.Equal(t, tt.expected, streamed)
+		})
+	}
+}
+
+func TestResponseTransformers_Choices(t *testing.T) {
+	transformers, err := domain.NewResponseTransformers([]string{domain.TransformerTrimWhitespace}, "")
+	require.NoError(t, err)
+
+	resp := &domain.CompletionResponse{
+		Content: " first ",
+		Choices: []domain.Choice{
+			{Index: 0, Content: " first "},
+			{Index: 1, Content: "\nsecond\n"},
+		},
+	}
+	transformers[0].TransformResponse(context.Background(), resp)
+
+	require.Equal(t, "first", resp.Content)
+	require.Equal(t, "first", resp.Choices[0].Content)
+	require.Equal(t, "second", resp.Choices[1].Content)
+}
@@ -0,0 +1,268 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Context window overflow strategies.
+const (
+	// ContextStrategyError rejects prompts that exceed the model context window.
+	ContextStrategyError = "error"
+
+	// ContextStrategyTrimOldest drops the oldest messages until the prompt fits.
+	ContextStrategyTrimOldest = "trim_oldest"
+
+	// ContextStrategySummarize replaces the oldest messages with a model-written summary.
+	ContextStrategySummarize = "summarize"
+)
+
+const (
+	// summaryMaxTokens caps the summary replacing dropped messages.
+	summaryMaxTokens = 256
+
+	// summaryTranscriptTokens caps the transcript sent for summarization, so a
+	// long dropped history cannot overflow the summary model or its bill.
+	summaryTranscriptTokens = 4096
+
+	summaryInstructions = "Summarize the following conversation in a few sentences. " +
+		"Keep names, facts, decisions, and open questions; omit pleasantries."
+	summaryPrefix = "Summary of the earlier conversation: "
+)
+
+// TrimResult describes the history trimming applied to a request.
+type TrimResult struct {
+	DroppedMessages    int // Messages removed from the history
+	SummarizedMessages int // Dropped messages replaced by a summary
+	OriginalTokens     int // Estimated prompt tokens before trimming
+	FinalTokens        int // Estimated prompt tokens after trimming
+}
+
+// Trimmed reports whether any message was removed.
+func (r TrimResult) Trimmed() bool {
+	return r.DroppedMessages > 0
+}
+
+// TrimMessages drops whole messages, oldest first, until the estimated prompt
+// fits within budget tokens. Instruction messages (system and developer) and the
+// latest user turn (the last user message and everything after it, or the last
+// message when there is no user message) are always preserved, so the result may
+// still exceed the budget when those alone do not fit. An assistant message
+// making tool calls and the tool messages answering them are dropped or kept
+// together, since upstreams reject either half without the other.
+func TrimMessages(messages []Message, budget int) ([]Message, TrimResult) {
+	kept, _, result := splitHistory(messages, budget, defaultTokenizer())
+	return kept, result
+}
+
+// splitHistory trims messages as TrimMessages does, counting tokens with
+// tokenizer, and also returns the dropped messages.
+func splitHistory(messages []Message, budget int, tokenizer Tokenizer) ([]Message, []Message, TrimResult) {
+	originalTokens := CountMessagesTokens(tokenizer, messages)
+	result := TrimResult{
+		DroppedMessages:    0,
+		SummarizedMessages: 0,
+		OriginalTokens:     originalTokens,
+		FinalTokens:        originalTokens,
+	}
+
+	if budget <= 0 || originalTokens <= budget {
+		return messages, nil, result
+	}
+
+	// Without a user message the latest turn is the last message alone.
+	latestUserTurn := len(messages) - 1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			latestUserTurn = i
+			break
+		}
+	}
+
+	// Mark droppable units oldest first until the history fits. A unit lying
+	// partly in the latest turn is kept whole.
+	units := historyUnits(messages)
+	dropped := make([]bool, len(messages))
+	total := originalTokens
+	for i := 0; i < latestUserTurn && total > budget; i++ {
+		unit := units[i]
+		if unit == nil || isInstruction(messages[i]) || unit[len(unit)-1] >= latestUserTurn {
+			continue
+		}
+		for _, member := range unit {
+			dropped[member] = true
+			total -= CountMessageTokens(tokenizer, messages[member])
+			result.DroppedMessages++
+		}
+	}
+
+	if result.DroppedMessages == 0 {
+		return messages, nil, result
+	}
+
+	kept := make([]Message, 0, len(messages)-result.DroppedMessages)
+	removed := make([]Message, 0, result.DroppedMessages)
+	for i, msg := range messages {
+		if dropped[i] {
+			removed = append(removed, msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+
+	result.FinalTokens = total
+	return kept, removed, result
+}
+
+// historyUnits groups messages into the units trimming drops together: an
+// assistant message making tool calls with the tool messages answering them,
+// and every other message on its own. It returns the indexes of each unit,
+// ascending, at the index of its first message and nil at the others.
+func historyUnits(messages []Message) [][]int {
+	units := make([][]int, len(messages))
+	answered := make(map[string]int) // tool call ID to the index of the message making it
+	for i, msg := range messages {
+		if msg.Role == "tool" {
+			if caller, ok := answered[msg.ToolCallID]; ok {
+				units[caller] = append(units[caller], i)
+				continue
+			}
+		}
+		units[i] = []int{i}
+		for _, call := range msg.ToolCalls {
+			answered[call.ID] = i
+		}
+	}
+	return units
+}
+
+// insertSummary places summary after the leading instruction messages.
+func insertSummary(messages []Message, summary Message) []Message {
+	at := 0
+	for at < len(messages) && isInstruction(messages[at]) {
+		at++
+	}
+
+	result := make([]Message, 0, len(messages)+1)
+	result = append(result, messages[:at]...)
+	result = append(result, summary)
+	return append(result, messages[at:]...)
+}
+
+// summarize asks provider to condense dropped messages into one system message.
+// The call is metered like a client completion: it holds a concurrency slot, and
+// its priced usage is recorded and counted against the caller's quotas.
+func (g *GatewayService) summarize(
+	ctx context.Context,
+	provider Provider,
+	model string,
+	dropped []Message,
+	metadata map[string]string,
+) (Message, error) {
+	req, err := summaryRequest(model, dropped, g.tokenizers.ForModel(model), metadata)
+	if err != nil {
+		return Message{}, err
+	}
+
+	release, err := g.acquireSlot(ctx, provider, model)
+	if err != nil {
+		return Message{}, err
+	}
+	defer release()
+
+	start := time.Now()
+	response, err := provider.Complete(ctx, req)
+	latency := time.Since(start)
+	g.observeProviderResult(ctx, provider, err)
+	g.observeLimit(provider, latency, err)
+	if err != nil {
+		return Message{}, fmt.Errorf("summarization failed: %w", err)
+	}
+
+	g.price(ctx, response.Model, &response.Usage)
+	g.recordUsage(ctx, UsageRecord{
+		Time:             time.Time{},
+		RequestID:        "",
+		Tenant:           "",
+		ClientKey:        "",
+		Provider:         response.Provider,
+		Model:            response.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+		CachedTokens:     response.Usage.CachedPromptTokens,
+		Images:           response.Usage.Images,
+		Cost:             response.Usage.Cost,
+		Stream:           false,
+		Estimated:        false,
+		Metadata:         map[string]string{MetadataSummarizedMessages: strconv.Itoa(len(dropped))},
+		Tags:             g.costTags(metadata),
+		ProviderCost:     response.Usage.ProviderCost,
+		Currency:         response.Usage.Currency,
+
+		OriginalPromptTokens:   0,
+		CompressedPromptTokens: 0,
+
+		Scores: nil,
+
+		Rollup:   "",
+		Requests: 0,
+	})
+
+	summary := strings.TrimSpace(response.Content)
+	if summary == "" {
+		return Message{}, fmt.Errorf("summarization by %s returned no content", model)
+	}
+	return Message{Role: "system", Content: summaryPrefix + summary, ToolCallID: "", ToolCalls: nil}, nil
+}
+
+// summaryRequest builds the request summarizing dropped messages. The transcript
+// keeps the most recent messages that fit in summaryTranscriptTokens; older ones
+// are left out.
+func summaryRequest(
+	model string,
+	dropped []Message,
+	tokenizer Tokenizer,
+	metadata map[string]string,
+) (*CompletionRequest, error) {
+	lines := make([]string, 0, len(dropped))
+	budget := summaryTranscriptTokens
+	for _, msg := range slices.Backward(dropped) {
+		line := msg.Role + ": " + msg.Content + "\n"
+		tokens := tokenizer.CountTokens(line)
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("the latest dropped message exceeds the summary transcript budget")
+	}
+	slices.Reverse(lines)
+
+	return &CompletionRequest{
+		Model: model,
+		Messages: []Message{
+			{Role: "system", Content: summaryInstructions, ToolCallID: "", ToolCalls: nil},
+			{Role: "user", Content: strings.Join(lines, ""), ToolCallID: "", ToolCalls: nil},
+		},
+		Temperature:      0,
+		MaxTokens:        summaryMaxTokens,
+		Stream:           false,
+		Metadata:         metadata,
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	}, nil
+}
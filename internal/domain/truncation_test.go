@@ -0,0 +1,359 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "empty text", text: "", expected: 0},
+		{name: "ascii text uses four characters per token", text: "Hello, world", expected: 3},
+		{name: "CJK text uses one token per character", text: "こんにちは世界", expected: 7},
+		{name: "cyrillic text uses two characters per token", text: "Привет", expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, domain.EstimateTokens(tt.text))
+		})
+	}
+}
+
+func TestTrimMessages(t *testing.T) {
+	long := strings.Repeat("word ", 40) // 50 tokens + overhead
+
+	messages := []domain.Message{
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: "latest question"},
+	}
+
+	t.Run("should keep history that fits the budget", func(t *testing.T) {
+		kept, result := domain.TrimMessages(messages, 1000)
+
+		require.Equal(t, messages, kept)
+		require.False(t, result.Trimmed())
+	})
+
+	t.Run("should drop oldest messages on message boundaries", func(t *testing.T) {
+		kept, result := domain.TrimMessages(messages, 80)
+
+		require.Len(t, kept, 3)
+		require.Equal(t, "system", kept[0].Role)
+		require.Equal(t, "assistant", kept[1].Role)
+		require.Equal(t, "latest question", kept[2].Content)
+		require.Equal(t, 1, result.DroppedMessages)
+		require.Less(t, result.FinalTokens, result.OriginalTokens)
+		require.LessOrEqual(t, result.FinalTokens, 80)
+	})
+
+	t.Run("should preserve system message and latest user turn", func(t *testing.T) {
+		kept, result := domain.TrimMessages(messages, 1)
+
+		require.Len(t, kept, 2)
+		require.Equal(t, "system", kept[0].Role)
+		require.Equal(t, "latest question", kept[1].Content)
+		require.Equal(t, 2, result.DroppedMessages)
+	})
+
+	t.Run("should drop a tool call and its results together", func(t *testing.T) {
+		call := domain.ToolCall{
+			ID:       "call_1",
+			Type:     "function",
+			Function: domain.ToolCallFunction{Name: "lookup", Arguments: "{}"},
+		}
+		withTools := []domain.Message{
+			{Role: "system", Content: "You are helpful"},
+			{Role: "assistant", Content: "", ToolCalls: []domain.ToolCall{call}},
+			{Role: "tool", Content: long, ToolCallID: "call_1"},
+			{Role: "assistant", Content: long},
+			{Role: "user", Content: "latest question"},
+		}
+		_, untrimmed := domain.TrimMessages(withTools, 0)
+
+		// Dropping the tool call alone would fit, but its result must go with it.
+		kept, result := domain.TrimMessages(withTools, untrimmed.OriginalTokens-1)
+
+		require.Equal(t, 2, result.DroppedMessages)
+		require.Len(t, kept, 3)
+		require.Equal(t, "system", kept[0].Role)
+		require.Equal(t, long, kept[1].Content)
+		require.Equal(t, "latest question", kept[2].Content)
+	})
+
+	t.Run("should keep a tool call whose results are in the latest turn", func(t *testing.T) {
+		call := domain.ToolCall{ID: "call_1", Type: "function", Function: domain.ToolCallFunction{Name: "lookup"}}
+		withTools := []domain.Message{
+			{Role: "user", Content: long},
+			{Role: "assistant", Content: "", ToolCalls: []domain.ToolCall{call}},
+			{Role: "user", Content: "latest question"},
+			{Role: "tool", Content: "result", ToolCallID: "call_1"},
+		}
+
+		kept, result := domain.TrimMessages(withTools, 1)
+
+		require.Equal(t, 1, result.DroppedMessages)
+		require.Equal(t, withTools[1:], kept)
+	})
+
+	t.Run("should preserve system message and last message without a user turn", func(t *testing.T) {
+		withoutUser := []domain.Message{
+			{Role: "system", Content: "You are helpful"},
+			{Role: "assistant", Content: long},
+			{Role: "assistant", Content: "latest answer"},
+		}
+
+		kept, result := domain.TrimMessages(withoutUser, 1)
+
+		require.Len(t, kept, 2)
+		require.Equal(t, "system", kept[0].Role)
+		require.Equal(t, "latest answer", kept[1].Content)
+		require.Equal(t, 1, result.DroppedMessages)
+	})
+}
+
+func TestGatewayService_HistoryTrimming(t *testing.T) {
+	t.Run("should trim history and report it in response metadata", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").Return(domain.ModelCapabilities{
+			ContextWindow:   100,
+			MaxOutputTokens: 0,
+		}, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return len(req.Messages) == 1 && req.Messages[0].Content == "latest"
+			})).
+			Return(&domain.CompletionResponse{
+				ID:         "test-id",
+				Model:      "gpt-4",
+				Provider:   "openai",
+				Content:    "ok",
+				FinishTime: time.Now(),
+			}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.AnythingOfType("domain.Usage")).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithHistoryTrimming(mockCapabilities, 50))
+
+		req := &domain.CompletionRequest{
+			Model: "gpt-4",
+			Messages: []domain.Message{
+				{Role: "user", Content: strings.Repeat("old ", 100)},
+				{Role: "user", Content: "latest"},
+			},
+		}
+
+		response, err := gateway.CompleteByModel(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Equal(t, "1", response.Metadata[domain.MetadataTrimmedMessages])
+		require.NotEmpty(t, response.Metadata[domain.MetadataTrimmedTokens])
+		require.Len(t, req.Messages, 2, "caller request must not be mutated")
+	})
+}
+
+func TestGatewayService_ContextWindowStrategies(t *testing.T) {
+	oversized := []domain.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: strings.Repeat("old ", 600)},
+		{Role: "assistant", Content: "noted"},
+		{Role: "user", Content: "latest"},
+	}
+	capabilities := domain.ModelCapabilities{ContextWindow: 400, MaxOutputTokens: 0}
+
+	t.Run("should reject an oversized prompt with the error strategy", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").Return(capabilities, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithContextWindow(mockCapabilities, 50, domain.ContextStrategyError, ""))
+
+		_, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: oversized})
+
+		var contextErr *domain.ContextWindowError
+		require.ErrorAs(t, err, &contextErr)
+		require.Equal(t, 350, contextErr.Limit)
+		require.Greater(t, contextErr.PromptTokens, 350)
+	})
+
+	t.Run("should reject a prompt whose latest turn alone does not fit", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").Return(capabilities, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithContextWindow(mockCapabilities, 50, domain.ContextStrategyTrimOldest, ""))
+
+		_, err := gateway.CompleteByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: strings.Repeat("huge ", 600)}},
+		})
+
+		var contextErr *domain.ContextWindowError
+		require.ErrorAs(t, err, &contextErr)
+	})
+
+	t.Run("should replace dropped messages with a summary", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").Return(capabilities, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.MaxTokens > 0 && strings.Contains(req.Messages[1].Content, "user: old")
+			})).
+			Return(&domain.CompletionResponse{
+				ID:         "summary-id",
+				Model:      "gpt-4",
+				Provider:   "openai",
+				Content:    "They said old things.",
+				FinishTime: time.Now(),
+			}, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return len(req.Messages) == 4 &&
+					req.Messages[0].Content == "be brief" &&
+					strings.HasSuffix(req.Messages[1].Content, "They said old things.") &&
+					req.Messages[3].Content == "latest"
+			})).
+			Return(&domain.CompletionResponse{
+				ID:         "test-id",
+				Model:      "gpt-4",
+				Provider:   "openai",
+				Content:    "ok",
+				FinishTime: time.Now(),
+			}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.AnythingOfType("domain.Usage")).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithContextWindow(mockCapabilities, 50, domain.ContextStrategySummarize, ""))
+
+		response, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: oversized})
+
+		require.NoError(t, err)
+		require.Equal(t, "1", response.Metadata[domain.MetadataTrimmedMessages])
+		require.Equal(t, "1", response.Metadata[domain.MetadataSummarizedMessages])
+	})
+
+	t.Run("should trim without a summary when summarization fails", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "summarizer").Return(nil, errors.New("unknown model"))
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").Return(capabilities, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return len(req.Messages) == 3 && req.Messages[2].Content == "latest"
+			})).
+			Return(&domain.CompletionResponse{
+				ID:         "test-id",
+				Model:      "gpt-4",
+				Provider:   "openai",
+				Content:    "ok",
+				FinishTime: time.Now(),
+			}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.AnythingOfType("domain.Usage")).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithContextWindow(mockCapabilities, 50, domain.ContextStrategySummarize, "summarizer"))
+
+		response, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: oversized})
+
+		require.NoError(t, err)
+		require.Equal(t, "1", response.Metadata[domain.MetadataTrimmedMessages])
+		require.Empty(t, response.Metadata[domain.MetadataSummarizedMessages])
+	})
+
+	t.Run("should meter the summary call and bound its transcript", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockCapabilities := mocks.NewMockCapabilityRegistry(t)
+		store := &memoryUsageStore{}
+
+		long := strings.Repeat("word ", 2400) // 3000 tokens
+		history := []domain.Message{{Role: "system", Content: "be brief"}}
+		for _, name := range []string{"old-1", "old-2", "old-3", "old-4", "old-5"} {
+			history = append(history, domain.Message{Role: "user", Content: name + " " + long})
+		}
+		history = append(history, domain.Message{Role: "user", Content: "latest"})
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockCapabilities.EXPECT().GetCapabilities(mock.Anything, "gpt-4").
+			Return(domain.ModelCapabilities{ContextWindow: 10000, MaxOutputTokens: 0}, nil)
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.MaxTokens > 0 && len(req.Messages) == 2
+			})).
+			RunAndReturn(func(_ context.Context, req *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+				// Only the newer dropped message fits the transcript budget.
+				require.NotContains(t, req.Messages[1].Content, "old-1")
+				require.Contains(t, req.Messages[1].Content, "old-2")
+				return &domain.CompletionResponse{
+					Model:    "gpt-4",
+					Provider: "openai",
+					Content:  "They said old things.",
+					Usage:    domain.Usage{PromptTokens: 3000, CompletionTokens: 10, TotalTokens: 3010},
+				}, nil
+			})
+		mockProvider.EXPECT().
+			Complete(mock.Anything, mock.MatchedBy(func(req *domain.CompletionRequest) bool {
+				return req.Messages[len(req.Messages)-1].Content == "latest"
+			})).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.AnythingOfType("domain.Usage")).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithContextWindow(mockCapabilities, 50, domain.ContextStrategySummarize, ""),
+			domain.WithUsageStore(store))
+
+		response, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: history})
+
+		require.NoError(t, err)
+		require.Equal(t, "2", response.Metadata[domain.MetadataSummarizedMessages])
+		require.Len(t, store.records, 2)
+		summary := store.records[0]
+		require.Equal(t, "2", summary.Metadata[domain.MetadataSummarizedMessages])
+		require.Equal(t, 3010, summary.TotalTokens)
+		require.InDelta(t, 0.01, summary.Cost, 1e-9)
+	})
+}
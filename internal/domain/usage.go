@@ -0,0 +1,338 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ErrUsageUnavailable indicates no usage store is configured.
+var ErrUsageUnavailable = errors.New("usage reporting is not available")
+
+// Usage report groupings.
+const (
+	GroupByModel    = "model"
+	GroupByProvider = "provider"
+	GroupByTenant   = "tenant"
+	GroupByKey      = "key"
+	GroupByDay      = "day"
+
+	// GroupByTagPrefix groups by an attribution tag, e.g. "tag:team".
+	GroupByTagPrefix = "tag:"
+)
+
+// UsageRecord captures the usage of one completed request.
+type UsageRecord struct {
+	Time             time.Time         `json:"time"`
+	RequestID        string            `json:"request_id,omitempty"`
+	Tenant           string            `json:"tenant"`
+	ClientKey        string            `json:"client_key,omitempty"`
+	Provider         string            `json:"provider"`
+	Model            string            `json:"model"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	TotalTokens      int               `json:"total_tokens"`
+	CachedTokens     int               `json:"cached_prompt_tokens,omitempty"` // prompt tokens from provider caches
+	Characters       int               `json:"characters,omitempty"`           // input characters of speech requests
+	Images           int               `json:"images,omitempty"`               // images billed per image
+	Cost             float64           `json:"cost"`
+	Stream           bool              `json:"stream,omitempty"`
+	Estimated        bool              `json:"estimated,omitempty"` // token counts were estimated, not reported
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`          // cost attribution tags from request metadata
+	ProviderCost     float64           `json:"provider_cost,omitempty"` // raw USD cost when Cost is a chargeback amount
+	Currency         string            `json:"currency,omitempty"`      // currency of a chargeback Cost
+
+	// Estimated prompt tokens before and after prompt compression; zero when not compressed.
+	OriginalPromptTokens   int `json:"original_prompt_tokens,omitempty"`
+	CompressedPromptTokens int `json:"compressed_prompt_tokens,omitempty"`
+
+	// Response quality scores, 0-1, by evaluator; set on responses sampled for evaluation.
+	Scores map[string]float64 `json:"scores,omitempty"`
+
+	// Rollup is RollupHourly or RollupDaily for a record folding the requests of
+	// an hour or day, counted by Requests; it is empty for a single request.
+	Rollup   string `json:"rollup,omitempty"`
+	Requests int    `json:"requests,omitempty"`
+}
+
+// requests returns the number of requests the record stands for.
+func (r UsageRecord) requests() int {
+	if r.Rollup == "" {
+		return 1
+	}
+	return r.Requests
+}
+
+// providerCost returns the raw USD provider cost of the record.
+// Without chargeback pricing, Cost already is the provider cost.
+func (r UsageRecord) providerCost() float64 {
+	if r.Currency == "" {
+		return r.Cost
+	}
+	return r.ProviderCost
+}
+
+// currency returns the currency of the record's Cost.
+func (r UsageRecord) currency() string {
+	if r.Currency == "" {
+		return DefaultCurrency
+	}
+	return r.Currency
+}
+
+// UsageFilter selects usage records. Zero values do not filter.
+type UsageFilter struct {
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+	ClientKey string
+}
+
+// Matches reports whether record passes the filter.
+func (f UsageFilter) Matches(record UsageRecord) bool {
+	if !f.From.IsZero() && record.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !record.Time.Before(f.To) {
+		return false
+	}
+	if f.ClientKey != "" && record.ClientKey != f.ClientKey {
+		return false
+	}
+	return true
+}
+
+// UsageAggregate sums usage for one group and the currency of its cost.
+type UsageAggregate struct {
+	Group            string  `json:"group"`
+	Currency         string  `json:"currency"` // currency of Cost
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Characters       int     `json:"characters,omitempty"`
+	Images           int     `json:"images,omitempty"`
+	Cost             float64 `json:"cost"`
+	ProviderCost     float64 `json:"provider_cost"` // raw USD provider cost, before chargeback markup or conversion
+}
+
+// UsageStore persists usage records for reporting.
+type UsageStore interface {
+	// Record stores the usage of a completed request.
+	Record(ctx context.Context, record UsageRecord) error
+
+	// Query returns the records matching filter, oldest first.
+	Query(ctx context.Context, filter UsageFilter) ([]UsageRecord, error)
+}
+
+// WithUsageStore records the usage of every completed request.
+func WithUsageStore(store UsageStore) GatewayOption {
+	return func(g *GatewayService) {
+		g.usage = store
+	}
+}
+
+// UsageReport aggregates recorded usage matching filter by groupBy.
+func (g *GatewayService) UsageReport(
+	ctx context.Context,
+	filter UsageFilter,
+	groupBy string,
+) ([]UsageAggregate, error) {
+	if g.usage == nil {
+		return nil, ErrUsageUnavailable
+	}
+
+	records, err := g.usage.Query(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	return AggregateUsage(records, groupBy)
+}
+
+// AggregateUsage sums records by groupBy, sorted by group. Costs charged in
+// different currencies are never added up: a group with records in several
+// currencies yields one aggregate per currency.
+func AggregateUsage(records []UsageRecord, groupBy string) ([]UsageAggregate, error) {
+	groupOf, err := usageGrouping(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	type groupKey struct{ group, currency string }
+	groups := make(map[groupKey]*UsageAggregate)
+	for _, record := range records {
+		key := groupKey{group: groupOf(record), currency: record.currency()}
+
+		aggregate, ok := groups[key]
+		if !ok {
+			aggregate = &UsageAggregate{
+				Group:            key.group,
+				Currency:         key.currency,
+				Requests:         0,
+				PromptTokens:     0,
+				CompletionTokens: 0,
+				TotalTokens:      0,
+				Characters:       0,
+				Images:           0,
+				Cost:             0,
+				ProviderCost:     0,
+			}
+			groups[key] = aggregate
+		}
+
+		aggregate.Requests += record.requests()
+		aggregate.PromptTokens += record.PromptTokens
+		aggregate.CompletionTokens += record.CompletionTokens
+		aggregate.TotalTokens += record.TotalTokens
+		aggregate.Characters += record.Characters
+		aggregate.Images += record.Images
+		aggregate.Cost += record.Cost
+		aggregate.ProviderCost += record.providerCost()
+	}
+
+	aggregates := make([]UsageAggregate, 0, len(groups))
+	for _, aggregate := range groups {
+		aggregates = append(aggregates, *aggregate)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Group != aggregates[j].Group {
+			return aggregates[i].Group < aggregates[j].Group
+		}
+		return aggregates[i].Currency < aggregates[j].Currency
+	})
+
+	return aggregates, nil
+}
+
+func usageGrouping(groupBy string) (func(UsageRecord) string, error) {
+	if tag, ok := strings.CutPrefix(groupBy, GroupByTagPrefix); ok && tag != "" {
+		return func(r UsageRecord) string { return r.Tags[tag] }, nil
+	}
+
+	switch groupBy {
+	case GroupByModel:
+		return func(r UsageRecord) string { return r.Model }, nil
+	case GroupByProvider:
+		return func(r UsageRecord) string { return r.Provider }, nil
+	case GroupByTenant:
+		return func(r UsageRecord) string { return r.Tenant }, nil
+	case GroupByKey:
+		return func(r UsageRecord) string { return r.ClientKey }, nil
+	case GroupByDay:
+		return func(r UsageRecord) string { return r.Time.UTC().Format(time.DateOnly) }, nil
+	default:
+		return nil, &UnsupportedGroupingError{GroupBy: groupBy}
+	}
+}
+
+// recordUsage attributes and stores the usage of a completed request. Failures
+// are logged and never fail the request.
+func (g *GatewayService) recordUsage(ctx context.Context, record UsageRecord) {
+	g.storeUsage(ctx, g.observeUsage(ctx, record))
+}
+
+// observeUsage completes record with the request's identity and feeds it to cost
+// attribution, alerts, quotas, and tenant limits.
+func (g *GatewayService) observeUsage(ctx context.Context, record UsageRecord) UsageRecord {
+	g.attributeCost(ctx, record)
+
+	record.Time = time.Now().UTC()
+	record.RequestID = observability.GetRequestID(ctx)
+	record.Tenant = normalizeTenant(observability.GetTenant(ctx))
+	record.ClientKey = observability.GetClientKey(ctx)
+	record.OriginalPromptTokens, record.CompressedPromptTokens = compressionSavings(record.Metadata)
+
+	if g.alerts != nil {
+		g.alerts.ObserveUsage(ctx, record)
+	}
+	if g.quotas != nil {
+		g.quotas.Observe(ctx, record)
+	}
+	if g.tenants != nil {
+		g.tenants.observe(record)
+	}
+	return record
+}
+
+// observesUsage reports whether anything consumes usage records, so streams
+// only estimate their usage when it is needed.
+func (g *GatewayService) observesUsage() bool {
+	return g.usage != nil || len(g.attributionTags) > 0 || g.evaluation != nil ||
+		g.alerts != nil || g.quotas != nil || g.tenants != nil
+}
+
+// storeUsage persists a usage record when a usage store is configured.
+func (g *GatewayService) storeUsage(ctx context.Context, record UsageRecord) {
+	if g.usage == nil {
+		return
+	}
+
+	if err := g.usage.Record(ctx, record); err != nil {
+		observability.FromContext(ctx).Error("failed to record usage", observability.Error(err))
+	}
+}
+
+// streamUsage estimates the usage of a streamed completion from its content,
+// since providers do not report token counts for streams. The usage is recorded
+// even when the client disconnected, so it outlives the request's cancellation.
+func (g *GatewayService) streamUsage(
+	ctx context.Context,
+	providerName string,
+	req *CompletionRequest,
+	metadata map[string]string,
+) func(content string) {
+	ctx = context.WithoutCancel(ctx)
+	tokenizer := g.tokenizers.ForModel(req.Model)
+	return func(content string) {
+		usage := Usage{
+			PromptTokens:     CountMessagesTokens(tokenizer, req.Messages),
+			CompletionTokens: tokenizer.CountTokens(content),
+			TotalTokens:      0,
+			Cost:             0,
+
+			CachedPromptTokens: 0,
+			ReasoningTokens:    0,
+			Characters:         0,
+			Images:             0,
+			ProviderCost:       0,
+			Currency:           "",
+		}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		g.price(ctx, req.Model, &usage)
+
+		g.recordEvaluatedUsage(ctx, UsageRecord{
+			Time:             time.Time{},
+			RequestID:        "",
+			Tenant:           "",
+			ClientKey:        "",
+			Provider:         providerName,
+			Model:            req.Model,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+			CachedTokens:     usage.CachedPromptTokens,
+			Images:           usage.Images,
+			Cost:             usage.Cost,
+			Stream:           true,
+			Estimated:        true,
+			Metadata:         metadata,
+			Tags:             g.costTags(req.Metadata),
+			ProviderCost:     usage.ProviderCost,
+			Currency:         usage.Currency,
+
+			OriginalPromptTokens:   0,
+			CompressedPromptTokens: 0,
+
+			Scores: nil,
+
+			Rollup:   "",
+			Requests: 0,
+		}, req, content)
+	}
+}
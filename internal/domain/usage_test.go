@@ -0,0 +1,234 @@
+package domain_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// memoryUsageStore records usage in a slice for assertions.
+type memoryUsageStore struct {
+	mu      sync.Mutex
+	records []domain.UsageRecord
+}
+
+func (s *memoryUsageStore) Record(_ context.Context, record domain.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *memoryUsageStore) Query(_ context.Context, filter domain.UsageFilter) ([]domain.UsageRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []domain.UsageRecord
+	for _, record := range s.records {
+		if filter.Matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
+
+func TestAggregateUsage(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	records := []domain.UsageRecord{
+		{Time: day, Model: "gpt-4", PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.1},
+		{Time: day.Add(time.Hour), Model: "gpt-4", PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25, Cost: 0.2},
+		{Time: day.Add(24 * time.Hour), Model: "echo4", TotalTokens: 4},
+	}
+
+	t.Run("should aggregate by model", func(t *testing.T) {
+		aggregates, err := domain.AggregateUsage(records, domain.GroupByModel)
+		require.NoError(t, err)
+		require.Len(t, aggregates, 2)
+		require.Equal(t, "echo4", aggregates[0].Group)
+		require.Equal(t, "gpt-4", aggregates[1].Group)
+		require.Equal(t, 2, aggregates[1].Requests)
+		require.Equal(t, 40, aggregates[1].TotalTokens)
+		require.InDelta(t, 0.3, aggregates[1].Cost, 1e-9)
+	})
+
+	t.Run("should aggregate by day", func(t *testing.T) {
+		aggregates, err := domain.AggregateUsage(records, domain.GroupByDay)
+		require.NoError(t, err)
+		require.Len(t, aggregates, 2)
+		require.Equal(t, "2026-03-10", aggregates[0].Group)
+		require.Equal(t, 2, aggregates[0].Requests)
+	})
+
+	t.Run("should keep costs in different currencies apart", func(t *testing.T) {
+		charged := []domain.UsageRecord{
+			{Time: day, Model: "gpt-4", Cost: 0.1},
+			{Time: day, Model: "gpt-4", Cost: 0.2, ProviderCost: 0.25, Currency: "EUR"},
+			{Time: day, Model: "gpt-4", Cost: 0.3, ProviderCost: 0.35, Currency: "EUR"},
+		}
+
+		aggregates, err := domain.AggregateUsage(charged, domain.GroupByModel)
+		require.NoError(t, err)
+		require.Len(t, aggregates, 2)
+		require.Equal(t, "EUR", aggregates[0].Currency)
+		require.InDelta(t, 0.5, aggregates[0].Cost, 1e-9)
+		require.InDelta(t, 0.6, aggregates[0].ProviderCost, 1e-9)
+		require.Equal(t, domain.DefaultCurrency, aggregates[1].Currency)
+		require.InDelta(t, 0.1, aggregates[1].Cost, 1e-9)
+	})
+
+	t.Run("should reject unknown grouping", func(t *testing.T) {
+		_, err := domain.AggregateUsage(records, "color")
+		var groupingErr *domain.UnsupportedGroupingError
+		require.ErrorAs(t, err, &groupingErr)
+	})
+}
+
+func TestGatewayService_RecordsUsage(t *testing.T) {
+	t.Run("should record completion usage with request attribution", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).Return(&domain.CompletionResponse{
+			Model:    "gpt-4",
+			Provider: "openai",
+			Usage:    domain.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.05, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithUsageStore(store))
+
+		ctx := observability.WithTenant(context.Background(), "acme")
+		ctx = observability.WithClientKey(ctx, "mobile")
+		_, err := gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, store.records, 1)
+		record := store.records[0]
+		require.Equal(t, "acme", record.Tenant)
+		require.Equal(t, "mobile", record.ClientKey)
+		require.Equal(t, "openai", record.Provider)
+		require.Equal(t, 15, record.TotalTokens)
+		require.InDelta(t, 0.05, record.Cost, 1e-9)
+		require.False(t, record.Estimated)
+	})
+
+	t.Run("should record estimated usage when a stream completes", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		ch := make(chan domain.StreamChunk, 2)
+		ch <- domain.StreamChunk{Delta: "Hello there friend"}
+		ch <- domain.StreamChunk{Done: true}
+		close(ch)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).Return((<-chan domain.StreamChunk)(ch), nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithUsageStore(store))
+
+		chunks, err := gateway.StreamByModel(context.Background(), &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+		for range chunks {
+		}
+
+		require.Len(t, store.records, 1)
+		record := store.records[0]
+		require.True(t, record.Stream)
+		require.True(t, record.Estimated)
+		require.Equal(t, "openai", record.Provider)
+		require.Positive(t, record.CompletionTokens)
+		require.Equal(t, record.PromptTokens+record.CompletionTokens, record.TotalTokens)
+	})
+
+	t.Run("should charge streamed tokens to quotas without a usage store", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		quotas, err := domain.NewQuotaManager(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, quotas.Set(context.Background(), domain.Quota{Key: "alice", TokensPerDay: 1000}))
+
+		ch := make(chan domain.StreamChunk, 2)
+		ch <- domain.StreamChunk{Delta: "Hello there friend"}
+		ch <- domain.StreamChunk{Done: true}
+		close(ch)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).Return((<-chan domain.StreamChunk)(ch), nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithQuotas(quotas))
+
+		ctx := observability.WithClientKey(context.Background(), "alice")
+		chunks, err := gateway.StreamByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+		for range chunks {
+		}
+
+		status, err := quotas.Get(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Positive(t, status.Usage.TokensToday)
+	})
+
+	t.Run("should record the usage streamed before the client disconnects", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+		store := &memoryUsageStore{}
+
+		// The provider never finishes the stream.
+		ch := make(chan domain.StreamChunk, 1)
+		ch <- domain.StreamChunk{Delta: "Hello there friend"}
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).Return((<-chan domain.StreamChunk)(ch), nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0.01, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithUsageStore(store))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		chunks, err := gateway.StreamByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		})
+		require.NoError(t, err)
+		<-chunks
+		cancel()
+		for range chunks {
+		}
+
+		require.Eventually(t, func() bool {
+			records, _ := store.Query(context.Background(), domain.UsageFilter{})
+			return len(records) == 1 && records[0].CompletionTokens > 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
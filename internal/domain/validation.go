@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// knownRoles are the message roles every provider accepts.
+//
+//nolint:gochecknoglobals // Read-only lookup table
+var knownRoles = map[string]bool{"system": true, "developer": true, "user": true, "assistant": true, "tool": true}
+
+// WithStrictRoleOrder requires requests routed to the listed providers to follow
+// strict role ordering (see ValidateRoleOrder), so they are rejected with a
+// field-level error instead of failing at the provider.
+func WithStrictRoleOrder(providers []string) GatewayOption {
+	return func(g *GatewayService) {
+		g.strictRoleOrder = providers
+	}
+}
+
+// ValidateMessages checks that a conversation has messages, that every message
+// has a known role and non-blank content, and that tool messages name the tool
+// call they answer. A tool result may be empty, as may an assistant message
+// that only calls tools.
+func ValidateMessages(messages []Message) error {
+	if len(messages) == 0 {
+		return &ValidationError{Param: "messages", Message: "at least one message is required"}
+	}
+
+	for i, msg := range messages {
+		if !knownRoles[msg.Role] {
+			return &ValidationError{
+				Param:   fmt.Sprintf("messages[%d].role", i),
+				Message: fmt.Sprintf("unknown role %q: must be system, developer, user, assistant, or tool", msg.Role),
+			}
+		}
+		if msg.Role == "tool" {
+			if msg.ToolCallID == "" {
+				return &ValidationError{
+					Param:   fmt.Sprintf("messages[%d].tool_call_id", i),
+					Message: "tool messages require a tool_call_id",
+				}
+			}
+			continue
+		}
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			continue
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			return &ValidationError{Param: fmt.Sprintf("messages[%d].content", i), Message: "content cannot be empty"}
+		}
+	}
+
+	return nil
+}
+
+// ValidateRoleOrder checks the ordering some providers require: instruction
+// messages only at the start, then a user message, then strictly alternating
+// user and assistant messages. Tool results take the user's turn, as they are
+// sent as user content to such providers.
+func ValidateRoleOrder(messages []Message) error {
+	expected := "user"
+	conversation := false
+	for i, msg := range messages {
+		param := fmt.Sprintf("messages[%d].role", i)
+
+		if isInstruction(msg) {
+			if conversation {
+				return &ValidationError{Param: param, Message: msg.Role + " messages must precede the conversation"}
+			}
+			continue
+		}
+
+		conversation = true
+		role := msg.Role
+		if role == "tool" {
+			role = "user"
+		}
+		if role != expected {
+			return &ValidationError{
+				Param:   param,
+				Message: fmt.Sprintf("expected a %s message: user and assistant messages must alternate", expected),
+			}
+		}
+		if expected == "user" {
+			expected = "assistant"
+		} else {
+			expected = "user"
+		}
+	}
+
+	if !conversation {
+		return &ValidationError{Param: "messages", Message: "at least one user message is required"}
+	}
+	return nil
+}
+
+// isInstruction reports whether msg instructs the model rather than taking a
+// conversation turn: system messages, and developer messages that replace them
+// for newer OpenAI models.
+func isInstruction(msg Message) bool {
+	return msg.Role == "system" || msg.Role == "developer"
+}
+
+// enforceRoleOrder applies strict role ordering when the routed provider requires it.
+func (g *GatewayService) enforceRoleOrder(provider Provider, req *CompletionRequest) error {
+	if len(g.strictRoleOrder) == 0 || !slices.Contains(g.strictRoleOrder, provider.Name()) {
+		return nil
+	}
+
+	if err := ValidateRoleOrder(req.Messages); err != nil {
+		return fmt.Errorf("provider %s: %w", provider.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestValidateMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []domain.Message
+		param    string
+	}{
+		{
+			name:     "should accept a conversation",
+			messages: []domain.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hi"}},
+			param:    "",
+		},
+		{
+			name:     "should require messages",
+			messages: nil,
+			param:    "messages",
+		},
+		{
+			name:     "should reject an unknown role",
+			messages: []domain.Message{{Role: "user", Content: "Hi"}, {Role: "function", Content: "42"}},
+			param:    "messages[1].role",
+		},
+		{
+			name:     "should reject a tool message without a tool call id",
+			messages: []domain.Message{{Role: "user", Content: "Hi"}, {Role: "tool", Content: "42"}},
+			param:    "messages[1].tool_call_id",
+		},
+		{
+			name: "should accept developer messages and empty tool results",
+			messages: []domain.Message{
+				{Role: "developer", Content: "Be brief."},
+				{Role: "user", Content: "Hi"},
+				{Role: "assistant", Content: "Calling a tool."},
+				{Role: "tool", Content: "", ToolCallID: "call_1"},
+			},
+			param: "",
+		},
+		{
+			name: "should accept assistant messages that only call tools",
+			messages: []domain.Message{
+				{Role: "user", Content: "Weather?"},
+				{Role: "assistant", ToolCalls: []domain.ToolCall{{ID: "call_1", Type: "function"}}},
+				{Role: "tool", Content: "Sunny", ToolCallID: "call_1"},
+			},
+			param: "",
+		},
+		{
+			name:     "should reject blank content",
+			messages: []domain.Message{{Role: "user", Content: " \n"}},
+			param:    "messages[0].content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := domain.ValidateMessages(tt.messages)
+			if tt.param == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.param, validationErr.Param)
+		})
+	}
+}
+
+func TestValidateRoleOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []domain.Message
+		param    string
+	}{
+		{
+			name: "should accept alternating turns after system messages",
+			messages: []domain.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hi"},
+				{Role: "assistant", Content: "Hello"},
+				{Role: "user", Content: "Bye"},
+			},
+			param: "",
+		},
+		{
+			name:     "should require the conversation to start with a user message",
+			messages: []domain.Message{{Role: "assistant", Content: "Hello"}, {Role: "user", Content: "Hi"}},
+			param:    "messages[0].role",
+		},
+		{
+			name:     "should reject consecutive user messages",
+			messages: []domain.Message{{Role: "user", Content: "Hi"}, {Role: "user", Content: "Hello?"}},
+			param:    "messages[1].role",
+		},
+		{
+			name: "should reject a system message within the conversation",
+			messages: []domain.Message{
+				{Role: "user", Content: "Hi"},
+				{Role: "system", Content: "Be brief."},
+			},
+			param: "messages[1].role",
+		},
+		{
+			name: "should treat tool results as the user's turn",
+			messages: []domain.Message{
+				{Role: "developer", Content: "Be brief."},
+				{Role: "user", Content: "Hi"},
+				{Role: "assistant", Content: "Calling a tool."},
+				{Role: "tool", Content: "42", ToolCallID: "call_1"},
+			},
+			param: "",
+		},
+		{
+			name:     "should require a user message",
+			messages: []domain.Message{{Role: "system", Content: "Be brief."}},
+			param:    "messages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := domain.ValidateRoleOrder(tt.messages)
+			if tt.param == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.param, validationErr.Param)
+		})
+	}
+}
+
+func TestGatewayService_StrictRoleOrder(t *testing.T) {
+	messages := []domain.Message{{Role: "user", Content: "Hi"}, {Role: "user", Content: "Hello?"}}
+
+	t.Run("should reject out-of-order messages for a strict provider", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "claude-3").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("anthropic")
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithStrictRoleOrder([]string{"anthropic"}))
+
+		_, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "claude-3", Messages: messages})
+
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Equal(t, "messages[1].role", validationErr.Param)
+	})
+
+	t.Run("should forward the same messages to other providers", func(t *testing.T) {
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		mockProvider := mocks.NewMockProvider(t)
+
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Complete(mock.Anything, mock.Anything).
+			Return(&domain.CompletionResponse{Model: "gpt-4", Provider: "openai", Content: "ok"}, nil)
+		mockCostCalc.EXPECT().Calculate(mock.Anything, "gpt-4", mock.Anything).Return(0, nil)
+
+		gateway := domain.NewGatewayService(mockRegistry, mockCostCalc,
+			domain.WithStrictRoleOrder([]string{"anthropic"}))
+
+		_, err := gateway.CompleteByModel(context.Background(),
+			&domain.CompletionRequest{Model: "gpt-4", Messages: messages})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// VirtualKeyPrefix starts every virtual key secret, so leaked keys are easy to recognize.
+	VirtualKeyPrefix = "ck-"
+
+	// virtualKeyBytes is the entropy of a virtual key secret.
+	virtualKeyBytes = 32
+
+	// virtualKeyPolicyPrefix names the policy reported when a virtual key's model list rejects a request.
+	virtualKeyPolicyPrefix = "virtual-key:"
+)
+
+// virtualKeyNamePattern bounds virtual key names, which appear in logs and usage reports.
+var virtualKeyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+var (
+	// ErrUnknownVirtualKey indicates no virtual key has the given name.
+	ErrUnknownVirtualKey = errors.New("unknown virtual key")
+
+	// ErrInvalidVirtualKey indicates a virtual key request with a missing, invalid, or taken name,
+	// a past expiry, or a negative budget.
+	ErrInvalidVirtualKey = errors.New("invalid virtual key")
+)
+
+// VirtualKey is a client key minted at runtime. Only a digest of its secret is
+// kept; the secret is returned once, when the key is issued.
+type VirtualKey struct {
+	Name          string    `json:"name"`
+	Hash          string    `json:"hash"` // hex SHA-256 of the secret
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at,omitzero"` // zero never expires
+	AllowModels   []string  `json:"allow_models,omitempty"`
+	MonthlyBudget float64   `json:"monthly_budget,omitempty"` // enforced as a spend quota
+}
+
+// VirtualKeyStore persists virtual keys so issued keys survive restarts.
+type VirtualKeyStore interface {
+	// Load returns the stored keys.
+	Load(ctx context.Context) ([]VirtualKey, error)
+
+	// Save replaces the stored keys.
+	Save(ctx context.Context, keys []VirtualKey) error
+}
+
+// VirtualKeys issues, authenticates, and revokes virtual keys. A key's model list
+// is enforced by the gateway and its budget through the quota manager.
+type VirtualKeys struct {
+	store    VirtualKeyStore
+	quotas   *QuotaManager
+	reserved map[string]bool // statically configured key names
+
+	mu     sync.RWMutex
+	byName map[string]*VirtualKey
+	byHash map[string]*VirtualKey
+}
+
+// NewVirtualKeys creates a virtual key manager loaded from store. Names in
+// reserved, the statically configured client keys, cannot be issued. A nil store
+// keeps keys in memory only.
+func NewVirtualKeys(
+	ctx context.Context,
+	store VirtualKeyStore,
+	quotas *QuotaManager,
+	reserved []string,
+) (*VirtualKeys, error) {
+	manager := &VirtualKeys{
+		store:    store,
+		quotas:   quotas,
+		reserved: make(map[string]bool, len(reserved)),
+		mu:       sync.RWMutex{},
+		byName:   make(map[string]*VirtualKey),
+		byHash:   make(map[string]*VirtualKey),
+	}
+	for _, name := range reserved {
+		manager.reserved[name] = true
+	}
+
+	if store == nil {
+		return manager, nil
+	}
+
+	keys, err := store.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load virtual keys: %w", err)
+	}
+	for i := range keys {
+		manager.byName[keys[i].Name] = &keys[i]
+		manager.byHash[keys[i].Hash] = &keys[i]
+	}
+
+	return manager, nil
+}
+
+// WithVirtualKeys enforces the model lists of virtual keys.
+func WithVirtualKeys(keys *VirtualKeys) GatewayOption {
+	return func(g *GatewayService) {
+		g.virtualKeys = keys
+	}
+}
+
+// Issue mints a virtual key and returns its secret, which is not stored and
+// cannot be recovered. A budget is set as the key's monthly spend quota.
+func (v *VirtualKeys) Issue(ctx context.Context, key VirtualKey) (string, VirtualKey, error) {
+	now := time.Now().UTC()
+	if !virtualKeyNamePattern.MatchString(key.Name) {
+		return "", VirtualKey{}, fmt.Errorf("%w: name %q must be 1-64 letters, digits, '.', '_' or '-'",
+			ErrInvalidVirtualKey, key.Name)
+	}
+	if !key.ExpiresAt.IsZero() && !key.ExpiresAt.After(now) {
+		return "", VirtualKey{}, fmt.Errorf("%w: expiry is in the past", ErrInvalidVirtualKey)
+	}
+	if key.MonthlyBudget < 0 {
+		return "", VirtualKey{}, fmt.Errorf("%w: budget is negative", ErrInvalidVirtualKey)
+	}
+
+	random := make([]byte, virtualKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", VirtualKey{}, fmt.Errorf("failed to generate virtual key: %w", err)
+	}
+	secret := VirtualKeyPrefix + hex.EncodeToString(random)
+	key.Hash = hashVirtualKey(secret)
+	key.CreatedAt = now
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.reserved[key.Name] || v.byName[key.Name] != nil {
+		return "", VirtualKey{}, fmt.Errorf("%w: name %s is taken", ErrInvalidVirtualKey, key.Name)
+	}
+
+	if key.MonthlyBudget > 0 && v.quotas != nil {
+		quota := Quota{Key: key.Name, RequestsPerDay: 0, TokensPerDay: 0, SpendPerMonth: key.MonthlyBudget}
+		if err := v.quotas.Set(ctx, quota); err != nil {
+			return "", VirtualKey{}, fmt.Errorf("failed to set virtual key budget: %w", err)
+		}
+	}
+
+	v.byName[key.Name] = &key
+	v.byHash[key.Hash] = &key
+	if err := v.save(ctx); err != nil {
+		delete(v.byName, key.Name)
+		delete(v.byHash, key.Hash)
+		if key.MonthlyBudget > 0 && v.quotas != nil {
+			_ = v.quotas.Delete(ctx, key.Name)
+		}
+		return "", VirtualKey{}, err
+	}
+
+	return secret, key, nil
+}
+
+// Authenticate returns the name of the unexpired virtual key with secret.
+func (v *VirtualKeys) Authenticate(secret string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	key, ok := v.byHash[hashVirtualKey(secret)]
+	if !ok || (!key.ExpiresAt.IsZero() && !time.Now().Before(key.ExpiresAt)) {
+		return "", false
+	}
+	return key.Name, true
+}
+
+// List returns every virtual key, including expired ones, sorted by name.
+func (v *VirtualKeys) List() []VirtualKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]VirtualKey, 0, len(v.byName))
+	for _, key := range v.byName {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// Revoke deletes a virtual key and its budget quota.
+func (v *VirtualKeys) Revoke(ctx context.Context, name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownVirtualKey, name)
+	}
+
+	delete(v.byName, name)
+	delete(v.byHash, key.Hash)
+	if err := v.save(ctx); err != nil {
+		v.byName[name] = key
+		v.byHash[key.Hash] = key
+		return err
+	}
+
+	if key.MonthlyBudget > 0 && v.quotas != nil {
+		if err := v.quotas.Delete(ctx, name); err != nil && !errors.Is(err, ErrUnknownQuota) {
+			return fmt.Errorf("failed to remove virtual key budget: %w", err)
+		}
+	}
+	return nil
+}
+
+// enforceVirtualKeyModel rejects a model outside the calling virtual key's model list.
+func (g *GatewayService) enforceVirtualKeyModel(ctx context.Context, model string) error {
+	if g.virtualKeys == nil {
+		return nil
+	}
+
+	name := observability.GetClientKey(ctx)
+	g.virtualKeys.mu.RLock()
+	key, ok := g.virtualKeys.byName[name]
+	g.virtualKeys.mu.RUnlock()
+	if !ok || permitted(model, key.AllowModels, nil) {
+		return nil
+	}
+
+	violation := &PolicyError{Policy: virtualKeyPolicyPrefix + name, Kind: "model", Name: model}
+	observability.PolicyViolations.WithLabelValues("virtual-key", violation.Kind).Inc()
+	observability.FromContext(ctx).Info("request rejected by virtual key model list",
+		observability.String("model", model),
+	)
+	return violation
+}
+
+// save persists the current keys. Caller must hold the write lock.
+func (v *VirtualKeys) save(ctx context.Context) error {
+	if v.store == nil {
+		return nil
+	}
+
+	keys := make([]VirtualKey, 0, len(v.byName))
+	for _, key := range v.byName {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+
+	if err := v.store.Save(ctx, keys); err != nil {
+		return fmt.Errorf("failed to save virtual keys: %w", err)
+	}
+	return nil
+}
+
+// hashVirtualKey returns the hex SHA-256 digest of a virtual key secret.
+func hashVirtualKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,155 @@
+package domain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// memoryVirtualKeyStore keeps saved virtual keys in memory.
+type memoryVirtualKeyStore struct {
+	keys []domain.VirtualKey
+}
+
+func (s *memoryVirtualKeyStore) Load(_ context.Context) ([]domain.VirtualKey, error) {
+	return s.keys, nil
+}
+
+func (s *memoryVirtualKeyStore) Save(_ context.Context, keys []domain.VirtualKey) error {
+	s.keys = keys
+	return nil
+}
+
+func TestVirtualKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should issue a key that authenticates and is stored only as a digest", func(t *testing.T) {
+		store := &memoryVirtualKeyStore{}
+		keys, err := domain.NewVirtualKeys(ctx, store, nil, nil)
+		require.NoError(t, err)
+
+		secret, key, err := keys.Issue(ctx, domain.VirtualKey{Name: "ci-bot"})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(secret, domain.VirtualKeyPrefix))
+		require.NotContains(t, key.Hash, secret)
+
+		name, ok := keys.Authenticate(secret)
+		require.True(t, ok)
+		require.Equal(t, "ci-bot", name)
+
+		_, ok = keys.Authenticate(secret + "x")
+		require.False(t, ok)
+
+		require.Len(t, store.keys, 1)
+		require.Equal(t, key.Hash, store.keys[0].Hash)
+	})
+
+	t.Run("should reload issued keys from the store", func(t *testing.T) {
+		store := &memoryVirtualKeyStore{}
+		keys, err := domain.NewVirtualKeys(ctx, store, nil, nil)
+		require.NoError(t, err)
+		secret, _, err := keys.Issue(ctx, domain.VirtualKey{Name: "ci-bot"})
+		require.NoError(t, err)
+
+		reloaded, err := domain.NewVirtualKeys(ctx, store, nil, nil)
+		require.NoError(t, err)
+		name, ok := reloaded.Authenticate(secret)
+		require.True(t, ok)
+		require.Equal(t, "ci-bot", name)
+	})
+
+	t.Run("should stop authenticating expired and revoked keys", func(t *testing.T) {
+		store := &memoryVirtualKeyStore{keys: []domain.VirtualKey{{
+			Name:      "old",
+			Hash:      "unused",
+			ExpiresAt: time.Now().Add(-time.Minute),
+		}}}
+		keys, err := domain.NewVirtualKeys(ctx, store, nil, nil)
+		require.NoError(t, err)
+
+		secret, _, err := keys.Issue(ctx, domain.VirtualKey{Name: "temp", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		_, ok := keys.Authenticate(secret)
+		require.True(t, ok)
+
+		require.NoError(t, keys.Revoke(ctx, "temp"))
+		_, ok = keys.Authenticate(secret)
+		require.False(t, ok)
+		require.ErrorIs(t, keys.Revoke(ctx, "temp"), domain.ErrUnknownVirtualKey)
+	})
+
+	t.Run("should set and remove the key's budget quota", func(t *testing.T) {
+		quotas, err := domain.NewQuotaManager(ctx, nil)
+		require.NoError(t, err)
+		keys, err := domain.NewVirtualKeys(ctx, nil, quotas, nil)
+		require.NoError(t, err)
+
+		_, _, err = keys.Issue(ctx, domain.VirtualKey{Name: "team-a", MonthlyBudget: 25})
+		require.NoError(t, err)
+		status, err := quotas.Get(ctx, "team-a")
+		require.NoError(t, err)
+		require.InDelta(t, 25.0, status.SpendPerMonth, 1e-9)
+
+		require.NoError(t, keys.Revoke(ctx, "team-a"))
+		_, err = quotas.Get(ctx, "team-a")
+		require.ErrorIs(t, err, domain.ErrUnknownQuota)
+	})
+
+	tests := []struct {
+		name string
+		key  domain.VirtualKey
+	}{
+		{name: "invalid names", key: domain.VirtualKey{Name: "has space"}},
+		{name: "reserved names", key: domain.VirtualKey{Name: "mobile"}},
+		{name: "past expiry", key: domain.VirtualKey{Name: "late", ExpiresAt: time.Now().Add(-time.Hour)}},
+		{name: "negative budgets", key: domain.VirtualKey{Name: "neg", MonthlyBudget: -1}},
+	}
+	for _, tt := range tests {
+		t.Run("should reject "+tt.name, func(t *testing.T) {
+			keys, err := domain.NewVirtualKeys(ctx, nil, nil, []string{"mobile"})
+			require.NoError(t, err)
+
+			_, _, err = keys.Issue(ctx, tt.key)
+			require.ErrorIs(t, err, domain.ErrInvalidVirtualKey)
+		})
+	}
+
+	t.Run("should reject duplicate names", func(t *testing.T) {
+		keys, err := domain.NewVirtualKeys(ctx, nil, nil, nil)
+		require.NoError(t, err)
+		_, _, err = keys.Issue(ctx, domain.VirtualKey{Name: "dup"})
+		require.NoError(t, err)
+
+		_, _, err = keys.Issue(ctx, domain.VirtualKey{Name: "dup"})
+		require.ErrorIs(t, err, domain.ErrInvalidVirtualKey)
+	})
+}
+
+func TestGatewayService_VirtualKeys(t *testing.T) {
+	t.Run("should reject models outside the key's allow list", func(t *testing.T) {
+		keys, err := domain.NewVirtualKeys(context.Background(), nil, nil, nil)
+		require.NoError(t, err)
+		_, _, err = keys.Issue(context.Background(), domain.VirtualKey{Name: "cheap", AllowModels: []string{"gpt-3.5*"}})
+		require.NoError(t, err)
+
+		gateway := domain.NewGatewayService(mocks.NewMockProviderRegistry(t), mocks.NewMockCostCalculator(t),
+			domain.WithVirtualKeys(keys))
+
+		ctx := observability.WithClientKey(context.Background(), "cheap")
+		_, err = gateway.CompleteByModel(ctx, &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+		})
+
+		var policyErr *domain.PolicyError
+		require.ErrorAs(t, err, &policyErr)
+		require.Equal(t, "virtual-key:cheap", policyErr.Policy)
+	})
+}
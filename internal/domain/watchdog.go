@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ErrStreamTimeout ends a stream the watchdog cancelled after the maximum
+// stream duration, so consumers can tell it from a completed stream.
+var ErrStreamTimeout = errors.New("stream exceeded the maximum duration")
+
+// StreamWatchdog tracks open provider streams and cancels any that outlive the
+// maximum stream duration. Every stream wrapper stops on cancellation, so a stalled
+// provider or a consumer that stops reading cannot hold goroutines and concurrency
+// slots forever.
+type StreamWatchdog struct {
+	maxDuration time.Duration
+
+	mu     sync.Mutex
+	nextID uint64
+	open   map[uint64]context.CancelFunc
+}
+
+// NewStreamWatchdog creates a stream watchdog. A zero maxDuration tracks streams
+// without a time limit.
+func NewStreamWatchdog(maxDuration time.Duration) *StreamWatchdog {
+	return &StreamWatchdog{
+		maxDuration: maxDuration,
+		mu:          sync.Mutex{},
+		nextID:      0,
+		open:        make(map[uint64]context.CancelFunc),
+	}
+}
+
+// WithStreamWatchdog tracks every provider stream with watchdog.
+func WithStreamWatchdog(watchdog *StreamWatchdog) GatewayOption {
+	return func(g *GatewayService) {
+		g.streams = watchdog
+	}
+}
+
+// Active returns the number of open streams.
+func (w *StreamWatchdog) Active() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.open)
+}
+
+// CancelAll cancels every open stream.
+func (w *StreamWatchdog) CancelAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, cancel := range w.open {
+		cancel()
+	}
+}
+
+// track registers a stream and returns its context, cancelled once the maximum
+// duration passes, and a function to call when the stream ends. A nil watchdog
+// returns ctx unchanged.
+func (w *StreamWatchdog) track(ctx context.Context) (context.Context, func()) {
+	if w == nil {
+		return ctx, func() {}
+	}
+
+	var cancel context.CancelFunc
+	if w.maxDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, w.maxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	w.mu.Lock()
+	id := w.nextID
+	w.nextID++
+	w.open[id] = cancel
+	observability.ActiveStreams.Set(float64(len(w.open)))
+	w.mu.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				observability.StreamTimeouts.Inc()
+				observability.FromContext(ctx).Warn("stream cancelled after maximum duration",
+					observability.Duration("max_duration", w.maxDuration),
+				)
+			}
+			cancel()
+
+			w.mu.Lock()
+			delete(w.open, id)
+			observability.ActiveStreams.Set(float64(len(w.open)))
+			w.mu.Unlock()
+		})
+	}
+}
+
+// watchStream forwards the chunks of a stream tracked under streamCtx and calls
+// release once the stream ends. A stream cut short by the maximum duration ends
+// with an ErrStreamTimeout chunk, delivered while the client request (ctx) lasts.
+func watchStream(ctx, streamCtx context.Context, in <-chan StreamChunk, release func()) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		ended := forwardUntilDone(streamCtx, in, out)
+		release()
+
+		if ended || !errors.Is(streamCtx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		select {
+		case out <- StreamChunk{Delta: "", Done: false, Error: ErrStreamTimeout, ProviderHeaders: nil, Metadata: nil}:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// forwardUntilDone forwards chunks from in to out until in closes or ctx is
+// done, and reports whether the stream ended with a done or error chunk.
+func forwardUntilDone(ctx context.Context, in <-chan StreamChunk, out chan<- StreamChunk) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case chunk, ok := <-in:
+			if !ok {
+				return false
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return false
+			}
+			if chunk.Done || chunk.Error != nil {
+				return true
+			}
+		}
+	}
+}
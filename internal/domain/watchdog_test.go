@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+)
+
+func TestStreamWatchdog(t *testing.T) {
+	newGateway := func(
+		t *testing.T,
+		chunks <-chan domain.StreamChunk,
+		watchdog *domain.StreamWatchdog,
+	) *domain.GatewayService {
+		t.Helper()
+
+		mockRegistry := mocks.NewMockProviderRegistry(t)
+		mockProvider := mocks.NewMockProvider(t)
+		mockRegistry.EXPECT().GetByModel(mock.Anything, "gpt-4").Return(mockProvider, nil)
+		mockProvider.EXPECT().Name().Return("openai")
+		mockProvider.EXPECT().Stream(mock.Anything, mock.Anything).Return(chunks, nil)
+
+		mockCostCalc := mocks.NewMockCostCalculator(t)
+		return domain.NewGatewayService(mockRegistry, mockCostCalc, domain.WithStreamWatchdog(watchdog))
+	}
+	request := func() *domain.CompletionRequest {
+		return &domain.CompletionRequest{
+			Model:    "gpt-4",
+			Messages: []domain.Message{{Role: "user", Content: "Hello"}},
+			Stream:   true,
+		}
+	}
+
+	t.Run("should track a stream until it ends", func(t *testing.T) {
+		upstream := make(chan domain.StreamChunk, 2)
+		watchdog := domain.NewStreamWatchdog(time.Minute)
+		gateway := newGateway(t, upstream, watchdog)
+
+		chunks, err := gateway.StreamByModel(context.Background(), request())
+		require.NoError(t, err)
+		require.Equal(t, 1, watchdog.Active())
+
+		upstream <- domain.StreamChunk{Delta: "Hi"}
+		upstream <- domain.StreamChunk{Done: true}
+		close(upstream)
+		for range chunks {
+			continue
+		}
+
+		require.Eventually(t, func() bool { return watchdog.Active() == 0 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should cancel a stream whose consumer stops reading", func(t *testing.T) {
+		upstream := make(chan domain.StreamChunk, 2)
+		upstream <- domain.StreamChunk{Delta: "Hi"}
+		upstream <- domain.StreamChunk{Delta: " there"}
+		watchdog := domain.NewStreamWatchdog(50 * time.Millisecond)
+		gateway := newGateway(t, upstream, watchdog)
+
+		chunks, err := gateway.StreamByModel(context.Background(), request())
+		require.NoError(t, err)
+		<-chunks
+
+		// Nobody reads the second chunk and the provider never finishes.
+		require.Eventually(t, func() bool { return watchdog.Active() == 0 }, time.Second, 5*time.Millisecond)
+		for range chunks {
+			continue
+		}
+	})
+
+	t.Run("should end a timed out stream with a timeout error", func(t *testing.T) {
+		upstream := make(chan domain.StreamChunk, 1)
+		upstream <- domain.StreamChunk{Delta: "Hi"}
+		watchdog := domain.NewStreamWatchdog(50 * time.Millisecond)
+		gateway := newGateway(t, upstream, watchdog)
+
+		chunks, err := gateway.StreamByModel(context.Background(), request())
+		require.NoError(t, err)
+
+		// The provider never finishes, so the stream ends when the watchdog fires.
+		var last domain.StreamChunk
+		for chunk := range chunks {
+			last = chunk
+		}
+
+		require.ErrorIs(t, last.Error, domain.ErrStreamTimeout)
+	})
+
+	t.Run("should cancel open streams on demand", func(t *testing.T) {
+		upstream := make(chan domain.StreamChunk)
+		watchdog := domain.NewStreamWatchdog(0)
+		gateway := newGateway(t, upstream, watchdog)
+
+		chunks, err := gateway.StreamByModel(context.Background(), request())
+		require.NoError(t, err)
+		require.Equal(t, 1, watchdog.Active())
+
+		watchdog.CancelAll()
+
+		for range chunks {
+			continue
+		}
+		require.Eventually(t, func() bool { return watchdog.Active() == 0 }, time.Second, 5*time.Millisecond)
+	})
+}
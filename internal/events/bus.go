@@ -0,0 +1,176 @@
+// Package events delivers gateway telemetry events to external sinks.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/webhook"
+)
+
+const (
+	// SinkWebhook posts each event as JSON to a URL.
+	SinkWebhook = "webhook"
+
+	// SinkStdout writes each event as a JSON line to standard output.
+	SinkStdout = "stdout"
+
+	// drainTimeout bounds how long shutdown waits for queued events to be delivered.
+	drainTimeout = 5 * time.Second
+)
+
+// Sink delivers events to one destination.
+type Sink interface {
+	// Name identifies the sink in metrics and logs.
+	Name() string
+	// Send delivers one event.
+	Send(ctx context.Context, event domain.Event) error
+}
+
+// Bus implements domain.EventPublisher with a bounded queue drained in the
+// background, so slow sinks never delay requests. Events that do not fit the
+// queue are dropped and counted.
+type Bus struct {
+	sinks   []Sink
+	queue   chan domain.Event
+	stopped chan struct{}
+}
+
+// NewBus creates an event bus delivering to sinks.
+func NewBus(queueSize int, sinks ...Sink) *Bus {
+	return &Bus{
+		sinks:   sinks,
+		queue:   make(chan domain.Event, max(queueSize, 1)),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Publish queues an event for delivery without blocking.
+func (b *Bus) Publish(_ context.Context, event domain.Event) {
+	select {
+	case b.queue <- event:
+	default:
+		observability.EventDeliveries.WithLabelValues("bus", "dropped").Inc()
+	}
+}
+
+// Run delivers queued events to every sink until ctx is done. It then drains
+// what is still queued, within drainTimeout, and closes sinks that hold
+// connections. Events the deadline leaves undelivered are counted as dropped.
+func (b *Bus) Run(ctx context.Context) {
+	defer close(b.stopped)
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain(context.WithoutCancel(ctx))
+			b.close()
+			return
+		case event := <-b.queue:
+			b.deliver(ctx, event)
+		}
+	}
+}
+
+// Stopped is closed once Run has drained the queue and closed the sinks.
+func (b *Bus) Stopped() <-chan struct{} {
+	return b.stopped
+}
+
+func (b *Bus) drain(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-b.queue:
+			if ctx.Err() != nil {
+				observability.EventDeliveries.WithLabelValues("bus", "dropped").Inc()
+				continue
+			}
+			b.deliver(ctx, event)
+		default:
+			return
+		}
+	}
+}
+
+func (b *Bus) close() {
+	for _, sink := range b.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+}
+
+func (b *Bus) deliver(ctx context.Context, event domain.Event) {
+	for _, sink := range b.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			observability.EventDeliveries.WithLabelValues(sink.Name(), "error").Inc()
+			observability.FromContext(ctx).Error("failed to deliver event",
+				observability.String("sink", sink.Name()),
+				observability.String("type", event.Type),
+				observability.Error(err),
+			)
+			continue
+		}
+		observability.EventDeliveries.WithLabelValues(sink.Name(), "success").Inc()
+	}
+}
+
+// WebhookSink posts events as JSON, retrying transient failures.
+type WebhookSink struct {
+	client *webhook.Client
+}
+
+// NewWebhookSink creates a webhook event sink.
+func NewWebhookSink(url string, timeout time.Duration, maxAttempts int, backoff time.Duration) *WebhookSink {
+	return &WebhookSink{client: webhook.NewClient(url, timeout, maxAttempts, backoff)}
+}
+
+// Name identifies the sink.
+func (s *WebhookSink) Name() string {
+	return SinkWebhook
+}
+
+// Send posts one event.
+func (s *WebhookSink) Send(ctx context.Context, event domain.Event) error {
+	return s.client.Post(ctx, event) //nolint:wrapcheck // Errors already describe the delivery
+}
+
+// WriterSink writes events as JSON lines, e.g. to stdout for a log shipper.
+type WriterSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink creates a sink writing JSON lines to writer.
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{mu: sync.Mutex{}, writer: writer}
+}
+
+// Name identifies the sink.
+func (s *WriterSink) Name() string {
+	return SinkStdout
+}
+
+// Send writes one event as a JSON line.
+func (s *WriterSink) Send(_ context.Context, event domain.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err = s.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
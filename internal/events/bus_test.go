@@ -0,0 +1,113 @@
+package events_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/events"
+)
+
+func TestBus(t *testing.T) {
+	t.Run("should deliver published events to every sink", func(t *testing.T) {
+		first := &syncBuffer{}
+		second := &syncBuffer{}
+		bus := events.NewBus(8, events.NewWriterSink(first), events.NewWriterSink(second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go bus.Run(ctx)
+
+		bus.Publish(ctx, domain.Event{Type: domain.EventRequestStarted, RequestID: "req-1"})
+
+		for _, buffer := range []*syncBuffer{first, second} {
+			require.Eventually(t, func() bool { return buffer.Len() > 0 }, time.Second, 5*time.Millisecond)
+
+			var event domain.Event
+			require.NoError(t, json.Unmarshal(buffer.Bytes(), &event))
+			require.Equal(t, domain.EventRequestStarted, event.Type)
+			require.Equal(t, "req-1", event.RequestID)
+		}
+	})
+
+	t.Run("should drop events instead of blocking when the queue is full", func(t *testing.T) {
+		buffer := &syncBuffer{}
+		bus := events.NewBus(1, events.NewWriterSink(buffer))
+
+		for range 10 {
+			bus.Publish(context.Background(), domain.Event{Type: domain.EventRequestStarted})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go bus.Run(ctx)
+
+		require.Eventually(t, func() bool { return buffer.Len() > 0 }, time.Second, 5*time.Millisecond)
+		require.Equal(t, 1, bytes.Count(buffer.Bytes(), []byte("\n")))
+	})
+
+	t.Run("should deliver events still queued when ctx is cancelled", func(t *testing.T) {
+		buffer := &syncBuffer{}
+		bus := events.NewBus(8, events.NewWriterSink(buffer))
+
+		for range 5 {
+			bus.Publish(context.Background(), domain.Event{Type: domain.EventRequestStarted})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		bus.Run(ctx)
+
+		select {
+		case <-bus.Stopped():
+		default:
+			t.Fatal("expected the bus to report it stopped")
+		}
+		require.Equal(t, 5, bytes.Count(buffer.Bytes(), []byte("\n")))
+	})
+}
+
+func TestWriterSink_Send(t *testing.T) {
+	t.Run("should write one JSON line per event", func(t *testing.T) {
+		var buffer bytes.Buffer
+		sink := events.NewWriterSink(&buffer)
+
+		require.NoError(t, sink.Send(context.Background(), domain.Event{Type: domain.EventCacheHit}))
+		require.NoError(t, sink.Send(context.Background(), domain.Event{Type: domain.EventCacheMiss}))
+
+		lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		require.Contains(t, string(lines[0]), `"type":"cache.hit"`)
+		require.Contains(t, string(lines[1]), `"type":"cache.miss"`)
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the bus writes to it.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(data)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Len()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buffer.Bytes())
+}
@@ -0,0 +1,224 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/connretry"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+const (
+	// SinkNATS publishes each event to a NATS subject.
+	SinkNATS = "nats"
+
+	// natsDefaultPort is used when the server URL has no port.
+	natsDefaultPort = "4222"
+
+	// natsClientName identifies the gateway's connection on the NATS server.
+	natsClientName = "calcifer"
+)
+
+// errNATSServer reports an -ERR reply from the NATS server.
+var errNATSServer = errors.New("nats server error")
+
+// natsConnectOptions is the CONNECT payload of the NATS client protocol.
+type natsConnectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// NATSSink publishes events as JSON to a NATS subject using the core NATS text
+// protocol over plain TCP. Each publish is followed by a PING and waits for the
+// server's PONG, so a nil error means the server accepted the message. A broken
+// connection is redialed once per event.
+type NATSSink struct {
+	address string
+	subject string
+	timeout time.Duration
+	connect natsConnectOptions
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSSink creates a NATS event sink for a nats://[user:pass@]host[:port] URL.
+func NewNATSSink(serverURL, subject string, timeout time.Duration) (*NATSSink, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if parsed.Scheme != "nats" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q: expected nats://host[:port]", serverURL)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid NATS subject %q", subject)
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	password, _ := parsed.User.Password()
+
+	return &NATSSink{
+		address: net.JoinHostPort(parsed.Hostname(), port),
+		subject: subject,
+		timeout: timeout,
+		connect: natsConnectOptions{
+			Verbose:  false,
+			Pedantic: false,
+			Name:     natsClientName,
+			Lang:     "go",
+			User:     parsed.User.Username(),
+			Pass:     password,
+		},
+		mu:     sync.Mutex{},
+		conn:   nil,
+		reader: nil,
+	}, nil
+}
+
+// Name identifies the sink.
+func (s *NATSSink) Name() string {
+	return SinkNATS
+}
+
+// Send publishes one event.
+func (s *NATSSink) Send(ctx context.Context, event domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return connretry.Stale(s.conn != nil, errNATSServer, func() error { return s.publish(ctx, payload) })
+}
+
+// Close closes the connection to the server, if any.
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.reader = nil, nil
+	return err //nolint:wrapcheck // Transparent connection close
+}
+
+// publish sends payload and waits for the server to acknowledge it, dropping the
+// connection on any failure. Caller must hold the lock.
+func (s *NATSSink) publish(ctx context.Context, payload []byte) error {
+	if err := s.dial(ctx); err != nil {
+		return err
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		s.drop()
+		return fmt.Errorf("failed to set NATS deadline: %w", err)
+	}
+
+	frame := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", s.subject, len(payload), payload)
+	if _, err := s.conn.Write([]byte(frame)); err != nil {
+		s.drop()
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+
+	if err := s.awaitPong(); err != nil {
+		s.drop()
+		return err
+	}
+	return nil
+}
+
+// dial connects and performs the protocol handshake unless already connected.
+// Caller must hold the lock.
+func (s *NATSSink) dial(ctx context.Context) error {
+	if s.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: s.timeout} //nolint:exhaustruct // Only the timeout matters
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	if err = s.handshake(); err != nil {
+		s.drop()
+		return err
+	}
+	return nil
+}
+
+// handshake reads the server INFO and sends CONNECT. Caller must hold the lock.
+func (s *NATSSink) handshake() error {
+	if err := s.conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return fmt.Errorf("failed to set NATS deadline: %w", err)
+	}
+
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read NATS server info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	options, err := json.Marshal(s.connect)
+	if err != nil {
+		return fmt.Errorf("failed to encode NATS connect options: %w", err)
+	}
+	if _, err = fmt.Fprintf(s.conn, "CONNECT %s\r\n", options); err != nil {
+		return fmt.Errorf("failed to send NATS connect: %w", err)
+	}
+	return nil
+}
+
+// awaitPong reads server messages until the PONG answering our PING, replying to
+// server PINGs along the way. Caller must hold the lock.
+func (s *NATSSink) awaitPong() error {
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read NATS reply: %w", err)
+		}
+
+		switch line = strings.TrimSpace(line); {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err = s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to answer NATS ping: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("%w: %s", errNATSServer, strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// drop closes and forgets the connection. Caller must hold the lock.
+func (s *NATSSink) drop() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+	s.conn, s.reader = nil, nil
+}
@@ -0,0 +1,173 @@
+package events_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/events"
+)
+
+// fakeNATS is a minimal NATS server that records published messages.
+type fakeNATS struct {
+	listener  net.Listener
+	published chan string
+	connects  chan string
+	reply     string // sent in answer to PUB instead of nothing, e.g. "-ERR 'Permissions Violation'"
+}
+
+func newFakeNATS(t *testing.T, reply string) *fakeNATS {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeNATS{
+		listener:  listener,
+		published: make(chan string, 16),
+		connects:  make(chan string, 16),
+		reply:     reply,
+	}
+	go server.serve()
+	return server
+}
+
+func (f *fakeNATS) url(userinfo string) string {
+	return "nats://" + userinfo + f.listener.Addr().String()
+}
+
+func (f *fakeNATS) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeNATS) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_, _ = fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			f.connects <- strings.TrimPrefix(line, "CONNECT ")
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var size int
+			_, _ = fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+			payload := make([]byte, size+2)
+			if _, err = io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if f.reply != "" {
+				_, _ = fmt.Fprintf(conn, "%s\r\n", f.reply)
+				continue
+			}
+			f.published <- subject + " " + string(payload[:size])
+		case line == "PING":
+			_, _ = fmt.Fprint(conn, "PING\r\nPONG\r\n")
+		}
+	}
+}
+
+func TestNATSSink_Send(t *testing.T) {
+	t.Run("should publish the event as JSON to the subject", func(t *testing.T) {
+		server := newFakeNATS(t, "")
+		sink, err := events.NewNATSSink(server.url("svc:secret@"), "gateway.events", time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sink.Close() })
+
+		err = sink.Send(context.Background(), domain.Event{Type: domain.EventRequestCompleted, RequestID: "req-1"})
+		require.NoError(t, err)
+
+		connect := <-server.connects
+		require.Contains(t, connect, `"user":"svc"`)
+		require.Contains(t, connect, `"pass":"secret"`)
+
+		message := <-server.published
+		subject, payload, _ := strings.Cut(message, " ")
+		require.Equal(t, "gateway.events", subject)
+
+		var event domain.Event
+		require.NoError(t, json.Unmarshal([]byte(payload), &event))
+		require.Equal(t, domain.EventRequestCompleted, event.Type)
+		require.Equal(t, "req-1", event.RequestID)
+	})
+
+	t.Run("should reuse the connection across events", func(t *testing.T) {
+		server := newFakeNATS(t, "")
+		sink, err := events.NewNATSSink(server.url(""), "gateway.events", time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sink.Close() })
+
+		for range 3 {
+			require.NoError(t, sink.Send(context.Background(), domain.Event{Type: domain.EventRequestStarted}))
+		}
+
+		require.Len(t, server.connects, 1)
+		require.Len(t, server.published, 3)
+	})
+
+	t.Run("should return server errors", func(t *testing.T) {
+		server := newFakeNATS(t, "-ERR 'Permissions Violation for Publish'")
+		sink, err := events.NewNATSSink(server.url(""), "gateway.events", time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sink.Close() })
+
+		err = sink.Send(context.Background(), domain.Event{Type: domain.EventRequestStarted})
+		require.ErrorContains(t, err, "Permissions Violation")
+	})
+
+	t.Run("should fail when the server is unreachable", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		sink, err := events.NewNATSSink("nats://"+address, "gateway.events", time.Second)
+		require.NoError(t, err)
+
+		err = sink.Send(context.Background(), domain.Event{Type: domain.EventRequestStarted})
+		require.ErrorContains(t, err, "failed to connect to NATS")
+	})
+}
+
+func TestNewNATSSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		subject string
+	}{
+		{name: "wrong scheme", url: "http://localhost:4222", subject: "events"},
+		{name: "missing host", url: "nats://", subject: "events"},
+		{name: "empty subject", url: "nats://localhost", subject: ""},
+		{name: "subject with spaces", url: "nats://localhost", subject: "gateway events"},
+	}
+
+	for _, tt := range tests {
+		t.Run("should reject "+tt.name, func(t *testing.T) {
+			_, err := events.NewNATSSink(tt.url, tt.subject, time.Second)
+			require.Error(t, err)
+		})
+	}
+}
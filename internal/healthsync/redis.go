@@ -0,0 +1,341 @@
+// Package healthsync shares provider health transitions between gateway replicas
+// over Redis pub/sub.
+package healthsync
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/connretry"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// redisDefaultPort is used when the server URL has no port.
+	redisDefaultPort = "6379"
+
+	// replicaSuffixBytes is the entropy appended to the host name in replica IDs.
+	replicaSuffixBytes = 4
+
+	// maxResubscribeDelay caps the backoff between subscription attempts.
+	maxResubscribeDelay = 30 * time.Second
+)
+
+// errRedisServer reports an error reply from the Redis server.
+var errRedisServer = errors.New("redis server error")
+
+// message is the payload published for each transition. Replica identifies the
+// publisher so replicas ignore their own messages.
+type message struct {
+	domain.HealthUpdate
+
+	Replica string `json:"replica"`
+}
+
+// Redis implements domain.HealthPeers with Redis PUBLISH and SUBSCRIBE, speaking
+// RESP over plain TCP. Publishes share one connection, redialed once when broken;
+// the subscription holds its own and resubscribes with backoff when it drops.
+// The subscription PINGs the server every timeout, so a dead connection is noticed
+// within twice that.
+type Redis struct {
+	address  string
+	username string
+	password string
+	channel  string
+	timeout  time.Duration
+	replica  string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis creates Redis health peers for a redis://[[user]:pass@]host[:port] URL.
+func NewRedis(serverURL, channel string, timeout time.Duration) (*Redis, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if parsed.Scheme != "redis" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q: expected redis://host[:port]", serverURL)
+	}
+	if channel == "" {
+		return nil, errors.New("health sync channel is required")
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = redisDefaultPort
+	}
+	password, _ := parsed.User.Password()
+
+	return &Redis{
+		address:  net.JoinHostPort(parsed.Hostname(), port),
+		username: parsed.User.Username(),
+		password: password,
+		channel:  channel,
+		timeout:  timeout,
+		replica:  replicaID(),
+		mu:       sync.Mutex{},
+		conn:     nil,
+		reader:   nil,
+	}, nil
+}
+
+// Publish announces a transition to the other replicas.
+func (r *Redis) Publish(ctx context.Context, update domain.HealthUpdate) error {
+	payload, err := json.Marshal(message{HealthUpdate: update, Replica: r.replica})
+	if err != nil {
+		return fmt.Errorf("failed to encode health update: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return connretry.Stale(r.conn != nil, errRedisServer, func() error { return r.publish(ctx, payload) })
+}
+
+// Subscribe calls handle with the transitions of other replicas until ctx is done.
+func (r *Redis) Subscribe(ctx context.Context, handle func(domain.HealthUpdate)) {
+	logger := observability.FromContext(ctx)
+	delay := time.Second
+
+	for {
+		err := r.subscribe(ctx, handle, func() { delay = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("provider health subscription lost, health is local until it resumes",
+			observability.Error(err), observability.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxResubscribeDelay)
+	}
+}
+
+// Close closes the publishing connection, if any.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn, r.reader = nil, nil
+	return err //nolint:wrapcheck // Transparent connection close
+}
+
+// publish sends payload, dropping the connection on any failure. Caller must hold the lock.
+func (r *Redis) publish(ctx context.Context, payload []byte) error {
+	if r.conn == nil {
+		conn, reader, err := r.dial(ctx)
+		if err != nil {
+			return err
+		}
+		r.conn, r.reader = conn, reader
+	}
+
+	if _, err := r.command(r.conn, r.reader, "PUBLISH", r.channel, string(payload)); err != nil {
+		_ = r.conn.Close()
+		r.conn, r.reader = nil, nil
+		return err
+	}
+	return nil
+}
+
+// subscribe runs one subscription until it fails or ctx is done. subscribed is
+// called once the server confirms the subscription.
+func (r *Redis) subscribe(ctx context.Context, handle func(domain.HealthUpdate), subscribed func()) error {
+	conn, reader, err := r.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = r.command(conn, reader, "SUBSCRIBE", r.channel); err != nil {
+		return err
+	}
+	subscribed()
+
+	// Closing the connection unblocks the read loop when ctx is done or a ping fails.
+	done := make(chan struct{})
+	defer close(done)
+	go r.keepAlive(ctx, conn, done)
+
+	for {
+		if err = conn.SetReadDeadline(time.Now().Add(2 * r.timeout)); err != nil {
+			return fmt.Errorf("failed to set Redis deadline: %w", err)
+		}
+		reply, readErr := readReply(reader)
+		if readErr != nil {
+			return readErr
+		}
+
+		push, ok := reply.([]any)
+		if !ok || len(push) != 3 || push[0] != "message" {
+			continue // subscription confirmations and pongs
+		}
+		payload, _ := push[2].(string)
+
+		var received message
+		if json.Unmarshal([]byte(payload), &received) != nil || received.Replica == r.replica {
+			continue
+		}
+		handle(received.HealthUpdate)
+	}
+}
+
+// keepAlive pings the server every timeout, closing conn when ctx is done or a
+// ping cannot be written.
+func (r *Redis) keepAlive(ctx context.Context, conn net.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(r.timeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.SetWriteDeadline(time.Now().Add(r.timeout)); err != nil {
+				_ = conn.Close()
+				return
+			}
+			if _, err := conn.Write(encodeCommand("PING")); err != nil {
+				_ = conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// dial connects and authenticates.
+func (r *Redis) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{Timeout: r.timeout} //nolint:exhaustruct // Only the timeout matters
+	conn, err := dialer.DialContext(ctx, "tcp", r.address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err = r.command(conn, reader, args...); err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+	}
+	return conn, reader, nil
+}
+
+// command sends a command and reads its reply within the timeout.
+func (r *Redis) command(conn net.Conn, reader *bufio.Reader, args ...string) (any, error) {
+	if err := conn.SetDeadline(time.Now().Add(r.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set Redis deadline: %w", err)
+	}
+	if _, err := conn.Write(encodeCommand(args...)); err != nil {
+		return nil, fmt.Errorf("failed to send Redis %s: %w", args[0], err)
+	}
+	return readReply(reader)
+}
+
+// encodeCommand encodes a command as a RESP array of bulk strings.
+func encodeCommand(args ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+// readReply reads one RESP reply: a string, an integer, nil, or an array of them.
+// Error replies are returned as errRedisServer errors.
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty Redis reply")
+	}
+
+	kind, body := line[0], line[1:]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, fmt.Errorf("%w: %s", errRedisServer, body)
+	case ':':
+		return parseInt(body)
+	case '$':
+		size, sizeErr := parseInt(body)
+		if sizeErr != nil || size < 0 {
+			return nil, sizeErr
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read Redis reply: %w", err)
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, countErr := parseInt(body)
+		if countErr != nil || count < 0 {
+			return nil, countErr
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
+
+func parseInt(value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Redis integer %q: %w", value, err)
+	}
+	return n, nil
+}
+
+// replicaID names this replica after its host, made unique by a random suffix.
+func replicaID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "calcifer"
+	}
+
+	suffix := make([]byte, replicaSuffixBytes)
+	_, _ = rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,231 @@
+package healthsync_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/healthsync"
+)
+
+// fakeRedis is a minimal Redis server supporting AUTH, PUBLISH, SUBSCRIBE, and PING.
+type fakeRedis struct {
+	listener net.Listener
+	auths    chan string
+	reply    string // sent in answer to PUBLISH instead of the subscriber count, e.g. "-NOPERM"
+
+	mu          sync.Mutex
+	subscribers map[net.Conn]bool
+}
+
+func newFakeRedis(t *testing.T, reply string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeRedis{
+		listener:    listener,
+		auths:       make(chan string, 16),
+		reply:       reply,
+		mu:          sync.Mutex{},
+		subscribers: make(map[net.Conn]bool),
+	}
+	go server.serve()
+	return server
+}
+
+func (f *fakeRedis) url(userinfo string) string {
+	return "redis://" + userinfo + f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer f.drop(conn)
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			f.auths <- strings.Join(args[1:], " ")
+			_, _ = fmt.Fprint(conn, "+OK\r\n")
+		case "SUBSCRIBE":
+			f.mu.Lock()
+			f.subscribers[conn] = true
+			f.mu.Unlock()
+			_, _ = fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n%s:1\r\n", bulk(args[1]))
+		case "PUBLISH":
+			if f.reply != "" {
+				_, _ = fmt.Fprintf(conn, "%s\r\n", f.reply)
+				continue
+			}
+			_, _ = fmt.Fprintf(conn, ":%d\r\n", f.broadcast(args[1], args[2]))
+		case "PING":
+			_, _ = fmt.Fprint(conn, "*2\r\n$4\r\npong\r\n$0\r\n\r\n")
+		}
+	}
+}
+
+func (f *fakeRedis) broadcast(channel, payload string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for conn := range f.subscribers {
+		_, _ = fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n%s%s", bulk(channel), bulk(payload))
+	}
+	return len(f.subscribers)
+}
+
+// subscriberCount returns the number of subscribed connections.
+func (f *fakeRedis) subscriberCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers)
+}
+
+// disconnectSubscribers closes every subscribed connection.
+func (f *fakeRedis) disconnectSubscribers() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for conn := range f.subscribers {
+		_ = conn.Close()
+		delete(f.subscribers, conn)
+	}
+}
+
+func (f *fakeRedis) drop(conn net.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.subscribers, conn)
+	_ = conn.Close()
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// readCommand reads a RESP array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, sizeErr := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// subscribe starts a subscription collecting received updates.
+func subscribe(t *testing.T, server *fakeRedis, peers *healthsync.Redis) <-chan domain.HealthUpdate {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	received := make(chan domain.HealthUpdate, 16)
+	go peers.Subscribe(ctx, func(update domain.HealthUpdate) { received <- update })
+	require.Eventually(t, func() bool { return server.subscriberCount() == 1 }, time.Second, 5*time.Millisecond)
+	return received
+}
+
+func TestRedis(t *testing.T) {
+	newPeers := func(t *testing.T, url string) *healthsync.Redis {
+		peers, err := healthsync.NewRedis(url, "calcifer:provider-health", time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = peers.Close() })
+		return peers
+	}
+
+	t.Run("should deliver transitions to other replicas but not back to the publisher", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		local := newPeers(t, server.url(""))
+		remote := newPeers(t, server.url(""))
+		received := subscribe(t, server, local)
+
+		ctx := context.Background()
+		require.NoError(t, local.Publish(ctx, domain.HealthUpdate{Provider: "own", Healthy: false}))
+		require.NoError(t, remote.Publish(ctx, domain.HealthUpdate{Provider: "openai", Healthy: false}))
+
+		require.Equal(t, domain.HealthUpdate{Provider: "openai", Healthy: false}, <-received)
+		require.Empty(t, received)
+	})
+
+	t.Run("should authenticate with the URL credentials", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		peers := newPeers(t, server.url("svc:secret@"))
+
+		require.NoError(t, peers.Publish(context.Background(), domain.HealthUpdate{Provider: "openai", Healthy: true}))
+		require.Equal(t, "svc secret", <-server.auths)
+	})
+
+	t.Run("should return server errors", func(t *testing.T) {
+		server := newFakeRedis(t, "-NOPERM this user has no permissions to access the channel")
+		peers := newPeers(t, server.url(""))
+
+		err := peers.Publish(context.Background(), domain.HealthUpdate{Provider: "openai", Healthy: true})
+		require.ErrorContains(t, err, "NOPERM")
+	})
+
+	t.Run("should resubscribe after losing the connection", func(t *testing.T) {
+		server := newFakeRedis(t, "")
+		local := newPeers(t, server.url(""))
+		remote := newPeers(t, server.url(""))
+		received := subscribe(t, server, local)
+
+		server.disconnectSubscribers()
+		require.Eventually(t, func() bool { return server.subscriberCount() == 1 }, 3*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, remote.Publish(context.Background(), domain.HealthUpdate{Provider: "openai", Healthy: true}))
+		require.Equal(t, domain.HealthUpdate{Provider: "openai", Healthy: true}, <-received)
+	})
+
+	t.Run("should reject URLs that are not redis://", func(t *testing.T) {
+		_, err := healthsync.NewRedis("http://localhost:6379", "calcifer:provider-health", time.Second)
+		require.Error(t, err)
+	})
+}
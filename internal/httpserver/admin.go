@@ -0,0 +1,628 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/billing"
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// maxAdminBodyBytes bounds admin request bodies.
+	maxAdminBodyBytes = 64 << 10
+
+	// dashboardWindow is the default usage window of the dashboard.
+	dashboardWindow = 24 * time.Hour
+
+	// dashboardRecentRequests is the number of recent requests on the dashboard.
+	dashboardRecentRequests = 20
+)
+
+// AdminHandler serves operational endpoints under /admin.
+// Every endpoint requires the configured admin bearer token.
+type AdminHandler struct {
+	registry  domain.ProviderRegistry
+	providers *domain.ProviderManager
+	health    *domain.HealthMonitor
+	gateway   *domain.GatewayService
+	load      *domain.LoadTracker
+	quotas    *domain.QuotaManager
+	keys      *domain.VirtualKeys
+	overrides *domain.Overrides
+	startup   *domain.StartupReport
+	token     string
+}
+
+// NewAdminHandler creates a new admin handler (DI constructor).
+func NewAdminHandler(
+	registry domain.ProviderRegistry,
+	providers *domain.ProviderManager,
+	health *domain.HealthMonitor,
+	gateway *domain.GatewayService,
+	load *domain.LoadTracker,
+	quotas *domain.QuotaManager,
+	keys *domain.VirtualKeys,
+	overrides *domain.Overrides,
+	startup *domain.StartupReport,
+	cfg *config.AdminConfig,
+) *AdminHandler {
+	return &AdminHandler{
+		registry:  registry,
+		providers: providers,
+		health:    health,
+		gateway:   gateway,
+		load:      load,
+		quotas:    quotas,
+		keys:      keys,
+		overrides: overrides,
+		startup:   startup,
+		token:     cfg.Token,
+	}
+}
+
+// RegisterRoutes adds the admin endpoints to mux. The dashboard UI is static and
+// served without the token; it asks the operator for it to call the admin API.
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/ui/", dashboardUI())
+	mux.HandleFunc("GET /admin/status", h.authorize(h.HandleStatus))
+	mux.HandleFunc("GET /admin/dashboard", h.authorize(h.HandleDashboard))
+	mux.HandleFunc("GET /admin/credentials", h.authorize(h.HandleCredentials))
+	mux.HandleFunc("GET /admin/tenants/load", h.authorize(h.HandleTenantLoad))
+	mux.HandleFunc("GET /admin/providers", h.authorize(h.HandleProviders))
+	mux.HandleFunc("POST /admin/providers/{name}/disable", h.authorize(h.HandleDisableProvider))
+	mux.HandleFunc("POST /admin/providers/{name}/enable", h.authorize(h.HandleEnableProvider))
+	mux.HandleFunc("GET /admin/quotas", h.authorize(h.HandleListQuotas))
+	mux.HandleFunc("GET /admin/quotas/{key}", h.authorize(h.HandleGetQuota))
+	mux.HandleFunc("PUT /admin/quotas/{key}", h.authorize(h.HandleSetQuota))
+	mux.HandleFunc("DELETE /admin/quotas/{key}", h.authorize(h.HandleDeleteQuota))
+	mux.HandleFunc("GET /admin/keys", h.authorize(h.requireVirtualKeys(h.HandleListKeys)))
+	mux.HandleFunc("POST /admin/keys", h.authorize(h.requireVirtualKeys(h.HandleIssueKey)))
+	mux.HandleFunc("DELETE /admin/keys/{name}", h.authorize(h.requireVirtualKeys(h.HandleRevokeKey)))
+	mux.HandleFunc("GET /admin/overrides", h.authorize(h.HandleListOverrides))
+	mux.HandleFunc("PUT /admin/overrides/pricing/{model}", h.authorize(h.HandleSetPricing))
+	mux.HandleFunc("DELETE /admin/overrides/pricing/{model}", h.authorize(h.HandleDeletePricing))
+	mux.HandleFunc("PUT /admin/overrides/aliases/{alias}", h.authorize(h.HandleSetAlias))
+	mux.HandleFunc("DELETE /admin/overrides/aliases/{alias}", h.authorize(h.HandleDeleteAlias))
+	mux.HandleFunc("PUT /admin/overrides/policies/{name}", h.authorize(h.HandleSetKeyPolicy))
+	mux.HandleFunc("DELETE /admin/overrides/policies/{name}", h.authorize(h.HandleDeleteKeyPolicy))
+	mux.HandleFunc("GET /admin/billing/export", h.authorize(h.HandleBillingExport))
+}
+
+// HandleStatus reports the startup report: which providers registered or were
+// skipped and why, and the effective routing and caching settings.
+func (h *AdminHandler) HandleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.startup.Status())
+}
+
+// HandleDashboard reports provider health, in-flight load, and a summary of the
+// usage recorded within the window query parameter (default 24h).
+func (h *AdminHandler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	window := dashboardWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "window must be a positive duration, e.g. 1h", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+
+	names, err := h.registry.List(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(names)
+
+	unhealthy := h.health.Unhealthy()
+	providers := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, map[string]any{
+			"name":    name,
+			"enabled": true,
+			"healthy": !slices.Contains(unhealthy, name),
+		})
+	}
+	for _, name := range h.providers.Disabled() {
+		providers = append(providers, map[string]any{"name": name, "enabled": false, "healthy": false})
+	}
+
+	var summary *domain.UsageSummary
+	filter := domain.UsageFilter{From: time.Now().Add(-window), To: time.Time{}, ClientKey: ""}
+	switch usage, usageErr := h.gateway.UsageSummary(ctx, filter, dashboardRecentRequests); {
+	case usageErr == nil:
+		summary = &usage
+	case !errors.Is(usageErr, domain.ErrUsageUnavailable):
+		http.Error(w, usageErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"providers": providers,
+		"tenants":   h.load.Snapshot(),
+		"window":    window.String(),
+		"usage":     summary,
+	})
+}
+
+// HandleTenantLoad reports in-flight and queued requests per active tenant.
+func (h *AdminHandler) HandleTenantLoad(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenants": h.load.Snapshot(),
+	})
+}
+
+// HandleCredentials reports the redacted health of rotated provider credentials.
+func (h *AdminHandler) HandleCredentials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	names, err := h.registry.List(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(names)
+
+	credentials := make(map[string][]domain.CredentialStatus, len(names))
+	for _, name := range names {
+		provider, getErr := h.registry.Get(ctx, name)
+		if getErr != nil {
+			continue
+		}
+
+		if reporter, ok := provider.(domain.CredentialReporter); ok {
+			credentials[name] = reporter.CredentialHealth(ctx)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"providers": credentials,
+	})
+}
+
+// HandleProviders lists routing and disabled providers.
+func (h *AdminHandler) HandleProviders(w http.ResponseWriter, r *http.Request) {
+	names, err := h.registry.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(names)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":  names,
+		"disabled": h.providers.Disabled(),
+	})
+}
+
+// HandleDisableProvider takes a provider out of routing.
+func (h *AdminHandler) HandleDisableProvider(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.providers.Disable(r.Context(), name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Warn("provider disabled by admin", observability.String("provider", name))
+	writeJSON(w, http.StatusOK, map[string]any{"provider": name, "enabled": false})
+}
+
+// HandleEnableProvider puts a disabled provider back into routing.
+func (h *AdminHandler) HandleEnableProvider(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.providers.Enable(r.Context(), name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("provider enabled by admin", observability.String("provider", name))
+	writeJSON(w, http.StatusOK, map[string]any{"provider": name, "enabled": true})
+}
+
+// HandleListQuotas lists every client key quota with the key's current usage.
+func (h *AdminHandler) HandleListQuotas(w http.ResponseWriter, r *http.Request) {
+	quotas, err := h.quotas.List(r.Context())
+	if err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"quotas": quotas,
+	})
+}
+
+// HandleGetQuota reports one client key's quota and current usage.
+func (h *AdminHandler) HandleGetQuota(w http.ResponseWriter, r *http.Request) {
+	status, err := h.quotas.Get(r.Context(), r.PathValue("key"))
+	if err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// HandleSetQuota creates or replaces a client key's quota. It takes effect immediately.
+func (h *AdminHandler) HandleSetQuota(w http.ResponseWriter, r *http.Request) {
+	var quota domain.Quota
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&quota); err != nil {
+		http.Error(w, "invalid quota: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	quota.Key = r.PathValue("key")
+
+	if err := h.quotas.Set(r.Context(), quota); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("quota set by admin", observability.String("key", quota.Key))
+	status, err := h.quotas.Get(r.Context(), quota.Key)
+	if err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// HandleDeleteQuota removes a client key's quota.
+func (h *AdminHandler) HandleDeleteQuota(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if err := h.quotas.Delete(r.Context(), key); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("quota deleted by admin", observability.String("key", key))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListKeys lists issued virtual keys without their secrets.
+func (h *AdminHandler) HandleListKeys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": h.keys.List(),
+	})
+}
+
+// issueKeyRequest is the body of a virtual key issuance request.
+type issueKeyRequest struct {
+	Name          string    `json:"name"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	ExpiresIn     int       `json:"expires_in"` // seconds; alternative to expires_at
+	AllowModels   []string  `json:"allow_models"`
+	MonthlyBudget float64   `json:"monthly_budget"`
+}
+
+// HandleIssueKey mints a virtual key. The secret is only ever returned in this response.
+func (h *AdminHandler) HandleIssueKey(w http.ResponseWriter, r *http.Request) {
+	var req issueKeyRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "invalid key request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if req.ExpiresIn > 0 {
+		expiresAt = time.Now().UTC().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+
+	secret, key, err := h.keys.Issue(r.Context(), domain.VirtualKey{
+		Name:          req.Name,
+		Hash:          "",
+		CreatedAt:     time.Time{},
+		ExpiresAt:     expiresAt,
+		AllowModels:   req.AllowModels,
+		MonthlyBudget: req.MonthlyBudget,
+	})
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("virtual key issued by admin", observability.String("key", key.Name))
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"secret": secret,
+		"key":    key,
+	})
+}
+
+// HandleRevokeKey deletes a virtual key; requests using it are rejected immediately.
+func (h *AdminHandler) HandleRevokeKey(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.keys.Revoke(r.Context(), name); err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("virtual key revoked by admin", observability.String("key", name))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListOverrides lists the pricing, model alias, and key policy overrides.
+func (h *AdminHandler) HandleListOverrides(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.overrides.List())
+}
+
+// HandleSetPricing creates or replaces a model's pricing override. It takes effect immediately.
+func (h *AdminHandler) HandleSetPricing(w http.ResponseWriter, r *http.Request) {
+	var pricing domain.PricingConfig
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&pricing); err != nil {
+		http.Error(w, "invalid pricing: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	override := domain.PricingOverride{PricingConfig: pricing, Model: r.PathValue("model")}
+	if err := h.overrides.SetPricing(r.Context(), override); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("pricing overridden by admin",
+		observability.String("model", override.Model))
+	writeJSON(w, http.StatusOK, override)
+}
+
+// HandleDeletePricing removes a model's pricing override, restoring its configured price.
+func (h *AdminHandler) HandleDeletePricing(w http.ResponseWriter, r *http.Request) {
+	model := r.PathValue("model")
+	if err := h.overrides.DeletePricing(r.Context(), model); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("pricing override deleted by admin",
+		observability.String("model", model))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetAlias creates or replaces a model alias, e.g. {"model": "gpt-4o"}.
+func (h *AdminHandler) HandleSetAlias(w http.ResponseWriter, r *http.Request) {
+	var alias domain.ModelAlias
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&alias); err != nil {
+		http.Error(w, "invalid alias: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	alias.Alias = r.PathValue("alias")
+
+	if err := h.overrides.SetAlias(r.Context(), alias); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("model alias set by admin",
+		observability.String("alias", alias.Alias), observability.String("model", alias.Model))
+	writeJSON(w, http.StatusOK, alias)
+}
+
+// HandleDeleteAlias removes a model alias.
+func (h *AdminHandler) HandleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+	if err := h.overrides.DeleteAlias(r.Context(), alias); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("model alias deleted by admin", observability.String("alias", alias))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetKeyPolicy creates or replaces a key policy. It takes precedence over
+// KEY_POLICIES_FILE policies for its keys and takes effect immediately.
+func (h *AdminHandler) HandleSetKeyPolicy(w http.ResponseWriter, r *http.Request) {
+	var policy domain.KeyPolicy
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		http.Error(w, "invalid key policy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	policy.Name = r.PathValue("name")
+
+	if err := h.overrides.SetKeyPolicy(r.Context(), policy); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("key policy set by admin", observability.String("policy", policy.Name))
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// HandleDeleteKeyPolicy removes a key policy.
+func (h *AdminHandler) HandleDeleteKeyPolicy(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.overrides.DeleteKeyPolicy(r.Context(), name); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+
+	observability.FromContext(r.Context()).Info("key policy deleted by admin", observability.String("policy", name))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleBillingExport exports the usage and cost of every client key over a
+// period as CSV (format=csv, the default) or an OpenCost custom cost response
+// (format=opencost). The period runs from the from query parameter, by default
+// the start of the current month (UTC), until to, by default now; group_by
+// groups the usage as for /v1/usage.
+func (h *AdminHandler) HandleBillingExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = billing.FormatCSV
+	}
+	if format != billing.FormatCSV && format != billing.FormatOpenCost {
+		http.Error(w, "format must be csv or opencost", http.StatusBadRequest)
+		return
+	}
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = domain.GroupByModel
+	}
+
+	var from, to time.Time
+	for name, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*target = parsed
+	}
+	from, to = billing.Period(from, to, time.Now())
+
+	report, err := h.gateway.BillingReport(r.Context(), from, to, groupBy)
+	if err != nil {
+		writeBillingError(w, err)
+		return
+	}
+
+	if format == billing.FormatOpenCost {
+		writeJSON(w, http.StatusOK, billing.OpenCost(report))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="calcifer-billing-%s-%s.csv"`,
+		from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly)))
+	w.WriteHeader(http.StatusOK)
+	if err = billing.WriteCSV(w, report); err != nil {
+		// Already written status, can't change it.
+		return
+	}
+}
+
+// requireVirtualKeys rejects virtual key requests when issuance is disabled.
+func (h *AdminHandler) requireVirtualKeys(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			http.Error(w, "virtual keys disabled: VIRTUAL_KEYS_ENABLED is not set", http.StatusNotImplemented)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorize rejects requests without the admin bearer token.
+func (h *AdminHandler) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.token == "" {
+			http.Error(w, "admin API disabled: ADMIN_TOKEN is not set", http.StatusForbidden)
+			return
+		}
+
+		if !hasBearerToken(r, h.token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// hasBearerToken reports whether r carries token as its bearer token. An empty
+// token never matches.
+func hasBearerToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	sent, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+// writeProviderError maps provider management errors to HTTP status codes.
+func writeProviderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownProvider):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrProviderNotDisabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeQuotaError maps quota management errors to HTTP status codes.
+func writeQuotaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownQuota):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidQuota):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeKeyError maps virtual key management errors to HTTP status codes.
+func writeKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownVirtualKey):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidVirtualKey):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeOverrideError maps override management errors to HTTP status codes.
+func writeOverrideError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownOverride):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidOverride):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeBillingError maps billing export errors to HTTP status codes.
+func writeBillingError(w http.ResponseWriter, err error) {
+	var groupingErr *domain.UnsupportedGroupingError
+	switch {
+	case errors.As(err, &groupingErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrUsageUnavailable):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		// Already written status, can't change it.
+		return
+	}
+}
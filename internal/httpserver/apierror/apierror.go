@@ -0,0 +1,42 @@
+// Package apierror writes the JSON error envelope of the gateway API. Handlers
+// and the middleware in front of them share it, so every error a client sees has
+// the same shape and carries the request ID.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Error types written by the middleware; handlers define their own alongside.
+const (
+	TypeInvalidRequest  = "invalid_request"
+	TypeUnauthorized    = "unauthorized"
+	TypeForbidden       = "forbidden"
+	TypeConflict        = "conflict"
+	TypeRequestTooLarge = "request_too_large"
+)
+
+// Write writes the JSON error envelope with status. Every envelope carries the
+// request ID so clients can correlate failures with gateway logs; fields adds
+// type-specific details.
+func Write(ctx context.Context, w http.ResponseWriter, status int, errorType, message string, fields map[string]any) {
+	body := map[string]any{
+		"type":    errorType,
+		"message": message,
+	}
+	if requestID := observability.GetRequestID(ctx); requestID != "" {
+		body["request_id"] = requestID
+	}
+	for name, value := range fields {
+		body[name] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// The status is already written, so an encoding failure cannot be reported.
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": body})
+}
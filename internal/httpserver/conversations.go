@@ -0,0 +1,142 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// conversationRequest is the body creating a conversation.
+type conversationRequest struct {
+	Model    string           `json:"model,omitempty"`
+	Messages []domain.Message `json:"messages,omitempty"`
+}
+
+// HandleConversations creates a conversation (POST /v1/conversations) whose
+// history the gateway keeps, optionally with a default model and opening
+// messages such as instructions.
+func (h *Handler) HandleConversations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var body conversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	conversation, err := h.conversations.Create(ctx, body.Model, body.Messages)
+	if err != nil {
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	observability.FromContext(ctx).Info("conversation created", observability.String("conversation", conversation.ID))
+	writeJSON(w, http.StatusCreated, conversation)
+}
+
+// HandleConversation returns (GET) or deletes (DELETE) a conversation of the
+// client key at /v1/conversations/{id}.
+func (h *Handler) HandleConversation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		conversation, err := h.conversations.Get(ctx, id)
+		if err != nil {
+			writeGatewayError(ctx, w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, conversation)
+	case http.MethodDelete:
+		if err := h.conversations.Delete(ctx, id); err != nil {
+			writeGatewayError(ctx, w, err)
+			return
+		}
+		observability.FromContext(ctx).Info("conversation deleted", observability.String("conversation", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+	}
+}
+
+// HandleConversationMessages sends a turn of a conversation (POST
+// /v1/conversations/{id}/messages). The body is a completion request holding
+// only the new messages; the gateway sends them after the stored history, uses
+// the conversation model when the request names none, and appends the turn and
+// its reply to the history once the reply completes.
+func (h *Handler) HandleConversationMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	if r.Header.Get(ProviderHeader) != "" {
+		writeBadRequest(ctx, w, ProviderHeader+" is not supported for conversations, which route by model")
+		return
+	}
+
+	var turn domain.CompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&turn); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	if turn.Model == "" {
+		conversation, err := h.conversations.Get(ctx, id)
+		if err != nil {
+			writeGatewayError(ctx, w, err)
+			return
+		}
+		turn.Model = conversation.Model
+	}
+	ctx, _, ok := h.admitCompletion(ctx, w, r, &turn)
+	if !ok {
+		return
+	}
+
+	done := h.load.Start(observability.GetTenant(ctx))
+	defer done()
+
+	logger := observability.FromContext(ctx).With(observability.String("conversation", id))
+	logger.Info("conversation turn received",
+		observability.String("model", turn.Model),
+		observability.Bool("stream", turn.Stream),
+	)
+
+	if turn.Stream {
+		setStreamHeaders(w, r)
+		chunks, err := h.conversations.Stream(ctx, id, &turn)
+		if err != nil {
+			logger.Error("conversation stream failed", observability.Error(err))
+			writeGatewayError(ctx, w, err)
+			return
+		}
+		h.relayStream(ctx, w, chunks)
+		return
+	}
+
+	response, err := h.conversations.Complete(ctx, id, &turn)
+	if err != nil {
+		logger.Error("conversation turn failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	logger.Info("conversation turn succeeded",
+		observability.Int("tokens", response.Usage.TotalTokens),
+		observability.Float64("cost", response.Usage.Cost),
+	)
+	h.headerAllowlist.apply(w.Header(), response.ProviderHeaders)
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,6 @@
+// Renders the gateway's OpenAPI document; kept out of index.html so the page
+// needs no inline scripts.
+window.ui = SwaggerUIBundle({
+  url: "/openapi.json",
+  dom_id: "#swagger-ui",
+});
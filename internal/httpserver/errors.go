@@ -0,0 +1,164 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/sse"
+)
+
+// Error envelope types.
+const (
+	errorTypeInvalidRequest   = apierror.TypeInvalidRequest
+	errorTypeUnauthorized     = apierror.TypeUnauthorized
+	errorTypeMethodNotAllowed = "method_not_allowed"
+	errorTypeNotFound         = "not_found"
+	errorTypeModelRetired     = "model_retired"
+	errorTypeContentFlagged   = "content_flagged"
+	errorTypeGuardrail        = "guardrail_blocked"
+	errorTypePolicyViolation  = "policy_violation"
+	errorTypeCapacity         = "capacity_exceeded"
+	errorTypeQuota            = "quota_exceeded"
+	errorTypeContextLength    = "context_length_exceeded"
+	errorTypeRateLimited      = "rate_limited"
+	errorTypeUpstream         = "upstream_error"
+	errorTypeNotImplemented   = "not_implemented"
+	errorTypeServer           = "server_error"
+)
+
+// errStreamIncomplete fails a stream whose channel closed without a done chunk,
+// so the client and the idempotency store do not take it as complete.
+var errStreamIncomplete = errors.New("stream ended before completion")
+
+// writeError writes the JSON error envelope (see apierror.Write).
+func writeError(
+	ctx context.Context,
+	w http.ResponseWriter,
+	status int,
+	errorType string,
+	message string,
+	fields map[string]any,
+) {
+	apierror.Write(ctx, w, status, errorType, message, fields)
+}
+
+// writeStreamError writes the final SSE error event of a failed stream. The event
+// carries the error envelope and whether content was already streamed (partial).
+func writeStreamError(ctx context.Context, events *sse.Encoder, err error, partial bool) {
+	body := map[string]any{
+		"type":    errorTypeServer,
+		"message": err.Error(),
+	}
+	if requestID := observability.GetRequestID(ctx); requestID != "" {
+		body["request_id"] = requestID
+	}
+
+	_ = events.Encode("error", map[string]any{"error": body, "partial": partial})
+}
+
+// writeBadRequest writes an invalid_request error envelope with status 400.
+func writeBadRequest(ctx context.Context, w http.ResponseWriter, message string) {
+	writeError(ctx, w, http.StatusBadRequest, errorTypeInvalidRequest, message, nil)
+}
+
+// writeGatewayError maps gateway errors to HTTP status codes and error envelopes.
+func writeGatewayError(ctx context.Context, w http.ResponseWriter, err error) {
+	status, errorType := http.StatusInternalServerError, errorTypeServer
+	var fields map[string]any
+
+	var (
+		capacityErr     *domain.CapacityError
+		quotaErr        *domain.QuotaExceededError
+		moderationErr   *domain.ModerationError
+		guardrailErr    *domain.GuardrailError
+		policyErr       *domain.PolicyError
+		unsupportedErr  *domain.UnsupportedParameterError
+		limitErr        *domain.ParameterLimitError
+		notSupportedErr *domain.ModelNotSupportedError
+		retiredErr      *domain.ModelRetiredError
+		groupingErr     *domain.UnsupportedGroupingError
+		contextErr      *domain.ContextWindowError
+		providerErr     *domain.ProviderError
+		validationErr   *domain.ValidationError
+	)
+
+	switch {
+	case errors.As(err, &capacityErr):
+		status, errorType = http.StatusServiceUnavailable, errorTypeCapacity
+		setRetryAfter(w, capacityErr.RetryAfter.Seconds())
+	case errors.As(err, &quotaErr):
+		status, errorType = http.StatusTooManyRequests, errorTypeQuota
+		fields = map[string]any{"limit": quotaErr.Limit}
+		setRetryAfter(w, quotaErr.RetryAfter.Seconds())
+	case errors.As(err, &moderationErr):
+		status, errorType = http.StatusBadRequest, errorTypeContentFlagged
+		fields = map[string]any{"categories": moderationErr.Categories}
+	case errors.As(err, &guardrailErr):
+		status, errorType = http.StatusBadRequest, errorTypeGuardrail
+		fields = map[string]any{"guardrail": guardrailErr.Guardrail}
+	case errors.As(err, &validationErr):
+		status, errorType = http.StatusBadRequest, errorTypeInvalidRequest
+		fields = map[string]any{"param": validationErr.Param}
+	case errors.As(err, &contextErr):
+		status, errorType = http.StatusBadRequest, errorTypeContextLength
+		fields = map[string]any{"prompt_tokens": contextErr.PromptTokens, "limit": contextErr.Limit}
+	case errors.As(err, &policyErr):
+		status, errorType = http.StatusForbidden, errorTypePolicyViolation
+		fields = map[string]any{"policy": policyErr.Policy}
+	case errors.As(err, &retiredErr):
+		status, errorType = http.StatusGone, errorTypeModelRetired
+		fields = map[string]any{"model": retiredErr.Model}
+	case errors.As(err, &unsupportedErr),
+		errors.As(err, &limitErr),
+		errors.As(err, &notSupportedErr),
+		errors.As(err, &groupingErr),
+		errors.Is(err, domain.ErrUnknownProvider):
+		status, errorType = http.StatusBadRequest, errorTypeInvalidRequest
+	case errors.Is(err, domain.ErrModerationUnavailable),
+		errors.Is(err, domain.ErrUsageUnavailable),
+		errors.Is(err, domain.ErrSpeechUnavailable):
+		status, errorType = http.StatusNotImplemented, errorTypeNotImplemented
+	case errors.Is(err, domain.ErrUnknownConversation):
+		status, errorType = http.StatusNotFound, errorTypeNotFound
+	case errors.As(err, &providerErr):
+		status, errorType = upstreamStatus(providerErr.StatusCode)
+		fields = map[string]any{"provider": providerErr.Provider, "upstream_status": providerErr.StatusCode}
+		for name, value := range providerErr.Headers {
+			w.Header().Set(name, value)
+		}
+		if providerErr.RetryAfter > 0 {
+			setRetryAfter(w, providerErr.RetryAfter.Seconds())
+		}
+	}
+
+	writeError(ctx, w, status, errorType, err.Error(), fields)
+}
+
+// upstreamStatus maps an upstream provider status to the status returned to clients.
+// Rate limits and unavailability pass through so clients back off; request errors
+// pass through so clients can fix them; other failures are the gateway's and become 502.
+func upstreamStatus(status int) (int, string) {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return status, errorTypeRateLimited
+	case status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return status, errorTypeUpstream
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusRequestTimeout:
+		return http.StatusBadGateway, errorTypeUpstream
+	case status >= http.StatusBadRequest && status < http.StatusInternalServerError:
+		return status, errorTypeInvalidRequest
+	default:
+		return http.StatusBadGateway, errorTypeUpstream
+	}
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds (at least 1).
+func setRetryAfter(w http.ResponseWriter, seconds float64) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(seconds)))))
+}
@@ -5,20 +5,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/davidbz/calcifer/internal/config"
 	"github.com/davidbz/calcifer/internal/domain"
 	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/sse"
+)
+
+const (
+	// DryRunHeader makes a completion request report its routing and estimated cost
+	// instead of calling the provider when set to "true".
+	DryRunHeader = "X-Calcifer-Dry-Run"
+
+	// ProviderHeader forces a completion request to a registered provider instead of
+	// routing by model. The provider must support the requested model.
+	ProviderHeader = "X-Provider"
+
+	// ModelReplacementHeader names the recommended replacement of a deprecated model.
+	ModelReplacementHeader = "X-Calcifer-Model-Replacement"
 )
 
 // Handler handles HTTP requests.
 type Handler struct {
-	gateway *domain.GatewayService
+	gateway         *domain.GatewayService
+	conversations   *domain.Conversations
+	load            *domain.LoadTracker
+	headerAllowlist *headerAllowlist
+	ensemble        config.EnsembleConfig
+	adminToken      string
 }
 
 // NewHandler creates a new HTTP handler (DI constructor).
-func NewHandler(gateway *domain.GatewayService) *Handler {
+func NewHandler(
+	gateway *domain.GatewayService,
+	conversations *domain.Conversations,
+	load *domain.LoadTracker,
+	cfg *config.ServerConfig,
+	ensembleCfg *config.EnsembleConfig,
+	adminCfg *config.AdminConfig,
+) *Handler {
 	return &Handler{
-		gateway: gateway,
+		gateway:         gateway,
+		conversations:   conversations,
+		load:            load,
+		headerAllowlist: newHeaderAllowlist(cfg.ResponseHeaderAllowlist),
+		ensemble:        *ensembleCfg,
+		adminToken:      adminCfg.Token,
 	}
 }
 
@@ -28,24 +62,35 @@ func (h *Handler) HandleCompletion(w http.ResponseWriter, r *http.Request) {
 
 	// Early validation.
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
 	// Parse request.
-	var req domain.CompletionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+	var body completionBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
 		return
 	}
 
-	if req.Model == "" {
-		http.Error(w, "model is required", http.StatusBadRequest)
+	// Legacy text completion requests send a prompt, answered in their own format.
+	legacy := body.legacy()
+	if legacy {
+		if err := body.translatePrompt(); err != nil {
+			writeBadRequest(ctx, w, err.Error())
+			return
+		}
+	}
+	req := &body.CompletionRequest
+
+	ctx, providerName, ok := h.admitCompletion(ctx, w, r, req)
+	if !ok {
 		return
 	}
 
-	// Inject model into context for downstream logging.
-	ctx = observability.WithModel(ctx, req.Model)
+	// Track the request as in flight for its tenant until the response (or stream) ends.
+	done := h.load.Start(observability.GetTenant(ctx))
+	defer done()
 
 	logger := observability.FromContext(ctx)
 	logger.Info("completion request received",
@@ -53,17 +98,26 @@ func (h *Handler) HandleCompletion(w http.ResponseWriter, r *http.Request) {
 		observability.Bool("stream", req.Stream),
 	)
 
+	if r.Header.Get(DryRunHeader) == "true" {
+		h.handleDryRun(ctx, w, providerName, req)
+		return
+	}
+
 	// Handle streaming vs non-streaming.
+	if req.Stream && legacy {
+		h.handleLegacyStream(ctx, w, providerName, &body)
+		return
+	}
 	if req.Stream {
-		h.handleStreamByModel(ctx, w, &req)
+		h.handleStream(ctx, w, r, providerName, req)
 		return
 	}
 
 	// Non-streaming response.
-	response, execErr := h.gateway.CompleteByModel(ctx, &req)
+	response, execErr := h.complete(ctx, providerName, req)
 	if execErr != nil {
 		logger.Error("completion failed", observability.Error(execErr))
-		http.Error(w, execErr.Error(), http.StatusInternalServerError)
+		writeGatewayError(ctx, w, execErr)
 		return
 	}
 
@@ -72,68 +126,200 @@ func (h *Handler) HandleCompletion(w http.ResponseWriter, r *http.Request) {
 		observability.Float64("cost", response.Usage.Cost),
 	)
 
+	h.headerAllowlist.apply(w.Header(), response.ProviderHeaders)
+	if legacy {
+		writeLegacyCompletion(w, response, &body)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	encodeErr := json.NewEncoder(w).Encode(response)
 	if encodeErr != nil {
 		logger.Error("failed to encode response", observability.Error(encodeErr))
-		http.Error(w, fmt.Sprintf("failed to encode response: %v", encodeErr), http.StatusInternalServerError)
+		message := fmt.Sprintf("failed to encode response: %v", encodeErr)
+		writeError(ctx, w, http.StatusInternalServerError, errorTypeServer, message, nil)
+		return
+	}
+}
+
+// admitCompletion validates a decoded completion request and annotates the
+// context and response headers for it. It returns the provider forced by the
+// ProviderHeader, if any, or writes the error response and returns false.
+func (h *Handler) admitCompletion(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	req *domain.CompletionRequest,
+) (context.Context, string, bool) {
+	if req.Model == "" {
+		writeBadRequest(ctx, w, "model is required")
+		return ctx, "", false
+	}
+
+	if err := domain.ValidateMessages(req.Messages); err != nil {
+		writeGatewayError(ctx, w, err)
+		return ctx, "", false
+	}
+
+	// Inject model into context for downstream logging.
+	ctx = observability.WithModel(ctx, req.Model)
+	observability.RecordModel(ctx, req.Model)
+
+	// Deprecated models are served with a warning until their sunset.
+	if deprecation, ok := h.gateway.Deprecation(req.Model); ok {
+		setDeprecationHeaders(w.Header(), deprecation)
+	}
+
+	// An optional provider override bypasses model routing.
+	providerName := r.Header.Get(ProviderHeader)
+	if providerName != "" {
+		ctx = observability.WithProvider(ctx, providerName)
+	}
+	return ctx, providerName, true
+}
+
+// complete runs a request on the forced provider, or routes it by model.
+func (h *Handler) complete(
+	ctx context.Context,
+	providerName string,
+	req *domain.CompletionRequest,
+) (*domain.CompletionResponse, error) {
+	if providerName != "" {
+		return h.gateway.Complete(ctx, providerName, req) //nolint:wrapcheck // Gateway errors map to responses
+	}
+	return h.gateway.CompleteByModel(ctx, req) //nolint:wrapcheck // Gateway errors map to responses
+}
+
+// stream streams a request from the forced provider, or routes it by model.
+func (h *Handler) stream(
+	ctx context.Context,
+	providerName string,
+	req *domain.CompletionRequest,
+) (<-chan domain.StreamChunk, error) {
+	if providerName != "" {
+		return h.gateway.Stream(ctx, providerName, req) //nolint:wrapcheck // Gateway errors map to responses
+	}
+	return h.gateway.StreamByModel(ctx, req) //nolint:wrapcheck // Gateway errors map to responses
+}
+
+// dryRunResponse reports what a dry-run request would have done.
+type dryRunResponse struct {
+	DryRun bool                 `json:"dry_run"`
+	Result *domain.DryRunResult `json:"result"`
+}
+
+// handleDryRun reports the routing decision and estimated cost of a request
+// without calling the provider.
+func (h *Handler) handleDryRun(
+	ctx context.Context,
+	w http.ResponseWriter,
+	providerName string,
+	req *domain.CompletionRequest,
+) {
+	logger := observability.FromContext(ctx)
+
+	result, err := h.gateway.DryRun(ctx, providerName, req)
+	if err != nil {
+		logger.Info("dry run rejected", observability.Error(err))
+		writeGatewayError(ctx, w, err)
 		return
 	}
+
+	logger.Info("dry run completed",
+		observability.String("provider", result.Provider),
+		observability.Float64("estimated_cost", result.EstimatedCost),
+	)
+
+	writeJSON(w, http.StatusOK, dryRunResponse{DryRun: true, Result: result})
 }
 
-func (h *Handler) handleStreamByModel(
+func (h *Handler) handleStream(
 	ctx context.Context,
 	w http.ResponseWriter,
+	r *http.Request,
+	providerName string,
 	req *domain.CompletionRequest,
 ) {
 	logger := observability.FromContext(ctx)
 	logger.Info("stream request started")
 
-	// Set headers for SSE.
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-
-	chunks, err := h.gateway.StreamByModel(ctx, req)
+	setStreamHeaders(w, r)
+	chunks, err := h.stream(ctx, providerName, req)
 	if err != nil {
 		logger.Error("stream failed", observability.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeGatewayError(ctx, w, err)
 		return
 	}
 
+	h.relayStream(ctx, w, chunks)
+}
+
+// setStreamHeaders sets the headers of a server-sent events response.
+func setStreamHeaders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.ProtoMajor < 2 {
+		// Connection-specific headers are forbidden in HTTP/2, where streams share a connection.
+		w.Header().Set("Connection", "keep-alive")
+	}
+}
+
+// relayStream writes chunks to the client as server-sent events until the
+// stream ends, fails, or the client goes away.
+func (h *Handler) relayStream(ctx context.Context, w http.ResponseWriter, chunks <-chan domain.StreamChunk) {
+	logger := observability.FromContext(ctx)
+
+	// Middleware writers implement Flush and Unwrap, so this holds under HTTP/1.1 and HTTP/2.
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		logger.Error("streaming not supported")
-		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		writeError(ctx, w, http.StatusInternalServerError, errorTypeServer, "streaming not supported", nil)
 		return
 	}
 
+	events := sse.NewEncoder(w)
+	defer events.Release()
+
+	// Content sent before a failure makes the response partial: clients must not treat
+	// it as complete, and it is never stored for replay.
+	contentSent := false
 	for {
 		select {
 		case <-ctx.Done():
 			// Client disconnected or timeout
 			logger.Info("stream context done", observability.Error(ctx.Err()))
+			observability.RecordPartial(ctx)
 			return
 
 		case chunk, chunkOk := <-chunks:
 			if !chunkOk {
-				// Channel closed normally
-				logger.Info("stream completed normally")
+				// The stream ended without a done chunk, so the response is incomplete.
+				logger.Warn("stream ended before completion", observability.Bool("partial", contentSent))
+				observability.RecordPartial(ctx)
+				writeStreamError(ctx, events, errStreamIncomplete, contentSent)
+				flusher.Flush()
 				return
 			}
 
+			// Upstream headers arrive with the first chunk, before the body is committed.
+			if chunk.ProviderHeaders != nil {
+				h.headerAllowlist.apply(w.Header(), chunk.ProviderHeaders)
+			}
+
 			if chunk.Error != nil {
-				logger.Error("stream chunk error", observability.Error(chunk.Error))
-				// Send error as event.
-				fmt.Fprintf(w, "event: error\ndata: %s\n\n", chunk.Error.Error())
+				logger.Error("stream chunk error",
+					observability.Error(chunk.Error),
+					observability.Bool("partial", contentSent),
+				)
+				observability.RecordPartial(ctx)
+				writeStreamError(ctx, events, chunk.Error, contentSent)
 				flusher.Flush()
 				return
 			}
 
 			// Send chunk as event.
-			data, _ := json.Marshal(chunk)
-			fmt.Fprintf(w, "data: %s\n\n", string(data))
+			_ = events.Encode("", &chunk)
 			flusher.Flush()
+			contentSent = contentSent || chunk.Delta != ""
 
 			if chunk.Done {
 				logger.Info("stream completed")
@@ -143,14 +329,233 @@ func (h *Handler) handleStreamByModel(
 	}
 }
 
+// HandleEnsemble processes multi-model ensemble requests.
+func (h *Handler) HandleEnsemble(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.ensemble.Enabled {
+		writeError(ctx, w, http.StatusNotFound, errorTypeNotFound, "ensemble mode is disabled", nil)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var req domain.EnsembleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	if len(req.Models) == 0 {
+		writeBadRequest(ctx, w, "models is required")
+		return
+	}
+
+	if h.ensemble.MaxModels > 0 && len(req.Models) > h.ensemble.MaxModels {
+		writeBadRequest(ctx, w, fmt.Sprintf("at most %d models are allowed", h.ensemble.MaxModels))
+		return
+	}
+
+	if err := domain.ValidateMessages(req.Messages); err != nil {
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	done := h.load.Start(observability.GetTenant(ctx))
+	defer done()
+
+	logger := observability.FromContext(ctx)
+	logger.Info("ensemble request received",
+		observability.Int("models", len(req.Models)),
+		observability.String("judge_model", req.JudgeModel),
+	)
+
+	response, err := h.gateway.CompleteEnsemble(ctx, &req)
+	if err != nil {
+		logger.Error("ensemble failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	logger.Info("ensemble succeeded",
+		observability.Int("tokens", response.Usage.TotalTokens),
+		observability.Float64("cost", response.Usage.Cost),
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// moderationRequest accepts input as a single string or an array of strings.
+type moderationRequest struct {
+	Input json.RawMessage `json:"input"`
+	Model string          `json:"model,omitempty"`
+}
+
+// HandleModeration classifies content with the configured moderation provider.
+func (h *Handler) HandleModeration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var body moderationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	// A null input would decode as an empty string or list, so it is rejected first.
+	if len(body.Input) == 0 || string(body.Input) == "null" {
+		writeBadRequest(ctx, w, "input is required")
+		return
+	}
+
+	var inputs []string
+	var single string
+	if err := json.Unmarshal(body.Input, &single); err == nil {
+		inputs = []string{single}
+	} else if err := json.Unmarshal(body.Input, &inputs); err != nil {
+		writeBadRequest(ctx, w, "input must be a string or an array of strings")
+		return
+	}
+
+	if len(inputs) == 0 {
+		writeBadRequest(ctx, w, "input is required")
+		return
+	}
+
+	logger := observability.FromContext(ctx)
+
+	response, err := h.gateway.Moderate(ctx, &domain.ModerationRequest{Input: inputs, Model: body.Model})
+	if err != nil {
+		logger.Error("moderation failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// usageResponse is aggregated usage over a period.
+type usageResponse struct {
+	GroupBy string                  `json:"group_by"`
+	From    time.Time               `json:"from"`
+	To      time.Time               `json:"to"`
+	Data    []domain.UsageAggregate `json:"data"`
+}
+
+// HandleUsage reports aggregated usage, e.g. GET /v1/usage?group_by=model&from=&to=.
+// from and to are RFC 3339 timestamps. Authenticated clients only see their own usage;
+// without client authentication the report covers every client and requires the admin token.
+func (h *Handler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodGet {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	clientKey := observability.GetClientKey(ctx)
+	if clientKey == "" && !hasBearerToken(r, h.adminToken) {
+		writeError(ctx, w, http.StatusUnauthorized, errorTypeUnauthorized,
+			"usage of all clients requires the admin token", nil)
+		return
+	}
+
+	query := r.URL.Query()
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = domain.GroupByModel
+	}
+
+	filter := domain.UsageFilter{
+		From:      time.Time{},
+		To:        time.Time{},
+		ClientKey: clientKey,
+	}
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeBadRequest(ctx, w, fmt.Sprintf("%s must be an RFC 3339 timestamp", name))
+			return
+		}
+		*target = parsed
+	}
+
+	report, err := h.gateway.UsageReport(ctx, filter, groupBy)
+	if err != nil {
+		observability.FromContext(ctx).Error("usage report failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usageResponse{GroupBy: groupBy, From: filter.From, To: filter.To, Data: report})
+}
+
+// modelsResponse lists the models clients can request.
+type modelsResponse struct {
+	Data []domain.ModelInfo `json:"data"`
+}
+
+// HandleModels lists the models clients can request, including configured aliases.
+func (h *Handler) HandleModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodGet {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	models, err := h.gateway.Models(ctx)
+	if err != nil {
+		observability.FromContext(ctx).Error("model listing failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, modelsResponse{Data: models})
+}
+
+// healthResponse reports that the gateway is serving.
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
 // HandleHealth handles health check requests.
 func (h *Handler) HandleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
-	}); err != nil {
+	if err := json.NewEncoder(w).Encode(healthResponse{Status: "healthy"}); err != nil {
 		// Already written status, can't change it, just log.
 		return
 	}
 }
+
+// setDeprecationHeaders announces a model's deprecation with the Deprecation and
+// Sunset (RFC 8594) headers and names its replacement, if any.
+func setDeprecationHeaders(header http.Header, deprecation domain.Deprecation) {
+	header.Set("Deprecation", "true")
+	header.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+	if deprecation.Replacement != "" {
+		header.Set(ModelReplacementHeader, deprecation.Replacement)
+	}
+}
+
+// responseID derives a response ID in a client API's format from the request ID,
+// which correlates it with gateway logs, falling back to the provider's ID.
+func responseID(ctx context.Context, prefix, fallback string) string {
+	if requestID := observability.GetRequestID(ctx); requestID != "" {
+		return prefix + strings.ReplaceAll(requestID, "-", "")
+	}
+	return prefix + fallback
+}
@@ -0,0 +1,79 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// blockedHeader reports whether a provider header is never forwarded, even when
+// an allowlist pattern such as "*" matches it: hop-by-hop and framing headers
+// describe the upstream connection, and the others carry credentials. name must
+// be lower case.
+func blockedHeader(name string) bool {
+	switch name {
+	case "connection", "keep-alive", "proxy-connection", "te", "trailer", "transfer-encoding", "upgrade",
+		"content-encoding", "content-length", "content-range", "content-type", "host",
+		"authorization", "proxy-authenticate", "proxy-authorization", "set-cookie", "www-authenticate":
+		return true
+	default:
+		return false
+	}
+}
+
+// headerAllowlist decides which upstream provider headers are forwarded to clients.
+type headerAllowlist struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// newHeaderAllowlist builds an allowlist from header names and "prefix*" patterns.
+func newHeaderAllowlist(patterns []string) *headerAllowlist {
+	allowlist := &headerAllowlist{
+		exact:    make(map[string]bool, len(patterns)),
+		prefixes: nil,
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			allowlist.prefixes = append(allowlist.prefixes, prefix)
+			continue
+		}
+		allowlist.exact[pattern] = true
+	}
+
+	return allowlist
+}
+
+// allows reports whether the header name is forwarded. Blocked headers never are.
+func (a *headerAllowlist) allows(name string) bool {
+	name = strings.ToLower(name)
+	if blockedHeader(name) {
+		return false
+	}
+	if a.exact[name] {
+		return true
+	}
+
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply copies allowed provider headers into dst.
+// Headers already set by the gateway (e.g. X-Request-Id) are never overwritten.
+func (a *headerAllowlist) apply(dst http.Header, providerHeaders map[string]string) {
+	for name, value := range providerHeaders {
+		if !a.allows(name) || dst.Get(name) != "" {
+			continue
+		}
+		dst.Set(name, value)
+	}
+}
@@ -0,0 +1,218 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/sse"
+)
+
+// legacyCompletionObject is the object type of legacy text completion responses.
+const legacyCompletionObject = "text_completion"
+
+// completionBody is a /v1/completions request body. A prompt instead of messages
+// selects the legacy text completion format (text-davinci-style payloads).
+type completionBody struct {
+	domain.CompletionRequest
+
+	Prompt   json.RawMessage `json:"prompt,omitempty"`
+	Echo     bool            `json:"echo,omitempty"`
+	Suffix   string          `json:"suffix,omitempty"`
+	BestOf   int             `json:"best_of,omitempty"`
+	Logprobs *int            `json:"logprobs,omitempty"`
+}
+
+// legacy reports whether the body is a legacy text completion request.
+func (b *completionBody) legacy() bool {
+	return len(b.Prompt) > 0 && string(b.Prompt) != "null"
+}
+
+// translatePrompt maps the legacy prompt to a single user message. Parameters
+// chat models cannot honor are rejected rather than ignored.
+func (b *completionBody) translatePrompt() error {
+	if len(b.Messages) > 0 {
+		return errors.New("prompt and messages are mutually exclusive")
+	}
+	if b.Suffix != "" || b.BestOf > 1 || b.Logprobs != nil {
+		return errors.New("suffix, best_of, and logprobs are not supported")
+	}
+
+	prompt, err := parsePrompt(b.Prompt)
+	if err != nil {
+		return err
+	}
+	b.Messages = []domain.Message{{Role: "user", Content: prompt, ToolCallID: "", ToolCalls: nil}}
+	return nil
+}
+
+// legacyCompletion is a legacy text completion response or stream chunk.
+type legacyCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []legacyChoice `json:"choices"`
+	Usage   *domain.Usage  `json:"usage,omitempty"`
+}
+
+// newLegacyCompletion translates a completion response, prefixing each choice
+// with the prompt when echo is set.
+func newLegacyCompletion(response *domain.CompletionResponse, echo string) *legacyCompletion {
+	choices := response.Choices
+	if len(choices) == 0 {
+		choices = []domain.Choice{{Index: 0, Content: response.Content, FinishReason: ""}}
+	}
+
+	legacyChoices := make([]legacyChoice, len(choices))
+	for i, choice := range choices {
+		legacyChoices[i] = newLegacyChoice(choice.Index, echo+choice.Content, choice.FinishReason)
+	}
+
+	usage := response.Usage
+	return &legacyCompletion{
+		ID:      response.ID,
+		Object:  legacyCompletionObject,
+		Created: response.FinishTime.Unix(),
+		Model:   response.Model,
+		Choices: legacyChoices,
+		Usage:   &usage,
+	}
+}
+
+// legacyChoice is one generated text. Logprobs are never reported.
+type legacyChoice struct {
+	Text         string  `json:"text"`
+	Index        int     `json:"index"`
+	Logprobs     any     `json:"logprobs"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+// newLegacyChoice returns a choice; an empty finish reason is reported as null.
+func newLegacyChoice(index int, text, finishReason string) legacyChoice {
+	choice := legacyChoice{Text: text, Index: index, Logprobs: nil, FinishReason: nil}
+	if finishReason != "" {
+		choice.FinishReason = &finishReason
+	}
+	return choice
+}
+
+// writeLegacyCompletion writes a completion response in the legacy text format.
+func writeLegacyCompletion(w http.ResponseWriter, response *domain.CompletionResponse, body *completionBody) {
+	echo := ""
+	if body.Echo {
+		echo = body.Messages[0].Content
+	}
+	writeJSON(w, http.StatusOK, newLegacyCompletion(response, echo))
+}
+
+// handleLegacyStream streams a completion as legacy text completion chunks, ending
+// with a "data: [DONE]" event as OpenAI does.
+func (h *Handler) handleLegacyStream(
+	ctx context.Context,
+	w http.ResponseWriter,
+	providerName string,
+	body *completionBody,
+) {
+	logger := observability.FromContext(ctx)
+
+	chunks, err := h.stream(ctx, providerName, &body.CompletionRequest)
+	if err != nil {
+		logger.Error("stream failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("streaming not supported")
+		writeError(ctx, w, http.StatusInternalServerError, errorTypeServer, "streaming not supported", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	chunk := legacyCompletion{
+		ID:      responseID(ctx, "cmpl-", ""),
+		Object:  legacyCompletionObject,
+		Created: time.Now().Unix(),
+		Model:   body.Model,
+		Choices: nil,
+		Usage:   nil,
+	}
+	events := sse.NewEncoder(w)
+	defer events.Release()
+	send := func(text string) {
+		chunk.Choices = []legacyChoice{newLegacyChoice(0, text, "")}
+		_ = events.Encode("", &chunk)
+		flusher.Flush()
+	}
+	if body.Echo {
+		send(body.Messages[0].Content)
+	}
+
+	contentSent := false
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stream context done", observability.Error(ctx.Err()))
+			observability.RecordPartial(ctx)
+			return
+
+		case next, nextOk := <-chunks:
+			if !nextOk {
+				// The stream ended without a done chunk, so the response is incomplete.
+				next.Error = errStreamIncomplete
+			}
+			if next.ProviderHeaders != nil {
+				h.headerAllowlist.apply(w.Header(), next.ProviderHeaders)
+			}
+
+			if next.Error != nil {
+				logger.Error("stream chunk error",
+					observability.Error(next.Error),
+					observability.Bool("partial", contentSent),
+				)
+				observability.RecordPartial(ctx)
+				writeStreamError(ctx, events, next.Error, contentSent)
+				flusher.Flush()
+				return
+			}
+
+			if next.Delta != "" {
+				send(next.Delta)
+				contentSent = true
+			}
+
+			if next.Done {
+				logger.Info("stream completed")
+				_ = events.Data("", []byte("[DONE]"))
+				flusher.Flush()
+				return
+			}
+		}
+	}
+}
+
+// parsePrompt accepts a prompt string, or an array holding exactly one. Batched
+// prompts and token arrays are not supported.
+func parsePrompt(raw json.RawMessage) (string, error) {
+	var prompt string
+	if err := json.Unmarshal(raw, &prompt); err == nil {
+		return prompt, nil
+	}
+
+	var prompts []string
+	if err := json.Unmarshal(raw, &prompts); err != nil {
+		return "", errors.New("prompt must be a string or an array of strings")
+	}
+	if len(prompts) != 1 {
+		return "", errors.New("prompt must hold exactly one string; batched prompts are not supported")
+	}
+	return prompts[0], nil
+}
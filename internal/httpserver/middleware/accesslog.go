@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// keyHashLength is the number of hex characters of the key digest kept in access logs:
+// enough to tell keys apart without logging anything usable as a credential.
+const keyHashLength = 12
+
+// statusWriter captures the status code and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err //nolint:wrapcheck // Transparent writer passthrough
+}
+
+// Flush keeps SSE streaming working through the writer.
+func (w *statusWriter) Flush() {
+	// The controller finds the flusher through further wrappers, under HTTP/1.1 and HTTP/2 alike.
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLog creates a middleware that logs one structured line per request once the
+// response is complete: status, duration, bytes written, model, provider, cache status,
+// and a truncated hash of the client API key.
+// It must run inside Trace so the line carries the request ID.
+func AccessLog() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, summary := observability.WithRequestSummary(r.Context())
+			writer := &statusWriter{ResponseWriter: w, status: 0, bytes: 0}
+
+			next.ServeHTTP(writer, r.WithContext(ctx))
+
+			status := writer.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			observability.FromContext(ctx).Info("request completed",
+				observability.String("method", r.Method),
+				observability.String("path", r.URL.Path),
+				observability.Int("status", status),
+				observability.Duration("duration", time.Since(start)),
+				observability.Int64("bytes", writer.bytes),
+				observability.String("model", summary.Model()),
+				observability.String("provider", summary.Provider()),
+				observability.String("cache_status", summary.CacheStatus()),
+				observability.Bool("partial", summary.Partial()),
+				observability.String("key_hash", keyHash(r)),
+			)
+		})
+	}
+}
+
+// keyHash returns a truncated digest of the request's bearer token, or "" if none.
+func keyHash(r *http.Request) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return ""
+	}
+	return hashParts(token)[:keyHashLength]
+}
@@ -0,0 +1,81 @@
+package middleware_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Run("should pass the response through unchanged", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			observability.RecordModel(r.Context(), "gpt-4")
+			observability.RecordProvider(r.Context(), "openai")
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte("short and stout"))
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+
+		middleware.AccessLog()(handler).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusTeapot, rec.Code)
+		require.Equal(t, "short and stout", rec.Body.String())
+	})
+
+	t.Run("should keep the writer flushable for streaming", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			flusher, ok := w.(http.Flusher)
+			require.True(t, ok)
+			_, _ = w.Write([]byte("data: chunk\n\n"))
+			flusher.Flush()
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		rec := httptest.NewRecorder()
+
+		middleware.AccessLog()(handler).ServeHTTP(rec, req)
+
+		require.True(t, rec.Flushed)
+		require.Equal(t, "data: chunk\n\n", rec.Body.String())
+	})
+
+	t.Run("should flush each chunk over h2c", func(t *testing.T) {
+		release := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, 2, r.ProtoMajor)
+			_, _ = w.Write([]byte("data: first\n\n"))
+			require.NoError(t, http.NewResponseController(w).Flush())
+			<-release
+		})
+
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		server := httptest.NewUnstartedServer(middleware.AccessLog()(handler))
+		server.Config.Protocols = protocols
+		server.Start()
+		defer server.Close()
+
+		client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"/v1/completions", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// The chunk arrives while the handler is still running.
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		close(release)
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.ProtoMajor)
+		require.Equal(t, "data: first\n", line)
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// authenticatedPrefix is the path prefix of the client-facing API.
+// Admin endpoints carry their own token and health/metrics stay open.
+const authenticatedPrefix = "/v1/"
+
+// Auth creates a middleware that authenticates client API keys: the configured keys
+// and, when virtualKeys is set, unexpired keys issued through the admin API.
+// When neither is configured the API stays open and requests are anonymous.
+// Authenticated requests carry the key name in their context; the secret itself is never logged.
+func Auth(cfg *config.AuthConfig, virtualKeys *domain.VirtualKeys) Middleware {
+	// Keys are looked up by digest so secrets are not compared byte by byte.
+	names := make(map[[sha256.Size]byte]string, len(cfg.ClientKeys))
+	for name, secret := range cfg.ClientKeys {
+		names[sha256.Sum256([]byte(secret))] = name
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(names) == 0 && virtualKeys == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, authenticatedPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			name, ok := names[sha256.Sum256([]byte(token))]
+			if !ok && virtualKeys != nil && strings.HasPrefix(token, domain.VirtualKeyPrefix) {
+				name, ok = virtualKeys.Authenticate(token)
+			}
+			if !found || !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				apierror.Write(r.Context(), w, http.StatusUnauthorized, apierror.TypeUnauthorized,
+					"invalid or missing API key", nil)
+				return
+			}
+
+			ctx := observability.WithClientKey(r.Context(), name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
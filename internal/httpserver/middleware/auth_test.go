@@ -0,0 +1,129 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestAuth(t *testing.T) {
+	// writeKeyName echoes the authenticated key name so tests can verify attribution.
+	writeKeyName := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(observability.GetClientKey(r.Context())))
+	})
+
+	tests := []struct {
+		name          string
+		keys          map[string]string
+		path          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{
+			name:       "should allow anonymous requests when no keys are configured",
+			keys:       nil,
+			path:       "/v1/completions",
+			wantStatus: http.StatusOK,
+			wantBody:   "",
+		},
+		{
+			name:          "should attribute request to the matching key name",
+			keys:          map[string]string{"mobile": "sk-mobile", "batch": "sk-batch"},
+			path:          "/v1/completions",
+			authorization: "Bearer sk-batch",
+			wantStatus:    http.StatusOK,
+			wantBody:      "batch",
+		},
+		{
+			name:          "should reject unknown key with 401",
+			keys:          map[string]string{"mobile": "sk-mobile"},
+			path:          "/v1/completions",
+			authorization: "Bearer sk-other",
+			wantStatus:    http.StatusUnauthorized,
+			wantBody:      "invalid or missing API key",
+		},
+		{
+			name:       "should reject missing key with 401",
+			keys:       map[string]string{"mobile": "sk-mobile"},
+			path:       "/v1/completions",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "invalid or missing API key",
+		},
+		{
+			name:       "should leave non-client endpoints open",
+			keys:       map[string]string{"mobile": "sk-mobile"},
+			path:       "/health",
+			wantStatus: http.StatusOK,
+			wantBody:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.Auth(&config.AuthConfig{ClientKeys: tt.keys}, nil)(writeKeyName)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestAuth_VirtualKeys(t *testing.T) {
+	writeKeyName := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(observability.GetClientKey(r.Context())))
+	})
+
+	virtualKeys, err := domain.NewVirtualKeys(context.Background(), nil, nil, nil)
+	require.NoError(t, err)
+	secret, _, err := virtualKeys.Issue(context.Background(), domain.VirtualKey{Name: "ci-bot"})
+	require.NoError(t, err)
+
+	handler := middleware.Auth(&config.AuthConfig{ClientKeys: map[string]string{"mobile": "sk-mobile"}}, virtualKeys)
+
+	t.Run("should attribute requests to the issued key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+
+		handler(writeKeyName).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "ci-bot", rec.Body.String())
+	})
+
+	t.Run("should still accept configured keys", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req.Header.Set("Authorization", "Bearer sk-mobile")
+		rec := httptest.NewRecorder()
+
+		handler(writeKeyName).ServeHTTP(rec, req)
+
+		require.Equal(t, "mobile", rec.Body.String())
+	})
+
+	t.Run("should require a key when only virtual keys are enabled", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		rec := httptest.NewRecorder()
+
+		middleware.Auth(&config.AuthConfig{ClientKeys: nil}, virtualKeys)(writeKeyName).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
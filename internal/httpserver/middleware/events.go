@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// apiPathPrefix selects the gateway API requests that publish events.
+const apiPathPrefix = "/v1/"
+
+// Events creates a middleware that publishes request lifecycle events for API
+// requests: request.started before the handler runs, then request.completed or
+// request.failed, plus cache.hit or cache.miss when the idempotency cache was consulted.
+// It must run inside Tenant so events carry the tenant and client key.
+// A nil publisher disables the middleware.
+func Events(publisher domain.EventPublisher) Middleware {
+	return func(next http.Handler) http.Handler {
+		if publisher == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			summary := observability.GetRequestSummary(ctx)
+			if summary == nil {
+				ctx, summary = observability.WithRequestSummary(ctx)
+			}
+
+			start := time.Now()
+			publisher.Publish(ctx, newEvent(ctx, r, domain.EventRequestStarted))
+
+			writer := &statusWriter{ResponseWriter: w, status: 0, bytes: 0}
+			next.ServeHTTP(writer, r.WithContext(ctx))
+
+			status := writer.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			switch summary.CacheStatus() {
+			case cacheStatusHit:
+				publisher.Publish(ctx, newEvent(ctx, r, domain.EventCacheHit))
+			case cacheStatusMiss:
+				publisher.Publish(ctx, newEvent(ctx, r, domain.EventCacheMiss))
+			}
+
+			eventType := domain.EventRequestCompleted
+			if status >= http.StatusBadRequest {
+				eventType = domain.EventRequestFailed
+			}
+			event := newEvent(ctx, r, eventType)
+			event.Model = summary.Model()
+			event.Provider = summary.Provider()
+			event.Status = status
+			event.Duration = float64(time.Since(start)) / float64(time.Millisecond)
+			publisher.Publish(ctx, event)
+		})
+	}
+}
+
+// newEvent creates an event of eventType carrying the request's identity.
+func newEvent(ctx context.Context, r *http.Request, eventType string) domain.Event {
+	return domain.Event{
+		Type:      eventType,
+		Time:      time.Now().UTC(),
+		RequestID: observability.GetRequestID(ctx),
+		Tenant:    observability.GetTenant(ctx),
+		ClientKey: observability.GetClientKey(ctx),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Model:     "",
+		Provider:  "",
+		Status:    0,
+		Duration:  0,
+
+		FallbackProvider: "",
+		FallbackModel:    "",
+		Error:            "",
+	}
+}
@@ -0,0 +1,102 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// recordingPublisher collects published events.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event domain.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	types := make([]string, 0, len(p.events))
+	for _, event := range p.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("should publish started and completed events for API requests", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			observability.RecordModel(r.Context(), "gpt-4")
+			observability.RecordProvider(r.Context(), "openai")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req = req.WithContext(observability.WithTenant(req.Context(), "acme"))
+		rec := httptest.NewRecorder()
+
+		middleware.Events(publisher)(handler).ServeHTTP(rec, req)
+
+		require.Equal(t, []string{domain.EventRequestStarted, domain.EventRequestCompleted}, publisher.types())
+		completed := publisher.events[1]
+		require.Equal(t, "acme", completed.Tenant)
+		require.Equal(t, "gpt-4", completed.Model)
+		require.Equal(t, "openai", completed.Provider)
+		require.Equal(t, http.StatusOK, completed.Status)
+	})
+
+	t.Run("should publish a failed event for error responses", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		middleware.Events(publisher)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Equal(t, []string{domain.EventRequestStarted, domain.EventRequestFailed}, publisher.types())
+		require.Equal(t, http.StatusBadGateway, publisher.events[1].Status)
+	})
+
+	t.Run("should publish cache events recorded by the handler chain", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			observability.RecordCacheStatus(r.Context(), "hit")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		middleware.Events(publisher)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Equal(t,
+			[]string{domain.EventRequestStarted, domain.EventCacheHit, domain.EventRequestCompleted},
+			publisher.types(),
+		)
+	})
+
+	t.Run("should ignore non-API paths", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		middleware.Events(publisher)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Empty(t, publisher.types())
+	})
+}
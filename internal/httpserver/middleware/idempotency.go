@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// IdempotencyKeyHeader is the client-supplied header identifying a logical request.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader marks responses served from the idempotency store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// cacheStatusHit and cacheStatusMiss record whether a response was replayed.
+	cacheStatusHit  = "hit"
+	cacheStatusMiss = "miss"
+
+	// skipReasonPartial and skipReasonTooLarge label responses not stored for replay.
+	skipReasonPartial  = "partial"
+	skipReasonTooLarge = "too_large"
+)
+
+// recordingWriter forwards writes to the client while capturing them for replay.
+// Once the captured body would exceed limit (when positive), capturing stops and
+// the buffer is dropped, while writes keep reaching the client.
+type recordingWriter struct {
+	http.ResponseWriter
+
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	tooLarge bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.tooLarge {
+		if w.limit > 0 && int64(w.body.Len()+len(data)) > w.limit {
+			w.tooLarge = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data) //nolint:wrapcheck // Transparent writer passthrough
+}
+
+// Flush keeps SSE streaming working through the recorder.
+func (w *recordingWriter) Flush() {
+	// The controller finds the flusher through further wrappers, under HTTP/1.1 and HTTP/2 alike.
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Idempotency creates a middleware honoring the Idempotency-Key header.
+// Successful responses are stored for the configured TTL and replayed on duplicate
+// submissions, so client retries after network errors are not charged twice.
+// Partial responses, such as streams that failed midway, and responses larger
+// than the configured maximum are never stored.
+// A duplicate arriving while the original is in flight gets 409, and reusing a key
+// with a different request body gets 422.
+func Idempotency(store *IdempotencyStore) Middleware {
+	if store == nil {
+		// Return no-op middleware if disabled.
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				apierror.Write(r.Context(), w, http.StatusBadRequest, apierror.TypeInvalidRequest,
+					"idempotency key is too long", nil)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				apierror.Write(r.Context(), w, http.StatusBadRequest, apierror.TypeInvalidRequest,
+					"failed to read request body", nil)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are scoped per tenant, caller, and route; the body fingerprint detects key reuse.
+			storeKey := hashParts(observability.GetTenant(r.Context()), r.Header.Get("Authorization"), r.URL.Path, key)
+			fingerprint := hashParts(string(body))
+
+			logger := observability.FromContext(r.Context())
+
+			entry, token := store.reserve(storeKey, fingerprint, time.Now())
+			if entry != nil {
+				switch {
+				case entry.fingerprint != fingerprint:
+					apierror.Write(r.Context(), w, http.StatusUnprocessableEntity, apierror.TypeInvalidRequest,
+						"idempotency key reused with a different request body", nil)
+				case entry.inFlight:
+					apierror.Write(r.Context(), w, http.StatusConflict, apierror.TypeConflict,
+						"a request with this idempotency key is in progress", nil)
+				default:
+					logger.Info("replaying idempotent response")
+					observability.RecordCacheStatus(r.Context(), cacheStatusHit)
+					replay(w, entry)
+				}
+				return
+			}
+
+			// The reservation is released unless the response is stored, even when the
+			// handler panics, so a failed attempt never leaves the key in flight.
+			stored := false
+			defer func() {
+				if !stored {
+					store.release(storeKey, token)
+				}
+			}()
+
+			ctx := r.Context()
+			summary := observability.GetRequestSummary(ctx)
+			if summary == nil {
+				ctx, summary = observability.WithRequestSummary(ctx)
+			}
+
+			observability.RecordCacheStatus(ctx, cacheStatusMiss)
+			recorder := &recordingWriter{
+				ResponseWriter: w,
+				status:         0,
+				body:           bytes.Buffer{},
+				limit:          store.maxResponseBytes,
+				tooLarge:       false,
+			}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			if recorder.status < http.StatusOK || recorder.status >= http.StatusMultipleChoices {
+				return
+			}
+			if summary.Partial() {
+				logger.Info("not storing partial idempotent response")
+				observability.IdempotencySkipped.WithLabelValues(skipReasonPartial).Inc()
+				return
+			}
+			if recorder.tooLarge {
+				logger.Info("not storing oversized idempotent response")
+				observability.IdempotencySkipped.WithLabelValues(skipReasonTooLarge).Inc()
+				return
+			}
+			store.complete(storeKey, token, recorder.status, w.Header().Clone(), recorder.body.Bytes(), time.Now())
+			stored = true
+		})
+	}
+}
+
+// replay writes a stored response to the client.
+// Headers already set for this request (e.g. X-Request-Id) are kept.
+func replay(w http.ResponseWriter, entry *storedResponse) {
+	for name, values := range entry.header {
+		if w.Header().Get(name) != "" {
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(entry.status)
+	_, _ = w.Write(entry.body)
+}
+
+// hashParts returns a hex SHA-256 over the given parts.
+func hashParts(parts ...string) string {
+	hasher := sha256.New()
+	for _, part := range parts {
+		hasher.Write([]byte(part))
+		hasher.Write([]byte{0})
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
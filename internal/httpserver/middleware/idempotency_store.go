@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// storedResponse is a completed response kept for replay, or the reservation
+// of a request still in flight.
+type storedResponse struct {
+	key         string
+	fingerprint string
+	token       uint64 // Identifies the reservation, so only its request completes or releases it
+	status      int
+	header      http.Header
+	body        []byte
+	size        int64
+	expiresAt   time.Time // Unset while in flight; in-flight entries never expire
+	inFlight    bool
+}
+
+// IdempotencyStore keeps completed responses in memory for a short TTL.
+// Entries are kept in LRU order so the memory budget evicts the least
+// recently used responses first.
+type IdempotencyStore struct {
+	mu                 sync.Mutex
+	ttl                time.Duration
+	maxBytes           int64
+	maxResponseBytes   int64
+	compactionInterval time.Duration
+	entries            map[string]*list.Element
+	lru                *list.List // Front is most recently used
+	totalBytes         int64
+	lastToken          uint64
+}
+
+// NewIdempotencyStore creates the response store (DI constructor).
+// It returns nil when idempotency is disabled.
+func NewIdempotencyStore(cfg *config.IdempotencyConfig) *IdempotencyStore {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	return &IdempotencyStore{
+		mu:                 sync.Mutex{},
+		ttl:                time.Duration(cfg.TTL) * time.Second,
+		maxBytes:           cfg.MaxBytes,
+		maxResponseBytes:   cfg.MaxResponseBytes,
+		compactionInterval: time.Duration(cfg.CompactionInterval) * time.Second,
+		entries:            make(map[string]*list.Element),
+		lru:                list.New(),
+		totalBytes:         0,
+		lastToken:          0,
+	}
+}
+
+// reserve returns the stored or in-flight entry for key. Otherwise it marks key
+// as in flight and returns nil with the token that completes or releases it.
+func (s *IdempotencyStore) reserve(key, fingerprint string, now time.Time) (*storedResponse, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.entries[key]; exists {
+		entry := elem.Value.(*storedResponse) //nolint:errcheck,forcetypeassert // List only holds entries
+		if entry.inFlight || now.Before(entry.expiresAt) {
+			s.lru.MoveToFront(elem)
+			return entry, 0
+		}
+		s.remove(elem)
+	}
+
+	s.lastToken++
+	s.entries[key] = s.lru.PushFront(&storedResponse{
+		key:         key,
+		fingerprint: fingerprint,
+		token:       s.lastToken,
+		status:      0,
+		header:      nil,
+		body:        nil,
+		size:        0,
+		expiresAt:   time.Time{},
+		inFlight:    true,
+	})
+	s.reportSize()
+	return nil, s.lastToken
+}
+
+// reservation returns the in-flight entry for key held by token. Caller must hold the lock.
+func (s *IdempotencyStore) reservation(key string, token uint64) (*list.Element, *storedResponse) {
+	elem, exists := s.entries[key]
+	if !exists {
+		return nil, nil
+	}
+	entry := elem.Value.(*storedResponse) //nolint:errcheck,forcetypeassert // List only holds entries
+	if !entry.inFlight || entry.token != token {
+		return nil, nil
+	}
+	return elem, entry
+}
+
+// complete stores the final response for the reservation held by token and
+// enforces the memory budget.
+func (s *IdempotencyStore) complete(
+	key string, token uint64, status int, header http.Header, body []byte, now time.Time,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, entry := s.reservation(key, token)
+	if entry == nil {
+		return
+	}
+
+	entry.status = status
+	entry.header = header
+	entry.body = body
+	entry.size = responseSize(key, header, body)
+	entry.expiresAt = now.Add(s.ttl)
+	entry.inFlight = false
+	s.totalBytes += entry.size
+	s.lru.MoveToFront(elem)
+
+	s.evictOverBudget()
+	s.reportSize()
+}
+
+// release forgets the reservation held by token so the client can retry, e.g.
+// after a failed attempt.
+func (s *IdempotencyStore) release(key string, token uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, _ := s.reservation(key, token); elem != nil {
+		s.remove(elem)
+		s.reportSize()
+	}
+}
+
+// Compact removes expired entries and enforces the memory budget. Entries of
+// requests still in flight are kept, however long the request runs.
+// It returns the number of entries and bytes retained.
+func (s *IdempotencyStore) Compact(now time.Time) (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for elem := s.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		entry := elem.Value.(*storedResponse) //nolint:errcheck,forcetypeassert // List only holds entries
+		if !entry.inFlight && !now.Before(entry.expiresAt) {
+			s.remove(elem)
+			observability.IdempotencyEvictions.WithLabelValues("expired").Inc()
+		}
+		elem = prev
+	}
+
+	s.evictOverBudget()
+	s.reportSize()
+
+	return len(s.entries), s.totalBytes
+}
+
+// RunCompaction compacts the store on the configured interval until ctx is done.
+func (s *IdempotencyStore) RunCompaction(ctx context.Context) {
+	if s.compactionInterval <= 0 {
+		return
+	}
+
+	logger := observability.FromContext(ctx)
+	ticker := time.NewTicker(s.compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			entries, bytes := s.Compact(now)
+			logger.Debug("idempotency store compacted",
+				observability.Int("entries", entries),
+				observability.Int64("bytes", bytes),
+			)
+		}
+	}
+}
+
+// evictOverBudget drops least recently used completed entries until the store
+// fits the memory budget. Caller must hold the lock.
+func (s *IdempotencyStore) evictOverBudget() {
+	if s.maxBytes <= 0 {
+		return
+	}
+
+	for elem := s.lru.Back(); elem != nil && s.totalBytes > s.maxBytes; {
+		prev := elem.Prev()
+		entry := elem.Value.(*storedResponse) //nolint:errcheck,forcetypeassert // List only holds entries
+		if !entry.inFlight {
+			s.remove(elem)
+			observability.IdempotencyEvictions.WithLabelValues("memory").Inc()
+		}
+		elem = prev
+	}
+}
+
+// remove deletes an entry. Caller must hold the lock.
+func (s *IdempotencyStore) remove(elem *list.Element) {
+	entry := elem.Value.(*storedResponse) //nolint:errcheck,forcetypeassert // List only holds entries
+	s.lru.Remove(elem)
+	delete(s.entries, entry.key)
+	s.totalBytes -= entry.size
+}
+
+// reportSize publishes the store size metrics. Caller must hold the lock.
+func (s *IdempotencyStore) reportSize() {
+	observability.IdempotencyStoreEntries.Set(float64(len(s.entries)))
+	observability.IdempotencyStoreBytes.Set(float64(s.totalBytes))
+}
+
+// responseSize approximates the memory held by a stored response.
+func responseSize(key string, header http.Header, body []byte) int64 {
+	size := len(key) + len(body)
+	for name, values := range header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return int64(size)
+}
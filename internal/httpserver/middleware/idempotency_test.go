@@ -0,0 +1,217 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestIdempotency(t *testing.T) {
+	cfg := &config.IdempotencyConfig{Enabled: true, TTL: 60}
+
+	newHandler := func(status int) (http.Handler, *atomic.Int32) {
+		calls := &atomic.Int32{}
+		next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"content":"hello"}`))
+		})
+		return middleware.Idempotency(middleware.NewIdempotencyStore(cfg))(next), calls
+	}
+
+	send := func(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should replay stored response for duplicate key", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusOK)
+
+		first := send(handler, "key-1", `{"model":"echo4"}`)
+		second := send(handler, "key-1", `{"model":"echo4"}`)
+
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, http.StatusOK, second.Code)
+		require.Equal(t, first.Body.String(), second.Body.String())
+		require.Equal(t, "true", second.Header().Get(middleware.IdempotentReplayedHeader))
+		require.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	})
+
+	t.Run("should reject key reuse with different body", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusOK)
+
+		send(handler, "key-2", `{"model":"echo4"}`)
+		second := send(handler, "key-2", `{"model":"gpt-4"}`)
+
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	})
+
+	t.Run("should not store failed responses", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusInternalServerError)
+
+		send(handler, "key-3", `{"model":"echo4"}`)
+		send(handler, "key-3", `{"model":"echo4"}`)
+
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("should not store partial responses", func(t *testing.T) {
+		calls := &atomic.Int32{}
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"delta\":\"hel\"}\n\nevent: error\ndata: {}\n\n"))
+			observability.RecordPartial(r.Context())
+		})
+		handler := middleware.Idempotency(middleware.NewIdempotencyStore(cfg))(next)
+
+		send(handler, "key-partial", `{"model":"echo4","stream":true}`)
+		rec := send(handler, "key-partial", `{"model":"echo4","stream":true}`)
+
+		require.Equal(t, int32(2), calls.Load())
+		require.Empty(t, rec.Header().Get(middleware.IdempotentReplayedHeader))
+	})
+
+	t.Run("should relay but not store responses over the size limit", func(t *testing.T) {
+		calls := &atomic.Int32{}
+		next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Content-Type", "text/event-stream")
+			for range 4 {
+				_, _ = w.Write([]byte("data: {\"delta\":\"hello\"}\n\n"))
+			}
+		})
+		store := middleware.NewIdempotencyStore(&config.IdempotencyConfig{Enabled: true, TTL: 60, MaxResponseBytes: 64})
+		handler := middleware.Idempotency(store)(next)
+
+		first := send(handler, "key-large", `{"model":"echo4","stream":true}`)
+		second := send(handler, "key-large", `{"model":"echo4","stream":true}`)
+
+		require.Equal(t, int32(2), calls.Load())
+		require.Equal(t, strings.Repeat("data: {\"delta\":\"hello\"}\n\n", 4), first.Body.String())
+		require.Empty(t, second.Header().Get(middleware.IdempotentReplayedHeader))
+		entries, _ := store.Compact(time.Now())
+		require.Zero(t, entries)
+	})
+
+	t.Run("should release the key when the handler panics", func(t *testing.T) {
+		panics := true
+		next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if panics {
+				panic("handler failed")
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		store := middleware.NewIdempotencyStore(cfg)
+		handler := middleware.Idempotency(store)(next)
+
+		require.Panics(t, func() { send(handler, "key-panic", `{"model":"echo4"}`) })
+		entries, _ := store.Compact(time.Now())
+		require.Zero(t, entries)
+
+		panics = false
+		rec := send(handler, "key-panic", `{"model":"echo4"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("should keep the key in flight when the request outlives the TTL", func(t *testing.T) {
+		store := middleware.NewIdempotencyStore(&config.IdempotencyConfig{Enabled: true, TTL: 1})
+		calls := &atomic.Int32{}
+		var handler http.Handler
+		var duplicate *httptest.ResponseRecorder
+		handler = middleware.Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if calls.Add(1) == 1 {
+				// The TTL passes while the first attempt is still running.
+				entries, _ := store.Compact(time.Now().Add(time.Minute))
+				require.Equal(t, 1, entries)
+				duplicate = send(handler, "key-slow", `{"model":"echo4"}`)
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"content":"slow"}`))
+		}))
+
+		first := send(handler, "key-slow", `{"model":"echo4"}`)
+
+		require.Equal(t, http.StatusOK, first.Code)
+		require.Equal(t, http.StatusConflict, duplicate.Code)
+		require.Equal(t, int32(1), calls.Load())
+
+		replayed := send(handler, "key-slow", `{"model":"echo4"}`)
+		require.Equal(t, "true", replayed.Header().Get(middleware.IdempotentReplayedHeader))
+		require.JSONEq(t, `{"content":"slow"}`, replayed.Body.String())
+	})
+
+	t.Run("should pass through requests without key", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusOK)
+
+		send(handler, "", `{"model":"echo4"}`)
+		rec := send(handler, "", `{"model":"echo4"}`)
+
+		require.Equal(t, int32(2), calls.Load())
+		require.Empty(t, rec.Header().Get(middleware.IdempotentReplayedHeader))
+	})
+}
+
+func TestIdempotencyStore_Compact(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	})
+
+	send := func(handler http.Handler, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{}`))
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should remove expired entries", func(t *testing.T) {
+		store := middleware.NewIdempotencyStore(&config.IdempotencyConfig{Enabled: true, TTL: 60})
+		handler := middleware.Idempotency(store)(next)
+
+		send(handler, "key-1")
+		send(handler, "key-2")
+
+		entries, bytes := store.Compact(time.Now())
+		require.Equal(t, 2, entries)
+		require.Positive(t, bytes)
+
+		entries, bytes = store.Compact(time.Now().Add(2 * time.Minute))
+		require.Zero(t, entries)
+		require.Zero(t, bytes)
+	})
+
+	t.Run("should evict least recently used entries over the memory budget", func(t *testing.T) {
+		// Each stored response takes roughly 200 bytes, so only two fit.
+		store := middleware.NewIdempotencyStore(&config.IdempotencyConfig{Enabled: true, TTL: 60, MaxBytes: 500})
+		handler := middleware.Idempotency(store)(next)
+
+		send(handler, "key-1")
+		send(handler, "key-2")
+		send(handler, "key-1") // Replay marks key-1 as recently used.
+		send(handler, "key-3")
+
+		entries, bytes := store.Compact(time.Now())
+		require.Equal(t, 2, entries)
+		require.LessOrEqual(t, bytes, int64(500))
+
+		require.Equal(t, "true", send(handler, "key-1").Header().Get(middleware.IdempotentReplayedHeader))
+		require.Empty(t, send(handler, "key-2").Header().Get(middleware.IdempotentReplayedHeader))
+	})
+}
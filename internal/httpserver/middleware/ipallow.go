@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// forwardedForHeader lists the client and proxy addresses a request passed through.
+const forwardedForHeader = "X-Forwarded-For"
+
+// IPAllowList holds the client networks allowed to reach the gateway and the proxies
+// trusted to report the client address in X-Forwarded-For.
+type IPAllowList struct {
+	allowed []netip.Prefix
+	trusted []netip.Prefix
+}
+
+// NewIPAllowList parses the configured networks (DI constructor). Entries are CIDRs
+// or single addresses. It returns nil when no allow-list is configured.
+func NewIPAllowList(cfg *config.IPAllowConfig) (*IPAllowList, error) {
+	if cfg == nil || len(cfg.AllowedCIDRs) == 0 {
+		return nil, nil //nolint:nilnil // A nil allow-list admits every client
+	}
+
+	allowed, err := parsePrefixes(cfg.AllowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP allow-list: %w", err)
+	}
+	trusted, err := parsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	return &IPAllowList{allowed: allowed, trusted: trusted}, nil
+}
+
+// IPAllow creates a middleware that rejects requests from clients outside the
+// allow-list with 403. The client address is the connection's peer, unless that
+// peer is a trusted proxy: then X-Forwarded-For is walked from the right, skipping
+// trusted proxies, and the first untrusted address is the client.
+// A nil allow-list disables the middleware.
+func IPAllow(allowList *IPAllowList) Middleware {
+	return func(next http.Handler) http.Handler {
+		if allowList == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := allowList.clientAddr(r)
+			if !ok || !contains(allowList.allowed, client) {
+				observability.FromContext(r.Context()).Warn("request rejected by IP allow-list",
+					observability.String("client_ip", client.String()),
+				)
+				apierror.Write(r.Context(), w, http.StatusForbidden, apierror.TypeForbidden,
+					"client address not allowed", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr resolves the client address of r.
+func (l *IPAllowList) clientAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	client, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	client = client.Unmap()
+
+	if !contains(l.trusted, client) {
+		return client, true
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values(forwardedForHeader), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// A malformed hop cannot be attributed; fail closed.
+			return netip.Addr{}, false
+		}
+		client = addr.Unmap()
+		if !contains(l.trusted, client) {
+			return client, true
+		}
+	}
+
+	// Every hop is a trusted proxy; the request originated inside the proxy tier.
+	return client, true
+}
+
+// parsePrefixes parses CIDRs and single addresses.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// contains reports whether addr falls in any of prefixes.
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+)
+
+func TestIPAllow(t *testing.T) {
+	allowList, err := middleware.NewIPAllowList(&config.IPAllowConfig{
+		AllowedCIDRs:   []string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32"},
+		TrustedProxies: []string{"172.16.0.0/12"},
+	})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		wantStatus   int
+	}{
+		{name: "should allow a client in an allowed network", remoteAddr: "10.1.2.3:5000", wantStatus: http.StatusOK},
+		{name: "should allow a single allowed address", remoteAddr: "192.0.2.7:5000", wantStatus: http.StatusOK},
+		{name: "should allow IPv6 clients", remoteAddr: "[2001:db8::1]:5000", wantStatus: http.StatusOK},
+		{name: "should reject other clients", remoteAddr: "198.51.100.1:5000", wantStatus: http.StatusForbidden},
+		{
+			name:         "should ignore X-Forwarded-For from untrusted peers",
+			remoteAddr:   "198.51.100.1:5000",
+			forwardedFor: []string{"10.1.2.3"},
+			wantStatus:   http.StatusForbidden,
+		},
+		{
+			name:         "should use X-Forwarded-For behind a trusted proxy",
+			remoteAddr:   "172.16.0.5:5000",
+			forwardedFor: []string{"10.1.2.3"},
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "should skip trusted hops and not trust spoofed leftmost entries",
+			remoteAddr:   "172.16.0.5:5000",
+			forwardedFor: []string{"10.1.2.3, 198.51.100.1", "172.16.0.9"},
+			wantStatus:   http.StatusForbidden,
+		},
+		{
+			name:         "should reject malformed X-Forwarded-For behind a trusted proxy",
+			remoteAddr:   "172.16.0.5:5000",
+			forwardedFor: []string{"not-an-ip"},
+			wantStatus:   http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			rec := httptest.NewRecorder()
+
+			middleware.IPAllow(allowList)(ok).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestNewIPAllowList(t *testing.T) {
+	t.Run("should return nil when no networks are configured", func(t *testing.T) {
+		allowList, err := middleware.NewIPAllowList(&config.IPAllowConfig{})
+		require.NoError(t, err)
+		require.Nil(t, allowList)
+	})
+
+	t.Run("should reject invalid entries", func(t *testing.T) {
+		_, err := middleware.NewIPAllowList(&config.IPAllowConfig{AllowedCIDRs: []string{"10.0.0.0/33"}})
+		require.Error(t, err)
+
+		_, err = middleware.NewIPAllowList(&config.IPAllowConfig{
+			AllowedCIDRs:   []string{"10.0.0.0/8"},
+			TrustedProxies: []string{"proxy.internal"},
+		})
+		require.Error(t, err)
+	})
+}
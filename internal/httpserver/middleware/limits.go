@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// limitedPayload is the subset of a request inspected by RequestLimits: chat
+// messages, the legacy completions prompt, and the Responses API input and
+// instructions, each of which becomes prompt messages.
+type limitedPayload struct {
+	Messages     []limitedMessage `json:"messages"`
+	Prompt       json.RawMessage  `json:"prompt"`
+	Input        json.RawMessage  `json:"input"`
+	Instructions string           `json:"instructions"`
+}
+
+// limitedMessage is a message or Responses input item, whose content is a
+// string or an array of text parts.
+type limitedMessage struct {
+	Content json.RawMessage `json:"content"`
+}
+
+// limitedText is one prompt message checked against the length limit, named by
+// the field it came from.
+type limitedText struct {
+	field string
+	text  string
+}
+
+// RequestLimits creates a middleware that rejects oversized request bodies with 413
+// and requests exceeding the message count or per-message length limits with 400.
+// Messages are counted and measured whether sent as chat messages, a legacy
+// prompt, or Responses API input. Bodies that are not valid JSON are passed
+// through for the handler to reject.
+// The size limit counts the bytes actually read, never the Content-Length
+// header, so chunked bodies and understated lengths cannot get past it.
+func RequestLimits(cfg *config.LimitsConfig) Middleware {
+	if cfg == nil {
+		// Return no-op middleware if config is nil.
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if cfg.MaxBodyBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+			}
+			// Bodies of reads are not inspected, but stay bounded for whoever reads them.
+			if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					apierror.Write(r.Context(), w, http.StatusRequestEntityTooLarge, apierror.TypeRequestTooLarge,
+						fmt.Sprintf("request body exceeds limit of %d bytes", maxBytesErr.Limit), nil)
+					return
+				}
+				apierror.Write(r.Context(), w, http.StatusBadRequest, apierror.TypeInvalidRequest,
+					fmt.Sprintf("failed to read request body: %v", err), nil)
+				return
+			}
+
+			if validationErr := validatePayload(cfg, data); validationErr != nil {
+				observability.FromContext(r.Context()).Info("request rejected by limits",
+					observability.Error(validationErr),
+				)
+				apierror.Write(r.Context(), w, http.StatusBadRequest, apierror.TypeInvalidRequest,
+					validationErr.Error(), nil)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validatePayload checks message count and per-message content length.
+func validatePayload(cfg *config.LimitsConfig, data []byte) error {
+	if cfg.MaxMessages <= 0 && cfg.MaxMessageLength <= 0 {
+		return nil
+	}
+
+	var payload limitedPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		//nolint:nilerr // Malformed bodies are reported by the handler with full context
+		return nil
+	}
+
+	texts := payload.texts()
+	if cfg.MaxMessages > 0 && len(texts) > cfg.MaxMessages {
+		return fmt.Errorf("too many messages: %d exceeds limit of %d", len(texts), cfg.MaxMessages)
+	}
+
+	if cfg.MaxMessageLength > 0 {
+		for _, text := range texts {
+			if length := utf8.RuneCountInString(text.text); length > cfg.MaxMessageLength {
+				return fmt.Errorf("%s: length %d exceeds limit of %d", text.field, length, cfg.MaxMessageLength)
+			}
+		}
+	}
+
+	return nil
+}
+
+// texts returns every prompt message of the payload. Shapes the handler rejects
+// yield no text here.
+func (p *limitedPayload) texts() []limitedText {
+	texts := make([]limitedText, 0, len(p.Messages)+1)
+	for i, msg := range p.Messages {
+		field := fmt.Sprintf("messages[%d].content", i)
+		texts = append(texts, limitedText{field: field, text: contentText(msg.Content)})
+	}
+
+	var prompt string
+	var prompts []string
+	switch {
+	case json.Unmarshal(p.Prompt, &prompt) == nil:
+		texts = append(texts, limitedText{field: "prompt", text: prompt})
+	case json.Unmarshal(p.Prompt, &prompts) == nil:
+		for i, prompt := range prompts {
+			texts = append(texts, limitedText{field: fmt.Sprintf("prompt[%d]", i), text: prompt})
+		}
+	}
+
+	if p.Instructions != "" {
+		texts = append(texts, limitedText{field: "instructions", text: p.Instructions})
+	}
+	var input string
+	var items []limitedMessage
+	switch {
+	case json.Unmarshal(p.Input, &input) == nil:
+		texts = append(texts, limitedText{field: "input", text: input})
+	case json.Unmarshal(p.Input, &items) == nil:
+		for i, item := range items {
+			field := fmt.Sprintf("input[%d].content", i)
+			texts = append(texts, limitedText{field: field, text: contentText(item.Content)})
+		}
+	}
+
+	return texts
+}
+
+// contentText joins message content, a string or an array of text parts.
+func contentText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return ""
+	}
+	var joined strings.Builder
+	for _, part := range parts {
+		joined.WriteString(part.Text)
+	}
+	return joined.String()
+}
@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+)
+
+func TestRequestLimits(t *testing.T) {
+	cfg := &config.LimitsConfig{
+		MaxBodyBytes:     128,
+		MaxMessages:      2,
+		MaxMessageLength: 10,
+	}
+
+	// echoBody writes the received body back so tests can verify it was restored.
+	echoBody := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, _ = w.Write(data)
+	})
+	handler := middleware.RequestLimits(cfg)(echoBody)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should pass valid request through with body intact",
+			body:       `{"model":"echo4","messages":[{"role":"user","content":"Hello"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"model":"echo4","messages":[{"role":"user","content":"Hello"}]}`,
+		},
+		{
+			name:       "should reject oversized body with 413",
+			body:       `{"model":"echo4","padding":"` + strings.Repeat("x", 200) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   "exceeds limit of 128 bytes",
+		},
+		{
+			name:       "should reject too many messages with 400",
+			body:       `{"messages":[{"content":"a"},{"content":"b"},{"content":"c"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "too many messages",
+		},
+		{
+			name:       "should reject long message content with 400",
+			body:       `{"messages":[{"content":"ok"},{"content":"this is too long"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "messages[1].content",
+		},
+		{
+			name:       "should count multi-byte characters as single characters",
+			body:       `{"messages":[{"content":"日本語のテキスト"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "日本語のテキスト",
+		},
+		{
+			name: "should reject message content parts that are too long together",
+			body: `{"messages":[{"content":[` +
+				`{"type":"text","text":"this is"},{"type":"text","text":" too long"}]}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "messages[0].content",
+		},
+		{
+			name:       "should reject a long legacy prompt",
+			body:       `{"prompt":"this is too long"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "prompt: length 16",
+		},
+		{
+			name:       "should count each legacy prompt of an array",
+			body:       `{"prompt":["a","b","c"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "too many messages",
+		},
+		{
+			name:       "should reject a long Responses input",
+			body:       `{"input":"this is too long"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "input: length 16",
+		},
+		{
+			name:       "should reject long Responses input item content",
+			body:       `{"input":[{"role":"user","content":[{"type":"input_text","text":"this is too long"}]}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "input[0].content",
+		},
+		{
+			name: "should count Responses instructions as a message",
+			body: `{"instructions":"Be brief",` +
+				`"input":[{"role":"user","content":"a"},{"role":"user","content":"b"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "too many messages: 3",
+		},
+		{
+			name:       "should pass malformed JSON through to the handler",
+			body:       `not json`,
+			wantStatus: http.StatusOK,
+			wantBody:   "not json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+
+	t.Run("should reject oversized bodies whatever their Content-Length", func(t *testing.T) {
+		body := `{"model":"echo4","padding":"` + strings.Repeat("x", 200) + `"}`
+
+		// -1 is an unknown length, as with chunked transfer encoding.
+		for _, contentLength := range []int64{10, -1} {
+			req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(body))
+			req.ContentLength = contentLength
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "Content-Length %d", contentLength)
+		}
+	})
+
+	t.Run("should bound the bodies of GET requests", func(t *testing.T) {
+		readBody := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.ReadAll(r.Body)
+			var maxBytesErr *http.MaxBytesError
+			require.ErrorAs(t, err, &maxBytesErr)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/v1/usage", strings.NewReader(strings.Repeat("x", 200)))
+		rec := httptest.NewRecorder()
+
+		middleware.RequestLimits(cfg)(readBody).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
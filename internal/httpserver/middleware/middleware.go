@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/pkg/plugin"
 )
 
 // Middleware wraps an http.Handler with additional functionality.
@@ -29,10 +31,34 @@ func Chain(middlewares ...Middleware) Middleware {
 }
 
 // BuildMiddlewareChain composes the middleware chain for production.
-// Order matters: CORS -> Trace.
-func BuildMiddlewareChain(corsConfig *config.CORSConfig) Middleware {
-	return Chain(
+// Order matters: CORS -> Trace -> AccessLog -> IPAllow -> Auth -> Tenant -> Priority -> Events -> RequestLimits
+// -> Idempotency, followed by plugin middleware in name order.
+func BuildMiddlewareChain(
+	corsConfig *config.CORSConfig,
+	authConfig *config.AuthConfig,
+	limitsConfig *config.LimitsConfig,
+	idempotencyStore *IdempotencyStore,
+	eventPublisher domain.EventPublisher,
+	tenants *domain.Tenants,
+	virtualKeys *domain.VirtualKeys,
+	ipAllowList *IPAllowList,
+	plugins *plugin.Registry,
+) Middleware {
+	middlewares := []Middleware{
 		CORS(corsConfig),
 		Trace(),
-	)
+		AccessLog(),
+		IPAllow(ipAllowList),
+		Auth(authConfig, virtualKeys),
+		Tenant(tenants),
+		Priority(),
+		Events(eventPublisher),
+		RequestLimits(limitsConfig),
+		Idempotency(idempotencyStore),
+	}
+	for _, extension := range plugins.Middleware() {
+		middlewares = append(middlewares, Middleware(extension))
+	}
+
+	return Chain(middlewares...)
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// PriorityHeader selects the priority class of a request: interactive or batch.
+const PriorityHeader = "X-Calcifer-Priority"
+
+// Priority creates a middleware that records the priority class requested by the
+// PriorityHeader. Requests naming an unknown class are rejected with 400. A key
+// policy may still demote the request to batch.
+func Priority() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			priority, err := domain.ParsePriority(r.Header.Get(PriorityHeader))
+			if err != nil {
+				apierror.Write(r.Context(), w, http.StatusBadRequest, apierror.TypeInvalidRequest, err.Error(), nil)
+				return
+			}
+
+			ctx := observability.WithPriority(r.Context(), string(priority))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestPriority(t *testing.T) {
+	serve := func(header string) (*httptest.ResponseRecorder, string) {
+		var priority string
+		handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			priority = observability.GetPriority(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		if header != "" {
+			req.Header.Set(middleware.PriorityHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		middleware.Priority()(handler).ServeHTTP(rec, req)
+		return rec, priority
+	}
+
+	t.Run("should default to interactive", func(t *testing.T) {
+		_, priority := serve("")
+		require.Equal(t, "interactive", priority)
+	})
+
+	t.Run("should record the requested class", func(t *testing.T) {
+		_, priority := serve("batch")
+		require.Equal(t, "batch", priority)
+	})
+
+	t.Run("should reject unknown classes", func(t *testing.T) {
+		rec, _ := serve("urgent")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should reject with the JSON error envelope", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req = req.WithContext(observability.WithRequestID(req.Context(), "req-1"))
+		req.Header.Set(middleware.PriorityHeader, "urgent")
+		rec := httptest.NewRecorder()
+		middleware.Priority()(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+		var body struct {
+			Error map[string]string `json:"error"`
+		}
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Equal(t, "invalid_request", body.Error["type"])
+		require.Equal(t, "req-1", body.Error["request_id"])
+		require.NotEmpty(t, body.Error["message"])
+	})
+}
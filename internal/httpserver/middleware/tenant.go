@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/apierror"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// TenantHeader carries the tenant a request is attributed to.
+const TenantHeader = "X-Tenant-Id"
+
+// Tenant creates a middleware that attributes every request to a tenant.
+// Client keys that belong to a configured tenant are always attributed to it;
+// other requests go to the default tenant. A header naming a configured tenant
+// the key does not belong to is rejected with 403. Without a tenant directory
+// (tenants is nil), requests use a valid X-Tenant-Id header, or the default
+// tenant. It must run inside Auth so the client key is known.
+func Tenant(tenants *domain.Tenants) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := r.Header.Get(TenantHeader)
+			if !domain.ValidTenant(tenant) {
+				tenant = domain.DefaultTenant
+			}
+
+			if tenants != nil {
+				resolved, err := tenants.Resolve(observability.GetClientKey(r.Context()), tenant)
+				if err != nil {
+					apierror.Write(r.Context(), w, http.StatusForbidden, apierror.TypeForbidden, err.Error(), nil)
+					return
+				}
+				tenant = resolved
+			}
+
+			ctx := observability.WithTenant(r.Context(), tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestTenant(t *testing.T) {
+	tenants := domain.NewTenants([]domain.TenantSettings{{Name: "acme", Keys: []string{"alice"}}})
+
+	serve := func(tenants *domain.Tenants, clientKey, header string) (*httptest.ResponseRecorder, string) {
+		var tenant string
+		handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			tenant = observability.GetTenant(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+		req = req.WithContext(observability.WithClientKey(req.Context(), clientKey))
+		if header != "" {
+			req.Header.Set(middleware.TenantHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		middleware.Tenant(tenants)(handler).ServeHTTP(rec, req)
+		return rec, tenant
+	}
+
+	t.Run("should use the header or the default tenant without a tenant directory", func(t *testing.T) {
+		_, tenant := serve(nil, "", "team-x")
+		require.Equal(t, "team-x", tenant)
+
+		_, tenant = serve(nil, "", "not a tenant!")
+		require.Equal(t, domain.DefaultTenant, tenant)
+	})
+
+	t.Run("should attribute a key to its configured tenant", func(t *testing.T) {
+		_, tenant := serve(tenants, "alice", "team-x")
+		require.Equal(t, "acme", tenant)
+	})
+
+	t.Run("should map unknown tenants to the default tenant with a tenant directory", func(t *testing.T) {
+		_, tenant := serve(tenants, "bob", "team-x")
+		require.Equal(t, domain.DefaultTenant, tenant)
+	})
+
+	t.Run("should reject claiming a tenant the key does not belong to", func(t *testing.T) {
+		rec, _ := serve(tenants, "bob", "acme")
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), `"type":"forbidden"`)
+	})
+}
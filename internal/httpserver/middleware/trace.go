@@ -6,7 +6,15 @@ import (
 	"github.com/davidbz/calcifer/internal/observability"
 )
 
+// RequestIDHeader carries the request ID in both directions: clients may supply
+// one to correlate their logs with the gateway's, and every response echoes it.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
 // Trace creates a middleware that injects trace ID and request ID into every request.
+// A valid client-supplied X-Request-Id is used instead of generating a new one.
 func Trace() Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -18,11 +26,14 @@ func Trace() Middleware {
 			spanID := observability.GenerateSpanID()
 			ctx = observability.WithSpanID(ctx, spanID)
 
-			requestID := observability.GenerateRequestID()
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = observability.GenerateRequestID()
+			}
 			ctx = observability.WithRequestID(ctx, requestID)
 
 			w.Header().Set("X-Trace-Id", traceID)
-			w.Header().Set("X-Request-Id", requestID)
+			w.Header().Set(RequestIDHeader, requestID)
 
 			contextLogger := observability.FromContext(ctx)
 			contextLogger.Info("request started",
@@ -35,3 +46,21 @@ func Trace() Middleware {
 		})
 	}
 }
+
+// validRequestID accepts 1-128 characters of letters, digits, and "-_.:", which
+// covers UUIDs and common trace formats while keeping log lines safe.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestTrace(t *testing.T) {
+	// writeRequestID echoes the request ID seen by the handler.
+	writeRequestID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(observability.GetRequestID(r.Context())))
+	})
+
+	tests := []struct {
+		name     string
+		supplied string
+		wantEcho bool
+	}{
+		{name: "should use a valid client request ID", supplied: "client-7f3a:batch.42", wantEcho: true},
+		{name: "should generate an ID when none is supplied", supplied: "", wantEcho: false},
+		{name: "should replace an ID with invalid characters", supplied: "bad id\n", wantEcho: false},
+		{name: "should replace an overlong ID", supplied: strings.Repeat("a", 129), wantEcho: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/completions", nil)
+			if tt.supplied != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.supplied)
+			}
+			rec := httptest.NewRecorder()
+
+			middleware.Trace()(writeRequestID).ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(middleware.RequestIDHeader)
+			require.NotEmpty(t, requestID)
+			require.Equal(t, requestID, rec.Body.String())
+			if tt.wantEcho {
+				require.Equal(t, tt.supplied, requestID)
+			} else {
+				require.NotEqual(t, tt.supplied, requestID)
+			}
+		})
+	}
+}
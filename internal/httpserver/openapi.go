@@ -0,0 +1,402 @@
+package httpserver
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/httpserver/middleware"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/openapi"
+)
+
+const (
+	// apiVersion is the version of the public API the document describes.
+	apiVersion = "v1"
+
+	// clientKeyScheme names the client API key security scheme.
+	clientKeyScheme = "clientKey"
+
+	// authenticatedPrefix is the path prefix of routes requiring a client API key
+	// when client authentication is enabled.
+	authenticatedPrefix = "/v1/"
+)
+
+// docsFiles holds the Swagger UI page rendering GET /openapi.json.
+//
+//go:embed docs
+var docsFiles embed.FS
+
+//nolint:gochecknoglobals // Immutable pattern
+var pathParameter = regexp.MustCompile(`\{(\w+)\}`)
+
+// route is a public endpoint with the operations it serves; the OpenAPI document
+// is generated from the same routes the server registers.
+type route struct {
+	pattern    string
+	handler    http.Handler
+	operations []operation
+}
+
+// operation documents one method of a route.
+type operation struct {
+	method      string
+	id          string
+	tag         string
+	summary     string
+	description string
+	parameters  []openapi.Parameter
+	request     reflect.Type // decoded JSON body, nil without one
+	status      int
+	response    reflect.Type // encoded JSON body, nil without one
+	events      reflect.Type // data of the server-sent events streamed instead, if the request asks
+	media       string       // media type of a non-JSON response body
+}
+
+// newOperation starts documenting method, answering 200 without a body.
+func newOperation(method, id, tag, summary string) operation {
+	return operation{
+		method:      method,
+		id:          id,
+		tag:         tag,
+		summary:     summary,
+		description: "",
+		parameters:  nil,
+		request:     nil,
+		status:      http.StatusOK,
+		response:    nil,
+		events:      nil,
+		media:       "",
+	}
+}
+
+// describe adds a description to the operation.
+func (o operation) describe(description string) operation {
+	o.description = description
+	return o
+}
+
+// with adds parameters to the operation.
+func (o operation) with(parameters ...openapi.Parameter) operation {
+	o.parameters = append(o.parameters, parameters...)
+	return o
+}
+
+// accepts sets the JSON request body of the operation.
+func (o operation) accepts(request reflect.Type) operation {
+	o.request = request
+	return o
+}
+
+// returns sets the success status and JSON response body of the operation.
+func (o operation) returns(status int, response reflect.Type) operation {
+	o.status = status
+	o.response = response
+	return o
+}
+
+// streams documents the server-sent events answering streaming requests.
+func (o operation) streams(events reflect.Type) operation {
+	o.events = events
+	return o
+}
+
+// serves sets the media type of a non-JSON response body.
+func (o operation) serves(media string) operation {
+	o.media = media
+	return o
+}
+
+// routes returns the public endpoints.
+func (s *Server) routes() []route {
+	h := s.handler
+	completionHeaders := []openapi.Parameter{
+		header(ProviderHeader, "Forces a registered provider instead of routing by model"),
+		header(DryRunHeader, `"true" reports the routing and estimated cost without calling the provider`),
+		header(middleware.IdempotencyKeyHeader, "Replays the stored response of a repeated request"),
+		header(middleware.PriorityHeader, "Queue priority under concurrency limits: interactive or batch"),
+		header(middleware.TenantHeader, "Tenant the request is attributed and rate limited to"),
+	}
+
+	return []route{
+		{
+			pattern: "/v1/completions",
+			handler: http.HandlerFunc(h.HandleCompletion),
+			operations: []operation{
+				newOperation(http.MethodPost, "createCompletion", "completions", "Create a chat completion").
+					describe("Routes the request by model, or to the X-Provider provider. "+
+						"Legacy text completion requests with a prompt are accepted too.").
+					with(completionHeaders...).
+					accepts(reflect.TypeFor[completionBody]()).
+					returns(http.StatusOK, reflect.TypeFor[domain.CompletionResponse]()).
+					streams(reflect.TypeFor[domain.StreamChunk]()),
+			},
+		},
+		{
+			pattern: "/v1/responses",
+			handler: http.HandlerFunc(h.HandleResponses),
+			operations: []operation{
+				newOperation(http.MethodPost, "createResponse", "completions", "Create a response").
+					describe("Serves the OpenAI Responses API by translating requests to chat completions. "+
+						"Streaming requests receive Responses API events, from response.created to "+
+						"response.completed or response.failed.").
+					with(completionHeaders...).
+					accepts(reflect.TypeFor[responsesRequest]()).
+					returns(http.StatusOK, reflect.TypeFor[responsesResponse]()).
+					streams(reflect.TypeFor[map[string]any]()),
+			},
+		},
+		{
+			pattern: "/v1/conversations",
+			handler: http.HandlerFunc(h.HandleConversations),
+			operations: []operation{
+				newOperation(http.MethodPost, "createConversation", "conversations", "Create a conversation").
+					describe("Starts a conversation whose history the gateway keeps.").
+					accepts(reflect.TypeFor[conversationRequest]()).
+					returns(http.StatusCreated, reflect.TypeFor[domain.Conversation]()),
+			},
+		},
+		{
+			pattern: "/v1/conversations/{id}",
+			handler: http.HandlerFunc(h.HandleConversation),
+			operations: []operation{
+				newOperation(http.MethodGet, "getConversation", "conversations", "Get a conversation").
+					returns(http.StatusOK, reflect.TypeFor[domain.Conversation]()),
+				newOperation(http.MethodDelete, "deleteConversation", "conversations", "Delete a conversation").
+					returns(http.StatusNoContent, nil),
+			},
+		},
+		{
+			pattern: "/v1/conversations/{id}/messages",
+			handler: http.HandlerFunc(h.HandleConversationMessages),
+			operations: []operation{
+				newOperation(http.MethodPost, "sendConversationMessage", "conversations", "Send a conversation turn").
+					describe("Sends the new messages after the stored history, using the conversation model "+
+						"when the request names none.").
+					accepts(reflect.TypeFor[domain.CompletionRequest]()).
+					returns(http.StatusOK, reflect.TypeFor[domain.CompletionResponse]()).
+					streams(reflect.TypeFor[domain.StreamChunk]()),
+			},
+		},
+		{
+			pattern: "/v1/ensemble",
+			handler: http.HandlerFunc(h.HandleEnsemble),
+			operations: []operation{
+				newOperation(http.MethodPost, "createEnsemble", "completions", "Create an ensemble completion").
+					describe("Asks several models the same question; a judge model, if set, synthesizes a "+
+						"final answer. 404 unless ensemble mode is enabled.").
+					accepts(reflect.TypeFor[domain.EnsembleRequest]()).
+					returns(http.StatusOK, reflect.TypeFor[domain.EnsembleResponse]()),
+			},
+		},
+		{
+			pattern: "/v1/moderations",
+			handler: http.HandlerFunc(h.HandleModeration),
+			operations: []operation{
+				newOperation(http.MethodPost, "createModeration", "moderations", "Classify content").
+					describe("input is a string or an array of strings.").
+					accepts(reflect.TypeFor[moderationRequest]()).
+					returns(http.StatusOK, reflect.TypeFor[domain.ModerationResponse]()),
+			},
+		},
+		{
+			pattern: "/v1/audio/speech",
+			handler: http.HandlerFunc(h.HandleSpeech),
+			operations: []operation{
+				newOperation(http.MethodPost, "createSpeech", "audio", "Synthesize speech").
+					describe("Streams the audio as the provider synthesizes it.").
+					with(header(ProviderHeader, "Forces a registered provider instead of routing by model")).
+					accepts(reflect.TypeFor[domain.SpeechRequest]()).
+					serves(defaultAudioContentType),
+			},
+		},
+		{
+			pattern: "/v1/usage",
+			handler: http.HandlerFunc(h.HandleUsage),
+			operations: []operation{
+				newOperation(http.MethodGet, "getUsage", "usage", "Report usage").
+					describe("Authenticated clients only see their own usage. Without client authentication, "+
+						"the report covers every client and requires the admin token.").
+					with(
+						query("group_by", "model (default), provider, tenant, key, day, or tag:<name>"),
+						query("from", "RFC 3339 start, inclusive"),
+						query("to", "RFC 3339 end, exclusive"),
+					).
+					returns(http.StatusOK, reflect.TypeFor[usageResponse]()),
+			},
+		},
+		{
+			pattern: "/v1/models",
+			handler: http.HandlerFunc(h.HandleModels),
+			operations: []operation{
+				newOperation(http.MethodGet, "listModels", "models", "List models").
+					describe("Lists the models clients can request, including configured aliases.").
+					returns(http.StatusOK, reflect.TypeFor[modelsResponse]()),
+			},
+		},
+		{
+			pattern: "/health",
+			handler: http.HandlerFunc(h.HandleHealth),
+			operations: []operation{
+				newOperation(http.MethodGet, "getHealth", "operations", "Check health").
+					returns(http.StatusOK, reflect.TypeFor[healthResponse]()),
+			},
+		},
+		{
+			pattern: "/metrics",
+			handler: observability.MetricsHandler(),
+			operations: []operation{
+				newOperation(http.MethodGet, "getMetrics", "operations", "Export Prometheus metrics").
+					serves("text/plain"),
+			},
+		},
+	}
+}
+
+// openAPIDocument generates the OpenAPI document of routes.
+func openAPIDocument(routes []route) *openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:       "Calcifer",
+		Version:     apiVersion,
+		Description: "LLM gateway routing chat completions to providers, with usage and cost tracking.",
+	})
+	builder.SecurityScheme(clientKeyScheme, &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Client API key, required when client authentication is enabled",
+	})
+	errorSchema := builder.Schema(reflect.TypeFor[errorEnvelope]())
+
+	for _, route := range routes {
+		for _, op := range route.operations {
+			builder.Add(op.method, route.pattern, op.document(builder, route.pattern, errorSchema))
+		}
+	}
+	return builder.Document()
+}
+
+// document returns the OpenAPI operation of o on the route pattern.
+func (o operation) document(builder *openapi.Builder, pattern string, errorSchema *openapi.Schema) *openapi.Operation {
+	parameters := make([]openapi.Parameter, 0, len(o.parameters))
+	for _, match := range pathParameter.FindAllStringSubmatch(pattern, -1) {
+		parameters = append(parameters, openapi.Parameter{
+			Name:        match[1],
+			In:          "path",
+			Description: "",
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string"},
+		})
+	}
+	parameters = append(parameters, o.parameters...)
+
+	var requestBody *openapi.RequestBody
+	if o.request != nil {
+		requestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: builder.Schema(o.request)}},
+		}
+	}
+
+	success := &openapi.Response{Description: http.StatusText(o.status), Content: nil}
+	if o.response != nil || o.events != nil || o.media != "" {
+		success.Content = make(map[string]openapi.MediaType)
+	}
+	if o.response != nil {
+		success.Content["application/json"] = openapi.MediaType{Schema: builder.Schema(o.response)}
+	}
+	if o.events != nil {
+		// Each event's data is one JSON chunk; failures end the stream with an error event.
+		success.Content["text/event-stream"] = openapi.MediaType{Schema: builder.Schema(o.events)}
+	}
+	if o.media != "" {
+		success.Content[o.media] = openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}
+	}
+
+	var security []map[string][]string
+	if strings.HasPrefix(pattern, authenticatedPrefix) {
+		security = []map[string][]string{{clientKeyScheme: {}}}
+	}
+
+	return &openapi.Operation{
+		OperationID: o.id,
+		Summary:     o.summary,
+		Description: o.description,
+		Tags:        []string{o.tag},
+		Parameters:  parameters,
+		RequestBody: requestBody,
+		Responses: map[string]*openapi.Response{
+			strconv.Itoa(o.status): success,
+			"default": {
+				Description: "Error",
+				Content:     map[string]openapi.MediaType{"application/json": {Schema: errorSchema}},
+			},
+		},
+		Security: security,
+	}
+}
+
+// errorEnvelope documents the body of writeError.
+type errorEnvelope struct {
+	Error errorDetails `json:"error"`
+}
+
+// errorDetails describes a failure; some error types add details such as
+// retry_after.
+type errorDetails struct {
+	Type      string `json:"type"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// header documents an optional request header.
+func header(name, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Required:    false,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// query documents an optional query parameter.
+func query(name, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Required:    false,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// openAPIHandler serves document as JSON.
+func openAPIHandler(document *openapi.Document) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, document)
+	})
+}
+
+// swaggerUI serves a Swagger UI page for the document under /docs/. The UI
+// scripts and styles load from the jsDelivr CDN.
+func swaggerUI() http.Handler {
+	files, err := fs.Sub(docsFiles, "docs")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	server := http.StripPrefix("/docs/", http.FileServerFS(files))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; "+
+			"script-src 'self' https://cdn.jsdelivr.net; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; "+
+			"img-src 'self' data:; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-cache")
+		server.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,414 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/sse"
+)
+
+// Responses API object types and statuses.
+const (
+	responsesObject           = "response"
+	responsesItemMessage      = "message"
+	responsesPartOutputText   = "output_text"
+	responsesStatusInProgress = "in_progress"
+	responsesStatusCompleted  = "completed"
+	responsesStatusFailed     = "failed"
+)
+
+// responsesRequest is the OpenAI Responses API request body. Only text input is
+// supported; stored conversation state and tools are rejected rather than ignored.
+type responsesRequest struct {
+	Model              string            `json:"model"`
+	Input              json.RawMessage   `json:"input"`
+	Instructions       string            `json:"instructions,omitempty"`
+	MaxOutputTokens    int               `json:"max_output_tokens,omitempty"`
+	Temperature        float64           `json:"temperature,omitempty"`
+	TopP               *float64          `json:"top_p,omitempty"`
+	Stream             bool              `json:"stream,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Tools              json.RawMessage   `json:"tools,omitempty"`
+}
+
+// completionRequest translates the request to the gateway's completion request.
+// Instructions become a leading system message.
+func (b *responsesRequest) completionRequest() (*domain.CompletionRequest, error) {
+	if b.PreviousResponseID != "" {
+		return nil, errors.New("previous_response_id is not supported; send the conversation as input")
+	}
+	if len(b.Tools) > 0 && string(b.Tools) != "null" && string(b.Tools) != "[]" {
+		return nil, errors.New("tools are not supported")
+	}
+
+	messages, err := parseResponsesInput(b.Input)
+	if err != nil {
+		return nil, err
+	}
+	if b.Instructions != "" {
+		system := domain.Message{Role: "system", Content: b.Instructions, ToolCallID: "", ToolCalls: nil}
+		messages = append([]domain.Message{system}, messages...)
+	}
+
+	return &domain.CompletionRequest{ //nolint:exhaustruct // Sampling parameters the Responses API lacks stay unset
+		Model:       b.Model,
+		Messages:    messages,
+		Temperature: b.Temperature,
+		MaxTokens:   b.MaxOutputTokens,
+		Stream:      b.Stream,
+		Metadata:    b.Metadata,
+		TopP:        b.TopP,
+	}, nil
+}
+
+// responsesInputItem is one item of an input array. Items without a type are
+// messages.
+type responsesInputItem struct {
+	Type    string          `json:"type,omitempty"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// responsesContentPart is one part of an input message's content array.
+type responsesContentPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// responsesResponse is the OpenAI Responses API response object.
+type responsesResponse struct {
+	ID        string                `json:"id"`
+	Object    string                `json:"object"`
+	CreatedAt int64                 `json:"created_at"`
+	Model     string                `json:"model"`
+	Status    string                `json:"status"`
+	Output    []responsesOutputItem `json:"output"`
+	Usage     *responsesUsage       `json:"usage,omitempty"`
+	Error     *responsesError       `json:"error,omitempty"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
+}
+
+// newResponsesResponse returns an in-progress response without output.
+func newResponsesResponse(id, model string, created time.Time) *responsesResponse {
+	return &responsesResponse{
+		ID:        id,
+		Object:    responsesObject,
+		CreatedAt: created.Unix(),
+		Model:     model,
+		Status:    responsesStatusInProgress,
+		Output:    []responsesOutputItem{},
+		Usage:     nil,
+		Error:     nil,
+		Metadata:  nil,
+	}
+}
+
+// message returns the response's assistant message with the given text.
+func (r *responsesResponse) message(status, text string) responsesOutputItem {
+	return responsesOutputItem{
+		Type:    responsesItemMessage,
+		ID:      "msg_" + strings.TrimPrefix(r.ID, "resp_"),
+		Status:  status,
+		Role:    "assistant",
+		Content: []responsesOutputText{newResponsesOutputText(text)},
+	}
+}
+
+// responsesOutputItem is an assistant message in a response's output.
+type responsesOutputItem struct {
+	Type    string                `json:"type"`
+	ID      string                `json:"id"`
+	Status  string                `json:"status"`
+	Role    string                `json:"role"`
+	Content []responsesOutputText `json:"content"`
+}
+
+// responsesOutputText is the text content of an output message.
+type responsesOutputText struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations"`
+}
+
+// newResponsesOutputText returns an output_text content part.
+func newResponsesOutputText(text string) responsesOutputText {
+	return responsesOutputText{Type: responsesPartOutputText, Text: text, Annotations: []any{}}
+}
+
+// responsesUsage reports token usage in the Responses API shape.
+type responsesUsage struct {
+	InputTokens         int                    `json:"input_tokens"`
+	InputTokensDetails  responsesInputDetails  `json:"input_tokens_details"`
+	OutputTokens        int                    `json:"output_tokens"`
+	OutputTokensDetails responsesOutputDetails `json:"output_tokens_details"`
+	TotalTokens         int                    `json:"total_tokens"`
+}
+
+// responsesInputDetails breaks down input tokens.
+type responsesInputDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// responsesOutputDetails breaks down output tokens.
+type responsesOutputDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// responsesError describes why a streamed response failed.
+type responsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// HandleResponses serves the OpenAI Responses API (POST /v1/responses), translating
+// requests to completions so SDKs that default to it can use the gateway.
+func (h *Handler) HandleResponses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var body responsesRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	req, err := body.completionRequest()
+	if err != nil {
+		writeBadRequest(ctx, w, err.Error())
+		return
+	}
+
+	ctx, providerName, ok := h.admitCompletion(ctx, w, r, req)
+	if !ok {
+		return
+	}
+
+	done := h.load.Start(observability.GetTenant(ctx))
+	defer done()
+
+	logger := observability.FromContext(ctx)
+	logger.Info("responses request received",
+		observability.String("model", req.Model),
+		observability.Bool("stream", req.Stream),
+	)
+
+	if r.Header.Get(DryRunHeader) == "true" {
+		h.handleDryRun(ctx, w, providerName, req)
+		return
+	}
+
+	if req.Stream {
+		h.handleResponsesStream(ctx, w, providerName, req)
+		return
+	}
+
+	response, err := h.complete(ctx, providerName, req)
+	if err != nil {
+		logger.Error("completion failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	logger.Info("completion succeeded",
+		observability.Int("tokens", response.Usage.TotalTokens),
+		observability.Float64("cost", response.Usage.Cost),
+	)
+
+	h.headerAllowlist.apply(w.Header(), response.ProviderHeaders)
+	result := newResponsesResponse(responseID(ctx, "resp_", response.ID), response.Model, response.FinishTime)
+	result.Status = responsesStatusCompleted
+	result.Output = []responsesOutputItem{result.message(responsesStatusCompleted, response.Content)}
+	result.Usage = &responsesUsage{
+		InputTokens:         response.Usage.PromptTokens,
+		InputTokensDetails:  responsesInputDetails{CachedTokens: response.Usage.CachedPromptTokens},
+		OutputTokens:        response.Usage.CompletionTokens,
+		OutputTokensDetails: responsesOutputDetails{ReasoningTokens: response.Usage.ReasoningTokens},
+		TotalTokens:         response.Usage.TotalTokens,
+	}
+	result.Metadata = response.Metadata
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleResponsesStream streams a completion as Responses API server-sent events:
+// the response and its message are announced, text arrives as output_text deltas,
+// and the stream ends with response.completed, or response.failed on error.
+func (h *Handler) handleResponsesStream(
+	ctx context.Context,
+	w http.ResponseWriter,
+	providerName string,
+	req *domain.CompletionRequest,
+) {
+	logger := observability.FromContext(ctx)
+
+	chunks, err := h.stream(ctx, providerName, req)
+	if err != nil {
+		logger.Error("stream failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("streaming not supported")
+		writeError(ctx, w, http.StatusInternalServerError, errorTypeServer, "streaming not supported", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	events := &responsesEvents{encoder: sse.NewEncoder(w), flusher: flusher, sequence: 0}
+	defer events.encoder.Release()
+	result := newResponsesResponse(responseID(ctx, "resp_", ""), req.Model, time.Now())
+	message := result.message(responsesStatusInProgress, "")
+	message.Content = []responsesOutputText{}
+	events.send("response.created", map[string]any{"response": result})
+	events.send("response.output_item.added", map[string]any{"output_index": 0, "item": message})
+	events.send("response.content_part.added", map[string]any{
+		"item_id": message.ID, "output_index": 0, "content_index": 0, "part": newResponsesOutputText(""),
+	})
+
+	var text strings.Builder
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stream context done", observability.Error(ctx.Err()))
+			observability.RecordPartial(ctx)
+			return
+
+		case chunk, chunkOk := <-chunks:
+			if !chunkOk {
+				// The stream ended without a done chunk, so the response is incomplete.
+				chunk.Error = errStreamIncomplete
+			}
+			if chunk.ProviderHeaders != nil {
+				h.headerAllowlist.apply(w.Header(), chunk.ProviderHeaders)
+			}
+			if chunk.Metadata != nil {
+				result.Metadata = chunk.Metadata
+			}
+
+			if chunk.Error != nil {
+				logger.Error("stream chunk error",
+					observability.Error(chunk.Error),
+					observability.Bool("partial", text.Len() > 0),
+				)
+				observability.RecordPartial(ctx)
+				result.Status = responsesStatusFailed
+				result.Error = &responsesError{Code: errorTypeServer, Message: chunk.Error.Error()}
+				events.send("response.failed", map[string]any{"response": result})
+				return
+			}
+
+			if chunk.Delta != "" {
+				text.WriteString(chunk.Delta)
+				events.send("response.output_text.delta", map[string]any{
+					"item_id": message.ID, "output_index": 0, "content_index": 0, "delta": chunk.Delta,
+				})
+			}
+
+			if chunk.Done {
+				logger.Info("stream completed")
+				events.complete(result, text.String())
+				return
+			}
+		}
+	}
+}
+
+// responsesEvents writes numbered Responses API server-sent events.
+type responsesEvents struct {
+	encoder  *sse.Encoder
+	flusher  http.Flusher
+	sequence int
+}
+
+// send writes one event whose data is fields plus its type and sequence number.
+func (e *responsesEvents) send(eventType string, fields map[string]any) {
+	fields["type"] = eventType
+	fields["sequence_number"] = e.sequence
+	e.sequence++
+
+	_ = e.encoder.Encode(eventType, fields)
+	e.flusher.Flush()
+}
+
+// complete closes the streamed message with its full text and completes the response.
+func (e *responsesEvents) complete(result *responsesResponse, text string) {
+	message := result.message(responsesStatusCompleted, text)
+	e.send("response.output_text.done", map[string]any{
+		"item_id": message.ID, "output_index": 0, "content_index": 0, "text": text,
+	})
+	e.send("response.content_part.done", map[string]any{
+		"item_id": message.ID, "output_index": 0, "content_index": 0, "part": message.Content[0],
+	})
+	e.send("response.output_item.done", map[string]any{"output_index": 0, "item": message})
+
+	result.Status = responsesStatusCompleted
+	result.Output = []responsesOutputItem{message}
+	e.send("response.completed", map[string]any{"response": result})
+}
+
+// parseResponsesInput converts Responses API input, a string or an array of
+// message items, to chat messages.
+func parseResponsesInput(input json.RawMessage) ([]domain.Message, error) {
+	var text string
+	if err := json.Unmarshal(input, &text); err == nil {
+		return []domain.Message{{Role: "user", Content: text, ToolCallID: "", ToolCalls: nil}}, nil
+	}
+
+	var items []responsesInputItem
+	if err := json.Unmarshal(input, &items); err != nil {
+		return nil, errors.New("input must be a string or an array of message items")
+	}
+
+	messages := make([]domain.Message, 0, len(items))
+	for i, item := range items {
+		if item.Type != "" && item.Type != responsesItemMessage {
+			return nil, fmt.Errorf("input[%d]: item type %q is not supported", i, item.Type)
+		}
+		content, err := parseResponsesContent(item.Content)
+		if err != nil {
+			return nil, fmt.Errorf("input[%d]: %w", i, err)
+		}
+		messages = append(messages, domain.Message{Role: item.Role, Content: content, ToolCallID: "", ToolCalls: nil})
+	}
+	return messages, nil
+}
+
+// parseResponsesContent joins a message's content, a string or an array of text
+// parts, into a single string.
+func parseResponsesContent(content json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+
+	var parts []responsesContentPart
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of content parts")
+	}
+
+	var joined strings.Builder
+	for _, part := range parts {
+		switch part.Type {
+		case "input_text", responsesPartOutputText, "text":
+			joined.WriteString(part.Text)
+		default:
+			return "", fmt.Errorf("content part type %q is not supported", part.Type)
+		}
+	}
+	return joined.String(), nil
+}
@@ -15,6 +15,7 @@ import (
 type Server struct {
 	config      config.ServerConfig
 	handler     *Handler
+	admin       *AdminHandler
 	middlewares middleware.Middleware
 	srv         *http.Server
 }
@@ -23,11 +24,13 @@ type Server struct {
 func NewServer(
 	cfg *config.Config,
 	handler *Handler,
+	admin *AdminHandler,
 	middlewares middleware.Middleware,
 ) *Server {
 	return &Server{
 		config:      cfg.Server,
 		handler:     handler,
+		admin:       admin,
 		middlewares: middlewares,
 		srv:         nil,
 	}
@@ -35,14 +38,9 @@ func NewServer(
 
 // Start starts the HTTP server.
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
-
-	// Register routes.
-	mux.HandleFunc("/v1/completions", s.handler.HandleCompletion)
-	mux.HandleFunc("/health", s.handler.HandleHealth)
+	handlerWithMiddleware := s.Handler()
 
-	// Apply middleware chain.
-	handlerWithMiddleware := s.middlewares(mux)
+	tls := s.config.TLSCertFile != "" || s.config.TLSKeyFile != ""
 
 	// Create server with timeouts.
 	s.srv = &http.Server{
@@ -50,17 +48,56 @@ func (s *Server) Start() error {
 		Handler:      handlerWithMiddleware,
 		ReadTimeout:  time.Duration(s.config.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(s.config.WriteTimeout) * time.Second,
+		Protocols:    serverProtocols(tls, s.config.H2C),
+		HTTP2:        &http.HTTP2Config{MaxConcurrentStreams: s.config.HTTP2MaxConcurrentStreams},
 	}
 
 	ctx := context.Background()
-	observability.FromContext(ctx).Info("starting HTTP server", observability.Int("port", s.config.Port))
+	observability.FromContext(ctx).Info("starting HTTP server",
+		observability.Int("port", s.config.Port),
+		observability.Bool("tls", tls),
+		observability.Bool("h2c", s.config.H2C),
+	)
 
-	if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if tls {
+		err = s.srv.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+	} else {
+		err = s.srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("server failed: %w", err)
 	}
 	return nil
 }
 
+// Handler returns the routes wrapped in the middleware chain, as served by Start.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Register routes, and the OpenAPI document generated from them.
+	routes := s.routes()
+	for _, route := range routes {
+		mux.Handle(route.pattern, route.handler)
+	}
+	mux.Handle("GET /openapi.json", openAPIHandler(openAPIDocument(routes)))
+	mux.Handle("GET /docs/", swaggerUI())
+	s.admin.RegisterRoutes(mux)
+
+	// Apply middleware chain.
+	return s.middlewares(mux)
+}
+
+// serverProtocols returns the protocols to serve: HTTP/1.1 always, HTTP/2 over TLS,
+// and unencrypted HTTP/2 (h2c with prior knowledge) when h2c is set.
+func serverProtocols(tls, h2c bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(tls)
+	protocols.SetUnencryptedHTTP2(h2c)
+	return protocols
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	observability.FromContext(ctx).Info("shutting down HTTP server")
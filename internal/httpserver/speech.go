@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// defaultAudioContentType is sent when the provider does not name the audio format.
+	defaultAudioContentType = "application/octet-stream"
+
+	// audioChunkBytes is the largest audio chunk relayed to the client per write.
+	audioChunkBytes = 32 * 1024
+)
+
+// HandleSpeech synthesizes speech (POST /v1/audio/speech), relaying the binary
+// audio to the client as the provider streams it, with chunked transfer encoding.
+func (h *Handler) HandleSpeech(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		writeError(ctx, w, http.StatusMethodNotAllowed, errorTypeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var req domain.SpeechRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(ctx, w, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	ctx = observability.WithModel(ctx, req.Model)
+	observability.RecordModel(ctx, req.Model)
+
+	providerName := r.Header.Get(ProviderHeader)
+	if providerName != "" {
+		ctx = observability.WithProvider(ctx, providerName)
+	}
+
+	done := h.load.Start(observability.GetTenant(ctx))
+	defer done()
+
+	logger := observability.FromContext(ctx)
+	response, err := h.gateway.Synthesize(ctx, providerName, &req)
+	if err != nil {
+		logger.Error("speech synthesis failed", observability.Error(err))
+		writeGatewayError(ctx, w, err)
+		return
+	}
+	defer response.Audio.Close()
+
+	logger.Info("speech synthesis started",
+		observability.String("provider", response.Provider),
+		observability.Int("characters", response.Usage.Characters),
+		observability.Float64("cost", response.Usage.Cost),
+	)
+
+	contentType := response.ContentType
+	if contentType == "" {
+		contentType = defaultAudioContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if err = relayAudio(w, response.Audio); err != nil {
+		// The status is already sent; the client sees a truncated body.
+		logger.Error("speech stream failed", observability.Error(err))
+		observability.RecordPartial(ctx)
+	}
+}
+
+// relayAudio copies audio to the client, flushing each chunk so playback can
+// start before synthesis finishes.
+func relayAudio(w http.ResponseWriter, audio io.Reader) error {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, audioChunkBytes)
+	for {
+		n, err := audio.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return fmt.Errorf("failed to write audio: %w", writeErr)
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audio: %w", err)
+		}
+	}
+}
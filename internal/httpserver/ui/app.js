@@ -0,0 +1,145 @@
+"use strict";
+
+// The dashboard polls GET /admin/dashboard with the admin token, which is kept
+// for the browser session only.
+const refreshMs = 5000;
+const tokenKey = "calcifer-admin-token";
+
+const $ = (id) => document.getElementById(id);
+let timer = null;
+
+function formatCost(value, currency) {
+  const amount = (value || 0).toFixed(4);
+  return currency && currency !== "USD" ? amount + " " + currency : "$" + amount;
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function row(cells) {
+  const tr = document.createElement("tr");
+  cells.forEach((c) => tr.appendChild(c));
+  return tr;
+}
+
+function fill(id, rows, empty) {
+  const body = $(id);
+  body.replaceChildren(...rows);
+  if (rows.length === 0) {
+    const td = cell(empty);
+    td.colSpan = body.parentElement.querySelectorAll("th").length;
+    body.appendChild(row([td]));
+  }
+}
+
+function renderProviders(providers) {
+  fill("providers", providers.map((p) => {
+    const status = !p.enabled ? "disabled" : p.healthy ? "healthy" : "unhealthy";
+    const badge = document.createElement("span");
+    badge.className = "badge " + status;
+    badge.textContent = status;
+    const td = document.createElement("td");
+    td.appendChild(badge);
+    return row([cell(p.name), td]);
+  }), "No providers registered");
+}
+
+function renderUsage(usage) {
+  if (!usage) {
+    ["requests", "spend", "cache"].forEach((id) => { $(id).textContent = "n/a"; });
+    fill("models", [], "Usage recording is disabled");
+    fill("recent", [], "Usage recording is disabled");
+    return;
+  }
+
+  $("requests").textContent = usage.requests;
+  $("spend").textContent = formatCost(usage.cost);
+  $("cache").textContent = (usage.cache_hit_rate * 100).toFixed(1) + "%";
+
+  const top = Math.max(...usage.spend_by_model.map((m) => m.cost), 0);
+  fill("models", usage.spend_by_model.map((m) => {
+    const bar = document.createElement("div");
+    bar.className = "bar";
+    bar.style.width = (top > 0 ? (m.cost / top) * 100 : 0) + "%";
+    const td = document.createElement("td");
+    td.appendChild(bar);
+    return row([
+      cell(m.group),
+      cell(m.requests, "number"),
+      cell(m.total_tokens, "number"),
+      cell(formatCost(m.cost, m.currency), "number"),
+      td,
+    ]);
+  }), "No requests in this window");
+
+  fill("recent", usage.recent.map((r) => row([
+    cell(new Date(r.time).toLocaleTimeString()),
+    cell(r.client_key || "-"),
+    cell(r.tenant || "-"),
+    cell(r.model),
+    cell(r.provider),
+    cell(r.total_tokens + (r.estimated ? "*" : ""), "number"),
+    cell(formatCost(r.cost), "number"),
+  ])), "No requests in this window");
+}
+
+function render(data) {
+  renderProviders(data.providers);
+  renderUsage(data.usage);
+
+  const inflight = (data.tenants || []).reduce((sum, t) => sum + (t.in_flight || 0), 0);
+  $("inflight").textContent = inflight;
+}
+
+function setStatus(message, error) {
+  $("status").textContent = message;
+  $("status").className = error ? "status error" : "status";
+}
+
+async function refresh() {
+  const token = sessionStorage.getItem(tokenKey);
+  if (!token) {
+    return;
+  }
+
+  try {
+    const span = encodeURIComponent($("window").value);
+    const resp = await fetch("/admin/dashboard?window=" + span, {
+      headers: { Authorization: "Bearer " + token },
+    });
+    if (!resp.ok) {
+      throw new Error((await resp.text()).trim() || resp.statusText);
+    }
+    render(await resp.json());
+    $("dashboard").hidden = false;
+    setStatus("Updated " + new Date().toLocaleTimeString(), false);
+  } catch (err) {
+    setStatus("Failed to load dashboard: " + err.message, true);
+  }
+}
+
+function start() {
+  clearInterval(timer);
+  refresh();
+  timer = setInterval(refresh, refreshMs);
+}
+
+$("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const token = $("token").value.trim();
+  if (token) {
+    sessionStorage.setItem(tokenKey, token);
+    $("token").value = "";
+  }
+  start();
+});
+
+$("window").addEventListener("change", start);
+
+start();
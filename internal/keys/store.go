@@ -0,0 +1,73 @@
+// Package keys persists virtual client keys to a JSON file.
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+const filePermissions = 0o600
+
+// Store implements domain.VirtualKeyStore on a JSON file holding an array of keys.
+// Only key digests are written. Saves replace the file atomically.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore creates the virtual key store (DI constructor). It returns nil when no
+// path is configured, keeping issued keys in memory only.
+func NewStore(cfg *config.VirtualKeyConfig) *Store {
+	if cfg == nil || cfg.Path == "" {
+		return nil
+	}
+
+	return &Store{mu: sync.Mutex{}, path: cfg.Path}
+}
+
+// Load returns the stored keys; a missing file holds none.
+func (s *Store) Load(_ context.Context) ([]domain.VirtualKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read virtual key store: %w", err)
+	}
+
+	var keys []domain.VirtualKey
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse virtual key store: %w", err)
+	}
+	return keys, nil
+}
+
+// Save replaces the stored keys.
+func (s *Store) Save(_ context.Context, keys []domain.VirtualKey) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode virtual keys: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	if err = os.WriteFile(tmpPath, append(data, '\n'), filePermissions); err != nil {
+		return fmt.Errorf("failed to write virtual key store: %w", err)
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace virtual key store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package keys_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/keys"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("should return nil without a path", func(t *testing.T) {
+		require.Nil(t, keys.NewStore(&config.VirtualKeyConfig{Path: ""}))
+	})
+
+	t.Run("should load what was saved", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		created := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+		saved := []domain.VirtualKey{
+			{Name: "ci-bot", Hash: "abc", CreatedAt: created, AllowModels: []string{"gpt-4o-mini"}},
+			{Name: "team-a", Hash: "def", CreatedAt: created, ExpiresAt: created.Add(time.Hour), MonthlyBudget: 10},
+		}
+
+		require.NoError(t, keys.NewStore(&config.VirtualKeyConfig{Path: path}).Save(context.Background(), saved))
+
+		loaded, err := keys.NewStore(&config.VirtualKeyConfig{Path: path}).Load(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, saved, loaded)
+	})
+
+	t.Run("should load nothing from a missing file and fail on a malformed one", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		loaded, err := keys.NewStore(&config.VirtualKeyConfig{Path: path}).Load(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, loaded)
+
+		require.NoError(t, os.WriteFile(path, []byte("["), 0o600))
+		_, err = keys.NewStore(&config.VirtualKeyConfig{Path: path}).Load(context.Background())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,350 @@
+// Package mockllm implements a scripted upstream that speaks the OpenAI chat
+// completions wire protocol, including SSE streaming, so the gateway can be
+// tested end to end without a real provider.
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultModels are served when no rule names a model.
+//
+//nolint:gochecknoglobals // Read-only defaults
+var DefaultModels = []string{"gpt-4", "gpt-3.5-turbo"}
+
+// Rule scripts the reply to matching requests. Rules are tried in order; a
+// request no rule matches gets its last message echoed back.
+type Rule struct {
+	Model    string   `json:"model,omitempty"`    // matches every model when empty
+	Contains string   `json:"contains,omitempty"` // substring of the last message; matches all when empty
+	Status   int      `json:"status,omitempty"`   // a status other than 200 replies with an error body
+	Content  string   `json:"content,omitempty"`
+	Chunks   []string `json:"chunks,omitempty"` // stream deltas; defaults to Content split into words
+	DelayMs  int      `json:"delay_ms,omitempty"`
+
+	// FailAfter, when positive, ends a stream with an error event after that
+	// many deltas, as an upstream failing mid-stream does.
+	FailAfter int `json:"fail_after,omitempty"`
+
+	// Headers are set on the reply, e.g. Retry-After or x-ratelimit-* with a 429 status.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// chatMessage is a chat completion request message.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"` // a string or an array of content parts
+}
+
+// chatRequest is the subset of a chat completion request the server reads.
+type chatRequest struct {
+	Model         string        `json:"model"`
+	Stream        bool          `json:"stream"`
+	Messages      []chatMessage `json:"messages"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// Server is a scripted OpenAI-compatible upstream.
+type Server struct {
+	rules    []Rule
+	requests atomic.Int64
+}
+
+// NewServer creates a server replying according to rules.
+func NewServer(rules []Rule) *Server {
+	return &Server{rules: rules, requests: atomic.Int64{}}
+}
+
+// LoadRules reads a JSON array of rules.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // The script path is chosen by the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var rules []Rule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	return rules, nil
+}
+
+// Requests returns the number of chat completion requests served.
+func (s *Server) Requests() int {
+	return int(s.requests.Load())
+}
+
+// ServeHTTP serves /v1/chat/completions, /v1/audio/speech, and /v1/models.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/chat/completions":
+		s.handleChat(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/audio/speech":
+		handleSpeech(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/models":
+		s.handleModels(w)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// handleChat replies to a chat completion request with the first matching rule.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages are required")
+		return
+	}
+
+	prompt := messageText(req.Messages[len(req.Messages)-1].Content)
+	rule := s.match(req.Model, prompt)
+
+	if rule.DelayMs > 0 {
+		select {
+		case <-time.After(time.Duration(rule.DelayMs) * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for name, value := range rule.Headers {
+		w.Header().Set(name, value)
+	}
+
+	if rule.Status != 0 && rule.Status != http.StatusOK {
+		writeError(w, rule.Status, errorType(rule.Status), "scripted failure")
+		return
+	}
+
+	promptTokens := countWords(req.Messages)
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		writeStream(w, req.Model, rule, promptTokens, includeUsage)
+		return
+	}
+
+	completionTokens := len(strings.Fields(rule.Content))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      fmt.Sprintf("chatcmpl-mock-%d", s.requests.Load()),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": rule.Content},
+			"finish_reason": "stop",
+		}},
+		"usage": usage(promptTokens, completionTokens),
+	})
+}
+
+// handleModels lists the models named by rules, or the default models.
+func (s *Server) handleModels(w http.ResponseWriter) {
+	var models []string
+	for _, rule := range s.rules {
+		if rule.Model != "" && !slices.Contains(models, rule.Model) {
+			models = append(models, rule.Model)
+		}
+	}
+	if len(models) == 0 {
+		models = DefaultModels
+	}
+
+	data := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		data = append(data, map[string]any{"id": model, "object": "model", "created": 0, "owned_by": "mockllm"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+// handleSpeech replies to a speech request with fake audio: the input behind an
+// "audio:" marker, sent in two flushed parts so clients see a chunked stream.
+func handleSpeech(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input string `json:"input"`
+		Voice string `json:"voice"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return
+	}
+	if req.Input == "" || req.Voice == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "input and voice are required")
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, "audio:")
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	_, _ = fmt.Fprint(w, req.Input)
+}
+
+// match returns the first rule matching the request, or an echo of prompt.
+func (s *Server) match(model, prompt string) Rule {
+	for _, rule := range s.rules {
+		if (rule.Model == "" || rule.Model == model) && strings.Contains(prompt, rule.Contains) {
+			return rule
+		}
+	}
+	return Rule{
+		Model:    "",
+		Contains: "",
+		Status:   0,
+		Content:  "mock reply: " + prompt,
+		Chunks:   nil,
+		DelayMs:  0,
+		Headers:  nil,
+	}
+}
+
+// writeStream writes a rule's reply as server-sent chat completion chunks.
+func writeStream(w http.ResponseWriter, model string, rule Rule, promptTokens int, includeUsage bool) {
+	chunks := rule.Chunks
+	if len(chunks) == 0 {
+		words := strings.Fields(rule.Content)
+		for i, word := range words {
+			if i < len(words)-1 {
+				word += " "
+			}
+			chunks = append(chunks, word)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+
+	id := fmt.Sprintf("chatcmpl-mock-stream-%d", time.Now().UnixNano())
+	send := func(payload any) {
+		data, _ := json.Marshal(payload)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		_ = controller.Flush()
+	}
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	completionTokens := 0
+	for i, delta := range chunks {
+		if rule.FailAfter > 0 && i == rule.FailAfter {
+			send(map[string]any{"error": map[string]any{
+				"message": "scripted failure mid-stream", "type": errorType(http.StatusInternalServerError), "code": nil,
+			}})
+			return
+		}
+		completionTokens += len(strings.Fields(delta))
+		send(chunk(map[string]any{"role": "assistant", "content": delta}, nil))
+	}
+	send(chunk(map[string]any{}, "stop"))
+
+	if includeUsage {
+		send(map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []any{},
+			"usage":   usage(promptTokens, completionTokens),
+		})
+	}
+
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	_ = controller.Flush()
+}
+
+// messageText returns the text of a message's content, a string or content parts.
+func messageText(content any) string {
+	switch value := content.(type) {
+	case string:
+		return value
+	case []any:
+		var parts []string
+		for _, part := range value {
+			if fields, ok := part.(map[string]any); ok {
+				if text, isText := fields["text"].(string); isText {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, " ")
+	default:
+		return ""
+	}
+}
+
+// countWords approximates prompt tokens as the words across all messages.
+func countWords(messages []chatMessage) int {
+	words := 0
+	for _, message := range messages {
+		words += len(strings.Fields(messageText(message.Content)))
+	}
+	return words
+}
+
+// usage builds an OpenAI usage object.
+func usage(promptTokens, completionTokens int) map[string]any {
+	return map[string]any{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+}
+
+// errorType maps a status to the OpenAI error type clients expect.
+func errorType(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	default:
+		return "invalid_request_error"
+	}
+}
+
+// writeError writes an OpenAI error body.
+func writeError(w http.ResponseWriter, status int, kind, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": kind, "code": nil},
+	})
+}
+
+// writeJSON writes payload as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
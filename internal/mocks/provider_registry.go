@@ -245,6 +245,148 @@ func (_c *MockProviderRegistry_Register_Call) RunAndReturn(run func(context.Cont
 	return _c
 }
 
+// Reindex provides a mock function with given fields: ctx, providerName
+func (_m *MockProviderRegistry) Reindex(ctx context.Context, providerName string) error {
+	ret := _m.Called(ctx, providerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reindex")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, providerName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockProviderRegistry_Reindex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reindex'
+type MockProviderRegistry_Reindex_Call struct {
+	*mock.Call
+}
+
+// Reindex is a helper method to define mock.On call
+//   - ctx context.Context
+//   - providerName string
+func (_e *MockProviderRegistry_Expecter) Reindex(ctx interface{}, providerName interface{}) *MockProviderRegistry_Reindex_Call {
+	return &MockProviderRegistry_Reindex_Call{Call: _e.mock.On("Reindex", ctx, providerName)}
+}
+
+func (_c *MockProviderRegistry_Reindex_Call) Run(run func(ctx context.Context, providerName string)) *MockProviderRegistry_Reindex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockProviderRegistry_Reindex_Call) Return(_a0 error) *MockProviderRegistry_Reindex_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockProviderRegistry_Reindex_Call) RunAndReturn(run func(context.Context, string) error) *MockProviderRegistry_Reindex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetHealthy provides a mock function with given fields: ctx, providerName, healthy
+func (_m *MockProviderRegistry) SetHealthy(ctx context.Context, providerName string, healthy bool) error {
+	ret := _m.Called(ctx, providerName, healthy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetHealthy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = rf(ctx, providerName, healthy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockProviderRegistry_SetHealthy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetHealthy'
+type MockProviderRegistry_SetHealthy_Call struct {
+	*mock.Call
+}
+
+// SetHealthy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - providerName string
+//   - healthy bool
+func (_e *MockProviderRegistry_Expecter) SetHealthy(ctx interface{}, providerName interface{}, healthy interface{}) *MockProviderRegistry_SetHealthy_Call {
+	return &MockProviderRegistry_SetHealthy_Call{Call: _e.mock.On("SetHealthy", ctx, providerName, healthy)}
+}
+
+func (_c *MockProviderRegistry_SetHealthy_Call) Run(run func(ctx context.Context, providerName string, healthy bool)) *MockProviderRegistry_SetHealthy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(bool))
+	})
+	return _c
+}
+
+func (_c *MockProviderRegistry_SetHealthy_Call) Return(_a0 error) *MockProviderRegistry_SetHealthy_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockProviderRegistry_SetHealthy_Call) RunAndReturn(run func(context.Context, string, bool) error) *MockProviderRegistry_SetHealthy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Unregister provides a mock function with given fields: ctx, providerName
+func (_m *MockProviderRegistry) Unregister(ctx context.Context, providerName string) error {
+	ret := _m.Called(ctx, providerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unregister")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, providerName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockProviderRegistry_Unregister_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Unregister'
+type MockProviderRegistry_Unregister_Call struct {
+	*mock.Call
+}
+
+// Unregister is a helper method to define mock.On call
+//   - ctx context.Context
+//   - providerName string
+func (_e *MockProviderRegistry_Expecter) Unregister(ctx interface{}, providerName interface{}) *MockProviderRegistry_Unregister_Call {
+	return &MockProviderRegistry_Unregister_Call{Call: _e.mock.On("Unregister", ctx, providerName)}
+}
+
+func (_c *MockProviderRegistry_Unregister_Call) Run(run func(ctx context.Context, providerName string)) *MockProviderRegistry_Unregister_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockProviderRegistry_Unregister_Call) Return(_a0 error) *MockProviderRegistry_Unregister_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockProviderRegistry_Unregister_Call) RunAndReturn(run func(context.Context, string) error) *MockProviderRegistry_Unregister_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockProviderRegistry creates a new instance of MockProviderRegistry. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockProviderRegistry(t interface {
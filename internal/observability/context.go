@@ -30,6 +30,15 @@ const (
 
 	// ModelKey holds the model name for this request.
 	ModelKey contextKey = "model"
+
+	// TenantKey holds the tenant the request is attributed to.
+	TenantKey contextKey = "tenant"
+
+	// ClientKeyKey holds the name of the authenticated client API key, never the secret.
+	ClientKeyKey contextKey = "client_key"
+
+	// PriorityKey holds the priority class the client requested.
+	PriorityKey contextKey = "priority"
 )
 
 // WithTraceID injects trace ID into context.
@@ -57,6 +66,21 @@ func WithModel(ctx context.Context, model string) context.Context {
 	return context.WithValue(ctx, ModelKey, model)
 }
 
+// WithTenant injects tenant identifier into context.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, TenantKey, tenant)
+}
+
+// WithClientKey injects the authenticated client API key name into context.
+func WithClientKey(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ClientKeyKey, name)
+}
+
+// WithPriority injects the requested priority class into context.
+func WithPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, PriorityKey, priority)
+}
+
 // GetTraceID extracts trace ID from context.
 func GetTraceID(ctx context.Context) string {
 	if traceID, ok := ctx.Value(TraceIDKey).(string); ok {
@@ -97,6 +121,30 @@ func GetModel(ctx context.Context) string {
 	return ""
 }
 
+// GetTenant extracts tenant identifier from context.
+func GetTenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(TenantKey).(string); ok {
+		return tenant
+	}
+	return ""
+}
+
+// GetClientKey extracts the authenticated client API key name from context.
+func GetClientKey(ctx context.Context) string {
+	if name, ok := ctx.Value(ClientKeyKey).(string); ok {
+		return name
+	}
+	return ""
+}
+
+// GetPriority extracts the requested priority class from context.
+func GetPriority(ctx context.Context) string {
+	if priority, ok := ctx.Value(PriorityKey).(string); ok {
+		return priority
+	}
+	return ""
+}
+
 // GenerateTraceID generates an OpenTelemetry-compatible trace ID (32 hex chars).
 func GenerateTraceID() string {
 	bytes := make([]byte, traceIDBytes)
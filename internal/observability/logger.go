@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"sync"
 
-	"go.uber.org/zap" //nolint:depguard // This is the logger abstraction layer
+	"go.uber.org/zap"         //nolint:depguard // This is the logger abstraction layer
+	"go.uber.org/zap/zapcore" //nolint:depguard // This is the logger abstraction layer
 )
 
 const (
-	maxLoggerFieldCapacity int = 5 // Maximum number of context fields to add to logger
+	maxLoggerFieldCapacity int = 7 // Maximum number of context fields to add to logger
 )
 
 // Global logger instance - shared across the application.
@@ -22,10 +23,11 @@ var (
 )
 
 // InitLogger initializes the base logger (called once at startup).
-func InitLogger() (*zap.Logger, error) {
-	logger, err := zap.NewProduction()
+// Credentials are always redacted from log output; options control content redaction.
+func InitLogger(options LoggerOptions) (*zap.Logger, error) {
+	logger, err := newLogger(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+		return nil, err
 	}
 
 	loggerMu.Lock()
@@ -43,12 +45,23 @@ func getBaseLogger() *zap.Logger {
 
 	if logger == nil {
 		// Fallback to production logger if not initialized
-		logger, _ = zap.NewProduction()
+		logger, _ = newLogger(LoggerOptions{RedactContent: false})
 	}
 
 	return logger
 }
 
+// newLogger builds a production logger that redacts sensitive data.
+func newLogger(options LoggerOptions) (*zap.Logger, error) {
+	logger, err := zap.NewProduction(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, options)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	return logger, nil
+}
+
 // FromContext creates a logger with fields extracted from context.
 func FromContext(ctx context.Context) *zap.Logger {
 	logger := getBaseLogger()
@@ -75,15 +88,27 @@ func FromContext(ctx context.Context) *zap.Logger {
 		fields = append(fields, zap.String("model", model))
 	}
 
+	if tenant := GetTenant(ctx); tenant != "" {
+		fields = append(fields, zap.String("tenant", tenant))
+	}
+
+	if clientKey := GetClientKey(ctx); clientKey != "" {
+		fields = append(fields, zap.String("client_key", clientKey))
+	}
+
 	return logger.With(fields...)
 }
 
+// Field is a structured log field, built with the constructors below.
+type Field = zap.Field
+
 // Re-export zap field constructors for use in application code.
 // This allows structured logging without direct zap dependency.
 //
 //nolint:gochecknoglobals // Re-exported functions for logging abstraction
 var (
 	String   = zap.String
+	Strings  = zap.Strings
 	Int      = zap.Int
 	Int64    = zap.Int64
 	Float64  = zap.Float64
@@ -0,0 +1,367 @@
+package observability
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "calcifer"
+
+// Metrics registry - shared across the application and exposed at /metrics.
+// Like the logger, metrics are process-wide singletons.
+//
+//nolint:gochecknoglobals // Singleton registry is a standard pattern
+var metricsRegistry = newMetricsRegistry()
+
+// Gateway metrics. Label values must come from bounded sets (tenants, models, providers).
+//
+//nolint:gochecknoglobals // Metric collectors are registered once at startup
+var (
+	// TenantInFlightRequests tracks requests currently being served per tenant.
+	TenantInFlightRequests = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "tenant_in_flight_requests",
+		Help:      "Number of requests currently being served, by tenant.",
+	}, []string{"tenant"})
+
+	// TenantQueuedRequests tracks requests waiting for a concurrency slot per tenant.
+	TenantQueuedRequests = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "tenant_queued_requests",
+		Help:      "Number of requests waiting for a concurrency slot, by tenant.",
+	}, []string{"tenant"})
+
+	// ConcurrencyRejections counts requests rejected by a concurrency limit.
+	ConcurrencyRejections = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "concurrency_rejections_total",
+		Help:      "Requests rejected because a provider or model concurrency limit was reached.",
+	}, []string{"scope"})
+
+	// StopSequenceTruncations counts responses the gateway cut at an emulated stop sequence.
+	StopSequenceTruncations = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stop_sequence_truncations_total",
+		Help:      "Responses truncated at a stop sequence enforced by the gateway, by provider.",
+	}, []string{"provider"})
+
+	// OutputLimitTruncations counts streams the gateway cut at a key's output token ceiling.
+	OutputLimitTruncations = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "output_limit_truncations_total",
+		Help:      "Streams cut at a key policy's output token ceiling, by provider.",
+	}, []string{"provider"})
+
+	// OutputFilterActions counts responses and streams the output filter masked or aborted.
+	OutputFilterActions = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "output_filter_actions_total",
+		Help:      "Responses and streams masked or aborted by the output filter, by provider and action.",
+	}, []string{"provider", "action"})
+
+	// ConcurrencyLimit tracks the current adaptive concurrency limit per provider.
+	ConcurrencyLimit = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive concurrency limit, by provider.",
+	}, []string{"provider"})
+
+	// ConcurrencyQueueWait tracks how long queued requests waited for a concurrency slot,
+	// whether they got one or not, by priority class.
+	ConcurrencyQueueWait = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "concurrency_queue_wait_seconds",
+		Help:      "Time requests spent waiting for a concurrency slot, by priority.",
+		Buckets:   latencyBuckets,
+	}, []string{"priority"})
+
+	// IdempotencyStoreEntries tracks responses held for Idempotency-Key replay.
+	IdempotencyStoreEntries = promauto.With(metricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "idempotency_store_entries",
+		Help:      "Number of responses held for Idempotency-Key replay.",
+	})
+
+	// IdempotencyStoreBytes tracks the approximate memory held by stored responses.
+	IdempotencyStoreBytes = promauto.With(metricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "idempotency_store_bytes",
+		Help:      "Approximate bytes held by responses stored for Idempotency-Key replay.",
+	})
+
+	// IdempotencyEvictions counts stored responses removed before replay.
+	IdempotencyEvictions = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "idempotency_evictions_total",
+		Help:      "Stored responses removed by compaction, by reason (expired, memory).",
+	}, []string{"reason"})
+
+	// IdempotencySkipped counts successful responses not stored for replay.
+	IdempotencySkipped = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "idempotency_skipped_total",
+		Help:      "Responses not stored for Idempotency-Key replay, by reason (partial, too_large).",
+	}, []string{"reason"})
+
+	// ProviderHealthy reports whether a provider passed its latest health checks (1) or not (0).
+	ProviderHealthy = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_healthy",
+		Help:      "Whether a provider is currently routable according to health checks (1) or not (0).",
+	}, []string{"provider"})
+
+	// ProviderHealthTransitions counts providers entering or leaving the unhealthy state.
+	ProviderHealthTransitions = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_health_transitions_total",
+		Help:      "Provider health state changes, by provider and new state (healthy, unhealthy).",
+	}, []string{"provider", "state"})
+
+	// HealthSyncMessages counts provider health transitions shared with peer replicas,
+	// by result: published, publish_failed, or received.
+	HealthSyncMessages = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "health_sync_messages_total",
+		Help:      "Provider health transitions shared with peer replicas, by result.",
+	}, []string{"result"})
+
+	// ShadowRequests counts mirrored shadow completions by outcome (success, error, dropped, skipped).
+	ShadowRequests = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shadow_requests_total",
+		Help:      "Shadow completions mirrored to a secondary provider, by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	// ShadowLatencyDelta observes shadow latency minus primary latency.
+	ShadowLatencyDelta = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "shadow_latency_delta_seconds",
+		Help:      "Shadow completion latency minus primary completion latency; negative means the shadow was faster.",
+		Buckets:   []float64{-10, -5, -2, -1, -0.5, -0.1, 0, 0.1, 0.5, 1, 2, 5, 10},
+	}, []string{"provider"})
+
+	// ShadowSimilarity observes how similar shadow content is to the primary response.
+	ShadowSimilarity = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "shadow_content_similarity",
+		Help:      "Word-set similarity between shadow and primary responses, from 0 to 1.",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	}, []string{"provider"})
+
+	// ShadowTokens counts tokens consumed by shadow completions.
+	ShadowTokens = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shadow_tokens_total",
+		Help:      "Total tokens consumed by shadow completions.",
+	}, []string{"provider"})
+
+	// ShadowCost counts the cost of shadow completions.
+	ShadowCost = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shadow_cost_total",
+		Help:      "Total cost of shadow completions.",
+	}, []string{"provider"})
+
+	// ExperimentRequests counts requests enrolled in an A/B experiment by arm.
+	ExperimentRequests = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "experiment_requests_total",
+		Help:      "Requests enrolled in an A/B experiment, by experiment and arm (control, variant).",
+	}, []string{"experiment", "arm"})
+
+	// RoutingHints counts requests routed by a client routing hint.
+	RoutingHints = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "routing_hints_total",
+		Help:      "Requests routed by a client routing hint, by hint (cheapest, fastest, provider, exclude).",
+	}, []string{"hint"})
+
+	// Fallbacks counts requests retried against the fallback of a provider error class, by class and outcome.
+	Fallbacks = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "fallbacks_total",
+		Help:      "Requests retried after a provider error, by error class and outcome (success, error).",
+	}, []string{"class", "outcome"})
+
+	// MaxTokensAdjustments counts retries with max_tokens lowered after a context length error.
+	MaxTokensAdjustments = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "max_tokens_adjustments_total",
+		Help:      "Retries with max_tokens lowered to fit the context window, by model and outcome (success, error).",
+	}, []string{"model", "outcome"})
+
+	// Evaluations counts response evaluations by evaluator and outcome (success, error, dropped).
+	Evaluations = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "evaluations_total",
+		Help:      "Response quality evaluations, by evaluator and outcome (success, error, dropped).",
+	}, []string{"evaluator", "outcome"})
+
+	// ResponseQuality observes response quality scores by provider, model, and evaluator.
+	ResponseQuality = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_quality_score",
+		Help:      "Response quality scores from evaluators, from 0 to 1.",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	}, []string{"provider", "model", "evaluator"})
+
+	// AutoModelSelections counts requests for the "auto" virtual model by the model chosen.
+	AutoModelSelections = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "auto_model_selections_total",
+		Help:      "Requests for the auto virtual model, by the model their complexity routed them to.",
+	}, []string{"model"})
+
+	// AttributedCost counts request cost by attribution tag. Tag names come from
+	// the configured allow-list; values are bounded by client conventions.
+	AttributedCost = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "attributed_cost_total",
+		Help:      "Total request cost, by attribution tag name and value.",
+	}, []string{"tag", "value"})
+
+	// AttributedTokens counts request tokens by attribution tag.
+	AttributedTokens = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "attributed_tokens_total",
+		Help:      "Total request tokens, by attribution tag name and value.",
+	}, []string{"tag", "value"})
+
+	// ModelDiscoveryRefreshes counts model list refreshes by outcome (success, error).
+	ModelDiscoveryRefreshes = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "model_discovery_refreshes_total",
+		Help:      "Provider model list refreshes, by provider and outcome (success, error).",
+	}, []string{"provider", "outcome"})
+
+	// ProviderModels reports how many models each provider currently serves.
+	ProviderModels = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_models",
+		Help:      "Number of models each provider serves after the last discovery refresh.",
+	}, []string{"provider"})
+
+	// PolicyViolations counts requests rejected by a client key policy.
+	PolicyViolations = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "policy_violations_total",
+		Help:      "Requests rejected by a client key policy, by policy and kind (model, provider).",
+	}, []string{"policy", "kind"})
+
+	// ParameterClamps counts request parameters lowered or raised to a configured limit.
+	ParameterClamps = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "parameter_clamps_total",
+		Help:      "Request parameters clamped to a configured limit, by parameter.",
+	}, []string{"parameter"})
+
+	// CoalescedRequests counts requests answered by sharing an identical in-flight provider call.
+	CoalescedRequests = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "coalesced_requests_total",
+		Help:      "Requests served from an identical in-flight provider call instead of a new one, by provider.",
+	}, []string{"provider"})
+
+	// CoalescedWaiting tracks requests waiting on an identical in-flight provider call.
+	CoalescedWaiting = promauto.With(metricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "coalesced_waiting_requests",
+		Help:      "Number of requests waiting on an identical in-flight provider call.",
+	})
+
+	// PricingCatalogRefreshes counts external price catalog loads.
+	PricingCatalogRefreshes = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pricing_catalog_refreshes_total",
+		Help:      "External price catalog loads, by outcome (success, error).",
+	}, []string{"outcome"})
+
+	// AlertsRaised counts spend and error-rate alerts.
+	AlertsRaised = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "alerts_raised_total",
+		Help:      "Alerts raised, by kind (spend, error_rate).",
+	}, []string{"kind"})
+
+	// AlertDeliveries counts alert deliveries to the configured sink.
+	AlertDeliveries = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "alert_deliveries_total",
+		Help:      "Alert deliveries to the webhook, by outcome (success, error, dropped).",
+	}, []string{"outcome"})
+
+	// EventDeliveries counts telemetry event deliveries per sink.
+	EventDeliveries = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_deliveries_total",
+		Help:      "Telemetry event deliveries, by sink and outcome (success, error, dropped).",
+	}, []string{"sink", "outcome"})
+
+	// QuotaRejections counts requests rejected because a client key quota was used up.
+	QuotaRejections = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "quota_rejections_total",
+		Help:      "Requests rejected by client key quotas, by exhausted limit.",
+	}, []string{"limit"})
+
+	// ProviderLatency observes successful non-streaming provider calls, from request to full response.
+	ProviderLatency = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_latency_seconds",
+		Help:      "Latency of successful non-streaming provider completions, by provider and model.",
+		Buckets:   latencyBuckets,
+	}, []string{"provider", "model"})
+
+	// StreamFirstChunkLatency observes the time from opening a provider stream to its first chunk.
+	StreamFirstChunkLatency = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "stream_first_chunk_seconds",
+		Help:      "Time from opening a provider stream to its first chunk (TTFB), by provider and model.",
+		Buckets:   latencyBuckets,
+	}, []string{"provider", "model"})
+
+	// StreamDuration observes the time from opening a provider stream to its successful completion.
+	StreamDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "stream_duration_seconds",
+		Help:      "Time from opening a provider stream to its final chunk, by provider and model.",
+		Buckets:   latencyBuckets,
+	}, []string{"provider", "model"})
+
+	// ActiveStreams tracks provider streams currently open.
+	ActiveStreams = promauto.With(metricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "active_streams",
+		Help:      "Number of provider streams currently open.",
+	})
+
+	// StreamTimeouts counts streams cancelled for exceeding the maximum stream duration.
+	StreamTimeouts = promauto.With(metricsRegistry).NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stream_timeouts_total",
+		Help:      "Provider streams cancelled for exceeding the maximum stream duration.",
+	})
+)
+
+// latencyBuckets span fast cached answers to long generations, in seconds.
+//
+//nolint:gochecknoglobals // Shared by the latency histograms above
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60, 120, 300}
+
+func newMetricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// MetricsHandler serves all registered metrics in the Prometheus exposition format.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
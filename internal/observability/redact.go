@@ -0,0 +1,122 @@
+package observability
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"         //nolint:depguard // This is the logger abstraction layer
+	"go.uber.org/zap/zapcore" //nolint:depguard // This is the logger abstraction layer
+)
+
+// redacted replaces sensitive values in log output.
+const redacted = "[REDACTED]"
+
+// credentialFields are field keys whose values are always redacted.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var credentialFields = map[string]bool{
+	"authorization": true,
+	"api_key":       true,
+	"apikey":        true,
+	"x-api-key":     true,
+	"token":         true,
+	"secret":        true,
+	"password":      true,
+}
+
+// contentFields are field keys carrying prompt or completion text, redacted on request.
+//
+//nolint:gochecknoglobals // Immutable lookup table
+var contentFields = map[string]bool{
+	"prompt":     true,
+	"content":    true,
+	"messages":   true,
+	"message":    true,
+	"completion": true,
+	"delta":      true,
+}
+
+// secretPatterns match credentials embedded in free text such as error messages.
+//
+//nolint:gochecknoglobals // Compiled once
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`), "Bearer " + redacted},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`), redacted},
+	{regexp.MustCompile(`(?i)(api[_-]?key["']?\s*[:=]\s*["']?)[^\s"'&,]+`), "${1}" + redacted},
+}
+
+// LoggerOptions controls what the base logger redacts.
+type LoggerOptions struct {
+	// RedactContent redacts prompt and completion text in addition to credentials.
+	RedactContent bool
+}
+
+// redactingCore scrubs credentials (and optionally content) from entries before
+// they reach the wrapped core.
+type redactingCore struct {
+	zapcore.Core
+
+	redactContent bool
+}
+
+// newRedactingCore wraps core with log redaction.
+func newRedactingCore(core zapcore.Core, options LoggerOptions) zapcore.Core {
+	return &redactingCore{Core: core, redactContent: options.RedactContent}
+}
+
+// With redacts fields added to a child logger.
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), redactContent: c.redactContent}
+}
+
+// Check routes enabled entries through this core so Write can redact them.
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write redacts the message and fields before writing.
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = RedactSecrets(entry.Message)
+	return c.Core.Write(entry, c.redact(fields)) //nolint:wrapcheck // Transparent core passthrough
+}
+
+// redact returns a copy of fields with sensitive values replaced.
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	scrubbed := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		scrubbed[i] = c.redactField(field)
+	}
+	return scrubbed
+}
+
+// redactField replaces a field's value when its key or content is sensitive.
+func (c *redactingCore) redactField(field zapcore.Field) zapcore.Field {
+	key := strings.ToLower(field.Key)
+	if credentialFields[key] || (c.redactContent && contentFields[key]) {
+		return zap.String(field.Key, redacted)
+	}
+
+	switch field.Type { //nolint:exhaustive // Only text-bearing fields can hold secrets
+	case zapcore.StringType:
+		field.String = RedactSecrets(field.String)
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			return zap.String(field.Key, RedactSecrets(err.Error()))
+		}
+	}
+	return field
+}
+
+// RedactSecrets masks bearer tokens and API keys embedded in text.
+func RedactSecrets(text string) string {
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
@@ -0,0 +1,44 @@
+package observability_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "should redact bearer tokens",
+			text: "upstream rejected Authorization: Bearer abc.def-123",
+			want: "upstream rejected Authorization: Bearer [REDACTED]",
+		},
+		{
+			name: "should redact provider API keys",
+			text: "invalid key sk-proj-AbCdEf0123456789xyz provided",
+			want: "invalid key [REDACTED] provided",
+		},
+		{
+			name: "should redact api_key assignments",
+			text: `request failed: {"api_key": "s3cr3t"}`,
+			want: `request failed: {"api_key": "[REDACTED]"}`,
+		},
+		{
+			name: "should leave ordinary text unchanged",
+			text: "completion failed: context deadline exceeded",
+			want: "completion failed: context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, observability.RedactSecrets(tt.text))
+		})
+	}
+}
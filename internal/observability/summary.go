@@ -0,0 +1,109 @@
+package observability
+
+import (
+	"context"
+	"sync"
+)
+
+// summaryKey holds the request summary shared between the access log and handlers.
+const summaryKey contextKey = "request_summary"
+
+// RequestSummary collects request attributes that are only discovered while the
+// request is served (the routed provider, cache outcome), so the access log can
+// report them once the response is complete. It is safe for concurrent use.
+type RequestSummary struct {
+	mu          sync.Mutex
+	model       string
+	provider    string
+	cacheStatus string
+	partial     bool
+}
+
+// WithRequestSummary attaches a new, empty request summary to the context.
+func WithRequestSummary(ctx context.Context) (context.Context, *RequestSummary) {
+	summary := &RequestSummary{mu: sync.Mutex{}, model: "", provider: "", cacheStatus: "", partial: false}
+	return context.WithValue(ctx, summaryKey, summary), summary
+}
+
+// HasRequestSummary reports whether the context carries a request summary.
+func HasRequestSummary(ctx context.Context) bool {
+	return summaryFrom(ctx) != nil
+}
+
+// GetRequestSummary returns the context's request summary, or nil if it has none.
+func GetRequestSummary(ctx context.Context) *RequestSummary {
+	return summaryFrom(ctx)
+}
+
+// RecordModel notes the requested model in the request summary, if any.
+func RecordModel(ctx context.Context, model string) {
+	if summary := summaryFrom(ctx); summary != nil {
+		summary.mu.Lock()
+		summary.model = model
+		summary.mu.Unlock()
+	}
+}
+
+// RecordProvider notes the provider serving the request in the request summary, if any.
+func RecordProvider(ctx context.Context, provider string) {
+	if summary := summaryFrom(ctx); summary != nil {
+		summary.mu.Lock()
+		summary.provider = provider
+		summary.mu.Unlock()
+	}
+}
+
+// RecordCacheStatus notes how a response cache handled the request (e.g. "hit", "miss").
+func RecordCacheStatus(ctx context.Context, status string) {
+	if summary := summaryFrom(ctx); summary != nil {
+		summary.mu.Lock()
+		summary.cacheStatus = status
+		summary.mu.Unlock()
+	}
+}
+
+// RecordPartial notes that the response was cut short, such as a stream ending in
+// an error, so it must not be stored for replay.
+func RecordPartial(ctx context.Context) {
+	if summary := summaryFrom(ctx); summary != nil {
+		summary.mu.Lock()
+		summary.partial = true
+		summary.mu.Unlock()
+	}
+}
+
+// Model returns the recorded model.
+func (s *RequestSummary) Model() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.model
+}
+
+// Provider returns the recorded provider.
+func (s *RequestSummary) Provider() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.provider
+}
+
+// CacheStatus returns the recorded cache status.
+func (s *RequestSummary) CacheStatus() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cacheStatus
+}
+
+// Partial reports whether the response was recorded as cut short.
+func (s *RequestSummary) Partial() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.partial
+}
+
+// summaryFrom extracts the request summary from context.
+func summaryFrom(ctx context.Context) *RequestSummary {
+	if summary, ok := ctx.Value(summaryKey).(*RequestSummary); ok {
+		return summary
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/openapi"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type node struct {
+	base
+
+	Name     string            `json:"name"`
+	Count    *int              `json:"count,omitempty"`
+	Created  time.Time         `json:"created,omitzero"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []node            `json:"children,omitempty"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Secret   string            `json:"-"`
+	Err      error             `json:"error,omitempty"`
+}
+
+func TestBuilder_Schema(t *testing.T) {
+	t.Run("should derive component schemas from JSON tags", func(t *testing.T) {
+		builder := openapi.NewBuilder(openapi.Info{Title: "Test", Version: "v1", Description: ""})
+
+		schema := builder.Schema(reflect.TypeFor[[]node]())
+
+		require.Equal(t, "array", schema.Type)
+		require.Equal(t, "#/components/schemas/Node", schema.Items.Ref)
+
+		component := builder.Document().Components.Schemas["Node"]
+		require.Equal(t, "object", component.Type)
+		require.ElementsMatch(t, []string{"id", "name"}, component.Required)
+		require.ElementsMatch(t,
+			[]string{"id", "name", "count", "created", "labels", "children", "raw"},
+			slices.Collect(maps.Keys(component.Properties)),
+		)
+		require.Equal(t, "integer", component.Properties["count"].Type)
+		require.Equal(t, "date-time", component.Properties["created"].Format)
+		require.Equal(t, "string", component.Properties["labels"].AdditionalProperties.Type)
+		require.Equal(t, "#/components/schemas/Node", component.Properties["children"].Items.Ref)
+		require.Equal(t, &openapi.Schema{}, component.Properties["raw"])
+	})
+
+	t.Run("should describe primitives inline", func(t *testing.T) {
+		builder := openapi.NewBuilder(openapi.Info{Title: "Test", Version: "v1", Description: ""})
+
+		require.Equal(t, "number", builder.Schema(reflect.TypeFor[float64]()).Type)
+		require.Equal(t, "byte", builder.Schema(reflect.TypeFor[[]byte]()).Format)
+		require.Empty(t, builder.Document().Components.Schemas)
+	})
+}
+
+func TestBuilder_Document(t *testing.T) {
+	t.Run("should encode operations by path and lower-case method", func(t *testing.T) {
+		builder := openapi.NewBuilder(openapi.Info{Title: "Test", Version: "v1", Description: ""})
+		builder.SecurityScheme("key", &openapi.SecurityScheme{Type: "http", Scheme: "bearer", Description: ""})
+		builder.Add("GET", "/things", &openapi.Operation{
+			OperationID: "listThings",
+			Summary:     "List things",
+			Description: "",
+			Tags:        nil,
+			Parameters:  nil,
+			RequestBody: nil,
+			Responses:   map[string]*openapi.Response{"200": {Description: "OK", Content: nil}},
+			Security:    []map[string][]string{{"key": {}}},
+		})
+
+		data, err := json.Marshal(builder.Document())
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+			"openapi": "3.1.0",
+			"info": {"title": "Test", "version": "v1"},
+			"paths": {"/things": {"get": {
+				"operationId": "listThings",
+				"summary": "List things",
+				"responses": {"200": {"description": "OK"}},
+				"security": [{"key": []}]
+			}}},
+			"components": {
+				"schemas": {},
+				"securitySchemes": {"key": {"type": "http", "scheme": "bearer"}}
+			}
+		}`, string(data))
+	})
+}
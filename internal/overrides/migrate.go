@@ -0,0 +1,26 @@
+package overrides
+
+// migrations returns the schema changes in order, applied by sqlstore.Open.
+// Released migrations are never edited, only appended to.
+func migrations() []string {
+	return []string{
+		`CREATE TABLE pricing_overrides (
+			model                    TEXT PRIMARY KEY,
+			input_cost_per_1k        REAL NOT NULL,
+			output_cost_per_1k       REAL NOT NULL,
+			cached_input_cost_per_1k REAL NOT NULL DEFAULT 0,
+			reasoning_cost_per_1k    REAL NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE model_aliases (
+			alias TEXT PRIMARY KEY,
+			model TEXT NOT NULL
+		)`,
+		`CREATE TABLE key_policies (
+			name   TEXT PRIMARY KEY,
+			policy TEXT NOT NULL
+		)`,
+		`ALTER TABLE pricing_overrides ADD COLUMN character_cost_per_1k REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE pricing_overrides ADD COLUMN request_cost REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE pricing_overrides ADD COLUMN image_cost REAL NOT NULL DEFAULT 0`,
+	}
+}
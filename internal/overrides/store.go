@@ -0,0 +1,171 @@
+// Package overrides persists pricing, model alias, and key policy overrides to a
+// SQLite database.
+package overrides
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/sqlstore"
+)
+
+// Store implements domain.OverrideStore on a SQLite database. Its schema is
+// migrated to the current version when the store opens.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens the override store (DI constructor), creating the database and
+// applying pending migrations. It returns nil when no path is configured, keeping
+// overrides in memory only.
+func NewStore(cfg *config.OverridesConfig) (*Store, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, nil //nolint:nilnil // A nil store keeps overrides in memory
+	}
+
+	return Open(context.Background(), cfg.Path)
+}
+
+// Open opens the SQLite database at path and migrates it.
+func Open(ctx context.Context, path string) (*Store, error) {
+	db, err := sqlstore.Open(ctx, path, "override store", migrations())
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Load returns every stored override.
+func (s *Store) Load(ctx context.Context) (domain.OverrideSet, error) {
+	set := domain.OverrideSet{Pricing: nil, Aliases: nil, KeyPolicies: nil}
+
+	pricing := `SELECT model, input_cost_per_1k, output_cost_per_1k, cached_input_cost_per_1k,
+		reasoning_cost_per_1k, character_cost_per_1k, request_cost, image_cost FROM pricing_overrides ORDER BY model`
+	err := s.query(ctx, pricing, func(rows *sql.Rows) error {
+		var override domain.PricingOverride
+		if err := rows.Scan(&override.Model, &override.InputCostPer1K, &override.OutputCostPer1K,
+			&override.CachedInputCostPer1K, &override.ReasoningCostPer1K, &override.CharacterCostPer1K,
+			&override.RequestCost, &override.ImageCost); err != nil {
+			return err //nolint:wrapcheck // Wrapped by query
+		}
+		set.Pricing = append(set.Pricing, override)
+		return nil
+	})
+	if err != nil {
+		return set, err
+	}
+
+	err = s.query(ctx, `SELECT alias, model FROM model_aliases ORDER BY alias`, func(rows *sql.Rows) error {
+		var alias domain.ModelAlias
+		if err := rows.Scan(&alias.Alias, &alias.Model); err != nil {
+			return err //nolint:wrapcheck // Wrapped by query
+		}
+		set.Aliases = append(set.Aliases, alias)
+		return nil
+	})
+	if err != nil {
+		return set, err
+	}
+
+	err = s.query(ctx, `SELECT policy FROM key_policies ORDER BY name`, func(rows *sql.Rows) error {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err //nolint:wrapcheck // Wrapped by query
+		}
+		var policy domain.KeyPolicy
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return fmt.Errorf("failed to parse key policy: %w", err)
+		}
+		set.KeyPolicies = append(set.KeyPolicies, policy)
+		return nil
+	})
+	return set, err
+}
+
+// SavePricing creates or replaces a pricing override.
+func (s *Store) SavePricing(ctx context.Context, override domain.PricingOverride) error {
+	return s.exec(ctx, `INSERT INTO pricing_overrides (model, input_cost_per_1k, output_cost_per_1k,
+		cached_input_cost_per_1k, reasoning_cost_per_1k, character_cost_per_1k, request_cost, image_cost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (model) DO UPDATE SET input_cost_per_1k = excluded.input_cost_per_1k,
+		output_cost_per_1k = excluded.output_cost_per_1k,
+		cached_input_cost_per_1k = excluded.cached_input_cost_per_1k,
+		reasoning_cost_per_1k = excluded.reasoning_cost_per_1k,
+		character_cost_per_1k = excluded.character_cost_per_1k,
+		request_cost = excluded.request_cost, image_cost = excluded.image_cost`,
+		override.Model, override.InputCostPer1K, override.OutputCostPer1K,
+		override.CachedInputCostPer1K, override.ReasoningCostPer1K, override.CharacterCostPer1K,
+		override.RequestCost, override.ImageCost)
+}
+
+// DeletePricing removes the pricing override for model.
+func (s *Store) DeletePricing(ctx context.Context, model string) error {
+	return s.exec(ctx, `DELETE FROM pricing_overrides WHERE model = ?`, model)
+}
+
+// SaveAlias creates or replaces a model alias.
+func (s *Store) SaveAlias(ctx context.Context, alias domain.ModelAlias) error {
+	return s.exec(ctx, `INSERT INTO model_aliases (alias, model) VALUES (?, ?)
+		ON CONFLICT (alias) DO UPDATE SET model = excluded.model`, alias.Alias, alias.Model)
+}
+
+// DeleteAlias removes a model alias.
+func (s *Store) DeleteAlias(ctx context.Context, alias string) error {
+	return s.exec(ctx, `DELETE FROM model_aliases WHERE alias = ?`, alias)
+}
+
+// SaveKeyPolicy creates or replaces a key policy, stored as JSON.
+func (s *Store) SaveKeyPolicy(ctx context.Context, policy domain.KeyPolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to encode key policy: %w", err)
+	}
+
+	return s.exec(ctx, `INSERT INTO key_policies (name, policy) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET policy = excluded.policy`, policy.Name, data)
+}
+
+// DeleteKeyPolicy removes the named key policy.
+func (s *Store) DeleteKeyPolicy(ctx context.Context, name string) error {
+	return s.exec(ctx, `DELETE FROM key_policies WHERE name = ?`, name)
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close override store: %w", err)
+	}
+	return nil
+}
+
+// exec runs a statement that changes the store.
+func (s *Store) exec(ctx context.Context, statement string, args ...any) error {
+	if _, err := s.db.ExecContext(ctx, statement, args...); err != nil {
+		return fmt.Errorf("failed to write override store: %w", err)
+	}
+	return nil
+}
+
+// query runs a query, calling scan for each row.
+func (s *Store) query(ctx context.Context, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read override store: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return fmt.Errorf("failed to read override store: %w", err)
+		}
+	}
+	if err = errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("failed to read override store: %w", err)
+	}
+	return nil
+}
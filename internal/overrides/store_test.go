@@ -0,0 +1,86 @@
+package overrides_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/overrides"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	open := func(t *testing.T, path string) *overrides.Store {
+		t.Helper()
+		store, err := overrides.Open(ctx, path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+
+	t.Run("should return nil without a path", func(t *testing.T) {
+		store, err := overrides.NewStore(&config.OverridesConfig{Path: ""})
+		require.NoError(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("should load nothing from a new database", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "overrides.db"))
+
+		set, err := store.Load(ctx)
+		require.NoError(t, err)
+		require.Empty(t, set.Pricing)
+		require.Empty(t, set.Aliases)
+		require.Empty(t, set.KeyPolicies)
+	})
+
+	t.Run("should persist overrides across reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.db")
+		store := open(t, path)
+
+		pricing := domain.PricingOverride{
+			PricingConfig: domain.PricingConfig{InputCostPer1K: 0.01, OutputCostPer1K: 0.03, RequestCost: 0.002},
+			Model:         "gpt-4",
+		}
+		policy := domain.KeyPolicy{Name: "interns", Keys: []string{"intern"}, AllowModels: []string{"gpt-4o-mini"}}
+		require.NoError(t, store.SavePricing(ctx, pricing))
+		require.NoError(t, store.SaveAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-4o-mini"}))
+		require.NoError(t, store.SaveKeyPolicy(ctx, policy))
+		require.NoError(t, store.Close())
+
+		set, err := open(t, path).Load(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []domain.PricingOverride{pricing}, set.Pricing)
+		require.Equal(t, []domain.ModelAlias{{Alias: "fast", Model: "gpt-4o-mini"}}, set.Aliases)
+		require.Equal(t, []domain.KeyPolicy{policy}, set.KeyPolicies)
+	})
+
+	t.Run("should replace and delete overrides", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "overrides.db"))
+
+		require.NoError(t, store.SaveAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-4o-mini"}))
+		require.NoError(t, store.SaveAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-3.5-turbo"}))
+		require.NoError(t, store.SaveAlias(ctx, domain.ModelAlias{Alias: "smart", Model: "gpt-4"}))
+		require.NoError(t, store.DeleteAlias(ctx, "smart"))
+
+		set, err := store.Load(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []domain.ModelAlias{{Alias: "fast", Model: "gpt-3.5-turbo"}}, set.Aliases)
+	})
+
+	t.Run("should migrate an existing database only once", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.db")
+		store := open(t, path)
+		require.NoError(t, store.SaveAlias(ctx, domain.ModelAlias{Alias: "fast", Model: "gpt-4o-mini"}))
+		require.NoError(t, store.Close())
+
+		set, err := open(t, path).Load(ctx)
+		require.NoError(t, err)
+		require.Len(t, set.Aliases, 1)
+	})
+}
@@ -0,0 +1,227 @@
+// Package pricing loads model pricing from an external price catalog, such as
+// the LiteLLM model price list, so prices can change without a redeploy.
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	tokensPerK      = 1000.0
+	maxCatalogBytes = 32 << 20
+
+	// sampleSpecEntry documents the LiteLLM catalog schema and is not a model.
+	sampleSpecEntry = "sample_spec"
+)
+
+// catalogEntry is one model in the catalog. Per-token fields follow the LiteLLM
+// schema; per-1K fields take precedence when both are set.
+type catalogEntry struct {
+	InputCostPerToken           *float64 `json:"input_cost_per_token"`
+	OutputCostPerToken          *float64 `json:"output_cost_per_token"`
+	CacheReadInputTokenCost     *float64 `json:"cache_read_input_token_cost"`
+	OutputCostPerReasoningToken *float64 `json:"output_cost_per_reasoning_token"`
+	InputCostPerRequest         *float64 `json:"input_cost_per_request"`
+	OutputCostPerImage          *float64 `json:"output_cost_per_image"`
+
+	InputCostPer1K       *float64 `json:"input_cost_per_1k"`
+	OutputCostPer1K      *float64 `json:"output_cost_per_1k"`
+	CachedInputCostPer1K *float64 `json:"cached_input_cost_per_1k"`
+	ReasoningCostPer1K   *float64 `json:"reasoning_cost_per_1k"`
+}
+
+// Catalog loads pricing from a file path or http(s) URL into the pricing registry.
+type Catalog struct {
+	source   string
+	registry domain.PricingRegistry
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewCatalog creates the price catalog loader (DI constructor).
+// It returns nil when no catalog source is configured.
+func NewCatalog(cfg *config.PricingCatalogConfig, registry domain.PricingRegistry) *Catalog {
+	if cfg == nil || cfg.Source == "" {
+		return nil
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	return &Catalog{
+		source:   cfg.Source,
+		registry: registry,
+		client: &http.Client{
+			Transport:     nil,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       timeout,
+		},
+		interval: time.Duration(cfg.RefreshInterval) * time.Second,
+	}
+}
+
+// Run loads the catalog immediately and then on the configured interval until
+// ctx is done. Failed loads keep the previously registered prices.
+func (c *Catalog) Run(ctx context.Context) {
+	c.refresh(ctx)
+
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh(ctx)
+		}
+	}
+}
+
+// Load reads the catalog and registers every model price it contains,
+// overriding the prices bundled with providers. It returns the number of models loaded.
+func (c *Catalog) Load(ctx context.Context) (int, error) {
+	data, err := c.read(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	prices, err := Parse(data)
+	if err != nil {
+		return 0, err
+	}
+
+	for model, price := range prices {
+		if registerErr := c.registry.RegisterPricing(ctx, model, price); registerErr != nil {
+			return 0, fmt.Errorf("failed to register pricing for model %s: %w", model, registerErr)
+		}
+	}
+	return len(prices), nil
+}
+
+func (c *Catalog) refresh(ctx context.Context) {
+	logger := observability.FromContext(ctx).With(observability.String("source", c.source))
+
+	loaded, err := c.Load(ctx)
+	if err != nil {
+		observability.PricingCatalogRefreshes.WithLabelValues("error").Inc()
+		logger.Error("failed to load price catalog", observability.Error(err))
+		return
+	}
+
+	observability.PricingCatalogRefreshes.WithLabelValues("success").Inc()
+	logger.Info("price catalog loaded", observability.Int("models", loaded))
+}
+
+// read fetches the raw catalog from a URL or reads it from disk.
+func (c *Catalog) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(c.source, "http://") && !strings.HasPrefix(c.source, "https://") {
+		data, err := os.ReadFile(c.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read price catalog: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build price catalog request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch price catalog: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price catalog: %w", err)
+	}
+	return data, nil
+}
+
+// Parse decodes a JSON price catalog: an object mapping model names to prices.
+// Entries without an input, output, request, or image price, or that fail to decode, are skipped
+// so one malformed model cannot block the rest of the catalog.
+func Parse(data []byte) (map[string]domain.PricingConfig, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid price catalog: %w", err)
+	}
+
+	prices := make(map[string]domain.PricingConfig, len(raw))
+	for model, message := range raw {
+		if model == sampleSpecEntry {
+			continue
+		}
+
+		var entry catalogEntry
+		if err := json.Unmarshal(message, &entry); err != nil {
+			continue
+		}
+
+		price, ok := entry.pricing()
+		if !ok {
+			continue
+		}
+		prices[model] = price
+	}
+	return prices, nil
+}
+
+// pricing converts the entry to per-1K pricing; ok is false when it has no price.
+func (e catalogEntry) pricing() (domain.PricingConfig, bool) {
+	input := perK(e.InputCostPer1K, e.InputCostPerToken)
+	output := perK(e.OutputCostPer1K, e.OutputCostPerToken)
+	if input == nil && output == nil && e.InputCostPerRequest == nil && e.OutputCostPerImage == nil {
+		return domain.PricingConfig{}, false
+	}
+
+	return domain.PricingConfig{
+		InputCostPer1K:       valueOrZero(input),
+		OutputCostPer1K:      valueOrZero(output),
+		CachedInputCostPer1K: valueOrZero(perK(e.CachedInputCostPer1K, e.CacheReadInputTokenCost)),
+		ReasoningCostPer1K:   valueOrZero(perK(e.ReasoningCostPer1K, e.OutputCostPerReasoningToken)),
+		CharacterCostPer1K:   0,
+		RequestCost:          valueOrZero(e.InputCostPerRequest),
+		ImageCost:            valueOrZero(e.OutputCostPerImage),
+	}, true
+}
+
+// perK returns the per-1K price, preferring an explicit per-1K value over a per-token one.
+func perK(per1K, perToken *float64) *float64 {
+	if per1K != nil {
+		return per1K
+	}
+	if perToken != nil {
+		value := *perToken * tokensPerK
+		return &value
+	}
+	return nil
+}
+
+func valueOrZero(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
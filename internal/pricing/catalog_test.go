@@ -0,0 +1,121 @@
+package pricing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/pricing"
+)
+
+const liteLLMCatalog = `{
+	"sample_spec": {"max_tokens": "LEGACY parameter", "input_cost_per_token": 0.0},
+	"gpt-4o": {
+		"input_cost_per_token": 2.5e-06,
+		"output_cost_per_token": 1e-05,
+		"cache_read_input_token_cost": 1.25e-06,
+		"litellm_provider": "openai"
+	},
+	"o3-mini": {"input_cost_per_token": 1.1e-06, "output_cost_per_token": 4.4e-06, "output_cost_per_reasoning_token": 4.4e-06},
+	"text-embedding-3-small": {"input_cost_per_token": 2e-08, "output_cost_per_token": 0},
+	"dall-e-3": {"mode": "image_generation"},
+	"broken": {"input_cost_per_token": "cheap"}
+}`
+
+func TestParse(t *testing.T) {
+	t.Run("should convert LiteLLM per-token prices to per-1K pricing", func(t *testing.T) {
+		prices, err := pricing.Parse([]byte(liteLLMCatalog))
+		require.NoError(t, err)
+
+		require.Len(t, prices, 3)
+		require.InDelta(t, 0.0025, prices["gpt-4o"].InputCostPer1K, 1e-12)
+		require.InDelta(t, 0.01, prices["gpt-4o"].OutputCostPer1K, 1e-12)
+		require.InDelta(t, 0.00125, prices["gpt-4o"].CachedInputCostPer1K, 1e-12)
+		require.InDelta(t, 0.0044, prices["o3-mini"].ReasoningCostPer1K, 1e-12)
+		require.NotContains(t, prices, "sample_spec")
+		require.NotContains(t, prices, "dall-e-3")
+		require.NotContains(t, prices, "broken")
+	})
+
+	t.Run("should prefer per-1K prices", func(t *testing.T) {
+		prices, err := pricing.Parse([]byte(`{"llama-3-70b": {"input_cost_per_1k": 0.0005, "output_cost_per_1k": 0.001}}`))
+		require.NoError(t, err)
+		require.Equal(t, domain.PricingConfig{InputCostPer1K: 0.0005, OutputCostPer1K: 0.001}, prices["llama-3-70b"])
+	})
+
+	t.Run("should convert per-request and per-image prices", func(t *testing.T) {
+		prices, err := pricing.Parse([]byte(`{
+			"sonar": {"input_cost_per_token": 1e-06, "input_cost_per_request": 0.005},
+			"gpt-image-1": {"output_cost_per_image": 0.04}
+		}`))
+		require.NoError(t, err)
+
+		require.InDelta(t, 0.001, prices["sonar"].InputCostPer1K, 1e-12)
+		require.InDelta(t, 0.005, prices["sonar"].RequestCost, 1e-12)
+		require.InDelta(t, 0.04, prices["gpt-image-1"].ImageCost, 1e-12)
+	})
+
+	t.Run("should reject a catalog that is not an object", func(t *testing.T) {
+		_, err := pricing.Parse([]byte(`[]`))
+		require.Error(t, err)
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	t.Run("should return nil without a source", func(t *testing.T) {
+		require.Nil(t, pricing.NewCatalog(&config.PricingCatalogConfig{}, domain.NewInMemoryPricingRegistry()))
+	})
+
+	t.Run("should load prices from a file over bundled prices", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prices.json")
+		require.NoError(t, os.WriteFile(path, []byte(liteLLMCatalog), 0o600))
+
+		registry := domain.NewInMemoryPricingRegistry()
+		require.NoError(t, registry.RegisterPricing(context.Background(), "gpt-4o", domain.PricingConfig{
+			InputCostPer1K:  1,
+			OutputCostPer1K: 1,
+		}))
+
+		catalog := pricing.NewCatalog(&config.PricingCatalogConfig{Source: path}, registry)
+		loaded, err := catalog.Load(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 3, loaded)
+
+		price, err := registry.GetPricing(context.Background(), "gpt-4o")
+		require.NoError(t, err)
+		require.InDelta(t, 0.0025, price.InputCostPer1K, 1e-12)
+	})
+
+	t.Run("should load prices from a URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(liteLLMCatalog))
+		}))
+		defer server.Close()
+
+		registry := domain.NewInMemoryPricingRegistry()
+		catalog := pricing.NewCatalog(&config.PricingCatalogConfig{Source: server.URL, Timeout: 5}, registry)
+		_, err := catalog.Load(context.Background())
+		require.NoError(t, err)
+
+		_, err = registry.GetPricing(context.Background(), "o3-mini")
+		require.NoError(t, err)
+	})
+
+	t.Run("should fail on an unsuccessful fetch", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		catalog := pricing.NewCatalog(&config.PricingCatalogConfig{Source: server.URL}, domain.NewInMemoryPricingRegistry())
+		_, err := catalog.Load(context.Background())
+		require.Error(t, err)
+	})
+}
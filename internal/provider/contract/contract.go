@@ -0,0 +1,242 @@
+//go:build integration
+
+// Package contract checks provider adapters against their real upstream APIs,
+// or against cassettes recorded from them, to catch upstream API drift: changed
+// usage fields, stream framing, or error statuses. It only builds with the
+// integration tag:
+//
+//	go test -tags integration ./internal/provider/...
+//
+// Tool calls are not covered yet.
+package contract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/replay"
+)
+
+const (
+	// RecordEnv, when "true", records the live responses of the suite as cassettes.
+	RecordEnv = "CONTRACT_RECORD"
+
+	// CassetteDirEnv overrides the directory cassettes are recorded to and replayed from.
+	CassetteDirEnv = "CONTRACT_CASSETTE_DIR"
+
+	// timeout bounds every upstream call of the suite.
+	timeout = 60 * time.Second
+
+	// prompt asks for a short, stable reply, keeping recorded cassettes small.
+	prompt = "Reply with the single word: pong"
+)
+
+// Source returns the provider to check: the live adapter built by newProvider
+// when live is set, recording to cassettes when CONTRACT_RECORD is "true", or
+// else a player of the cassettes recorded for name. It skips the test when
+// neither is available.
+func Source(t *testing.T, name string, live bool, newProvider func() (domain.Provider, error)) domain.Provider {
+	t.Helper()
+
+	if live {
+		provider, err := newProvider()
+		require.NoError(t, err)
+		if os.Getenv(RecordEnv) == "true" {
+			return replay.NewRecorder(provider, CassetteDir())
+		}
+		return provider
+	}
+
+	players, err := replay.NewPlayers(CassetteDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		require.NoError(t, err)
+	}
+	for _, player := range players {
+		if player.Name() == name {
+			return player
+		}
+	}
+
+	t.Skipf("no API key and no cassettes for %s", name)
+	return nil
+}
+
+// CassetteDir returns the directory cassettes are recorded to and replayed
+// from, testdata/contract at the repository root unless overridden.
+func CassetteDir() string {
+	if dir := os.Getenv(CassetteDirEnv); dir != "" {
+		return dir
+	}
+
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "testdata", "contract")
+}
+
+// RequireLive skips the test unless it runs against the live API. Errors and
+// speech are never recorded, so their checks cannot be replayed.
+func RequireLive(t *testing.T, live bool) {
+	t.Helper()
+
+	if !live {
+		t.Skip("requires the live API")
+	}
+}
+
+// Completion checks that a completion carries content, the provider's name,
+// and token usage that adds up.
+func Completion(t *testing.T, provider domain.Provider, model string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	resp, err := provider.Complete(ctx, request(model, 16))
+	require.NoError(t, err)
+
+	require.Equal(t, provider.Name(), resp.Provider)
+	require.NotEmpty(t, resp.ID)
+	require.NotEmpty(t, resp.Model)
+	require.NotEmpty(t, strings.TrimSpace(resp.Content))
+	require.False(t, resp.FinishTime.IsZero())
+	requireUsage(t, resp.Usage)
+}
+
+// MaxTokens checks that max_tokens bounds the completion tokens reported.
+func MaxTokens(t *testing.T, provider domain.Provider, model string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	req := request(model, 1)
+	req.Messages[0].Content = "Count from one to twenty in words."
+
+	resp, err := provider.Complete(ctx, req)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, resp.Usage.CompletionTokens)
+}
+
+// Stream checks that a stream delivers content in deltas and ends with a
+// single done chunk carrying no error.
+func Stream(t *testing.T, provider domain.Provider, model string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	chunks, err := provider.Stream(ctx, request(model, 16))
+	require.NoError(t, err)
+
+	var content strings.Builder
+	var done int
+	for chunk := range chunks {
+		require.NoError(t, chunk.Error)
+		require.Zero(t, done, "chunk received after the done chunk")
+		content.WriteString(chunk.Delta)
+		if chunk.Done {
+			done++
+		}
+	}
+
+	require.Equal(t, 1, done, "stream ended without a done chunk")
+	require.NotEmpty(t, strings.TrimSpace(content.String()))
+}
+
+// UpstreamError checks that a request the upstream rejects surfaces as a
+// *domain.ProviderError carrying the provider's name and the upstream status.
+func UpstreamError(t *testing.T, provider domain.Provider, model string, status int) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	req := request(model, 16)
+	req.Temperature = 5 // outside every provider's accepted range
+
+	_, err := provider.Complete(ctx, req)
+
+	var providerErr *domain.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, provider.Name(), providerErr.Provider)
+	require.Equal(t, status, providerErr.StatusCode)
+}
+
+// Speech checks that synthesizing req streams audio of a declared content type.
+func Speech(t *testing.T, synthesizer domain.Synthesizer, req *domain.SpeechRequest) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	resp, err := synthesizer.Synthesize(ctx, req)
+	require.NoError(t, err)
+	defer resp.Audio.Close()
+
+	require.Equal(t, synthesizer.Name(), resp.Provider)
+	require.Contains(t, resp.ContentType, "audio/")
+
+	audio, err := io.ReadAll(resp.Audio)
+	require.NoError(t, err)
+	require.NotEmpty(t, audio)
+}
+
+// SpeechError checks that a speech request the upstream rejects surfaces as a
+// *domain.ProviderError carrying the provider's name and the upstream status.
+func SpeechError(t *testing.T, synthesizer domain.Synthesizer, req *domain.SpeechRequest, status int) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), timeout)
+	defer cancel()
+
+	resp, err := synthesizer.Synthesize(ctx, req)
+	if err == nil {
+		resp.Audio.Close()
+	}
+
+	var providerErr *domain.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, synthesizer.Name(), providerErr.Provider)
+	require.Equal(t, status, providerErr.StatusCode)
+}
+
+// request returns the completion request of the suite. It is the same on
+// every run, so replays find the cassettes recorded for it.
+func request(model string, maxTokens int) *domain.CompletionRequest {
+	return &domain.CompletionRequest{
+		Model:            model,
+		Messages:         []domain.Message{{Role: "user", Content: prompt, ToolCallID: "", ToolCalls: nil}},
+		Temperature:      0,
+		MaxTokens:        maxTokens,
+		Stream:           false,
+		Metadata:         nil,
+		TopP:             nil,
+		Stop:             nil,
+		N:                0,
+		Seed:             nil,
+		FrequencyPenalty: nil,
+		PresencePenalty:  nil,
+		LogitBias:        nil,
+		Tools:            nil,
+	}
+}
+
+// requireUsage checks that usage reports both prompt and completion tokens and
+// that they add up to the total.
+func requireUsage(t *testing.T, usage domain.Usage) {
+	t.Helper()
+
+	require.Positive(t, usage.PromptTokens)
+	require.Positive(t, usage.CompletionTokens)
+	require.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+}
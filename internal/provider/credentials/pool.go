@@ -0,0 +1,254 @@
+// Package credentials provides API key rotation for provider adapters.
+// A Pool spreads requests across several upstream keys and temporarily
+// disables keys that are rejected (401/403) or rate limited (429).
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// Strategy selects the next key to use.
+type Strategy string
+
+const (
+	// StrategyRoundRobin cycles through healthy keys in order.
+	StrategyRoundRobin Strategy = "round-robin"
+
+	// StrategyLeastUsed picks the healthy key with the fewest in-flight requests.
+	StrategyLeastUsed Strategy = "least-used"
+)
+
+const (
+	// authCooldown is how long a key rejected with 401/403 stays disabled.
+	authCooldown = 10 * time.Minute
+
+	// rateLimitCooldown is used for 429 responses without a Retry-After header.
+	rateLimitCooldown = 30 * time.Second
+
+	redactPrefixLen = 3
+	redactSuffixLen = 4
+)
+
+// keyState tracks usage and health of a single key.
+type keyState struct {
+	secret        string
+	disabledUntil time.Time
+	lastStatus    int
+	inFlight      int
+	requests      int64
+	failures      int64
+}
+
+// Pool rotates between multiple API keys.
+type Pool struct {
+	mu       sync.Mutex
+	keys     []*keyState
+	strategy Strategy
+	next     int
+	now      func() time.Time
+}
+
+// Lease is a key acquired for a single upstream request.
+type Lease struct {
+	pool *Pool
+	key  *keyState
+}
+
+// NewPool creates a key pool. Empty and duplicate keys are ignored.
+func NewPool(keys []string, strategy Strategy) (*Pool, error) {
+	switch strategy {
+	case "":
+		strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyLeastUsed:
+	default:
+		return nil, fmt.Errorf("unknown key selection strategy: %s", strategy)
+	}
+
+	seen := make(map[string]bool, len(keys))
+	states := make([]*keyState, 0, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		states = append(states, &keyState{
+			secret:        key,
+			disabledUntil: time.Time{},
+			lastStatus:    0,
+			inFlight:      0,
+			requests:      0,
+			failures:      0,
+		})
+	}
+
+	if len(states) == 0 {
+		return nil, errors.New("at least one API key is required")
+	}
+
+	return &Pool{
+		mu:       sync.Mutex{},
+		keys:     states,
+		strategy: strategy,
+		next:     0,
+		now:      time.Now,
+	}, nil
+}
+
+// Acquire selects a key according to the pool strategy.
+// When every key is disabled, the key that recovers soonest is returned so
+// requests keep flowing and the upstream error reaches the client.
+func (p *Pool) Acquire() *Lease {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+
+	var selected *keyState
+	switch p.strategy {
+	case StrategyLeastUsed:
+		selected = p.leastUsed(now)
+	default:
+		selected = p.roundRobin(now)
+	}
+
+	if selected == nil {
+		selected = p.soonestAvailable()
+	}
+
+	selected.inFlight++
+	selected.requests++
+	return &Lease{pool: p, key: selected}
+}
+
+// Size returns the number of keys in the pool.
+func (p *Pool) Size() int {
+	return len(p.keys)
+}
+
+// Status returns redacted health information for every key.
+func (p *Pool) Status() []domain.CredentialStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	statuses := make([]domain.CredentialStatus, 0, len(p.keys))
+	for _, key := range p.keys {
+		status := domain.CredentialStatus{
+			ID:            Redact(key.secret),
+			Healthy:       !now.Before(key.disabledUntil),
+			DisabledUntil: time.Time{},
+			LastStatus:    key.lastStatus,
+			InFlight:      key.inFlight,
+			Requests:      key.requests,
+			Failures:      key.failures,
+		}
+		if !status.Healthy {
+			status.DisabledUntil = key.disabledUntil
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// roundRobin returns the next healthy key in order. Caller must hold the lock.
+func (p *Pool) roundRobin(now time.Time) *keyState {
+	for range p.keys {
+		key := p.keys[p.next]
+		p.next = (p.next + 1) % len(p.keys)
+		if !now.Before(key.disabledUntil) {
+			return key
+		}
+	}
+	return nil
+}
+
+// leastUsed returns the healthy key with the fewest in-flight requests. Caller must hold the lock.
+func (p *Pool) leastUsed(now time.Time) *keyState {
+	var selected *keyState
+	for _, key := range p.keys {
+		if now.Before(key.disabledUntil) {
+			continue
+		}
+		if selected == nil || key.inFlight < selected.inFlight ||
+			(key.inFlight == selected.inFlight && key.requests < selected.requests) {
+			selected = key
+		}
+	}
+	return selected
+}
+
+// soonestAvailable returns the disabled key that recovers first. Caller must hold the lock.
+func (p *Pool) soonestAvailable() *keyState {
+	selected := p.keys[0]
+	for _, key := range p.keys[1:] {
+		if key.disabledUntil.Before(selected.disabledUntil) {
+			selected = key
+		}
+	}
+	return selected
+}
+
+// Key returns the secret to send upstream.
+func (l *Lease) Key() string {
+	return l.key.secret
+}
+
+// Release returns the key to the pool with the upstream outcome.
+// statusCode is the upstream HTTP status (0 when no response was received);
+// retryAfter overrides the default cooldown for 429 responses when positive.
+func (l *Lease) Release(statusCode int, retryAfter time.Duration) {
+	l.pool.mu.Lock()
+	defer l.pool.mu.Unlock()
+
+	key := l.key
+	key.inFlight--
+	if statusCode != 0 {
+		key.lastStatus = statusCode
+	}
+
+	now := l.pool.now()
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		key.failures++
+		key.disabledUntil = now.Add(authCooldown)
+	case http.StatusTooManyRequests:
+		key.failures++
+		if retryAfter <= 0 {
+			retryAfter = rateLimitCooldown
+		}
+		key.disabledUntil = now.Add(retryAfter)
+	}
+}
+
+// RetryAfter parses a Retry-After header expressed in seconds or as an HTTP date.
+func RetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// Redact hides all but the edges of a secret, e.g. "sk-...abcd".
+func Redact(secret string) string {
+	if len(secret) <= redactPrefixLen+redactSuffixLen {
+		return "..."
+	}
+	return secret[:redactPrefixLen] + "..." + secret[len(secret)-redactSuffixLen:]
+}
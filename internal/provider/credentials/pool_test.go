@@ -0,0 +1,115 @@
+package credentials_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/provider/credentials"
+)
+
+func TestNewPool(t *testing.T) {
+	t.Run("should deduplicate and skip empty keys", func(t *testing.T) {
+		pool, err := credentials.NewPool([]string{"sk-key-one", "", "sk-key-one", "sk-key-two"}, "")
+
+		require.NoError(t, err)
+		require.Equal(t, 2, pool.Size())
+	})
+
+	t.Run("should require at least one key", func(t *testing.T) {
+		pool, err := credentials.NewPool([]string{""}, credentials.StrategyRoundRobin)
+
+		require.Error(t, err)
+		require.Nil(t, pool)
+	})
+
+	t.Run("should reject unknown strategy", func(t *testing.T) {
+		pool, err := credentials.NewPool([]string{"sk-key-one"}, "random")
+
+		require.Error(t, err)
+		require.Nil(t, pool)
+		require.Contains(t, err.Error(), "unknown key selection strategy")
+	})
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	pool, err := credentials.NewPool([]string{"sk-key-one", "sk-key-two"}, credentials.StrategyRoundRobin)
+	require.NoError(t, err)
+
+	first := pool.Acquire()
+	second := pool.Acquire()
+	third := pool.Acquire()
+
+	require.Equal(t, "sk-key-one", first.Key())
+	require.Equal(t, "sk-key-two", second.Key())
+	require.Equal(t, "sk-key-one", third.Key())
+}
+
+func TestPool_LeastUsed(t *testing.T) {
+	pool, err := credentials.NewPool([]string{"sk-key-one", "sk-key-two"}, credentials.StrategyLeastUsed)
+	require.NoError(t, err)
+
+	first := pool.Acquire()
+	second := pool.Acquire()
+	require.NotEqual(t, first.Key(), second.Key())
+
+	first.Release(http.StatusOK, 0)
+	third := pool.Acquire()
+	require.Equal(t, first.Key(), third.Key())
+}
+
+func TestPool_DisablesFailingKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized},
+		{name: "rate limited", status: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := credentials.NewPool([]string{"sk-key-one", "sk-key-two"}, credentials.StrategyRoundRobin)
+			require.NoError(t, err)
+
+			pool.Acquire().Release(tt.status, time.Minute)
+
+			for range 3 {
+				lease := pool.Acquire()
+				require.Equal(t, "sk-key-two", lease.Key())
+				lease.Release(http.StatusOK, 0)
+			}
+
+			statuses := pool.Status()
+			require.Len(t, statuses, 2)
+			require.False(t, statuses[0].Healthy)
+			require.Equal(t, tt.status, statuses[0].LastStatus)
+			require.Equal(t, int64(1), statuses[0].Failures)
+			require.False(t, statuses[0].DisabledUntil.IsZero())
+			require.True(t, statuses[1].Healthy)
+		})
+	}
+}
+
+func TestPool_Status_Redacted(t *testing.T) {
+	pool, err := credentials.NewPool([]string{"sk-secret-abcd"}, credentials.StrategyRoundRobin)
+	require.NoError(t, err)
+
+	statuses := pool.Status()
+
+	require.Len(t, statuses, 1)
+	require.Equal(t, "sk-...abcd", statuses[0].ID)
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	require.Zero(t, credentials.RetryAfter(header))
+
+	header.Set("Retry-After", "12")
+	require.Equal(t, 12*time.Second, credentials.RetryAfter(header))
+
+	header.Set("Retry-After", "soon")
+	require.Zero(t, credentials.RetryAfter(header))
+}
@@ -47,15 +47,23 @@ func (p *Provider) Complete(ctx context.Context, req *domain.CompletionRequest)
 		return nil, fmt.Errorf("model %s is not supported by echo provider", req.Model)
 	}
 
+	if err := p.checkParameters(req); err != nil {
+		return nil, err
+	}
+
 	logger := observability.FromContext(ctx)
 	logger.Debug("echoing request")
 
 	// Build echo content from messages
-	echoContent := buildEchoContent(req.Messages)
+	echoContent := applyStop(buildEchoContent(req.Messages), req.Stop)
 
 	// Count tokens (simple word-based counting)
 	promptTokens := countTokens(echoContent)
 	completionTokens := promptTokens // Echo returns same size
+	choices := echoChoices(echoContent, req.N)
+	if len(choices) > 0 {
+		completionTokens *= len(choices)
+	}
 	totalTokens := promptTokens + completionTokens
 
 	logger.Debug("echo completed",
@@ -64,17 +72,28 @@ func (p *Provider) Complete(ctx context.Context, req *domain.CompletionRequest)
 	)
 
 	return &domain.CompletionResponse{
-		ID:       fmt.Sprintf("echo-%d", time.Now().UnixNano()),
-		Model:    req.Model,
-		Provider: p.name,
-		Content:  echoContent,
+		ID:        fmt.Sprintf("echo-%d", time.Now().UnixNano()),
+		Model:     req.Model,
+		Provider:  p.name,
+		Content:   echoContent,
+		Choices:   choices,
+		ToolCalls: nil,
 		Usage: domain.Usage{
 			PromptTokens:     promptTokens,
 			CompletionTokens: completionTokens,
 			TotalTokens:      totalTokens,
 			Cost:             0.0,
+
+			CachedPromptTokens: 0,
+			ReasoningTokens:    0,
+			Characters:         0,
+			Images:             0,
+			ProviderCost:       0,
+			Currency:           "",
 		},
-		FinishTime: time.Now(),
+		FinishTime:      time.Now(),
+		Metadata:        nil,
+		ProviderHeaders: nil,
 	}, nil
 }
 
@@ -88,11 +107,15 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 		return nil, fmt.Errorf("model %s is not supported by echo provider", req.Model)
 	}
 
+	if err := p.checkParameters(req); err != nil {
+		return nil, err
+	}
+
 	logger := observability.FromContext(ctx)
 	logger.Debug("streaming echo request")
 
 	// Build echo content
-	echoContent := buildEchoContent(req.Messages)
+	echoContent := applyStop(buildEchoContent(req.Messages), req.Stop)
 
 	// Create output channel
 	chunks := make(chan domain.StreamChunk)
@@ -106,7 +129,7 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 		if len(words) == 0 {
 			// Send empty done chunk
 			select {
-			case chunks <- domain.StreamChunk{Delta: "", Done: true, Error: nil}:
+			case chunks <- domain.StreamChunk{Delta: "", Done: true, Error: nil, ProviderHeaders: nil, Metadata: nil}:
 			case <-ctx.Done():
 			}
 			return
@@ -122,19 +145,21 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 			select {
 			case <-ctx.Done():
 				chunks <- domain.StreamChunk{
-					Delta: "",
-					Done:  true,
-					Error: ctx.Err(),
+					Delta:           "",
+					Done:            true,
+					Error:           ctx.Err(),
+					ProviderHeaders: nil,
+					Metadata:        nil,
 				}
 				return
-			case chunks <- domain.StreamChunk{Delta: delta, Done: false, Error: nil}:
+			case chunks <- domain.StreamChunk{Delta: delta, Done: false, Error: nil, ProviderHeaders: nil, Metadata: nil}:
 				time.Sleep(chunkDelay)
 			}
 		}
 
 		// Send final done chunk
 		select {
-		case chunks <- domain.StreamChunk{Delta: "", Done: true, Error: nil}:
+		case chunks <- domain.StreamChunk{Delta: "", Done: true, Error: nil, ProviderHeaders: nil, Metadata: nil}:
 		case <-ctx.Done():
 		}
 	}()
@@ -161,6 +186,46 @@ func (p *Provider) SupportedModels(_ context.Context) []string {
 	return models
 }
 
+// checkParameters rejects parameters the echo provider cannot honor.
+// Sampling parameters are accepted and ignored because echo output is deterministic.
+func (p *Provider) checkParameters(req *domain.CompletionRequest) error {
+	if len(req.LogitBias) > 0 {
+		return &domain.UnsupportedParameterError{Provider: p.name, Parameter: "logit_bias"}
+	}
+	if len(req.Tools) > 0 {
+		return &domain.UnsupportedParameterError{Provider: p.name, Parameter: "tools"}
+	}
+
+	return nil
+}
+
+// applyStop truncates content at the earliest stop sequence.
+func applyStop(content string, stop []string) string {
+	end := len(content)
+	for _, sequence := range stop {
+		if sequence == "" {
+			continue
+		}
+		if idx := strings.Index(content, sequence); idx >= 0 && idx < end {
+			end = idx
+		}
+	}
+	return content[:end]
+}
+
+// echoChoices repeats the echoed content n times; n <= 1 yields no choices.
+func echoChoices(content string, n int) []domain.Choice {
+	if n <= 1 {
+		return nil
+	}
+
+	choices := make([]domain.Choice, n)
+	for i := range choices {
+		choices[i] = domain.Choice{Index: i, Content: content, FinishReason: "stop"}
+	}
+	return choices
+}
+
 // buildEchoContent constructs the echo response from request messages.
 func buildEchoContent(messages []domain.Message) string {
 	if len(messages) == 0 {
@@ -71,6 +71,65 @@ func TestComplete_UnsupportedModel(t *testing.T) {
 	require.Contains(t, err.Error(), "not supported")
 }
 
+func TestComplete_StopSequence(t *testing.T) {
+	provider := echo.NewProvider()
+	ctx := context.Background()
+
+	req := &domain.CompletionRequest{
+		Model: "echo4",
+		Messages: []domain.Message{
+			{Role: "user", Content: "Hello world. Goodbye"},
+		},
+		Stop: []string{"Goodbye", "."},
+	}
+
+	resp, err := provider.Complete(ctx, req)
+
+	require.NoError(t, err)
+	require.Equal(t, "[user]: Hello world", resp.Content)
+}
+
+func TestComplete_UnsupportedParameter(t *testing.T) {
+	provider := echo.NewProvider()
+	ctx := context.Background()
+
+	req := &domain.CompletionRequest{
+		Model: "echo4",
+		Messages: []domain.Message{
+			{Role: "user", Content: "Hello"},
+		},
+		LogitBias: map[string]int{"50256": -100},
+	}
+
+	resp, err := provider.Complete(ctx, req)
+
+	var unsupportedErr *domain.UnsupportedParameterError
+	require.ErrorAs(t, err, &unsupportedErr)
+	require.Nil(t, resp)
+	require.Equal(t, "logit_bias", unsupportedErr.Parameter)
+}
+
+func TestComplete_MultipleChoices(t *testing.T) {
+	provider := echo.NewProvider()
+	ctx := context.Background()
+
+	req := &domain.CompletionRequest{
+		Model: "echo4",
+		Messages: []domain.Message{
+			{Role: "user", Content: "Hello"},
+		},
+		N: 3,
+	}
+
+	resp, err := provider.Complete(ctx, req)
+
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 3)
+	require.Equal(t, 2, resp.Choices[2].Index)
+	require.Equal(t, resp.Content, resp.Choices[2].Content)
+	require.Equal(t, 3*resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+}
+
 func TestComplete_EmptyMessages(t *testing.T) {
 	provider := echo.NewProvider()
 	ctx := context.Background()
@@ -0,0 +1,24 @@
+package echo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// echo4ContextWindow is small so history trimming can be exercised locally.
+const echo4ContextWindow = 4096
+
+// RegisterCapabilities registers echo and chaos model capabilities with the registry.
+func RegisterCapabilities(ctx context.Context, registry domain.CapabilityRegistry) error {
+	for _, model := range []string{modelName, chaosModelName} {
+		if err := registry.RegisterCapabilities(ctx, model, domain.ModelCapabilities{
+			ContextWindow:   echo4ContextWindow,
+			MaxOutputTokens: 0,
+		}); err != nil {
+			return fmt.Errorf("failed to register echo capabilities: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// ChaosProviderName identifies the chaos provider.
+const ChaosProviderName = "chaos"
+
+const chaosModelName = "chaos4"
+
+// Request metadata fields controlling the faults a chaos request injects.
+const (
+	// MetadataChaosLatency delays the response, or a stream's first chunk, by a number of
+	// milliseconds, either fixed ("200") or uniformly drawn from a range ("100-500").
+	MetadataChaosLatency = "chaos_latency_ms"
+
+	// MetadataChaosErrorRate is the probability, from 0 to 1, that the request fails.
+	MetadataChaosErrorRate = "chaos_error_rate"
+
+	// MetadataChaosMalformedRate is the probability, from 0 to 1, that each stream
+	// chunk carries corrupted bytes instead of its text.
+	MetadataChaosMalformedRate = "chaos_malformed_rate"
+
+	// MetadataChaosDropAfter ends a stream with an unexpected EOF after this many chunks.
+	MetadataChaosDropAfter = "chaos_drop_after"
+)
+
+// malformedDelta is invalid UTF-8, as produced by a corrupted upstream frame.
+const malformedDelta = "\xff\xfe\xfd"
+
+// ErrChaosInjected is the failure returned when a chaos request draws an error.
+var ErrChaosInjected = errors.New("chaos: injected provider failure")
+
+// chaosFaults are the faults requested through a request's metadata.
+type chaosFaults struct {
+	minLatency    time.Duration
+	maxLatency    time.Duration
+	errorRate     float64
+	malformedRate float64
+	dropAfter     int // 0 never drops
+}
+
+// ChaosProvider echoes requests like Provider, but injects the latency, errors,
+// malformed chunks, and mid-stream drops asked for in each request's metadata,
+// so routing and failure handling can be exercised without a real provider.
+type ChaosProvider struct {
+	echo *Provider
+}
+
+// NewChaosProvider creates a chaos provider.
+func NewChaosProvider() *ChaosProvider {
+	return &ChaosProvider{
+		echo: &Provider{
+			name:            ChaosProviderName,
+			supportedModels: map[string]bool{chaosModelName: true},
+		},
+	}
+}
+
+// Complete echoes the request after the requested latency, unless an error is drawn.
+func (p *ChaosProvider) Complete(
+	ctx context.Context,
+	req *domain.CompletionRequest,
+) (*domain.CompletionResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	faults, err := parseChaosFaults(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = faults.delay(ctx); err != nil {
+		return nil, err
+	}
+	if faults.fail() {
+		observability.FromContext(ctx).Debug("chaos provider injected failure")
+		return nil, ErrChaosInjected
+	}
+
+	return p.echo.Complete(ctx, req)
+}
+
+// Stream echoes the request as a stream with the requested faults injected. An
+// injected error fails the call before any chunk is sent.
+func (p *ChaosProvider) Stream(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.StreamChunk, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	faults, err := parseChaosFaults(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if faults.fail() {
+		observability.FromContext(ctx).Debug("chaos provider injected failure")
+		return nil, ErrChaosInjected
+	}
+
+	in, err := p.echo.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan domain.StreamChunk)
+	go func() {
+		defer close(out)
+		// Drain the echo stream so its goroutine exits however this one stops.
+		defer func() {
+			for range in {
+				continue
+			}
+		}()
+
+		// Latency delays the first chunk, as a slow upstream would.
+		if faults.delay(ctx) != nil {
+			return
+		}
+
+		sent := 0
+		for chunk := range in {
+			if faults.dropAfter > 0 && sent == faults.dropAfter {
+				chunk = domain.StreamChunk{
+					Delta:           "",
+					Done:            true,
+					Error:           fmt.Errorf("chaos: stream dropped: %w", io.ErrUnexpectedEOF),
+					ProviderHeaders: nil,
+					Metadata:        nil,
+				}
+			} else if !chunk.Done && faults.malformed() {
+				chunk.Delta = malformedDelta
+			}
+
+			select {
+			case out <- chunk:
+				sent++
+			case <-ctx.Done():
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Name returns the provider identifier.
+func (p *ChaosProvider) Name() string {
+	return p.echo.Name()
+}
+
+// IsModelSupported checks if the provider supports the given model.
+func (p *ChaosProvider) IsModelSupported(ctx context.Context, model string) bool {
+	return p.echo.IsModelSupported(ctx, model)
+}
+
+// SupportedModels returns a list of all models this provider supports.
+func (p *ChaosProvider) SupportedModels(ctx context.Context) []string {
+	return p.echo.SupportedModels(ctx)
+}
+
+// parseChaosFaults reads the chaos fields of request metadata.
+func parseChaosFaults(metadata map[string]string) (chaosFaults, error) {
+	faults := chaosFaults{minLatency: 0, maxLatency: 0, errorRate: 0, malformedRate: 0, dropAfter: 0}
+
+	if value, ok := metadata[MetadataChaosLatency]; ok {
+		low, high, isRange := strings.Cut(value, "-")
+		if !isRange {
+			high = low
+		}
+		minMs, minErr := strconv.Atoi(strings.TrimSpace(low))
+		maxMs, maxErr := strconv.Atoi(strings.TrimSpace(high))
+		if minErr != nil || maxErr != nil || minMs < 0 || maxMs < minMs {
+			return chaosFaults{}, fmt.Errorf("invalid %s %q: expected milliseconds or a min-max range",
+				MetadataChaosLatency, value)
+		}
+		faults.minLatency = time.Duration(minMs) * time.Millisecond
+		faults.maxLatency = time.Duration(maxMs) * time.Millisecond
+	}
+
+	var err error
+	if faults.errorRate, err = parseChaosRate(metadata, MetadataChaosErrorRate); err != nil {
+		return chaosFaults{}, err
+	}
+	if faults.malformedRate, err = parseChaosRate(metadata, MetadataChaosMalformedRate); err != nil {
+		return chaosFaults{}, err
+	}
+
+	if value, ok := metadata[MetadataChaosDropAfter]; ok {
+		faults.dropAfter, err = strconv.Atoi(value)
+		if err != nil || faults.dropAfter < 1 {
+			return chaosFaults{}, fmt.Errorf("invalid %s %q: expected a positive chunk count",
+				MetadataChaosDropAfter, value)
+		}
+	}
+
+	return faults, nil
+}
+
+// parseChaosRate reads a probability from request metadata; missing fields are 0.
+func parseChaosRate(metadata map[string]string, field string) (float64, error) {
+	value, ok := metadata[field]
+	if !ok {
+		return 0, nil
+	}
+
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("invalid %s %q: expected a probability from 0 to 1", field, value)
+	}
+	return rate, nil
+}
+
+// delay waits for a latency drawn from the requested range.
+func (f chaosFaults) delay(ctx context.Context) error {
+	latency := f.minLatency
+	if f.maxLatency > f.minLatency {
+		latency += rand.N(f.maxLatency - f.minLatency + 1) //nolint:gosec // Not security-sensitive
+	}
+	if latency <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("chaos: %w", ctx.Err())
+	}
+}
+
+// fail draws whether the request fails.
+func (f chaosFaults) fail() bool {
+	return f.errorRate > 0 && rand.Float64() < f.errorRate //nolint:gosec // Not security-sensitive
+}
+
+// malformed draws whether a chunk is corrupted.
+func (f chaosFaults) malformed() bool {
+	return f.malformedRate > 0 && rand.Float64() < f.malformedRate //nolint:gosec // Not security-sensitive
+}
@@ -0,0 +1,111 @@
+package echo_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/echo"
+)
+
+func chaosRequest(metadata map[string]string) *domain.CompletionRequest {
+	return &domain.CompletionRequest{
+		Model:    "chaos4",
+		Messages: []domain.Message{{Role: "user", Content: "one two three four"}},
+		Metadata: metadata,
+	}
+}
+
+func collectChunks(t *testing.T, chunks <-chan domain.StreamChunk) []domain.StreamChunk {
+	t.Helper()
+
+	var received []domain.StreamChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+	}
+	return received
+}
+
+func TestChaosProvider(t *testing.T) {
+	provider := echo.NewChaosProvider()
+
+	t.Run("should echo like the echo provider without faults", func(t *testing.T) {
+		resp, err := provider.Complete(context.Background(), chaosRequest(nil))
+
+		require.NoError(t, err)
+		require.Equal(t, "chaos", resp.Provider)
+		require.Equal(t, "[user]: one two three four\n", resp.Content)
+		require.True(t, provider.IsModelSupported(context.Background(), "chaos4"))
+	})
+
+	t.Run("should fail when the error rate is 1", func(t *testing.T) {
+		metadata := map[string]string{echo.MetadataChaosErrorRate: "1"}
+
+		_, err := provider.Complete(context.Background(), chaosRequest(metadata))
+		require.ErrorIs(t, err, echo.ErrChaosInjected)
+
+		_, err = provider.Stream(context.Background(), chaosRequest(metadata))
+		require.ErrorIs(t, err, echo.ErrChaosInjected)
+	})
+
+	t.Run("should delay the response by the requested latency", func(t *testing.T) {
+		start := time.Now()
+		_, err := provider.Complete(context.Background(), chaosRequest(map[string]string{
+			echo.MetadataChaosLatency: "30-40",
+		}))
+
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("should give up waiting when the context ends", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := provider.Complete(ctx, chaosRequest(map[string]string{echo.MetadataChaosLatency: "5000"}))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should drop the stream after the requested chunks", func(t *testing.T) {
+		chunks, err := provider.Stream(context.Background(), chaosRequest(map[string]string{
+			echo.MetadataChaosDropAfter: "2",
+		}))
+		require.NoError(t, err)
+
+		received := collectChunks(t, chunks)
+		require.Len(t, received, 3)
+		require.True(t, received[2].Done)
+		require.ErrorIs(t, received[2].Error, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("should corrupt chunks at the malformed rate", func(t *testing.T) {
+		chunks, err := provider.Stream(context.Background(), chaosRequest(map[string]string{
+			echo.MetadataChaosMalformedRate: "1",
+		}))
+		require.NoError(t, err)
+
+		received := collectChunks(t, chunks)
+		require.NotEmpty(t, received)
+		for _, chunk := range received[:len(received)-1] {
+			require.Equal(t, "\xff\xfe\xfd", chunk.Delta)
+		}
+		require.True(t, received[len(received)-1].Done)
+		require.NoError(t, received[len(received)-1].Error)
+	})
+
+	t.Run("should reject invalid fault settings", func(t *testing.T) {
+		for _, metadata := range []map[string]string{
+			{echo.MetadataChaosLatency: "500-100"},
+			{echo.MetadataChaosErrorRate: "1.5"},
+			{echo.MetadataChaosMalformedRate: "often"},
+			{echo.MetadataChaosDropAfter: "0"},
+		} {
+			_, err := provider.Complete(context.Background(), chaosRequest(metadata))
+			require.Error(t, err, metadata)
+		}
+	})
+}
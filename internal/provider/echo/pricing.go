@@ -12,14 +12,21 @@ const (
 	echo4OutputCostPer1K = 0.0
 )
 
-// RegisterPricing registers echo model pricing with the registry.
+// RegisterPricing registers echo and chaos model pricing with the registry.
 // Echo models have zero cost as they are for testing purposes only.
 func RegisterPricing(ctx context.Context, registry domain.PricingRegistry) error {
-	if err := registry.RegisterPricing(ctx, modelName, domain.PricingConfig{
-		InputCostPer1K:  echo4InputCostPer1K,
-		OutputCostPer1K: echo4OutputCostPer1K,
-	}); err != nil {
-		return fmt.Errorf("failed to register echo pricing: %w", err)
+	for _, model := range []string{modelName, chaosModelName} {
+		if err := registry.RegisterPricing(ctx, model, domain.PricingConfig{
+			InputCostPer1K:       echo4InputCostPer1K,
+			OutputCostPer1K:      echo4OutputCostPer1K,
+			CachedInputCostPer1K: 0,
+			ReasoningCostPer1K:   0,
+			CharacterCostPer1K:   0,
+			RequestCost:          0,
+			ImageCost:            0,
+		}); err != nil {
+			return fmt.Errorf("failed to register echo pricing: %w", err)
+		}
 	}
 	return nil
 }
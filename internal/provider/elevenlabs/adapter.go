@@ -0,0 +1,132 @@
+// Package elevenlabs provides a speech-only adapter for the ElevenLabs
+// text-to-speech API. It implements domain.Synthesizer over plain HTTP.
+package elevenlabs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/provider/credentials"
+	"github.com/davidbz/calcifer/internal/provider/transport"
+)
+
+// ProviderName identifies the ElevenLabs provider.
+const ProviderName = "elevenlabs"
+
+// maxErrorBytes bounds how much of an upstream error body is kept.
+const maxErrorBytes = 4096
+
+// Provider implements domain.Synthesizer for ElevenLabs.
+type Provider struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+// NewProvider creates a new ElevenLabs provider.
+func NewProvider(config Config) (*Provider, error) {
+	if !config.HasAPIKey() {
+		return nil, errors.New("ElevenLabs API key is required")
+	}
+
+	return &Provider{
+		client: &http.Client{
+			Transport:     transport.Shared(config.Transport),
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       time.Duration(config.Timeout) * time.Second,
+		},
+		apiKey:  config.APIKey,
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+	}, nil
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// SpeechModels returns the text-to-speech models the provider serves.
+func (p *Provider) SpeechModels(_ context.Context) []string {
+	return SpeechModels()
+}
+
+// Synthesize streams speech for req.Input spoken by the voice with ID req.Voice.
+func (p *Provider) Synthesize(ctx context.Context, req *domain.SpeechRequest) (*domain.SpeechResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	body := map[string]any{"text": req.Input, "model_id": req.Model}
+	if req.Speed > 0 {
+		body["voice_settings"] = map[string]any{"speed": req.Speed}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ElevenLabs request: %w", err)
+	}
+
+	endpoint := p.baseURL + "/v1/text-to-speech/" + url.PathEscape(req.Voice) + "/stream"
+	if format := outputFormat(req.ResponseFormat); format != "" {
+		endpoint += "?output_format=" + url.QueryEscape(format)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ElevenLabs request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Xi-Api-Key", p.apiKey)
+
+	observability.FromContext(ctx).Debug("calling ElevenLabs speech API")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ElevenLabs speech call failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return nil, &domain.ProviderError{
+			Provider:   ProviderName,
+			StatusCode: resp.StatusCode,
+			Code:       "",
+			RetryAfter: credentials.RetryAfter(resp.Header),
+			Headers:    nil,
+			Err:        fmt.Errorf("ElevenLabs speech call failed: %s", strings.TrimSpace(string(message))),
+		}
+	}
+
+	return &domain.SpeechResponse{
+		Audio:       resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Provider:    ProviderName,
+		Model:       req.Model,
+		Usage:       domain.Usage{}, // Billed per character by the gateway
+	}, nil
+}
+
+// outputFormat maps OpenAI-style response formats to ElevenLabs output formats.
+// Other values are passed through as ElevenLabs formats, e.g. "mp3_22050_32".
+func outputFormat(format string) string {
+	switch format {
+	case "mp3":
+		return "mp3_44100_128"
+	case "pcm":
+		return "pcm_24000"
+	case "opus":
+		return "opus_48000_128"
+	default:
+		return format
+	}
+}
@@ -0,0 +1,75 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/elevenlabs"
+)
+
+func TestProvider_Synthesize(t *testing.T) {
+	newProvider := func(t *testing.T, handler http.HandlerFunc) *elevenlabs.Provider {
+		t.Helper()
+
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		provider, err := elevenlabs.NewProvider(elevenlabs.Config{APIKey: "xi-key", BaseURL: server.URL, Timeout: 5})
+		require.NoError(t, err)
+		return provider
+	}
+	req := &domain.SpeechRequest{Model: "eleven_flash_v2_5", Input: "Hello", Voice: "voice-1", ResponseFormat: "mp3"}
+
+	t.Run("should stream audio from the voice endpoint", func(t *testing.T) {
+		provider := newProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/v1/text-to-speech/voice-1/stream", r.URL.Path)
+			require.Equal(t, "mp3_44100_128", r.URL.Query().Get("output_format"))
+			require.Equal(t, "xi-key", r.Header.Get("Xi-Api-Key"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, "Hello", body["text"])
+			require.Equal(t, "eleven_flash_v2_5", body["model_id"])
+
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write([]byte("mp3-bytes"))
+		})
+
+		response, err := provider.Synthesize(context.Background(), req)
+		require.NoError(t, err)
+		defer response.Audio.Close()
+
+		audio, err := io.ReadAll(response.Audio)
+		require.NoError(t, err)
+		require.Equal(t, "mp3-bytes", string(audio))
+		require.Equal(t, "audio/mpeg", response.ContentType)
+	})
+
+	t.Run("should report upstream errors with their status", func(t *testing.T) {
+		provider := newProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "3")
+			http.Error(w, `{"detail":"quota exceeded"}`, http.StatusTooManyRequests)
+		})
+
+		_, err := provider.Synthesize(context.Background(), req)
+
+		var providerErr *domain.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+		require.Equal(t, "elevenlabs", providerErr.Provider)
+		require.ErrorContains(t, err, "quota exceeded")
+	})
+
+	t.Run("should require an API key", func(t *testing.T) {
+		_, err := elevenlabs.NewProvider(elevenlabs.Config{})
+
+		require.Error(t, err)
+	})
+}
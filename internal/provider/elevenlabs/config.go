@@ -0,0 +1,18 @@
+package elevenlabs
+
+import "github.com/davidbz/calcifer/internal/provider/transport"
+
+// Config contains ElevenLabs provider configuration. The provider only serves
+// speech requests and is registered when APIKey is set.
+type Config struct {
+	APIKey  string `env:"ELEVENLABS_API_KEY"`
+	BaseURL string `env:"ELEVENLABS_BASE_URL" envDefault:"https://api.elevenlabs.io"`
+	Timeout int    `env:"ELEVENLABS_TIMEOUT"  envDefault:"60"` // seconds, for the whole audio stream
+
+	Transport transport.Config `envPrefix:"ELEVENLABS_"`
+}
+
+// HasAPIKey reports whether an API key is configured.
+func (c Config) HasAPIKey() bool {
+	return c.APIKey != ""
+}
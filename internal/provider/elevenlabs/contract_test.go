@@ -0,0 +1,56 @@
+//go:build integration
+
+package elevenlabs_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/contract"
+	"github.com/davidbz/calcifer/internal/provider/elevenlabs"
+)
+
+func TestContract(t *testing.T) {
+	config := elevenlabs.Config{
+		APIKey:  os.Getenv("ELEVENLABS_API_KEY"),
+		BaseURL: os.Getenv("ELEVENLABS_BASE_URL"),
+		Timeout: 60,
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.elevenlabs.io"
+	}
+	voice := os.Getenv("CONTRACT_ELEVENLABS_VOICE")
+	if voice == "" {
+		voice = "21m00Tcm4TlvDq8ikWAM" // Rachel, a premade voice every account has
+	}
+	req := &domain.SpeechRequest{
+		Model:          "eleven_flash_v2_5",
+		Input:          "pong",
+		Voice:          voice,
+		ResponseFormat: "mp3",
+		Speed:          0,
+		Metadata:       nil,
+	}
+
+	t.Run("should synthesize speech", func(t *testing.T) {
+		contract.RequireLive(t, config.HasAPIKey())
+		provider, err := elevenlabs.NewProvider(config)
+		require.NoError(t, err)
+
+		contract.Speech(t, provider, req)
+	})
+
+	t.Run("should map rejected API keys to provider errors", func(t *testing.T) {
+		contract.RequireLive(t, config.HasAPIKey())
+		rejected := config
+		rejected.APIKey = "invalid"
+		provider, err := elevenlabs.NewProvider(rejected)
+		require.NoError(t, err)
+
+		contract.SpeechError(t, provider, req, http.StatusUnauthorized)
+	})
+}
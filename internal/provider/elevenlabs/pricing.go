@@ -0,0 +1,50 @@
+package elevenlabs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+const (
+	// Multilingual model pricing per 1K characters
+	multilingualCharacterCostPer1K = 0.10
+
+	// Flash and Turbo model pricing per 1K characters
+	flashCharacterCostPer1K = 0.05
+)
+
+// SpeechModels returns the text-to-speech models served by the ElevenLabs provider.
+func SpeechModels() []string {
+	return []string{
+		"eleven_multilingual_v2",
+		"eleven_turbo_v2_5",
+		"eleven_flash_v2_5",
+	}
+}
+
+// RegisterPricing registers ElevenLabs model pricing with the registry.
+func RegisterPricing(ctx context.Context, registry domain.PricingRegistry) error {
+	models := map[string]float64{
+		"eleven_multilingual_v2": multilingualCharacterCostPer1K,
+		"eleven_turbo_v2_5":      flashCharacterCostPer1K,
+		"eleven_flash_v2_5":      flashCharacterCostPer1K,
+	}
+
+	for model, characterCost := range models {
+		if err := registry.RegisterPricing(ctx, model, domain.PricingConfig{
+			InputCostPer1K:       0, // Billed by the character
+			OutputCostPer1K:      0,
+			CachedInputCostPer1K: 0,
+			ReasoningCostPer1K:   0,
+			CharacterCostPer1K:   characterCost,
+			RequestCost:          0,
+			ImageCost:            0,
+		}); err != nil {
+			return fmt.Errorf("failed to register pricing for model %s: %w", model, err)
+		}
+	}
+
+	return nil
+}
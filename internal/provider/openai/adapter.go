@@ -6,33 +6,70 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 
 	"github.com/davidbz/calcifer/internal/domain"
 	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/provider/credentials"
+	"github.com/davidbz/calcifer/internal/provider/transport"
 )
 
+// ProviderName identifies the OpenAI provider.
+const ProviderName = "openai"
+
 // Provider implements the domain.Provider interface for OpenAI
 type Provider struct {
-	client          openai.Client
-	name            string
+	client         openai.Client
+	keys           *credentials.Pool
+	name           string
+	declaredModels []string // always served, even when discovery omits them
+	speechModels   []string // text-to-speech models; none for compatible endpoints
+	developerRole  bool     // sends developer messages as-is rather than as system messages
+
+	modelsMu        sync.RWMutex
 	supportedModels map[string]bool
 }
 
 // NewProvider creates a new OpenAI provider.
 func NewProvider(config Config) (*Provider, error) {
-	if config.APIKey == "" {
+	if !config.HasAPIKey() {
 		return nil, errors.New("OpenAI API key is required")
 	}
 
+	provider, err := newProvider(ProviderName, config, SupportedModels())
+	if err != nil {
+		return nil, err
+	}
+	provider.speechModels = SpeechModels()
+	return provider, nil
+}
+
+// newProvider creates a provider for any endpoint speaking the OpenAI API.
+func newProvider(name string, config Config, models []string) (*Provider, error) {
+	keys, err := credentials.NewPool(config.Keys(), credentials.Strategy(config.KeyStrategy))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAI key configuration: %w", err)
+	}
+
 	opts := []option.RequestOption{
-		option.WithAPIKey(config.APIKey),
+		option.WithAPIKey(config.Keys()[0]),
+		option.WithHTTPClient(&http.Client{
+			Transport:     transport.Shared(config.Transport),
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       0, // bounded per request by option.WithRequestTimeout
+		}),
 	}
 
 	if config.BaseURL != "" {
@@ -49,8 +86,13 @@ func NewProvider(config Config) (*Provider, error) {
 
 	return &Provider{
 		client:          openai.NewClient(opts...),
-		name:            "openai",
-		supportedModels: buildModelSet(SupportedModels()),
+		keys:            keys,
+		name:            name,
+		declaredModels:  models,
+		speechModels:    nil,
+		developerRole:   name == ProviderName, // compatible endpoints rarely know the role
+		modelsMu:        sync.RWMutex{},
+		supportedModels: buildModelSet(models),
 	}, nil
 }
 
@@ -66,11 +108,17 @@ func (p *Provider) Complete(ctx context.Context, req *domain.CompletionRequest)
 	// Convert domain request to SDK parameters
 	params := p.toSDKParams(req)
 
-	// Call OpenAI SDK
-	resp, err := p.client.Chat.Completions.New(ctx, params)
+	// Call OpenAI SDK with a rotated key, capturing the raw response for header passthrough
+	lease := p.keys.Acquire()
+	var httpResp *http.Response
+	resp, err := p.client.Chat.Completions.New(ctx, params,
+		option.WithAPIKey(lease.Key()),
+		option.WithResponseInto(&httpResp),
+	)
+	releaseKey(lease, httpResp)
 	if err != nil {
 		logger.Error("OpenAI API call failed", observability.Error(err))
-		return nil, fmt.Errorf("OpenAI API call failed: %w", err)
+		return nil, p.upstreamError(fmt.Errorf("OpenAI API call failed: %w", err))
 	}
 
 	logger.Debug("OpenAI API call succeeded",
@@ -79,7 +127,10 @@ func (p *Provider) Complete(ctx context.Context, req *domain.CompletionRequest)
 	)
 
 	// Convert SDK response to domain response
-	return p.toDomainResponse(resp), nil
+	response := p.toDomainResponse(resp)
+	response.ProviderHeaders = flattenHeaders(httpResp)
+
+	return response, nil
 }
 
 // Stream sends a completion request and returns a stream of chunks.
@@ -90,14 +141,36 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 		return nil, errors.New("request cannot be nil")
 	}
 
+	// Streamed tool call deltas are not relayed, so tools are only offered to completions.
+	if len(req.Tools) > 0 {
+		return nil, &domain.UnsupportedParameterError{Provider: p.name, Parameter: "tools"}
+	}
+
 	logger := observability.FromContext(ctx)
 	logger.Debug("calling OpenAI streaming API")
 
 	// Convert domain request to SDK parameters
 	params := p.toSDKParams(req)
 
-	// Call OpenAI SDK streaming
-	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	// Call OpenAI SDK streaming (the request is sent before NewStreaming returns)
+	lease := p.keys.Acquire()
+	var httpResp *http.Response
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params,
+		option.WithAPIKey(lease.Key()),
+		option.WithResponseInto(&httpResp),
+	)
+
+	// A request the upstream rejects fails the call rather than the stream, so
+	// callers see the upstream status, can fall back, and can retry.
+	if err := stream.Err(); err != nil {
+		_ = stream.Close()
+		releaseKey(lease, httpResp)
+		logger.Error("OpenAI streaming API call failed", observability.Error(err))
+		return nil, p.upstreamError(fmt.Errorf("OpenAI streaming API call failed: %w", err))
+	}
+
+	// Upstream headers are attached to the first chunk only
+	headers := flattenHeaders(httpResp)
 
 	// Convert SDK stream to domain chunks channel
 	// Use buffered channel to prevent blocking on first chunk
@@ -106,6 +179,9 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 	go func() {
 		defer close(domainChunks)
 		defer logger.Debug("OpenAI stream completed")
+		// The key stays in flight until the stream ends, so load spreads across keys.
+		defer releaseKey(lease, httpResp)
+		defer stream.Close()
 
 		// Process stream with context cancellation support
 		for stream.Next() {
@@ -116,9 +192,11 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 				// Send cancellation error
 				select {
 				case domainChunks <- domain.StreamChunk{
-					Delta: "",
-					Done:  false,
-					Error: ctx.Err(),
+					Delta:           "",
+					Done:            false,
+					Error:           ctx.Err(),
+					ProviderHeaders: nil,
+					Metadata:        nil,
 				}:
 				default:
 					// Channel full or consumer gone, exit silently
@@ -136,10 +214,13 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 				done := chunk.Choices[0].FinishReason != ""
 
 				streamChunk := domain.StreamChunk{
-					Delta: delta,
-					Done:  done,
-					Error: nil,
+					Delta:           delta,
+					Done:            done,
+					Error:           nil,
+					ProviderHeaders: headers,
+					Metadata:        nil,
 				}
+				headers = nil
 
 				// Try to send chunk, but respect context cancellation
 				select {
@@ -164,9 +245,11 @@ func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<
 				// Try to send error, but don't block
 				select {
 				case domainChunks <- domain.StreamChunk{
-					Delta: "",
-					Done:  false,
-					Error: fmt.Errorf("OpenAI stream error: %w", err),
+					Delta:           "",
+					Done:            false,
+					Error:           p.upstreamError(fmt.Errorf("OpenAI stream error: %w", err)),
+					ProviderHeaders: headers,
+					Metadata:        nil,
 				}:
 				case <-ctx.Done():
 					// Context cancelled, exit silently
@@ -187,11 +270,17 @@ func (p *Provider) Name() string {
 
 // IsModelSupported checks if the provider supports the given model.
 func (p *Provider) IsModelSupported(_ context.Context, model string) bool {
+	p.modelsMu.RLock()
+	defer p.modelsMu.RUnlock()
+
 	return p.supportedModels[model]
 }
 
 // SupportedModels returns a list of all models this provider supports.
 func (p *Provider) SupportedModels(_ context.Context) []string {
+	p.modelsMu.RLock()
+	defer p.modelsMu.RUnlock()
+
 	models := make([]string, 0, len(p.supportedModels))
 	for model := range p.supportedModels {
 		models = append(models, model)
@@ -199,6 +288,146 @@ func (p *Provider) SupportedModels(_ context.Context) []string {
 	return models
 }
 
+// CredentialHealth returns the redacted health of every configured API key.
+func (p *Provider) CredentialHealth(_ context.Context) []domain.CredentialStatus {
+	return p.keys.Status()
+}
+
+// Ping checks availability by listing models, a cheap authenticated request.
+func (p *Provider) Ping(ctx context.Context) error {
+	lease := p.keys.Acquire()
+	var httpResp *http.Response
+	_, err := p.client.Models.List(ctx,
+		option.WithAPIKey(lease.Key()),
+		option.WithMaxRetries(0),
+		option.WithResponseInto(&httpResp),
+	)
+	releaseKey(lease, httpResp)
+	if err != nil {
+		return fmt.Errorf("OpenAI health check failed: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshModels replaces the supported models with the chat models listed by the
+// upstream /models endpoint, keeping the declared models. Embedding, audio,
+// image, and moderation models are left out, so chat requests for them fail
+// fast instead of being routed upstream.
+func (p *Provider) RefreshModels(ctx context.Context) error {
+	lease := p.keys.Acquire()
+	var httpResp *http.Response
+	page, err := p.client.Models.List(ctx,
+		option.WithAPIKey(lease.Key()),
+		option.WithResponseInto(&httpResp),
+	)
+	releaseKey(lease, httpResp)
+	if err != nil {
+		return fmt.Errorf("OpenAI model listing failed: %w", err)
+	}
+
+	models := buildModelSet(p.declaredModels)
+	for _, model := range page.Data {
+		if model.ID != "" && isChatModel(model.ID) {
+			models[model.ID] = true
+		}
+	}
+
+	p.modelsMu.Lock()
+	p.supportedModels = models
+	p.modelsMu.Unlock()
+
+	return nil
+}
+
+// Moderate classifies content with the OpenAI moderation API.
+func (p *Provider) Moderate(ctx context.Context, req *domain.ModerationRequest) (*domain.ModerationResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	params := openai.ModerationNewParams{
+		Input: openai.ModerationNewParamsInputUnion{OfStringArray: req.Input},
+	}
+	if req.Model != "" {
+		params.Model = req.Model
+	}
+
+	lease := p.keys.Acquire()
+	var httpResp *http.Response
+	resp, err := p.client.Moderations.New(ctx, params,
+		option.WithAPIKey(lease.Key()),
+		option.WithResponseInto(&httpResp),
+	)
+	releaseKey(lease, httpResp)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI moderation call failed: %w", err)
+	}
+
+	results := make([]domain.ModerationResult, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		converted := domain.ModerationResult{
+			Flagged:        result.Flagged,
+			Categories:     map[string]bool{},
+			CategoryScores: map[string]float64{},
+		}
+
+		// Category fields change as OpenAI adds categories; decode them generically.
+		if err := json.Unmarshal([]byte(result.Categories.RawJSON()), &converted.Categories); err != nil {
+			return nil, fmt.Errorf("failed to decode moderation categories: %w", err)
+		}
+		if err := json.Unmarshal([]byte(result.CategoryScores.RawJSON()), &converted.CategoryScores); err != nil {
+			return nil, fmt.Errorf("failed to decode moderation scores: %w", err)
+		}
+
+		results = append(results, converted)
+	}
+
+	return &domain.ModerationResponse{
+		ID:      resp.ID,
+		Model:   resp.Model,
+		Results: results,
+	}, nil
+}
+
+// SpeechModels returns the text-to-speech models the provider serves.
+func (p *Provider) SpeechModels(_ context.Context) []string {
+	return p.speechModels
+}
+
+// Synthesize synthesizes speech with the OpenAI speech API, returning the audio
+// body as it streams from the upstream.
+func (p *Provider) Synthesize(ctx context.Context, req *domain.SpeechRequest) (*domain.SpeechResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	params := openai.AudioSpeechNewParams{
+		Input:          req.Input,
+		Model:          req.Model,
+		Voice:          openai.AudioSpeechNewParamsVoice(req.Voice),
+		ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(req.ResponseFormat),
+	}
+	if req.Speed > 0 {
+		params.Speed = openai.Float(req.Speed)
+	}
+
+	lease := p.keys.Acquire()
+	httpResp, err := p.client.Audio.Speech.New(ctx, params, option.WithAPIKey(lease.Key()))
+	releaseKey(lease, httpResp)
+	if err != nil {
+		return nil, p.upstreamError(fmt.Errorf("OpenAI speech call failed: %w", err))
+	}
+
+	return &domain.SpeechResponse{
+		Audio:       httpResp.Body,
+		ContentType: httpResp.Header.Get("Content-Type"),
+		Provider:    p.name,
+		Model:       req.Model,
+		Usage:       domain.Usage{}, // Billed per character by the gateway
+	}, nil
+}
+
 // toSDKParams converts domain request to SDK ChatCompletionNewParams
 func (p *Provider) toSDKParams(req *domain.CompletionRequest) openai.ChatCompletionNewParams {
 	// Convert messages
@@ -208,9 +437,17 @@ func (p *Provider) toSDKParams(req *domain.CompletionRequest) openai.ChatComplet
 		case "user":
 			messages[i] = openai.UserMessage(msg.Content)
 		case "assistant":
-			messages[i] = openai.AssistantMessage(msg.Content)
+			messages[i] = assistantMessage(msg)
 		case "system":
 			messages[i] = openai.SystemMessage(msg.Content)
+		case "developer":
+			if p.developerRole {
+				messages[i] = openai.DeveloperMessage(msg.Content)
+			} else {
+				messages[i] = openai.SystemMessage(msg.Content)
+			}
+		case "tool":
+			messages[i] = openai.ToolMessage(msg.Content, msg.ToolCallID)
 		default:
 			// Fallback to user message if role is unknown
 			messages[i] = openai.UserMessage(msg.Content)
@@ -231,27 +468,206 @@ func (p *Provider) toSDKParams(req *domain.CompletionRequest) openai.ChatComplet
 		params.MaxTokens = openai.Int(int64(req.MaxTokens))
 	}
 
+	if req.TopP != nil {
+		params.TopP = openai.Float(*req.TopP)
+	}
+
+	if len(req.Stop) > 0 {
+		//nolint:exhaustruct // Union sets exactly one variant
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: req.Stop}
+	}
+
+	if req.N > 0 {
+		params.N = openai.Int(int64(req.N))
+	}
+
+	if req.Seed != nil {
+		params.Seed = openai.Int(*req.Seed)
+	}
+
+	if req.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.Float(*req.FrequencyPenalty)
+	}
+
+	if req.PresencePenalty != nil {
+		params.PresencePenalty = openai.Float(*req.PresencePenalty)
+	}
+
+	if len(req.LogitBias) > 0 {
+		params.LogitBias = make(map[string]int64, len(req.LogitBias))
+		for token, bias := range req.LogitBias {
+			params.LogitBias[token] = int64(bias)
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		params.Tools = make([]openai.ChatCompletionToolParam, len(req.Tools))
+		for i, tool := range req.Tools {
+			//nolint:exhaustruct // Strict mode is left to the upstream default
+			function := shared.FunctionDefinitionParam{
+				Name:       tool.Function.Name,
+				Parameters: tool.Function.Parameters,
+			}
+			if tool.Function.Description != "" {
+				function.Description = openai.String(tool.Function.Description)
+			}
+			//nolint:exhaustruct // Type is a constant
+			params.Tools[i] = openai.ChatCompletionToolParam{Function: function}
+		}
+	}
+
 	return params
 }
 
+// assistantMessage converts an assistant message, with the tool calls it made,
+// so the tool messages answering them match up upstream.
+func assistantMessage(msg domain.Message) openai.ChatCompletionMessageParamUnion {
+	if len(msg.ToolCalls) == 0 {
+		return openai.AssistantMessage(msg.Content)
+	}
+
+	//nolint:exhaustruct // Refusal, audio, and name are not modeled
+	assistant := openai.ChatCompletionAssistantMessageParam{
+		ToolCalls: make([]openai.ChatCompletionMessageToolCallParam, len(msg.ToolCalls)),
+	}
+	if msg.Content != "" {
+		assistant.Content.OfString = openai.String(msg.Content)
+	}
+	for i, call := range msg.ToolCalls {
+		//nolint:exhaustruct // Type is a constant
+		assistant.ToolCalls[i] = openai.ChatCompletionMessageToolCallParam{
+			ID: call.ID,
+			Function: openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+	}
+	//nolint:exhaustruct // Union sets exactly one variant
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
 // toDomainResponse converts SDK response to domain response (WITHOUT cost calculation)
 func (p *Provider) toDomainResponse(resp *openai.ChatCompletion) *domain.CompletionResponse {
 	content := ""
+	var toolCalls []domain.ToolCall
 	if len(resp.Choices) > 0 {
 		content = resp.Choices[0].Message.Content
+		toolCalls = toDomainToolCalls(resp.Choices[0].Message.ToolCalls)
+	}
+
+	var choices []domain.Choice
+	if len(resp.Choices) > 1 {
+		choices = make([]domain.Choice, 0, len(resp.Choices))
+		for _, choice := range resp.Choices {
+			choices = append(choices, domain.Choice{
+				Index:        int(choice.Index),
+				Content:      choice.Message.Content,
+				FinishReason: choice.FinishReason,
+			})
+		}
 	}
 
 	return &domain.CompletionResponse{
-		ID:       resp.ID,
-		Model:    resp.Model,
-		Provider: p.name,
-		Content:  content,
+		ID:        resp.ID,
+		Model:     resp.Model,
+		Provider:  p.name,
+		Content:   content,
+		Choices:   choices,
+		ToolCalls: toolCalls,
 		Usage: domain.Usage{
 			PromptTokens:     int(resp.Usage.PromptTokens),
 			CompletionTokens: int(resp.Usage.CompletionTokens),
 			TotalTokens:      int(resp.Usage.TotalTokens),
 			Cost:             0, // Will be calculated by domain layer
+
+			CachedPromptTokens: int(resp.Usage.PromptTokensDetails.CachedTokens),
+			ReasoningTokens:    int(resp.Usage.CompletionTokensDetails.ReasoningTokens),
+			Characters:         0,
+			Images:             0,
+			ProviderCost:       0,
+			Currency:           "",
 		},
-		FinishTime: time.Now(),
+		FinishTime:      time.Now(),
+		Metadata:        nil,
+		ProviderHeaders: nil,
+	}
+}
+
+// toDomainToolCalls converts the tool calls of a response message.
+func toDomainToolCalls(calls []openai.ChatCompletionMessageToolCall) []domain.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	converted := make([]domain.ToolCall, len(calls))
+	for i, call := range calls {
+		converted[i] = domain.ToolCall{
+			ID:   call.ID,
+			Type: string(call.Type),
+			Function: domain.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+	}
+	return converted
+}
+
+// releaseKey reports the upstream outcome of a request to the key pool.
+func releaseKey(lease *credentials.Lease, resp *http.Response) {
+	if resp == nil {
+		lease.Release(0, 0)
+		return
+	}
+	lease.Release(resp.StatusCode, credentials.RetryAfter(resp.Header))
+}
+
+// upstreamError wraps an error carrying an upstream HTTP status in a domain.ProviderError,
+// keeping the Retry-After and rate-limit headers clients need to back off.
+func (p *Provider) upstreamError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return err
+	}
+
+	return &domain.ProviderError{
+		Provider:   p.name,
+		StatusCode: apiErr.StatusCode,
+		Code:       apiErr.Code,
+		RetryAfter: credentials.RetryAfter(apiErr.Response.Header),
+		Headers:    rateLimitHeaders(apiErr.Response.Header),
+		Err:        err,
+	}
+}
+
+// rateLimitHeaders returns the Retry-After and x-ratelimit-* headers of an upstream response.
+func rateLimitHeaders(header http.Header) map[string]string {
+	var headers map[string]string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if len(values) == 0 || (lower != "retry-after" && !strings.HasPrefix(lower, "x-ratelimit-")) {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = values[0]
+	}
+	return headers
+}
+
+// flattenHeaders converts raw upstream response headers to a single-value map.
+func flattenHeaders(resp *http.Response) map[string]string {
+	if resp == nil || len(resp.Header) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
 	}
+	return headers
 }
@@ -2,13 +2,48 @@ package openai_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/davidbz/calcifer/internal/domain"
 	"github.com/davidbz/calcifer/internal/provider/openai"
 )
 
+const chatCompletionBody = `{
+	"id": "chatcmpl-123",
+	"object": "chat.completion",
+	"created": 1700000000,
+	"model": "gpt-4",
+	"choices": [{
+		"index": 0,
+		"message": {"role": "assistant", "content": "Hello!"},
+		"finish_reason": "stop"
+	}],
+	"usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}
+}`
+
+// newTestServer starts a fake OpenAI API that answers chat completions with body.
+func newTestServer(t *testing.T, headers map[string]string, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
 func TestNewProvider_Success(t *testing.T) {
 	config := openai.Config{
 		APIKey:     "test-api-key",
@@ -141,3 +176,460 @@ func TestProvider_Stream_NilRequest(t *testing.T) {
 	require.Nil(t, chunks)
 	require.Contains(t, err.Error(), "request cannot be nil")
 }
+
+func TestProvider_Complete_ProviderHeaders(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"X-Ratelimit-Remaining-Requests": "99",
+		"Openai-Model":                   "gpt-4-0613",
+	}, chatCompletionBody)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", resp.Content)
+	require.Equal(t, 7, resp.Usage.TotalTokens)
+	require.Equal(t, "99", resp.ProviderHeaders["X-Ratelimit-Remaining-Requests"])
+	require.Equal(t, "gpt-4-0613", resp.ProviderHeaders["Openai-Model"])
+}
+
+func TestProvider_Complete_UpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("Retry-After-Ms", "1")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.Header().Set("Openai-Model", "gpt-4-0613")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached","code":"rate_limit_exceeded"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:     "test-key",
+		BaseURL:    server.URL,
+		MaxRetries: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+	})
+
+	var providerErr *domain.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, "openai", providerErr.Provider)
+	require.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+	require.Equal(t, 30*time.Second, providerErr.RetryAfter)
+	require.Equal(t, map[string]string{
+		"Retry-After":                    "30",
+		"X-Ratelimit-Remaining-Requests": "0",
+	}, providerErr.Headers)
+}
+
+func TestProvider_Complete_SamplingParameters(t *testing.T) {
+	var sent map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(chatCompletionBody))
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	topP := 0.9
+	seed := int64(0)
+	penalty := 0.5
+	_, err = provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:            "gpt-4",
+		Messages:         []domain.Message{{Role: "user", Content: "Hi"}},
+		TopP:             &topP,
+		Stop:             []string{"\n\n"},
+		N:                2,
+		Seed:             &seed,
+		FrequencyPenalty: &penalty,
+		LogitBias:        map[string]int{"50256": -100},
+	})
+	require.NoError(t, err)
+
+	require.InDelta(t, 0.9, sent["top_p"], 1e-9)
+	require.Equal(t, []any{"\n\n"}, sent["stop"])
+	require.InDelta(t, 2, sent["n"], 0)
+	require.InDelta(t, 0, sent["seed"], 0)
+	require.InDelta(t, 0.5, sent["frequency_penalty"], 1e-9)
+	require.NotContains(t, sent, "presence_penalty")
+	require.Equal(t, map[string]any{"50256": float64(-100)}, sent["logit_bias"])
+}
+
+func TestProvider_Complete_MessageRoles(t *testing.T) {
+	messages := []domain.Message{
+		{Role: "developer", Content: "Be brief."},
+		{Role: "user", Content: "Weather?"},
+		{Role: "assistant", Content: "Checking."},
+		{Role: "tool", Content: "Sunny", ToolCallID: "call_1"},
+	}
+
+	tests := []struct {
+		name          string
+		newProvider   func(baseURL string) (*openai.Provider, error)
+		developerRole string
+	}{
+		{
+			name: "should send developer messages to OpenAI as-is",
+			newProvider: func(baseURL string) (*openai.Provider, error) {
+				return openai.NewProvider(openai.Config{APIKey: "test-key", BaseURL: baseURL})
+			},
+			developerRole: "developer",
+		},
+		{
+			name: "should send developer messages to compatible endpoints as system messages",
+			newProvider: func(baseURL string) (*openai.Provider, error) {
+				return openai.NewCompatibleProvider(openai.CompatibleConfig{
+					Name:    "vllm",
+					Type:    openai.CompatibleType,
+					BaseURL: baseURL,
+					Models:  []openai.CompatibleModel{{Name: "gpt-4"}},
+				})
+			},
+			developerRole: "system",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent struct {
+				Messages []map[string]any `json:"messages"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(chatCompletionBody))
+			}))
+			t.Cleanup(server.Close)
+
+			provider, err := tt.newProvider(server.URL)
+			require.NoError(t, err)
+
+			_, err = provider.Complete(context.Background(), &domain.CompletionRequest{
+				Model:    "gpt-4",
+				Messages: messages,
+			})
+			require.NoError(t, err)
+
+			require.Len(t, sent.Messages, 4)
+			require.Equal(t, tt.developerRole, sent.Messages[0]["role"])
+			require.Equal(t, "tool", sent.Messages[3]["role"])
+			require.Equal(t, "call_1", sent.Messages[3]["tool_call_id"])
+			require.Equal(t, "Sunny", sent.Messages[3]["content"])
+		})
+	}
+}
+
+func TestProvider_Complete_ToolCalls(t *testing.T) {
+	var sent struct {
+		Messages []map[string]any `json:"messages"`
+		Tools    []map[string]any `json:"tools"`
+	}
+	responses := []string{
+		`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 1700000000,
+			"model": "gpt-4",
+			"choices": [{
+				"index": 0,
+				"message": {
+					"role": "assistant",
+					"content": null,
+					"tool_calls": [{
+						"id": "call_1",
+						"type": "function",
+						"function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}
+					}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 20, "completion_tokens": 10, "total_tokens": 30}
+		}`,
+		chatCompletionBody,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openai.NewProvider(openai.Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []domain.Message{{Role: "user", Content: "Weather in Paris?"}}
+	tools := []domain.Tool{{
+		Type: "function",
+		Function: domain.ToolFunction{
+			Name:        "get_weather",
+			Description: "Current weather of a city",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			},
+		},
+	}}
+
+	// The model calls the offered tool.
+	resp, err := provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: messages,
+		Tools:    tools,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, sent.Tools, 1)
+	require.Equal(t, "get_weather", sent.Tools[0]["function"].(map[string]any)["name"])
+	require.Equal(t, "object", sent.Tools[0]["function"].(map[string]any)["parameters"].(map[string]any)["type"])
+	require.Equal(t, []domain.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: domain.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}, resp.ToolCalls)
+
+	// The tool result is sent back after the assistant message that called the tool.
+	messages = append(messages,
+		domain.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls},
+		domain.Message{Role: "tool", Content: "Sunny", ToolCallID: resp.ToolCalls[0].ID},
+	)
+	_, err = provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: messages,
+		Tools:    tools,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, sent.Messages, 3)
+	require.Equal(t, "assistant", sent.Messages[1]["role"])
+	require.NotContains(t, sent.Messages[1], "content")
+	calls, ok := sent.Messages[1]["tool_calls"].([]any)
+	require.True(t, ok)
+	require.Len(t, calls, 1)
+	call := calls[0].(map[string]any)
+	require.Equal(t, "call_1", call["id"])
+	require.Equal(t, "function", call["type"])
+	require.Equal(t, map[string]any{"name": "get_weather", "arguments": `{"city":"Paris"}`}, call["function"])
+	require.Equal(t, call["id"], sent.Messages[2]["tool_call_id"])
+}
+
+func TestProvider_Stream_UpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("Retry-After-Ms", "1")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached","code":"rate_limit_exceeded"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openai.NewProvider(openai.Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: 1})
+	require.NoError(t, err)
+
+	chunks, err := provider.Stream(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+	})
+
+	require.Nil(t, chunks)
+	var providerErr *domain.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+	require.Equal(t, 30*time.Second, providerErr.RetryAfter)
+	require.Zero(t, provider.CredentialHealth(context.Background())[0].InFlight)
+}
+
+func TestProvider_Stream_HoldsKeyUntilStreamEnds(t *testing.T) {
+	finish := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := `{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4",` +
+			`"choices":[{"index":0,"delta":{"content":"%s"},"finish_reason":%s}]}`
+		_, _ = fmt.Fprintf(w, "data: "+chunk+"\n\n", "Hi", "null")
+		w.(http.Flusher).Flush()
+		<-finish
+		_, _ = fmt.Fprintf(w, "data: "+chunk+"\n\ndata: [DONE]\n\n", "", `"stop"`)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := openai.NewProvider(openai.Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	chunks, err := provider.Stream(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "Hi", (<-chunks).Delta)
+	require.Equal(t, 1, provider.CredentialHealth(context.Background())[0].InFlight)
+
+	close(finish)
+	for range chunks {
+	}
+	require.Eventually(t, func() bool {
+		return provider.CredentialHealth(context.Background())[0].InFlight == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestProvider_Stream_RejectsTools(t *testing.T) {
+	provider, err := openai.NewProvider(openai.Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	_, err = provider.Stream(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+		Tools:    []domain.Tool{{Type: "function", Function: domain.ToolFunction{Name: "get_weather"}}},
+	})
+
+	var unsupportedErr *domain.UnsupportedParameterError
+	require.ErrorAs(t, err, &unsupportedErr)
+	require.Equal(t, "tools", unsupportedErr.Parameter)
+}
+
+func TestProvider_Moderate(t *testing.T) {
+	server := newTestServer(t, nil, `{
+		"id": "modr-1",
+		"model": "omni-moderation-latest",
+		"results": [{
+			"flagged": true,
+			"categories": {"harassment": true, "violence": false},
+			"category_scores": {"harassment": 0.91, "violence": 0.02},
+			"category_applied_input_types": {}
+		}]
+	}`)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Moderate(context.Background(), &domain.ModerationRequest{Input: []string{"you are awful"}})
+
+	require.NoError(t, err)
+	require.Equal(t, "omni-moderation-latest", resp.Model)
+	require.Len(t, resp.Results, 1)
+	require.True(t, resp.Flagged())
+	require.Equal(t, []string{"harassment"}, resp.FlaggedCategories())
+	require.InDelta(t, 0.91, resp.Results[0].CategoryScores["harassment"], 1e-9)
+}
+
+func TestProvider_Complete_MultipleChoices(t *testing.T) {
+	server := newTestServer(t, nil, `{
+		"id": "chatcmpl-123",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "gpt-4",
+		"choices": [
+			{"index": 0, "message": {"role": "assistant", "content": "Hi!"}, "finish_reason": "stop"},
+			{"index": 1, "message": {"role": "assistant", "content": "Hello!"}, "finish_reason": "length"}
+		],
+		"usage": {"prompt_tokens": 5, "completion_tokens": 4, "total_tokens": 9}
+	}`)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+		N:        2,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "Hi!", resp.Content)
+	require.Equal(t, []domain.Choice{
+		{Index: 0, Content: "Hi!", FinishReason: "stop"},
+		{Index: 1, Content: "Hello!", FinishReason: "length"},
+	}, resp.Choices)
+	require.Equal(t, 4, resp.Usage.CompletionTokens)
+}
+
+func TestProvider_Complete_TokenDetails(t *testing.T) {
+	server := newTestServer(t, nil, `{
+		"id": "chatcmpl-123",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "gpt-4",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi!"}, "finish_reason": "stop"}],
+		"usage": {
+			"prompt_tokens": 2048,
+			"completion_tokens": 400,
+			"total_tokens": 2448,
+			"prompt_tokens_details": {"cached_tokens": 1536},
+			"completion_tokens_details": {"reasoning_tokens": 384}
+		}
+	}`)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	resp, err := provider.Complete(context.Background(), &domain.CompletionRequest{
+		Model:    "gpt-4",
+		Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2048, resp.Usage.PromptTokens)
+	require.Equal(t, 1536, resp.Usage.CachedPromptTokens)
+	require.Equal(t, 384, resp.Usage.ReasoningTokens)
+}
+
+func TestProvider_RefreshModels(t *testing.T) {
+	server := newTestServer(t, nil, `{
+		"object": "list",
+		"data": [
+			{"id": "gpt-5", "object": "model", "created": 1700000000, "owned_by": "openai"},
+			{"id": "gpt-4", "object": "model", "created": 1700000000, "owned_by": "openai"},
+			{"id": "text-embedding-3-small", "object": "model", "created": 1700000000, "owned_by": "openai"},
+			{"id": "whisper-1", "object": "model", "created": 1700000000, "owned_by": "openai"},
+			{"id": "gpt-4o-mini-tts", "object": "model", "created": 1700000000, "owned_by": "openai"},
+			{"id": "omni-moderation-latest", "object": "model", "created": 1700000000, "owned_by": "openai"}
+		]
+	}`)
+
+	provider, err := openai.NewProvider(openai.Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.False(t, provider.IsModelSupported(ctx, "gpt-5"))
+	require.NoError(t, provider.RefreshModels(ctx))
+
+	require.True(t, provider.IsModelSupported(ctx, "gpt-5"))
+	// Models that cannot serve chat completions are not routed to.
+	for _, model := range []string{"text-embedding-3-small", "whisper-1", "gpt-4o-mini-tts", "omni-moderation-latest"} {
+		require.False(t, provider.IsModelSupported(ctx, model), model)
+	}
+	// Declared models stay routable even when the listing omits them.
+	require.True(t, provider.IsModelSupported(ctx, "gpt-3.5-turbo"))
+}
@@ -0,0 +1,51 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+const (
+	gpt4ContextWindow       = 8192
+	gpt4TurboContextWindow  = 128000
+	gpt35TurboContextWindow = 16385
+
+	gpt4TurboMaxOutputTokens  = 4096
+	gpt35TurboMaxOutputTokens = 4096
+)
+
+// RegisterCapabilities registers OpenAI model capabilities with the registry.
+func RegisterCapabilities(ctx context.Context, registry domain.CapabilityRegistry) error {
+	models := map[string]domain.ModelCapabilities{
+		"gpt-4": {
+			ContextWindow:   gpt4ContextWindow,
+			MaxOutputTokens: 0,
+		},
+		"gpt-4-turbo": {
+			ContextWindow:   gpt4TurboContextWindow,
+			MaxOutputTokens: gpt4TurboMaxOutputTokens,
+		},
+		"gpt-4-turbo-preview": {
+			ContextWindow:   gpt4TurboContextWindow,
+			MaxOutputTokens: gpt4TurboMaxOutputTokens,
+		},
+		"gpt-3.5-turbo": {
+			ContextWindow:   gpt35TurboContextWindow,
+			MaxOutputTokens: gpt35TurboMaxOutputTokens,
+		},
+		"gpt-3.5-turbo-16k": {
+			ContextWindow:   gpt35TurboContextWindow,
+			MaxOutputTokens: gpt35TurboMaxOutputTokens,
+		},
+	}
+
+	for model, capabilities := range models {
+		if err := registry.RegisterCapabilities(ctx, model, capabilities); err != nil {
+			return fmt.Errorf("failed to register capabilities for model %s: %w", model, err)
+		}
+	}
+
+	return nil
+}
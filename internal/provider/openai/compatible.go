@@ -0,0 +1,174 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/transport"
+)
+
+const (
+	// CompatibleType is the custom provider type for OpenAI-compatible endpoints.
+	CompatibleType = "openai-compatible"
+
+	// unusedAPIKey is sent to endpoints that do not require authentication,
+	// since the SDK always sets an Authorization header.
+	unusedAPIKey = "unused"
+)
+
+// CompatibleConfig declares a self-hosted or third-party endpoint that speaks the
+// OpenAI API, such as vLLM, TGI, or LM Studio.
+type CompatibleConfig struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	BaseURL    string            `json:"base_url"`
+	APIKey     string            `json:"api_key"`     // ${VAR} references are expanded from the environment
+	Timeout    int               `json:"timeout"`     // seconds, 0 = SDK default
+	MaxRetries int               `json:"max_retries"` // 0 = SDK default
+	Models     []CompatibleModel `json:"models"`
+
+	// Transport tunes the connection pool; unset fields take the transport defaults.
+	Transport transport.Config `json:"transport"`
+}
+
+// CompatibleModel describes one model served by a compatible endpoint.
+type CompatibleModel struct {
+	Name            string  `json:"name"`
+	InputCostPer1K  float64 `json:"input_cost_per_1k"`
+	OutputCostPer1K float64 `json:"output_cost_per_1k"`
+	ContextWindow   int     `json:"context_window"`    // 0 = unknown, history is not trimmed
+	MaxOutputTokens int     `json:"max_output_tokens"` // 0 = bounded only by ContextWindow
+
+	// CachedInputCostPer1K prices prompt-cached input tokens; 0 bills them as input tokens.
+	CachedInputCostPer1K float64 `json:"cached_input_cost_per_1k"`
+	// ReasoningCostPer1K prices reasoning tokens; 0 bills them as output tokens.
+	ReasoningCostPer1K float64 `json:"reasoning_cost_per_1k"`
+	// RequestCost prices each request, added to any token cost.
+	RequestCost float64 `json:"request_cost"`
+	// ImageCost prices each image the endpoint reports.
+	ImageCost float64 `json:"image_cost"`
+}
+
+// LoadCompatibleConfigs reads and validates custom provider declarations from a
+// JSON file holding an array of CompatibleConfig.
+func LoadCompatibleConfigs(path string) ([]CompatibleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom providers: %w", err)
+	}
+
+	var configs []CompatibleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse custom providers: %w", err)
+	}
+
+	names := make(map[string]bool, len(configs))
+	for i := range configs {
+		if err := configs[i].validate(); err != nil {
+			return nil, fmt.Errorf("custom provider %d: %w", i, err)
+		}
+		if names[configs[i].Name] {
+			return nil, fmt.Errorf("custom provider %s is declared twice", configs[i].Name)
+		}
+		names[configs[i].Name] = true
+		configs[i].APIKey = os.ExpandEnv(configs[i].APIKey)
+	}
+
+	return configs, nil
+}
+
+// NewCompatibleProvider creates a provider for an OpenAI-compatible endpoint.
+func NewCompatibleProvider(config CompatibleConfig) (*Provider, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = unusedAPIKey
+	}
+
+	models := make([]string, 0, len(config.Models))
+	for _, model := range config.Models {
+		models = append(models, model.Name)
+	}
+
+	return newProvider(config.Name, Config{
+		APIKey:      apiKey,
+		APIKeys:     nil,
+		KeyStrategy: "",
+		BaseURL:     config.BaseURL,
+		Timeout:     config.Timeout,
+		MaxRetries:  config.MaxRetries,
+		Transport:   config.Transport,
+	}, models)
+}
+
+// RegisterPricing registers the declared model pricing with the registry.
+func (c CompatibleConfig) RegisterPricing(ctx context.Context, registry domain.PricingRegistry) error {
+	for _, model := range c.Models {
+		err := registry.RegisterPricing(ctx, model.Name, domain.PricingConfig{
+			InputCostPer1K:       model.InputCostPer1K,
+			OutputCostPer1K:      model.OutputCostPer1K,
+			CachedInputCostPer1K: model.CachedInputCostPer1K,
+			ReasoningCostPer1K:   model.ReasoningCostPer1K,
+			CharacterCostPer1K:   0,
+			RequestCost:          model.RequestCost,
+			ImageCost:            model.ImageCost,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register pricing for model %s: %w", model.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RegisterCapabilities registers the declared model capabilities with the registry.
+// Models without a context window are skipped.
+func (c CompatibleConfig) RegisterCapabilities(ctx context.Context, registry domain.CapabilityRegistry) error {
+	for _, model := range c.Models {
+		if model.ContextWindow <= 0 {
+			continue
+		}
+
+		err := registry.RegisterCapabilities(ctx, model.Name, domain.ModelCapabilities{
+			ContextWindow:   model.ContextWindow,
+			MaxOutputTokens: model.MaxOutputTokens,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register capabilities for model %s: %w", model.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c CompatibleConfig) validate() error {
+	switch {
+	case c.Name == "":
+		return errors.New("name is required")
+	case c.Type != CompatibleType:
+		return fmt.Errorf("unsupported provider type %q", c.Type)
+	case c.BaseURL == "":
+		return fmt.Errorf("provider %s: base_url is required", c.Name)
+	case len(c.Models) == 0:
+		return fmt.Errorf("provider %s: at least one model is required", c.Name)
+	}
+
+	if err := c.Transport.Validate(); err != nil {
+		return fmt.Errorf("provider %s: invalid transport: %w", c.Name, err)
+	}
+
+	for _, model := range c.Models {
+		if model.Name == "" {
+			return fmt.Errorf("provider %s: model name is required", c.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package openai_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/openai"
+)
+
+func writeCustomProviders(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "providers.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadCompatibleConfigs(t *testing.T) {
+	t.Run("should load providers and expand key references", func(t *testing.T) {
+		t.Setenv("VLLM_KEY", "secret")
+		path := writeCustomProviders(t, `[
+			{"name": "vllm", "type": "openai-compatible", "base_url": "http://vllm:8000/v1",
+			 "api_key": "${VLLM_KEY}", "models": [{"name": "llama-3-70b", "input_cost_per_1k": 0.001}]},
+			{"name": "lmstudio", "type": "openai-compatible", "base_url": "http://localhost:1234/v1",
+			 "models": [{"name": "qwen2.5-7b"}]}
+		]`)
+
+		configs, err := openai.LoadCompatibleConfigs(path)
+		require.NoError(t, err)
+		require.Len(t, configs, 2)
+		require.Equal(t, "secret", configs[0].APIKey)
+		require.InDelta(t, 0.001, configs[0].Models[0].InputCostPer1K, 1e-9)
+		require.Empty(t, configs[1].APIKey)
+	})
+
+	t.Run("should reject invalid declarations", func(t *testing.T) {
+		cases := map[string]string{
+			"unknown type":   `[{"name": "a", "type": "anthropic", "base_url": "http://a", "models": [{"name": "m"}]}]`,
+			"missing url":    `[{"name": "a", "type": "openai-compatible", "models": [{"name": "m"}]}]`,
+			"missing models": `[{"name": "a", "type": "openai-compatible", "base_url": "http://a"}]`,
+			"bad transport": `[{"name": "a", "type": "openai-compatible", "base_url": "http://a",
+				"models": [{"name": "m"}], "transport": {"dial_timeout": -1}}]`,
+			"duplicate name": `[{"name": "a", "type": "openai-compatible", "base_url": "http://a", "models": [{"name": "m"}]},
+				{"name": "a", "type": "openai-compatible", "base_url": "http://b", "models": [{"name": "n"}]}]`,
+		}
+
+		for name, content := range cases {
+			_, err := openai.LoadCompatibleConfigs(writeCustomProviders(t, content))
+			require.Error(t, err, name)
+		}
+	})
+}
+
+func TestNewCompatibleProvider(t *testing.T) {
+	server := newTestServer(t, nil, chatCompletionBody)
+
+	config := openai.CompatibleConfig{
+		Name:    "vllm",
+		Type:    openai.CompatibleType,
+		BaseURL: server.URL,
+		Models: []openai.CompatibleModel{
+			{Name: "llama-3-70b", InputCostPer1K: 0.001, OutputCostPer1K: 0.002, ContextWindow: 8192},
+			{Name: "mistral-7b"},
+		},
+	}
+
+	provider, err := openai.NewCompatibleProvider(config)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("should serve only the declared models under its own name", func(t *testing.T) {
+		require.Equal(t, "vllm", provider.Name())
+		require.True(t, provider.IsModelSupported(ctx, "llama-3-70b"))
+		require.False(t, provider.IsModelSupported(ctx, "gpt-4"))
+	})
+
+	t.Run("should complete without an API key", func(t *testing.T) {
+		resp, err := provider.Complete(ctx, &domain.CompletionRequest{
+			Model:    "llama-3-70b",
+			Messages: []domain.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "vllm", resp.Provider)
+		require.Equal(t, "Hello!", resp.Content)
+	})
+
+	t.Run("should register declared pricing and capabilities", func(t *testing.T) {
+		pricing := domain.NewInMemoryPricingRegistry()
+		capabilities := domain.NewInMemoryCapabilityRegistry()
+
+		require.NoError(t, config.RegisterPricing(ctx, pricing))
+		require.NoError(t, config.RegisterCapabilities(ctx, capabilities))
+
+		price, err := pricing.GetPricing(ctx, "llama-3-70b")
+		require.NoError(t, err)
+		require.InDelta(t, 0.002, price.OutputCostPer1K, 1e-9)
+
+		caps, err := capabilities.GetCapabilities(ctx, "llama-3-70b")
+		require.NoError(t, err)
+		require.Equal(t, 8192, caps.ContextWindow)
+
+		_, err = capabilities.GetCapabilities(ctx, "mistral-7b")
+		require.Error(t, err)
+	})
+}
@@ -1,14 +1,38 @@
 package openai
 
+import "github.com/davidbz/calcifer/internal/provider/transport"
+
 // Config contains OpenAI provider configuration.
 // All fields map to OpenAI SDK options:
 //   - APIKey: Maps to option.WithAPIKey()
 //   - BaseURL: Maps to option.WithBaseURL()
 //   - Timeout: Maps to option.WithRequestTimeout() (in seconds)
 //   - MaxRetries: Maps to option.WithMaxRetries()
+//   - Transport: Maps to option.WithHTTPClient(), tuning the shared connection pool
+//
+// APIKeys adds extra keys rotated per request using KeyStrategy
+// ("round-robin" or "least-used") to spread org-level rate limits.
 type Config struct {
-	APIKey     string `env:"OPENAI_API_KEY"`
-	BaseURL    string `env:"OPENAI_BASE_URL"    envDefault:"https://api.openai.com/v1"`
-	Timeout    int    `env:"OPENAI_TIMEOUT"     envDefault:"60"`
-	MaxRetries int    `env:"OPENAI_MAX_RETRIES" envDefault:"3"`
+	APIKey      string   `env:"OPENAI_API_KEY"`
+	APIKeys     []string `env:"OPENAI_API_KEYS"     envSeparator:","`
+	KeyStrategy string   `env:"OPENAI_KEY_STRATEGY"                  envDefault:"round-robin"`
+	BaseURL     string   `env:"OPENAI_BASE_URL"                      envDefault:"https://api.openai.com/v1"`
+	Timeout     int      `env:"OPENAI_TIMEOUT"                       envDefault:"60"`
+	MaxRetries  int      `env:"OPENAI_MAX_RETRIES"                   envDefault:"3"`
+
+	Transport transport.Config `envPrefix:"OPENAI_"`
+}
+
+// HasAPIKey reports whether at least one API key is configured.
+func (c Config) HasAPIKey() bool {
+	return c.APIKey != "" || len(c.APIKeys) > 0
+}
+
+// Keys returns all configured API keys, primary key first.
+func (c Config) Keys() []string {
+	keys := make([]string, 0, len(c.APIKeys)+1)
+	if c.APIKey != "" {
+		keys = append(keys, c.APIKey)
+	}
+	return append(keys, c.APIKeys...)
 }
@@ -0,0 +1,67 @@
+//go:build integration
+
+package openai_test
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/contract"
+	"github.com/davidbz/calcifer/internal/provider/openai"
+)
+
+func TestContract(t *testing.T) {
+	config := openai.Config{
+		APIKey:      os.Getenv("OPENAI_API_KEY"),
+		APIKeys:     nil,
+		KeyStrategy: "round-robin",
+		BaseURL:     os.Getenv("OPENAI_BASE_URL"),
+		Timeout:     60,
+		MaxRetries:  0,
+	}
+	live := config.APIKey != ""
+	model := os.Getenv("CONTRACT_OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-3.5-turbo"
+	}
+
+	provider := contract.Source(t, openai.ProviderName, live, func() (domain.Provider, error) {
+		return openai.NewProvider(config)
+	})
+
+	t.Run("should complete with usage", func(t *testing.T) {
+		contract.Completion(t, provider, model)
+	})
+
+	t.Run("should honor max tokens", func(t *testing.T) {
+		contract.MaxTokens(t, provider, model)
+	})
+
+	t.Run("should stream until done", func(t *testing.T) {
+		contract.Stream(t, provider, model)
+	})
+
+	t.Run("should map rejected requests to provider errors", func(t *testing.T) {
+		contract.RequireLive(t, live)
+		contract.UpstreamError(t, provider, model, http.StatusBadRequest)
+	})
+
+	t.Run("should synthesize speech", func(t *testing.T) {
+		contract.RequireLive(t, live)
+		synthesizer, err := openai.NewProvider(config)
+		require.NoError(t, err)
+
+		contract.Speech(t, synthesizer, &domain.SpeechRequest{
+			Model:          "tts-1",
+			Input:          "pong",
+			Voice:          "alloy",
+			ResponseFormat: "mp3",
+			Speed:          0,
+			Metadata:       nil,
+		})
+	})
+}
@@ -1,5 +1,7 @@
 package openai
 
+import "strings"
+
 // SupportedModels returns the list of models supported by OpenAI provider.
 func SupportedModels() []string {
 	return []string{
@@ -11,6 +13,43 @@ func SupportedModels() []string {
 	}
 }
 
+// SpeechModels returns the text-to-speech models served by the OpenAI provider.
+func SpeechModels() []string {
+	return []string{
+		"tts-1",
+		"tts-1-hd",
+	}
+}
+
+// nonChatModelMarkers returns the ID fragments of upstream models that cannot
+// serve chat completions: embeddings, audio, images, and moderation.
+func nonChatModelMarkers() []string {
+	return []string{
+		"embedding",
+		"moderation",
+		"whisper",
+		"tts",
+		"transcribe",
+		"audio",
+		"realtime",
+		"dall-e",
+		"image",
+	}
+}
+
+// isChatModel reports whether an upstream model ID names a chat model. The
+// /models endpoint lists every model of the account without capabilities, so
+// the ID is all there is to go by.
+func isChatModel(id string) bool {
+	id = strings.ToLower(id)
+	for _, marker := range nonChatModelMarkers() {
+		if strings.Contains(id, marker) {
+			return false
+		}
+	}
+	return true
+}
+
 // buildModelSet creates a map for O(1) lookup.
 func buildModelSet(models []string) map[string]bool {
 	set := make(map[string]bool, len(models))
@@ -19,22 +19,81 @@ const (
 	// GPT-3.5 Turbo pricing per 1K tokens
 	gpt35TurboInputCostPer1K  = 0.0005
 	gpt35TurboOutputCostPer1K = 0.0015
+
+	// GPT-3.5 Turbo 16K pricing per 1K tokens
+	gpt35Turbo16KInputCostPer1K  = 0.003
+	gpt35Turbo16KOutputCostPer1K = 0.004
+
+	// TTS pricing per 1K input characters
+	tts1CharacterCostPer1K   = 0.015
+	tts1HDCharacterCostPer1K = 0.03
 )
 
 // RegisterPricing registers OpenAI model pricing with the registry.
 func RegisterPricing(ctx context.Context, registry domain.PricingRegistry) error {
 	models := map[string]domain.PricingConfig{
 		"gpt-4": {
-			InputCostPer1K:  gpt4InputCostPer1K,
-			OutputCostPer1K: gpt4OutputCostPer1K,
+			InputCostPer1K:       gpt4InputCostPer1K,
+			OutputCostPer1K:      gpt4OutputCostPer1K,
+			CachedInputCostPer1K: 0, // No prompt-cache discount for these models
+			ReasoningCostPer1K:   0, // Not reasoning models
+			CharacterCostPer1K:   0, // Billed by the token
+			RequestCost:          0,
+			ImageCost:            0,
 		},
 		"gpt-4-turbo": {
-			InputCostPer1K:  gpt4TurboInputCostPer1K,
-			OutputCostPer1K: gpt4TurboOutputCostPer1K,
+			InputCostPer1K:       gpt4TurboInputCostPer1K,
+			OutputCostPer1K:      gpt4TurboOutputCostPer1K,
+			CachedInputCostPer1K: 0, // No prompt-cache discount for these models
+			ReasoningCostPer1K:   0, // Not reasoning models
+			CharacterCostPer1K:   0, // Billed by the token
+			RequestCost:          0,
+			ImageCost:            0,
+		},
+		"gpt-4-turbo-preview": {
+			InputCostPer1K:       gpt4TurboInputCostPer1K,
+			OutputCostPer1K:      gpt4TurboOutputCostPer1K,
+			CachedInputCostPer1K: 0, // No prompt-cache discount for these models
+			ReasoningCostPer1K:   0, // Not reasoning models
+			CharacterCostPer1K:   0, // Billed by the token
+			RequestCost:          0,
+			ImageCost:            0,
 		},
 		"gpt-3.5-turbo": {
-			InputCostPer1K:  gpt35TurboInputCostPer1K,
-			OutputCostPer1K: gpt35TurboOutputCostPer1K,
+			InputCostPer1K:       gpt35TurboInputCostPer1K,
+			OutputCostPer1K:      gpt35TurboOutputCostPer1K,
+			CachedInputCostPer1K: 0, // No prompt-cache discount for these models
+			ReasoningCostPer1K:   0, // Not reasoning models
+			CharacterCostPer1K:   0, // Billed by the token
+			RequestCost:          0,
+			ImageCost:            0,
+		},
+		"gpt-3.5-turbo-16k": {
+			InputCostPer1K:       gpt35Turbo16KInputCostPer1K,
+			OutputCostPer1K:      gpt35Turbo16KOutputCostPer1K,
+			CachedInputCostPer1K: 0, // No prompt-cache discount for these models
+			ReasoningCostPer1K:   0, // Not reasoning models
+			CharacterCostPer1K:   0, // Billed by the token
+			RequestCost:          0,
+			ImageCost:            0,
+		},
+		"tts-1": {
+			InputCostPer1K:       0, // Billed by the character
+			OutputCostPer1K:      0,
+			CachedInputCostPer1K: 0,
+			ReasoningCostPer1K:   0,
+			CharacterCostPer1K:   tts1CharacterCostPer1K,
+			RequestCost:          0,
+			ImageCost:            0,
+		},
+		"tts-1-hd": {
+			InputCostPer1K:       0, // Billed by the character
+			OutputCostPer1K:      0,
+			CachedInputCostPer1K: 0,
+			ReasoningCostPer1K:   0,
+			CharacterCostPer1K:   tts1HDCharacterCostPer1K,
+			RequestCost:          0,
+			ImageCost:            0,
 		},
 	}
 
@@ -14,6 +14,7 @@ type Registry struct {
 	mu              sync.RWMutex
 	providers       map[string]domain.Provider
 	modelToProvider map[string]string
+	unhealthy       map[string]bool
 }
 
 // NewRegistry creates a new provider registry.
@@ -22,6 +23,7 @@ func NewRegistry() *Registry {
 		mu:              sync.RWMutex{},
 		providers:       make(map[string]domain.Provider),
 		modelToProvider: make(map[string]string),
+		unhealthy:       make(map[string]bool),
 	}
 }
 
@@ -54,6 +56,26 @@ func (r *Registry) Register(ctx context.Context, provider domain.Provider) error
 	return nil
 }
 
+// Unregister removes a provider, its model index entries, and its health state.
+func (r *Registry) Unregister(_ context.Context, providerName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[providerName]; !exists {
+		return fmt.Errorf("provider %s not found", providerName)
+	}
+
+	delete(r.providers, providerName)
+	delete(r.unhealthy, providerName)
+	for model, name := range r.modelToProvider {
+		if name == providerName {
+			delete(r.modelToProvider, model)
+		}
+	}
+
+	return nil
+}
+
 // Get retrieves a provider by name.
 func (r *Registry) Get(_ context.Context, providerName string) (domain.Provider, error) {
 	if providerName == "" {
@@ -84,7 +106,68 @@ func (r *Registry) List(_ context.Context) ([]string, error) {
 	return names, nil
 }
 
-// GetByModel retrieves a provider that supports the given model.
+// SetHealthy marks a provider as healthy or unhealthy.
+// Unhealthy providers stay registered but are skipped by GetByModel.
+func (r *Registry) SetHealthy(_ context.Context, providerName string, healthy bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[providerName]; !exists {
+		return fmt.Errorf("provider %s not found", providerName)
+	}
+
+	if healthy {
+		delete(r.unhealthy, providerName)
+	} else {
+		r.unhealthy[providerName] = true
+	}
+
+	return nil
+}
+
+// IsHealthy reports whether the named provider is registered and healthy.
+func (r *Registry) IsHealthy(_ context.Context, providerName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.providers[providerName]
+	return exists && !r.unhealthy[providerName]
+}
+
+// Reindex rebuilds the model index of a provider from its current SupportedModels,
+// dropping models it no longer serves. Models already indexed to another provider
+// keep their mapping so discovery never steals routes.
+func (r *Registry) Reindex(ctx context.Context, providerName string) error {
+	r.mu.RLock()
+	provider, exists := r.providers[providerName]
+	r.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("provider %s not found", providerName)
+	}
+
+	// Query the provider outside the lock; it may take its own locks.
+	supportedModels := provider.SupportedModels(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for model, name := range r.modelToProvider {
+		if name == providerName {
+			delete(r.modelToProvider, model)
+		}
+	}
+
+	for _, model := range supportedModels {
+		if _, taken := r.modelToProvider[model]; !taken {
+			r.modelToProvider[model] = providerName
+		}
+	}
+
+	return nil
+}
+
+// GetByModel retrieves a healthy provider that supports the given model.
 func (r *Registry) GetByModel(ctx context.Context, model string) (domain.Provider, error) {
 	if model == "" {
 		return nil, errors.New("model cannot be empty")
@@ -95,22 +178,29 @@ func (r *Registry) GetByModel(ctx context.Context, model string) (domain.Provide
 
 	// Use reverse index for O(1) lookup
 	providerName, exists := r.modelToProvider[model]
-	if !exists {
-		// Fallback to linear search for unknown models
-		// This handles dynamic models not in the known list
-		for _, provider := range r.providers {
-			if provider.IsModelSupported(ctx, model) {
-				return provider, nil
-			}
+	if exists && !r.unhealthy[providerName] {
+		provider, found := r.providers[providerName]
+		if !found {
+			// This shouldn't happen, but handle gracefully
+			return nil, fmt.Errorf("provider not found: %s", providerName)
 		}
-		return nil, fmt.Errorf("no provider found for model: %s", model)
+		return provider, nil
 	}
 
-	provider, exists := r.providers[providerName]
-	if !exists {
-		// This shouldn't happen, but handle gracefully
-		return nil, fmt.Errorf("provider not found: %s", providerName)
+	// Fallback to linear search for unknown models or when the indexed provider is unhealthy.
+	// This handles dynamic models not in the known list
+	for name, provider := range r.providers {
+		if r.unhealthy[name] {
+			continue
+		}
+		if provider.IsModelSupported(ctx, model) {
+			return provider, nil
+		}
 	}
 
-	return provider, nil
+	if exists {
+		return nil, fmt.Errorf("provider %s for model %s is unhealthy", providerName, model)
+	}
+
+	return nil, fmt.Errorf("no provider found for model: %s", model)
 }
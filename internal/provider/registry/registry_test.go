@@ -275,3 +275,150 @@ func TestRegistry_GetByModel(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistry_SetHealthy(t *testing.T) {
+	t.Run("should skip unhealthy provider until it recovers", func(t *testing.T) {
+		reg := registry.NewRegistry()
+		ctx := context.Background()
+
+		mockOpenAI := mocks.NewMockProvider(t)
+		mockOpenAI.EXPECT().Name().Return("openai")
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"})
+
+		err := reg.Register(ctx, mockOpenAI)
+		require.NoError(t, err)
+
+		err = reg.SetHealthy(ctx, "openai", false)
+		require.NoError(t, err)
+		require.False(t, reg.IsHealthy(ctx, "openai"))
+
+		_, err = reg.GetByModel(ctx, "gpt-4")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unhealthy")
+
+		err = reg.SetHealthy(ctx, "openai", true)
+		require.NoError(t, err)
+		require.True(t, reg.IsHealthy(ctx, "openai"))
+		require.False(t, reg.IsHealthy(ctx, "missing"))
+
+		provider, err := reg.GetByModel(ctx, "gpt-4")
+		require.NoError(t, err)
+		require.Equal(t, "openai", provider.Name())
+	})
+
+	t.Run("should fall back to another healthy provider supporting the model", func(t *testing.T) {
+		reg := registry.NewRegistry()
+		ctx := context.Background()
+
+		mockPrimary := mocks.NewMockProvider(t)
+		mockPrimary.EXPECT().Name().Return("primary")
+		mockPrimary.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"})
+
+		mockSecondary := mocks.NewMockProvider(t)
+		mockSecondary.EXPECT().Name().Return("secondary")
+		mockSecondary.EXPECT().SupportedModels(mock.Anything).Return([]string{})
+		mockSecondary.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(true)
+
+		require.NoError(t, reg.Register(ctx, mockPrimary))
+		require.NoError(t, reg.Register(ctx, mockSecondary))
+		require.NoError(t, reg.SetHealthy(ctx, "primary", false))
+
+		provider, err := reg.GetByModel(ctx, "gpt-4")
+		require.NoError(t, err)
+		require.Equal(t, "secondary", provider.Name())
+	})
+
+	t.Run("should return error for unknown provider", func(t *testing.T) {
+		reg := registry.NewRegistry()
+
+		err := reg.SetHealthy(context.Background(), "missing", false)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestRegistry_Reindex(t *testing.T) {
+	t.Run("should route discovered models and drop removed ones", func(t *testing.T) {
+		reg := registry.NewRegistry()
+		ctx := context.Background()
+
+		mockOpenAI := mocks.NewMockProvider(t)
+		mockOpenAI.EXPECT().Name().Return("openai")
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"}).Once()
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-5"}).Once()
+		mockOpenAI.EXPECT().IsModelSupported(mock.Anything, "gpt-4").Return(false)
+
+		require.NoError(t, reg.Register(ctx, mockOpenAI))
+		require.NoError(t, reg.Reindex(ctx, "openai"))
+
+		// The new model resolves through the index without a linear search.
+		provider, err := reg.GetByModel(ctx, "gpt-5")
+		require.NoError(t, err)
+		require.Equal(t, "openai", provider.Name())
+
+		_, err = reg.GetByModel(ctx, "gpt-4")
+		require.Error(t, err)
+	})
+
+	t.Run("should not steal models indexed to another provider", func(t *testing.T) {
+		reg := registry.NewRegistry()
+		ctx := context.Background()
+
+		mockAzure := mocks.NewMockProvider(t)
+		mockAzure.EXPECT().Name().Return("azure")
+		mockAzure.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"})
+
+		mockOpenAI := mocks.NewMockProvider(t)
+		mockOpenAI.EXPECT().Name().Return("openai")
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{}).Once()
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"}).Once()
+
+		require.NoError(t, reg.Register(ctx, mockAzure))
+		require.NoError(t, reg.Register(ctx, mockOpenAI))
+		require.NoError(t, reg.Reindex(ctx, "openai"))
+
+		provider, err := reg.GetByModel(ctx, "gpt-4")
+		require.NoError(t, err)
+		require.Equal(t, "azure", provider.Name())
+	})
+
+	t.Run("should return error for unknown provider", func(t *testing.T) {
+		reg := registry.NewRegistry()
+
+		err := reg.Reindex(context.Background(), "missing")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
+func TestRegistry_Unregister(t *testing.T) {
+	t.Run("should remove the provider and its models from routing", func(t *testing.T) {
+		reg := registry.NewRegistry()
+		ctx := context.Background()
+
+		mockOpenAI := mocks.NewMockProvider(t)
+		mockOpenAI.EXPECT().Name().Return("openai")
+		mockOpenAI.EXPECT().SupportedModels(mock.Anything).Return([]string{"gpt-4"})
+
+		require.NoError(t, reg.Register(ctx, mockOpenAI))
+		require.NoError(t, reg.Unregister(ctx, "openai"))
+
+		_, err := reg.Get(ctx, "openai")
+		require.Error(t, err)
+
+		_, err = reg.GetByModel(ctx, "gpt-4")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no provider found")
+
+		// The provider can be registered again afterwards.
+		require.NoError(t, reg.Register(ctx, mockOpenAI))
+	})
+
+	t.Run("should return error for unknown provider", func(t *testing.T) {
+		reg := registry.NewRegistry()
+
+		err := reg.Unregister(context.Background(), "missing")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
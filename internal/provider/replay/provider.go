@@ -0,0 +1,361 @@
+// Package replay records provider responses to cassette files and replays them,
+// so integration tests of the gateway run without API keys or network access.
+//
+// Each cassette holds one request and its response, or its stream chunks, in a
+// JSON file named by a digest of the request under a directory per provider.
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+// Cassette modes.
+const (
+	// ModeOff serves every provider normally.
+	ModeOff = "off"
+
+	// ModeRecord serves requests from the upstream provider and saves each response.
+	ModeRecord = "record"
+
+	// ModeReplay serves requests from saved cassettes only.
+	ModeReplay = "replay"
+)
+
+const (
+	dirPermissions  = 0o750
+	filePermissions = 0o600
+
+	kindComplete = "complete"
+	kindStream   = "stream"
+)
+
+// ErrCassetteNotFound indicates no recorded response matches a replayed request.
+var ErrCassetteNotFound = errors.New("no recorded response for request")
+
+// Cassette is one recorded request and its response or stream.
+type Cassette struct {
+	Provider        string                     `json:"provider"`
+	Model           string                     `json:"model"`
+	Kind            string                     `json:"kind"` // complete or stream
+	Request         *domain.CompletionRequest  `json:"request"`
+	Response        *domain.CompletionResponse `json:"response,omitempty"`
+	ResponseHeaders map[string]string          `json:"response_headers,omitempty"`
+	Chunks          []Chunk                    `json:"chunks,omitempty"`
+}
+
+// Chunk is a recorded stream chunk.
+type Chunk struct {
+	Delta           string            `json:"delta"`
+	Done            bool              `json:"done"`
+	Error           string            `json:"error,omitempty"`
+	ProviderHeaders map[string]string `json:"provider_headers,omitempty"`
+}
+
+// Provider records or replays the responses of one provider. A recorder wraps
+// the upstream provider; a player serves the models found in its cassettes.
+type Provider struct {
+	name     string
+	dir      string
+	upstream domain.Provider // nil when replaying
+	models   map[string]bool // recorded models, when replaying
+}
+
+// NewRecorder wraps upstream, saving every successful response under dir.
+func NewRecorder(upstream domain.Provider, dir string) *Provider {
+	return &Provider{
+		name:     upstream.Name(),
+		dir:      filepath.Join(dir, upstream.Name()),
+		upstream: upstream,
+		models:   nil,
+	}
+}
+
+// NewPlayers creates a player for every provider with cassettes under dir.
+func NewPlayers(dir string) ([]*Provider, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette directory: %w", err)
+	}
+
+	var players []*Provider
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		player := &Provider{
+			name:     entry.Name(),
+			dir:      filepath.Join(dir, entry.Name()),
+			upstream: nil,
+			models:   make(map[string]bool),
+		}
+		if err = player.loadModels(); err != nil {
+			return nil, err
+		}
+		players = append(players, player)
+	}
+
+	return players, nil
+}
+
+// Complete returns the recorded response to req, or records the upstream's.
+func (p *Provider) Complete(ctx context.Context, req *domain.CompletionRequest) (*domain.CompletionResponse, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if p.upstream == nil {
+		cassette, err := p.load(kindComplete, req)
+		if err != nil {
+			return nil, err
+		}
+		response := *cassette.Response
+		response.ProviderHeaders = cassette.ResponseHeaders
+		return &response, nil
+	}
+
+	response, err := p.upstream.Complete(ctx, req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Recording is transparent to upstream errors
+	}
+
+	recorded := *response
+	p.save(ctx, &Cassette{
+		Provider:        p.name,
+		Model:           req.Model,
+		Kind:            kindComplete,
+		Request:         req,
+		Response:        &recorded,
+		ResponseHeaders: response.ProviderHeaders,
+		Chunks:          nil,
+	})
+	return response, nil
+}
+
+// Stream replays the recorded stream for req, or records the upstream's as it is read.
+func (p *Provider) Stream(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.StreamChunk, error) {
+	if req == nil {
+		return nil, errors.New("request cannot be nil")
+	}
+
+	if p.upstream == nil {
+		cassette, err := p.load(kindStream, req)
+		if err != nil {
+			return nil, err
+		}
+		return replayChunks(ctx, cassette.Chunks), nil
+	}
+
+	in, err := p.upstream.Stream(ctx, req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Recording is transparent to upstream errors
+	}
+
+	out := make(chan domain.StreamChunk)
+	go func() {
+		defer close(out)
+
+		var chunks []Chunk
+		for chunk := range in {
+			chunks = append(chunks, recordChunk(chunk))
+			if chunk.Done && chunk.Error == nil {
+				p.save(ctx, &Cassette{
+					Provider:        p.name,
+					Model:           req.Model,
+					Kind:            kindStream,
+					Request:         req,
+					Response:        nil,
+					ResponseHeaders: nil,
+					Chunks:          chunks,
+				})
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Name returns the recorded provider's name.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// IsModelSupported reports whether the upstream, or the cassettes when replaying, serve model.
+func (p *Provider) IsModelSupported(ctx context.Context, model string) bool {
+	if p.upstream != nil {
+		return p.upstream.IsModelSupported(ctx, model)
+	}
+
+	return p.models[model]
+}
+
+// SupportedModels lists the upstream's models, or the recorded ones when replaying.
+func (p *Provider) SupportedModels(ctx context.Context) []string {
+	if p.upstream != nil {
+		return p.upstream.SupportedModels(ctx)
+	}
+
+	models := make([]string, 0, len(p.models))
+	for model := range p.models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// loadModels indexes the models of the player's cassettes.
+func (p *Provider) loadModels() error {
+	paths, err := filepath.Glob(filepath.Join(p.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list cassettes: %w", err)
+	}
+
+	for _, path := range paths {
+		cassette, readErr := readCassette(path)
+		if readErr != nil {
+			return readErr
+		}
+		p.models[cassette.Model] = true
+	}
+	return nil
+}
+
+// load reads the cassette recorded for req.
+func (p *Provider) load(kind string, req *domain.CompletionRequest) (*Cassette, error) {
+	key, err := cassetteKey(kind, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette, err := readCassette(filepath.Join(p.dir, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s request for %s (%s)", ErrCassetteNotFound, p.name, kind, req.Model, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if kind == kindComplete && cassette.Response == nil {
+		return nil, fmt.Errorf("cassette %s has no response", key)
+	}
+	return cassette, nil
+}
+
+// save writes a cassette, logging failures so recording never fails a request.
+func (p *Provider) save(ctx context.Context, cassette *Cassette) {
+	logger := observability.FromContext(ctx)
+
+	key, err := cassetteKey(cassette.Kind, cassette.Request)
+	if err != nil {
+		logger.Error("failed to record cassette", observability.Error(err))
+		return
+	}
+
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode cassette", observability.Error(err))
+		return
+	}
+
+	if err = os.MkdirAll(p.dir, dirPermissions); err != nil {
+		logger.Error("failed to create cassette directory", observability.Error(err))
+		return
+	}
+
+	path := filepath.Join(p.dir, key+".json")
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, append(data, '\n'), filePermissions); err != nil {
+		logger.Error("failed to write cassette", observability.Error(err))
+		return
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		logger.Error("failed to replace cassette", observability.Error(err))
+		return
+	}
+
+	logger.Debug("recorded cassette", observability.String("cassette", path))
+}
+
+// cassetteKey digests a request so identical requests share a cassette. The
+// stream flag is covered by kind, so a request recorded either way can be found.
+func cassetteKey(kind string, req *domain.CompletionRequest) (string, error) {
+	normalized := *req
+	normalized.Stream = false
+
+	data, err := json.Marshal(&normalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	sum := sha256.Sum256(append([]byte(kind+"\n"), data...))
+	return kind + "-" + hex.EncodeToString(sum[:16]), nil
+}
+
+// readCassette reads and decodes a cassette file.
+func readCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Cassette paths come from the configured directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err = json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", filepath.Base(path), err)
+	}
+	return &cassette, nil
+}
+
+// recordChunk converts a stream chunk to its recorded form.
+func recordChunk(chunk domain.StreamChunk) Chunk {
+	recorded := Chunk{Delta: chunk.Delta, Done: chunk.Done, Error: "", ProviderHeaders: chunk.ProviderHeaders}
+	if chunk.Error != nil {
+		recorded.Error = chunk.Error.Error()
+	}
+	return recorded
+}
+
+// replayChunks streams recorded chunks.
+func replayChunks(ctx context.Context, chunks []Chunk) <-chan domain.StreamChunk {
+	out := make(chan domain.StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		for _, recorded := range chunks {
+			chunk := domain.StreamChunk{
+				Delta:           recorded.Delta,
+				Done:            recorded.Done,
+				Error:           nil,
+				ProviderHeaders: recorded.ProviderHeaders,
+				Metadata:        nil,
+			}
+			if recorded.Error != "" {
+				chunk.Error = errors.New(recorded.Error)
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,80 @@
+package replay_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/echo"
+	"github.com/davidbz/calcifer/internal/provider/replay"
+)
+
+func echoRequest(content string, stream bool) *domain.CompletionRequest {
+	return &domain.CompletionRequest{
+		Model:    "echo4",
+		Messages: []domain.Message{{Role: "user", Content: content}},
+		Stream:   stream,
+	}
+}
+
+func collect(t *testing.T, chunks <-chan domain.StreamChunk) string {
+	t.Helper()
+
+	var content string
+	for chunk := range chunks {
+		require.NoError(t, chunk.Error)
+		content += chunk.Delta
+	}
+	return content
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	recorder := replay.NewRecorder(echo.NewProvider(), dir)
+	require.Equal(t, "echo", recorder.Name())
+
+	recorded, err := recorder.Complete(ctx, echoRequest("Hello world", false))
+	require.NoError(t, err)
+	chunks, err := recorder.Stream(ctx, echoRequest("Stream me", true))
+	require.NoError(t, err)
+	recordedStream := collect(t, chunks)
+
+	players, err := replay.NewPlayers(dir)
+	require.NoError(t, err)
+	require.Len(t, players, 1)
+	player := players[0]
+
+	t.Run("should serve the recorded models", func(t *testing.T) {
+		require.Equal(t, "echo", player.Name())
+		require.True(t, player.IsModelSupported(ctx, "echo4"))
+		require.False(t, player.IsModelSupported(ctx, "gpt-4"))
+		require.Equal(t, []string{"echo4"}, player.SupportedModels(ctx))
+	})
+
+	t.Run("should replay a recorded completion", func(t *testing.T) {
+		replayed, err := player.Complete(ctx, echoRequest("Hello world", false))
+
+		require.NoError(t, err)
+		require.Equal(t, recorded.Content, replayed.Content)
+		require.Equal(t, recorded.Usage, replayed.Usage)
+	})
+
+	t.Run("should replay a recorded stream", func(t *testing.T) {
+		chunks, err := player.Stream(ctx, echoRequest("Stream me", true))
+
+		require.NoError(t, err)
+		require.Equal(t, recordedStream, collect(t, chunks))
+	})
+
+	t.Run("should reject requests that were not recorded", func(t *testing.T) {
+		_, err := player.Complete(ctx, echoRequest("Something else", false))
+		require.ErrorIs(t, err, replay.ErrCassetteNotFound)
+
+		_, err = player.Stream(ctx, echoRequest("Hello world", true))
+		require.ErrorIs(t, err, replay.ErrCassetteNotFound)
+	})
+}
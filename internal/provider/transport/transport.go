@@ -0,0 +1,106 @@
+// Package transport builds the HTTP transports of provider adapters. They are
+// tuned for many concurrent requests to a few upstream hosts, and shared by
+// every client with the same settings so those clients pool connections.
+package transport
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults applied to zero settings.
+const (
+	DefaultMaxIdleConnsPerHost = 100
+	DefaultIdleConnTimeout     = 90 // seconds
+	DefaultDialTimeout         = 10 // seconds
+
+	// keepAlive is the interval of TCP keep-alive probes on idle connections.
+	keepAlive = 30 * time.Second
+)
+
+// Config tunes the connection pool of a provider's HTTP client. Zero values
+// take the defaults, so an unset Config yields a tuned transport.
+type Config struct {
+	// MaxIdleConnsPerHost bounds the keep-alive connections kept per upstream host.
+	// net/http keeps only 2, which churns connections under high-QPS traffic to a
+	// single provider host.
+	MaxIdleConnsPerHost int `env:"MAX_IDLE_CONNS_PER_HOST" envDefault:"100" json:"max_idle_conns_per_host"`
+	IdleConnTimeout     int `env:"IDLE_CONN_TIMEOUT"       envDefault:"90"  json:"idle_conn_timeout"` // seconds
+	DialTimeout         int `env:"DIAL_TIMEOUT"            envDefault:"10"  json:"dial_timeout"`      // seconds
+
+	// DisableHTTP2 keeps connections on HTTP/1.1, for upstreams or proxies that
+	// mishandle HTTP/2.
+	DisableHTTP2 bool `env:"DISABLE_HTTP2" envDefault:"false" json:"disable_http2"`
+}
+
+// Validate reports settings that cannot be applied.
+func (c Config) Validate() error {
+	if c.MaxIdleConnsPerHost < 0 || c.IdleConnTimeout < 0 || c.DialTimeout < 0 {
+		return errors.New("max idle connections per host, idle connection timeout, and dial timeout cannot be negative")
+	}
+	return nil
+}
+
+// withDefaults returns c with zero settings replaced by the defaults.
+func (c Config) withDefaults() Config {
+	if c.MaxIdleConnsPerHost <= 0 {
+		c.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout <= 0 {
+		c.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = DefaultDialTimeout
+	}
+	return c
+}
+
+//nolint:gochecknoglobals // Process-wide cache, so clients with the same settings share connections
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[Config]*http.Transport)
+)
+
+// Shared returns the transport for config, built on first use and shared by
+// every caller asking for the same settings.
+func Shared(config Config) *http.Transport {
+	config = config.withDefaults()
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	transport, ok := shared[config]
+	if !ok {
+		transport = New(config)
+		shared[config] = transport
+	}
+	return transport
+}
+
+// New builds a transport for config. It keeps the proxy and TLS settings of
+// http.DefaultTransport.
+func New(config Config) *http.Transport {
+	config = config.withDefaults()
+
+	//nolint:exhaustruct // Only the timeouts are tuned
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(config.DialTimeout) * time.Second,
+		KeepAlive: keepAlive,
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(!config.DisableHTTP2)
+
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	transport = transport.Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = 0 // bounded per host instead
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = time.Duration(config.IdleConnTimeout) * time.Second
+	transport.Protocols = protocols
+	return transport
+}
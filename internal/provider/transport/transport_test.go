@@ -0,0 +1,69 @@
+package transport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/provider/transport"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should apply the defaults to zero settings", func(t *testing.T) {
+		tr := transport.New(transport.Config{
+			MaxIdleConnsPerHost: 0,
+			IdleConnTimeout:     0,
+			DialTimeout:         0,
+			DisableHTTP2:        false,
+		})
+
+		require.Equal(t, transport.DefaultMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+		require.Equal(t, transport.DefaultIdleConnTimeout*time.Second, tr.IdleConnTimeout)
+		require.True(t, tr.Protocols.HTTP1())
+		require.True(t, tr.Protocols.HTTP2())
+		require.NotNil(t, tr.Proxy)
+	})
+
+	t.Run("should apply the configured settings", func(t *testing.T) {
+		tr := transport.New(transport.Config{
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     30,
+			DialTimeout:         2,
+			DisableHTTP2:        true,
+		})
+
+		require.Equal(t, 16, tr.MaxIdleConnsPerHost)
+		require.Equal(t, 30*time.Second, tr.IdleConnTimeout)
+		require.True(t, tr.Protocols.HTTP1())
+		require.False(t, tr.Protocols.HTTP2())
+	})
+}
+
+func TestShared(t *testing.T) {
+	t.Run("should share one transport per distinct settings", func(t *testing.T) {
+		tuned := transport.Config{MaxIdleConnsPerHost: 7, IdleConnTimeout: 0, DialTimeout: 0, DisableHTTP2: false}
+		defaults := transport.Config{
+			MaxIdleConnsPerHost: transport.DefaultMaxIdleConnsPerHost,
+			IdleConnTimeout:     transport.DefaultIdleConnTimeout,
+			DialTimeout:         transport.DefaultDialTimeout,
+			DisableHTTP2:        false,
+		}
+
+		require.Same(t, transport.Shared(tuned), transport.Shared(tuned))
+		require.Same(t, transport.Shared(transport.Config{}), transport.Shared(defaults))
+		require.NotSame(t, transport.Shared(tuned), transport.Shared(defaults))
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("should reject negative settings", func(t *testing.T) {
+		config := transport.Config{MaxIdleConnsPerHost: 0, IdleConnTimeout: -1, DialTimeout: 0, DisableHTTP2: false}
+
+		require.Error(t, config.Validate())
+	})
+
+	t.Run("should accept zero settings", func(t *testing.T) {
+		require.NoError(t, transport.Config{}.Validate())
+	})
+}
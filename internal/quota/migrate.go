@@ -0,0 +1,24 @@
+package quota
+
+// migrations returns the schema changes in order, applied by sqlstore.Open.
+// Released migrations are never edited, only appended to.
+func migrations() []string {
+	return []string{
+		`CREATE TABLE quotas (
+			key              TEXT PRIMARY KEY,
+			requests_per_day INTEGER NOT NULL DEFAULT 0,
+			tokens_per_day   INTEGER NOT NULL DEFAULT 0,
+			spend_per_month  REAL NOT NULL DEFAULT 0
+		)`,
+		// A key's usage per period: a UTC day (2006-01-02) counts requests and
+		// tokens, and a UTC month (2006-01) counts spend.
+		`CREATE TABLE quota_usage (
+			key      TEXT NOT NULL,
+			period   TEXT NOT NULL,
+			requests INTEGER NOT NULL DEFAULT 0,
+			tokens   INTEGER NOT NULL DEFAULT 0,
+			spend    REAL NOT NULL DEFAULT 0,
+			PRIMARY KEY (key, period)
+		)`,
+	}
+}
@@ -0,0 +1,204 @@
+// Package quota persists client key quotas, and the usage counted against them,
+// to a SQLite database.
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/sqlstore"
+)
+
+// Period layouts of quota_usage rows.
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// Store implements domain.QuotaCounterStore on a SQLite database. Requests are
+// admitted by checking and incrementing a key's counts in one transaction, so
+// gateways sharing the database never admit more than a quota allows.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens the quota store (DI constructor), creating the database and
+// applying pending migrations. It returns nil when no path is configured,
+// keeping quotas and their usage in memory only.
+func NewStore(cfg *config.QuotaConfig) (*Store, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, nil //nolint:nilnil // A nil store keeps quotas in memory
+	}
+
+	return Open(context.Background(), cfg.Path)
+}
+
+// Open opens the SQLite database at path and migrates it.
+func Open(ctx context.Context, path string) (*Store, error) {
+	db, err := sqlstore.Open(ctx, path, "quota store", migrations())
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already describes the failure
+	}
+	return &Store{db: db}, nil
+}
+
+// Load returns the stored quotas, sorted by key.
+func (s *Store) Load(ctx context.Context) ([]domain.Quota, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, requests_per_day, tokens_per_day, spend_per_month FROM quotas ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota store: %w", err)
+	}
+	defer rows.Close()
+
+	var quotas []domain.Quota
+	for rows.Next() {
+		var quota domain.Quota
+		if err = rows.Scan(&quota.Key, &quota.RequestsPerDay, &quota.TokensPerDay, &quota.SpendPerMonth); err != nil {
+			return nil, fmt.Errorf("failed to read quota store: %w", err)
+		}
+		quotas = append(quotas, quota)
+	}
+	if err = errors.Join(rows.Err(), rows.Close()); err != nil {
+		return nil, fmt.Errorf("failed to read quota store: %w", err)
+	}
+	return quotas, nil
+}
+
+// Save replaces the stored quotas in one transaction. Usage counts are kept.
+func (s *Store) Save(ctx context.Context, quotas []domain.Quota) error {
+	return s.transact(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM quotas`); err != nil {
+			return err //nolint:wrapcheck // Wrapped by transact
+		}
+		for _, quota := range quotas {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO quotas (key, requests_per_day, tokens_per_day,
+				spend_per_month) VALUES (?, ?, ?, ?)`,
+				quota.Key, quota.RequestsPerDay, quota.TokensPerDay, quota.SpendPerMonth); err != nil {
+				return err //nolint:wrapcheck // Wrapped by transact
+			}
+		}
+		return nil
+	})
+}
+
+// Admit counts a request for key unless its usage in the periods of now has
+// already reached a limit of quota, returning the exhausted limit, or "" when
+// the request was counted. The check and the increment share a transaction
+// that starts by writing, so it holds SQLite's write lock throughout.
+func (s *Store) Admit(ctx context.Context, key string, quota domain.Quota, now time.Time) (string, error) {
+	day, month := periods(now)
+
+	var limit string
+	err := s.transact(ctx, func(tx *sql.Tx) error {
+		created, err := tx.ExecContext(ctx,
+			`INSERT INTO quota_usage (key, period) VALUES (?, ?), (?, ?) ON CONFLICT DO NOTHING`,
+			key, day, key, month)
+		if err != nil {
+			return err //nolint:wrapcheck // Wrapped by transact
+		}
+		// A key's first request of a day drops its counts of earlier months.
+		if added, _ := created.RowsAffected(); added > 0 {
+			if _, err = tx.ExecContext(ctx, `DELETE FROM quota_usage WHERE key = ? AND period < ?`,
+				key, month); err != nil {
+				return err //nolint:wrapcheck // Wrapped by transact
+			}
+		}
+
+		counted, err := usage(ctx, tx, key, day, month)
+		if err != nil {
+			return err
+		}
+		if limit = quota.Exceeded(counted); limit != "" {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE quota_usage SET requests = requests + 1 WHERE key = ? AND period = ?`,
+			key, day)
+		return err //nolint:wrapcheck // Wrapped by transact
+	})
+	return limit, err
+}
+
+// Add adds tokens to key's usage on the UTC day of at, and spend to its usage in
+// the UTC month of at.
+func (s *Store) Add(ctx context.Context, key string, at time.Time, tokens int64, spend float64) error {
+	day, month := periods(at)
+
+	return s.transact(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO quota_usage (key, period, tokens) VALUES (?, ?, ?)
+			ON CONFLICT (key, period) DO UPDATE SET tokens = tokens + excluded.tokens`,
+			key, day, tokens); err != nil {
+			return err //nolint:wrapcheck // Wrapped by transact
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO quota_usage (key, period, spend) VALUES (?, ?, ?)
+			ON CONFLICT (key, period) DO UPDATE SET spend = spend + excluded.spend`,
+			key, month, spend)
+		return err //nolint:wrapcheck // Wrapped by transact
+	})
+}
+
+// Usage returns key's usage in the periods of now.
+func (s *Store) Usage(ctx context.Context, key string, now time.Time) (domain.QuotaUsage, error) {
+	day, month := periods(now)
+
+	var result domain.QuotaUsage
+	err := s.transact(ctx, func(tx *sql.Tx) error {
+		var err error
+		result, err = usage(ctx, tx, key, day, month)
+		return err
+	})
+	return result, err
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close quota store: %w", err)
+	}
+	return nil
+}
+
+// transact runs fn in a transaction, committing it when fn succeeds.
+func (s *Store) transact(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin quota store transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = fn(tx); err != nil {
+		return fmt.Errorf("failed to update quota store: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quota store transaction: %w", err)
+	}
+	return nil
+}
+
+// usage reads key's counts for day and month. Missing rows count as zero.
+func usage(ctx context.Context, tx *sql.Tx, key, day, month string) (domain.QuotaUsage, error) {
+	var result domain.QuotaUsage
+	err := tx.QueryRowContext(ctx, `SELECT
+		COALESCE(SUM(CASE WHEN period = ? THEN requests END), 0),
+		COALESCE(SUM(CASE WHEN period = ? THEN tokens END), 0),
+		COALESCE(SUM(CASE WHEN period = ? THEN spend END), 0)
+		FROM quota_usage WHERE key = ? AND period IN (?, ?)`,
+		day, day, month, key, day, month).
+		Scan(&result.RequestsToday, &result.TokensToday, &result.SpendThisMonth)
+	if err != nil {
+		return result, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+	return result, nil
+}
+
+// periods returns the UTC day and month of t as quota_usage periods.
+func periods(t time.Time) (string, string) {
+	t = t.UTC()
+	return t.Format(dayLayout), t.Format(monthLayout)
+}
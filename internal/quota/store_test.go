@@ -0,0 +1,122 @@
+package quota_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/quota"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	open := func(t *testing.T, path string) *quota.Store {
+		t.Helper()
+		store, err := quota.Open(ctx, path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+
+	t.Run("should return nil without a path", func(t *testing.T) {
+		store, err := quota.NewStore(&config.QuotaConfig{Path: ""})
+		require.NoError(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("should load nothing from a new database", func(t *testing.T) {
+		quotas, err := open(t, filepath.Join(t.TempDir(), "quotas.db")).Load(ctx)
+		require.NoError(t, err)
+		require.Empty(t, quotas)
+	})
+
+	t.Run("should load what was saved after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "quotas.db")
+		store := open(t, path)
+		require.NoError(t, store.Save(ctx, []domain.Quota{{Key: "carol", RequestsPerDay: 1}}))
+
+		saved := []domain.Quota{
+			{Key: "alice", RequestsPerDay: 100, TokensPerDay: 50000},
+			{Key: "bob", SpendPerMonth: 25},
+		}
+		require.NoError(t, store.Save(ctx, saved))
+		require.NoError(t, store.Close())
+
+		loaded, err := open(t, path).Load(ctx)
+		require.NoError(t, err)
+		require.Equal(t, saved, loaded)
+	})
+
+	t.Run("should admit requests until the daily request quota is used up", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "quotas.db"))
+		limit := domain.Quota{Key: "alice", RequestsPerDay: 2}
+
+		for range 2 {
+			exceeded, err := store.Admit(ctx, "alice", limit, now)
+			require.NoError(t, err)
+			require.Empty(t, exceeded)
+		}
+
+		exceeded, err := store.Admit(ctx, "alice", limit, now)
+		require.NoError(t, err)
+		require.Equal(t, domain.QuotaRequestsPerDay, exceeded)
+
+		exceeded, err = store.Admit(ctx, "alice", limit, now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, exceeded, "the request quota resets the next UTC day")
+	})
+
+	t.Run("should count tokens per day and spend per month", func(t *testing.T) {
+		store := open(t, filepath.Join(t.TempDir(), "quotas.db"))
+
+		require.NoError(t, store.Add(ctx, "alice", now, 100, 1.5))
+		require.NoError(t, store.Add(ctx, "alice", now.Add(-24*time.Hour), 50, 0.5))
+		require.NoError(t, store.Add(ctx, "bob", now, 7, 0.1))
+
+		usage, err := store.Usage(ctx, "alice", now)
+		require.NoError(t, err)
+		require.Equal(t, domain.QuotaUsage{RequestsToday: 0, TokensToday: 100, SpendThisMonth: 2}, usage)
+
+		exceeded, err := store.Admit(ctx, "alice", domain.Quota{Key: "alice", SpendPerMonth: 2}, now)
+		require.NoError(t, err)
+		require.Equal(t, domain.QuotaSpendPerMonth, exceeded)
+	})
+
+	t.Run("should never admit more requests than the quota across concurrent callers", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "quotas.db")
+		stores := []*quota.Store{open(t, path), open(t, path)}
+		limit := domain.Quota{Key: "alice", RequestsPerDay: 10}
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			admitted int
+		)
+		for i := range 40 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				exceeded, err := stores[i%2].Admit(ctx, "alice", limit, now)
+				if err == nil && exceeded == "" {
+					mu.Lock()
+					admitted++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, 10, admitted)
+		usage, err := stores[0].Usage(ctx, "alice", now)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), usage.RequestsToday)
+	})
+}
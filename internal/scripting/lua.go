@@ -0,0 +1,316 @@
+// Package scripting runs operator-written Lua policies against gateway requests,
+// so that rules can change with configuration rather than a new build.
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	// GuardrailName names the policy script in the errors of rejected requests.
+	GuardrailName = "policy_script"
+
+	requestHook  = "on_request"
+	responseHook = "on_response"
+)
+
+// LuaHook implements domain.RequestHook with a Lua script defining either or both of:
+//
+//	on_request(req)         -- may set req.model, req.max_tokens, req.temperature and
+//	                        -- req.metadata; returning a string rejects the request
+//	on_response(req, resp)  -- may set resp.metadata
+//
+// Scripts run sandboxed with only the base, table, string, and math libraries and
+// without file access. Each hook call gets a fresh copy of the request, so state
+// kept in globals is not shared reliably between requests.
+type LuaHook struct {
+	proto   *lua.FunctionProto
+	timeout time.Duration
+	states  sync.Pool
+}
+
+// NewLuaHook loads the configured policy script (DI constructor). It returns nil
+// when no script is configured.
+func NewLuaHook(cfg *config.ScriptingConfig) (*LuaHook, error) {
+	if cfg == nil || cfg.PolicyScript == "" {
+		return nil, nil //nolint:nilnil // A nil hook disables scripting
+	}
+
+	source, err := os.ReadFile(cfg.PolicyScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy script: %w", err)
+	}
+
+	return CompileLuaHook(cfg.PolicyScript, string(source), time.Duration(cfg.TimeoutMs)*time.Millisecond)
+}
+
+// CompileLuaHook compiles source and runs its top level once to catch errors at
+// startup. A zero timeout leaves hook calls unbounded.
+func CompileLuaHook(name, source string, timeout time.Duration) (*LuaHook, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy script: %w", err)
+	}
+
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile policy script: %w", err)
+	}
+
+	hook := &LuaHook{
+		proto:   proto,
+		timeout: timeout,
+		states:  sync.Pool{New: nil},
+	}
+
+	state, err := hook.newState()
+	if err != nil {
+		return nil, err
+	}
+	hook.states.Put(state)
+
+	return hook, nil
+}
+
+// BeforeRequest runs on_request against req, applying its rewrites.
+func (h *LuaHook) BeforeRequest(ctx context.Context, req *domain.CompletionRequest) (map[string]string, error) {
+	var metadata map[string]string
+
+	err := h.call(ctx, requestHook, func(state *lua.LState, fn lua.LValue) error {
+		reqTable := requestTable(ctx, state, req)
+		reqTable.RawSetString("metadata", state.NewTable())
+
+		if err := state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, reqTable); err != nil {
+			return err
+		}
+		result := state.Get(-1)
+		state.Pop(1)
+
+		if reason, ok := result.(lua.LString); ok && reason != "" {
+			return &domain.GuardrailError{Guardrail: GuardrailName, Err: errors.New(string(reason))}
+		}
+
+		if err := applyRequest(reqTable, req); err != nil {
+			return err
+		}
+		metadata = metadataOf(reqTable)
+		return nil
+	})
+
+	return metadata, err
+}
+
+// AfterResponse runs on_response against a finished completion, merging the
+// metadata it sets into resp.
+func (h *LuaHook) AfterResponse(
+	ctx context.Context,
+	req *domain.CompletionRequest,
+	resp *domain.CompletionResponse,
+) error {
+	return h.call(ctx, responseHook, func(state *lua.LState, fn lua.LValue) error {
+		respTable := responseTable(state, resp)
+
+		if err := state.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true},
+			requestTable(ctx, state, req), respTable); err != nil {
+			return err
+		}
+
+		for key, value := range metadataOf(respTable) {
+			if resp.Metadata == nil {
+				resp.Metadata = make(map[string]string)
+			}
+			resp.Metadata[key] = value
+		}
+		return nil
+	})
+}
+
+// call runs invoke with the named hook function on a pooled state, bounded by the
+// configured timeout. Scripts not defining the hook are skipped.
+func (h *LuaHook) call(
+	ctx context.Context,
+	name string,
+	invoke func(state *lua.LState, fn lua.LValue) error,
+) error {
+	state, err := h.acquire()
+	if err != nil {
+		return err
+	}
+
+	fn := state.GetGlobal(name)
+	if fn.Type() != lua.LTFunction {
+		h.states.Put(state)
+		return nil
+	}
+
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	state.SetContext(ctx)
+
+	err = invoke(state, fn)
+	state.RemoveContext()
+
+	var guardrailErr *domain.GuardrailError
+	switch {
+	case err == nil, errors.As(err, &guardrailErr):
+		state.SetTop(0)
+		h.states.Put(state)
+		return err
+	default:
+		// A state interrupted mid-call may be inconsistent; it is not reused.
+		state.Close()
+		return fmt.Errorf("policy script %s failed: %w", name, err)
+	}
+}
+
+// acquire returns a pooled state, creating one when the pool is empty.
+func (h *LuaHook) acquire() (*lua.LState, error) {
+	if state, ok := h.states.Get().(*lua.LState); ok {
+		return state, nil
+	}
+	return h.newState()
+}
+
+// newState creates a sandboxed state and runs the script's top level in it.
+func (h *LuaHook) newState() (*lua.LState, error) {
+	state := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+
+	// The base library can reach the file system and stdout.
+	for _, name := range []string{"dofile", "loadfile", "module", "require", "print"} {
+		state.SetGlobal(name, lua.LNil)
+	}
+	state.SetGlobal("log", state.NewFunction(luaLog))
+
+	state.Push(state.NewFunctionFromProto(h.proto))
+	if err := state.PCall(0, 0, nil); err != nil {
+		state.Close()
+		return nil, fmt.Errorf("failed to load policy script: %w", err)
+	}
+
+	return state, nil
+}
+
+// luaLog logs its message argument with the request's logger, in place of print.
+func luaLog(state *lua.LState) int {
+	ctx := state.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	observability.FromContext(ctx).Info("policy script", observability.String("message", state.CheckString(1)))
+	return 0
+}
+
+// requestTable exposes req and the calling client to a script.
+func requestTable(ctx context.Context, state *lua.LState, req *domain.CompletionRequest) *lua.LTable {
+	messages := state.CreateTable(len(req.Messages), 0)
+	for _, msg := range req.Messages {
+		message := state.CreateTable(0, 3)
+		message.RawSetString("role", lua.LString(msg.Role))
+		message.RawSetString("content", lua.LString(msg.Content))
+		if msg.ToolCallID != "" {
+			message.RawSetString("tool_call_id", lua.LString(msg.ToolCallID))
+		}
+		messages.Append(message)
+	}
+
+	table := state.CreateTable(0, 8)
+	table.RawSetString("model", lua.LString(req.Model))
+	table.RawSetString("max_tokens", lua.LNumber(req.MaxTokens))
+	table.RawSetString("temperature", lua.LNumber(req.Temperature))
+	table.RawSetString("stream", lua.LBool(req.Stream))
+	table.RawSetString("messages", messages)
+	table.RawSetString("client_key", lua.LString(observability.GetClientKey(ctx)))
+	table.RawSetString("tenant", lua.LString(observability.GetTenant(ctx)))
+	return table
+}
+
+// responseTable exposes a finished completion to a script.
+func responseTable(state *lua.LState, resp *domain.CompletionResponse) *lua.LTable {
+	usage := state.CreateTable(0, 4)
+	usage.RawSetString("prompt_tokens", lua.LNumber(resp.Usage.PromptTokens))
+	usage.RawSetString("completion_tokens", lua.LNumber(resp.Usage.CompletionTokens))
+	usage.RawSetString("total_tokens", lua.LNumber(resp.Usage.TotalTokens))
+	usage.RawSetString("cost", lua.LNumber(resp.Usage.Cost))
+
+	metadata := state.CreateTable(0, len(resp.Metadata))
+	for key, value := range resp.Metadata {
+		metadata.RawSetString(key, lua.LString(value))
+	}
+
+	table := state.CreateTable(0, 5)
+	table.RawSetString("model", lua.LString(resp.Model))
+	table.RawSetString("provider", lua.LString(resp.Provider))
+	table.RawSetString("content", lua.LString(resp.Content))
+	table.RawSetString("usage", usage)
+	table.RawSetString("metadata", metadata)
+	return table
+}
+
+// applyRequest copies the fields a script may rewrite back into req.
+func applyRequest(table *lua.LTable, req *domain.CompletionRequest) error {
+	model, ok := table.RawGetString("model").(lua.LString)
+	if !ok || model == "" {
+		return errors.New("req.model must be a non-empty string")
+	}
+	req.Model = string(model)
+
+	maxTokens, ok := table.RawGetString("max_tokens").(lua.LNumber)
+	if !ok || maxTokens < 0 {
+		return errors.New("req.max_tokens must be a non-negative number")
+	}
+	req.MaxTokens = int(maxTokens)
+
+	temperature, ok := table.RawGetString("temperature").(lua.LNumber)
+	if !ok {
+		return errors.New("req.temperature must be a number")
+	}
+	req.Temperature = float64(temperature)
+
+	return nil
+}
+
+// metadataOf returns the metadata table of a request or response as strings.
+func metadataOf(table *lua.LTable) map[string]string {
+	metadata, ok := table.RawGetString("metadata").(*lua.LTable)
+	if !ok {
+		return nil
+	}
+
+	values := make(map[string]string)
+	metadata.ForEach(func(key, value lua.LValue) {
+		if value != lua.LNil {
+			values[key.String()] = value.String()
+		}
+	})
+	return values
+}
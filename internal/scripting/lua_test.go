@@ -0,0 +1,143 @@
+package scripting_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+	"github.com/davidbz/calcifer/internal/scripting"
+)
+
+const policyScript = `
+function on_request(req)
+  if req.client_key == "batch" and req.model == "gpt-4" then
+    req.model = "gpt-3.5-turbo"
+  end
+  if #req.messages > 2 then
+    return "too many messages"
+  end
+  if req.max_tokens > 100 then
+    req.max_tokens = 100
+  end
+  req.metadata.team = "search"
+  req.metadata.turns = #req.messages
+end
+
+function on_response(req, resp)
+  if resp.usage.total_tokens > 10 then
+    resp.metadata.size = "large"
+  end
+end
+`
+
+func newRequest(messages int) *domain.CompletionRequest {
+	req := &domain.CompletionRequest{Model: "gpt-4", MaxTokens: 500}
+	for range messages {
+		req.Messages = append(req.Messages, domain.Message{Role: "user", Content: "Hi"})
+	}
+	return req
+}
+
+func TestLuaHook_BeforeRequest(t *testing.T) {
+	hook, err := scripting.CompileLuaHook("policy.lua", policyScript, time.Second)
+	require.NoError(t, err)
+
+	t.Run("should rewrite the request and return its metadata", func(t *testing.T) {
+		ctx := observability.WithClientKey(context.Background(), "batch")
+		req := newRequest(1)
+
+		metadata, err := hook.BeforeRequest(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-3.5-turbo", req.Model)
+		require.Equal(t, 100, req.MaxTokens)
+		require.Equal(t, map[string]string{"team": "search", "turns": "1"}, metadata)
+	})
+
+	t.Run("should leave requests of other clients on their model", func(t *testing.T) {
+		req := newRequest(1)
+
+		_, err := hook.BeforeRequest(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4", req.Model)
+	})
+
+	t.Run("should reject a request when the script returns a reason", func(t *testing.T) {
+		_, err := hook.BeforeRequest(context.Background(), newRequest(3))
+
+		var guardrailErr *domain.GuardrailError
+		require.ErrorAs(t, err, &guardrailErr)
+		require.Equal(t, scripting.GuardrailName, guardrailErr.Guardrail)
+		require.ErrorContains(t, err, "too many messages")
+	})
+}
+
+func TestLuaHook_AfterResponse(t *testing.T) {
+	hook, err := scripting.CompileLuaHook("policy.lua", policyScript, time.Second)
+	require.NoError(t, err)
+
+	resp := &domain.CompletionResponse{
+		Model:    "gpt-4",
+		Usage:    domain.Usage{PromptTokens: 8, CompletionTokens: 8, TotalTokens: 16},
+		Metadata: map[string]string{"model_alias": "fast"},
+	}
+
+	require.NoError(t, hook.AfterResponse(context.Background(), newRequest(1), resp))
+	require.Equal(t, map[string]string{"model_alias": "fast", "size": "large"}, resp.Metadata)
+}
+
+func TestLuaHook_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		errMsg string
+	}{
+		{
+			name:   "should fail a script that runs past its timeout",
+			script: `function on_request(req) while true do end end`,
+			errMsg: "policy script on_request failed",
+		},
+		{
+			name:   "should fail a script that clears the model",
+			script: `function on_request(req) req.model = nil end`,
+			errMsg: "req.model must be a non-empty string",
+		},
+		{
+			name:   "should not expose the file system",
+			script: `function on_request(req) dofile("/etc/passwd") end`,
+			errMsg: "policy script on_request failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook, err := scripting.CompileLuaHook("policy.lua", tt.script, 20*time.Millisecond)
+			require.NoError(t, err)
+
+			_, err = hook.BeforeRequest(context.Background(), newRequest(1))
+			require.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestNewLuaHook(t *testing.T) {
+	t.Run("should be disabled without a script", func(t *testing.T) {
+		hook, err := scripting.NewLuaHook(&config.ScriptingConfig{PolicyScript: "", TimeoutMs: 50})
+		require.NoError(t, err)
+		require.Nil(t, hook)
+	})
+
+	t.Run("should reject a script that does not parse", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policy.lua")
+		require.NoError(t, os.WriteFile(path, []byte("function on_request("), 0o600))
+
+		_, err := scripting.NewLuaHook(&config.ScriptingConfig{PolicyScript: path, TimeoutMs: 50})
+		require.ErrorContains(t, err, "failed to parse policy script")
+	})
+}
@@ -0,0 +1,81 @@
+// Package sqlstore opens the SQLite databases behind the gateway's persistent
+// stores and migrates their schemas.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite" // Registers the pure-Go sqlite database/sql driver, so builds need no cgo.
+)
+
+// Open opens the SQLite database at path and applies the migrations it has not
+// seen. name describes the store in errors, e.g. "override store".
+//
+// A migration's version is its position in migrations plus one, so released
+// migrations are never edited, only appended to.
+func Open(ctx context.Context, path, name string, migrations []string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	// SQLite allows a single writer; one connection avoids busy errors between them.
+	db.SetMaxOpenConns(1)
+
+	if err = Migrate(ctx, db, name, migrations); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applies the migrations db has not seen, each in its own transaction
+// together with the schema_migrations row that records it.
+func Migrate(ctx context.Context, db *sql.DB, name string, migrations []string) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL DEFAULT (datetime('now'))
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).
+		Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if current > len(migrations) {
+		return fmt.Errorf("%s schema version %d is newer than this gateway supports (%d)",
+			name, current, len(migrations))
+	}
+
+	for i, statement := range migrations[current:] {
+		version := current + i + 1
+		if err := apply(ctx, db, version, statement); err != nil {
+			return fmt.Errorf("failed to apply %s migration %d: %w", name, version, err)
+		}
+	}
+	return nil
+}
+
+// apply runs one migration and records its version.
+func apply(ctx context.Context, db *sql.DB, version int, statement string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("failed to run migration: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return nil
+}
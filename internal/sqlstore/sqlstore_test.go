@@ -0,0 +1,46 @@
+package sqlstore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/sqlstore"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	first := `CREATE TABLE notes (id INTEGER PRIMARY KEY)`
+	second := `ALTER TABLE notes ADD COLUMN body TEXT NOT NULL DEFAULT ''`
+
+	t.Run("should apply only the migrations the database has not seen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notes.db")
+
+		db, err := sqlstore.Open(ctx, path, "note store", []string{first})
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db, err = sqlstore.Open(ctx, path, "note store", []string{first, second})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		var version int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version))
+		require.Equal(t, 2, version)
+		_, err = db.ExecContext(ctx, `INSERT INTO notes (id, body) VALUES (1, 'hi')`)
+		require.NoError(t, err)
+	})
+
+	t.Run("should reject a schema newer than its migrations", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notes.db")
+
+		db, err := sqlstore.Open(ctx, path, "note store", []string{first, second})
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		_, err = sqlstore.Open(ctx, path, "note store", []string{first})
+		require.ErrorContains(t, err, "note store schema version 2 is newer than this gateway supports (1)")
+	})
+}
@@ -0,0 +1,104 @@
+// Package sse writes server-sent events. Encoders draw their buffers from a
+// pool, so relaying streams does not allocate per event.
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// initialBufferSize fits a typical stream chunk without growing.
+	initialBufferSize = 1 << 10
+
+	// maxPooledBufferSize keeps the buffers of unusually large events out of the
+	// pool, so one large event does not pin its memory for every later stream.
+	maxPooledBufferSize = 64 << 10
+)
+
+//nolint:gochecknoglobals // Process-wide pool shared by all encoders
+var buffers = sync.Pool{
+	New: func() any {
+		b := &buffer{Buffer: *bytes.NewBuffer(make([]byte, 0, initialBufferSize)), json: nil}
+		b.json = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// buffer frames events, with a JSON encoder appending to it.
+type buffer struct {
+	bytes.Buffer
+
+	json *json.Encoder
+}
+
+// Encoder writes server-sent events to a writer, framing each event in a pooled
+// buffer and writing it in a single call. An Encoder is not safe for concurrent
+// use and must be released when the stream ends.
+type Encoder struct {
+	w   io.Writer
+	buf *buffer
+}
+
+// NewEncoder returns an encoder writing events to w.
+func NewEncoder(w io.Writer) *Encoder {
+	buf, _ := buffers.Get().(*buffer)
+	return &Encoder{w: w, buf: buf}
+}
+
+// Encode writes an event named event, or an unnamed one when event is empty,
+// whose data is the JSON encoding of v. As with json.Marshal, HTML characters
+// in strings are escaped.
+func (e *Encoder) Encode(event string, v any) error {
+	e.start(event)
+	// The JSON encoder terminates the value with the newline ending the data line.
+	if err := e.buf.json.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+	return e.flush()
+}
+
+// Data writes an event named event, or an unnamed one when event is empty,
+// whose data is the single line data, e.g. the "[DONE]" sentinel.
+func (e *Encoder) Data(event string, data []byte) error {
+	e.start(event)
+	e.buf.Write(data)
+	e.buf.WriteByte('\n')
+	return e.flush()
+}
+
+// Release returns the encoder's buffer to the pool. The encoder must not be
+// used afterwards.
+func (e *Encoder) Release() {
+	if e.buf == nil {
+		return
+	}
+	if e.buf.Cap() <= maxPooledBufferSize {
+		e.buf.Reset()
+		buffers.Put(e.buf)
+	}
+	e.buf = nil
+}
+
+// start begins framing an event in the buffer.
+func (e *Encoder) start(event string) {
+	e.buf.Reset()
+	if event != "" {
+		e.buf.WriteString("event: ")
+		e.buf.WriteString(event)
+		e.buf.WriteByte('\n')
+	}
+	e.buf.WriteString("data: ")
+}
+
+// flush ends the framed event and writes it.
+func (e *Encoder) flush() error {
+	e.buf.WriteByte('\n')
+	if _, err := e.w.Write(e.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
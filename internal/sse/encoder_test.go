@@ -0,0 +1,119 @@
+package sse_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/sse"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	t.Run("should write unnamed events as a data line", func(t *testing.T) {
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+
+		require.NoError(t, encoder.Encode("", map[string]any{"delta": "Hi", "done": false}))
+		require.NoError(t, encoder.Encode("", map[string]any{"delta": "", "done": true}))
+
+		require.Equal(t,
+			"data: {\"delta\":\"Hi\",\"done\":false}\n\ndata: {\"delta\":\"\",\"done\":true}\n\n",
+			out.String(),
+		)
+	})
+
+	t.Run("should name events", func(t *testing.T) {
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+
+		require.NoError(t, encoder.Encode("error", map[string]any{"partial": true}))
+
+		require.Equal(t, "event: error\ndata: {\"partial\":true}\n\n", out.String())
+	})
+
+	t.Run("should escape HTML as json.Marshal does", func(t *testing.T) {
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+
+		require.NoError(t, encoder.Encode("", "<b>"))
+
+		require.Equal(t, "data: \"\\u003cb\\u003e\"\n\n", out.String())
+	})
+
+	t.Run("should write nothing for values JSON cannot encode", func(t *testing.T) {
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+
+		require.Error(t, encoder.Encode("", func() {}))
+		require.NoError(t, encoder.Encode("", 1))
+
+		require.Equal(t, "data: 1\n\n", out.String())
+	})
+
+	t.Run("should return write errors", func(t *testing.T) {
+		encoder := sse.NewEncoder(failingWriter{})
+		defer encoder.Release()
+
+		require.ErrorContains(t, encoder.Encode("", 1), "connection reset")
+	})
+}
+
+func TestEncoder_Data(t *testing.T) {
+	t.Run("should write raw data", func(t *testing.T) {
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+
+		require.NoError(t, encoder.Data("", []byte("[DONE]")))
+
+		require.Equal(t, "data: [DONE]\n\n", out.String())
+	})
+}
+
+func TestEncoder_Release(t *testing.T) {
+	t.Run("should not leak a large event into later encoders", func(t *testing.T) {
+		large := sse.NewEncoder(io.Discard)
+		require.NoError(t, large.Encode("", strings.Repeat("x", 128<<10)))
+		large.Release()
+		large.Release()
+
+		var out bytes.Buffer
+		encoder := sse.NewEncoder(&out)
+		defer encoder.Release()
+		require.NoError(t, encoder.Encode("", "y"))
+
+		require.Equal(t, "data: \"y\"\n\n", out.String())
+	})
+}
+
+func BenchmarkEncoder_Encode(b *testing.B) {
+	encoder := sse.NewEncoder(io.Discard)
+	defer encoder.Release()
+	chunk := &domain.StreamChunk{
+		Delta:           "Hello, world",
+		Done:            false,
+		Error:           nil,
+		ProviderHeaders: nil,
+		Metadata:        nil,
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = encoder.Encode("", chunk)
+	}
+}
@@ -0,0 +1,357 @@
+// Package usage provides the usage record store behind usage reporting.
+// Records are held in memory for fast aggregation and, when a path is
+// configured, appended to a JSON Lines file that is replayed on startup.
+// Records past the retention window are rolled up into hourly and then daily
+// aggregates, keeping the store small while reports keep their groupings.
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/observability"
+)
+
+const (
+	filePermissions = 0o600
+	maxLineBytes    = 1 << 20
+)
+
+// Store implements domain.UsageStore.
+type Store struct {
+	mu                 sync.RWMutex
+	records            []domain.UsageRecord // Oldest first
+	path               string
+	file               *os.File
+	retention          time.Duration
+	maxRecords         int
+	compactionInterval time.Duration
+
+	// Rollups of records past the retention window, oldest first.
+	rollups         []domain.UsageRecord
+	hourlyRetention time.Duration
+	dailyRetention  time.Duration
+}
+
+// NewStore creates the usage store (DI constructor), replaying the persisted
+// file when one is configured. It returns nil when usage recording is disabled.
+func NewStore(cfg *config.UsageConfig) (*Store, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil //nolint:nilnil // A nil store disables usage recording
+	}
+
+	store := &Store{
+		mu:                 sync.RWMutex{},
+		records:            nil,
+		path:               cfg.Path,
+		file:               nil,
+		retention:          time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		maxRecords:         cfg.MaxRecords,
+		compactionInterval: time.Duration(cfg.CompactionInterval) * time.Second,
+
+		rollups:         nil,
+		hourlyRetention: time.Duration(cfg.RollupHourlyDays) * 24 * time.Hour,
+		dailyRetention:  time.Duration(cfg.RollupDailyDays) * 24 * time.Hour,
+	}
+
+	if store.path == "" {
+		return store, nil
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(store.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage store: %w", err)
+	}
+	store.file = file
+
+	return store, nil
+}
+
+// Record stores a usage record. The record is kept in memory even when
+// persisting it fails. Records arriving late, such as those held back for
+// response evaluation, are kept in time order.
+func (s *Store) Record(_ context.Context, record domain.UsageRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := len(s.records)
+	for i > 0 && s.records[i-1].Time.After(record.Time) {
+		i--
+	}
+	s.records = slices.Insert(s.records, i, record)
+	s.enforceMaxRecords()
+
+	if s.file == nil {
+		return nil
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to persist usage record: %w", err)
+	}
+	return nil
+}
+
+// Query returns the records matching filter, oldest first. Rollups, which
+// precede the records they are older than, match by the start of their hour or
+// day.
+func (s *Store) Query(_ context.Context, filter domain.UsageFilter) ([]domain.UsageRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := query(nil, s.rollups, filter)
+	return query(matched, s.records, filter), nil
+}
+
+// Compact rolls records older than the retention window up into hourly
+// rollups, hourly rollups older than their retention into daily ones, and drops
+// expired daily rollups, then rewrites the persisted file. Without an hourly
+// retention beyond the record retention, records roll up into daily rollups
+// directly. It returns the number of records rolled up.
+func (s *Store) Compact(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	expired := expiredBefore(s.records, now.Add(-s.retention))
+	hourly, daily := splitRollups(s.rollups)
+	changed := expired > 0
+
+	if s.hourlyRetention > s.retention {
+		hourly = domain.RollupUsage(append(hourly, s.records[:expired]...), domain.RollupHourly)
+		aged := expiredBefore(hourly, now.Add(-s.hourlyRetention))
+		changed = changed || aged > 0
+		daily = append(daily, hourly[:aged]...)
+		hourly = hourly[aged:]
+	} else {
+		changed = changed || len(hourly) > 0
+		daily = append(append(daily, hourly...), s.records[:expired]...)
+		hourly = nil
+	}
+
+	daily = domain.RollupUsage(daily, domain.RollupDaily)
+	if s.dailyRetention > 0 {
+		dropped := expiredBefore(daily, now.Add(-s.dailyRetention))
+		changed = changed || dropped > 0
+		daily = daily[dropped:]
+	}
+
+	if !changed {
+		return 0, nil
+	}
+
+	// Rollups are grouped in the order their keys first appear, so they are
+	// sorted to keep the store in time order for queries.
+	s.rollups = append(daily, hourly...)
+	slices.SortStableFunc(s.rollups, byTime)
+	s.records = append([]domain.UsageRecord(nil), s.records[expired:]...)
+
+	if s.file == nil {
+		return expired, nil
+	}
+
+	return expired, s.rewrite()
+}
+
+// RunCompaction compacts the store on the configured interval until ctx is done.
+func (s *Store) RunCompaction(ctx context.Context) {
+	if s.compactionInterval <= 0 {
+		return
+	}
+
+	logger := observability.FromContext(ctx)
+	ticker := time.NewTicker(s.compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			rolledUp, err := s.Compact(now)
+			if err != nil {
+				logger.Error("usage store compaction failed", observability.Error(err))
+				continue
+			}
+			logger.Debug("usage store compacted", observability.Int("rolled_up_records", rolledUp))
+		}
+	}
+}
+
+// Close flushes and closes the persisted file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close usage store: %w", err)
+	}
+	return nil
+}
+
+// load replays the persisted file. Malformed lines are skipped.
+func (s *Store) load() error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open usage store: %w", err)
+	}
+	defer file.Close()
+
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineBytes)
+	for scanner.Scan() {
+		var record domain.UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			skipped++
+			continue
+		}
+		if record.Rollup != "" {
+			s.rollups = append(s.rollups, record)
+			continue
+		}
+		s.records = append(s.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read usage store: %w", err)
+	}
+
+	// Concurrent writers may have appended slightly out of order.
+	slices.SortStableFunc(s.records, byTime)
+	slices.SortStableFunc(s.rollups, byTime)
+	s.enforceMaxRecords()
+
+	observability.FromContext(context.Background()).Info("usage store loaded",
+		observability.Int("records", len(s.records)),
+		observability.Int("rollups", len(s.rollups)),
+		observability.Int("skipped_lines", skipped),
+	)
+	return nil
+}
+
+// rewrite replaces the persisted file with the in-memory rollups and records.
+// The caller must hold s.mu.
+func (s *Store) rewrite() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create usage store: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range slices.Concat(s.rollups, s.records) {
+		if err := encoder.Encode(record); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("failed to write usage store: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write usage store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write usage store: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace usage store: %w", err)
+	}
+
+	// The append handle still points at the replaced file.
+	_ = s.file.Close()
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePermissions)
+	if err != nil {
+		s.file = nil
+		return fmt.Errorf("failed to reopen usage store: %w", err)
+	}
+	s.file = file
+	return nil
+}
+
+// enforceMaxRecords drops the oldest records beyond the configured cap. Records
+// are trimmed in batches so the backing array is not copied on every insert.
+// The caller must hold s.mu.
+func (s *Store) enforceMaxRecords() {
+	if s.maxRecords <= 0 || len(s.records) <= s.maxRecords+s.maxRecords/10 {
+		return
+	}
+
+	excess := len(s.records) - s.maxRecords
+	s.records = append([]domain.UsageRecord(nil), s.records[excess:]...)
+}
+
+// byTime orders usage records oldest first.
+func byTime(a, b domain.UsageRecord) int {
+	return a.Time.Compare(b.Time)
+}
+
+// query appends the records matching filter to matched. records must be oldest
+// first, which Record, load, and Compact maintain, since the scan starts and
+// stops by time.
+func query(matched, records []domain.UsageRecord, filter domain.UsageFilter) []domain.UsageRecord {
+	start := 0
+	if !filter.From.IsZero() {
+		start = expiredBefore(records, filter.From)
+	}
+
+	for _, record := range records[start:] {
+		if !filter.To.IsZero() && !record.Time.Before(filter.To) {
+			break
+		}
+		if filter.Matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
+
+// expiredBefore returns the number of records, oldest first, dated before cutoff.
+func expiredBefore(records []domain.UsageRecord, cutoff time.Time) int {
+	return sort.Search(len(records), func(i int) bool {
+		return !records[i].Time.Before(cutoff)
+	})
+}
+
+// splitRollups separates hourly from daily rollups, keeping their order.
+func splitRollups(rollups []domain.UsageRecord) ([]domain.UsageRecord, []domain.UsageRecord) {
+	var hourly, daily []domain.UsageRecord
+	for _, rollup := range rollups {
+		if rollup.Rollup == domain.RollupHourly {
+			hourly = append(hourly, rollup)
+		} else {
+			daily = append(daily, rollup)
+		}
+	}
+	return hourly, daily
+}
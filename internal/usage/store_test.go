@@ -0,0 +1,203 @@
+package usage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/config"
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/usage"
+)
+
+func newRecord(at time.Time, model, key string) domain.UsageRecord {
+	return domain.UsageRecord{
+		Time:        at,
+		Tenant:      domain.DefaultTenant,
+		ClientKey:   key,
+		Provider:    "openai",
+		Model:       model,
+		TotalTokens: 10,
+		Cost:        0.01,
+	}
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should return nil when disabled", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{Enabled: false})
+		require.NoError(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("should query records by time range and key", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{Enabled: true})
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		require.NoError(t, store.Record(ctx, newRecord(now.Add(-2*time.Hour), "gpt-4", "mobile")))
+		require.NoError(t, store.Record(ctx, newRecord(now.Add(-time.Hour), "gpt-4", "batch")))
+		require.NoError(t, store.Record(ctx, newRecord(now, "gpt-4o", "mobile")))
+
+		records, err := store.Query(ctx, domain.UsageFilter{From: now.Add(-90 * time.Minute), To: now})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "batch", records[0].ClientKey)
+
+		records, err = store.Query(ctx, domain.UsageFilter{ClientKey: "mobile"})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+	})
+
+	t.Run("should keep late records in time order", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{Enabled: true})
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		require.NoError(t, store.Record(ctx, newRecord(now, "gpt-4o", "mobile")))
+		require.NoError(t, store.Record(ctx, newRecord(now.Add(-time.Minute), "gpt-4", "batch")))
+
+		records, err := store.Query(ctx, domain.UsageFilter{From: now.Add(-time.Hour), To: now})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "batch", records[0].ClientKey)
+	})
+
+	t.Run("should find every record in range after out of order writes", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{Enabled: true})
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		offsets := []time.Duration{0, -3 * time.Hour, time.Hour, -time.Hour, -2 * time.Hour, 2 * time.Hour}
+		for _, offset := range offsets {
+			require.NoError(t, store.Record(ctx, newRecord(now.Add(offset), "gpt-4", "mobile")))
+		}
+
+		records, err := store.Query(ctx, domain.UsageFilter{From: now.Add(-2 * time.Hour), To: now.Add(2 * time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		for i := 1; i < len(records); i++ {
+			require.True(t, records[i-1].Time.Before(records[i].Time))
+		}
+	})
+
+	t.Run("should persist records and replay them on restart", func(t *testing.T) {
+		cfg := &config.UsageConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "usage.jsonl")}
+		ctx := context.Background()
+
+		store, err := usage.NewStore(cfg)
+		require.NoError(t, err)
+		require.NoError(t, store.Record(ctx, newRecord(now, "gpt-4", "mobile")))
+		require.NoError(t, store.Close())
+
+		reopened, err := usage.NewStore(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = reopened.Close() })
+
+		records, err := reopened.Query(ctx, domain.UsageFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "gpt-4", records[0].Model)
+	})
+
+	t.Run("should roll up expired records and rewrite the file on compaction", func(t *testing.T) {
+		cfg := &config.UsageConfig{
+			Enabled:       true,
+			Path:          filepath.Join(t.TempDir(), "usage.jsonl"),
+			RetentionDays: 1,
+		}
+		ctx := context.Background()
+
+		store, err := usage.NewStore(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+
+		require.NoError(t, store.Record(ctx, newRecord(now.Add(-48*time.Hour), "gpt-4", "mobile")))
+		require.NoError(t, store.Record(ctx, newRecord(now, "gpt-4o", "mobile")))
+
+		rolledUp, err := store.Compact(now)
+		require.NoError(t, err)
+		require.Equal(t, 1, rolledUp)
+
+		// Records written after compaction land in the rewritten file.
+		require.NoError(t, store.Record(ctx, newRecord(now.Add(time.Minute), "gpt-4o", "batch")))
+
+		data, err := os.ReadFile(cfg.Path)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"cost":0.01,"rollup":"day","requests":1}`)
+		require.Contains(t, string(data), `"client_key":"batch"`)
+
+		// Rollups are replayed on restart.
+		require.NoError(t, store.Close())
+		store, err = usage.NewStore(cfg)
+		require.NoError(t, err)
+
+		records, err := store.Query(ctx, domain.UsageFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		require.Equal(t, domain.RollupDaily, records[0].Rollup)
+	})
+
+	t.Run("should roll records up hourly, then daily, and drop expired rollups", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{
+			Enabled:          true,
+			RetentionDays:    1,
+			RollupHourlyDays: 3,
+			RollupDailyDays:  10,
+		})
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		for _, at := range []time.Time{
+			now.Add(-20 * 24 * time.Hour),
+			now.Add(-5*24*time.Hour - time.Hour),
+			now.Add(-5 * 24 * time.Hour),
+			now.Add(-48*time.Hour - time.Minute),
+			now.Add(-48*time.Hour - 2*time.Minute),
+			now,
+		} {
+			require.NoError(t, store.Record(ctx, newRecord(at, "gpt-4", "mobile")))
+		}
+
+		rolledUp, err := store.Compact(now)
+		require.NoError(t, err)
+		require.Equal(t, 5, rolledUp)
+
+		records, err := store.Query(ctx, domain.UsageFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+
+		require.Equal(t, domain.RollupDaily, records[0].Rollup)
+		require.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), records[0].Time)
+		require.Equal(t, 2, records[0].Requests)
+		require.Equal(t, domain.RollupHourly, records[1].Rollup)
+		require.Equal(t, time.Date(2026, 3, 8, 11, 0, 0, 0, time.UTC), records[1].Time)
+		require.Equal(t, 2, records[1].Requests)
+		require.InDelta(t, 0.02, records[1].Cost, 1e-9)
+		require.Empty(t, records[2].Rollup)
+
+		aggregates, err := domain.AggregateUsage(records, domain.GroupByModel)
+		require.NoError(t, err)
+		require.Equal(t, 5, aggregates[0].Requests)
+	})
+
+	t.Run("should cap records held in memory", func(t *testing.T) {
+		store, err := usage.NewStore(&config.UsageConfig{Enabled: true, MaxRecords: 10})
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		for i := range 30 {
+			require.NoError(t, store.Record(ctx, newRecord(now.Add(time.Duration(i)*time.Second), "gpt-4", "")))
+		}
+
+		records, err := store.Query(ctx, domain.UsageFilter{})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(records), 11)
+		require.Equal(t, now.Add(29*time.Second), records[len(records)-1].Time)
+	})
+}
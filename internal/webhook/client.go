@@ -0,0 +1,88 @@
+// Package webhook posts JSON payloads to HTTP endpoints with retries.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// errRetryable marks webhook failures worth retrying.
+var errRetryable = errors.New("retryable webhook failure")
+
+// Client POSTs JSON payloads to one URL, retrying network errors, 429, and 5xx
+// responses with exponential backoff.
+type Client struct {
+	url         string
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewClient creates a webhook client. maxAttempts below 1 is treated as 1.
+func NewClient(url string, timeout time.Duration, maxAttempts int, backoff time.Duration) *Client {
+	return &Client{
+		url: url,
+		client: &http.Client{
+			Transport:     nil,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       timeout,
+		},
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+	}
+}
+
+// Post delivers payload as JSON, retrying transient failures until attempts run out or ctx is done.
+func (c *Client) Post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delay := c.backoff
+	for attempt := 1; ; attempt++ {
+		err = c.post(ctx, body)
+		if err == nil || !errors.Is(err, errRetryable) || attempt >= c.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook delivery canceled: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes one delivery attempt.
+func (c *Client) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRetryable, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: webhook returned status %d", errRetryable, resp.StatusCode)
+	default:
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/webhook"
+)
+
+func TestClient_Post(t *testing.T) {
+	payload := map[string]string{"subject": "key:alice"}
+
+	t.Run("should retry server errors until delivered", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var received map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			require.Equal(t, payload, received)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := webhook.NewClient(server.URL, time.Second, 3, time.Millisecond)
+		require.NoError(t, client.Post(context.Background(), payload))
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := webhook.NewClient(server.URL, time.Second, 2, time.Millisecond)
+		require.Error(t, client.Post(context.Background(), payload))
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("should not retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := webhook.NewClient(server.URL, time.Second, 3, time.Millisecond)
+		require.Error(t, client.Post(context.Background(), payload))
+		require.Equal(t, int32(1), calls.Load())
+	})
+}
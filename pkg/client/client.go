@@ -0,0 +1,319 @@
+// Package client is a Go SDK for the Calcifer gateway HTTP API. It sends chat
+// completions, streams replies as an iterator, retries transient failures, and
+// exposes the gateway's idempotency cache headers, so Go services need no
+// hand-rolled HTTP or server-sent events code.
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(os.Getenv("CALCIFER_API_KEY")))
+//	resp, err := c.Complete(ctx, &client.CompletionRequest{
+//		Model:    "gpt-4o",
+//		Messages: []client.Message{{Role: "user", Content: "Hello"}},
+//	})
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Gateway HTTP headers.
+const (
+	// IdempotencyKeyHeader identifies a logical request; the gateway replays the
+	// stored response of a duplicate instead of calling a provider again.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader marks responses replayed from the idempotency cache.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// ProviderHeader forces a provider instead of routing by model.
+	ProviderHeader = "X-Provider"
+
+	// TenantHeader names the tenant of a request.
+	TenantHeader = "X-Tenant-Id"
+
+	// RequestIDHeader carries the gateway request ID.
+	RequestIDHeader = "X-Request-Id"
+)
+
+// idempotencyKeyBytes is the entropy of generated idempotency keys.
+const idempotencyKeyBytes = 16
+
+// Client calls a Calcifer gateway. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+	retry   RetryPolicy
+	header  http.Header
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with a client API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient sends requests with httpClient instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+// WithRetry retries transient failures by policy; see DefaultRetryPolicy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithHeader sends a header with every request, such as TenantHeader.
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.header.Set(name, value)
+	}
+}
+
+// New creates a client for the gateway at baseURL, retrying by DefaultRetryPolicy.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  "",
+		http:    http.DefaultClient,
+		retry:   DefaultRetryPolicy(),
+		header:  make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// RequestOption configures a single request.
+type RequestOption func(http.Header)
+
+// WithIdempotencyKey sets the idempotency key of a request. Without one, retried
+// requests get a generated key so a retry of a request that reached the gateway
+// is replayed rather than billed twice, when the gateway caches responses.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(h http.Header) {
+		h.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// WithProvider forces the provider of a request instead of routing by model.
+func WithProvider(name string) RequestOption {
+	return func(h http.Header) {
+		h.Set(ProviderHeader, name)
+	}
+}
+
+// WithRequestHeader sends a header with a single request.
+func WithRequestHeader(name, value string) RequestOption {
+	return func(h http.Header) {
+		h.Set(name, value)
+	}
+}
+
+// Replayed reports whether a response with header was replayed from the
+// gateway's idempotency cache.
+func Replayed(header http.Header) bool {
+	return header.Get(IdempotentReplayedHeader) == "true"
+}
+
+// Complete sends a chat completion request and waits for the full reply.
+func (c *Client) Complete(
+	ctx context.Context,
+	req *CompletionRequest,
+	opts ...RequestOption,
+) (*CompletionResponse, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/v1/completions", completionBody{req, false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion CompletionResponse
+	if err = json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	completion.Header = resp.Header
+	return &completion, nil
+}
+
+// Stream sends a chat completion request and returns its reply as a stream of
+// chunks. Only establishing the stream is retried. The caller must Close it.
+func (c *Client) Stream(ctx context.Context, req *CompletionRequest, opts ...RequestOption) (*Stream, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/v1/completions", completionBody{req, true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp), nil
+}
+
+// Models lists the models and aliases the gateway serves.
+func (c *Client) Models(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/v1/models", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var models struct {
+		Data []ModelInfo `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return models.Data, nil
+}
+
+// completionBody encodes a completion request with its stream flag.
+type completionBody struct {
+	*CompletionRequest
+
+	Stream bool `json:"stream,omitempty"`
+}
+
+// send sends a request, retrying transient failures, and turns non-2xx
+// responses into an APIError.
+func (c *Client) send(
+	ctx context.Context,
+	method, path string,
+	body any,
+	opts []RequestOption,
+) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	header := c.header.Clone()
+	for _, opt := range opts {
+		opt(header)
+	}
+	if method == http.MethodPost && c.retry.MaxAttempts > 1 && header.Get(IdempotencyKeyHeader) == "" {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return nil, err
+		}
+		header.Set(IdempotencyKeyHeader, key)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, method, path, data, header)
+		if err == nil {
+			return resp, nil
+		}
+
+		delay, retryable := c.retry.delay(attempt, err)
+		if !retryable || ctx.Err() != nil {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one request.
+func (c *Client) attempt(
+	ctx context.Context,
+	method, path string,
+	data []byte,
+	header http.Header,
+) (*http.Response, error) {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = header.Clone()
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach gateway: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apiError(resp, body)
+	}
+	return resp, nil
+}
+
+// apiError parses an error response, falling back to its raw body.
+func apiError(resp *http.Response, data []byte) *APIError {
+	apiErr := &APIError{
+		Status:     resp.StatusCode,
+		Type:       "",
+		Message:    strings.TrimSpace(string(data)),
+		RequestID:  resp.Header.Get(RequestIDHeader),
+		Partial:    false,
+		RetryAfter: retryAfter(resp.Header),
+	}
+	parseErrorEnvelope(apiErr, data)
+	return apiErr
+}
+
+// parseErrorEnvelope fills apiErr from the gateway's JSON error envelope, if
+// data holds one.
+func parseErrorEnvelope(apiErr *APIError, data []byte) {
+	var envelope struct {
+		Error struct {
+			Type      string `json:"type"`
+			Message   string `json:"message"`
+			RequestID string `json:"request_id"`
+		} `json:"error"`
+		Partial bool `json:"partial"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error.Message == "" {
+		return
+	}
+
+	apiErr.Type = envelope.Error.Type
+	apiErr.Message = envelope.Error.Message
+	apiErr.Partial = envelope.Partial
+	if envelope.Error.RequestID != "" {
+		apiErr.RequestID = envelope.Error.RequestID
+	}
+}
+
+// newIdempotencyKey generates a random idempotency key.
+func newIdempotencyKey() (string, error) {
+	random := make([]byte, idempotencyKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
@@ -0,0 +1,317 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/pkg/client"
+)
+
+// reply is one canned gateway response.
+type reply struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// serve starts a gateway stand-in answering successive requests with replies,
+// repeating the last one, and records the requests it received.
+func serve(t *testing.T, replies ...reply) (*httptest.Server, func() []*http.Request) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		clone := r.Clone(r.Context())
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+
+		mu.Lock()
+		received = append(received, clone)
+		next := replies[min(len(received), len(replies))-1]
+		mu.Unlock()
+
+		for name, value := range next.header {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(next.status)
+		fmt.Fprint(w, next.body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+// fastRetry retries without noticeable delays.
+func fastRetry() client.Option {
+	return client.WithRetry(client.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+}
+
+func request() *client.CompletionRequest {
+	return &client.CompletionRequest{Model: "gpt-4", Messages: []client.Message{{Role: "user", Content: "hi"}}}
+}
+
+func TestClient_Complete(t *testing.T) {
+	t.Run("should decode the completion and expose its headers", func(t *testing.T) {
+		server, received := serve(t, reply{
+			status: http.StatusOK,
+			header: map[string]string{client.RequestIDHeader: "req-1", client.IdempotentReplayedHeader: "true"},
+			body:   `{"id":"c1","model":"gpt-4","provider":"openai","content":"hello","usage":{"total_tokens":3}}`,
+		})
+		c := client.New(server.URL, client.WithAPIKey("secret"), client.WithHeader(client.TenantHeader, "acme"))
+
+		resp, err := c.Complete(t.Context(), request(), client.WithProvider("openai"))
+
+		require.NoError(t, err)
+		require.Equal(t, "hello", resp.Content)
+		require.Equal(t, 3, resp.Usage.TotalTokens)
+		require.Equal(t, "req-1", resp.RequestID())
+		require.True(t, resp.Replayed())
+
+		req := received()[0]
+		require.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+		require.Equal(t, "acme", req.Header.Get(client.TenantHeader))
+		require.Equal(t, "openai", req.Header.Get(client.ProviderHeader))
+	})
+
+	t.Run("should retry transient failures with the same idempotency key", func(t *testing.T) {
+		server, received := serve(t,
+			reply{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "0"}, body: "busy"},
+			reply{status: http.StatusOK, header: nil, body: `{"content":"hello"}`},
+		)
+		c := client.New(server.URL, fastRetry())
+
+		resp, err := c.Complete(t.Context(), request())
+
+		require.NoError(t, err)
+		require.Equal(t, "hello", resp.Content)
+		require.Len(t, received(), 2)
+		key := received()[0].Header.Get(client.IdempotencyKeyHeader)
+		require.NotEmpty(t, key)
+		require.Equal(t, key, received()[1].Header.Get(client.IdempotencyKeyHeader))
+	})
+
+	t.Run("should keep a caller idempotency key", func(t *testing.T) {
+		server, received := serve(t, reply{status: http.StatusOK, header: nil, body: `{}`})
+		c := client.New(server.URL)
+
+		_, err := c.Complete(t.Context(), request(), client.WithIdempotencyKey("order-42"))
+
+		require.NoError(t, err)
+		require.Equal(t, "order-42", received()[0].Header.Get(client.IdempotencyKeyHeader))
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		server, received := serve(t, reply{status: http.StatusBadGateway, header: nil, body: "upstream down"})
+		c := client.New(server.URL, fastRetry())
+
+		_, err := c.Complete(t.Context(), request())
+
+		var apiErr *client.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusBadGateway, apiErr.Status)
+		require.Len(t, received(), 3)
+	})
+
+	t.Run("should parse the error envelope", func(t *testing.T) {
+		server, received := serve(t, reply{
+			status: http.StatusTooManyRequests,
+			header: map[string]string{"Retry-After": "7"},
+			body:   `{"error":{"type":"rate_limit_error","message":"slow down","request_id":"req-9"}}`,
+		})
+		c := client.New(server.URL, client.WithRetry(client.NoRetry()))
+
+		_, err := c.Complete(t.Context(), request())
+
+		var apiErr *client.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusTooManyRequests, apiErr.Status)
+		require.Equal(t, "rate_limit_error", apiErr.Type)
+		require.Equal(t, "slow down", apiErr.Message)
+		require.Equal(t, "req-9", apiErr.RequestID)
+		require.Equal(t, 7*time.Second, apiErr.RetryAfter)
+		require.Len(t, received(), 1)
+		require.Empty(t, received()[0].Header.Get(client.IdempotencyKeyHeader))
+	})
+
+	t.Run("should not retry client errors", func(t *testing.T) {
+		server, received := serve(t, reply{status: http.StatusBadRequest, header: nil, body: "bad request"})
+		c := client.New(server.URL, fastRetry())
+
+		_, err := c.Complete(t.Context(), request())
+
+		var apiErr *client.APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusBadRequest, apiErr.Status)
+		require.Equal(t, "bad request", apiErr.Message)
+		require.Len(t, received(), 1)
+	})
+}
+
+func TestClient_Stream(t *testing.T) {
+	t.Run("should iterate chunks until the done chunk", func(t *testing.T) {
+		server, received := serve(t, reply{
+			status: http.StatusOK,
+			header: nil,
+			body: "data: {\"delta\":\"Hel\",\"done\":false,\"metadata\":{\"route\":\"a\"}}\n\n" +
+				"data: {\"delta\":\"lo\",\"done\":false}\n\n" +
+				"data: {\"delta\":\"\",\"done\":true}\n\n",
+		})
+		c := client.New(server.URL)
+
+		stream, err := c.Stream(t.Context(), request())
+		require.NoError(t, err)
+		defer stream.Close()
+
+		var content strings.Builder
+		for stream.Next() {
+			content.WriteString(stream.Chunk().Delta)
+		}
+
+		require.NoError(t, stream.Err())
+		require.Equal(t, "Hello", content.String())
+		require.Equal(t, map[string]string{"route": "a"}, stream.Metadata())
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(received()[0].Body).Decode(&body))
+		require.Equal(t, true, body["stream"])
+	})
+
+	t.Run("should report an error event as a partial APIError", func(t *testing.T) {
+		server, _ := serve(t, reply{
+			status: http.StatusOK,
+			header: nil,
+			body: "data: {\"delta\":\"Hel\",\"done\":false}\n\n" +
+				"event: error\ndata: {\"error\":{\"type\":\"server_error\",\"message\":\"boom\"},\"partial\":true}\n\n",
+		})
+		c := client.New(server.URL)
+
+		stream, err := c.Stream(t.Context(), request())
+		require.NoError(t, err)
+		defer stream.Close()
+
+		require.True(t, stream.Next())
+		require.False(t, stream.Next())
+
+		var apiErr *client.APIError
+		require.ErrorAs(t, stream.Err(), &apiErr)
+		require.Equal(t, "server_error", apiErr.Type)
+		require.Equal(t, "boom", apiErr.Message)
+		require.True(t, apiErr.Partial)
+	})
+
+	t.Run("should fail a stream that ends without the done chunk", func(t *testing.T) {
+		server, _ := serve(t, reply{
+			status: http.StatusOK,
+			header: nil,
+			body:   "data: {\"delta\":\"Hel\",\"done\":false}\n\n",
+		})
+		c := client.New(server.URL)
+
+		stream, err := c.Stream(t.Context(), request())
+		require.NoError(t, err)
+		defer stream.Close()
+
+		require.True(t, stream.Next())
+		require.False(t, stream.Next())
+		require.Error(t, stream.Err())
+	})
+}
+
+func TestClient_Models(t *testing.T) {
+	t.Run("should list models with deprecations", func(t *testing.T) {
+		server, _ := serve(t, reply{
+			status: http.StatusOK,
+			header: nil,
+			body: `{"data":[{"id":"gpt-4","providers":["openai"],` +
+				`"deprecation":{"model":"gpt-4","sunset":"2027-01-01T00:00:00Z","replacement":"gpt-4o"}}]}`,
+		})
+		c := client.New(server.URL)
+
+		models, err := c.Models(t.Context())
+
+		require.NoError(t, err)
+		require.Len(t, models, 1)
+		require.Equal(t, []string{"openai"}, models[0].Providers)
+		require.Equal(t, "gpt-4o", models[0].Deprecation.Replacement)
+	})
+}
+
+func TestTypes_MirrorDomain(t *testing.T) {
+	t.Run("should decode a gateway completion response losslessly", func(t *testing.T) {
+		want := domain.CompletionResponse{
+			ID:         "c1",
+			Model:      "gpt-4",
+			Provider:   "openai",
+			Content:    "hello",
+			FinishTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Usage: domain.Usage{
+				PromptTokens:       5,
+				CompletionTokens:   7,
+				TotalTokens:        12,
+				Cost:               0.5,
+				CachedPromptTokens: 2,
+				ReasoningTokens:    3,
+				Characters:         4,
+				Images:             1,
+				ProviderCost:       0.4,
+				Currency:           "EUR",
+			},
+			Choices:  []domain.Choice{{Index: 0, Content: "hello", FinishReason: "stop"}},
+			Metadata: map[string]string{"route": "a"},
+		}
+		data, err := json.Marshal(want)
+		require.NoError(t, err)
+
+		var got client.CompletionResponse
+		require.NoError(t, json.Unmarshal(data, &got))
+		roundTrip, err := json.Marshal(got)
+		require.NoError(t, err)
+
+		require.JSONEq(t, string(data), string(roundTrip))
+	})
+
+	t.Run("should encode a request the gateway decodes losslessly", func(t *testing.T) {
+		topP, seed := 0.9, int64(7)
+		req := client.CompletionRequest{
+			Model:       "gpt-4",
+			Messages:    []client.Message{{Role: "tool", Content: "42", ToolCallID: "call-1"}},
+			Temperature: 0.2,
+			MaxTokens:   64,
+			Metadata:    map[string]string{"team": "a"},
+			TopP:        &topP,
+			Stop:        []string{"\n"},
+			N:           2,
+			Seed:        &seed,
+			LogitBias:   map[string]int{"50256": -100},
+		}
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		var decoded domain.CompletionRequest
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		roundTrip, err := json.Marshal(decoded)
+		require.NoError(t, err)
+
+		require.JSONEq(t, string(data), string(roundTrip))
+	})
+}
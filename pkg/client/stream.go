@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errStreamIncomplete reports a stream that ended without its final chunk.
+var errStreamIncomplete = errors.New("stream ended before completion")
+
+// Stream iterates over the chunks of a streamed completion:
+//
+//	for stream.Next() {
+//		fmt.Print(stream.Chunk().Delta)
+//	}
+//	if err := stream.Err(); err != nil { ... }
+type Stream struct {
+	resp     *http.Response
+	scanner  *bufio.Scanner
+	chunk    StreamChunk
+	metadata map[string]string
+	done     bool
+	err      error
+}
+
+// newStream reads the server-sent events of resp.
+func newStream(resp *http.Response) *Stream {
+	return &Stream{
+		resp:     resp,
+		scanner:  bufio.NewScanner(resp.Body),
+		chunk:    StreamChunk{Delta: "", Done: false, Metadata: nil},
+		metadata: nil,
+		done:     false,
+		err:      nil,
+	}
+}
+
+// Next advances to the next chunk, returning false when the stream completed
+// or failed; Err tells which.
+func (s *Stream) Next() bool {
+	if s.done || s.err != nil {
+		return false
+	}
+
+	event := ""
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			if event == "error" {
+				s.err = apiError(s.resp, data)
+				return false
+			}
+
+			var chunk StreamChunk
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				s.err = fmt.Errorf("failed to decode stream chunk: %w", err)
+				return false
+			}
+			if s.metadata == nil {
+				s.metadata = chunk.Metadata
+			}
+			s.chunk = chunk
+			s.done = chunk.Done
+			return true
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		s.err = fmt.Errorf("failed to read stream: %w", err)
+	} else {
+		s.err = errStreamIncomplete
+	}
+	return false
+}
+
+// Chunk returns the chunk Next advanced to.
+func (s *Stream) Chunk() StreamChunk {
+	return s.chunk
+}
+
+// Err returns the error that ended the stream, or nil once its final chunk was
+// read. A failure reported by the gateway mid-stream is an *APIError.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Metadata returns the gateway annotations of the first chunk.
+func (s *Stream) Metadata() map[string]string {
+	return s.metadata
+}
+
+// Header returns the HTTP response headers.
+func (s *Stream) Header() http.Header {
+	return s.resp.Header
+}
+
+// Close releases the connection; call it even when the stream is not drained.
+func (s *Stream) Close() error {
+	if err := s.resp.Body.Close(); err != nil {
+		return fmt.Errorf("failed to close stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CompletionRequest is a chat completion request, as accepted by /v1/completions.
+type CompletionRequest struct {
+	Model       string            `json:"model"`
+	Messages    []Message         `json:"messages"`
+	Temperature float64           `json:"temperature,omitempty"`
+	MaxTokens   int               `json:"max_tokens,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+
+	// Optional sampling parameters. Pointers distinguish "unset" from a zero value.
+	TopP             *float64       `json:"top_p,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	N                int            `json:"n,omitempty"`
+	Seed             *int64         `json:"seed,omitempty"`
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
+	LogitBias        map[string]int `json:"logit_bias,omitempty"`
+
+	// Tools lists the functions the model may call instead of answering.
+	Tools []Tool `json:"tools,omitempty"`
+}
+
+// Message is a chat message.
+type Message struct {
+	Role    string `json:"role"` // system, developer, user, assistant, or tool
+	Content string `json:"content"`
+
+	// ToolCallID names the tool call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+
+	// ToolCalls lists the tool calls an assistant message made.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function; Parameters is its JSON schema.
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a call of a tool made by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // always "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and carries its JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// CompletionResponse is a chat completion.
+type CompletionResponse struct {
+	ID         string    `json:"id"`
+	Model      string    `json:"model"`
+	Provider   string    `json:"provider"`
+	Content    string    `json:"content"`
+	Usage      Usage     `json:"usage"`
+	FinishTime time.Time `json:"finish_time"`
+
+	// Choices lists every generated completion when more than one was requested (n > 1).
+	// Content always holds the first choice.
+	Choices []Choice `json:"choices,omitempty"`
+
+	// ToolCalls lists the tool calls the model made for the first choice.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// Metadata carries gateway annotations about how the request was handled.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Header holds the HTTP response headers, including forwarded provider headers.
+	Header http.Header `json:"-"`
+}
+
+// RequestID returns the gateway request ID, for correlating with gateway logs.
+func (r *CompletionResponse) RequestID() string {
+	return r.Header.Get(RequestIDHeader)
+}
+
+// Replayed reports whether the gateway served the response from its idempotency
+// cache instead of calling a provider, so it was not billed again.
+func (r *CompletionResponse) Replayed() bool {
+	return Replayed(r.Header)
+}
+
+// Choice is one of several completions generated for a single request.
+type Choice struct {
+	Index        int    `json:"index"`
+	Content      string `json:"content"`
+	FinishReason string `json:"finish_reason,omitempty"`
+}
+
+// Usage is the token consumption and cost of a request.
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+
+	// CachedPromptTokens counts the prompt tokens served from the provider's prompt cache.
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
+	// ReasoningTokens counts hidden reasoning tokens, included in CompletionTokens.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	// Characters counts the input characters of a speech request.
+	Characters int `json:"characters,omitempty"`
+	// Images counts the images billed for the request.
+	Images int `json:"images,omitempty"`
+
+	// ProviderCost is the raw provider cost in USD, set when Cost is a marked-up or
+	// converted chargeback amount.
+	ProviderCost float64 `json:"provider_cost,omitempty"`
+	// Currency is the currency of Cost when it is a chargeback amount.
+	Currency string `json:"currency,omitempty"`
+}
+
+// StreamChunk is one piece of a streamed completion.
+type StreamChunk struct {
+	Delta string `json:"delta"`
+	Done  bool   `json:"done"`
+
+	// Metadata carries gateway annotations about how the request was handled; set on the
+	// first chunk, and on the last when the gateway cuts the stream short.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ModelInfo describes a model the gateway serves.
+type ModelInfo struct {
+	ID        string   `json:"id"`
+	Providers []string `json:"providers,omitempty"` // routing providers serving the model, by name
+	AliasOf   string   `json:"alias_of,omitempty"`  // set for configured model aliases
+
+	Deprecation *Deprecation `json:"deprecation,omitempty"` // set for deprecated models
+}
+
+// Deprecation announces that a model is retired at Sunset.
+type Deprecation struct {
+	Model       string    `json:"model"`
+	Sunset      time.Time `json:"sunset"`
+	Replacement string    `json:"replacement,omitempty"`
+}
+
+// APIError is a non-2xx gateway response. Type is empty for responses without the
+// JSON error envelope.
+type APIError struct {
+	Status    int
+	Type      string
+	Message   string
+	RequestID string
+
+	// Partial is set when a stream failed after content was already delivered.
+	Partial bool
+
+	// RetryAfter is the delay the gateway asked for with Retry-After, if any.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("gateway returned %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("gateway returned %d %s: %s", e.Status, e.Type, e.Message)
+}
@@ -0,0 +1,108 @@
+// Package gateway embeds Calcifer's routing, request coalescing, and cost logic
+// in another Go program, calling providers in-process without the HTTP server.
+//
+//	openai, err := gateway.NewOpenAIProvider(gateway.OpenAIConfig{APIKey: os.Getenv("OPENAI_API_KEY")})
+//	gw, err := gateway.New(ctx,
+//		gateway.WithProvider(openai),
+//		gateway.WithPricing("gpt-4o", gateway.PricingConfig{InputCostPer1K: 0.0025, OutputCostPer1K: 0.01}),
+//	)
+//	resp, err := gw.Complete(ctx, &gateway.CompletionRequest{
+//		Model:    "gpt-4o",
+//		Messages: []gateway.Message{{Role: "user", Content: "Hello"}},
+//	})
+//
+// HTTP concerns, such as client authentication, rate limiting, and the
+// idempotency cache, stay with the server.
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/registry"
+)
+
+// Gateway routes completion requests to its providers and prices their usage.
+// It is safe for concurrent use.
+type Gateway struct {
+	service   *domain.GatewayService
+	providers domain.ProviderRegistry
+}
+
+// New builds a gateway from opts. At least one provider is needed to serve
+// requests; models without pricing are served at zero cost.
+func New(ctx context.Context, opts ...Option) (*Gateway, error) {
+	settings := newSettings()
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	providers := registry.NewRegistry()
+	for _, provider := range settings.providers {
+		if err := providers.Register(ctx, provider); err != nil {
+			return nil, fmt.Errorf("failed to register provider %s: %w", provider.Name(), err)
+		}
+	}
+
+	pricing := domain.NewInMemoryPricingRegistry()
+	for model, config := range settings.pricing {
+		if err := pricing.RegisterPricing(ctx, model, config); err != nil {
+			return nil, fmt.Errorf("failed to register pricing for %s: %w", model, err)
+		}
+	}
+
+	var calculator domain.CostCalculator = domain.NewStandardCostCalculator(pricing)
+	if settings.chargeback != nil {
+		chargeback, err := domain.NewChargebackCostCalculator(
+			calculator,
+			settings.chargeback.markupPercent,
+			settings.chargeback.currency,
+			settings.chargeback.exchangeRates,
+		)
+		if err != nil {
+			return nil, err
+		}
+		calculator = chargeback
+	}
+
+	gatewayOpts, err := settings.gatewayOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Gateway{
+		service:   domain.NewGatewayService(providers, calculator, gatewayOpts...),
+		providers: providers,
+	}, nil
+}
+
+// Complete routes req to a provider serving its model and waits for the full reply.
+func (g *Gateway) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	return g.service.CompleteByModel(ctx, req)
+}
+
+// Stream routes req to a provider serving its model and streams the reply. The
+// channel is closed after the done chunk, or after a chunk carrying an error.
+func (g *Gateway) Stream(ctx context.Context, req *CompletionRequest) (<-chan StreamChunk, error) {
+	return g.service.StreamByModel(ctx, req)
+}
+
+// CompleteWith sends req to the named provider instead of routing by model.
+func (g *Gateway) CompleteWith(
+	ctx context.Context,
+	provider string,
+	req *CompletionRequest,
+) (*CompletionResponse, error) {
+	return g.service.Complete(ctx, provider, req)
+}
+
+// StreamWith streams req from the named provider instead of routing by model.
+func (g *Gateway) StreamWith(ctx context.Context, provider string, req *CompletionRequest) (<-chan StreamChunk, error) {
+	return g.service.Stream(ctx, provider, req)
+}
+
+// Providers lists the names of the registered providers.
+func (g *Gateway) Providers(ctx context.Context) ([]string, error) {
+	return g.providers.List(ctx)
+}
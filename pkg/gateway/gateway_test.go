@@ -0,0 +1,138 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/pkg/gateway"
+)
+
+// memoryUsage is a usage store keeping records in memory.
+type memoryUsage struct {
+	mu      sync.Mutex
+	records []gateway.UsageRecord
+}
+
+func (m *memoryUsage) Record(_ context.Context, record gateway.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *memoryUsage) Query(context.Context, gateway.UsageFilter) ([]gateway.UsageRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.records, nil
+}
+
+// blockAll is a guardrail rejecting every request.
+type blockAll struct{}
+
+func (blockAll) Name() string { return "block-all" }
+
+func (blockAll) Check(context.Context, *gateway.CompletionRequest) error {
+	return errors.New("blocked")
+}
+
+func request(model string) *gateway.CompletionRequest {
+	return &gateway.CompletionRequest{Model: model, Messages: []gateway.Message{{Role: "user", Content: "hello"}}}
+}
+
+func TestGateway_Complete(t *testing.T) {
+	t.Run("should route by model and price the usage", func(t *testing.T) {
+		store := &memoryUsage{}
+		gw, err := gateway.New(t.Context(),
+			gateway.WithProvider(gateway.NewEchoProvider()),
+			gateway.WithPricing("echo4", gateway.PricingConfig{InputCostPer1K: 1, OutputCostPer1K: 2}),
+			gateway.WithUsageStore(store, "team"),
+		)
+		require.NoError(t, err)
+
+		req := request("echo4")
+		req.Metadata = map[string]string{"team": "search"}
+		resp, err := gw.Complete(t.Context(), req)
+
+		require.NoError(t, err)
+		require.Equal(t, "echo", resp.Provider)
+		require.Contains(t, resp.Content, "hello")
+		require.Positive(t, resp.Usage.Cost)
+
+		records, err := store.Query(t.Context(), gateway.UsageFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.InDelta(t, resp.Usage.Cost, records[0].Cost, 1e-9)
+		require.Equal(t, map[string]string{"team": "search"}, records[0].Tags)
+	})
+
+	t.Run("should resolve model aliases", func(t *testing.T) {
+		gw, err := gateway.New(t.Context(),
+			gateway.WithProvider(gateway.NewEchoProvider()),
+			gateway.WithModelAliases(map[string]string{"fast": "echo4"}),
+		)
+		require.NoError(t, err)
+
+		resp, err := gw.Complete(t.Context(), request("fast"))
+
+		require.NoError(t, err)
+		require.Equal(t, "echo", resp.Provider)
+	})
+
+	t.Run("should apply chargeback pricing", func(t *testing.T) {
+		gw, err := gateway.New(t.Context(),
+			gateway.WithProvider(gateway.NewEchoProvider()),
+			gateway.WithPricing("echo4", gateway.PricingConfig{InputCostPer1K: 1, OutputCostPer1K: 1}),
+			gateway.WithChargeback(50, "USD", nil),
+		)
+		require.NoError(t, err)
+
+		resp, err := gw.Complete(t.Context(), request("echo4"))
+
+		require.NoError(t, err)
+		require.InDelta(t, resp.Usage.ProviderCost*1.5, resp.Usage.Cost, 1e-9)
+	})
+
+	t.Run("should block requests failing a guardrail", func(t *testing.T) {
+		gw, err := gateway.New(t.Context(),
+			gateway.WithProvider(gateway.NewEchoProvider()),
+			gateway.WithGuardrails(blockAll{}),
+		)
+		require.NoError(t, err)
+
+		_, err = gw.Complete(t.Context(), request("echo4"))
+
+		require.ErrorContains(t, err, "blocked")
+	})
+
+	t.Run("should reject invalid fallbacks", func(t *testing.T) {
+		_, err := gateway.New(t.Context(), gateway.WithFallbacks(map[string]string{"echo4": "echo4"}, nil))
+
+		require.Error(t, err)
+	})
+}
+
+func TestGateway_Stream(t *testing.T) {
+	t.Run("should stream the reply of a named provider", func(t *testing.T) {
+		gw, err := gateway.New(t.Context(), gateway.WithProvider(gateway.NewEchoProvider()))
+		require.NoError(t, err)
+
+		chunks, err := gw.StreamWith(t.Context(), "echo", request("echo4"))
+		require.NoError(t, err)
+
+		var content strings.Builder
+		for chunk := range chunks {
+			require.NoError(t, chunk.Error)
+			content.WriteString(chunk.Delta)
+		}
+		require.Contains(t, content.String(), "hello")
+
+		providers, err := gw.Providers(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, []string{"echo"}, providers)
+	})
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"maps"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// Option configures a Gateway.
+type Option func(*settings)
+
+// settings collects the options of a Gateway before it is built.
+type settings struct {
+	providers       []Provider
+	pricing         map[string]PricingConfig
+	chargeback      *chargeback
+	aliases         map[string]string
+	fallbacks       map[string]string // context length: model to larger-context model
+	filterRoutes    map[string]string // content filter: provider to provider
+	coalescing      bool
+	usage           UsageStore
+	guardrails      []Guardrail
+	routers         []Router
+	attributionTags []string
+}
+
+// chargeback marks up and converts costs.
+type chargeback struct {
+	markupPercent float64
+	currency      string
+	exchangeRates map[string]float64
+}
+
+// newSettings returns the settings of a gateway without options.
+func newSettings() *settings {
+	return &settings{
+		providers:       nil,
+		pricing:         make(map[string]PricingConfig),
+		chargeback:      nil,
+		aliases:         nil,
+		fallbacks:       nil,
+		filterRoutes:    nil,
+		coalescing:      false,
+		usage:           nil,
+		guardrails:      nil,
+		routers:         nil,
+		attributionTags: nil,
+	}
+}
+
+// WithProvider registers a provider; requests for a model are routed to the
+// first registered provider serving it.
+func WithProvider(provider Provider) Option {
+	return func(s *settings) {
+		s.providers = append(s.providers, provider)
+	}
+}
+
+// WithPricing prices a model, so responses and usage records carry a cost.
+func WithPricing(model string, pricing PricingConfig) Option {
+	return func(s *settings) {
+		s.pricing[model] = pricing
+	}
+}
+
+// WithChargeback reports costs marked up by markupPercent and converted to
+// currency at exchangeRates (units per USD), keeping the USD provider cost.
+func WithChargeback(markupPercent float64, currency string, exchangeRates map[string]float64) Option {
+	return func(s *settings) {
+		s.chargeback = &chargeback{
+			markupPercent: markupPercent,
+			currency:      currency,
+			exchangeRates: maps.Clone(exchangeRates),
+		}
+	}
+}
+
+// WithModelAliases resolves requested model names through aliases before routing.
+func WithModelAliases(aliases map[string]string) Option {
+	return func(s *settings) {
+		s.aliases = maps.Clone(aliases)
+	}
+}
+
+// WithFallbacks re-routes failed requests: prompts too long for a model go to the
+// larger-context model contextLength maps it to, and requests refused by a
+// provider's content filter go to the provider contentFilter maps it to.
+func WithFallbacks(contextLength, contentFilter map[string]string) Option {
+	return func(s *settings) {
+		s.fallbacks = maps.Clone(contextLength)
+		s.filterRoutes = maps.Clone(contentFilter)
+	}
+}
+
+// WithRequestCoalescing serves identical concurrent requests with a single
+// provider call.
+func WithRequestCoalescing() Option {
+	return func(s *settings) {
+		s.coalescing = true
+	}
+}
+
+// WithUsageStore records the usage and cost of every completed request in store,
+// attributing it to the values of the request metadata keys in tags.
+func WithUsageStore(store UsageStore, tags ...string) Option {
+	return func(s *settings) {
+		s.usage = store
+		s.attributionTags = tags
+	}
+}
+
+// WithGuardrails checks every request with guardrails before it is routed.
+func WithGuardrails(guardrails ...Guardrail) Option {
+	return func(s *settings) {
+		s.guardrails = append(s.guardrails, guardrails...)
+	}
+}
+
+// WithRouters consults routers, in order, before routing requests by model.
+func WithRouters(routers ...Router) Option {
+	return func(s *settings) {
+		s.routers = append(s.routers, routers...)
+	}
+}
+
+// gatewayOptions translates the settings into gateway service options.
+func (s *settings) gatewayOptions() ([]domain.GatewayOption, error) {
+	opts := []domain.GatewayOption{
+		domain.WithModelAliases(s.aliases),
+		domain.WithGuardrails(s.guardrails...),
+		domain.WithRouters(s.routers...),
+		domain.WithCostAttribution(s.attributionTags),
+	}
+
+	if len(s.fallbacks) > 0 || len(s.filterRoutes) > 0 {
+		fallbacks, err := domain.NewFallbacks(s.fallbacks, s.filterRoutes)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, domain.WithFallbacks(fallbacks))
+	}
+
+	if s.coalescing {
+		opts = append(opts, domain.WithRequestCoalescing())
+	}
+
+	if s.usage != nil {
+		opts = append(opts, domain.WithUsageStore(s.usage))
+	}
+
+	return opts, nil
+}
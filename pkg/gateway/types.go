@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/provider/echo"
+	"github.com/davidbz/calcifer/internal/provider/openai"
+)
+
+// Core types, shared with the gateway server so embedding programs and the HTTP
+// API speak the same requests, responses, and extension points.
+type (
+	// CompletionRequest is a unified chat completion request.
+	CompletionRequest = domain.CompletionRequest
+
+	// Message is a chat message.
+	Message = domain.Message
+
+	// CompletionResponse is a unified chat completion.
+	CompletionResponse = domain.CompletionResponse
+
+	// Choice is one of several completions generated for a single request.
+	Choice = domain.Choice
+
+	// StreamChunk is one piece of a streamed completion; a chunk carrying an
+	// Error ends the stream.
+	StreamChunk = domain.StreamChunk
+
+	// Usage is the token consumption and cost of a request.
+	Usage = domain.Usage
+
+	// PricingConfig is the price of a model.
+	PricingConfig = domain.PricingConfig
+
+	// Provider is an LLM provider requests are routed to.
+	Provider = domain.Provider
+
+	// Guardrail inspects requests before they are routed.
+	Guardrail = domain.Guardrail
+
+	// Router picks the provider for requests routed by model.
+	Router = domain.Router
+
+	// UsageStore keeps the usage record of every completed request.
+	UsageStore = domain.UsageStore
+
+	// UsageRecord is the usage of one completed request.
+	UsageRecord = domain.UsageRecord
+
+	// UsageFilter selects usage records.
+	UsageFilter = domain.UsageFilter
+
+	// OpenAIConfig configures the built-in OpenAI provider. Zero Timeout and
+	// MaxRetries leave the OpenAI SDK defaults.
+	OpenAIConfig = openai.Config
+)
+
+// NewOpenAIProvider creates the built-in OpenAI provider.
+func NewOpenAIProvider(config OpenAIConfig) (Provider, error) {
+	provider, err := openai.NewProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAI provider: %w", err)
+	}
+	return provider, nil
+}
+
+// NewEchoProvider creates the echo provider, which serves the echo4 model by
+// replying with the request's messages without calling any API.
+func NewEchoProvider() Provider {
+	return echo.NewProvider()
+}
@@ -0,0 +1,206 @@
+// Package plugin registers proprietary providers, middleware, guardrails,
+// routers, response evaluators, and tokenizers at compile time. A plugin is a
+// package exposing a function that adds its extensions to a Registry; it is
+// enabled by calling that function from cmd/plugins.go, so that adding one
+// never requires changes to the gateway's own packages. Plugins may live in
+// other modules: the extension points they implement are aliased here.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/davidbz/calcifer/internal/domain"
+)
+
+// Extension points, shared with the gateway's domain so plugins implement the
+// same interfaces as the built-in extensions.
+type (
+	// Provider is an LLM provider requests are routed to.
+	Provider = domain.Provider
+
+	// PricingRegistry holds the pricing of the models providers serve.
+	PricingRegistry = domain.PricingRegistry
+
+	// CapabilityRegistry holds the context windows and features of models.
+	CapabilityRegistry = domain.CapabilityRegistry
+
+	// Guardrail inspects requests before they are routed.
+	Guardrail = domain.Guardrail
+
+	// Router picks the provider for requests routed by model.
+	Router = domain.Router
+
+	// Evaluator scores sampled responses from 0 to 1.
+	Evaluator = domain.Evaluator
+
+	// Tokenizer counts the tokens of a model's prompts.
+	Tokenizer = domain.Tokenizer
+)
+
+// ProviderFactory creates a plugin provider and registers its pricing and
+// capabilities. Returning a nil provider and nil error skips the provider,
+// e.g. when its configuration is absent.
+type ProviderFactory func(
+	ctx context.Context,
+	pricing domain.PricingRegistry,
+	capabilities domain.CapabilityRegistry,
+) (domain.Provider, error)
+
+// Middleware wraps the gateway's HTTP handler. Plugin middleware runs after the
+// built-in chain, so the client key and tenant are already resolved.
+type Middleware func(http.Handler) http.Handler
+
+// Registry holds registered plugins. Every kind of plugin is keyed by a unique
+// name and applied in name order, so behavior does not depend on import order.
+type Registry struct {
+	mu         sync.Mutex
+	providers  map[string]ProviderFactory
+	middleware map[string]Middleware
+	guardrails map[string]domain.Guardrail
+	routers    map[string]domain.Router
+	evaluators map[string]domain.Evaluator
+	tokenizers map[string]domain.Tokenizer
+}
+
+// NewRegistry creates an empty plugin registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:         sync.Mutex{},
+		providers:  make(map[string]ProviderFactory),
+		middleware: make(map[string]Middleware),
+		guardrails: make(map[string]domain.Guardrail),
+		routers:    make(map[string]domain.Router),
+		evaluators: make(map[string]domain.Evaluator),
+		tokenizers: make(map[string]domain.Tokenizer),
+	}
+}
+
+// RegisterProvider adds a provider factory. It panics if name is already registered.
+func (r *Registry) RegisterProvider(name string, factory ProviderFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.providers, "provider", name, factory)
+}
+
+// RegisterMiddleware adds HTTP middleware. It panics if name is already registered.
+func (r *Registry) RegisterMiddleware(name string, middleware Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.middleware, "middleware", name, middleware)
+}
+
+// RegisterGuardrail adds a guardrail under its Name. It panics if the name is already registered.
+func (r *Registry) RegisterGuardrail(guardrail domain.Guardrail) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.guardrails, "guardrail", guardrail.Name(), guardrail)
+}
+
+// RegisterRouter adds a router. It panics if name is already registered.
+func (r *Registry) RegisterRouter(name string, router domain.Router) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.routers, "router", name, router)
+}
+
+// RegisterEvaluator adds a response evaluator under its Name. It panics if the name is already registered.
+func (r *Registry) RegisterEvaluator(evaluator domain.Evaluator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.evaluators, "evaluator", evaluator.Name(), evaluator)
+}
+
+// RegisterTokenizer adds a tokenizer, such as an exact encoder, for models matching
+// pattern, where a trailing "*" matches any suffix. It panics if pattern is already registered.
+func (r *Registry) RegisterTokenizer(pattern string, tokenizer domain.Tokenizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	register(r.tokenizers, "tokenizer", pattern, tokenizer)
+}
+
+// Providers creates the registered providers. Those whose factory returns nil are
+// left out and their registered names returned as skipped.
+func (r *Registry) Providers(
+	ctx context.Context,
+	pricing domain.PricingRegistry,
+	capabilities domain.CapabilityRegistry,
+) ([]domain.Provider, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	providers := make([]domain.Provider, 0, len(r.providers))
+	var skipped []string
+	for _, name := range slices.Sorted(maps.Keys(r.providers)) {
+		provider, err := r.providers[name](ctx, pricing, capabilities)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create plugin provider %s: %w", name, err)
+		}
+		if provider == nil {
+			skipped = append(skipped, name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers, skipped, nil
+}
+
+// Middleware returns the registered middleware in name order.
+func (r *Registry) Middleware() []Middleware {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedValues(r.middleware)
+}
+
+// Guardrails returns the registered guardrails in name order.
+func (r *Registry) Guardrails() []domain.Guardrail {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedValues(r.guardrails)
+}
+
+// Routers returns the registered routers in name order.
+func (r *Registry) Routers() []domain.Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedValues(r.routers)
+}
+
+// Evaluators returns the registered response evaluators in name order.
+func (r *Registry) Evaluators() []domain.Evaluator {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedValues(r.evaluators)
+}
+
+// Tokenizers returns the registered tokenizers by model pattern.
+func (r *Registry) Tokenizers() map[string]domain.Tokenizer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.tokenizers)
+}
+
+// register stores value under name, panicking on an empty or duplicate name:
+// both are programming errors best caught at startup.
+func register[T any](registered map[string]T, kind, name string, value T) {
+	if name == "" {
+		panic(fmt.Sprintf("plugin: %s name cannot be empty", kind))
+	}
+	if _, exists := registered[name]; exists {
+		panic(fmt.Sprintf("plugin: %s %q registered twice", kind, name))
+	}
+	registered[name] = value
+}
+
+// sortedValues returns the values of registered in name order.
+func sortedValues[T any](registered map[string]T) []T {
+	values := make([]T, 0, len(registered))
+	for _, name := range slices.Sorted(maps.Keys(registered)) {
+		values = append(values, registered[name])
+	}
+	return values
+}
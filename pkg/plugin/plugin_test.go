@@ -0,0 +1,122 @@
+package plugin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidbz/calcifer/internal/domain"
+	"github.com/davidbz/calcifer/internal/mocks"
+	"github.com/davidbz/calcifer/pkg/plugin"
+)
+
+type namedGuardrail string
+
+func (g namedGuardrail) Name() string {
+	return string(g)
+}
+
+func (g namedGuardrail) Check(context.Context, *domain.CompletionRequest) error {
+	return nil
+}
+
+func TestRegistry_Providers(t *testing.T) {
+	t.Run("should create providers in name order and skip unconfigured ones", func(t *testing.T) {
+		registry := plugin.NewRegistry()
+		first := mocks.NewMockProvider(t)
+		second := mocks.NewMockProvider(t)
+
+		registry.RegisterProvider("zeta", func(
+			context.Context, domain.PricingRegistry, domain.CapabilityRegistry,
+		) (domain.Provider, error) {
+			return second, nil
+		})
+		registry.RegisterProvider("alpha", func(
+			context.Context, domain.PricingRegistry, domain.CapabilityRegistry,
+		) (domain.Provider, error) {
+			return first, nil
+		})
+		registry.RegisterProvider("unconfigured", func(
+			context.Context, domain.PricingRegistry, domain.CapabilityRegistry,
+		) (domain.Provider, error) {
+			return nil, nil //nolint:nilnil // A nil provider skips registration
+		})
+
+		providers, skipped, err := registry.Providers(context.Background(), nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Provider{first, second}, providers)
+		require.Equal(t, []string{"unconfigured"}, skipped)
+	})
+
+	t.Run("should report the failing plugin", func(t *testing.T) {
+		registry := plugin.NewRegistry()
+		registry.RegisterProvider("acme", func(
+			context.Context, domain.PricingRegistry, domain.CapabilityRegistry,
+		) (domain.Provider, error) {
+			return nil, errors.New("missing credentials")
+		})
+
+		_, _, err := registry.Providers(context.Background(), nil, nil)
+		require.ErrorContains(t, err, "plugin provider acme: missing credentials")
+	})
+}
+
+func TestRegistry_Middleware(t *testing.T) {
+	registry := plugin.NewRegistry()
+	for _, name := range []string{"b", "a"} {
+		registry.RegisterMiddleware(name, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Plugin", name)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+
+	var handler http.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	middleware := registry.Middleware()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, []string{"a", "b"}, recorder.Header().Values("X-Plugin"))
+}
+
+func TestRegistry_Register(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(registry *plugin.Registry)
+	}{
+		{
+			name: "should reject a duplicate guardrail",
+			register: func(registry *plugin.Registry) {
+				registry.RegisterGuardrail(namedGuardrail("pii"))
+				registry.RegisterGuardrail(namedGuardrail("pii"))
+			},
+		},
+		{
+			name: "should reject an unnamed guardrail",
+			register: func(registry *plugin.Registry) {
+				registry.RegisterGuardrail(namedGuardrail(""))
+			},
+		},
+		{
+			name: "should reject a duplicate evaluator",
+			register: func(registry *plugin.Registry) {
+				registry.RegisterEvaluator(domain.NewJudgeEvaluator(nil, "gpt-4o-mini"))
+				registry.RegisterEvaluator(domain.NewJudgeEvaluator(nil, "gpt-4o"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Panics(t, func() { tt.register(plugin.NewRegistry()) })
+		})
+	}
+}